@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ctfer-io/monitoring/internal/stackconfig"
+	"github.com/ctfer-io/monitoring/services"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+	BuiltBy = ""
+)
+
+func main() {
+	app := &cli.Command{
+		Name:  "Monitoring Config Diff",
+		Usage: "Preview the OpenTelemetry Collector and Prometheus configurations changes between two Monitoring stack configurations, offline.",
+		Flags: []cli.Flag{
+			cli.VersionFlag,
+			cli.HelpFlag,
+			&cli.StringFlag{
+				Name:  "from",
+				Usage: "The current stack configuration file (Pulumi.<stack>.yaml). Defaults to the default configuration.",
+			},
+			&cli.StringFlag{
+				Name:  "to",
+				Usage: "The proposed stack configuration file. Defaults to the current one.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "Override a value of the proposed configuration, as key=value (e.g. cold-extract=true, arrays in JSON). Could be repeated.",
+			},
+			&cli.StringFlag{
+				Name:  "project",
+				Value: "monitoring",
+				Usage: "The Pulumi project namespacing the configuration keys.",
+			},
+		},
+		DisableSliceFlagSeparator: true, // --set values could contain commas
+		Action:                    run,
+		Authors: []any{
+			"CTFer.io Authors & Contributors - ctfer-io@protonmail.com",
+		},
+		Version: Version,
+		Metadata: map[string]any{
+			"version": Version,
+			"commit":  Commit,
+			"date":    Date,
+			"builtBy": BuiltBy,
+		},
+	}
+
+	if err := app.Run(context.Background(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(_ context.Context, cmd *cli.Command) error {
+	from, to := cmd.String("from"), cmd.String("to")
+	if to == "" {
+		to = from
+	}
+
+	current, err := stackconfig.Load(from, cmd.String("project"))
+	if err != nil {
+		return err
+	}
+	proposed, err := stackconfig.Load(to, cmd.String("project"))
+	if err != nil {
+		return err
+	}
+	for _, kv := range cmd.StringSlice("set") {
+		if err := proposed.Set(kv); err != nil {
+			return err
+		}
+	}
+
+	currentCfgs, err := current.Render()
+	if err != nil {
+		return errors.Wrap(err, "current configuration")
+	}
+	proposedCfgs, err := proposed.Render()
+	if err != nil {
+		return errors.Wrap(err, "proposed configuration")
+	}
+	return writeDiff(os.Stdout, currentCfgs, proposedCfgs)
+}
+
+// writeDiff writes the unified diff of the configurations, nothing if they
+// are the same.
+func writeDiff(w io.Writer, current, proposed *services.Configs) error {
+	for _, f := range []struct {
+		Name              string
+		Current, Proposed string
+	}{
+		{"otel-collector.yaml", current.OTELCollector, proposed.OTELCollector},
+		{"prometheus.yaml", current.Prometheus, proposed.Prometheus},
+	} {
+		if err := difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+			A:        difflib.SplitLines(f.Current),
+			B:        difflib.SplitLines(f.Proposed),
+			FromFile: "current/" + f.Name,
+			ToFile:   "proposed/" + f.Name,
+			Context:  3,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
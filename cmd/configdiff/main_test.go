@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/internal/stackconfig"
+)
+
+func Test_U_WriteDiff(t *testing.T) {
+	t.Parallel()
+
+	current, err := stackconfig.Config{}.Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// No change, no diff
+	buf := &bytes.Buffer{}
+	if err := writeDiff(buf, current, current); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no diff, got:\n%s", buf.String())
+	}
+
+	// Config change
+	proposed, err := stackconfig.Config{"dependency-graph": "true"}.Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf.Reset()
+	if err := writeDiff(buf, current, proposed); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	diff := buf.String()
+	if !strings.Contains(diff, "--- current/otel-collector.yaml") || !strings.Contains(diff, "+  servicegraph:") {
+		t.Errorf("expected the otel collector config diff, got:\n%s", diff)
+	}
+	if strings.Contains(diff, "prometheus.yaml") {
+		t.Errorf("expected no prometheus config diff, got:\n%s", diff)
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+)
+
+// batchReportFile is the combined report of the targets of a manifest,
+// written at the root of the directory. Each target has its own report in
+// its directory.
+const batchReportFile = "manifest-report.json"
+
+type (
+	// batchReport is the combined report of the targets of a manifest.
+	batchReport struct {
+		Version   int           `json:"version"`
+		Status    string        `json:"status"`
+		Error     string        `json:"error,omitempty"`
+		Manifest  string        `json:"manifest"`
+		StartedAt time.Time     `json:"started_at"`
+		Duration  float64       `json:"duration_seconds"`
+		Succeeded int           `json:"succeeded"`
+		Failed    int           `json:"failed"`
+		Files     int           `json:"files"`
+		Bytes     int64         `json:"bytes"`
+		Targets   []batchTarget `json:"targets"`
+
+		// Report is the path to the report file, if written.
+		Report string `json:"report,omitempty"`
+	}
+
+	// batchTarget is the extraction of a target of the manifest.
+	batchTarget struct {
+		Name      string `json:"name"`
+		Context   string `json:"context,omitempty"`
+		Namespace string `json:"namespace"`
+		PVCName   string `json:"pvc_name,omitempty"`
+		Selector  string `json:"selector,omitempty"`
+		Directory string `json:"directory"`
+
+		// Status and ExitCode are the ones a run extracting this target
+		// only would end with.
+		Status   string `json:"status"`
+		ExitCode int    `json:"exit_code"`
+		Error    string `json:"error,omitempty"`
+
+		Files    int      `json:"files"`
+		Bytes    int64    `json:"bytes"`
+		Duration float64  `json:"duration_seconds"`
+		Warnings []string `json:"warnings,omitempty"`
+
+		// Summary is the human-readable summary of the target extraction.
+		Summary []extract.SummaryLine `json:"summary,omitempty"`
+
+		// Report is the path to the report file of the target, if written.
+		Report string `json:"report,omitempty"`
+	}
+
+	// batchDump extracts a target of the manifest.
+	batchDump func(ctx context.Context, tgt manifestTarget) (*extract.Result, error)
+)
+
+// runManifest extracts the targets of the manifest, writes the combined
+// report at the root of the directory, and the summary or output of it.
+func runManifest(ctx context.Context, cmd *cli.Command, start time.Time, out *os.File) error {
+	for _, name := range []string{"namespace", "pvc-name", "discover", "all", "record", "replay", "dry-run", "timestamped", "max-local-size", "max-local-runs", "progress-events"} {
+		if cmd.IsSet(name) {
+			return errors.Errorf("%s is mutually exclusive with manifest", name)
+		}
+	}
+	switch {
+	case cmd.String("target") != extract.SourceOTelCollector:
+		return errors.Errorf("manifest is only supported with the %s target", extract.SourceOTelCollector)
+	case cmd.String("sink") != sinkDirectory:
+		return errors.New("manifest requires the directory sink")
+	}
+
+	root := cmd.String("directory")
+	mf, err := readManifest(cmd.String("manifest"), root)
+	if err != nil {
+		return err
+	}
+	targets := runBatch(ctx, mf, func(ctx context.Context, tgt manifestTarget) (*extract.Result, error) {
+		return dumpManifestTarget(ctx, cmd, tgt)
+	})
+	rep, err := aggregateBatch(cmd.String("manifest"), start, targets)
+
+	if werr := os.MkdirAll(root, 0o755); werr != nil {
+		return errors.Wrap(werr, "creating directory")
+	}
+	rep.Report = filepath.Join(root, batchReportFile)
+	if werr := writeBatchReport(rep.Report, rep); werr != nil {
+		return werr
+	}
+
+	switch cmd.String("output") {
+	case outputJSON:
+		if werr := json.NewEncoder(out).Encode(rep); werr != nil {
+			return werr
+		}
+	default:
+		if werr := writeBatchSummary(out, rep, useColor(out, cmd.Bool("no-color"))); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+// dumpManifestTarget extracts the target as a run of the flags would, the
+// target options overriding them.
+func dumpManifestTarget(ctx context.Context, cmd *cli.Command, tgt manifestTarget) (*extract.Result, error) {
+	limit := cmd.String("bandwidth-limit")
+	if tgt.Options.BandwidthLimit != "" {
+		limit = tgt.Options.BandwidthLimit
+	}
+	bandwidthLimit, err := parseBandwidthLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// The progress events of concurrent targets would interleave
+	opts := append(pvcOptions(cmd, bandwidthLimit, nil), tgt.options()...)
+	opts = append(opts, extract.WithLogger(log().With(zap.String("target", tgt.Name))))
+	if tgt.Selector != "" {
+		return extract.DumpAll(ctx, tgt.Namespace, tgt.Directory, opts...)
+	}
+	return extract.DumpOTelCollector(ctx, tgt.Namespace, tgt.PVC, tgt.Directory, opts...)
+}
+
+// runBatch extracts the targets of the manifest through the dump function,
+// at most the concurrency of the manifest at once. The targets sharing a
+// namespace of a cluster are extracted one after the other, as the
+// extraction Pod is unique in it. A failing target does not abort the
+// others. The targets are returned in the manifest order.
+func runBatch(ctx context.Context, mf *manifest, dump batchDump) []batchTarget {
+	locks := map[string]*sync.Mutex{}
+	for _, tgt := range mf.Targets {
+		if _, ok := locks[tgt.key()]; !ok {
+			locks[tgt.key()] = &sync.Mutex{}
+		}
+	}
+
+	sem := make(chan struct{}, mf.Concurrency)
+	targets := make([]batchTarget, len(mf.Targets))
+	wg := sync.WaitGroup{}
+	for i, tgt := range mf.Targets {
+		wg.Go(func() {
+			// Don't hold a slot while waiting for the namespace
+			lock := locks[tgt.key()]
+			lock.Lock()
+			defer lock.Unlock()
+			sem <- struct{}{}
+			defer func() {
+				<-sem
+			}()
+
+			start := time.Now()
+			var res *extract.Result
+			err := ctx.Err()
+			if err == nil {
+				log().Info("extracting manifest target",
+					zap.String("target", tgt.Name),
+					zap.String("context", tgt.Context),
+					zap.String("namespace", tgt.Namespace),
+				)
+				res, err = dump(ctx, tgt)
+			}
+			targets[i] = newBatchTarget(tgt, res, err, time.Since(start))
+			if err != nil {
+				log().Error("extracting manifest target",
+					zap.String("target", tgt.Name),
+					zap.Error(err),
+				)
+			}
+		})
+	}
+	wg.Wait()
+	return targets
+}
+
+// newBatchTarget returns the extraction of the target from its result and
+// error. The result could be nil if it failed early.
+func newBatchTarget(tgt manifestTarget, res *extract.Result, err error, elapsed time.Duration) batchTarget {
+	bt := batchTarget{
+		Name:      tgt.Name,
+		Context:   tgt.Context,
+		Namespace: tgt.Namespace,
+		PVCName:   tgt.PVC,
+		Selector:  tgt.Selector,
+		Directory: tgt.Directory,
+		Status:    "success",
+		Duration:  elapsed.Seconds(),
+	}
+	if err != nil {
+		bt.Status = "failure"
+		bt.ExitCode = 1
+		bt.Error = err.Error()
+	}
+	if res != nil {
+		bt.Files = res.Files
+		bt.Bytes = res.Bytes
+		bt.Warnings = res.Warnings
+		bt.Summary = res.Summary
+		bt.Report = res.Report
+	}
+	return bt
+}
+
+// aggregateBatch combines the extractions of the targets, failing if any
+// did.
+func aggregateBatch(manifest string, start time.Time, targets []batchTarget) (*batchReport, error) {
+	rep := &batchReport{
+		Version:   outputVersion,
+		Status:    "success",
+		Manifest:  manifest,
+		StartedAt: start,
+		Duration:  time.Since(start).Seconds(),
+		Targets:   targets,
+	}
+	for _, tgt := range targets {
+		rep.Files += tgt.Files
+		rep.Bytes += tgt.Bytes
+		if tgt.ExitCode != 0 {
+			rep.Failed++
+			continue
+		}
+		rep.Succeeded++
+	}
+	if rep.Failed != 0 {
+		err := fmt.Errorf("%d of %d manifest targets failed to extract", rep.Failed, len(targets))
+		rep.Status = "failure"
+		rep.Error = err.Error()
+		return rep, err
+	}
+	return rep, nil
+}
+
+// writeBatchReport writes the combined report into the file.
+func writeBatchReport(path string, rep *batchReport) error {
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return errors.Wrap(err, "writing manifest report")
+	}
+	return nil
+}
+
+// writeBatchSummary writes the human-readable recap of the targets, the
+// first summary line of each (or its error) and its warnings.
+func writeBatchSummary(w io.Writer, rep *batchReport, color bool) error {
+	if _, err := fmt.Fprintln(w, "Summary:"); err != nil {
+		return err
+	}
+	for _, tgt := range rep.Targets {
+		line := fmt.Sprintf("%s: %s", tgt.Name, tgt.Status)
+		if len(tgt.Summary) != 0 {
+			line = fmt.Sprintf("%s: %s", tgt.Name, tgt.Summary[0].Text)
+		}
+		if _, err := fmt.Fprintf(w, "  %s\n", line); err != nil {
+			return err
+		}
+		for _, warn := range tgt.Warnings {
+			if _, err := fmt.Fprintf(w, "  %s%s: %s\n", colorize("warning: ", colorYellow, color), tgt.Name, warn); err != nil {
+				return err
+			}
+		}
+		if tgt.Error != "" {
+			if _, err := fmt.Fprintf(w, "  %s%s: %s\n", colorize("error: ", colorRed, color), tgt.Name, tgt.Error); err != nil {
+				return err
+			}
+		}
+	}
+	status := fmt.Sprintf("%d of %d targets extracted", rep.Succeeded, len(rep.Targets))
+	if rep.Error != "" {
+		status = colorize("error: ", colorRed, color) + rep.Error
+	}
+	if _, err := fmt.Fprintf(w, "  %s\n", status); err != nil {
+		return err
+	}
+	if rep.Report != "" {
+		if _, err := fmt.Fprintf(w, "  report: %s\n", rep.Report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
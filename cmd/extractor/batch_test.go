@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_RunBatch(t *testing.T) {
+	t.Parallel()
+
+	mf := &manifest{
+		Concurrency: 2,
+		Targets: []manifestTarget{
+			{Name: "finals-eu", Context: "finals-eu", Namespace: "monitoring", PVC: "signals", Directory: "extract/finals-eu"},
+			{Name: "finals-us", Context: "finals-us", Namespace: "monitoring", PVC: "signals", Directory: "extract/finals-us"},
+			{Name: "finals-as", Context: "finals-as", Namespace: "monitoring", PVC: "signals", Directory: "extract/finals-as"},
+			{Name: "finals-eu-jaeger", Context: "finals-eu", Namespace: "monitoring", Selector: "app.kubernetes.io/component=jaeger", Directory: "extract/finals-eu-jaeger"},
+		},
+	}
+
+	mu := sync.Mutex{}
+	running, maxRunning := 0, 0
+	namespaces := map[string]int{}
+	dump := func(_ context.Context, tgt manifestTarget) (*extract.Result, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		namespaces[tgt.key()]++
+		if namespaces[tgt.key()] > 1 {
+			t.Errorf("target %s extracted along another one of namespace %s", tgt.Name, tgt.key())
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		namespaces[tgt.key()]--
+		mu.Unlock()
+
+		if tgt.Name == "finals-us" {
+			return &extract.Result{Files: 0}, errors.New("pod extractor never got ready")
+		}
+		return &extract.Result{
+			Files:    3,
+			Bytes:    2048,
+			Warnings: []string{"file no longer on the PVC: otel_logs"},
+			Report:   filepath.Join(tgt.Directory, extract.ReportFile),
+		}, nil
+	}
+
+	targets := runBatch(context.Background(), mf, dump)
+	if maxRunning > mf.Concurrency {
+		t.Errorf("expected at most %d targets at once, got %d", mf.Concurrency, maxRunning)
+	}
+	if len(targets) != len(mf.Targets) {
+		t.Fatalf("expected %d targets, got %d", len(mf.Targets), len(targets))
+	}
+	for i, tgt := range targets {
+		if tgt.Name != mf.Targets[i].Name {
+			t.Errorf("expected target %d to be %s, got %s", i, mf.Targets[i].Name, tgt.Name)
+		}
+		expectedCode := 0
+		if tgt.Name == "finals-us" {
+			expectedCode = 1
+		}
+		if tgt.ExitCode != expectedCode {
+			t.Errorf("expected target %s exit code %d, got %d (%s)", tgt.Name, expectedCode, tgt.ExitCode, tgt.Error)
+		}
+	}
+}
+
+func Test_U_RunBatch_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	mf := &manifest{
+		Concurrency: 1,
+		Targets: []manifestTarget{
+			{Name: "quals", Namespace: "monitoring", PVC: "signals"},
+		},
+	}
+	targets := runBatch(ctx, mf, func(context.Context, manifestTarget) (*extract.Result, error) {
+		t.Error("expected no extraction once canceled")
+		return nil, nil
+	})
+	if targets[0].ExitCode != 1 || targets[0].Error == "" {
+		t.Errorf("expected the target to fail, got %+v", targets[0])
+	}
+}
+
+func Test_U_AggregateBatch(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Targets           []batchTarget
+		ExpectErr         bool
+		ExpectedStatus    string
+		ExpectedSucceeded int
+		ExpectedFailed    int
+		ExpectedFiles     int
+		ExpectedBytes     int64
+	}{
+		"success": {
+			Targets: []batchTarget{
+				{Name: "finals-eu", Status: "success", Files: 3, Bytes: 2048},
+				{Name: "finals-us", Status: "success", Files: 1, Bytes: 1024},
+			},
+			ExpectedStatus:    "success",
+			ExpectedSucceeded: 2,
+			ExpectedFiles:     4,
+			ExpectedBytes:     3072,
+		},
+		"partial-failure": {
+			Targets: []batchTarget{
+				{Name: "finals-eu", Status: "success", Files: 3, Bytes: 2048},
+				{Name: "finals-us", Status: "failure", ExitCode: 1, Error: "pod extractor never got ready"},
+				{Name: "finals-as", Status: "failure", ExitCode: 1, Error: "context deadline exceeded", Files: 1, Bytes: 512},
+			},
+			ExpectErr:         true,
+			ExpectedStatus:    "failure",
+			ExpectedSucceeded: 1,
+			ExpectedFailed:    2,
+			ExpectedFiles:     4,
+			ExpectedBytes:     2560,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			rep, err := aggregateBatch("manifest.yaml", time.Now(), tt.Targets)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if rep.Status != tt.ExpectedStatus {
+				t.Errorf("expected status %s, got %s", tt.ExpectedStatus, rep.Status)
+			}
+			if rep.Succeeded != tt.ExpectedSucceeded || rep.Failed != tt.ExpectedFailed {
+				t.Errorf("expected %d succeeded and %d failed, got %d and %d", tt.ExpectedSucceeded, tt.ExpectedFailed, rep.Succeeded, rep.Failed)
+			}
+			if rep.Files != tt.ExpectedFiles || rep.Bytes != tt.ExpectedBytes {
+				t.Errorf("expected %d files (%d bytes), got %d (%d bytes)", tt.ExpectedFiles, tt.ExpectedBytes, rep.Files, rep.Bytes)
+			}
+			if rep.Version != outputVersion {
+				t.Errorf("expected version %d, got %d", outputVersion, rep.Version)
+			}
+		})
+	}
+}
+
+func Test_U_WriteBatchSummary(t *testing.T) {
+	t.Parallel()
+
+	rep, _ := aggregateBatch("manifest.yaml", time.Now(), []batchTarget{
+		{
+			Name:     "finals-eu",
+			Status:   "success",
+			Files:    3,
+			Bytes:    2048,
+			Warnings: []string{"file no longer on the PVC: otel_logs"},
+			Summary: []extract.SummaryLine{
+				{Level: extract.SummaryInfo, Text: "copied 3 files (2.0 KiB) from PVC monitoring/signals to extract/finals-eu in 1.5s"},
+			},
+		},
+		{
+			Name:     "finals-us",
+			Status:   "failure",
+			ExitCode: 1,
+			Error:    "pod extractor never got ready",
+		},
+	})
+	rep.Report = "extract/manifest-report.json"
+
+	buf := &bytes.Buffer{}
+	if err := writeBatchSummary(buf, rep, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected, err := os.ReadFile(filepath.Join("testdata", "summary-manifest.golden"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, buf.String())
+	}
+}
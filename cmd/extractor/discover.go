@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+// selectTarget picks the target to extract among the discovered candidates.
+// If there is a single one, it is confirmed by the user unless assumeYes is set.
+// If there are multiple, the user has to choose one, which is impossible with
+// assumeYes as we can't decide on its behalf.
+func selectTarget(candidates []extract.Target, in io.Reader, out io.Writer, assumeYes bool) (*extract.Target, error) {
+	switch len(candidates) {
+	case 0:
+		return nil, errors.New("no monitoring signals PVC discovered")
+
+	case 1:
+		tgt := candidates[0]
+		if assumeYes {
+			return &tgt, nil
+		}
+		_, _ = fmt.Fprintf(out, "Extract from PVC %s in namespace %s? [y/N] ", tgt.PVCName, tgt.Namespace)
+		ans, err := readLine(in)
+		if err != nil {
+			return nil, err
+		}
+		if ans != "y" && ans != "yes" {
+			return nil, errors.New("extraction aborted")
+		}
+		return &tgt, nil
+
+	default:
+		if assumeYes {
+			return nil, fmt.Errorf("%d monitoring signals PVCs discovered, set the namespace to pick one", len(candidates))
+		}
+		_, _ = fmt.Fprintln(out, "Multiple monitoring signals PVCs discovered:")
+		for i, tgt := range candidates {
+			_, _ = fmt.Fprintf(out, "  [%d] %s/%s\n", i+1, tgt.Namespace, tgt.PVCName)
+		}
+		_, _ = fmt.Fprintf(out, "Which one to extract from? [1-%d] ", len(candidates))
+		ans, err := readLine(in)
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(ans)
+		if err != nil || i < 1 || i > len(candidates) {
+			return nil, fmt.Errorf("invalid choice %q", ans)
+		}
+		return &candidates[i-1], nil
+	}
+}
+
+func readLine(in io.Reader) (string, error) {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(line)), nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_SelectTarget(t *testing.T) {
+	t.Parallel()
+
+	one := []extract.Target{
+		{Namespace: "monitoring-a", PVCName: "signals-a"},
+	}
+	two := []extract.Target{
+		{Namespace: "monitoring-a", PVCName: "signals-a"},
+		{Namespace: "monitoring-b", PVCName: "signals-b"},
+	}
+
+	var tests = map[string]struct {
+		Candidates []extract.Target
+		Input      string
+		AssumeYes  bool
+		Expected   *extract.Target
+	}{
+		"none": {
+			Candidates: nil,
+			Expected:   nil,
+		},
+		"single-confirmed": {
+			Candidates: one,
+			Input:      "y\n",
+			Expected:   &one[0],
+		},
+		"single-refused": {
+			Candidates: one,
+			Input:      "n\n",
+			Expected:   nil,
+		},
+		"single-assume-yes": {
+			Candidates: one,
+			AssumeYes:  true,
+			Expected:   &one[0],
+		},
+		"multiple-chosen": {
+			Candidates: two,
+			Input:      "2\n",
+			Expected:   &two[1],
+		},
+		"multiple-out-of-range": {
+			Candidates: two,
+			Input:      "3\n",
+			Expected:   nil,
+		},
+		"multiple-assume-yes": {
+			Candidates: two,
+			AssumeYes:  true,
+			Expected:   nil,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			tgt, err := selectTarget(tt.Candidates, strings.NewReader(tt.Input), out, tt.AssumeYes)
+			if tt.Expected == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got target %v", tgt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if *tgt != *tt.Expected {
+				t.Errorf("expected %v, got %v", *tt.Expected, *tgt)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 var (
@@ -29,35 +34,351 @@ const (
 func main() {
 	app := &cli.Command{
 		Name:  "Monitoring Extractor",
-		Usage: "Extract the Monitoring files from an OpenTelemetry Collector.",
+		Usage: "Extract the Monitoring files from an OpenTelemetry Collector, or a Prometheus TSDB snapshot.",
 		Flags: []cli.Flag{
 			cli.VersionFlag,
 			cli.HelpFlag,
 			&cli.StringFlag{
-				Name:     "namespace",
-				Sources:  cli.EnvVars("NAMESPACE"),
-				Required: true,
-				Usage:    "The namespace in which to deploy the extraction Pod.",
+				Name:    "target",
+				Sources: cli.EnvVars("TARGET"),
+				Value:   extract.SourceOTelCollector,
+				Usage:   "What to extract: otel for the OpenTelemetry Collector signals PVC, or prometheus for a snapshot of its TSDB (requires its admin API).",
+				Validator: func(t string) error {
+					if t != extract.SourceOTelCollector && t != extract.SourcePrometheus {
+						return fmt.Errorf("invalid target %s", t)
+					}
+					return nil
+				},
 			},
 			&cli.StringFlag{
-				Name:     "pvc-name",
-				Sources:  cli.EnvVars("PVC_NAME"),
-				Required: true,
-				Usage:    "The PVC name to mount and copy files from.",
+				Name:    "kube-context",
+				Sources: cli.EnvVars("KUBE_CONTEXT"),
+				Usage:   "The context of the kubeconfig to reach the cluster through. Defaults to the current one.",
 			},
 			&cli.StringFlag{
-				Name:     "directory",
-				Sources:  cli.EnvVars("DIRECTORY"),
-				Required: true,
-				Usage:    "The directory in which to export the OpenTelemetry Collector files.",
+				Name:    "namespace",
+				Sources: cli.EnvVars("NAMESPACE"),
+				Usage:   "The namespace in which to deploy the extraction Pod. Required unless discovering.",
+			},
+			&cli.StringFlag{
+				Name:    "pvc-name",
+				Sources: cli.EnvVars("PVC_NAME"),
+				Usage:   "The PVC name to mount and copy files from. Required unless discovering.",
+			},
+			&cli.BoolFlag{
+				Name:    "discover",
+				Sources: cli.EnvVars("DISCOVER"),
+				Usage:   "Discover the namespace (if not set) and PVC to extract from, among the Monitoring deployments in the cluster.",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Sources: cli.EnvVars("YES"),
+				Usage:   "Don't prompt for confirmation of the discovered PVC. Fails if multiple are discovered.",
+			},
+			&cli.BoolFlag{
+				Name:    "all",
+				Sources: cli.EnvVars("ALL"),
+				Usage:   "Extract every PVC of the Monitoring in the namespace, each into its own subdirectory, along a combined report. A failing PVC does not abort the others. Only with the otel target.",
+			},
+			&cli.StringFlag{
+				Name:    "manifest",
+				Sources: cli.EnvVars("MANIFEST"),
+				Usage:   "Extract the targets listed in this YAML manifest, e.g. across clusters, with a bounded concurrency, along a combined report. A failing target does not abort the others. Relative target directories are under the directory. Only with the otel target and the directory sink.",
+			},
+			&cli.StringFlag{
+				Name:    "directory",
+				Sources: cli.EnvVars("DIRECTORY"),
+				Usage:   "The directory in which to export the OpenTelemetry Collector files, or to verify the extraction of. Required with the directory sink.",
+			},
+			&cli.BoolFlag{
+				Name:    "timestamped",
+				Sources: cli.EnvVars("TIMESTAMPED"),
+				Usage:   "Extract into a new timestamped subdirectory of the directory (e.g. 20261016T020000Z), such that repeated runs are kept apart.",
+			},
+			&cli.StringFlag{
+				Name:    "max-local-size",
+				Sources: cli.EnvVars("MAX_LOCAL_SIZE"),
+				Usage:   "Before extracting, purge the oldest timestamped runs until they total at most this size, as a Kubernetes quantity (e.g. 20Gi). Requires timestamped.",
+			},
+			&cli.IntFlag{
+				Name:    "max-local-runs",
+				Sources: cli.EnvVars("MAX_LOCAL_RUNS"),
+				Usage:   "Before extracting, purge the oldest timestamped runs such that at most this number remains, this one included. Requires timestamped.",
+			},
+			&cli.BoolFlag{
+				Name:    "dry-run",
+				Sources: cli.EnvVars("DRY_RUN"),
+				Usage:   "Only show where the run would extract, and the timestamped runs it would purge first.",
+			},
+			&cli.StringFlag{
+				Name:    "sink",
+				Sources: cli.EnvVars("SINK"),
+				Value:   sinkDirectory,
+				Usage:   "Where the extracted files land: directory, archive for a tar.gz file, s3 for an object uploaded in parts, oci for an artifact pushed to a registry, or stdout for a tar stream. Only the directory sink works with the timestamped, max-local-*, dry-run, decompress, fsync and all options.",
+				Validator: func(s string) error {
+					switch s {
+					case sinkDirectory, sinkArchive, sinkS3, sinkOCI, sinkStdout:
+						return nil
+					}
+					return fmt.Errorf("invalid sink %s", s)
+				},
+			},
+			&cli.StringFlag{
+				Name:    "archive-path",
+				Sources: cli.EnvVars("ARCHIVE_PATH"),
+				Usage:   "The tar.gz file to write with the archive sink. It only appears once complete.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-endpoint",
+				Sources: cli.EnvVars("S3_ENDPOINT"),
+				Usage:   "The S3 endpoint URL of the s3 sink, e.g. https://s3.eu-west-3.amazonaws.com or the one of a MinIO. Buckets are addressed by path.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-region",
+				Sources: cli.EnvVars("S3_REGION", "AWS_REGION"),
+				Usage:   "The region to sign the s3 sink requests for. Defaults to us-east-1.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-bucket",
+				Sources: cli.EnvVars("S3_BUCKET"),
+				Usage:   "The bucket of the s3 sink.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-key",
+				Sources: cli.EnvVars("S3_KEY"),
+				Usage:   "The key of the tar.gz object of the s3 sink.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-access-key-id",
+				Sources: cli.EnvVars("AWS_ACCESS_KEY_ID"),
+				Usage:   "The access key ID of the s3 sink.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-secret-access-key",
+				Sources: cli.EnvVars("AWS_SECRET_ACCESS_KEY"),
+				Usage:   "The secret access key of the s3 sink.",
+			},
+			&cli.StringFlag{
+				Name:    "s3-session-token",
+				Sources: cli.EnvVars("AWS_SESSION_TOKEN"),
+				Usage:   "The session token of the s3 sink, for temporary credentials.",
+			},
+			&cli.StringFlag{
+				Name:    "oci-reference",
+				Sources: cli.EnvVars("OCI_REFERENCE"),
+				Usage:   "The reference to push the artifact of the oci sink to, e.g. registry.example.com/ctf/extractions:2026-10-16. Digests are not supported.",
+			},
+			&cli.StringFlag{
+				Name:    "oci-username",
+				Sources: cli.EnvVars("OCI_USERNAME"),
+				Usage:   "The username to authenticate to the registry of the oci sink.",
+			},
+			&cli.StringFlag{
+				Name:    "oci-password",
+				Sources: cli.EnvVars("OCI_PASSWORD"),
+				Usage:   "The password or token to authenticate to the registry of the oci sink.",
+			},
+			&cli.BoolFlag{
+				Name:    "oci-plain-http",
+				Sources: cli.EnvVars("OCI_PLAIN_HTTP"),
+				Usage:   "Reach the registry of the oci sink over plain HTTP, e.g. a local one.",
 			},
 			&cli.StringFlag{
 				Name:    "registry",
 				Sources: cli.EnvVars("REGISTRY"),
 				Usage:   "An optional OCI registry from which to pool the Docker image used to extract the files (" + img + ").",
 			},
+			&cli.StringFlag{
+				Name:    "pod-image",
+				Sources: cli.EnvVars("POD_IMAGE"),
+				Usage:   "The image of the extraction Pod replacing " + img + ", e.g. a distroless one. Pulled as is, whatever the registry. It must provide tar, and requires the exec transport.",
+			},
+			&cli.StringSliceFlag{
+				Name:    "pod-command",
+				Sources: cli.EnvVars("POD_COMMAND"),
+				Usage:   "The command keeping the extraction Pod alive, for images without a shell, e.g. /busybox/sleep. Requires the exec transport. Defaults to /bin/sh -c --.",
+			},
+			&cli.StringSliceFlag{
+				Name:    "pod-args",
+				Sources: cli.EnvVars("POD_ARGS"),
+				Usage:   "The arguments of the command keeping the extraction Pod alive, e.g. infinity. Requires the exec transport. Defaults to \"sleep infinity\" with the default command, none with a custom one.",
+			},
+			&cli.BoolFlag{
+				Name:    "verify-remote",
+				Sources: cli.EnvVars("VERIFY_REMOTE"),
+				Usage:   "Verify the extracted files against the PVC ones, by comparing their SHA256 checksums computed in the Pod.",
+			},
+			&cli.StringFlag{
+				Name:    "bandwidth-limit",
+				Sources: cli.EnvVars("BANDWIDTH_LIMIT"),
+				Usage:   "Limit the copy to this number of bytes per second on average, as a Kubernetes quantity (e.g. 50Mi).",
+			},
+			&cli.IntFlag{
+				Name:    "workers",
+				Sources: cli.EnvVars("WORKERS"),
+				Usage:   "The number of workers writing and hashing the extracted files. Defaults to GOMAXPROCS.",
+			},
+			&cli.DurationFlag{
+				Name:    "max-duration",
+				Sources: cli.EnvVars("MAX_DURATION"),
+				Usage:   "Fail the copy with a timeout error if not completed within this duration (e.g. stalled on an NFS issue), cleaning up the extraction Pod. Caps the auto deadline. Disabled by default.",
+			},
+			&cli.BoolFlag{
+				Name:    "auto-deadline",
+				Sources: cli.EnvVars("AUTO_DEADLINE"),
+				Usage:   "Fail the copy with a timeout error if not completed within a deadline estimated from the size of the files to copy, and the bandwidth limit if any.",
+			},
+			&cli.StringFlag{
+				Name:    "runtime-class",
+				Sources: cli.EnvVars("RUNTIME_CLASS"),
+				Usage:   "An optional RuntimeClass for the extraction Pod (e.g. gvisor).",
+			},
+			&cli.StringFlag{
+				Name:    "seccomp-profile",
+				Sources: cli.EnvVars("SECCOMP_PROFILE"),
+				Usage:   "The seccomp profile type of the extraction Pod: RuntimeDefault (default), Localhost or Unconfined.",
+			},
+			&cli.StringFlag{
+				Name:    "seccomp-localhost-profile",
+				Sources: cli.EnvVars("SECCOMP_LOCALHOST_PROFILE"),
+				Usage:   "The seccomp profile path on the node, required by the Localhost seccomp profile type.",
+			},
+			&cli.StringFlag{
+				Name:    "mount-path",
+				Sources: cli.EnvVars("MOUNT_PATH"),
+				Value:   "/data",
+				Usage:   "Where to mount the PVC in the extraction Pod.",
+			},
+			&cli.StringFlag{
+				Name:    "source-path",
+				Sources: cli.EnvVars("SOURCE_PATH"),
+				Usage:   "The directory to copy files from in the extraction Pod, under the mount path. Defaults to the mount path.",
+			},
+			&cli.BoolFlag{
+				Name:    "decompress",
+				Sources: cli.EnvVars("DECOMPRESS"),
+				Usage:   "Decompress the extracted .gz and .zst files, with their suffix stripped. Corrupted files are kept as-is.",
+			},
+			&cli.IntFlag{
+				Name:    "decompress-limit",
+				Sources: cli.EnvVars("DECOMPRESS_LIMIT"),
+				Usage:   "The number of bytes the decompressed files total at most, the ones beyond it being kept compressed with a warning. Defaults to 17179869184 (16Gi).",
+			},
+			&cli.BoolFlag{
+				Name:    "fsync",
+				Sources: cli.EnvVars("FSYNC"),
+				Usage:   "Sync the directories of the extracted files, for them to survive a crash or the unmount of a network filesystem (e.g. NFS). Slower on many small files.",
+			},
+			&cli.BoolFlag{
+				Name:    "strict",
+				Sources: cli.EnvVars("STRICT"),
+				Usage:   "Fail the extraction when files vanish from the PVC while archived (e.g. rotated), rather than extracting the others and warning about them.",
+			},
+			&cli.BoolFlag{
+				Name:    "keep-snapshot",
+				Sources: cli.EnvVars("KEEP_SNAPSHOT"),
+				Usage:   "Keep the Prometheus TSDB snapshot in its pod once extracted, rather than removing it.",
+			},
+			&cli.BoolFlag{
+				Name:    "platform-uid",
+				Sources: cli.EnvVars("PLATFORM_UID"),
+				Usage:   "Let the platform assign the extraction Pod UID (e.g. OpenShift), rather than pinning it.",
+			},
+			&cli.DurationFlag{
+				Name:    "gc-after",
+				Sources: cli.EnvVars("GC_AFTER"),
+				Usage:   "Let the cluster delete the extraction Pod after this duration, even if the extractor dies, by running it as a Job. Disabled by default.",
+			},
+			&cli.DurationFlag{
+				Name:    "delete-timeout",
+				Sources: cli.EnvVars("DELETE_TIMEOUT"),
+				Value:   2 * time.Minute,
+				Usage:   "How long to wait for the extraction Pod to disappear once deleted (e.g. held by a stuck CSI detach), before reporting it lingering or force deleting it.",
+			},
+			&cli.BoolFlag{
+				Name:    "force-delete",
+				Sources: cli.EnvVars("FORCE_DELETE"),
+				Usage:   "Force delete the extraction Pod, with a grace period of zero, when still there after the delete timeout.",
+			},
+			&cli.IntFlag{
+				Name:    "diagnostics-limit",
+				Sources: cli.EnvVars("DIAGNOSTICS_LIMIT"),
+				Usage:   "The number of bytes of the extraction Pod logs kept, the last ones, in the report and the summary when the extraction fails. Defaults to 65536.",
+			},
+			&cli.StringFlag{
+				Name:    "age-recipient",
+				Sources: cli.EnvVars("AGE_RECIPIENT"),
+				Usage:   "Encrypt the archive to this age X25519 public key (age1...) in the extraction Pod, so that only the ciphertext leaves it, landing as extraction.tar.age in the directory. Requires the exec transport and an age image.",
+			},
+			&cli.StringFlag{
+				Name:    "age-image",
+				Sources: cli.EnvVars("AGE_IMAGE"),
+				Usage:   "The image providing a statically-linked age binary and cp, copied into the extraction Pod by an init container. Pulled as is, whatever the registry.",
+			},
+			&cli.StringFlag{
+				Name:    "age-binary",
+				Sources: cli.EnvVars("AGE_BINARY"),
+				Value:   extract.DefaultAgeBinary,
+				Usage:   "The path of the age binary in the age image.",
+			},
+			&cli.StringFlag{
+				Name:    "transport",
+				Sources: cli.EnvVars("TRANSPORT"),
+				Value:   extract.TransportAuto,
+				Usage:   "How to reach the extraction Pod: exec, port-forward to a file server in the Pod for clusters where exec is disabled, or auto to fall back to port-forward once exec is forbidden. The prometheus target always execs.",
+				Validator: func(t string) error {
+					if t != extract.TransportAuto && t != extract.TransportExec && t != extract.TransportPortForward {
+						return fmt.Errorf("invalid transport %s", t)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:    "record",
+				Sources: cli.EnvVars("RECORD"),
+				Usage:   "Record the raw tar stream and the pod outputs, along the pod and PVC metadata, into this fixture directory for development. Only with the otel target.",
+			},
+			&cli.StringFlag{
+				Name:    "replay",
+				Sources: cli.EnvVars("REPLAY"),
+				Usage:   "Replay the recorded fixture of this directory through the extraction (untar, verification, decompression and report) without touching any cluster. Only with the otel target.",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Sources: cli.EnvVars("OUTPUT"),
+				Value:   outputText,
+				Usage:   "The output format: text, or json to emit a final JSON document on stdout (logs remain on stderr).",
+				Validator: func(o string) error {
+					if o != outputText && o != outputJSON {
+						return fmt.Errorf("invalid output format %s", o)
+					}
+					return nil
+				},
+			},
+			&cli.StringFlag{
+				Name:    "progress-events",
+				Sources: cli.EnvVars("PROGRESS_EVENTS"),
+				Usage:   "Emit the progress events (phases, counters, warnings and completion) as newline-delimited JSON into this file, or - for stdout, e.g. for a UI to follow the extraction. The text summary then goes to stderr.",
+			},
+			&cli.DurationFlag{
+				Name:    "progress-interval",
+				Sources: cli.EnvVars("PROGRESS_INTERVAL"),
+				Value:   5 * time.Second,
+				Usage:   "How often to emit the files and bytes counters while copying, along the progress events.",
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Don't colorize the text summary, as when NO_COLOR is set. Only terminals get colors anyway.",
+			},
 		},
 		Action: run,
+		Commands: []*cli.Command{
+			verifyCommand(),
+			reportCommand(),
+			statCommand(),
+		},
 		Authors: []any{
 			"CTFer.io Authors & Contributors - ctfer-io@protonmail.com",
 		},
@@ -83,15 +404,251 @@ func main() {
 }
 
 func run(ctx context.Context, cmd *cli.Command) error {
+	start := time.Now()
+	if err := checkSinkOptions(cmd); err != nil {
+		return err
+	}
+	if cmd.String("manifest") != "" {
+		return runManifest(ctx, cmd, start, os.Stdout)
+	}
+	if cmd.Bool("dry-run") {
+		return dryRun(os.Stdout, cmd, start)
+	}
+
+	progress, closeProgress, err := openProgress(cmd.String("progress-events"), cmd.Duration("progress-interval"), cmd.String("output"))
+	if err != nil {
+		return err
+	}
+	defer closeProgress()
+	out := os.Stdout
+	if cmd.String("progress-events") == progressStdout || cmd.String("sink") == sinkStdout {
+		out = os.Stderr
+	}
+
+	res, err := extractRun(ctx, cmd, start, progress)
+	if perr := progress.Done(res, err); perr != nil {
+		log().Error("writing progress events",
+			zap.Error(perr),
+		)
+	}
+	switch cmd.String("output") {
+	case outputJSON:
+		if werr := writeOutput(out, res, err, time.Since(start)); werr != nil {
+			return werr
+		}
+	default:
+		if werr := writeSummary(out, res, err, useColor(out, cmd.Bool("no-color"))); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func extractRun(ctx context.Context, cmd *cli.Command, start time.Time, progress *extract.Progress) (*extract.Result, error) {
+	sink, err := newSink(sinkConfigOf(cmd), os.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	directory := ""
+	if sink == nil {
+		directory, err = prepareDirectory(cmd, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.String("record") != "" && cmd.String("replay") != "" {
+		return nil, errors.New("record and replay are mutually exclusive")
+	}
+	if cmd.String("target") == extract.SourcePrometheus {
+		if cmd.String("record") != "" || cmd.String("replay") != "" || cmd.Bool("all") {
+			return nil, errors.New("record, replay and all are only supported with the otel target")
+		}
+		if cmd.String("age-recipient") != "" {
+			return nil, errors.New("age-recipient is not supported with the prometheus target")
+		}
+		return extractPrometheus(ctx, cmd, directory, sink, progress)
+	}
+	if fixture := cmd.String("replay"); fixture != "" {
+		if cmd.String("age-recipient") != "" {
+			return nil, errors.New("age-recipient is not supported when replaying, the fixture being in plaintext")
+		}
+		return replayFixture(ctx, cmd, fixture, directory, sink, progress)
+	}
+	if cmd.Bool("all") {
+		return extractAll(ctx, cmd, directory, progress)
+	}
+
+	namespace, pvcName := cmd.String("namespace"), cmd.String("pvc-name")
+	if cmd.Bool("discover") {
+		candidates, err := extract.Discover(ctx, namespace, extract.WithKubeContext(cmd.String("kube-context")))
+		if err != nil {
+			return nil, err
+		}
+		tgt, err := selectTarget(candidates, os.Stdin, os.Stderr, cmd.Bool("yes"))
+		if err != nil {
+			return nil, err
+		}
+		namespace, pvcName = tgt.Namespace, tgt.PVCName
+		log().Info("discovered PVC",
+			zap.String("namespace", namespace),
+			zap.String("pvc", pvcName),
+		)
+	}
+	if namespace == "" || pvcName == "" {
+		return nil, errors.New("namespace and pvc-name are required when not discovering")
+	}
+
+	bandwidthLimit, err := parseBandwidthLimit(cmd.String("bandwidth-limit"))
+	if err != nil {
+		return nil, err
+	}
+
 	return extract.DumpOTelCollector(ctx,
-		cmd.String("namespace"),
-		cmd.String("pvc-name"),
-		cmd.String("directory"),
+		namespace,
+		pvcName,
+		directory,
+		append(pvcOptions(cmd, bandwidthLimit, progress),
+			extract.WithRecord(cmd.String("record")),
+			extract.WithSink(sink),
+		)...,
+	)
+}
+
+// extractAll extracts every PVC of the Monitoring in the namespace.
+func extractAll(ctx context.Context, cmd *cli.Command, directory string, progress *extract.Progress) (*extract.Result, error) {
+	namespace := cmd.String("namespace")
+	switch {
+	case namespace == "":
+		return nil, errors.New("namespace is required to extract all PVCs")
+	case cmd.String("pvc-name") != "" || cmd.Bool("discover"):
+		return nil, errors.New("all is mutually exclusive with pvc-name and discover")
+	case cmd.String("record") != "":
+		return nil, errors.New("record is not supported when extracting all PVCs")
+	}
+
+	bandwidthLimit, err := parseBandwidthLimit(cmd.String("bandwidth-limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	return extract.DumpAll(ctx,
+		namespace,
+		directory,
+		pvcOptions(cmd, bandwidthLimit, progress)...,
+	)
+}
+
+// pvcOptions returns the options of the extraction of a PVC through a pod.
+func pvcOptions(cmd *cli.Command, bandwidthLimit int, progress *extract.Progress) []extract.Option {
+	opts := []extract.Option{
 		extract.WithLogger(log()),
+		extract.WithKubeContext(cmd.String("kube-context")),
 		extract.WithRegistry(cmd.String("registry")), // deal with empty string, don't worry ;)
+		extract.WithVerifyRemote(cmd.Bool("verify-remote")),
+		extract.WithBandwidthLimit(bandwidthLimit),
+		extract.WithWorkers(cmd.Int("workers")),
+		extract.WithMaxDuration(cmd.Duration("max-duration")),
+		extract.WithAutoDeadline(cmd.Bool("auto-deadline")),
+		extract.WithRuntimeClass(cmd.String("runtime-class")),
+		extract.WithSeccompProfile(cmd.String("seccomp-profile"), cmd.String("seccomp-localhost-profile")),
+		extract.WithMountPath(cmd.String("mount-path")),
+		extract.WithSourcePath(cmd.String("source-path")),
+		extract.WithPlatformUID(cmd.Bool("platform-uid")),
+		extract.WithDecompress(cmd.Bool("decompress")),
+		extract.WithDecompressLimit(int64(cmd.Int("decompress-limit"))),
+		extract.WithFsync(cmd.Bool("fsync")),
+		extract.WithStrict(cmd.Bool("strict")),
+		extract.WithGCAfter(cmd.Duration("gc-after")),
+		extract.WithDeleteTimeout(cmd.Duration("delete-timeout")),
+		extract.WithForceDelete(cmd.Bool("force-delete")),
+		extract.WithDiagnosticsLimit(int64(cmd.Int("diagnostics-limit"))),
+		extract.WithTransport(cmd.String("transport")),
+		extract.WithPodImage(cmd.String("pod-image")),
+		extract.WithPodCommand(cmd.StringSlice("pod-command"), cmd.StringSlice("pod-args")),
+		extract.WithProgress(progress),
+	}
+	if recipient := cmd.String("age-recipient"); recipient != "" {
+		opts = append(opts, extract.WithAgeEncryption(extract.AgeEncryption{
+			Recipient: recipient,
+			Image:     cmd.String("age-image"),
+			Binary:    cmd.String("age-binary"),
+		}))
+	}
+	return opts
+}
+
+func extractPrometheus(ctx context.Context, cmd *cli.Command, directory string, sink extract.Sink, progress *extract.Progress) (*extract.Result, error) {
+	namespace := cmd.String("namespace")
+	if namespace == "" {
+		return nil, errors.New("namespace is required with the prometheus target")
+	}
+
+	bandwidthLimit, err := parseBandwidthLimit(cmd.String("bandwidth-limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	return extract.DumpPrometheus(ctx,
+		namespace,
+		directory,
+		extract.WithLogger(log()),
+		extract.WithKubeContext(cmd.String("kube-context")),
+		extract.WithBandwidthLimit(bandwidthLimit),
+		extract.WithWorkers(cmd.Int("workers")),
+		extract.WithMaxDuration(cmd.Duration("max-duration")),
+		extract.WithAutoDeadline(cmd.Bool("auto-deadline")),
+		extract.WithKeepSnapshot(cmd.Bool("keep-snapshot")),
+		extract.WithFsync(cmd.Bool("fsync")),
+		extract.WithStrict(cmd.Bool("strict")),
+		extract.WithProgress(progress),
+		extract.WithSink(sink),
+	)
+}
+
+// replayFixture replays the recorded fixture, with the options of the local
+// pipeline only.
+func replayFixture(ctx context.Context, cmd *cli.Command, fixture, directory string, sink extract.Sink, progress *extract.Progress) (*extract.Result, error) {
+	bandwidthLimit, err := parseBandwidthLimit(cmd.String("bandwidth-limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	return extract.ReplayOTelCollector(ctx,
+		fixture,
+		directory,
+		extract.WithLogger(log()),
+		extract.WithVerifyRemote(cmd.Bool("verify-remote")),
+		extract.WithBandwidthLimit(bandwidthLimit),
+		extract.WithWorkers(cmd.Int("workers")),
+		extract.WithMaxDuration(cmd.Duration("max-duration")),
+		extract.WithAutoDeadline(cmd.Bool("auto-deadline")),
+		extract.WithDecompress(cmd.Bool("decompress")),
+		extract.WithDecompressLimit(int64(cmd.Int("decompress-limit"))),
+		extract.WithFsync(cmd.Bool("fsync")),
+		extract.WithStrict(cmd.Bool("strict")),
+		extract.WithProgress(progress),
+		extract.WithSink(sink),
 	)
 }
 
+// parseBandwidthLimit parses a Kubernetes quantity (e.g. 50Mi) as a number
+// of bytes per second. An empty string means no limit.
+func parseBandwidthLimit(str string) (int, error) {
+	if str == "" {
+		return 0, nil
+	}
+	q, err := resource.ParseQuantity(str)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid bandwidth limit")
+	}
+	bps, ok := q.AsInt64()
+	if !ok || bps <= 0 || bps > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid bandwidth limit %s", str)
+	}
+	return int(bps), nil
+}
+
 func log() *zap.Logger {
 	loggerOnce.Do(func() {
 		logger, _ = zap.NewProduction()
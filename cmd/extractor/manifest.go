@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// defaultManifestConcurrency is the number of targets extracted at once,
+// unless the manifest says otherwise.
+const defaultManifestConcurrency = 4
+
+type (
+	// manifest lists the targets to extract in a single run, e.g. across the
+	// clusters of an event.
+	manifest struct {
+		// Concurrency is the number of targets extracted at once.
+		// Defaults to defaultManifestConcurrency.
+		Concurrency int `yaml:"concurrency"`
+
+		Targets []manifestTarget `yaml:"targets"`
+	}
+
+	// manifestTarget is either a PVC, or the PVCs of the Monitoring matching
+	// a label selector, of a namespace in a kubeconfig context.
+	manifestTarget struct {
+		// Name identifies the target in the report, and is the default
+		// directory of its files.
+		Name string `yaml:"name"`
+
+		// Context is the kubeconfig context of the cluster. Defaults to the
+		// kube-context flag.
+		Context string `yaml:"context"`
+
+		Namespace string `yaml:"namespace"`
+		PVC       string `yaml:"pvc"`
+		Selector  string `yaml:"selector"`
+
+		// Directory is where the files land, under the directory flag when
+		// relative. Defaults to the name.
+		Directory string `yaml:"directory"`
+
+		// Options override the flags of the same name for this target.
+		Options targetOptions `yaml:"options"`
+	}
+
+	// targetOptions are the per-target overrides of the flags, the zero
+	// values keeping the flags ones.
+	targetOptions struct {
+		Registry       string        `yaml:"registry"`
+		Transport      string        `yaml:"transport"`
+		BandwidthLimit string        `yaml:"bandwidth-limit"`
+		Workers        int           `yaml:"workers"`
+		MaxDuration    time.Duration `yaml:"max-duration"`
+		PodImage       string        `yaml:"pod-image"`
+		VerifyRemote   *bool         `yaml:"verify-remote"`
+		Decompress     *bool         `yaml:"decompress"`
+		Fsync          *bool         `yaml:"fsync"`
+		Strict         *bool         `yaml:"strict"`
+	}
+)
+
+// readManifest reads and validates the manifest file, the target
+// directories resolved under root.
+func readManifest(path, root string) (*manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening manifest")
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return parseManifest(f, root)
+}
+
+// parseManifest decodes the manifest, rejecting unknown keys as they are
+// most likely typos, and validates it.
+func parseManifest(r io.Reader, root string) (*manifest, error) {
+	mf := &manifest{}
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	if err := dec.Decode(mf); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty manifest")
+		}
+		return nil, errors.Wrap(err, "decoding manifest")
+	}
+	if err := mf.validate(root); err != nil {
+		return nil, err
+	}
+	return mf, nil
+}
+
+// validate checks the targets are complete and apart from each other,
+// defaulting the concurrency and resolving their directories under root.
+func (mf *manifest) validate(root string) error {
+	if len(mf.Targets) == 0 {
+		return errors.New("manifest lists no target")
+	}
+	if mf.Concurrency < 0 {
+		return fmt.Errorf("manifest concurrency %d is negative", mf.Concurrency)
+	}
+	if mf.Concurrency == 0 {
+		mf.Concurrency = defaultManifestConcurrency
+	}
+
+	names := map[string]struct{}{}
+	directories := map[string]string{}
+	for i := range mf.Targets {
+		tgt := &mf.Targets[i]
+		if err := tgt.validate(); err != nil {
+			if tgt.Name == "" {
+				return fmt.Errorf("manifest target %d: %w", i, err)
+			}
+			return fmt.Errorf("manifest target %s: %w", tgt.Name, err)
+		}
+		if _, ok := names[tgt.Name]; ok {
+			return fmt.Errorf("manifest target %s is listed twice", tgt.Name)
+		}
+		names[tgt.Name] = struct{}{}
+
+		if tgt.Directory == "" {
+			tgt.Directory = tgt.Name
+		}
+		if !filepath.IsAbs(tgt.Directory) {
+			tgt.Directory = filepath.Join(root, tgt.Directory)
+		}
+		tgt.Directory = filepath.Clean(tgt.Directory)
+		if other, ok := directories[tgt.Directory]; ok {
+			return fmt.Errorf("manifest targets %s and %s extract into the same directory %s", other, tgt.Name, tgt.Directory)
+		}
+		directories[tgt.Directory] = tgt.Name
+	}
+	return nil
+}
+
+// validate checks the target designates PVCs, and its options.
+func (tgt *manifestTarget) validate() error {
+	if tgt.Name == "" {
+		return errors.New("name is required")
+	}
+	if errs := validation.IsDNS1123Label(tgt.Name); len(errs) != 0 {
+		return fmt.Errorf("name %s: %s", tgt.Name, strings.Join(errs, ", "))
+	}
+	if tgt.Namespace == "" {
+		return errors.New("namespace is required")
+	}
+	switch {
+	case tgt.PVC == "" && tgt.Selector == "":
+		return errors.New("either pvc or selector is required")
+	case tgt.PVC != "" && tgt.Selector != "":
+		return errors.New("pvc and selector are mutually exclusive")
+	case tgt.Selector != "":
+		if _, err := labels.Parse(tgt.Selector); err != nil {
+			return fmt.Errorf("invalid selector %s: %w", tgt.Selector, err)
+		}
+	}
+
+	opts := tgt.Options
+	switch opts.Transport {
+	case "", extract.TransportAuto, extract.TransportExec, extract.TransportPortForward:
+	default:
+		return fmt.Errorf("invalid transport %s", opts.Transport)
+	}
+	if _, err := parseBandwidthLimit(opts.BandwidthLimit); err != nil {
+		return err
+	}
+	if opts.Workers < 0 {
+		return fmt.Errorf("workers %d is negative", opts.Workers)
+	}
+	if opts.MaxDuration < 0 {
+		return fmt.Errorf("max duration %s is negative", opts.MaxDuration)
+	}
+	return nil
+}
+
+// key returns what the targets extracted one after the other share, as
+// the extraction Pod is unique in the namespace.
+func (tgt manifestTarget) key() string {
+	return tgt.Context + "/" + tgt.Namespace
+}
+
+// options returns the overrides of the flags options for the target.
+func (tgt manifestTarget) options() []extract.Option {
+	opts := []extract.Option{}
+	if tgt.Context != "" {
+		opts = append(opts, extract.WithKubeContext(tgt.Context))
+	}
+	if tgt.Selector != "" {
+		opts = append(opts, extract.WithPVCSelector(tgt.Selector))
+	}
+	o := tgt.Options
+	if o.Registry != "" {
+		opts = append(opts, extract.WithRegistry(o.Registry))
+	}
+	if o.Transport != "" {
+		opts = append(opts, extract.WithTransport(o.Transport))
+	}
+	if o.Workers != 0 {
+		opts = append(opts, extract.WithWorkers(o.Workers))
+	}
+	if o.MaxDuration != 0 {
+		opts = append(opts, extract.WithMaxDuration(o.MaxDuration))
+	}
+	if o.PodImage != "" {
+		opts = append(opts, extract.WithPodImage(o.PodImage))
+	}
+	if o.VerifyRemote != nil {
+		opts = append(opts, extract.WithVerifyRemote(*o.VerifyRemote))
+	}
+	if o.Decompress != nil {
+		opts = append(opts, extract.WithDecompress(*o.Decompress))
+	}
+	if o.Fsync != nil {
+		opts = append(opts, extract.WithFsync(*o.Fsync))
+	}
+	if o.Strict != nil {
+		opts = append(opts, extract.WithStrict(*o.Strict))
+	}
+	return opts
+}
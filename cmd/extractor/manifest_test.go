@@ -0,0 +1,247 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_U_ParseManifest(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Manifest            string
+		ExpectErr           bool
+		ExpectedConcurrency int
+		ExpectedDirectories []string
+	}{
+		"complete": {
+			Manifest: `
+concurrency: 2
+targets:
+  - name: finals-eu
+    context: finals-eu
+    namespace: monitoring
+    pvc: signals
+    options:
+      transport: exec
+      bandwidth-limit: 50Mi
+      max-duration: 30m
+      verify-remote: true
+  - name: finals-us
+    context: finals-us
+    namespace: monitoring
+    selector: app.kubernetes.io/component in (otel-collector,jaeger)
+    directory: /mnt/backup/finals-us
+`,
+			ExpectedConcurrency: 2,
+			ExpectedDirectories: []string{"extract/finals-eu", "/mnt/backup/finals-us"},
+		},
+		"default-concurrency": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+    directory: quals/2026
+`,
+			ExpectedConcurrency: defaultManifestConcurrency,
+			ExpectedDirectories: []string{"extract/quals/2026"},
+		},
+		"empty": {
+			Manifest:  "",
+			ExpectErr: true,
+		},
+		"no-target": {
+			Manifest:  "concurrency: 2\n",
+			ExpectErr: true,
+		},
+		"unknown-key": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc_name: signals
+`,
+			ExpectErr: true,
+		},
+		"negative-concurrency": {
+			Manifest: `
+concurrency: -1
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+`,
+			ExpectErr: true,
+		},
+		"missing-name": {
+			Manifest: `
+targets:
+  - namespace: monitoring
+    pvc: signals
+`,
+			ExpectErr: true,
+		},
+		"invalid-name": {
+			Manifest: `
+targets:
+  - name: ../quals
+    namespace: monitoring
+    pvc: signals
+`,
+			ExpectErr: true,
+		},
+		"duplicated-name": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+  - name: quals
+    namespace: monitoring
+    pvc: jaeger-archive
+`,
+			ExpectErr: true,
+		},
+		"same-directory": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+  - name: finals
+    namespace: monitoring
+    pvc: signals
+    directory: quals/
+`,
+			ExpectErr: true,
+		},
+		"missing-namespace": {
+			Manifest: `
+targets:
+  - name: quals
+    pvc: signals
+`,
+			ExpectErr: true,
+		},
+		"missing-pvc": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+`,
+			ExpectErr: true,
+		},
+		"pvc-and-selector": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+    selector: app.kubernetes.io/component=jaeger
+`,
+			ExpectErr: true,
+		},
+		"invalid-selector": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    selector: "app.kubernetes.io/component in ("
+`,
+			ExpectErr: true,
+		},
+		"invalid-transport": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+    options:
+      transport: rsync
+`,
+			ExpectErr: true,
+		},
+		"invalid-bandwidth-limit": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+    options:
+      bandwidth-limit: fast
+`,
+			ExpectErr: true,
+		},
+		"invalid-max-duration": {
+			Manifest: `
+targets:
+  - name: quals
+    namespace: monitoring
+    pvc: signals
+    options:
+      max-duration: soon
+`,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			mf, err := parseManifest(strings.NewReader(tt.Manifest), "extract")
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+			if mf.Concurrency != tt.ExpectedConcurrency {
+				t.Errorf("expected concurrency %d, got %d", tt.ExpectedConcurrency, mf.Concurrency)
+			}
+			if len(mf.Targets) != len(tt.ExpectedDirectories) {
+				t.Fatalf("expected %d targets, got %d", len(tt.ExpectedDirectories), len(mf.Targets))
+			}
+			for i, tgt := range mf.Targets {
+				if tgt.Directory != tt.ExpectedDirectories[i] {
+					t.Errorf("expected target %s directory %s, got %s", tgt.Name, tt.ExpectedDirectories[i], tgt.Directory)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_ManifestTargetOptions(t *testing.T) {
+	t.Parallel()
+
+	mf, err := parseManifest(strings.NewReader(`
+targets:
+  - name: finals-eu
+    context: finals-eu
+    namespace: monitoring
+    selector: app.kubernetes.io/component=jaeger
+    options:
+      transport: exec
+      max-duration: 30m
+      strict: false
+`), "extract")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tgt := mf.Targets[0]
+	if tgt.Options.MaxDuration != 30*time.Minute {
+		t.Errorf("expected max duration 30m, got %s", tgt.Options.MaxDuration)
+	}
+	if tgt.Options.Strict == nil || *tgt.Options.Strict {
+		t.Errorf("expected strict to be explicitly disabled, got %v", tgt.Options.Strict)
+	}
+	if tgt.Options.VerifyRemote != nil {
+		t.Errorf("expected verify remote to keep the flag, got %v", *tgt.Options.VerifyRemote)
+	}
+	// Context, selector, transport, max duration and strict
+	if opts := tgt.options(); len(opts) != 5 {
+		t.Errorf("expected 5 overriding options, got %d", len(opts))
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	// outputVersion is the version of the JSON output schema.
+	// Bump it on any breaking change, as orchestrators parse it.
+	outputVersion = 1
+)
+
+// output is the JSON document emitted at the end of a run.
+type output struct {
+	Version  int      `json:"version"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+	Files    int      `json:"files"`
+	Bytes    int64    `json:"bytes"`
+	Duration float64  `json:"duration_seconds"`
+	Warnings []string `json:"warnings"`
+	Report   string   `json:"report,omitempty"`
+
+	// Sink is where the files landed, when not into the directory.
+	Sink *extract.SinkReport `json:"sink,omitempty"`
+
+	// Encrypted is the age-encrypted archive file of the directory, when
+	// encrypted in the extraction Pod.
+	Encrypted string `json:"encrypted,omitempty"`
+
+	// Components are the PVCs extracted along the namespace, keyed by their
+	// component label, when extracting them all.
+	Components map[string][]extract.PVCResult `json:"components,omitempty"`
+
+	// Diagnostics are the logs and events of the extraction Pod, when it
+	// failed.
+	Diagnostics *extract.PodDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// writeOutput writes the JSON document of the run result and error.
+// The result could be nil if the run failed early.
+func writeOutput(w io.Writer, res *extract.Result, err error, elapsed time.Duration) error {
+	out := output{
+		Version:  outputVersion,
+		Status:   "success",
+		Duration: elapsed.Seconds(),
+		Warnings: []string{},
+	}
+	if err != nil {
+		out.Status = "failure"
+		out.Error = err.Error()
+	}
+	if res != nil {
+		out.Files = res.Files
+		out.Bytes = res.Bytes
+		out.Report = res.Report
+		out.Sink = res.Sink
+		out.Encrypted = res.Encrypted
+		out.Components = res.Components
+		out.Diagnostics = res.Diagnostics
+		if res.Warnings != nil {
+			out.Warnings = res.Warnings
+		}
+	}
+	return json.NewEncoder(w).Encode(out)
+}
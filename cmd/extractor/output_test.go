@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_WriteOutput(t *testing.T) {
+	t.Parallel()
+
+	// Orchestrators parse this output: if this test breaks, bump outputVersion.
+	var tests = map[string]struct {
+		Result   *extract.Result
+		Err      error
+		Expected string
+	}{
+		"success": {
+			Result: &extract.Result{
+				Files:    3,
+				Bytes:    2048,
+				Warnings: []string{"file no longer on the PVC: otel_logs"},
+				Report:   "extract/report.json",
+			},
+			Expected: `{"version":1,"status":"success","files":3,"bytes":2048,"duration_seconds":1.5,"warnings":["file no longer on the PVC: otel_logs"],"report":"extract/report.json"}` + "\n",
+		},
+		"encrypted": {
+			Result: &extract.Result{
+				Files:     1,
+				Bytes:     4096,
+				Encrypted: extract.EncryptedArchiveFile,
+				Report:    "extract/report.json",
+			},
+			Expected: `{"version":1,"status":"success","files":1,"bytes":4096,"duration_seconds":1.5,"warnings":[],"report":"extract/report.json","encrypted":"extraction.tar.age"}` + "\n",
+		},
+		"sink": {
+			Result: &extract.Result{
+				Files: 3,
+				Bytes: 2048,
+				Sink: &extract.SinkReport{
+					Kind:     extract.SinkS3,
+					Location: "s3://extractions/run-1/extraction.tar.gz",
+					Entries:  4,
+					Bytes:    1024,
+				},
+			},
+			Expected: `{"version":1,"status":"success","files":3,"bytes":2048,"duration_seconds":1.5,"warnings":[],"sink":{"kind":"s3","location":"s3://extractions/run-1/extraction.tar.gz","entries":4,"bytes":1024}}` + "\n",
+		},
+		"all-partial-failure": {
+			Result: &extract.Result{
+				Files: 3,
+				Bytes: 2048,
+				Components: map[string][]extract.PVCResult{
+					"jaeger":         {{PVCName: "jaeger-archive", Directory: "extract/jaeger-archive", Error: "pod not ready"}},
+					"otel-collector": {{PVCName: "signals", Directory: "extract/signals", Files: 3, Bytes: 2048}},
+				},
+				Report: "extract/report.json",
+			},
+			Err:      errors.New("1 of 2 PVCs failed to extract"),
+			Expected: `{"version":1,"status":"failure","error":"1 of 2 PVCs failed to extract","files":3,"bytes":2048,"duration_seconds":1.5,"warnings":[],"report":"extract/report.json","components":{"jaeger":[{"pvc_name":"jaeger-archive","directory":"extract/jaeger-archive","files":0,"bytes":0,"error":"pod not ready"}],"otel-collector":[{"pvc_name":"signals","directory":"extract/signals","files":3,"bytes":2048}]}}` + "\n",
+		},
+		"diagnostics": {
+			Result: &extract.Result{
+				Diagnostics: &extract.PodDiagnostics{
+					Pod:  "extractor",
+					Logs: "mount failed\n",
+				},
+				Report: "extract/report.json",
+			},
+			Err:      errors.New("pod extractor never got ready"),
+			Expected: `{"version":1,"status":"failure","error":"pod extractor never got ready","files":0,"bytes":0,"duration_seconds":1.5,"warnings":[],"report":"extract/report.json","diagnostics":{"pod":"extractor","logs":"mount failed\n"}}` + "\n",
+		},
+		"early-failure": {
+			Result:   nil,
+			Err:      errors.New("namespace and pvc-name are required when not discovering"),
+			Expected: `{"version":1,"status":"failure","error":"namespace and pvc-name are required when not discovering","files":0,"bytes":0,"duration_seconds":1.5,"warnings":[]}` + "\n",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := writeOutput(buf, tt.Result, tt.Err, 1500*time.Millisecond); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if buf.String() != tt.Expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.Expected, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+)
+
+// progressStdout is the progress events destination of stdout.
+const progressStdout = "-"
+
+// openProgress returns the progress emitting the events into the file, or
+// stdout for progressStdout, and the function closing it. No destination
+// means no progress, which emits nothing.
+func openProgress(dest string, interval time.Duration, output string) (*extract.Progress, func(), error) {
+	if interval < 0 {
+		return nil, nil, errors.Errorf("invalid progress interval %s", interval)
+	}
+	switch dest {
+	case "":
+		return nil, func() {}, nil
+	case progressStdout:
+		if output == outputJSON {
+			return nil, nil, errors.New("progress events and the json output could not both be written to stdout")
+		}
+		return extract.NewProgress(os.Stdout, interval), func() {}, nil
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "opening progress events")
+	}
+	return extract.NewProgress(f, interval), func() { _ = f.Close() }, nil
+}
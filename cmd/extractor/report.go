@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// reportCommand groups the commands on the extraction reports, which only
+// read them locally.
+func reportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "report",
+		Usage: "Inspect extraction reports, without any cluster access.",
+		Commands: []*cli.Command{
+			{
+				Name:      "diff",
+				Usage:     "Compare two extraction reports, e.g. of two nightly extractions: the files added, removed and changed, the bytes and time window of each signal directory.",
+				ArgsUsage: "<old report.json> <new report.json>",
+				Action:    runReportDiff,
+			},
+		},
+	}
+}
+
+func runReportDiff(_ context.Context, cmd *cli.Command) error {
+	diff, err := reportDiffRun(cmd.Args().Slice())
+	switch cmd.String("output") {
+	case outputJSON:
+		if werr := writeReportDiffOutput(os.Stdout, diff, err); werr != nil {
+			return werr
+		}
+	default:
+		if werr := writeReportDiff(os.Stdout, diff, err, useColor(os.Stdout, cmd.Bool("no-color"))); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func reportDiffRun(args []string) (*extract.ReportDiff, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected the old and new reports, got %d arguments", len(args))
+	}
+	older, err := extract.ReadReportFile(args[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the old report")
+	}
+	newer, err := extract.ReadReportFile(args[1])
+	if err != nil {
+		return nil, errors.Wrap(err, "reading the new report")
+	}
+	return extract.DiffReports(older, newer), nil
+}
+
+// writeReportDiff writes the human-readable comparison of the reports, then
+// the error if any. The comparison could be nil if a report was not read.
+func writeReportDiff(w io.Writer, diff *extract.ReportDiff, err error, color bool) error {
+	if _, werr := fmt.Fprintln(w, "Report diff:"); werr != nil {
+		return werr
+	}
+	if diff != nil {
+		if _, werr := fmt.Fprintf(w, "  %s -> %s\n", diff.Old, diff.New); werr != nil {
+			return werr
+		}
+		for _, line := range diff.Summary {
+			prefix := ""
+			if line.Level == extract.SummaryWarning {
+				prefix = colorize("warning: ", colorYellow, color)
+			}
+			if _, werr := fmt.Fprintf(w, "  %s%s\n", prefix, line.Text); werr != nil {
+				return werr
+			}
+		}
+		for _, cat := range []struct {
+			name  string
+			files []extract.InventoryFile
+		}{
+			{"added", diff.Added},
+			{"removed", diff.Removed},
+		} {
+			if len(cat.files) == 0 {
+				continue
+			}
+			if _, werr := fmt.Fprintf(w, "  %s:\n", cat.name); werr != nil {
+				return werr
+			}
+			for _, f := range cat.files {
+				if _, werr := fmt.Fprintf(w, "    %s (%d bytes)\n", f.Path, f.Bytes); werr != nil {
+					return werr
+				}
+			}
+		}
+		if len(diff.Changed) != 0 {
+			if _, werr := fmt.Fprintln(w, "  changed:"); werr != nil {
+				return werr
+			}
+			for _, f := range diff.Changed {
+				if _, werr := fmt.Fprintf(w, "    %s (%d -> %d bytes)\n", f.Path, f.OldBytes, f.NewBytes); werr != nil {
+					return werr
+				}
+			}
+		}
+		if len(diff.Signals) != 0 {
+			if _, werr := fmt.Fprintln(w, "  signals:"); werr != nil {
+				return werr
+			}
+			for _, sd := range diff.Signals {
+				if _, werr := fmt.Fprintf(w, "    %s: %d -> %d bytes (%+d), %s -> %s\n",
+					sd.Directory, sd.OldBytes, sd.NewBytes, sd.BytesDelta, formatWindow(sd.OldWindow), formatWindow(sd.NewWindow)); werr != nil {
+					return werr
+				}
+			}
+		}
+	}
+	if err != nil {
+		if _, werr := fmt.Fprintf(w, "  %s%s\n", colorize("error: ", colorRed, color), err); werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// formatWindow formats the time window, none if nil.
+func formatWindow(tw *extract.TimeWindow) string {
+	if tw == nil {
+		return "none"
+	}
+	return "[" + tw.From.Format(time.RFC3339) + ", " + tw.To.Format(time.RFC3339) + "]"
+}
+
+// reportDiffOutput is the JSON document emitted at the end of a report diff.
+type reportDiffOutput struct {
+	Version int                 `json:"version"`
+	Status  string              `json:"status"`
+	Error   string              `json:"error,omitempty"`
+	Diff    *extract.ReportDiff `json:"diff,omitempty"`
+}
+
+// writeReportDiffOutput writes the JSON document of the comparison and
+// error. The comparison could be nil if a report was not read.
+func writeReportDiffOutput(w io.Writer, diff *extract.ReportDiff, err error) error {
+	out := reportDiffOutput{
+		Version: outputVersion,
+		Status:  "success",
+		Diff:    diff,
+	}
+	if err != nil {
+		out.Status = "failure"
+		out.Error = err.Error()
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_U_WriteReportDiff(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      []string
+		Golden    string
+		ExpectErr bool
+	}{
+		"nightly": {
+			Args:   []string{filepath.Join("testdata", "report-old.json"), filepath.Join("testdata", "report-new.json")},
+			Golden: "report-diff.golden",
+		},
+		"missing-report": {
+			Args:      []string{filepath.Join("testdata", "report-old.json"), filepath.Join("testdata", "report-none.json")},
+			Golden:    "report-diff-missing.golden",
+			ExpectErr: true,
+		},
+		"one-report": {
+			Args:      []string{filepath.Join("testdata", "report-old.json")},
+			Golden:    "report-diff-args.golden",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			diff, err := reportDiffRun(tt.Args)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+
+			buf := &bytes.Buffer{}
+			if werr := writeReportDiff(buf, diff, err, false); werr != nil {
+				t.Fatalf("unexpected error: %s", werr)
+			}
+			expected, rerr := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if rerr != nil {
+				t.Fatalf("reading golden file: %s", rerr)
+			}
+			if buf.String() != string(expected) {
+				t.Errorf("expected:\n%q\ngot:\n%q", expected, buf.String())
+			}
+		})
+	}
+}
+
+func Test_U_WriteReportDiffOutput(t *testing.T) {
+	t.Parallel()
+
+	diff, err := reportDiffRun([]string{filepath.Join("testdata", "report-old.json"), filepath.Join("testdata", "report-new.json")})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeReportDiffOutput(buf, diff, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out := reportDiffOutput{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid output: %s", err)
+	}
+	if out.Version != outputVersion || out.Status != "success" || out.Diff == nil {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+	if out.Diff.BytesDelta != 2048 || len(out.Diff.Added) != 1 || len(out.Diff.Removed) != 1 || len(out.Diff.Changed) != 2 {
+		t.Errorf("unexpected diff %s", buf.String())
+	}
+
+	// A failure has no diff
+	buf.Reset()
+	if err := writeReportDiffOutput(buf, nil, errors.New("reading the new report")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := `{"version":1,"status":"failure","error":"reading the new report"}` + "\n"; buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// localRetention returns the retention of the local extraction directories.
+// It requires the runs to be timestamped, as they are the ones rotated.
+func localRetention(maxSize string, maxRuns int, timestamped bool) (extract.Retention, error) {
+	retention := extract.Retention{
+		MaxRuns: maxRuns,
+	}
+	if maxSize != "" {
+		q, err := resource.ParseQuantity(maxSize)
+		if err != nil {
+			return retention, errors.Wrap(err, "invalid max local size")
+		}
+		size, ok := q.AsInt64()
+		if !ok || size <= 0 {
+			return retention, fmt.Errorf("invalid max local size %s", maxSize)
+		}
+		retention.MaxSize = size
+	}
+	if err := retention.Validate(); err != nil {
+		return retention, err
+	}
+	if retention.Enabled() && !timestamped {
+		return retention, errors.New("max-local-size and max-local-runs require timestamped")
+	}
+	return retention, nil
+}
+
+// prepareDirectory purges the previous runs out of the retention, and
+// returns the directory of this one.
+func prepareDirectory(cmd *cli.Command, start time.Time) (string, error) {
+	retention, err := localRetention(cmd.String("max-local-size"), cmd.Int("max-local-runs"), cmd.Bool("timestamped"))
+	if err != nil {
+		return "", err
+	}
+
+	root := cmd.String("directory")
+	purged, err := extract.PurgeLocalRuns(root, retention, false)
+	for _, run := range purged {
+		log().Info("purged previous run",
+			zap.String("directory", run.Directory),
+			zap.Time("started_at", run.StartedAt),
+			zap.Int64("bytes", run.Bytes),
+		)
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "purging previous runs")
+	}
+
+	if cmd.Bool("timestamped") {
+		return extract.RunDirectory(root, start), nil
+	}
+	return root, nil
+}
+
+// dryRun writes the previous runs the retention would purge, and where the
+// run would extract, without purging nor extracting anything.
+func dryRun(w io.Writer, cmd *cli.Command, start time.Time) error {
+	retention, err := localRetention(cmd.String("max-local-size"), cmd.Int("max-local-runs"), cmd.Bool("timestamped"))
+	if err != nil {
+		return err
+	}
+
+	root := cmd.String("directory")
+	purged, err := extract.PurgeLocalRuns(root, retention, true)
+	if err != nil {
+		return err
+	}
+	directory := root
+	if cmd.Bool("timestamped") {
+		directory = extract.RunDirectory(root, start)
+	}
+	return writeDryRun(w, directory, purged)
+}
+
+// writeDryRun writes the directory the run would extract into, and the
+// previous runs it would purge first.
+func writeDryRun(w io.Writer, directory string, purged []extract.LocalRun) error {
+	if _, err := fmt.Fprintf(w, "Dry run, would extract into %s\n", directory); err != nil {
+		return err
+	}
+	if len(purged) == 0 {
+		_, err := fmt.Fprintln(w, "  nothing to purge")
+		return err
+	}
+	for _, run := range purged {
+		if _, err := fmt.Fprintf(w, "  would purge %s (started at %s, %s)\n",
+			run.Directory,
+			run.StartedAt.UTC().Format(time.RFC3339),
+			resource.NewQuantity(run.Bytes, resource.BinarySI),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_LocalRetention(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		MaxSize     string
+		MaxRuns     int
+		Timestamped bool
+		Expected    extract.Retention
+		ExpectErr   bool
+	}{
+		"disabled": {},
+		"timestamped": {
+			Timestamped: true,
+		},
+		"bounded": {
+			MaxSize:     "2Gi",
+			MaxRuns:     7,
+			Timestamped: true,
+			Expected:    extract.Retention{MaxSize: 2 << 30, MaxRuns: 7},
+		},
+		"not-timestamped": {
+			MaxRuns:   7,
+			ExpectErr: true,
+		},
+		"invalid-size": {
+			MaxSize:     "two gigs",
+			Timestamped: true,
+			ExpectErr:   true,
+		},
+		"negative-runs": {
+			MaxRuns:     -1,
+			Timestamped: true,
+			ExpectErr:   true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			retention, err := localRetention(tt.MaxSize, tt.MaxRuns, tt.Timestamped)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if !tt.ExpectErr && retention != tt.Expected {
+				t.Errorf("expected %+v, got %+v", tt.Expected, retention)
+			}
+		})
+	}
+}
+
+func Test_U_WriteDryRun(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	if err := writeDryRun(buf, "extract/20261016T020000Z", []extract.LocalRun{
+		{Directory: "extract/20261001T020000Z", StartedAt: time.Date(2026, time.October, 1, 2, 0, 0, 0, time.UTC), Bytes: 3 << 20},
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "Dry run, would extract into extract/20261016T020000Z\n" +
+		"  would purge extract/20261001T020000Z (started at 2026-10-01T02:00:00Z, 3Mi)\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// Sinks selectable by the sink option.
+const (
+	sinkDirectory = "directory"
+	sinkArchive   = "archive"
+	sinkS3        = "s3"
+	sinkOCI       = "oci"
+	sinkStdout    = "stdout"
+)
+
+// sinkConfig holds the options of the sinks.
+type sinkConfig struct {
+	Kind string
+
+	ArchivePath string
+
+	S3 extract.S3Config
+
+	OCI extract.OCIConfig
+}
+
+// sinkConfigOf reads the options of the sinks.
+func sinkConfigOf(cmd *cli.Command) sinkConfig {
+	return sinkConfig{
+		Kind:        cmd.String("sink"),
+		ArchivePath: cmd.String("archive-path"),
+		S3: extract.S3Config{
+			Endpoint:        cmd.String("s3-endpoint"),
+			Region:          cmd.String("s3-region"),
+			Bucket:          cmd.String("s3-bucket"),
+			Key:             cmd.String("s3-key"),
+			AccessKeyID:     cmd.String("s3-access-key-id"),
+			SecretAccessKey: cmd.String("s3-secret-access-key"),
+			SessionToken:    cmd.String("s3-session-token"),
+		},
+		OCI: extract.OCIConfig{
+			Reference: cmd.String("oci-reference"),
+			Username:  cmd.String("oci-username"),
+			Password:  cmd.String("oci-password"),
+			PlainHTTP: cmd.Bool("oci-plain-http"),
+		},
+	}
+}
+
+// newSink returns the sink the files land into, nil for the directory one
+// the extraction defaults to. The stdout sink writes into stdout.
+func newSink(cfg sinkConfig, stdout io.Writer) (extract.Sink, error) {
+	switch cfg.Kind {
+	case "", sinkDirectory:
+		return nil, nil
+	case sinkArchive:
+		if cfg.ArchivePath == "" {
+			return nil, errors.New("archive-path is required with the archive sink")
+		}
+		return extract.NewArchiveSink(cfg.ArchivePath), nil
+	case sinkS3:
+		if cfg.S3.Endpoint == "" || cfg.S3.Bucket == "" || cfg.S3.Key == "" {
+			return nil, errors.New("s3-endpoint, s3-bucket and s3-key are required with the s3 sink")
+		}
+		return extract.NewS3Sink(cfg.S3), nil
+	case sinkOCI:
+		if cfg.OCI.Reference == "" {
+			return nil, errors.New("oci-reference is required with the oci sink")
+		}
+		return extract.NewOCISink(cfg.OCI), nil
+	case sinkStdout:
+		return extract.NewStreamSink(sinkStdout, stdout), nil
+	}
+	return nil, errors.Errorf("invalid sink %s", cfg.Kind)
+}
+
+// checkSinkOptions makes sure the options working on the local directory
+// are not set along another sink.
+func checkSinkOptions(cmd *cli.Command) error {
+	if kind := cmd.String("sink"); kind != sinkDirectory {
+		for _, name := range []string{"directory", "timestamped", "max-local-size", "max-local-runs", "dry-run", "decompress", "fsync", "all"} {
+			if cmd.IsSet(name) {
+				return errors.Errorf("%s requires the directory sink, not %s", name, kind)
+			}
+		}
+		if kind == sinkStdout && cmd.String("progress-events") == progressStdout {
+			return errors.New("progress events and the stdout sink could not both be written to stdout")
+		}
+		return nil
+	}
+	if cmd.String("directory") == "" {
+		return errors.New("directory is required with the directory sink")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_NewSink(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Config       sinkConfig
+		ExpectedKind string
+		ExpectErr    bool
+	}{
+		"directory": {
+			Config: sinkConfig{Kind: sinkDirectory},
+		},
+		"archive": {
+			Config:       sinkConfig{Kind: sinkArchive, ArchivePath: "extract.tar.gz"},
+			ExpectedKind: extract.SinkArchive,
+		},
+		"archive-no-path": {
+			Config:    sinkConfig{Kind: sinkArchive},
+			ExpectErr: true,
+		},
+		"s3": {
+			Config: sinkConfig{Kind: sinkS3, S3: extract.S3Config{
+				Endpoint: "https://s3.eu-west-3.amazonaws.com",
+				Bucket:   "extractions",
+				Key:      "run-1/extraction.tar.gz",
+			}},
+			ExpectedKind: extract.SinkS3,
+		},
+		"s3-no-bucket": {
+			Config: sinkConfig{Kind: sinkS3, S3: extract.S3Config{
+				Endpoint: "https://s3.eu-west-3.amazonaws.com",
+				Key:      "run-1/extraction.tar.gz",
+			}},
+			ExpectErr: true,
+		},
+		"oci": {
+			Config:       sinkConfig{Kind: sinkOCI, OCI: extract.OCIConfig{Reference: "registry.example.com/ctf/extractions:run-1"}},
+			ExpectedKind: extract.SinkOCI,
+		},
+		"oci-no-reference": {
+			Config:    sinkConfig{Kind: sinkOCI},
+			ExpectErr: true,
+		},
+		"stdout": {
+			Config:       sinkConfig{Kind: sinkStdout},
+			ExpectedKind: extract.SinkStream,
+		},
+		"invalid": {
+			Config:    sinkConfig{Kind: "ftp"},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			sink, err := newSink(tt.Config, &bytes.Buffer{})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			switch {
+			case tt.ExpectedKind == "" && sink != nil:
+				t.Errorf("expected the directory default, got the %s sink", sink.Report().Kind)
+			case tt.ExpectedKind != "" && (sink == nil || sink.Report().Kind != tt.ExpectedKind):
+				t.Errorf("expected the %s sink, got %v", tt.ExpectedKind, sink)
+			}
+		})
+	}
+}
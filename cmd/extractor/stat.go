@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+)
+
+// statCommand measures the usage of the PVC of the namespace and pvc-name
+// options. It reads the global options of the Pod (e.g. registry,
+// mount-path, source-path), and the growth rate since the previous stat
+// of the snapshot option.
+func statCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stat",
+		Usage: "Report the usage of the PVC measured in a Pod (capacity, used and available bytes, source bytes and files), and its growth rate and time to full since the previous stat of the snapshot file.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "snapshot",
+				Usage:   "The file of the previous stat to compute the growth rate from, if any. The current stat is saved in it for the next run.",
+				Sources: cli.EnvVars("SNAPSHOT"),
+			},
+		},
+		Action: runStat,
+	}
+}
+
+func runStat(ctx context.Context, cmd *cli.Command) error {
+	st, growth, err := statRun(ctx, cmd)
+	switch cmd.String("output") {
+	case outputJSON:
+		if werr := writeStatOutput(os.Stdout, st, growth, err); werr != nil {
+			return werr
+		}
+	default:
+		if werr := writeStat(os.Stdout, st, growth, err); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func statRun(ctx context.Context, cmd *cli.Command) (*extract.PVCStat, *extract.PVCGrowth, error) {
+	namespace, pvcName := cmd.String("namespace"), cmd.String("pvc-name")
+	if namespace == "" || pvcName == "" {
+		return nil, nil, errors.New("namespace and pvc-name are required to stat a PVC")
+	}
+
+	// Read the previous stat first, not to measure for nothing
+	snapshot := cmd.String("snapshot")
+	var prev *extract.PVCStat
+	if snapshot != "" {
+		var err error
+		prev, err = extract.ReadStat(snapshot)
+		switch {
+		case os.IsNotExist(err):
+			log().Info("no previous stat, the growth rate is computed from the next run",
+				zap.String("snapshot", snapshot),
+			)
+		case err != nil:
+			return nil, nil, err
+		case prev.Namespace != namespace || prev.PVCName != pvcName:
+			return nil, nil, fmt.Errorf("snapshot %s is of PVC %s/%s, not %s/%s",
+				snapshot, prev.Namespace, prev.PVCName, namespace, pvcName)
+		}
+	}
+
+	log().Info("measuring PVC usage",
+		zap.String("namespace", namespace),
+		zap.String("pvc", pvcName),
+	)
+	st, err := extract.StatPVC(ctx,
+		namespace,
+		pvcName,
+		extract.WithLogger(log()),
+		extract.WithKubeContext(cmd.String("kube-context")),
+		extract.WithRegistry(cmd.String("registry")),
+		extract.WithRuntimeClass(cmd.String("runtime-class")),
+		extract.WithSeccompProfile(cmd.String("seccomp-profile"), cmd.String("seccomp-localhost-profile")),
+		extract.WithMountPath(cmd.String("mount-path")),
+		extract.WithSourcePath(cmd.String("source-path")),
+		extract.WithPlatformUID(cmd.Bool("platform-uid")),
+		extract.WithGCAfter(cmd.Duration("gc-after")),
+		extract.WithDeleteTimeout(cmd.Duration("delete-timeout")),
+		extract.WithForceDelete(cmd.Bool("force-delete")),
+		extract.WithTransport(cmd.String("transport")),
+		extract.WithPodImage(cmd.String("pod-image")),
+		extract.WithPodCommand(cmd.StringSlice("pod-command"), cmd.StringSlice("pod-args")),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var growth *extract.PVCGrowth
+	if prev != nil {
+		growth, err = extract.ComputeGrowth(*prev, *st)
+		if err != nil {
+			return st, nil, err
+		}
+	}
+	if snapshot != "" {
+		if err := extract.WriteStat(snapshot, st); err != nil {
+			return st, growth, errors.Wrap(err, "saving stat")
+		}
+	}
+	return st, growth, nil
+}
+
+// writeStat writes the human-readable usage and growth of the PVC, then the
+// error if any. The stat could be nil if the measure failed, and the growth
+// is nil without a previous stat.
+func writeStat(w io.Writer, st *extract.PVCStat, growth *extract.PVCGrowth, err error) error {
+	if _, werr := fmt.Fprintln(w, "Stat:"); werr != nil {
+		return werr
+	}
+	if st != nil {
+		if _, werr := fmt.Fprintf(w, "  pvc: %s/%s\n  capacity: %d bytes\n  used: %d bytes (%.1f%%)\n  available: %d bytes\n  source: %d bytes, %d files\n",
+			st.Namespace, st.PVCName, st.CapacityBytes, st.UsedBytes, st.UsedPercent(), st.AvailableBytes, st.SourceBytes, st.Files); werr != nil {
+			return werr
+		}
+	}
+	if growth != nil {
+		if _, werr := fmt.Fprintf(w, "  growth since %s: %+.0f bytes/h, %+.1f files/h\n",
+			growth.Since.Format(time.RFC3339), growth.BytesPerHour, growth.FilesPerHour); werr != nil {
+			return werr
+		}
+		full := "not growing"
+		if growth.TimeToFull != nil {
+			full = fmt.Sprintf("in %s, at %s", growth.TimeToFull.Round(time.Minute), growth.FullAt.Format(time.RFC3339))
+		}
+		if _, werr := fmt.Fprintf(w, "  full: %s\n", full); werr != nil {
+			return werr
+		}
+	}
+	if err != nil {
+		if _, werr := fmt.Fprintf(w, "  error: %s\n", err); werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// statOutput is the JSON document emitted at the end of a stat.
+type statOutput struct {
+	Version int               `json:"version"`
+	Status  string            `json:"status"`
+	Error   string            `json:"error,omitempty"`
+	Stat    *extract.PVCStat  `json:"stat,omitempty"`
+	Growth  *statGrowthOutput `json:"growth,omitempty"`
+}
+
+// statGrowthOutput is the growth of the PVC, along its time to full in
+// seconds as the durations of the other documents.
+type statGrowthOutput struct {
+	*extract.PVCGrowth
+	TimeToFull *float64 `json:"time_to_full_seconds,omitempty"`
+}
+
+// writeStatOutput writes the JSON document of the usage, growth and error.
+// The stat could be nil if the measure failed, and the growth is nil
+// without a previous stat.
+func writeStatOutput(w io.Writer, st *extract.PVCStat, growth *extract.PVCGrowth, err error) error {
+	out := statOutput{
+		Version: outputVersion,
+		Status:  "success",
+		Stat:    st,
+	}
+	if growth != nil {
+		out.Growth = &statGrowthOutput{PVCGrowth: growth}
+		if growth.TimeToFull != nil {
+			ttf := growth.TimeToFull.Seconds()
+			out.Growth.TimeToFull = &ttf
+		}
+	}
+	if err != nil {
+		out.Status = "failure"
+		out.Error = err.Error()
+	}
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_WriteStat(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	st := &extract.PVCStat{
+		Namespace:      "monitoring",
+		PVCName:        "otel-signals",
+		Time:           at.Add(2 * time.Hour),
+		CapacityBytes:  700 << 20,
+		UsedBytes:      200 << 20,
+		AvailableBytes: 500 << 20,
+		SourceBytes:    150 << 20,
+		Files:          30,
+	}
+	ttf := 10 * time.Hour
+	fullAt := st.Time.Add(ttf)
+	growing := &extract.PVCGrowth{
+		Since:        at,
+		BytesPerHour: 50 << 20,
+		FilesPerHour: 10,
+		TimeToFull:   &ttf,
+		FullAt:       &fullAt,
+	}
+
+	var tests = map[string]struct {
+		Stat     *extract.PVCStat
+		Growth   *extract.PVCGrowth
+		Err      error
+		Expected []string
+	}{
+		"first": {
+			Stat:     st,
+			Expected: []string{"pvc: monitoring/otel-signals", "used: 209715200 bytes (28.6%)", "source: 157286400 bytes, 30 files"},
+		},
+		"growing": {
+			Stat:     st,
+			Growth:   growing,
+			Expected: []string{"growth since 2026-10-17T08:00:00Z: +52428800 bytes/h, +10.0 files/h", "full: in 10h0m0s, at 2026-10-17T20:00:00Z"},
+		},
+		"not-growing": {
+			Stat:     st,
+			Growth:   &extract.PVCGrowth{Since: at},
+			Expected: []string{"full: not growing"},
+		},
+		"failure": {
+			Err:      errors.New("pod not ready"),
+			Expected: []string{"error: pod not ready"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := writeStat(buf, tt.Stat, tt.Growth, tt.Err); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for _, line := range tt.Expected {
+				if !strings.Contains(buf.String(), line) {
+					t.Errorf("expected %q in output, got:\n%s", line, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_U_WriteStatOutput(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	ttf := 10 * time.Hour
+	fullAt := at.Add(ttf)
+	buf := &bytes.Buffer{}
+	err := writeStatOutput(buf, &extract.PVCStat{Namespace: "monitoring", PVCName: "otel-signals", Time: at}, &extract.PVCGrowth{
+		Since:        at.Add(-time.Hour),
+		BytesPerHour: 1024,
+		TimeToFull:   &ttf,
+		FullAt:       &fullAt,
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	out := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid output: %s", err)
+	}
+	if out["status"] != "success" || out["version"] != float64(outputVersion) {
+		t.Errorf("unexpected status or version: %v", out)
+	}
+	growth, ok := out["growth"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected the growth, got %v", out["growth"])
+	}
+	if growth["time_to_full_seconds"] != ttf.Seconds() {
+		t.Errorf("expected the time to full in seconds, got %v", growth["time_to_full_seconds"])
+	}
+	if growth["bytes_per_hour"] != float64(1024) || growth["full_at"] != "2026-10-17T18:00:00Z" {
+		t.Errorf("unexpected growth %v", growth)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"golang.org/x/term"
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+)
+
+// writeSummary writes the human-readable recap of the run, distinct from the
+// logs: the report summary, then the run error if any.
+// The result could be nil if the run failed early.
+func writeSummary(w io.Writer, res *extract.Result, err error, color bool) error {
+	var lines []extract.SummaryLine
+	if res != nil {
+		lines = append(lines, res.Summary...)
+	}
+	if err != nil {
+		lines = append(lines, extract.SummaryLine{
+			Level: extract.SummaryError,
+			Text:  err.Error(),
+		})
+	}
+
+	if _, werr := fmt.Fprintln(w, "Summary:"); werr != nil {
+		return werr
+	}
+	for _, line := range lines {
+		prefix := ""
+		switch line.Level {
+		case extract.SummaryWarning:
+			prefix = colorize("warning: ", colorYellow, color)
+		case extract.SummaryError:
+			prefix = colorize("error: ", colorRed, color)
+		}
+		if _, werr := fmt.Fprintf(w, "  %s%s\n", prefix, line.Text); werr != nil {
+			return werr
+		}
+	}
+	if res != nil && res.Diagnostics != nil {
+		if werr := writeDiagnostics(w, res.Diagnostics); werr != nil {
+			return werr
+		}
+	}
+	if res != nil && res.Report != "" {
+		if _, werr := fmt.Fprintf(w, "  report: %s\n", res.Report); werr != nil {
+			return werr
+		}
+	}
+	if res != nil && res.Sink != nil {
+		if _, werr := fmt.Fprintf(w, "  sink: %s %s\n", res.Sink.Kind, res.Sink.Location); werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// writeDiagnostics writes the events and logs of the failed extraction Pod,
+// indented under the summary.
+func writeDiagnostics(w io.Writer, diag *extract.PodDiagnostics) error {
+	if _, err := fmt.Fprintf(w, "  pod %s diagnostics:\n", diag.Pod); err != nil {
+		return err
+	}
+	for _, ev := range diag.Events {
+		if _, err := fmt.Fprintf(w, "    event %s %s: %s\n", ev.Type, ev.Reason, ev.Message); err != nil {
+			return err
+		}
+	}
+	for _, e := range diag.Errors {
+		if _, err := fmt.Fprintf(w, "    unavailable: %s\n", e); err != nil {
+			return err
+		}
+	}
+	if diag.Logs == "" {
+		return nil
+	}
+	header := "logs"
+	if diag.LogsTruncated {
+		header = "logs (truncated)"
+	}
+	if _, err := fmt.Fprintf(w, "    %s:\n", header); err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimRight(diag.Logs, "\n"), "\n") {
+		if _, err := fmt.Fprintf(w, "      %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func colorize(str, color string, enabled bool) string {
+	if !enabled {
+		return str
+	}
+	return color + str + colorReset
+}
+
+// useColor returns whether to colorize the output written to the file: only
+// for terminals, unless turned off (see https://no-color.org).
+func useColor(f *os.File, noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_WriteSummary(t *testing.T) {
+	t.Parallel()
+
+	res := &extract.Result{
+		Summary: []extract.SummaryLine{
+			{Level: extract.SummaryInfo, Text: "copied 3 files (2.0 KiB) from PVC monitoring/signals to extract in 1.5s"},
+			{Level: extract.SummaryWarning, Text: "file no longer on the PVC: otel_logs"},
+		},
+		Report: "extract/report.json",
+	}
+
+	var tests = map[string]struct {
+		Result *extract.Result
+		Err    error
+		Color  bool
+		Golden string
+	}{
+		"success": {
+			Result: res,
+			Golden: "summary-success.golden",
+		},
+		"success-color": {
+			Result: res,
+			Color:  true,
+			Golden: "summary-success-color.golden",
+		},
+		"failure-color": {
+			Result: res,
+			Err:    errors.New("1 extracted files mismatch the PVC ones: otel_traces"),
+			Color:  true,
+			Golden: "summary-failure-color.golden",
+		},
+		"failure-diagnostics": {
+			Result: &extract.Result{
+				Summary: []extract.SummaryLine{
+					{Level: extract.SummaryInfo, Text: "copied 0 files (0 B) from PVC monitoring/signals to extract in 2m0s"},
+				},
+				Diagnostics: &extract.PodDiagnostics{
+					Pod:           "extractor",
+					Logs:          "mount failed: input/output error\nexiting\n",
+					LogsTruncated: true,
+					Events: []extract.PodEvent{
+						{Type: "Warning", Reason: "FailedMount", Message: `MountVolume.SetUp failed for volume "data"`},
+					},
+				},
+				Report: "extract/report.json",
+			},
+			Err:    errors.New("pod extractor never got ready"),
+			Golden: "summary-failure-diagnostics.golden",
+		},
+		"early-failure": {
+			Result: nil,
+			Err:    errors.New("namespace and pvc-name are required when not discovering"),
+			Golden: "summary-early-failure.golden",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := writeSummary(buf, tt.Result, tt.Err, tt.Color); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			expected, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			if buf.String() != string(expected) {
+				t.Errorf("expected:\n%q\ngot:\n%q", expected, buf.String())
+			}
+		})
+	}
+}
+
+func Test_U_UseColor(t *testing.T) {
+	// Not parallel, as it sets NO_COLOR
+	f, err := os.CreateTemp(t.TempDir(), "output")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	// A file is not a terminal
+	if useColor(f, false) {
+		t.Error("expected no color when not writing to a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if useColor(os.Stdout, false) {
+		t.Error("expected no color with NO_COLOR set")
+	}
+}
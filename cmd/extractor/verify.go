@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"go.uber.org/zap"
+)
+
+// verifyCommand verifies the extraction of the directory option. It reads
+// the global options of the Pod (e.g. registry, mount-path, source-path),
+// and the namespace and pvc-name ones default to the extraction report.
+func verifyCommand() *cli.Command {
+	return &cli.Command{
+		Name:   "verify",
+		Usage:  "Verify a previous extraction in the directory against the PVC, by comparing the SHA256 checksums of the files still on it computed in a Pod. Fails only on mismatching files, not on the ones rotated away since.",
+		Action: runVerify,
+	}
+}
+
+func runVerify(ctx context.Context, cmd *cli.Command) error {
+	rep, err := verifyRun(ctx, cmd)
+	switch cmd.String("output") {
+	case outputJSON:
+		if werr := writeVerifyOutput(os.Stdout, rep, err); werr != nil {
+			return werr
+		}
+	default:
+		if werr := writeVerify(os.Stdout, rep, err); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func verifyRun(ctx context.Context, cmd *cli.Command) (*extract.VerifyReport, error) {
+	directory := cmd.String("directory")
+	if directory == "" {
+		return nil, errors.New("directory is required to verify an extraction")
+	}
+	namespace, pvcName, err := verifyTarget(directory, cmd.String("namespace"), cmd.String("pvc-name"))
+	if err != nil {
+		return nil, err
+	}
+	log().Info("verifying extraction",
+		zap.String("directory", directory),
+		zap.String("namespace", namespace),
+		zap.String("pvc", pvcName),
+	)
+
+	return extract.VerifyDirectory(ctx,
+		namespace,
+		pvcName,
+		directory,
+		extract.WithLogger(log()),
+		extract.WithKubeContext(cmd.String("kube-context")),
+		extract.WithRegistry(cmd.String("registry")),
+		extract.WithRuntimeClass(cmd.String("runtime-class")),
+		extract.WithSeccompProfile(cmd.String("seccomp-profile"), cmd.String("seccomp-localhost-profile")),
+		extract.WithMountPath(cmd.String("mount-path")),
+		extract.WithSourcePath(cmd.String("source-path")),
+		extract.WithPlatformUID(cmd.Bool("platform-uid")),
+		extract.WithGCAfter(cmd.Duration("gc-after")),
+		extract.WithDeleteTimeout(cmd.Duration("delete-timeout")),
+		extract.WithForceDelete(cmd.Bool("force-delete")),
+		extract.WithTransport(cmd.String("transport")),
+		extract.WithPodImage(cmd.String("pod-image")),
+		extract.WithPodCommand(cmd.StringSlice("pod-command"), cmd.StringSlice("pod-args")),
+	)
+}
+
+// verifyTarget returns the namespace and PVC to verify the extraction in the
+// directory against, defaulting to the ones of its report.
+func verifyTarget(directory, namespace, pvcName string) (string, string, error) {
+	if namespace != "" && pvcName != "" {
+		return namespace, pvcName, nil
+	}
+	res, err := extract.ReadReport(directory)
+	if err != nil {
+		return "", "", errors.Wrap(err, "namespace and pvc-name are required without an extraction report")
+	}
+	if res.Source == extract.SourcePrometheus || res.Source == extract.SourceAll {
+		return "", "", fmt.Errorf("could not verify a %s extraction, only the ones of a PVC", res.Source)
+	}
+	if namespace == "" {
+		namespace = res.Namespace
+	}
+	if pvcName == "" {
+		pvcName = res.PVCName
+	}
+	if namespace == "" || pvcName == "" {
+		return "", "", errors.New("namespace and pvc-name are required, the extraction report does not provide them")
+	}
+	return namespace, pvcName, nil
+}
+
+// writeVerify writes the human-readable verification report, then the error
+// if any. The report could be nil if the verification failed early.
+func writeVerify(w io.Writer, rep *extract.VerifyReport, err error) error {
+	if _, werr := fmt.Fprintln(w, "Verification:"); werr != nil {
+		return werr
+	}
+	if rep != nil {
+		for _, cat := range []struct {
+			name  string
+			files []string
+		}{
+			{"matching", rep.Matching},
+			{"mismatched", rep.Mismatched},
+			{"missing remote", rep.MissingRemote},
+		} {
+			if _, werr := fmt.Fprintf(w, "  %s: %d\n", cat.name, len(cat.files)); werr != nil {
+				return werr
+			}
+			if cat.name == "matching" {
+				continue
+			}
+			for _, f := range cat.files {
+				if _, werr := fmt.Fprintf(w, "    %s\n", f); werr != nil {
+					return werr
+				}
+			}
+		}
+	}
+	if err != nil {
+		if _, werr := fmt.Fprintf(w, "  error: %s\n", err); werr != nil {
+			return werr
+		}
+	}
+	return nil
+}
+
+// verifyOutput is the JSON document emitted at the end of a verification.
+type verifyOutput struct {
+	Version int                   `json:"version"`
+	Status  string                `json:"status"`
+	Error   string                `json:"error,omitempty"`
+	Verify  *extract.VerifyReport `json:"verify,omitempty"`
+}
+
+// writeVerifyOutput writes the JSON document of the verification report and
+// error. The report could be nil if the verification failed early.
+func writeVerifyOutput(w io.Writer, rep *extract.VerifyReport, err error) error {
+	out := verifyOutput{
+		Version: outputVersion,
+		Status:  "success",
+		Verify:  rep,
+	}
+	if err != nil {
+		out.Status = "failure"
+		out.Error = err.Error()
+	}
+	return json.NewEncoder(w).Encode(out)
+}
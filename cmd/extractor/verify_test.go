@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_U_VerifyTarget(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Report             *extract.Result
+		Namespace, PVCName string
+		ExpectedNamespace  string
+		ExpectedPVCName    string
+		ExpectErr          bool
+	}{
+		"flags": {
+			Namespace:         "monitoring",
+			PVCName:           "signals",
+			ExpectedNamespace: "monitoring",
+			ExpectedPVCName:   "signals",
+		},
+		"report": {
+			Report:            &extract.Result{Source: extract.SourceOTelCollector, Namespace: "monitoring", PVCName: "signals"},
+			ExpectedNamespace: "monitoring",
+			ExpectedPVCName:   "signals",
+		},
+		"report-overridden": {
+			Report:            &extract.Result{Source: extract.SourceOTelCollector, Namespace: "monitoring", PVCName: "signals"},
+			PVCName:           "signals-restored",
+			ExpectedNamespace: "monitoring",
+			ExpectedPVCName:   "signals-restored",
+		},
+		"no-report": {
+			Namespace: "monitoring",
+			ExpectErr: true,
+		},
+		"prometheus-report": {
+			Report:    &extract.Result{Source: extract.SourcePrometheus, Namespace: "monitoring"},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if tt.Report != nil {
+				b, err := json.Marshal(tt.Report)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, extract.ReportFile), b, 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			namespace, pvcName, err := verifyTarget(dir, tt.Namespace, tt.PVCName)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error %t, got %v", tt.ExpectErr, err)
+			}
+			if namespace != tt.ExpectedNamespace || pvcName != tt.ExpectedPVCName {
+				t.Errorf("expected %s/%s, got %s/%s", tt.ExpectedNamespace, tt.ExpectedPVCName, namespace, pvcName)
+			}
+		})
+	}
+}
+
+func Test_U_WriteVerify(t *testing.T) {
+	t.Parallel()
+
+	rep := &extract.VerifyReport{
+		Matching:      []string{"collector/otel_traces"},
+		Mismatched:    []string{"collector/otel_metrics"},
+		MissingRemote: []string{"collector/otel_logs"},
+	}
+	buf := &bytes.Buffer{}
+	if err := writeVerify(buf, rep, errors.New("1 extracted files mismatch the PVC ones: collector/otel_metrics")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `Verification:
+  matching: 1
+  mismatched: 1
+    collector/otel_metrics
+  missing remote: 1
+    collector/otel_logs
+  error: 1 extracted files mismatch the PVC ones: collector/otel_metrics
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
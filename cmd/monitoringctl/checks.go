@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	partOfSelector = "app.kubernetes.io/part-of=monitoring"
+
+	jaegerSelector = partOfSelector + ",app.kubernetes.io/component=jaeger"
+	jaegerUIPort   = "ui"
+
+	// probeService is the service.name of the probe span.
+	probeService = "monitoringctl"
+)
+
+// checkWorkloads checks every Deployment, StatefulSet and DaemonSet of the
+// Monitoring, in its namespace and the log shipper one, has all its replicas
+// ready.
+func (env *healthEnv) checkWorkloads(ctx context.Context) (string, error) {
+	namespaces := []string{env.outputs.Namespace}
+	if ns := env.outputs.LogShipperNamespace; ns != "" && ns != env.outputs.Namespace {
+		namespaces = append(namespaces, ns)
+	}
+
+	total := 0
+	notReady := []string{}
+	for _, ns := range namespaces {
+		deps, err := env.clientset.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{LabelSelector: partOfSelector})
+		if err != nil {
+			return "", err
+		}
+		for _, dep := range deps.Items {
+			total++
+			desired := int32(1)
+			if dep.Spec.Replicas != nil {
+				desired = *dep.Spec.Replicas
+			}
+			if dep.Status.AvailableReplicas < desired {
+				notReady = append(notReady, fmt.Sprintf("deployment %s/%s %d/%d available", ns, dep.Name, dep.Status.AvailableReplicas, desired))
+			}
+		}
+
+		stss, err := env.clientset.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{LabelSelector: partOfSelector})
+		if err != nil {
+			return "", err
+		}
+		for _, sts := range stss.Items {
+			total++
+			desired := int32(1)
+			if sts.Spec.Replicas != nil {
+				desired = *sts.Spec.Replicas
+			}
+			if sts.Status.ReadyReplicas < desired {
+				notReady = append(notReady, fmt.Sprintf("statefulset %s/%s %d/%d ready", ns, sts.Name, sts.Status.ReadyReplicas, desired))
+			}
+		}
+
+		dss, err := env.clientset.AppsV1().DaemonSets(ns).List(ctx, metav1.ListOptions{LabelSelector: partOfSelector})
+		if err != nil {
+			return "", err
+		}
+		for _, ds := range dss.Items {
+			total++
+			if ds.Status.NumberReady < ds.Status.DesiredNumberScheduled {
+				notReady = append(notReady, fmt.Sprintf("daemonset %s/%s %d/%d ready", ns, ds.Name, ds.Status.NumberReady, ds.Status.DesiredNumberScheduled))
+			}
+		}
+	}
+
+	if total == 0 {
+		return "", errors.Errorf("no workload of the Monitoring in namespace %s", env.outputs.Namespace)
+	}
+	if len(notReady) != 0 {
+		return "", errors.Errorf("%d of %d workloads not ready: %s", len(notReady), total, strings.Join(notReady, ", "))
+	}
+	return fmt.Sprintf("%d workloads ready", total), nil
+}
+
+// checkJaeger checks the Jaeger query API lists the services it holds the
+// traces of.
+func (env *healthEnv) checkJaeger(ctx context.Context) (string, error) {
+	url := env.jaegerURL
+	if url == "" {
+		var err error
+		url, err = env.jaegerUIURL(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(url, "/")+"/api/services", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := env.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", errors.Errorf("jaeger query API status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	services := struct {
+		Data []string `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+		return "", errors.Wrap(err, "decoding the jaeger services")
+	}
+	return fmt.Sprintf("%d services traced", len(services.Data)), nil
+}
+
+// jaegerUIURL returns the in-cluster URL of the Jaeger UI Service.
+func (env *healthEnv) jaegerUIURL(ctx context.Context) (string, error) {
+	svcs, err := env.clientset.CoreV1().Services(env.outputs.Namespace).List(ctx, metav1.ListOptions{LabelSelector: jaegerSelector})
+	if err != nil {
+		return "", err
+	}
+	for _, svc := range svcs.Items {
+		for _, port := range svc.Spec.Ports {
+			if port.Name == jaegerUIPort {
+				return fmt.Sprintf("http://%s.%s:%d", svc.Name, svc.Namespace, port.Port), nil
+			}
+		}
+	}
+	return "", errors.Errorf("no Jaeger UI Service in namespace %s", env.outputs.Namespace)
+}
+
+// checkOTLP sends a probe span to the OTEL Collector, and checks it is
+// accepted.
+func (env *healthEnv) checkOTLP(ctx context.Context) (string, error) {
+	endpoint := env.otlpEndpoint
+	if endpoint == "" {
+		endpoint = env.outputs.OTELEndpoint
+	}
+	if endpoint == "" {
+		return "", errors.New("no OTLP endpoint, neither exported nor given")
+	}
+
+	creds := insecure.NewCredentials()
+	if env.otlpCA != "" {
+		var err error
+		creds, err = credentials.NewClientTLSFromFile(env.otlpCA, "")
+		if err != nil {
+			return "", err
+		}
+	}
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	req, traceID, err := probeSpan(time.Now())
+	if err != nil {
+		return "", err
+	}
+	resp, err := collectortracepb.NewTraceServiceClient(conn).Export(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if ps := resp.GetPartialSuccess(); ps != nil && ps.GetRejectedSpans() != 0 {
+		return "", errors.Errorf("probe span rejected: %s", ps.GetErrorMessage())
+	}
+	return "probe span accepted, trace " + traceID, nil
+}
+
+// probeSpan returns the export request of a probe span ending at the time,
+// with its trace ID.
+func probeSpan(end time.Time) (*collectortracepb.ExportTraceServiceRequest, string, error) {
+	ids := make([]byte, 16+8)
+	if _, err := rand.Read(ids); err != nil {
+		return nil, "", err
+	}
+	traceID, spanID := ids[:16], ids[16:]
+
+	return &collectortracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{
+			{
+				Resource: &resourcepb.Resource{
+					Attributes: []*commonpb.KeyValue{
+						{
+							Key: "service.name",
+							Value: &commonpb.AnyValue{
+								Value: &commonpb.AnyValue_StringValue{StringValue: probeService},
+							},
+						},
+					},
+				},
+				ScopeSpans: []*tracepb.ScopeSpans{
+					{
+						Scope: &commonpb.InstrumentationScope{Name: probeService},
+						Spans: []*tracepb.Span{
+							{
+								TraceId:           traceID,
+								SpanId:            spanID,
+								Name:              "health-probe",
+								Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+								StartTimeUnixNano: uint64(end.Add(-time.Millisecond).UnixNano()),
+								EndTimeUnixNano:   uint64(end.UnixNano()),
+							},
+						},
+					},
+				},
+			},
+		},
+	}, hex.EncodeToString(traceID), nil
+}
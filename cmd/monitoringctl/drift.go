@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/stackconfig"
+	"github.com/ctfer-io/monitoring/pkg/drift"
+	"github.com/ctfer-io/monitoring/services"
+)
+
+// configKey is the key of the configurations in the Monitoring ConfigMaps.
+const configKey = "config"
+
+func driftCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "drift",
+		Usage: "Report the OpenTelemetry Collector and Prometheus ConfigMaps edited in the cluster since deployed (requires config-drift-annotations), and what the update to the stack configuration would change in them.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "namespace",
+				Required: true,
+				Usage:    "The namespace of the Monitoring.",
+			},
+			&cli.StringFlag{
+				Name:    "kube-context",
+				Sources: cli.EnvVars("KUBE_CONTEXT"),
+				Usage:   "The context of the kubeconfig to reach the cluster through. Defaults to the current one.",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "The stack configuration file (Pulumi.<stack>.yaml) to be deployed. Defaults to the default configuration.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "set",
+				Usage: "Override a value of the configuration, as key=value. Could be repeated.",
+			},
+			&cli.StringFlag{
+				Name:  "project",
+				Value: "monitoring",
+				Usage: "The Pulumi project namespacing the configuration keys.",
+			},
+		},
+		Action: runDrift,
+	}
+}
+
+func runDrift(ctx context.Context, cmd *cli.Command) error {
+	cfg, err := stackconfig.Load(cmd.String("config"), cmd.String("project"))
+	if err != nil {
+		return err
+	}
+	for _, kv := range cmd.StringSlice("set") {
+		if err := cfg.Set(kv); err != nil {
+			return err
+		}
+	}
+	cfgs, err := cfg.Render()
+	if err != nil {
+		return err
+	}
+
+	live, err := drift.Live(ctx, cmd.String("kube-context"), cmd.String("namespace"))
+	if err != nil {
+		return err
+	}
+	rendered, err := renderedAt(live, cfgs)
+	if err != nil {
+		return err
+	}
+	rep, err := drift.Compare(live, configKey, rendered)
+	if err != nil {
+		return err
+	}
+	return writeDrift(os.Stdout, rep)
+}
+
+// renderedAt returns the rendered configurations by component, with the
+// URLs only known once deployed taken from the live OTEL Collector
+// configuration, such that they do not show as differences.
+func renderedAt(live []drift.ConfigMap, cfgs *services.Configs) (map[string]string, error) {
+	otel := cfgs.OTELCollector
+	for _, cm := range live {
+		if cm.Component != "otel-collector" {
+			continue
+		}
+		jaegerURL, prometheusURL, err := otelURLs(cm.Data[configKey])
+		if err != nil {
+			return nil, errors.Wrapf(err, "live configuration %s", cm.Name)
+		}
+		otel = strings.NewReplacer(
+			services.RenderJaegerURL, jaegerURL,
+			services.RenderPrometheusURL, prometheusURL,
+		).Replace(otel)
+	}
+	return map[string]string{
+		"otel-collector": otel,
+		"prometheus":     cfgs.Prometheus,
+	}, nil
+}
+
+// otelURLs returns the Jaeger and Prometheus URLs of the OTEL Collector
+// configuration.
+func otelURLs(config string) (jaegerURL, prometheusURL string, err error) {
+	cfg := struct {
+		Exporters struct {
+			OTLP struct {
+				Endpoint string `yaml:"endpoint"`
+			} `yaml:"otlp"`
+			PrometheusRemoteWrite struct {
+				Endpoint string `yaml:"endpoint"`
+			} `yaml:"prometheusremotewrite"`
+			PrometheusOTLP struct {
+				Endpoint string `yaml:"endpoint"`
+			} `yaml:"otlphttp/prometheus"`
+		} `yaml:"exporters"`
+	}{}
+	if err := yaml.Unmarshal([]byte(config), &cfg); err != nil {
+		return "", "", err
+	}
+	// The metrics are either remote written or pushed over OTLP
+	if cfg.Exporters.PrometheusOTLP.Endpoint != "" {
+		return cfg.Exporters.OTLP.Endpoint, strings.TrimSuffix(cfg.Exporters.PrometheusOTLP.Endpoint, "/api/v1/otlp"), nil
+	}
+	return cfg.Exporters.OTLP.Endpoint, strings.TrimSuffix(cfg.Exporters.PrometheusRemoteWrite.Endpoint, "/api/v1/write"), nil
+}
+
+// writeDrift writes the drift of every ConfigMap, and fails if any was
+// edited in the cluster as the update would revert it.
+func writeDrift(w io.Writer, rep *drift.Report) error {
+	edited := 0
+	for _, d := range rep.ConfigMaps {
+		status := "unchanged"
+		switch {
+		case !d.Stamped:
+			status = "not stamped, edits unknown"
+		case d.Edited:
+			status = "edited in the cluster"
+			edited++
+		}
+		if _, err := fmt.Fprintf(w, "%s (%s): %s\n", d.Name, d.Component, status); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, d.Diff); err != nil {
+			return err
+		}
+	}
+	if edited != 0 {
+		return errors.Errorf("%d ConfigMaps edited in the cluster, the update would revert them", edited)
+	}
+	return nil
+}
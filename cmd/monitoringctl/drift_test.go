@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ctfer-io/monitoring/internal/stackconfig"
+	"github.com/ctfer-io/monitoring/pkg/drift"
+	"github.com/ctfer-io/monitoring/services"
+)
+
+func Test_U_Drift(t *testing.T) {
+	t.Parallel()
+
+	cfgs, err := stackconfig.Config{}.Render()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// As deployed, with the URLs of the Services
+	deployed := strings.NewReplacer(
+		services.RenderJaegerURL, "http://jaeger-collector-1234.monitoring-abcdefgh:4317",
+		services.RenderPrometheusURL, "http://prometheus-5678.monitoring-abcdefgh:9090",
+	).Replace(cfgs.OTELCollector)
+	live := []drift.ConfigMap{
+		{
+			Name:      "otel-config-1234",
+			Component: "otel-collector",
+			Data:      map[string]string{configKey: deployed},
+			Hash:      drift.Hash(map[string]string{configKey: deployed}),
+		},
+		{
+			Name:      "prometheus-conf-5678",
+			Component: "prometheus",
+			Data:      map[string]string{configKey: cfgs.Prometheus},
+			Hash:      drift.Hash(map[string]string{configKey: cfgs.Prometheus}),
+		},
+	}
+
+	// Unchanged, the live URLs are not reported
+	rendered, err := renderedAt(live, cfgs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rep, err := drift.Compare(live, configKey, rendered)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeDrift(buf, rep); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := "otel-config-1234 (otel-collector): unchanged\nprometheus-conf-5678 (prometheus): unchanged\n"
+	if buf.String() != expected {
+		t.Errorf("expected no drift, got:\n%s", buf.String())
+	}
+
+	// Hot-patched, the update would revert it
+	live[1].Data = map[string]string{configKey: cfgs.Prometheus + "  - job_name: hotfix\n"}
+	rep, err = drift.Compare(live, configKey, rendered)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf.Reset()
+	if err := writeDrift(buf, rep); err == nil {
+		t.Error("expected the edited ConfigMap to fail the check")
+	}
+	out := buf.String()
+	if !strings.Contains(out, "prometheus-conf-5678 (prometheus): edited in the cluster") || !strings.Contains(out, "-  - job_name: hotfix") {
+		t.Errorf("expected the prometheus config to be reported edited, got:\n%s", out)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v3"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/internal/kubeclient"
+	"github.com/ctfer-io/monitoring/internal/promclient"
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// Names of the health checks, in the order they run.
+const (
+	checkWorkloads  = "workloads"
+	checkPrometheus = "prometheus"
+	checkJaeger     = "jaeger"
+	checkOTLP       = "otlp"
+)
+
+var checkNames = []string{checkWorkloads, checkPrometheus, checkJaeger, checkOTLP}
+
+// Statuses of the health checks.
+const (
+	statusOK      = "ok"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+func healthCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "health",
+		Usage: "Check the Monitoring serves: its workloads are ready, Prometheus and Jaeger answer, and optionally the OTEL Collector accepts a probe span. Exits non-zero if any check fails.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "outputs",
+				Sources: cli.EnvVars("OUTPUTS"),
+				Usage:   "The stack outputs file, as printed by pulumi stack output --json, or - for stdin. Defaults to reading them with the pulumi CLI.",
+			},
+			&cli.StringFlag{
+				Name:    "stack",
+				Sources: cli.EnvVars("PULUMI_STACK"),
+				Usage:   "The stack to read the outputs of with the pulumi CLI. Defaults to the selected one.",
+			},
+			&cli.StringFlag{
+				Name:    "kube-context",
+				Sources: cli.EnvVars("KUBE_CONTEXT"),
+				Usage:   "The context of the kubeconfig to reach the cluster through. Defaults to the current one.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "skip",
+				Usage: "Skip a check, among " + strings.Join(checkNames, ", ") + ". Could be repeated.",
+				Validator: func(skip []string) error {
+					for _, s := range skip {
+						if !slices.Contains(checkNames, s) {
+							return fmt.Errorf("unknown check %s", s)
+						}
+					}
+					return nil
+				},
+			},
+			&cli.BoolFlag{
+				Name:  "probe-span",
+				Usage: "Send a probe span to the OTEL Collector endpoint. Skipped otherwise.",
+			},
+			&cli.StringFlag{
+				Name:    "prometheus-url",
+				Sources: cli.EnvVars("PROMETHEUS_URL"),
+				Usage:   "The Prometheus URL, e.g. port-forwarded. Defaults to the prometheus-url output.",
+			},
+			&cli.StringFlag{
+				Name:    "prometheus-password",
+				Sources: cli.EnvVars("PROMETHEUS_PASSWORD"),
+				Usage:   "The password of the Prometheus basic auth, if required.",
+			},
+			&cli.StringFlag{
+				Name:    "jaeger-url",
+				Sources: cli.EnvVars("JAEGER_URL"),
+				Usage:   "The Jaeger UI URL, e.g. port-forwarded. Defaults to its Service in the namespace.",
+			},
+			&cli.StringFlag{
+				Name:    "otlp-endpoint",
+				Sources: cli.EnvVars("OTLP_ENDPOINT"),
+				Usage:   "The OTLP gRPC endpoint to send the probe span to, e.g. port-forwarded. Defaults to the otel-endpoint output.",
+			},
+			&cli.StringFlag{
+				Name:    "otlp-ca",
+				Sources: cli.EnvVars("OTLP_CA"),
+				Usage:   "The CA file of the OTLP receiver certificate, when served over TLS.",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Value: 10 * time.Second,
+				Usage: "The timeout of each check.",
+			},
+		},
+		Action: runHealth,
+	}
+}
+
+func runHealth(ctx context.Context, cmd *cli.Command) error {
+	outputs, err := loadOutputs(ctx, cmd.String("outputs"), cmd.String("stack"))
+	if err != nil {
+		return err
+	}
+
+	env := &healthEnv{
+		outputs:            outputs,
+		httpClient:         http.DefaultClient,
+		prometheusURL:      cmd.String("prometheus-url"),
+		prometheusPassword: cmd.String("prometheus-password"),
+		jaegerURL:          cmd.String("jaeger-url"),
+		otlpEndpoint:       cmd.String("otlp-endpoint"),
+		otlpCA:             cmd.String("otlp-ca"),
+	}
+	skip := cmd.StringSlice("skip")
+	if !cmd.Bool("probe-span") {
+		skip = append(skip, checkOTLP)
+	}
+	if !slices.Contains(skip, checkWorkloads) || (!slices.Contains(skip, checkJaeger) && env.jaegerURL == "") {
+		env.clientset, err = kubeclient.New(cmd.String("kube-context"))
+		if err != nil {
+			return errors.Wrap(err, "loading kubeconfig")
+		}
+	}
+
+	results := runChecks(ctx, env.checks(), skip, cmd.Duration("timeout"))
+	if err := writeHealth(os.Stdout, results); err != nil {
+		return err
+	}
+	return healthError(results)
+}
+
+// healthEnv is what the checks need to reach the Monitoring.
+type healthEnv struct {
+	outputs    *stackOutputs
+	clientset  kubernetes.Interface
+	httpClient *http.Client
+
+	prometheusURL      string
+	prometheusPassword string
+	jaegerURL          string
+	otlpEndpoint       string
+	otlpCA             string
+}
+
+// check is a health check, returning the detail of its success.
+type check struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// checkResult is the outcome of a check.
+type checkResult struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+func (env *healthEnv) checks() []check {
+	return []check{
+		{name: checkWorkloads, run: env.checkWorkloads},
+		{name: checkPrometheus, run: env.checkPrometheus},
+		{name: checkJaeger, run: env.checkJaeger},
+		{name: checkOTLP, run: env.checkOTLP},
+	}
+}
+
+// runChecks runs the checks not skipped one after the other, each within
+// the timeout.
+func runChecks(ctx context.Context, checks []check, skip []string, timeout time.Duration) []checkResult {
+	results := make([]checkResult, 0, len(checks))
+	for _, c := range checks {
+		if slices.Contains(skip, c.name) {
+			results = append(results, checkResult{Name: c.name, Status: statusSkipped})
+			continue
+		}
+
+		cctx, cancel := context.WithTimeout(ctx, timeout)
+		detail, err := c.run(cctx)
+		cancel()
+		if err != nil {
+			results = append(results, checkResult{Name: c.name, Status: statusFailed, Detail: err.Error()})
+			continue
+		}
+		results = append(results, checkResult{Name: c.name, Status: statusOK, Detail: detail})
+	}
+	return results
+}
+
+// writeHealth writes the results as a table.
+func writeHealth(w io.Writer, results []checkResult) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, res := range results {
+		// Keep the table on one line per check
+		detail := strings.ReplaceAll(res.Detail, "\n", " ")
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Name, res.Status, detail)
+	}
+	return tw.Flush()
+}
+
+// healthError returns the error of the failed checks, if any.
+func healthError(results []checkResult) error {
+	failed := []string{}
+	for _, res := range results {
+		if res.Status == statusFailed {
+			failed = append(failed, res.Name)
+		}
+	}
+	if len(failed) != 0 {
+		return errors.Errorf("%d of %d checks failed: %s", len(failed), len(results), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (env *healthEnv) checkPrometheus(ctx context.Context) (string, error) {
+	url := env.prometheusURL
+	if url == "" {
+		url = env.outputs.PrometheusURL
+	}
+	if url == "" {
+		return "", errors.New("no Prometheus URL, neither exported nor given")
+	}
+	opts := []promclient.Option{
+		promclient.WithHTTPClient(env.httpClient),
+	}
+	if env.prometheusPassword != "" {
+		opts = append(opts, promclient.WithBasicAuth(parts.PrometheusBasicAuthUsername, env.prometheusPassword))
+	}
+	client, err := promclient.New(url, opts...)
+	if err != nil {
+		return "", err
+	}
+	info, err := client.BuildInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "version " + info.Version, nil
+}
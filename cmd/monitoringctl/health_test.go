@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	collectortracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var monitoringLabels = map[string]string{
+	"app.kubernetes.io/part-of":   "monitoring",
+	"app.kubernetes.io/component": "jaeger",
+}
+
+func deployment(name string, desired, available int32) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "monitoring", Labels: monitoringLabels},
+		Spec:       appsv1.DeploymentSpec{Replicas: &desired},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: available},
+	}
+}
+
+func daemonSet(name string, desired, ready int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "log-shipper", Labels: monitoringLabels},
+		Status:     appsv1.DaemonSetStatus{DesiredNumberScheduled: desired, NumberReady: ready},
+	}
+}
+
+// traceServer is a fake OTLP receiver, rejecting the spans if requested.
+type traceServer struct {
+	collectortracepb.UnimplementedTraceServiceServer
+
+	reject bool
+
+	mu    sync.Mutex
+	spans []string
+}
+
+func (srv *traceServer) Export(_ context.Context, req *collectortracepb.ExportTraceServiceRequest) (*collectortracepb.ExportTraceServiceResponse, error) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for _, rs := range req.GetResourceSpans() {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				srv.spans = append(srv.spans, span.GetName())
+			}
+		}
+	}
+	if srv.reject {
+		return &collectortracepb.ExportTraceServiceResponse{
+			PartialSuccess: &collectortracepb.ExportTracePartialSuccess{
+				RejectedSpans: 1,
+				ErrorMessage:  "memory limiter",
+			},
+		}, nil
+	}
+	return &collectortracepb.ExportTraceServiceResponse{}, nil
+}
+
+// otlpServer serves the fake OTLP receiver, and returns its endpoint.
+func otlpServer(t *testing.T, srv *traceServer) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	collectortracepb.RegisterTraceServiceServer(gs, srv)
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+// statusServer answers the path with the status and body.
+func statusServer(t *testing.T, path string, status int, body string) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func Test_U_Health(t *testing.T) {
+	t.Parallel()
+
+	const (
+		buildInfo   = `{"status":"success","data":{"version":"3.7.3"}}`
+		unavailable = `{"status":"error","errorType":"unavailable","error":"TSDB not ready"}`
+		services    = `{"data":["monitoringctl","ctfd"],"total":2}`
+	)
+
+	var tests = map[string]struct {
+		Objects          []runtime.Object
+		PrometheusStatus int
+		PrometheusBody   string
+		JaegerStatus     int
+		RejectSpan       bool
+		Skip             []string
+		ExpectedStatuses map[string]string
+	}{
+		"healthy": {
+			Objects: []runtime.Object{
+				deployment("otel", 1, 1),
+				deployment("jaeger", 1, 1),
+				daemonSet("log-shipper", 3, 3),
+			},
+			PrometheusStatus: http.StatusOK,
+			PrometheusBody:   buildInfo,
+			JaegerStatus:     http.StatusOK,
+			ExpectedStatuses: map[string]string{
+				checkWorkloads:  statusOK,
+				checkPrometheus: statusOK,
+				checkJaeger:     statusOK,
+				checkOTLP:       statusOK,
+			},
+		},
+		"unhealthy": {
+			Objects: []runtime.Object{
+				deployment("otel", 2, 1),
+				daemonSet("log-shipper", 3, 3),
+			},
+			PrometheusStatus: http.StatusServiceUnavailable,
+			PrometheusBody:   unavailable,
+			JaegerStatus:     http.StatusInternalServerError,
+			RejectSpan:       true,
+			ExpectedStatuses: map[string]string{
+				checkWorkloads:  statusFailed,
+				checkPrometheus: statusFailed,
+				checkJaeger:     statusFailed,
+				checkOTLP:       statusFailed,
+			},
+		},
+		"no-workload": {
+			PrometheusStatus: http.StatusOK,
+			PrometheusBody:   buildInfo,
+			JaegerStatus:     http.StatusOK,
+			ExpectedStatuses: map[string]string{
+				checkWorkloads:  statusFailed,
+				checkPrometheus: statusOK,
+				checkJaeger:     statusOK,
+				checkOTLP:       statusOK,
+			},
+		},
+		"skipped": {
+			Objects: []runtime.Object{
+				deployment("otel", 2, 1),
+			},
+			PrometheusStatus: http.StatusServiceUnavailable,
+			PrometheusBody:   unavailable,
+			JaegerStatus:     http.StatusOK,
+			Skip:             []string{checkWorkloads, checkPrometheus, checkOTLP},
+			ExpectedStatuses: map[string]string{
+				checkWorkloads:  statusSkipped,
+				checkPrometheus: statusSkipped,
+				checkJaeger:     statusOK,
+				checkOTLP:       statusSkipped,
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			traces := &traceServer{reject: tt.RejectSpan}
+			env := &healthEnv{
+				outputs: &stackOutputs{
+					Namespace:           "monitoring",
+					OTELEndpoint:        otlpServer(t, traces),
+					PrometheusURL:       statusServer(t, "/api/v1/status/buildinfo", tt.PrometheusStatus, tt.PrometheusBody),
+					LogShipperNamespace: "log-shipper",
+				},
+				clientset:  fake.NewClientset(tt.Objects...),
+				httpClient: http.DefaultClient,
+				jaegerURL:  statusServer(t, "/api/services", tt.JaegerStatus, services),
+			}
+
+			results := runChecks(context.Background(), env.checks(), tt.Skip, 5*time.Second)
+			for _, res := range results {
+				if expected := tt.ExpectedStatuses[res.Name]; res.Status != expected {
+					t.Errorf("expected check %s to be %s, got %s (%s)", res.Name, expected, res.Status, res.Detail)
+				}
+			}
+
+			failed := false
+			for _, status := range tt.ExpectedStatuses {
+				failed = failed || status == statusFailed
+			}
+			if err := healthError(results); (err != nil) != failed {
+				t.Errorf("expected error: %t, got: %v", failed, err)
+			}
+
+			if tt.ExpectedStatuses[checkOTLP] != statusSkipped && (len(traces.spans) != 1 || traces.spans[0] != "health-probe") {
+				t.Errorf("expected the probe span to be sent, got %v", traces.spans)
+			}
+		})
+	}
+}
+
+func Test_U_JaegerUIURL(t *testing.T) {
+	t.Parallel()
+
+	env := &healthEnv{
+		outputs: &stackOutputs{Namespace: "monitoring"},
+		clientset: fake.NewClientset(
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "jaeger-grpc-abcd", Namespace: "monitoring", Labels: monitoringLabels},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "grpc", Port: 4317}}},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "jaeger-ui-efgh", Namespace: "monitoring", Labels: monitoringLabels},
+				Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "ui", Port: 16686}}},
+			},
+		),
+	}
+	url, err := env.jaegerUIURL(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if url != "http://jaeger-ui-efgh.monitoring:16686" {
+		t.Errorf("unexpected Jaeger UI URL %s", url)
+	}
+}
+
+func Test_U_ParseOutputs(t *testing.T) {
+	t.Parallel()
+
+	outputs, err := parseOutputs([]byte(`{
+		"namespace": "monitoring-abcd",
+		"otel-endpoint": "otlp-grpc.monitoring-abcd:4317",
+		"prometheus-url": "http://prometheus.monitoring-abcd:9090",
+		"log-shipper-namespace": "",
+		"ready": true
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if outputs.Namespace != "monitoring-abcd" || outputs.OTELEndpoint != "otlp-grpc.monitoring-abcd:4317" || outputs.PrometheusURL != "http://prometheus.monitoring-abcd:9090" {
+		t.Errorf("unexpected outputs %+v", outputs)
+	}
+
+	if _, err := parseOutputs([]byte(`{"otel-endpoint": "otlp-grpc:4317"}`)); err == nil {
+		t.Error("expected outputs without namespace to fail")
+	}
+}
+
+func Test_U_WriteHealth(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	err := writeHealth(buf, []checkResult{
+		{Name: checkWorkloads, Status: statusOK, Detail: "4 workloads ready"},
+		{Name: checkPrometheus, Status: statusFailed, Detail: "connection refused\nretrying"},
+		{Name: checkOTLP, Status: statusSkipped},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := strings.Join([]string{
+		"CHECK       STATUS   DETAIL",
+		"workloads   ok       4 workloads ready",
+		"prometheus  failed   connection refused retrying",
+		"otlp        skipped  ",
+		"",
+	}, "\n")
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
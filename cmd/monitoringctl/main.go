@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v3"
+)
+
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+	BuiltBy = ""
+)
+
+func main() {
+	app := &cli.Command{
+		Name:  "Monitoring Control",
+		Usage: "Operate a deployed Monitoring stack.",
+		Flags: []cli.Flag{
+			cli.VersionFlag,
+			cli.HelpFlag,
+		},
+		Commands: []*cli.Command{
+			healthCommand(),
+			driftCommand(),
+			resumeCommand(),
+		},
+		Authors: []any{
+			"CTFer.io Authors & Contributors - ctfer-io@protonmail.com",
+		},
+		Version: Version,
+		Metadata: map[string]any{
+			"version": Version,
+			"commit":  Commit,
+			"date":    Date,
+			"builtBy": BuiltBy,
+		},
+	}
+
+	if err := app.Run(context.Background(), os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// stackOutputs are the outputs of the Monitoring stack the checks rely on.
+type stackOutputs struct {
+	Namespace           string `json:"namespace"`
+	OTELEndpoint        string `json:"otel-endpoint"`
+	PrometheusURL       string `json:"prometheus-url"`
+	LogShipperNamespace string `json:"log-shipper-namespace"`
+}
+
+// loadOutputs reads the stack outputs, as printed by pulumi stack output
+// --json, from the file or stdin if "-". If no file is given, they are read
+// from the pulumi CLI, of the stack if any or the selected one.
+func loadOutputs(ctx context.Context, file, stack string) (*stackOutputs, error) {
+	var (
+		b   []byte
+		err error
+	)
+	switch file {
+	case "":
+		args := []string{"stack", "output", "--json"}
+		if stack != "" {
+			args = append(args, "--stack", stack)
+		}
+		b, err = exec.CommandContext(ctx, "pulumi", args...).Output()
+		if err != nil {
+			return nil, errors.Wrap(err, "reading the stack outputs with pulumi")
+		}
+	case "-":
+		b, err = io.ReadAll(os.Stdin)
+	default:
+		b, err = os.ReadFile(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseOutputs(b)
+}
+
+// parseOutputs parses the stack outputs, which must at least hold the
+// namespace.
+func parseOutputs(b []byte) (*stackOutputs, error) {
+	outputs := &stackOutputs{}
+	if err := json.Unmarshal(b, outputs); err != nil {
+		return nil, errors.Wrap(err, "invalid stack outputs")
+	}
+	if outputs.Namespace == "" {
+		return nil, errors.New("no namespace in the stack outputs, is it a Monitoring stack?")
+	}
+	return outputs, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/ctfer-io/monitoring/pkg/rollout"
+)
+
+func resumeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "resume",
+		Usage: "Resume the Monitoring Deployments paused during a live event (see pause-rollouts), for the changes recorded since to roll out.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "namespace",
+				Required: true,
+				Usage:    "The namespace of the Monitoring.",
+			},
+			&cli.StringFlag{
+				Name:    "kube-context",
+				Sources: cli.EnvVars("KUBE_CONTEXT"),
+				Usage:   "The context of the kubeconfig to reach the cluster through. Defaults to the current one.",
+			},
+		},
+		Action: runResume,
+	}
+}
+
+func runResume(ctx context.Context, cmd *cli.Command) error {
+	resumed, err := rollout.Resume(ctx, cmd.String("kube-context"), cmd.String("namespace"))
+	if werr := writeResumed(os.Stdout, resumed); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
+
+// writeResumed writes the Deployments resumed, even those before a failure.
+func writeResumed(w io.Writer, resumed []string) error {
+	if len(resumed) == 0 {
+		_, err := fmt.Fprintln(w, "no paused deployment")
+		return err
+	}
+	for _, name := range resumed {
+		if _, err := fmt.Fprintf(w, "deployment %s resumed\n", name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
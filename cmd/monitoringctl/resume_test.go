@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_U_WriteResumed(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Resumed  []string
+		Expected string
+	}{
+		"none": {
+			Resumed:  nil,
+			Expected: "no paused deployment\n",
+		},
+		"resumed": {
+			Resumed:  []string{"otel", "prometheus"},
+			Expected: "deployment otel resumed\ndeployment prometheus resumed\n",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			if err := writeResumed(buf, tt.Resumed); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if buf.String() != tt.Expected {
+				t.Errorf("expected %q, got %q", tt.Expected, buf.String())
+			}
+		})
+	}
+}
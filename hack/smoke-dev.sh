@@ -0,0 +1,18 @@
+#!/bin/bash
+# Runs the dev mode smoke test against a local single-node k3d cluster.
+# Usage: ./hack/smoke-dev.sh [cluster-name]
+
+set -euo pipefail
+
+CLUSTER="${1:-monitoring-dev}"
+
+export PULUMI_CONFIG_PASSPHRASE=""
+pulumi login --local
+
+# k3d ships the local-path storage class the dev mode defaults to
+if ! k3d cluster list "${CLUSTER}" >/dev/null 2>&1; then
+  k3d cluster create "${CLUSTER}" --agents 0 --wait
+fi
+kubectl config use-context "k3d-${CLUSTER}"
+
+go test -v ./smoke/ -run=^Test_S_DevMode$ -timeout=10m
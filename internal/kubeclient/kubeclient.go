@@ -0,0 +1,29 @@
+// Package kubeclient builds the Kubernetes clients of the monitoringctl
+// commands, from the kubeconfig kubectl would load.
+package kubeclient
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Config returns the REST config of the context of the kubeconfig, its
+// current one if empty. As with kubectl, the kubeconfig is the one of the
+// KUBECONFIG environment variable if set, ~/.kube/config otherwise.
+func Config(kubeContext string) (*rest.Config, error) {
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+}
+
+// New returns the client of the context of the kubeconfig, as Config loads
+// it.
+func New(kubeContext string) (*kubernetes.Clientset, error) {
+	config, err := Config(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
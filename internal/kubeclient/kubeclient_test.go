@@ -0,0 +1,73 @@
+package kubeclient
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// kubeconfig holds two clusters, the current context being the first one.
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: ctf-a
+clusters:
+- name: ctf-a
+  cluster:
+    server: https://ctf-a.example.com:6443
+- name: ctf-b
+  cluster:
+    server: https://ctf-b.example.com:6443
+users:
+- name: ops
+  user:
+    token: token
+contexts:
+- name: ctf-a
+  context:
+    cluster: ctf-a
+    user: ops
+- name: ctf-b
+  context:
+    cluster: ctf-b
+    user: ops
+`
+
+func Test_U_Config(t *testing.T) {
+	// The kubeconfig is read from KUBECONFIG, set for the whole process
+	file := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(file, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Setenv("KUBECONFIG", file)
+
+	var tests = map[string]struct {
+		KubeContext   string
+		ExpectedHost  string
+		ExpectedError bool
+	}{
+		"current": {
+			KubeContext:  "",
+			ExpectedHost: "https://ctf-a.example.com:6443",
+		},
+		"context": {
+			KubeContext:  "ctf-b",
+			ExpectedHost: "https://ctf-b.example.com:6443",
+		},
+		"unknown-context": {
+			KubeContext:   "ctf-c",
+			ExpectedError: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			config, err := Config(tt.KubeContext)
+			if (err != nil) != tt.ExpectedError {
+				t.Fatalf("expected error: %t, got %v", tt.ExpectedError, err)
+			}
+			if err == nil && config.Host != tt.ExpectedHost {
+				t.Errorf("expected host %s, got %s", tt.ExpectedHost, config.Host)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package mocks provides a Pulumi resource monitor mock for unit tests,
+// recording the inputs of every registered resource.
+package mocks
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Mocks records the inputs of every resource registered during a test,
+// and echoes them back as outputs.
+type Mocks struct {
+	// NotReady are the names of the Deployments, StatefulSets and DaemonSets
+	// whose rollout is mimicked as incomplete, i.e. without any ready replica.
+	NotReady []string
+
+	// Failing are the error messages of the resources, by name, whose
+	// creation fails as the provider reports it, e.g. a Job awaited until
+	// it failed.
+	Failing map[string]string
+
+	mu        sync.Mutex
+	resources []pulumi.MockResourceArgs
+}
+
+var _ pulumi.MockResourceMonitor = (*Mocks)(nil)
+
+func (m *Mocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	m.resources = append(m.resources, args)
+	m.mu.Unlock()
+
+	if msg, ok := m.Failing[args.Name]; ok {
+		return "", nil, errors.New(msg)
+	}
+
+	outs := args.Inputs.Copy()
+	// Kubernetes defaults the name and namespace, mimic it
+	if md, ok := outs["metadata"]; ok && md.IsObject() {
+		obj := md.ObjectValue().Copy()
+		if _, ok := obj["name"]; !ok {
+			obj["name"] = resource.NewStringProperty(args.Name)
+		}
+		if _, ok := obj["namespace"]; !ok {
+			obj["namespace"] = resource.NewStringProperty("default")
+		}
+		outs["metadata"] = resource.NewObjectProperty(obj)
+	}
+	// Workloads are awaited by the provider, mimic their rollout status
+	if args.TypeToken == "kubernetes:apps/v1:Deployment" || args.TypeToken == "kubernetes:apps/v1:StatefulSet" {
+		ready := 1.
+		if spec, ok := outs["spec"]; ok && spec.IsObject() {
+			if r, ok := spec.ObjectValue()["replicas"]; ok && r.IsNumber() {
+				ready = r.NumberValue()
+			}
+		}
+		if slices.Contains(m.NotReady, args.Name) {
+			ready = 0
+		}
+		outs["status"] = resource.NewObjectProperty(resource.PropertyMap{
+			"readyReplicas": resource.NewNumberProperty(ready),
+		})
+	}
+	// DaemonSets are mimicked on a single node
+	if args.TypeToken == "kubernetes:apps/v1:DaemonSet" {
+		ready := 1.
+		if slices.Contains(m.NotReady, args.Name) {
+			ready = 0
+		}
+		outs["status"] = resource.NewObjectProperty(resource.PropertyMap{
+			"desiredNumberScheduled": resource.NewNumberProperty(1),
+			"numberReady":            resource.NewNumberProperty(ready),
+		})
+	}
+	// Random strings are generated by the provider
+	if args.TypeToken == "random:index/randomString:RandomString" {
+		outs["result"] = resource.NewStringProperty("abcdefgh")
+	}
+	if args.TypeToken == "random:index/randomPassword:RandomPassword" {
+		outs["result"] = resource.NewStringProperty("password")
+		outs["bcryptHash"] = resource.NewStringProperty("$2a$10$hash")
+	}
+	return args.Name + "_id", outs, nil
+}
+
+func (m *Mocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return args.Args, nil
+}
+
+// ByType returns the inputs of all registered resources of the given type token.
+func (m *Mocks) ByType(typ string) []resource.PropertyMap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := []resource.PropertyMap{}
+	for _, r := range m.resources {
+		if r.TypeToken == typ {
+			out = append(out, r.Inputs)
+		}
+	}
+	return out
+}
+
+// Names returns the logical names of all registered resources of the given
+// type token.
+func (m *Mocks) Names(typ string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := []string{}
+	for _, r := range m.resources {
+		if r.TypeToken == typ {
+			out = append(out, r.Name)
+		}
+	}
+	return out
+}
+
+// ByName returns the inputs of the registered resource of the given type
+// token and logical name, or nil if none matches.
+func (m *Mocks) ByName(typ, name string) resource.PropertyMap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.resources {
+		if r.TypeToken == typ && r.Name == name {
+			return r.Inputs
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the URNs the first registered resource of the given
+// type token and name depends on, as observed by the program.
+func (m *Mocks) Dependencies(typ, name string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.resources {
+		if r.TypeToken == typ && r.Name == name {
+			return r.RegisterRPC.GetDependencies()
+		}
+	}
+	return nil
+}
+
+// Protected returns whether the first registered resource of the given type
+// token and name is protected from deletion.
+func (m *Mocks) Protected(typ, name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.resources {
+		if r.TypeToken == typ && r.Name == name {
+			return r.RegisterRPC.GetProtect()
+		}
+	}
+	return false
+}
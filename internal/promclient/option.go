@@ -0,0 +1,123 @@
+package promclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type options struct {
+	httpClient *http.Client
+
+	username, password string
+	bearerToken        string
+
+	timeout time.Duration
+
+	attempts int
+	backoff  time.Duration
+}
+
+// validate checks the options are consistent.
+func (opts *options) validate() error {
+	if opts.httpClient == nil {
+		return errors.New("http client could not be nil")
+	}
+	if opts.username != "" && opts.bearerToken != "" {
+		return errors.New("basic auth and bearer token are mutually exclusive")
+	}
+	if opts.timeout <= 0 {
+		return fmt.Errorf("timeout %s is not positive", opts.timeout)
+	}
+	if opts.attempts < 1 {
+		return fmt.Errorf("attempts %d is less than 1", opts.attempts)
+	}
+	if opts.backoff < 0 {
+		return fmt.Errorf("backoff %s is negative", opts.backoff)
+	}
+	return nil
+}
+
+// authorize sets the credentials of the request, if any.
+func (opts *options) authorize(req *http.Request) {
+	switch {
+	case opts.username != "":
+		req.SetBasicAuth(opts.username, opts.password)
+	case opts.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+opts.bearerToken)
+	}
+}
+
+// Option is the interface for all client-related functional options.
+type Option interface {
+	apply(*options)
+}
+
+type httpClientOption struct {
+	client *http.Client
+}
+
+func (opt httpClientOption) apply(opts *options) {
+	opts.httpClient = opt.client
+}
+
+// WithHTTPClient sets the HTTP client of the requests, e.g. to trust the CA
+// of the Prometheus TLS certificate. Defaults to http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return httpClientOption{client: client}
+}
+
+type basicAuthOption struct {
+	username, password string
+}
+
+func (opt basicAuthOption) apply(opts *options) {
+	opts.username, opts.password = opt.username, opt.password
+}
+
+// WithBasicAuth authenticates the requests, e.g. as
+// parts.PrometheusBasicAuthUsername when the basic auth is required.
+func WithBasicAuth(username, password string) Option {
+	return basicAuthOption{username: username, password: password}
+}
+
+type bearerTokenOption string
+
+func (opt bearerTokenOption) apply(opts *options) {
+	opts.bearerToken = string(opt)
+}
+
+// WithBearerToken authenticates the requests with the token, e.g. behind
+// an authenticating proxy.
+func WithBearerToken(token string) Option {
+	return bearerTokenOption(token)
+}
+
+type timeoutOption time.Duration
+
+func (opt timeoutOption) apply(opts *options) {
+	opts.timeout = time.Duration(opt)
+}
+
+// WithTimeout bounds each attempt of a query. Defaults to 30s.
+func WithTimeout(timeout time.Duration) Option {
+	return timeoutOption(timeout)
+}
+
+type retryOption struct {
+	attempts int
+	backoff  time.Duration
+}
+
+func (opt retryOption) apply(opts *options) {
+	opts.attempts, opts.backoff = opt.attempts, opt.backoff
+}
+
+// WithRetry retries the queries failing on a connection error, a timeout
+// or a 429/5xx status, up to the attempts and waiting the backoff between
+// them, e.g. while a freshly deployed Prometheus starts. Rejected queries
+// are not retried. Defaults to a single attempt.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return retryOption{attempts: attempts, backoff: backoff}
+}
@@ -0,0 +1,381 @@
+// Package promclient queries the Prometheus HTTP API of the Monitoring,
+// e.g. for the smoke tests and health checks to assert on the metrics it
+// ingested.
+package promclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of the query results.
+const (
+	ResultVector = "vector"
+	ResultMatrix = "matrix"
+	ResultScalar = "scalar"
+	ResultString = "string"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// maxErrorBody bounds the body read from a response that is not one of
+	// the API, e.g. of a proxy.
+	maxErrorBody = 4096
+)
+
+// Client queries the Prometheus HTTP API at its URL, e.g. the one exported
+// as prometheus-url or a port-forwarded one.
+type Client struct {
+	url     string
+	options *options
+}
+
+// Sample is a value of a series at a time, as returned by an instant query.
+type Sample struct {
+	Metric map[string]string
+	Point
+}
+
+// Series are the values of a series over time, as returned by a range query.
+type Series struct {
+	Metric map[string]string
+	Points []Point
+}
+
+// Point is a value at a time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Result is the outcome of a query. Only the field of its Type is set.
+type Result struct {
+	Type string
+
+	Vector []Sample
+	Matrix []Series
+	Scalar *Point
+	String string
+
+	// Warnings are the non-fatal issues reported by Prometheus, e.g. a
+	// query hitting partial data.
+	Warnings []string
+}
+
+// Range is the time range of a range query, evaluated every Step.
+type Range struct {
+	Start, End time.Time
+	Step       time.Duration
+}
+
+// BuildInfo is the build information of the Prometheus server.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// APIError is an error returned by the Prometheus API, e.g. a bad_data one
+// for an invalid query.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (err *APIError) Error() string {
+	if err.Type == "" {
+		return fmt.Sprintf("prometheus API status %d: %s", err.StatusCode, err.Message)
+	}
+	return fmt.Sprintf("prometheus API %s error (status %d): %s", err.Type, err.StatusCode, err.Message)
+}
+
+// retryable tells whether the API could answer once ready, e.g. while
+// Prometheus replays its WAL.
+func (err *APIError) retryable() bool {
+	return err.StatusCode == http.StatusTooManyRequests || err.StatusCode >= 500
+}
+
+// New returns the Client of the Prometheus at the URL.
+func New(prometheusURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(prometheusURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid Prometheus URL %s: unsupported scheme %q", prometheusURL, u.Scheme)
+	}
+
+	options := &options{
+		httpClient: http.DefaultClient,
+		timeout:    defaultTimeout,
+		attempts:   1,
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		url:     strings.TrimSuffix(prometheusURL, "/"),
+		options: options,
+	}, nil
+}
+
+// InstantQuery evaluates the PromQL query at the time, or at the time of
+// the Prometheus server if zero.
+func (c *Client) InstantQuery(ctx context.Context, query string, at time.Time) (*Result, error) {
+	params := url.Values{"query": {query}}
+	if !at.IsZero() {
+		params.Set("time", formatTime(at))
+	}
+	return c.query(ctx, "/api/v1/query", params)
+}
+
+// RangeQuery evaluates the PromQL query over the range.
+func (c *Client) RangeQuery(ctx context.Context, query string, r Range) (*Result, error) {
+	if r.Step <= 0 {
+		return nil, fmt.Errorf("range step %s is not positive", r.Step)
+	}
+	if r.End.Before(r.Start) {
+		return nil, fmt.Errorf("range ends at %s before its start at %s", r.End, r.Start)
+	}
+	return c.query(ctx, "/api/v1/query_range", url.Values{
+		"query": {query},
+		"start": {formatTime(r.Start)},
+		"end":   {formatTime(r.End)},
+		"step":  {strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64)},
+	})
+}
+
+// BuildInfo returns the build information of the Prometheus server, e.g. to
+// check it serves.
+func (c *Client) BuildInfo(ctx context.Context) (*BuildInfo, error) {
+	data, _, err := c.do(ctx, "/api/v1/status/buildinfo", url.Values{})
+	if err != nil {
+		return nil, err
+	}
+	info := &BuildInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, &decodeError{err: err}
+	}
+	return info, nil
+}
+
+// query evaluates the query of the API path.
+func (c *Client) query(ctx context.Context, path string, params url.Values) (*Result, error) {
+	data, warnings, err := c.do(ctx, path, params)
+	if err != nil {
+		return nil, err
+	}
+	res, err := decodeData(data)
+	if err != nil {
+		return nil, &decodeError{err: err}
+	}
+	res.Warnings = warnings
+	return res, nil
+}
+
+// do gets the API path and returns the data and warnings of its response,
+// retrying the failures Prometheus could recover from until the attempts
+// are exhausted.
+func (c *Client) do(ctx context.Context, path string, params url.Values) (json.RawMessage, []string, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		var resp *response
+		resp, err = c.get(ctx, path, params)
+		if err == nil {
+			return resp.Data, resp.Warnings, nil
+		}
+		if attempt >= c.options.attempts || !retryable(err) {
+			return nil, nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, errors.Join(err, ctx.Err())
+		case <-time.After(c.options.backoff):
+		}
+	}
+}
+
+// retryable tells whether the failure could be recovered from, i.e. it is
+// not a rejected query nor a canceled context.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if apiErr := (*APIError)(nil); errors.As(err, &apiErr) {
+		return apiErr.retryable()
+	}
+	var decodeErr *decodeError
+	return !errors.As(err, &decodeErr)
+}
+
+// decodeError is a response not decoded as one of the API.
+type decodeError struct {
+	err error
+}
+
+func (err *decodeError) Error() string {
+	return "decoding the Prometheus API response: " + err.err.Error()
+}
+
+func (err *decodeError) Unwrap() error {
+	return err.err
+}
+
+// response is the envelope of the Prometheus API responses.
+type response struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+	Warnings  []string        `json:"warnings"`
+}
+
+func (c *Client) get(ctx context.Context, path string, params url.Values) (*response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.options.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.options.authorize(req)
+	resp, err := c.options.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	apiResp := &response{}
+	if err := json.Unmarshal(body, apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			// Not the API answering, e.g. a proxy or an auth failure
+			return nil, &APIError{
+				StatusCode: resp.StatusCode,
+				Message:    strings.TrimSpace(string(body[:min(len(body), maxErrorBody)])),
+			}
+		}
+		return nil, &decodeError{err: err}
+	}
+	if apiResp.Status != "success" {
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			Type:       apiResp.ErrorType,
+			Message:    apiResp.Error,
+		}
+	}
+
+	return apiResp, nil
+}
+
+// decodeData decodes the data of a query response given its result type.
+func decodeData(data json.RawMessage) (*Result, error) {
+	raw := struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	res := &Result{Type: raw.ResultType}
+	switch raw.ResultType {
+	case ResultVector:
+		samples := []struct {
+			Metric map[string]string `json:"metric"`
+			Value  Point             `json:"value"`
+		}{}
+		if err := json.Unmarshal(raw.Result, &samples); err != nil {
+			return nil, err
+		}
+		res.Vector = make([]Sample, 0, len(samples))
+		for _, s := range samples {
+			res.Vector = append(res.Vector, Sample{Metric: s.Metric, Point: s.Value})
+		}
+	case ResultMatrix:
+		series := []struct {
+			Metric map[string]string `json:"metric"`
+			Values []Point           `json:"values"`
+		}{}
+		if err := json.Unmarshal(raw.Result, &series); err != nil {
+			return nil, err
+		}
+		res.Matrix = make([]Series, 0, len(series))
+		for _, s := range series {
+			res.Matrix = append(res.Matrix, Series{Metric: s.Metric, Points: s.Values})
+		}
+	case ResultScalar:
+		res.Scalar = &Point{}
+		if err := json.Unmarshal(raw.Result, res.Scalar); err != nil {
+			return nil, err
+		}
+	case ResultString:
+		pair := [2]any{}
+		if err := json.Unmarshal(raw.Result, &pair); err != nil {
+			return nil, err
+		}
+		str, ok := pair[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string result %v", pair[1])
+		}
+		res.String = str
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", raw.ResultType)
+	}
+	return res, nil
+}
+
+// UnmarshalJSON decodes the [<unix time>, "<value>"] pairs of the API, the
+// value being a string to carry NaN and infinities.
+func (p *Point) UnmarshalJSON(b []byte) error {
+	pair := []json.RawMessage{}
+	if err := json.Unmarshal(b, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid point %s", b)
+	}
+	var ts float64
+	if err := json.Unmarshal(pair[0], &ts); err != nil {
+		return fmt.Errorf("invalid point time %s: %w", pair[0], err)
+	}
+	var str string
+	if err := json.Unmarshal(pair[1], &str); err != nil {
+		return fmt.Errorf("invalid point value %s: %w", pair[1], err)
+	}
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return fmt.Errorf("invalid point value %s: %w", pair[1], err)
+	}
+
+	sec := int64(ts)
+	p.Time = time.Unix(sec, int64((ts-float64(sec))*float64(time.Second))).UTC().Round(time.Millisecond)
+	p.Value = v
+	return nil
+}
+
+// formatTime formats the time as the API expects, i.e. a unix time with
+// sub-second precision.
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1e3, 'f', -1, 64)
+}
@@ -0,0 +1,309 @@
+package promclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	vectorResponse = `{"status":"success","data":{"resultType":"vector","result":[
+		{"metric":{"__name__":"up","job":"otel-collector"},"value":[1792116000.123,"1"]},
+		{"metric":{"__name__":"up","job":"jaeger"},"value":[1792116000.123,"NaN"]}
+	]},"warnings":["partial data"]}`
+
+	matrixResponse = `{"status":"success","data":{"resultType":"matrix","result":[
+		{"metric":{"job":"otel-collector"},"values":[[1792116000,"1"],[1792116015,"+Inf"]]}
+	]}}`
+
+	scalarResponse = `{"status":"success","data":{"resultType":"scalar","result":[1792116000,"42"]}}`
+
+	badDataResponse = `{"status":"error","errorType":"bad_data","error":"invalid parameter \"query\": 1:4: parse error"}`
+
+	unavailableResponse = `{"status":"error","errorType":"unavailable","error":"TSDB not ready"}`
+)
+
+// cannedServer answers with the status and body of the responses in turn,
+// the last one repeated, and records the requests.
+func cannedServer(t *testing.T, responses ...[2]any) (*httptest.Server, *[]*http.Request) {
+	requests := []*http.Request{}
+	mu := sync.Mutex{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r)
+		i := min(len(requests)-1, len(responses)-1)
+		mu.Unlock()
+		w.WriteHeader(responses[i][0].(int))
+		_, _ = w.Write([]byte(responses[i][1].(string)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func Test_U_InstantQuery(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := cannedServer(t, [2]any{http.StatusOK, vectorResponse})
+	client, err := New(srv.URL+"/", WithBasicAuth("monitoring", "secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	at := time.Unix(1792116000, 0)
+	res, err := client.InstantQuery(context.Background(), "up", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	req := (*requests)[0]
+	if req.URL.Path != "/api/v1/query" || req.URL.Query().Get("query") != "up" || req.URL.Query().Get("time") != "1792116000" {
+		t.Errorf("unexpected request %s", req.URL)
+	}
+	if user, pass, ok := req.BasicAuth(); !ok || user != "monitoring" || pass != "secret" {
+		t.Errorf("expected the basic auth, got %s:%s (%t)", user, pass, ok)
+	}
+
+	if res.Type != ResultVector || len(res.Vector) != 2 {
+		t.Fatalf("expected a vector of 2 samples, got %+v", res)
+	}
+	first := res.Vector[0]
+	if first.Metric["job"] != "otel-collector" || first.Value != 1 {
+		t.Errorf("unexpected first sample %+v", first)
+	}
+	if expected := time.UnixMilli(1792116000123).UTC(); !first.Time.Equal(expected) {
+		t.Errorf("expected sample time %s, got %s", expected, first.Time)
+	}
+	if !math.IsNaN(res.Vector[1].Value) {
+		t.Errorf("expected a NaN sample, got %v", res.Vector[1].Value)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("expected the warnings, got %v", res.Warnings)
+	}
+}
+
+func Test_U_RangeQuery(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := cannedServer(t, [2]any{http.StatusOK, matrixResponse})
+	client, err := New(srv.URL, WithBearerToken("token"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	start := time.Unix(1792116000, 0)
+	res, err := client.RangeQuery(context.Background(), "up", Range{
+		Start: start,
+		End:   start.Add(15 * time.Second),
+		Step:  15 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	req := (*requests)[0]
+	q := req.URL.Query()
+	if req.URL.Path != "/api/v1/query_range" || q.Get("start") != "1792116000" || q.Get("end") != "1792116015" || q.Get("step") != "15" {
+		t.Errorf("unexpected request %s", req.URL)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("expected the bearer token, got %q", auth)
+	}
+
+	if res.Type != ResultMatrix || len(res.Matrix) != 1 || len(res.Matrix[0].Points) != 2 {
+		t.Fatalf("expected a matrix of 1 series of 2 points, got %+v", res)
+	}
+	if !math.IsInf(res.Matrix[0].Points[1].Value, 1) {
+		t.Errorf("expected an infinite point, got %v", res.Matrix[0].Points[1].Value)
+	}
+
+	if _, err := client.RangeQuery(context.Background(), "up", Range{Start: start, End: start}); err == nil {
+		t.Error("expected a range without step to fail")
+	}
+}
+
+func Test_U_Query_Scalar(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := cannedServer(t, [2]any{http.StatusOK, scalarResponse})
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res, err := client.InstantQuery(context.Background(), "scalar(42)", time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Scalar == nil || res.Scalar.Value != 42 {
+		t.Errorf("expected the scalar 42, got %+v", res.Scalar)
+	}
+}
+
+func Test_U_BuildInfo(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := cannedServer(t, [2]any{http.StatusOK, `{"status":"success","data":{"version":"3.7.3","revision":"abc","branch":"HEAD","buildDate":"20261001-10:00:00","goVersion":"go1.25.1"}}`})
+	client, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	info, err := client.BuildInfo(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if (*requests)[0].URL.Path != "/api/v1/status/buildinfo" {
+		t.Errorf("unexpected request %s", (*requests)[0].URL)
+	}
+	if info.Version != "3.7.3" || info.Revision != "abc" {
+		t.Errorf("unexpected build info %+v", info)
+	}
+}
+
+func Test_U_Query_Retry(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Responses        [][2]any
+		Attempts         int
+		ExpectedRequests int
+		ExpectedErrType  string
+	}{
+		"starting": {
+			Responses: [][2]any{
+				{http.StatusServiceUnavailable, "Service Unavailable"},
+				{http.StatusServiceUnavailable, unavailableResponse},
+				{http.StatusOK, vectorResponse},
+			},
+			Attempts:         5,
+			ExpectedRequests: 3,
+		},
+		"exhausted": {
+			Responses: [][2]any{
+				{http.StatusServiceUnavailable, unavailableResponse},
+			},
+			Attempts:         3,
+			ExpectedRequests: 3,
+			ExpectedErrType:  "unavailable",
+		},
+		"bad-data": {
+			Responses: [][2]any{
+				{http.StatusBadRequest, badDataResponse},
+			},
+			Attempts:         5,
+			ExpectedRequests: 1,
+			ExpectedErrType:  "bad_data",
+		},
+		"no-retry": {
+			Responses: [][2]any{
+				{http.StatusServiceUnavailable, unavailableResponse},
+			},
+			Attempts:         1,
+			ExpectedRequests: 1,
+			ExpectedErrType:  "unavailable",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			srv, requests := cannedServer(t, tt.Responses...)
+			client, err := New(srv.URL, WithRetry(tt.Attempts, time.Millisecond))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			_, err = client.InstantQuery(context.Background(), "up", time.Time{})
+			if len(*requests) != tt.ExpectedRequests {
+				t.Errorf("expected %d requests, got %d", tt.ExpectedRequests, len(*requests))
+			}
+			if tt.ExpectedErrType == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				return
+			}
+			apiErr := (*APIError)(nil)
+			if !errors.As(err, &apiErr) || apiErr.Type != tt.ExpectedErrType {
+				t.Errorf("expected a %s API error, got %v", tt.ExpectedErrType, err)
+			}
+		})
+	}
+}
+
+func Test_U_Query_Timeout(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(block) })
+
+	client, err := New(srv.URL, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := client.InstantQuery(context.Background(), "up", time.Time{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the query to time out, got %v", err)
+	}
+}
+
+func Test_U_New(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		URL       string
+		Opts      []Option
+		ExpectErr bool
+	}{
+		"valid": {
+			URL: "http://prometheus.monitoring:9090",
+		},
+		"no-scheme": {
+			URL:       "prometheus.monitoring:9090",
+			ExpectErr: true,
+		},
+		"both-auth": {
+			URL: "http://prometheus.monitoring:9090",
+			Opts: []Option{
+				WithBasicAuth("monitoring", "secret"),
+				WithBearerToken("token"),
+			},
+			ExpectErr: true,
+		},
+		"no-attempt": {
+			URL: "http://prometheus.monitoring:9090",
+			Opts: []Option{
+				WithRetry(0, time.Second),
+			},
+			ExpectErr: true,
+		},
+		"no-timeout": {
+			URL: "http://prometheus.monitoring:9090",
+			Opts: []Option{
+				WithTimeout(0),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := New(tt.URL, tt.Opts...)
+			if (err != nil) != tt.ExpectErr {
+				t.Errorf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Package stackconfig loads the configuration of a Monitoring stack from its
+// Pulumi stack configuration file, offline, for the tools rendering the
+// configurations it deploys.
+package stackconfig
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ctfer-io/monitoring/services"
+	"github.com/ctfer-io/monitoring/services/parts"
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration of a Monitoring stack, indexed by key without
+// the project namespace (e.g. cold-extract).
+type Config map[string]any
+
+// Load loads the configuration of the project from a Pulumi stack
+// configuration file (Pulumi.<stack>.yaml). An empty file name stands for an
+// empty configuration, i.e. the defaults.
+func Load(file, project string) (Config, error) {
+	cfg := Config{}
+	if file == "" {
+		return cfg, nil
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	stack := struct {
+		Config map[string]any `yaml:"config"`
+	}{}
+	if err := yaml.Unmarshal(b, &stack); err != nil {
+		return nil, errors.Wrapf(err, "parsing stack configuration %s", file)
+	}
+	for k, v := range stack.Config {
+		if key, ok := strings.CutPrefix(k, project+":"); ok {
+			cfg[key] = v
+		}
+	}
+	return cfg, nil
+}
+
+// Set overrides a value of the configuration, given as key=value.
+// Arrays are given in JSON, e.g. cold-extract-tenants=["ctf-2026"].
+func (cfg Config) Set(kv string) error {
+	key, value, ok := strings.Cut(kv, "=")
+	if !ok || key == "" {
+		return errors.Errorf("invalid value %q, expected key=value", kv)
+	}
+	cfg[key] = value
+	return nil
+}
+
+func (cfg Config) getBool(key string) (bool, error) {
+	switch v := cfg[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, errors.Wrapf(err, "invalid %s", key)
+	default:
+		return false, errors.Errorf("invalid %s: unsupported value %v", key, v)
+	}
+}
+
+func (cfg Config) getStrings(key string) ([]string, error) {
+	switch v := cfg[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		// Set through pulumi config set, as JSON
+		var strs []string
+		if err := json.Unmarshal([]byte(v), &strs); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", key)
+		}
+		return strs, nil
+	case []any:
+		// Set through pulumi config set --path
+		strs := make([]string, 0, len(v))
+		for _, e := range v {
+			str, ok := e.(string)
+			if !ok {
+				return nil, errors.Errorf("invalid %s: unsupported item %v", key, e)
+			}
+			strs = append(strs, str)
+		}
+		return strs, nil
+	default:
+		return nil, errors.Errorf("invalid %s: unsupported value %v", key, v)
+	}
+}
+
+// MonitoringArgs maps the configuration to the Monitoring arguments, as the
+// Pulumi program does, limited to those reaching the rendered configurations.
+func (cfg Config) MonitoringArgs() (*services.MonitoringArgs, error) {
+	args := &services.MonitoringArgs{}
+
+	var err error
+	if args.ColdExtract, err = cfg.getBool("cold-extract"); err != nil {
+		return nil, err
+	}
+	tenants, err := cfg.getStrings("cold-extract-tenants")
+	if err != nil {
+		return nil, err
+	}
+	if len(tenants) != 0 {
+		args.ColdExtractTenantRouting = &parts.TenantRoutingArgs{
+			Tenants: tenants,
+		}
+	}
+	if args.DependencyGraph, err = cfg.getBool("dependency-graph"); err != nil {
+		return nil, err
+	}
+	if args.TracesFailover, err = cfg.getBool("traces-failover"); err != nil {
+		return nil, err
+	}
+	tls, err := cfg.getBool("otel-receiver-tls")
+	if err != nil {
+		return nil, err
+	}
+	mtls, err := cfg.getBool("otel-receiver-mtls")
+	if err != nil {
+		return nil, err
+	}
+	if tls || mtls {
+		args.OTELReceiverTLS = &parts.ReceiverTLSArgs{
+			RequireClientCertificate: mtls,
+		}
+	}
+
+	if args.PrometheusAgentMode, err = cfg.getBool("prometheus-agent-mode"); err != nil {
+		return nil, err
+	}
+	if args.PrometheusRemoteWriteBasicAuth, err = cfg.getBool("prometheus-remote-write-basic-auth"); err != nil {
+		return nil, err
+	}
+	args.DisableJaegerSPM = args.PrometheusAgentMode || args.PrometheusRemoteWriteBasicAuth // SPM requires querying Prometheus, without credentials
+	remoteWriteURLs, err := cfg.getStrings("prometheus-remote-write-urls")
+	if err != nil {
+		return nil, err
+	}
+	args.PrometheusRemoteWriteURLs = pulumi.ToStringArray(remoteWriteURLs)
+	if args.PrometheusQueryLog, err = cfg.getBool("prometheus-query-log"); err != nil {
+		return nil, err
+	}
+
+	return args, nil
+}
+
+// Render renders the OTEL Collector and Prometheus configurations of the
+// stack.
+func (cfg Config) Render() (*services.Configs, error) {
+	args, err := cfg.MonitoringArgs()
+	if err != nil {
+		return nil, err
+	}
+	return services.RenderConfigs(args)
+}
@@ -0,0 +1,61 @@
+package stackconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_U_Load(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "Pulumi.dev.yaml")
+	if err := os.WriteFile(file, []byte(`config:
+  monitoring:cold-extract: "true"
+  monitoring:cold-extract-tenants:
+    - ctf-2026
+  monitoring:prometheus-remote-write-urls: '["http://mimir:9009/api/v1/push"]'
+  monitoring:dependency-graph: false
+  kubernetes:context: kind-monitoring
+`), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cfg, err := Load(file, "monitoring")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := cfg["context"]; ok {
+		t.Error("expected other projects configuration to be ignored")
+	}
+	if err := cfg.Set("otel-receiver-mtls=true"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := cfg.Set("dependency-graph"); err == nil {
+		t.Error("expected an error without value")
+	}
+
+	args, err := cfg.MonitoringArgs()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !args.ColdExtract {
+		t.Error("expected cold extract")
+	}
+	if args.ColdExtractTenantRouting == nil || len(args.ColdExtractTenantRouting.Tenants) != 1 {
+		t.Errorf("expected one tenant, got %+v", args.ColdExtractTenantRouting)
+	}
+	if args.DependencyGraph {
+		t.Error("expected no dependency graph")
+	}
+	if args.OTELReceiverTLS == nil || !args.OTELReceiverTLS.RequireClientCertificate {
+		t.Errorf("expected mutual TLS, got %+v", args.OTELReceiverTLS)
+	}
+
+	if err := cfg.Set("cold-extract=maybe"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := cfg.MonitoringArgs(); err == nil {
+		t.Error("expected an error with an invalid boolean")
+	}
+}
@@ -1,23 +1,146 @@
 package main
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
 	"github.com/ctfer-io/monitoring/services"
+	"github.com/ctfer-io/monitoring/services/parts"
+	"github.com/pkg/errors"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
+var (
+	// Build info, set through ldflags, e.g.
+	// go build -ldflags "-X main.Version=v0.1.0 -X main.Commit=$(git rev-parse HEAD)"
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+	BuiltBy = ""
+)
+
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
-		cfg := loadConfig(ctx)
+		cfg, err := loadConfig(ctx)
+		if err != nil {
+			return err
+		}
+		queryTimeout, err := parseDuration(cfg.PrometheusQueryTimeout)
+		if err != nil {
+			return errors.Wrap(err, "invalid prometheus-query-timeout")
+		}
+		archive, err := jaegerArchive(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid jaeger-archive")
+		}
+		query, err := jaegerQuery(cfg)
+		if err != nil {
+			return err
+		}
+		alertRules, err := alertRules(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid alert-rules")
+		}
+		persesBootstrap, err := persesBootstrap(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid perses-bootstrap-timeout")
+		}
+		logsMetrics, err := logsMetrics(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid otel-logs-metrics-dedup-interval")
+		}
+		remoteWriteWAL, err := remoteWriteWAL(cfg)
+		if err != nil {
+			return errors.Wrap(err, "invalid otel-remote-write-wal-truncate-frequency")
+		}
+		exporterRetry, err := exporterRetry(cfg)
+		if err != nil {
+			return err
+		}
 
 		mon, err := services.NewMonitoring(ctx, "monitoring", &services.MonitoringArgs{
-			ColdExtract:      cfg.ColdExtract,
-			Registry:         pulumi.String(cfg.Registry),
-			StorageClassName: pulumi.String(cfg.StorageClassName),
-			StorageSize:      pulumi.String(cfg.StorageSize),
-			PVCAccessModes: pulumi.ToStringArray([]string{
-				cfg.PVCAccessMode,
-			}),
+			ColdExtract:                          cfg.ColdExtract,
+			ColdExtractTenantRouting:             tenantRouting(cfg.ColdExtractTenants),
+			Registry:                             pulumi.String(cfg.Registry),
+			StorageClassName:                     pulumi.String(cfg.StorageClassName),
+			StorageSize:                          pulumi.String(cfg.StorageSize),
+			PVCAccessModes:                       pvcAccessModes(cfg.PVCAccessMode),
+			PublishNotReadyAddresses:             pulumi.Bool(cfg.PublishNotReadyAddresses),
+			SpreadAcrossZones:                    cfg.SpreadAcrossZones,
+			OTELReplicas:                         cfg.OTELReplicas,
+			PrometheusAgentMode:                  cfg.PrometheusAgentMode,
+			PrometheusRemoteWriteURLs:            pulumi.ToStringArray(cfg.PrometheusRemoteWriteURLs),
+			PrometheusAdminAPI:                   cfg.PrometheusAdminAPI,
+			PrometheusQueryLog:                   cfg.PrometheusQueryLog,
+			PrometheusAnnotationScrape:           annotationScrape(cfg.PrometheusAnnotationScrapeNamespaces),
+			PrometheusQueryTimeout:               queryTimeout,
+			PrometheusQueryMaxConcurrency:        cfg.PrometheusQueryMaxConcurrency,
+			PrometheusPort:                       cfg.PrometheusPort,
+			PrometheusAlertRules:                 alertRules,
+			PersesWaitsForPrometheus:             cfg.PersesWaitsForPrometheus,
+			PersesReplicas:                       cfg.PersesReplicas,
+			PersesDisruptionBudget:               persesDisruptionBudget(cfg.PersesReplicas),
+			PersesAccess:                         persesAccess(cfg),
+			PersesBootstrap:                      persesBootstrap,
+			PersesExtraValues:                    cfg.PersesExtraValues,
+			PersesForceExtraValues:               cfg.PersesForceExtraValues,
+			PrometheusRemoteWriteBasicAuth:       cfg.PrometheusRemoteWriteBasicAuth,
+			PrometheusRemoteWriteBasicAuthSecret: existingSecret(cfg.PrometheusBasicAuthSecret),
+			PrometheusOTLPIngestion:              cfg.PrometheusOTLPIngestion,
+			DisableJaegerSPM:                     cfg.PrometheusAgentMode || cfg.PrometheusRemoteWriteBasicAuth, // SPM requires querying Prometheus, without credentials
+			JaegerArchive:                        archive,
+			JaegerQuery:                          query,
+			DependencyGraph:                      cfg.DependencyGraph,
+			Exemplars:                            cfg.Exemplars,
+			TracesFailover:                       cfg.TracesFailover,
+			IngressPeers:                         ingressPeers(cfg.OTELIngressNamespaces),
+			EventLog:                             cfg.EventLog,
+			NetworkFlowsConfigMap:                cfg.NetworkFlowsConfigMap,
+			OTELReceiverTLS:                      receiverTLS(cfg.OTELReceiverTLS, cfg.OTELReceiverMTLS),
+			OTELStatsdReceiver:                   cfg.OTELStatsdReceiver,
+			OTELSyslogReceiver:                   syslogReceiver(cfg.OTELSyslogReceiver, cfg.OTELSyslogProtocol),
+			OTELPorts:                            otelPorts(cfg),
+			OTELRedaction:                        redaction(cfg),
+			OTELKafka:                            kafka(cfg),
+			OTELMetricsConversion:                metricsConversion(cfg),
+			OTELLogsMetrics:                      logsMetrics,
+			LogShipper:                           logShipper(cfg.LogShipper),
+			OpenShift:                            openShift(cfg.OpenShift, cfg.OpenShiftRoutes),
+			Hosts:                                hosts(cfg),
+			GrafanaDatasources:                   grafanaDatasources(cfg),
+			ClusterDomain:                        cfg.ClusterDomain,
+			IPFamily:                             ipFamily(cfg.IPFamilyPolicy, cfg.IPFamilies),
+			Mesh:                                 mesh(cfg),
+			OTELCollectorImage:                   cfg.OTELCollectorImage,
+			OTELCollectorComponents:              cfg.OTELCollectorComponents,
+			OTELValidateConfig:                   cfg.OTELValidateConfig,
+			DevMode:                              cfg.DevMode,
+			Preset:                               cfg.Preset,
+			OTELQueueSize:                        cfg.OTELQueueSize,
+			ExporterRetry:                        exporterRetry,
+			OTELAwaitBackends:                    cfg.OTELAwaitBackends,
+			OTELHeadSamplingPercent:              cfg.OTELHeadSamplingPercent,
+			OTELIngestionQuotas:                  ingestionQuotas(cfg),
+			OTELRemoteWriteWAL:                   remoteWriteWAL,
+			OTELMemoryLimitPercent:               cfg.OTELMemoryLimitPercent,
+			PrometheusRetention:                  cfg.PrometheusRetention,
+			JaegerMemoryMaxTraces:                cfg.JaegerMemoryMaxTraces,
+			ConfigDriftAnnotations:               cfg.ConfigDriftAnnotations,
+			Protect:                              cfg.Protect,
+			PauseRollouts:                        cfg.PauseRollouts,
+			MinReadySeconds:                      cfg.MinReadySeconds,
+			FreezeWindows:                        cfg.FreezeWindows,
+			FreezeOverride:                       cfg.FreezeOverride,
+			BuildInfo: &services.BuildInfo{
+				Version: Version,
+				Commit:  Commit,
+				Date:    Date,
+				BuiltBy: BuiltBy,
+			},
 		})
 		if err != nil {
 			return err
@@ -26,26 +149,818 @@ func main() {
 		ctx.Export("namespace", mon.Namespace)
 		ctx.Export("otel-endpoint", mon.OTEL.Endpoint)
 		ctx.Export("otel-cold-extract-pvc-name", mon.OTEL.ColdExtractPVCName)
+		ctx.Export("otel-cold-extract-layout", mon.OTEL.ColdExtractLayout)
+		ctx.Export("otel-pipelines", mon.OTEL.Pipelines)
+		ctx.Export("otel-pod-endpoints", mon.OTEL.PodEndpoints)
+		ctx.Export("otel-ca-secret-name", mon.OTEL.CASecretName)
+		ctx.Export("log-shipper-namespace", mon.LogShipper.Namespace)
+		ctx.Export("log-shipper-pod-labels", mon.LogShipper.PodLabels)
+		ctx.Export("prometheus-url", mon.PrometheusURL)
+		ctx.Export("perses-dashboard-discovery", mon.DashboardDiscovery.ToMap())
+		ctx.Export("version", mon.Version)
+		ctx.Export("summary", mon.Summary)
+		ctx.Export("ready", mon.Ready)
+		ctx.Export("network-flows", mon.NetworkFlows)
+		ctx.Export("hosts", mon.Hosts)
+		ctx.Export("grafana-datasources", mon.GrafanaDatasources)
 
 		return nil
 	})
 }
 
 type Config struct {
-	ColdExtract      bool
-	Registry         string
-	StorageClassName string
-	StorageSize      string
-	PVCAccessMode    string
+	ColdExtract        bool
+	ColdExtractTenants []string
+	Registry           string
+	StorageClassName   string
+	StorageSize        string
+	PVCAccessMode      string
+
+	PublishNotReadyAddresses             bool
+	SpreadAcrossZones                    bool
+	PrometheusAgentMode                  bool
+	PrometheusRemoteWriteURLs            []string
+	PrometheusAdminAPI                   bool
+	PrometheusRemoteWriteBasicAuth       bool
+	PrometheusBasicAuthSecret            string
+	PrometheusOTLPIngestion              bool
+	PrometheusQueryLog                   bool
+	PrometheusAnnotationScrapeNamespaces []string
+	PrometheusQueryTimeout               string
+	PrometheusQueryMaxConcurrency        int
+	PrometheusPort                       int
+	PrometheusRetention                  string
+	AlertRules                           string
+	AlertRulesDisabled                   []string
+	AlertRulesPVCFullPercent             int
+	AlertRulesFor                        string
+	AlertRulesLabels                     map[string]string
+	PersesWaitsForPrometheus             bool
+	PersesReplicas                       int
+	PersesBootstrap                      bool
+	PersesBootstrapTimeout               string
+	OTELIngressNamespaces                []string
+	JaegerArchive                        string
+	JaegerArchiveStorageSize             string
+	JaegerArchiveESURLs                  []string
+	JaegerArchiveESIndexPrefix           string
+	JaegerArchiveESUsername              string
+	JaegerArchiveESPassword              pulumi.StringInput
+	JaegerArchiveESPasswordSecret        string
+	JaegerQueryMaxClockSkewAdjust        string
+	JaegerQueryMaxTraces                 int
+	JaegerQueryMaxLookback               string
+	JaegerQueryHTTPReadTimeout           string
+	JaegerQueryHTTPWriteTimeout          string
+	JaegerQueryGRPCMaxConnAge            string
+	JaegerMemoryMaxTraces                int
+	DependencyGraph                      bool
+	Exemplars                            bool
+	TracesFailover                       bool
+	EventLog                             bool
+	NetworkFlowsConfigMap                bool
+	OTELReceiverTLS                      bool
+	OTELReceiverMTLS                     bool
+	OTELStatsdReceiver                   bool
+	OTELSyslogReceiver                   bool
+	OTELSyslogProtocol                   string
+	OTELOTLPPort                         int
+	OTELStatsdPort                       int
+	OTELSyslogPort                       int
+	OTELQueueSize                        int
+	OTELExporterRetryInitialInterval     string
+	OTELExporterRetryMaxInterval         string
+	OTELExporterRetryMaxElapsedTime      string
+	OTELExporterRetryMultiplier          string
+	OTELExporterRetryRandomization       string
+	OTELAwaitBackends                    bool
+	OTELHeadSamplingPercent              *int
+	OTELReplicas                         int
+	OTELIngestionQuotas                  map[string]int
+	OTELIngestionQuotaAttribute          string
+	OTELRemoteWriteWAL                   bool
+	OTELRemoteWriteWALDirectory          string
+	OTELRemoteWriteWALBufferSize         int
+	OTELRemoteWriteWALTruncateFreq       string
+	OTELRemoteWriteWALSizeLimit          string
+	OTELMemoryLimitPercent               int
+	OTELRedactionDeleteKeys              []string
+	OTELRedactionMaskPatterns            []string
+	OTELRedactionHashPatterns            []string
+	OTELRedactionRawStorage              bool
+	OTELRedactionRawColdExtract          bool
+	OTELKafkaBrokers                     []string
+	OTELKafkaTracesTopic                 string
+	OTELKafkaMetricsTopic                string
+	OTELKafkaLogsTopic                   string
+	OTELKafkaEncoding                    string
+	OTELKafkaTLSSecret                   string
+	OTELKafkaTLSClientCertificate        bool
+	OTELKafkaSASLUsername                string
+	OTELKafkaSASLMechanism               string
+	OTELKafkaSASLPasswordSecret          string
+	OTELDeltaToCumulative                bool
+	OTELExponentialHistograms            bool
+	OTELLogsMetrics                      bool
+	OTELLogsMetricsAttributes            []string
+	OTELLogsMetricsMinSeverity           string
+	OTELLogsMetricsDedup                 bool
+	OTELLogsMetricsDedupInterval         string
+	LogShipper                           bool
+	OpenShift                            bool
+	OpenShiftRoutes                      bool
+	BaseDomain                           string
+	JaegerHostPrefix                     string
+	PersesHostPrefix                     string
+	PrometheusHostPrefix                 string
+	JaegerHost                           string
+	PersesHost                           string
+	PrometheusHost                       string
+	ExposePrometheus                     bool
+	ExternalDNS                          bool
+	GrafanaDatasources                   bool
+	GrafanaDatasourcesConfigMap          bool
+	GrafanaDatasourcesNamespace          string
+	ClusterDomain                        string
+	IPFamilyPolicy                       string
+	IPFamilies                           []string
+	MeshProvider                         string
+	MeshInject                           bool
+	MeshExcludeInboundPorts              []int
+	MeshExcludeOutboundPorts             []int
+	OTELCollectorImage                   string
+	OTELCollectorComponents              *parts.CollectorComponents
+	OTELValidateConfig                   bool
+	DevMode                              bool
+	Preset                               string
+	ConfigDriftAnnotations               bool
+	Protect                              bool
+	PauseRollouts                        bool
+	MinReadySeconds                      int
+	FreezeWindows                        []string
+	FreezeOverride                       bool
+	PersesOrganizers                     []string
+	PersesSpectators                     []string
+	PersesPublicProject                  string
+	PersesExtraValues                    map[string]any
+	PersesForceExtraValues               bool
 }
 
-func loadConfig(ctx *pulumi.Context) *Config {
+func loadConfig(ctx *pulumi.Context) (*Config, error) {
 	cfg := config.New(ctx, "monitoring")
+
+	var (
+		remoteWriteURLs                                []string
+		ingressNamespaces                              []string
+		tenants                                        []string
+		quotas                                         map[string]int
+		components                                     *parts.CollectorComponents
+		esURLs                                         []string
+		redactionKeys, redactionMasks, redactionHashes []string
+		persesOrganizers, persesSpectators             []string
+		persesExtraValues                              map[string]any
+		kafkaBrokers                                   []string
+		annotationScrapeNamespaces                     []string
+		logsMetricsAttributes                          []string
+		ipFamilies                                     []string
+		meshInbound, meshOutbound                      []int
+		freezeWindows                                  []string
+		alertsDisabled                                 []string
+		alertsLabels                                   map[string]string
+	)
+	// Unset keeps every trace, unlike 0
+	var headSampling *int
+	switch v, err := cfg.TryInt("otel-head-sampling-percent"); {
+	case err == nil:
+		headSampling = &v
+	case !errors.Is(err, config.ErrMissingVar):
+		return nil, errors.Wrap(err, "invalid otel-head-sampling-percent")
+	}
+
+	// A value not decoding fails the program, rather than being ignored
+	for _, obj := range []struct {
+		key string
+		v   any
+	}{
+		{"prometheus-remote-write-urls", &remoteWriteURLs},
+		{"otel-ingress-namespaces", &ingressNamespaces},
+		{"cold-extract-tenants", &tenants},
+		{"otel-ingestion-quotas", &quotas},
+		{"otel-collector-components", &components},
+		{"jaeger-archive-es-urls", &esURLs},
+		{"otel-redaction-delete-keys", &redactionKeys},
+		{"otel-redaction-mask-patterns", &redactionMasks},
+		{"otel-redaction-hash-patterns", &redactionHashes},
+		{"perses-organizers", &persesOrganizers},
+		{"perses-spectators", &persesSpectators},
+		{"perses-extra-values", &persesExtraValues},
+		{"otel-kafka-brokers", &kafkaBrokers},
+		{"prometheus-annotation-scrape-namespaces", &annotationScrapeNamespaces},
+		{"otel-logs-metrics-attributes", &logsMetricsAttributes},
+		{"ip-families", &ipFamilies},
+		{"mesh-exclude-inbound-ports", &meshInbound},
+		{"mesh-exclude-outbound-ports", &meshOutbound},
+		{"freeze-windows", &freezeWindows},
+		{"alert-rules-disabled", &alertsDisabled},
+		{"alert-rules-labels", &alertsLabels},
+	} {
+		if err := cfg.GetObject(obj.key, obj.v); err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", obj.key)
+		}
+	}
+
 	return &Config{
-		ColdExtract:      cfg.GetBool("cold-extract"),
-		Registry:         cfg.Get("registry"),
-		StorageClassName: cfg.Get("storage-class-name"),
-		StorageSize:      cfg.Get("storage-size"),
-		PVCAccessMode:    cfg.Get("pvc-access-mode"),
+		ColdExtract:        cfg.GetBool("cold-extract"),
+		ColdExtractTenants: tenants,
+		Registry:           cfg.Get("registry"),
+		StorageClassName:   cfg.Get("storage-class-name"),
+		StorageSize:        cfg.Get("storage-size"),
+		PVCAccessMode:      cfg.Get("pvc-access-mode"),
+
+		PublishNotReadyAddresses:             cfg.GetBool("publish-not-ready-addresses"),
+		SpreadAcrossZones:                    cfg.GetBool("spread-across-zones"),
+		PrometheusAgentMode:                  cfg.GetBool("prometheus-agent-mode"),
+		PrometheusRemoteWriteURLs:            remoteWriteURLs,
+		PrometheusAdminAPI:                   cfg.GetBool("prometheus-admin-api"),
+		PrometheusRemoteWriteBasicAuth:       cfg.GetBool("prometheus-remote-write-basic-auth"),
+		PrometheusBasicAuthSecret:            cfg.Get("prometheus-remote-write-basic-auth-secret"),
+		PrometheusOTLPIngestion:              cfg.GetBool("prometheus-otlp-ingestion"),
+		PrometheusQueryLog:                   cfg.GetBool("prometheus-query-log"),
+		PrometheusAnnotationScrapeNamespaces: annotationScrapeNamespaces,
+		PrometheusQueryTimeout:               cfg.Get("prometheus-query-timeout"),
+		PrometheusQueryMaxConcurrency:        cfg.GetInt("prometheus-query-max-concurrency"),
+		PrometheusPort:                       cfg.GetInt("prometheus-port"),
+		PrometheusRetention:                  cfg.Get("prometheus-retention"),
+		AlertRules:                           cfg.Get("alert-rules"),
+		AlertRulesDisabled:                   alertsDisabled,
+		AlertRulesPVCFullPercent:             cfg.GetInt("alert-rules-pvc-full-percent"),
+		AlertRulesFor:                        cfg.Get("alert-rules-for"),
+		AlertRulesLabels:                     alertsLabels,
+		PersesWaitsForPrometheus:             cfg.GetBool("perses-waits-for-prometheus"),
+		PersesReplicas:                       cfg.GetInt("perses-replicas"),
+		PersesBootstrap:                      cfg.GetBool("perses-bootstrap"),
+		PersesBootstrapTimeout:               cfg.Get("perses-bootstrap-timeout"),
+		OTELIngressNamespaces:                ingressNamespaces,
+		JaegerArchive:                        cfg.Get("jaeger-archive"),
+		JaegerArchiveStorageSize:             cfg.Get("jaeger-archive-storage-size"),
+		JaegerArchiveESURLs:                  esURLs,
+		JaegerArchiveESIndexPrefix:           cfg.Get("jaeger-archive-es-index-prefix"),
+		JaegerArchiveESUsername:              cfg.Get("jaeger-archive-es-username"),
+		JaegerArchiveESPassword:              optionalSecret(cfg, "jaeger-archive-es-password"),
+		JaegerArchiveESPasswordSecret:        cfg.Get("jaeger-archive-es-password-secret"),
+		JaegerQueryMaxClockSkewAdjust:        cfg.Get("jaeger-query-max-clock-skew-adjust"),
+		JaegerQueryMaxTraces:                 cfg.GetInt("jaeger-query-max-traces"),
+		JaegerQueryMaxLookback:               cfg.Get("jaeger-query-max-lookback"),
+		JaegerQueryHTTPReadTimeout:           cfg.Get("jaeger-query-http-read-timeout"),
+		JaegerQueryHTTPWriteTimeout:          cfg.Get("jaeger-query-http-write-timeout"),
+		JaegerQueryGRPCMaxConnAge:            cfg.Get("jaeger-query-grpc-max-connection-age"),
+		JaegerMemoryMaxTraces:                cfg.GetInt("jaeger-memory-max-traces"),
+		DependencyGraph:                      cfg.GetBool("dependency-graph"),
+		Exemplars:                            cfg.GetBool("exemplars"),
+		TracesFailover:                       cfg.GetBool("traces-failover"),
+		EventLog:                             cfg.GetBool("event-log"),
+		NetworkFlowsConfigMap:                cfg.GetBool("network-flows-configmap"),
+		OTELReceiverTLS:                      cfg.GetBool("otel-receiver-tls"),
+		OTELReceiverMTLS:                     cfg.GetBool("otel-receiver-mtls"),
+		OTELStatsdReceiver:                   cfg.GetBool("otel-statsd-receiver"),
+		OTELSyslogReceiver:                   cfg.GetBool("otel-syslog-receiver"),
+		OTELSyslogProtocol:                   cfg.Get("otel-syslog-protocol"),
+		OTELOTLPPort:                         cfg.GetInt("otel-otlp-port"),
+		OTELStatsdPort:                       cfg.GetInt("otel-statsd-port"),
+		OTELSyslogPort:                       cfg.GetInt("otel-syslog-port"),
+		OTELQueueSize:                        cfg.GetInt("otel-queue-size"),
+		OTELExporterRetryInitialInterval:     cfg.Get("otel-exporter-retry-initial-interval"),
+		OTELExporterRetryMaxInterval:         cfg.Get("otel-exporter-retry-max-interval"),
+		OTELExporterRetryMaxElapsedTime:      cfg.Get("otel-exporter-retry-max-elapsed-time"),
+		OTELExporterRetryMultiplier:          cfg.Get("otel-exporter-retry-multiplier"),
+		OTELExporterRetryRandomization:       cfg.Get("otel-exporter-retry-randomization-factor"),
+		OTELAwaitBackends:                    cfg.GetBool("otel-await-backends"),
+		OTELHeadSamplingPercent:              headSampling,
+		OTELReplicas:                         cfg.GetInt("otel-replicas"),
+		OTELIngestionQuotas:                  quotas,
+		OTELIngestionQuotaAttribute:          cfg.Get("otel-ingestion-quota-attribute"),
+		OTELRemoteWriteWAL:                   cfg.GetBool("otel-remote-write-wal"),
+		OTELRemoteWriteWALDirectory:          cfg.Get("otel-remote-write-wal-directory"),
+		OTELRemoteWriteWALBufferSize:         cfg.GetInt("otel-remote-write-wal-buffer-size"),
+		OTELRemoteWriteWALTruncateFreq:       cfg.Get("otel-remote-write-wal-truncate-frequency"),
+		OTELRemoteWriteWALSizeLimit:          cfg.Get("otel-remote-write-wal-size-limit"),
+		OTELMemoryLimitPercent:               cfg.GetInt("otel-memory-limit-percent"),
+		OTELRedactionDeleteKeys:              redactionKeys,
+		OTELRedactionMaskPatterns:            redactionMasks,
+		OTELRedactionHashPatterns:            redactionHashes,
+		OTELRedactionRawStorage:              cfg.GetBool("otel-redaction-raw-storage"),
+		OTELRedactionRawColdExtract:          cfg.GetBool("otel-redaction-raw-cold-extract"),
+		OTELKafkaBrokers:                     kafkaBrokers,
+		OTELKafkaTracesTopic:                 cfg.Get("otel-kafka-traces-topic"),
+		OTELKafkaMetricsTopic:                cfg.Get("otel-kafka-metrics-topic"),
+		OTELKafkaLogsTopic:                   cfg.Get("otel-kafka-logs-topic"),
+		OTELKafkaEncoding:                    cfg.Get("otel-kafka-encoding"),
+		OTELKafkaTLSSecret:                   cfg.Get("otel-kafka-tls-secret"),
+		OTELKafkaTLSClientCertificate:        cfg.GetBool("otel-kafka-tls-client-certificate"),
+		OTELKafkaSASLUsername:                cfg.Get("otel-kafka-sasl-username"),
+		OTELKafkaSASLMechanism:               cfg.Get("otel-kafka-sasl-mechanism"),
+		OTELKafkaSASLPasswordSecret:          cfg.Get("otel-kafka-sasl-password-secret"),
+		OTELDeltaToCumulative:                cfg.GetBool("otel-delta-to-cumulative"),
+		OTELExponentialHistograms:            cfg.GetBool("otel-exponential-histograms"),
+		OTELLogsMetrics:                      cfg.GetBool("otel-logs-metrics"),
+		OTELLogsMetricsAttributes:            logsMetricsAttributes,
+		OTELLogsMetricsMinSeverity:           cfg.Get("otel-logs-metrics-min-severity"),
+		OTELLogsMetricsDedup:                 cfg.GetBool("otel-logs-metrics-dedup"),
+		OTELLogsMetricsDedupInterval:         cfg.Get("otel-logs-metrics-dedup-interval"),
+		LogShipper:                           cfg.GetBool("log-shipper"),
+		OpenShift:                            cfg.GetBool("openshift"),
+		OpenShiftRoutes:                      cfg.GetBool("openshift-routes"),
+		BaseDomain:                           cfg.Get("base-domain"),
+		JaegerHostPrefix:                     cfg.Get("jaeger-host-prefix"),
+		PersesHostPrefix:                     cfg.Get("perses-host-prefix"),
+		PrometheusHostPrefix:                 cfg.Get("prometheus-host-prefix"),
+		JaegerHost:                           cfg.Get("jaeger-host"),
+		PersesHost:                           cfg.Get("perses-host"),
+		PrometheusHost:                       cfg.Get("prometheus-host"),
+		ExposePrometheus:                     cfg.GetBool("expose-prometheus"),
+		ExternalDNS:                          cfg.GetBool("external-dns"),
+		GrafanaDatasources:                   cfg.GetBool("grafana-datasources"),
+		GrafanaDatasourcesConfigMap:          cfg.GetBool("grafana-datasources-configmap"),
+		GrafanaDatasourcesNamespace:          cfg.Get("grafana-datasources-namespace"),
+		ClusterDomain:                        cfg.Get("cluster-domain"),
+		IPFamilyPolicy:                       cfg.Get("ip-family-policy"),
+		IPFamilies:                           ipFamilies,
+		MeshProvider:                         cfg.Get("mesh-provider"),
+		MeshInject:                           cfg.GetBool("mesh-inject"),
+		MeshExcludeInboundPorts:              meshInbound,
+		MeshExcludeOutboundPorts:             meshOutbound,
+		OTELCollectorImage:                   cfg.Get("otel-collector-image"),
+		OTELCollectorComponents:              components,
+		OTELValidateConfig:                   cfg.GetBool("otel-validate-config"),
+		DevMode:                              cfg.GetBool("dev-mode"),
+		Preset:                               cfg.Get("preset"),
+		ConfigDriftAnnotations:               cfg.GetBool("config-drift-annotations"),
+		Protect:                              cfg.GetBool("protect"),
+		PauseRollouts:                        cfg.GetBool("pause-rollouts"),
+		MinReadySeconds:                      cfg.GetInt("min-ready-seconds"),
+		FreezeWindows:                        freezeWindows,
+		FreezeOverride:                       cfg.GetBool("freeze-override"),
+		PersesOrganizers:                     persesOrganizers,
+		PersesSpectators:                     persesSpectators,
+		PersesPublicProject:                  cfg.Get("perses-public-project"),
+		PersesExtraValues:                    persesExtraValues,
+		PersesForceExtraValues:               cfg.GetBool("perses-force-extra-values"),
+	}, nil
+}
+
+// optionalSecret returns the secret configuration value, or nil if not set.
+func optionalSecret(cfg *config.Config, key string) pulumi.StringInput {
+	v, err := cfg.TrySecret(key)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// existingSecret references the pre-existing Secret, if any.
+func existingSecret(name string) *parts.ExistingSecretArgs {
+	if name == "" {
+		return nil
+	}
+	return &parts.ExistingSecretArgs{
+		Name: pulumi.String(name),
+	}
+}
+
+// ingressPeers allows the given namespaces to send telemetry.
+func ingressPeers(namespaces []string) []services.IngressPeer {
+	peers := make([]services.IngressPeer, 0, len(namespaces))
+	for _, ns := range namespaces {
+		peers = append(peers, services.IngressPeer{
+			NamespaceLabels: map[string]string{
+				"kubernetes.io/metadata.name": ns,
+			},
+		})
+	}
+	return peers
+}
+
+// pvcAccessModes returns the PVC access modes, none set meaning the default
+// ones.
+func pvcAccessModes(mode string) pulumi.StringArrayInput {
+	if mode == "" {
+		return nil
+	}
+	return pulumi.ToStringArray([]string{mode})
+}
+
+// parseDuration parses the duration, an empty string meaning the default one.
+func parseDuration(str string) (time.Duration, error) {
+	if str == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(str)
+}
+
+// exporterRetry tunes the OTEL Collector exporters retries, if any of its
+// settings is set.
+func exporterRetry(cfg *Config) (*parts.ExporterRetryArgs, error) {
+	retry := &parts.ExporterRetryArgs{}
+	for _, f := range []struct {
+		Key   string
+		Value string
+		Dst   **float64
+	}{
+		{"otel-exporter-retry-multiplier", cfg.OTELExporterRetryMultiplier, &retry.Multiplier},
+		{"otel-exporter-retry-randomization-factor", cfg.OTELExporterRetryRandomization, &retry.RandomizationFactor},
+	} {
+		if f.Value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(f.Value, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid "+f.Key)
+		}
+		*f.Dst = &v
+	}
+	for _, d := range []struct {
+		Key   string
+		Value string
+		Dst   *time.Duration
+	}{
+		{"otel-exporter-retry-initial-interval", cfg.OTELExporterRetryInitialInterval, &retry.InitialInterval},
+		{"otel-exporter-retry-max-interval", cfg.OTELExporterRetryMaxInterval, &retry.MaxInterval},
+		{"otel-exporter-retry-max-elapsed-time", cfg.OTELExporterRetryMaxElapsedTime, &retry.MaxElapsedTime},
+	} {
+		v, err := parseDuration(d.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid "+d.Key)
+		}
+		*d.Dst = v
+	}
+	if *retry == (parts.ExporterRetryArgs{}) {
+		return nil, nil
+	}
+	return retry, nil
+}
+
+// jaegerQuery bounds the load the Jaeger query service accepts, if any of
+// its limits is set.
+func jaegerQuery(cfg *Config) (*parts.JaegerQueryArgs, error) {
+	query := &parts.JaegerQueryArgs{
+		MaxTraces:   cfg.JaegerQueryMaxTraces,
+		MaxLookback: cfg.JaegerQueryMaxLookback,
+	}
+	for _, d := range []struct {
+		Key   string
+		Value string
+		Dst   *time.Duration
+	}{
+		{"jaeger-query-max-clock-skew-adjust", cfg.JaegerQueryMaxClockSkewAdjust, &query.MaxClockSkewAdjust},
+		{"jaeger-query-http-read-timeout", cfg.JaegerQueryHTTPReadTimeout, &query.HTTPReadTimeout},
+		{"jaeger-query-http-write-timeout", cfg.JaegerQueryHTTPWriteTimeout, &query.HTTPWriteTimeout},
+		{"jaeger-query-grpc-max-connection-age", cfg.JaegerQueryGRPCMaxConnAge, &query.GRPCMaxConnectionAge},
+	} {
+		v, err := parseDuration(d.Value)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid "+d.Key)
+		}
+		*d.Dst = v
+	}
+	if *query == (parts.JaegerQueryArgs{}) {
+		return nil, nil
+	}
+	return query, nil
+}
+
+// jaegerArchive configures the Jaeger archive storage, if any. The Badger
+// PVC shares the storage class of the cold extract one.
+func jaegerArchive(cfg *Config) (*parts.JaegerArchiveArgs, error) {
+	switch cfg.JaegerArchive {
+	case "":
+		return nil, nil
+	case "badger":
+		return &parts.JaegerArchiveArgs{
+			Badger: &parts.JaegerBadgerArgs{
+				StorageClassName: pulumi.String(cfg.StorageClassName),
+				StorageSize:      pulumi.String(cfg.JaegerArchiveStorageSize),
+			},
+		}, nil
+	case "elasticsearch":
+		es := &parts.JaegerElasticsearchArgs{
+			ServerURLs:  cfg.JaegerArchiveESURLs,
+			IndexPrefix: cfg.JaegerArchiveESIndexPrefix,
+			Username:    cfg.JaegerArchiveESUsername,
+		}
+		if cfg.JaegerArchiveESPassword != nil {
+			es.Password = cfg.JaegerArchiveESPassword
+		}
+		if cfg.JaegerArchiveESPasswordSecret != "" {
+			es.PasswordSecret = &parts.ExistingSecretArgs{
+				Name: pulumi.String(cfg.JaegerArchiveESPasswordSecret),
+			}
+		}
+		return &parts.JaegerArchiveArgs{
+			Elasticsearch: es,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage %q, expected badger or elasticsearch", cfg.JaegerArchive)
+	}
+}
+
+// alertRules ships the default alerts, if any, into the Prometheus rule
+// files or as a PrometheusRule.
+func alertRules(cfg *Config) (*parts.AlertRulesArgs, error) {
+	args := &parts.AlertRulesArgs{
+		PrometheusRuleLabels: cfg.AlertRulesLabels,
+		Disabled:             cfg.AlertRulesDisabled,
+		PVCFullPercent:       cfg.AlertRulesPVCFullPercent,
+		For:                  cfg.AlertRulesFor,
+	}
+	switch cfg.AlertRules {
+	case "":
+		return nil, nil
+	case "rule-files":
+		return args, nil
+	case "prometheus-rule":
+		args.PrometheusRule = true
+		return args, nil
+	default:
+		return nil, fmt.Errorf("unsupported mode %q, expected rule-files or prometheus-rule", cfg.AlertRules)
+	}
+}
+
+// persesBootstrap awaits Perses to serve the global datasource, if enabled.
+func persesBootstrap(cfg *Config) (*parts.PersesBootstrapArgs, error) {
+	if !cfg.PersesBootstrap {
+		return nil, nil
+	}
+	timeout, err := parseDuration(cfg.PersesBootstrapTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &parts.PersesBootstrapArgs{
+		Timeout: timeout,
+	}, nil
+}
+
+// persesAccess turns on the Perses authentication once any user is listed,
+// for Perses to check someone organizes.
+func persesAccess(cfg *Config) *parts.PersesAccessArgs {
+	if len(cfg.PersesOrganizers) == 0 && len(cfg.PersesSpectators) == 0 {
+		return nil
+	}
+	return &parts.PersesAccessArgs{
+		Organizers:    cfg.PersesOrganizers,
+		Spectators:    cfg.PersesSpectators,
+		PublicProject: cfg.PersesPublicProject,
+	}
+}
+
+// persesDisruptionBudget evicts the Perses pods one at a time, when there
+// are several.
+func persesDisruptionBudget(replicas int) *parts.DisruptionBudgetArgs {
+	if replicas < 2 {
+		return nil
+	}
+	return &parts.DisruptionBudgetArgs{
+		MaxUnavailable: 1,
+	}
+}
+
+// tenantRouting routes the cold extract signals of the given tenants, by
+// stack name, into their own directory.
+func tenantRouting(tenants []string) *parts.TenantRoutingArgs {
+	if len(tenants) == 0 {
+		return nil
+	}
+	return &parts.TenantRoutingArgs{
+		Tenants: tenants,
+	}
+}
+
+// ingestionQuotas limits the tenants to their memory percent, sorted for
+// the configuration not to change between updates.
+func ingestionQuotas(cfg *Config) *parts.IngestionQuotasArgs {
+	if len(cfg.OTELIngestionQuotas) == 0 {
+		return nil
+	}
+	iq := &parts.IngestionQuotasArgs{
+		Attribute: cfg.OTELIngestionQuotaAttribute,
+	}
+	for _, tenant := range slices.Sorted(maps.Keys(cfg.OTELIngestionQuotas)) {
+		iq.Quotas = append(iq.Quotas, parts.TenantQuotaArgs{
+			Tenant:        tenant,
+			MemoryPercent: cfg.OTELIngestionQuotas[tenant],
+		})
+	}
+	return iq
+}
+
+// syslogReceiver turns on the OTEL Collector syslog receivers.
+func syslogReceiver(enabled bool, protocol string) *parts.SyslogReceiverArgs {
+	if !enabled {
+		return nil
+	}
+	return &parts.SyslogReceiverArgs{
+		Protocol: protocol,
+	}
+}
+
+// otelPorts sets the OTEL Collector receivers ports, if any is set.
+func otelPorts(cfg *Config) *parts.OtelPortsArgs {
+	if cfg.OTELOTLPPort == 0 && cfg.OTELStatsdPort == 0 && cfg.OTELSyslogPort == 0 {
+		return nil
+	}
+	return &parts.OtelPortsArgs{
+		OTLP:   cfg.OTELOTLPPort,
+		Statsd: cfg.OTELStatsdPort,
+		Syslog: cfg.OTELSyslogPort,
+	}
+}
+
+// redaction turns on the OTEL Collector redaction, if any of its settings
+// is set such that incomplete ones are reported rather than ignored.
+func redaction(cfg *Config) *parts.RedactionArgs {
+	r := &parts.RedactionArgs{
+		DeleteKeys:     cfg.OTELRedactionDeleteKeys,
+		MaskPatterns:   cfg.OTELRedactionMaskPatterns,
+		HashPatterns:   cfg.OTELRedactionHashPatterns,
+		RawStorage:     cfg.OTELRedactionRawStorage,
+		RawColdExtract: cfg.OTELRedactionRawColdExtract,
+	}
+	if len(r.DeleteKeys)+len(r.MaskPatterns)+len(r.HashPatterns) == 0 && !r.RawStorage && !r.RawColdExtract {
+		return nil
+	}
+	return r
+}
+
+// kafka streams the signals into Kafka if any broker or topic is set, for
+// the OTEL Collector to check the others are.
+func kafka(cfg *Config) *parts.KafkaExporterArgs {
+	k := &parts.KafkaExporterArgs{
+		Brokers: cfg.OTELKafkaBrokers,
+		Topics: parts.KafkaTopicsArgs{
+			Traces:  cfg.OTELKafkaTracesTopic,
+			Metrics: cfg.OTELKafkaMetricsTopic,
+			Logs:    cfg.OTELKafkaLogsTopic,
+		},
+		Encoding: cfg.OTELKafkaEncoding,
+	}
+	if len(k.Brokers) == 0 && k.Topics == (parts.KafkaTopicsArgs{}) {
+		return nil
+	}
+	if cfg.OTELKafkaTLSSecret != "" {
+		k.TLS = &parts.KafkaTLSArgs{
+			SecretName:        pulumi.String(cfg.OTELKafkaTLSSecret),
+			ClientCertificate: cfg.OTELKafkaTLSClientCertificate,
+		}
+	}
+	if cfg.OTELKafkaSASLUsername != "" || cfg.OTELKafkaSASLPasswordSecret != "" {
+		k.SASL = &parts.KafkaSASLArgs{
+			Username:  cfg.OTELKafkaSASLUsername,
+			Mechanism: cfg.OTELKafkaSASLMechanism,
+		}
+		if cfg.OTELKafkaSASLPasswordSecret != "" {
+			k.SASL.PasswordSecretName = pulumi.String(cfg.OTELKafkaSASLPasswordSecret)
+		}
+	}
+	return k
+}
+
+// metricsConversion returns the conversions of the metrics sent to
+// Prometheus, or nil if none is turned on.
+func metricsConversion(cfg *Config) *parts.MetricsConversionArgs {
+	if !cfg.OTELDeltaToCumulative && !cfg.OTELExponentialHistograms {
+		return nil
+	}
+	return &parts.MetricsConversionArgs{
+		DeltaToCumulative:     cfg.OTELDeltaToCumulative,
+		ExponentialHistograms: cfg.OTELExponentialHistograms,
+	}
+}
+
+// logsMetrics turns on the metrics counted from the log records, if
+// requested.
+func logsMetrics(cfg *Config) (*parts.LogsMetricsArgs, error) {
+	if !cfg.OTELLogsMetrics {
+		return nil, nil
+	}
+	dedupInterval, err := parseDuration(cfg.OTELLogsMetricsDedupInterval)
+	if err != nil {
+		return nil, err
+	}
+	return &parts.LogsMetricsArgs{
+		Attributes:    cfg.OTELLogsMetricsAttributes,
+		MinSeverity:   cfg.OTELLogsMetricsMinSeverity,
+		Dedup:         cfg.OTELLogsMetricsDedup,
+		DedupInterval: dedupInterval,
+	}, nil
+}
+
+// remoteWriteWAL turns on the OTEL Collector remote write WAL, if requested.
+func remoteWriteWAL(cfg *Config) (*parts.RemoteWriteWALArgs, error) {
+	if !cfg.OTELRemoteWriteWAL {
+		return nil, nil
+	}
+	truncateFrequency, err := parseDuration(cfg.OTELRemoteWriteWALTruncateFreq)
+	if err != nil {
+		return nil, err
+	}
+	return &parts.RemoteWriteWALArgs{
+		Directory:         cfg.OTELRemoteWriteWALDirectory,
+		BufferSize:        cfg.OTELRemoteWriteWALBufferSize,
+		TruncateFrequency: truncateFrequency,
+		SizeLimit:         cfg.OTELRemoteWriteWALSizeLimit,
+	}, nil
+}
+
+// annotationScrape turns on the Prometheus annotation scrape in the given
+// namespaces, if any.
+func annotationScrape(namespaces []string) *parts.AnnotationScrapeArgs {
+	if len(namespaces) == 0 {
+		return nil
+	}
+	return &parts.AnnotationScrapeArgs{
+		Namespaces: namespaces,
+	}
+}
+
+// ipFamily sets the IP family of the Services, if either the policy or the
+// families are set.
+func ipFamily(policy string, families []string) *parts.IPFamilyArgs {
+	if policy == "" && len(families) == 0 {
+		return nil
+	}
+	return &parts.IPFamilyArgs{
+		Policy:   policy,
+		Families: families,
+	}
+}
+
+// mesh annotates the pods for the service mesh, if its provider is set.
+func mesh(cfg *Config) *parts.MeshArgs {
+	if cfg.MeshProvider == "" {
+		return nil
+	}
+	return &parts.MeshArgs{
+		Provider:             cfg.MeshProvider,
+		Inject:               cfg.MeshInject,
+		ExcludeInboundPorts:  cfg.MeshExcludeInboundPorts,
+		ExcludeOutboundPorts: cfg.MeshExcludeOutboundPorts,
+	}
+}
+
+// logShipper turns on the log shipper, with its defaults.
+func logShipper(enabled bool) *parts.LogShipperArgs {
+	if !enabled {
+		return nil
+	}
+	return &parts.LogShipperArgs{}
+}
+
+// receiverTLS turns on the OTEL Collector receiver TLS, which mutual TLS implies.
+func receiverTLS(tls, mtls bool) *parts.ReceiverTLSArgs {
+	if !tls && !mtls {
+		return nil
+	}
+	return &parts.ReceiverTLSArgs{
+		RequireClientCertificate: mtls,
+	}
+}
+
+// hosts derives the hosts of the Routes, if a base domain or any host is
+// set.
+func hosts(cfg *Config) *services.HostsArgs {
+	if cfg.BaseDomain == "" && cfg.JaegerHost == "" && cfg.PersesHost == "" && cfg.PrometheusHost == "" {
+		return nil
+	}
+	return &services.HostsArgs{
+		BaseDomain:       cfg.BaseDomain,
+		JaegerPrefix:     cfg.JaegerHostPrefix,
+		PersesPrefix:     cfg.PersesHostPrefix,
+		PrometheusPrefix: cfg.PrometheusHostPrefix,
+		JaegerHost:       cfg.JaegerHost,
+		PersesHost:       cfg.PersesHost,
+		PrometheusHost:   cfg.PrometheusHost,
+		ExposePrometheus: cfg.ExposePrometheus,
+		ExternalDNS:      cfg.ExternalDNS,
+	}
+}
+
+// grafanaDatasources renders the Grafana datasources provisioning, also
+// recorded in a ConfigMap if requested.
+func grafanaDatasources(cfg *Config) *services.GrafanaDatasourcesArgs {
+	if !cfg.GrafanaDatasources {
+		return nil
+	}
+	return &services.GrafanaDatasourcesArgs{
+		ConfigMap:          cfg.GrafanaDatasourcesConfigMap,
+		ConfigMapNamespace: cfg.GrafanaDatasourcesNamespace,
+	}
+}
+
+// openShift turns on the OpenShift compatibility, which the Routes imply.
+func openShift(openshift, routes bool) *services.OpenShiftArgs {
+	if !openshift && !routes {
+		return nil
+	}
+	return &services.OpenShiftArgs{
+		Routes: routes,
 	}
 }
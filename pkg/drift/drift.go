@@ -0,0 +1,164 @@
+// Package drift detects the Monitoring ConfigMaps edited in the cluster since
+// they were deployed, e.g. hot-patched during an incident, for the next update
+// not to revert them silently.
+package drift
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+
+	"github.com/pmezard/go-difflib/difflib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/internal/kubeclient"
+)
+
+// HashAnnotation is the annotation the Monitoring stamps on its ConfigMaps
+// with the Hash of their content, when deployed.
+const HashAnnotation = "ctfer.io/config-hash"
+
+var (
+	// monitoringSelector matches the resources deployed by the Monitoring.
+	monitoringSelector = labels.Set{
+		"app.kubernetes.io/part-of": "monitoring",
+	}
+
+	// componentLabel tells apart the parts of the Monitoring.
+	componentLabel = "app.kubernetes.io/component"
+)
+
+// ConfigMap is the live content of a Monitoring ConfigMap.
+type ConfigMap struct {
+	Name      string
+	Component string
+	Data      map[string]string
+
+	// Hash is the HashAnnotation stamped when deployed, if any.
+	Hash string
+}
+
+// Report is the drift of the Monitoring ConfigMaps.
+type Report struct {
+	ConfigMaps []ConfigMapDrift
+}
+
+// ConfigMapDrift is the drift of a ConfigMap.
+type ConfigMapDrift struct {
+	Name      string
+	Component string
+
+	// Stamped is whether the ConfigMap has a HashAnnotation, without which
+	// the edits could not be told.
+	Stamped bool
+
+	// Edited is whether the content no longer matches the HashAnnotation.
+	Edited bool
+
+	// Diff is the unified diff from the live content to the rendered one,
+	// i.e. what the next update would change. Empty if they are the same or
+	// nothing is rendered for the component.
+	Diff string
+}
+
+// Hash returns the hash of the ConfigMap data, whatever the order of its keys.
+func Hash(data map[string]string) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		// Separate the keys and values such that they could not shift
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(data[k]))
+		h.Write([]byte{0})
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Live lists the ConfigMaps of the Monitoring in the namespace, sorted. The
+// cluster is reached through the context of the kubeconfig, its current one
+// if empty.
+func Live(ctx context.Context, kubeContext, namespace string) ([]ConfigMap, error) {
+	client, err := kubeclient.New(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return live(ctx, client, namespace)
+}
+
+func live(ctx context.Context, client kubernetes.Interface, namespace string) ([]ConfigMap, error) {
+	cms, err := client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: monitoringSelector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ConfigMap, 0, len(cms.Items))
+	for _, cm := range cms.Items {
+		out = append(out, ConfigMap{
+			Name:      cm.Name,
+			Component: cm.Labels[componentLabel],
+			Data:      cm.Data,
+			Hash:      cm.Annotations[HashAnnotation],
+		})
+	}
+	slices.SortFunc(out, func(a, b ConfigMap) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return out, nil
+}
+
+// Compare checks the live ConfigMaps against their HashAnnotation, and diffs
+// their content at key against the rendered one of their component (e.g.
+// otel-collector). The ConfigMaps neither stamped nor rendered are skipped.
+func Compare(live []ConfigMap, key string, rendered map[string]string) (*Report, error) {
+	rep := &Report{
+		ConfigMaps: []ConfigMapDrift{},
+	}
+	for _, cm := range live {
+		expected, ok := rendered[cm.Component]
+		if cm.Hash == "" && !ok {
+			continue
+		}
+
+		d := ConfigMapDrift{
+			Name:      cm.Name,
+			Component: cm.Component,
+			Stamped:   cm.Hash != "",
+			Edited:    cm.Hash != "" && cm.Hash != Hash(cm.Data),
+		}
+		if ok {
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(cm.Data[key]),
+				B:        difflib.SplitLines(expected),
+				FromFile: "live/" + cm.Name,
+				ToFile:   "rendered/" + cm.Name,
+				Context:  3,
+			})
+			if err != nil {
+				return nil, err
+			}
+			d.Diff = diff
+		}
+		rep.ConfigMaps = append(rep.ConfigMaps, d)
+	}
+	return rep, nil
+}
+
+// Edited returns whether any ConfigMap was edited since deployed, i.e. the
+// next update would revert it.
+func (rep *Report) Edited() bool {
+	return slices.ContainsFunc(rep.ConfigMaps, func(d ConfigMapDrift) bool {
+		return d.Edited
+	})
+}
@@ -0,0 +1,120 @@
+package drift
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_U_Hash(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		A, B        map[string]string
+		ExpectEqual bool
+	}{
+		"same": {
+			A:           map[string]string{"config": "a: 1\n", "other": "b"},
+			B:           map[string]string{"other": "b", "config": "a: 1\n"},
+			ExpectEqual: true,
+		},
+		"edited": {
+			A: map[string]string{"config": "a: 1\n"},
+			B: map[string]string{"config": "a: 2\n"},
+		},
+		"shifted": {
+			A: map[string]string{"ab": "c"},
+			B: map[string]string{"a": "bc"},
+		},
+		"empty": {
+			A:           nil,
+			B:           map[string]string{},
+			ExpectEqual: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			a, b := Hash(tt.A), Hash(tt.B)
+			if !strings.HasPrefix(a, "sha256:") {
+				t.Errorf("expected a sha256 hash, got %s", a)
+			}
+			if (a == b) != tt.ExpectEqual {
+				t.Errorf("expected equal hashes: %t, got %s and %s", tt.ExpectEqual, a, b)
+			}
+		})
+	}
+}
+
+func Test_U_Compare(t *testing.T) {
+	t.Parallel()
+
+	deployed := map[string]string{"config": "receivers:\n  otlp: {}\n"}
+	patched := map[string]string{"config": "receivers:\n  otlp: {}\n  statsd: {}\n"}
+	client := fake.NewClientset(
+		configMap("otel-config-1234", "otel-collector", patched, Hash(deployed)),
+		configMap("prometheus-conf-5678", "prometheus", map[string]string{"config": "scrape_configs: []\n"}, Hash(map[string]string{"config": "scrape_configs: []\n"})),
+		configMap("perses-config", "perses", map[string]string{"config": "{}"}, ""),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring-abcdefgh", Name: "kube-root-ca.crt"},
+		},
+	)
+
+	live, err := live(context.Background(), client, "monitoring-abcdefgh")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(live) != 3 {
+		t.Fatalf("expected the 3 monitoring ConfigMaps, got %d", len(live))
+	}
+
+	rep, err := Compare(live, "config", map[string]string{
+		"otel-collector": deployed["config"],
+		"prometheus":     "scrape_configs: []\n",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The unstamped and unrendered Perses one is skipped
+	if len(rep.ConfigMaps) != 2 {
+		t.Fatalf("expected 2 ConfigMaps, got %+v", rep.ConfigMaps)
+	}
+	otel, prom := rep.ConfigMaps[0], rep.ConfigMaps[1]
+	if !otel.Stamped || !otel.Edited {
+		t.Errorf("expected the otel collector config to be edited, got %+v", otel)
+	}
+	if !strings.Contains(otel.Diff, "--- live/otel-config-1234") || !strings.Contains(otel.Diff, "-  statsd: {}") {
+		t.Errorf("expected the update to revert the patch, got:\n%s", otel.Diff)
+	}
+	if !prom.Stamped || prom.Edited || prom.Diff != "" {
+		t.Errorf("expected the prometheus config to be unchanged, got %+v", prom)
+	}
+	if !rep.Edited() {
+		t.Error("expected the report to be edited")
+	}
+}
+
+func configMap(name, component string, data map[string]string, hash string) *corev1.ConfigMap {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring-abcdefgh",
+			Name:      name,
+			Labels: map[string]string{
+				"app.kubernetes.io/part-of": "monitoring",
+				componentLabel:              component,
+			},
+		},
+		Data: data,
+	}
+	if hash != "" {
+		cm.Annotations = map[string]string{HashAnnotation: hash}
+	}
+	return cm
+}
@@ -0,0 +1,157 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// unlabeledComponent keys the PVCs without a component label.
+const unlabeledComponent = "unknown"
+
+// PVCResult is the extraction of a PVC among every one of the namespace.
+// Its own report lies in its directory.
+type PVCResult struct {
+	PVCName   string `json:"pvc_name"`
+	Directory string `json:"directory"`
+	Files     int    `json:"files"`
+	Bytes     int64  `json:"bytes"`
+
+	// Error is the reason the extraction failed, if it did. The other PVCs
+	// are extracted anyway.
+	Error string `json:"error,omitempty"`
+
+	// Diagnostics are the logs and events of its extraction Pod, if it
+	// failed.
+	Diagnostics *PodDiagnostics `json:"diagnostics,omitempty"`
+}
+
+// DumpAll extracts every PVC of the Monitoring in the namespace (see
+// DiscoverAll), each into its subdirectory named after it, as
+// DumpOTelCollector would. A failing PVC does not abort the others: it is
+// reported in the combined result, written as the report file at the root
+// of the directory, and the error returned once all are extracted.
+func DumpAll(
+	ctx context.Context,
+	namespace, into string,
+	opts ...Option,
+) (*Result, error) {
+	// Check the options once for all, rather than failing every PVC
+	options := &options{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.record != "" {
+		return nil, errors.New("could not record every PVC at once")
+	}
+	if options.sink != nil {
+		return nil, errors.New("could not extract every PVC into a single sink")
+	}
+
+	targets, err := DiscoverAll(ctx, namespace, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		if options.pvcSelector != "" {
+			return nil, fmt.Errorf("no monitoring PVC matching %s in namespace %s", options.pvcSelector, namespace)
+		}
+		return nil, fmt.Errorf("no monitoring PVC in namespace %s", namespace)
+	}
+
+	res, err := dumpAll(ctx, namespace, into, targets, options.logger, func(ctx context.Context, tgt Target, into string) (*Result, error) {
+		source := SourcePVC
+		if tgt.Component == signalsSelector[componentLabel] {
+			source = SourceOTelCollector
+		}
+		return dumpPVC(ctx, source, tgt.Namespace, tgt.PVCName, into, opts...)
+	})
+	if res == nil {
+		return nil, err
+	}
+	res.Synced = options.fsync
+	sink := NewDirectorySink(into)
+	sink.fsync = options.fsync
+	if ferr := res.finish(ctx, sink); ferr != nil {
+		return nil, ferr
+	}
+	return res, err
+}
+
+// dumpAll extracts the targets one after the other, as the extraction pod
+// is unique in the namespace, through the dump function.
+func dumpAll(
+	ctx context.Context,
+	namespace, into string,
+	targets []Target,
+	logger *zap.Logger,
+	dump func(ctx context.Context, tgt Target, into string) (*Result, error),
+) (*Result, error) {
+	res := &Result{
+		Source:     SourceAll,
+		Namespace:  namespace,
+		Directory:  into,
+		StartedAt:  time.Now(),
+		Components: map[string][]PVCResult{},
+	}
+
+	failed := 0
+	for _, tgt := range targets {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		component := tgt.Component
+		if component == "" {
+			component = unlabeledComponent
+		}
+		logger.Info("extracting PVC",
+			zap.String("pvc", tgt.PVCName),
+			zap.String("component", component),
+		)
+
+		pvcRes := PVCResult{
+			PVCName:   tgt.PVCName,
+			Directory: filepath.Join(into, tgt.PVCName),
+		}
+		sub, err := dump(ctx, tgt, pvcRes.Directory)
+		if sub != nil {
+			pvcRes.Files, pvcRes.Bytes = sub.Files, sub.Bytes
+			pvcRes.Diagnostics = sub.Diagnostics
+			res.Files += sub.Files
+			res.Bytes += sub.Bytes
+			for _, f := range sub.Inventory {
+				f.Path = path.Join(tgt.PVCName, f.Path)
+				res.Inventory = append(res.Inventory, f)
+			}
+			for _, warn := range sub.Warnings {
+				res.Warnings = append(res.Warnings, tgt.PVCName+": "+warn)
+			}
+		}
+		if err != nil {
+			failed++
+			pvcRes.Error = err.Error()
+			logger.Error("extracting PVC",
+				zap.String("pvc", tgt.PVCName),
+				zap.Error(err),
+			)
+		}
+		res.Components[component] = append(res.Components[component], pvcRes)
+	}
+	res.Inventory = sortedInventory(res.Inventory)
+
+	if failed != 0 {
+		return res, fmt.Errorf("%d of %d PVCs failed to extract", failed, len(targets))
+	}
+	return res, nil
+}
@@ -0,0 +1,97 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_U_DumpAll(t *testing.T) {
+	t.Parallel()
+
+	targets := []Target{
+		{Namespace: "monitoring", PVCName: "jaeger-archive", Component: "jaeger"},
+		{Namespace: "monitoring", PVCName: "other"},
+		{Namespace: "monitoring", PVCName: "signals", Component: "otel-collector"},
+	}
+	into := t.TempDir()
+
+	// The Jaeger archive one fails, the others are extracted anyway
+	dumped := []string{}
+	res, err := dumpAll(context.Background(), "monitoring", into, targets, zap.NewNop(), func(_ context.Context, tgt Target, dir string) (*Result, error) {
+		dumped = append(dumped, dir)
+		if tgt.Component == "jaeger" {
+			return nil, errors.New("pod not ready")
+		}
+		return &Result{
+			Files:    2,
+			Bytes:    1024,
+			Warnings: []string{"something odd"},
+		}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Errorf("expected a partial failure, got %v", err)
+	}
+	if len(dumped) != len(targets) {
+		t.Fatalf("expected every PVC to be extracted, got %v", dumped)
+	}
+	for i, tgt := range targets {
+		if expected := filepath.Join(into, tgt.PVCName); dumped[i] != expected {
+			t.Errorf("expected %s to be extracted into %s, got %s", tgt.PVCName, expected, dumped[i])
+		}
+	}
+
+	if res.Source != SourceAll || res.Files != 4 || res.Bytes != 2048 {
+		t.Errorf("expected the combined totals, got %+v", res)
+	}
+	if len(res.Warnings) != 2 || !strings.HasPrefix(res.Warnings[0], "other: ") {
+		t.Errorf("expected the warnings prefixed by their PVC, got %v", res.Warnings)
+	}
+	if jaeger := res.Components["jaeger"]; len(jaeger) != 1 || jaeger[0].Error != "pod not ready" {
+		t.Errorf("expected the jaeger failure to be reported, got %+v", jaeger)
+	}
+	if otel := res.Components["otel-collector"]; len(otel) != 1 || otel[0].Error != "" || otel[0].Files != 2 {
+		t.Errorf("expected the otel-collector extraction to be reported, got %+v", otel)
+	}
+	if len(res.Components[unlabeledComponent]) != 1 {
+		t.Errorf("expected the unlabeled PVC to be reported, got %+v", res.Components)
+	}
+
+	// The summary reports each PVC
+	levels := map[string]string{}
+	for _, line := range res.summarize() {
+		levels[line.Text] = line.Level
+	}
+	if levels["jaeger PVC jaeger-archive: pod not ready"] != SummaryError {
+		t.Errorf("expected the jaeger failure in the summary, got %v", levels)
+	}
+	if levels["otel-collector PVC signals: 2 files (1.0 KiB)"] != SummaryInfo {
+		t.Errorf("expected the otel-collector extraction in the summary, got %v", levels)
+	}
+}
+
+func Test_U_DumpAll_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	targets := []Target{
+		{Namespace: "monitoring", PVCName: "a"},
+		{Namespace: "monitoring", PVCName: "b"},
+	}
+	dumped := 0
+	_, err := dumpAll(ctx, "monitoring", t.TempDir(), targets, zap.NewNop(), func(context.Context, Target, string) (*Result, error) {
+		dumped++
+		cancel()
+		return &Result{}, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation, got %v", err)
+	}
+	if dumped != 1 {
+		t.Errorf("expected the extraction to stop once canceled, got %d PVCs", dumped)
+	}
+}
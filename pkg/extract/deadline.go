@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrDeadlineExceeded is returned when the copy does not complete in time,
+// e.g. stalled on an NFS issue. It is distinct from the cancellation of the
+// extraction, or any other failure.
+var ErrDeadlineExceeded = errors.New("extraction deadline exceeded")
+
+const (
+	// defaultThroughput is the copy throughput assumed to estimate the auto
+	// deadline without bandwidth limit, in bytes per second. It is a low
+	// estimate, for slow API servers not to be mistaken for stalled copies.
+	defaultThroughput = 5 << 20
+
+	// deadlineFactor and deadlineMargin make up for the throughput
+	// variations, and the small copies dominated by their setup.
+	deadlineFactor = 2
+	deadlineMargin = time.Minute
+
+	// usageTimeout bounds the measure of the disk usage, which stalls the
+	// same way as the copy.
+	usageTimeout = time.Minute
+)
+
+// estimateDeadline returns the time a copy of usage bytes should take at
+// most, at the bandwidth limit if any or the default throughput otherwise.
+func estimateDeadline(usage int64, bandwidthLimit int) time.Duration {
+	throughput := int64(defaultThroughput)
+	if bandwidthLimit > 0 {
+		throughput = int64(bandwidthLimit)
+	}
+	estimate := time.Duration(usage / throughput * int64(time.Second))
+	return deadlineFactor*estimate + deadlineMargin
+}
+
+// copyDeadline returns the deadline of the copy of podPath, zero meaning
+// none. The auto deadline measures the disk usage of podPath in the pod,
+// and is capped by the max duration if any.
+func copyDeadline(ctx context.Context, exec podExecutor, podPath string, options *options) (time.Duration, error) {
+	if !options.autoDeadline {
+		return options.maxDuration, nil
+	}
+	usage, err := diskUsage(ctx, exec, podPath)
+	if err != nil {
+		return 0, fmt.Errorf("measuring disk usage of %s: %w", podPath, err)
+	}
+	deadline := estimateDeadline(usage, options.bandwidthLimit)
+	if options.maxDuration > 0 && deadline > options.maxDuration {
+		deadline = options.maxDuration
+	}
+	options.logger.Info("estimated copy deadline",
+		zap.Int64("usage_bytes", usage),
+		zap.Duration("deadline", deadline),
+	)
+	return deadline, nil
+}
+
+// diskUsage returns the disk usage of podPath in the pod, in bytes.
+func diskUsage(ctx context.Context, exec podExecutor, podPath string) (int64, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, usageTimeout,
+		fmt.Errorf("%w: disk usage not measured within %s", ErrDeadlineExceeded, usageTimeout))
+	defer cancel()
+
+	out := &bytes.Buffer{}
+	if err := exec(ctx, duCommand(podPath), out); err != nil {
+		if cause := context.Cause(ctx); errors.Is(cause, ErrDeadlineExceeded) {
+			return 0, cause
+		}
+		return 0, err
+	}
+	return parseDiskUsage(out.String())
+}
+
+// duCommand returns the command printing the disk usage of podPath, in KiB.
+func duCommand(podPath string) []string {
+	return []string{"du", "-sk", podPath}
+}
+
+// parseDiskUsage parses the output of du -sk, in bytes.
+func parseDiskUsage(out string) (int64, error) {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return 0, errors.New("empty disk usage")
+	}
+	kib, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || kib < 0 {
+		return 0, fmt.Errorf("invalid disk usage %q", fields[0])
+	}
+	return kib << 10, nil
+}
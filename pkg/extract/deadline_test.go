@@ -0,0 +1,220 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func Test_U_EstimateDeadline(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Usage          int64
+		BandwidthLimit int
+		Expected       time.Duration
+	}{
+		"empty": {
+			Usage:    0,
+			Expected: deadlineMargin,
+		},
+		"default-throughput": {
+			Usage:    60 * defaultThroughput,
+			Expected: 2*time.Minute + deadlineMargin,
+		},
+		"bandwidth-limit": {
+			Usage:          30 << 20,
+			BandwidthLimit: 1 << 20,
+			Expected:       time.Minute + deadlineMargin,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if d := estimateDeadline(tt.Usage, tt.BandwidthLimit); d != tt.Expected {
+				t.Errorf("expected deadline %s, got %s", tt.Expected, d)
+			}
+		})
+	}
+}
+
+func Test_U_ParseDiskUsage(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Output    string
+		Expected  int64
+		ExpectErr bool
+	}{
+		"valid": {
+			Output:   "2048\t/data\n",
+			Expected: 2 << 20,
+		},
+		"empty": {
+			Output:    "",
+			ExpectErr: true,
+		},
+		"invalid": {
+			Output:    "du: /data: Stale file handle\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			usage, err := parseDiskUsage(tt.Output)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if usage != tt.Expected {
+				t.Errorf("expected usage %d, got %d", tt.Expected, usage)
+			}
+		})
+	}
+}
+
+func Test_U_CopyDeadline(t *testing.T) {
+	t.Parallel()
+
+	// 30MiB to copy at 1MiB/s
+	exec := func(_ context.Context, command []string, stdout io.Writer) error {
+		if !slices.Equal(command, duCommand("/data")) {
+			t.Errorf("unexpected command %v", command)
+		}
+		_, err := io.WriteString(stdout, "30720\t/data\n")
+		return err
+	}
+
+	var tests = map[string]struct {
+		Options  *options
+		Expected time.Duration
+	}{
+		"none": {
+			Options:  &options{},
+			Expected: 0,
+		},
+		"max-duration": {
+			Options:  &options{maxDuration: time.Hour},
+			Expected: time.Hour,
+		},
+		"auto": {
+			Options:  &options{autoDeadline: true, bandwidthLimit: 1 << 20},
+			Expected: 2 * time.Minute,
+		},
+		"auto-capped": {
+			Options:  &options{autoDeadline: true, bandwidthLimit: 1 << 20, maxDuration: 90 * time.Second},
+			Expected: 90 * time.Second,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			tt.Options.logger = zap.NewNop()
+			d, err := copyDeadline(context.Background(), exec, "/data", tt.Options)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if d != tt.Expected {
+				t.Errorf("expected deadline %s, got %s", tt.Expected, d)
+			}
+		})
+	}
+}
+
+func Test_U_CopyFromPod_Deadline(t *testing.T) {
+	t.Parallel()
+
+	archive, _ := tarFixture(t, 1, 2, 1024)
+
+	var tests = map[string]struct {
+		// IgnoreCancel makes the stalled exec not return on cancellation,
+		// as one blocked in the kernel.
+		Stall         bool
+		IgnoreCancel  bool
+		Cancel        bool
+		ExpectErr     bool
+		ExpectTimeout bool
+	}{
+		"completed": {},
+		"stalled": {
+			Stall:         true,
+			ExpectErr:     true,
+			ExpectTimeout: true,
+		},
+		"stalled-ignoring-cancel": {
+			Stall:         true,
+			IgnoreCancel:  true,
+			ExpectErr:     true,
+			ExpectTimeout: true,
+		},
+		"canceled": {
+			Stall:     true,
+			Cancel:    true,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			release := make(chan struct{})
+			defer close(release)
+			exec := func(ctx context.Context, _ []string, stdout io.Writer) error {
+				if !tt.Stall {
+					_, err := stdout.Write(archive)
+					return err
+				}
+				// Send the first file header then block, as on a stale NFS
+				if _, err := stdout.Write(archive[:512]); err != nil {
+					return err
+				}
+				if tt.IgnoreCancel {
+					<-release
+					return nil
+				}
+				<-ctx.Done()
+				return ctx.Err()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.Cancel {
+				time.AfterFunc(50*time.Millisecond, cancel)
+			}
+
+			// Short enough to stall, long enough to complete
+			maxDuration := 200 * time.Millisecond
+			if !tt.Stall {
+				maxDuration = time.Minute
+			}
+
+			start := time.Now()
+			_, err := copyFromPod(ctx, exec, "/data", NewDirectorySink(t.TempDir()), &options{
+				logger:      zap.NewNop(),
+				workers:     1,
+				maxDuration: maxDuration,
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if errors.Is(err, ErrDeadlineExceeded) != tt.ExpectTimeout {
+				t.Errorf("expected deadline exceeded: %t, got: %v", tt.ExpectTimeout, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("expected the copy to be interrupted, took %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package extract
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+)
+
+// defaultDecompressLimit is the size the decompressed files total at most.
+const defaultDecompressLimit = 16 << 30
+
+// DecompressedFile records a compressed file landed decompressed locally.
+type DecompressedFile struct {
+	// Path of the decompressed file, relative to the extraction directory.
+	Path string `json:"path"`
+	// OriginalBytes is the size of the compressed file.
+	OriginalBytes int64 `json:"original_bytes"`
+	// DecompressedBytes is the size of the decompressed file.
+	DecompressedBytes int64 `json:"decompressed_bytes"`
+}
+
+// decompressors indexes by suffix the readers of the supported formats.
+var decompressors = map[string]func(io.Reader) (io.ReadCloser, error){
+	".gz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	".zst": func(r io.Reader) (io.ReadCloser, error) {
+		dec, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return dec.IOReadCloser(), nil
+	},
+}
+
+// decompressAll decompresses in place the compressed files of the
+// inventory, i.e. the ones this run extracted into dir, with their suffix
+// stripped. They total at most limit bytes once decompressed, such that a
+// compression bomb could not fill the disk.
+// Corrupted files, as the ones over the limit, are kept as-is and reported
+// as warnings, rather than failing the whole extraction.
+func decompressAll(dir string, inventory []InventoryFile, limit int64, logger *zap.Logger) (files []DecompressedFile, warnings []string, err error) {
+	for _, inv := range inventory {
+		ext := path.Ext(inv.Path)
+		if _, ok := decompressors[ext]; !ok {
+			continue
+		}
+
+		p := filepath.Join(dir, filepath.FromSlash(inv.Path))
+		f, err := decompressFile(p, ext, limit)
+		if err != nil {
+			logger.Warn("keeping compressed file",
+				zap.String("file", p),
+				zap.Error(err),
+			)
+			warnings = append(warnings, fmt.Sprintf("kept compressed file %s: %s", p, err))
+			continue
+		}
+		limit -= f.DecompressedBytes
+		f.Path = strings.TrimSuffix(inv.Path, ext)
+		files = append(files, *f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return
+}
+
+// decompressFile decompresses src next to it, with the ext suffix stripped,
+// and removes src once done.
+// On error, e.g. when decompressing more than limit bytes, src is left
+// untouched and no partial file remains.
+func decompressFile(src, ext string, limit int64) (*DecompressedFile, error) {
+	dst := strings.TrimSuffix(src, ext)
+	if _, err := os.Stat(dst); err == nil {
+		return nil, fmt.Errorf("%s already exists", dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	stat, err := in.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := decompressors[ext](in)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Write to a temporary file so a corrupted input leaves nothing behind
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".decompress-*")
+	if err != nil {
+		return nil, err
+	}
+	// Read one byte past the limit to tell whether it is exceeded
+	n, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if err == nil && n > limit {
+		err = fmt.Errorf("exceeds the decompression limit of %d bytes", limit)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+	if err := os.Remove(src); err != nil {
+		return nil, err
+	}
+	return &DecompressedFile{
+		OriginalBytes:     stat.Size(),
+		DecompressedBytes: n,
+	}, nil
+}
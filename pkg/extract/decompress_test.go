@@ -0,0 +1,166 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+)
+
+func Test_U_DecompressAll(t *testing.T) {
+	t.Parallel()
+
+	content := []byte(`{"resourceSpans":[]}` + "\n")
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "collector"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"collector/otel_traces.gz":   gzipped(t, content),
+		"collector/otel_metrics.zst": zstded(t, content),
+		"collector/otel_logs.zst":    []byte("not zstd"),
+		"collector/otel_raw":         content,
+	}
+	inventory := []InventoryFile{}
+	for p, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, p), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		inventory = append(inventory, InventoryFile{Path: p, Bytes: int64(len(b))})
+	}
+	// Files of previous runs are left untouched
+	previous := gzipped(t, content)
+	if err := os.WriteFile(filepath.Join(dir, "collector/otel_previous.gz"), previous, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	decompressed, warnings, err := decompressAll(dir, sortedInventory(inventory), defaultDecompressLimit, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Valid files are decompressed with their suffix stripped
+	if len(decompressed) != 2 {
+		t.Fatalf("expected 2 decompressed files, got %v", decompressed)
+	}
+	for i, p := range []string{"collector/otel_metrics.zst", "collector/otel_traces.gz"} {
+		src := p
+		p = strings.TrimSuffix(p, filepath.Ext(p))
+		if decompressed[i].Path != p {
+			t.Errorf("expected %s to be decompressed, got %s", p, decompressed[i].Path)
+		}
+		if decompressed[i].OriginalBytes != int64(len(files[src])) {
+			t.Errorf("expected original size %d for %s, got %d", len(files[src]), p, decompressed[i].OriginalBytes)
+		}
+		if decompressed[i].DecompressedBytes != int64(len(content)) {
+			t.Errorf("expected decompressed size %d for %s, got %d", len(content), p, decompressed[i].DecompressedBytes)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, p))
+		if err != nil {
+			t.Fatalf("reading decompressed file: %s", err)
+		}
+		if !bytes.Equal(b, content) {
+			t.Errorf("unexpected content for %s: %q", p, b)
+		}
+	}
+	for _, p := range []string{"collector/otel_traces.gz", "collector/otel_metrics.zst"} {
+		if _, err := os.Stat(filepath.Join(dir, p)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", p, err)
+		}
+	}
+
+	// Corrupted files are kept as-is, with a warning
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "collector/otel_logs.zst")); err != nil || string(b) != "not zstd" {
+		t.Errorf("expected the corrupted file to be kept, got %q (%v)", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "collector/otel_logs")); !os.IsNotExist(err) {
+		t.Errorf("expected no partial file to remain, got %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "collector"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("expected 5 files to remain, got %d", len(entries))
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "collector/otel_previous.gz")); err != nil || !bytes.Equal(b, previous) {
+		t.Errorf("expected the file of a previous run to be kept, got %q (%v)", b, err)
+	}
+}
+
+func Test_U_DecompressAll_Limit(t *testing.T) {
+	t.Parallel()
+
+	// A small file compressing a large content, as a compression bomb does
+	bomb := bytes.Repeat([]byte{0}, 1<<20)
+	small := []byte("small\n")
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.gz": gzipped(t, small),
+		"b.gz": gzipped(t, bomb),
+		"c.gz": gzipped(t, small),
+	}
+	inventory := []InventoryFile{}
+	for p, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, p), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		inventory = append(inventory, InventoryFile{Path: p, Bytes: int64(len(b))})
+	}
+
+	decompressed, warnings, err := decompressAll(dir, sortedInventory(inventory), 1<<10, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The files within the limit are decompressed, the bomb is kept
+	if len(decompressed) != 2 || decompressed[0].Path != "a" || decompressed[1].Path != "c" {
+		t.Fatalf("expected a and c to be decompressed, got %v", decompressed)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "decompression limit") {
+		t.Fatalf("expected a warning about the limit, got %v", warnings)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "b.gz")); err != nil || !bytes.Equal(b, files["b.gz"]) {
+		t.Errorf("expected the bomb to be kept compressed, got %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected no partial file to remain, got %d files", len(entries))
+	}
+}
+
+func gzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, b []byte) []byte {
+	t.Helper()
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer enc.Close()
+	return enc.EncodeAll(b, nil)
+}
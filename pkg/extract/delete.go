@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrPodLingering is returned when the extraction Pod is still there once
+// the delete timeout elapsed, e.g. stuck on a finalizer or a CSI detach. The
+// next extraction in the namespace could not create its own until it is
+// gone.
+var ErrPodLingering = errors.New("extraction pod lingering")
+
+// defaultDeleteTimeout is how long the Pod is awaited to disappear once
+// deleted, then once force deleted.
+const defaultDeleteTimeout = 2 * time.Minute
+
+// deleteExtractor deletes the Pod, or the Job controlling it along with it,
+// and waits for the Pod to be gone. A lingering one is force deleted if
+// requested, else reported with ErrPodLingering.
+func deleteExtractor(ctx context.Context, clientset kubernetes.Interface, namespace, pod string, options *options) error {
+	if err := retryDelete(ctx, defaultBackoff, func() error {
+		if options.gcAfter == 0 {
+			return clientset.CoreV1().Pods(namespace).Delete(ctx, pod, metav1.DeleteOptions{})
+		}
+		return clientset.BatchV1().Jobs(namespace).Delete(ctx, jobName, metav1.DeleteOptions{
+			PropagationPolicy: ptr(metav1.DeletePropagationBackground),
+		})
+	}); err != nil {
+		return err
+	}
+
+	lingering, err := waitForPodGone(ctx, clientset, namespace, pod, options.deleteTimeout)
+	if err != nil || lingering == nil {
+		return err
+	}
+	if !options.forceDelete {
+		return lingeringError(lingering, options.deleteTimeout, false)
+	}
+
+	// Skip the graceful termination, the kubelet may never confirm it
+	options.logger.Warn("force deleting the lingering pod",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+		zap.Strings("finalizers", lingering.Finalizers),
+	)
+	if err := retryAPI(ctx, defaultBackoff, func() error {
+		err := clientset.CoreV1().Pods(namespace).Delete(ctx, pod, metav1.DeleteOptions{
+			GracePeriodSeconds: ptr(int64(0)),
+		})
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+	lingering, err = waitForPodGone(ctx, clientset, namespace, pod, options.deleteTimeout)
+	if err != nil || lingering == nil {
+		return err
+	}
+	return lingeringError(lingering, options.deleteTimeout, true)
+}
+
+// waitForPodGone waits for the Pod to disappear, and returns it if still
+// there after the timeout.
+func waitForPodGone(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, timeout time.Duration) (*corev1.Pod, error) {
+	var pod *corev1.Pod
+	err := wait.PollUntilContextTimeout(ctx, deletePollInterval(timeout), timeout, true, func(ctx context.Context) (bool, error) {
+		if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+			pod, err = clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+			return
+		}); err != nil {
+			if apierrors.IsNotFound(err) {
+				pod = nil
+				return true, nil
+			}
+			return false, err
+		}
+		return false, nil
+	})
+	if err != nil && pod != nil && wait.Interrupted(err) && ctx.Err() == nil {
+		return pod, nil
+	}
+	return nil, err
+}
+
+// deletePollInterval polls every 2 seconds, or more often for short
+// timeouts to be honored.
+func deletePollInterval(timeout time.Duration) time.Duration {
+	return min(2*time.Second, max(timeout/10, 10*time.Millisecond))
+}
+
+// lingeringError describes the lingering Pod, and what holds it.
+func lingeringError(pod *corev1.Pod, timeout time.Duration, forced bool) error {
+	state := string(pod.Status.Phase)
+	if pod.DeletionTimestamp != nil {
+		state = "terminating since " + pod.DeletionTimestamp.UTC().Format(time.RFC3339)
+	}
+	details := []string{state}
+	if len(pod.Finalizers) != 0 {
+		details = append(details, "finalizers "+strings.Join(pod.Finalizers, ", "))
+	}
+	if pod.Spec.NodeName != "" {
+		details = append(details, "on node "+pod.Spec.NodeName)
+	}
+	hint := "force delete it"
+	if forced {
+		hint = "even force deleted, check its node and volume attachments"
+	}
+	return fmt.Errorf("%w: pod %s/%s still there %s after deletion (%s), %s",
+		ErrPodLingering, pod.Namespace, pod.Name, timeout, strings.Join(details, ", "), hint)
+}
@@ -0,0 +1,146 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// stuckOnDelete makes the pod deletions only mark the pod as terminating,
+// as a kubelet never confirming it does, unless forced if it gives in.
+func stuckOnDelete(clientset *fake.Clientset, givesInToForce bool) {
+	clientset.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		opts := action.(k8stesting.DeleteActionImpl).DeleteOptions
+		if givesInToForce && opts.GracePeriodSeconds != nil && *opts.GracePeriodSeconds == 0 {
+			return false, nil, nil
+		}
+		pods := corev1.SchemeGroupVersion.WithResource("pods")
+		obj, err := clientset.Tracker().Get(pods, action.GetNamespace(), action.(k8stesting.DeleteActionImpl).Name)
+		if err != nil {
+			return true, nil, err
+		}
+		pod := obj.(*corev1.Pod)
+		if pod.DeletionTimestamp == nil {
+			pod.DeletionTimestamp = &metav1.Time{Time: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)}
+		}
+		return true, nil, clientset.Tracker().Update(pods, pod, action.GetNamespace())
+	})
+}
+
+func Test_U_DeleteExtractor(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Stuck          bool
+		GivesInToForce bool
+		ForceDelete    bool
+		ExpectLinger   bool
+		ExpectForced   bool
+		ExpectedHint   string
+	}{
+		"graceful": {},
+		"stuck": {
+			Stuck:        true,
+			ExpectLinger: true,
+			ExpectedHint: "force delete it",
+		},
+		"forced": {
+			Stuck:          true,
+			GivesInToForce: true,
+			ForceDelete:    true,
+			ExpectForced:   true,
+		},
+		"stuck-even-forced": {
+			Stuck:        true,
+			ForceDelete:  true,
+			ExpectLinger: true,
+			ExpectForced: true,
+			ExpectedHint: "even force deleted",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:  "monitoring",
+					Name:       podName,
+					Finalizers: []string{"example.com/csi-detach"},
+				},
+				Spec: corev1.PodSpec{
+					NodeName: "node-1",
+				},
+			})
+			if tt.Stuck {
+				stuckOnDelete(clientset, tt.GivesInToForce)
+			}
+			options := &options{logger: zap.NewNop()}
+			WithDeleteTimeout(100 * time.Millisecond).apply(options)
+			WithForceDelete(tt.ForceDelete).apply(options)
+			if err := options.validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			err := deleteExtractor(context.Background(), clientset, "monitoring", podName, options)
+			if errors.Is(err, ErrPodLingering) != tt.ExpectLinger {
+				t.Fatalf("expected the pod lingering: %t, got: %v", tt.ExpectLinger, err)
+			}
+			if !tt.ExpectLinger && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The lingering pod is reported with what holds it
+			if tt.ExpectLinger {
+				for _, detail := range []string{"monitoring/" + podName, "terminating since 2026-10-16T08:00:00Z", "example.com/csi-detach", "node-1", tt.ExpectedHint} {
+					if !strings.Contains(err.Error(), detail) {
+						t.Errorf("expected the error to mention %q, got: %s", detail, err)
+					}
+				}
+			} else if _, err := clientset.CoreV1().Pods("monitoring").Get(context.Background(), podName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+				t.Errorf("expected the pod to be gone, got %v", err)
+			}
+
+			forced := false
+			for _, action := range clientset.Actions() {
+				if del, ok := action.(k8stesting.DeleteActionImpl); ok {
+					if gp := del.DeleteOptions.GracePeriodSeconds; gp != nil && *gp == 0 {
+						forced = true
+					}
+				}
+			}
+			if forced != tt.ExpectForced {
+				t.Errorf("expected the pod force deleted: %t, got %t", tt.ExpectForced, forced)
+			}
+		})
+	}
+}
+
+func Test_U_DeleteTimeout_Validate(t *testing.T) {
+	t.Parallel()
+
+	defaulted := &options{}
+	if err := defaulted.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if defaulted.deleteTimeout != defaultDeleteTimeout {
+		t.Errorf("expected the delete timeout to default to %s, got %s", defaultDeleteTimeout, defaulted.deleteTimeout)
+	}
+
+	negative := &options{}
+	WithDeleteTimeout(-time.Second).apply(negative)
+	if err := negative.validate(); err == nil {
+		t.Error("expected a negative delete timeout to fail")
+	}
+}
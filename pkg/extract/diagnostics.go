@@ -0,0 +1,203 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultDiagnosticsLimit is the size of the Pod logs kept on failure.
+	defaultDiagnosticsLimit = 64 << 10
+
+	// maxDiagnosticsEvents is the number of most recent Pod events kept on
+	// failure.
+	maxDiagnosticsEvents = 20
+
+	// diagnosticsTimeout bounds the capture of the diagnostics, as the
+	// extraction could have failed on an unresponsive API server.
+	diagnosticsTimeout = 30 * time.Second
+)
+
+// PodDiagnostics are the logs and events of the extraction Pod, captured
+// when the extraction fails and before the Pod is deleted.
+type PodDiagnostics struct {
+	Pod string `json:"pod"`
+
+	// Logs are the last logs of the extraction container, up to the
+	// diagnostics limit (see WithDiagnosticsLimit).
+	Logs string `json:"logs"`
+	// LogsTruncated is whether the beginning of the logs was cut off.
+	LogsTruncated bool `json:"logs_truncated,omitempty"`
+
+	// Events are the most recent events of the Pod, oldest first.
+	Events []PodEvent `json:"events,omitempty"`
+
+	// Errors are why some diagnostics could not be captured, if any.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PodEvent is an event of the extraction Pod, e.g. a failed mount.
+type PodEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+}
+
+// runInPod runs the extraction steps once the Pod is created. On failure,
+// its diagnostics are captured into the result and the report written into
+// the directory, if any, while the Pod is still there to tell why.
+func runInPod(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	namespace, pod string,
+	res *Result,
+	options *options,
+	steps func(ctx context.Context) error,
+) error {
+	err := steps(ctx)
+	if err == nil {
+		return nil
+	}
+
+	options.logger.Info("capturing pod diagnostics",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+	)
+	res.Error = err.Error()
+	res.Diagnostics = captureDiagnostics(ctx, clientset, namespace, pod, options.diagnosticsLimit)
+
+	// Other sinks are aborted, so hold no report
+	if res.Directory != "" {
+		res.Duration = time.Since(res.StartedAt)
+		sink := NewDirectorySink(res.Directory)
+		sink.fsync = options.fsync
+		if werr := res.writeReport(ctx, sink); werr != nil {
+			err = errors.Join(err, fmt.Errorf("writing failure report: %w", werr))
+		}
+	}
+	return err
+}
+
+// captureDiagnostics returns the last logs of the extraction container and
+// the events of the Pod. Failing to capture them is recorded rather than
+// returned, as they only explain another failure.
+func captureDiagnostics(ctx context.Context, clientset kubernetes.Interface, namespace, pod string, limit int64) *PodDiagnostics {
+	// The extraction could have failed on the context itself
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), diagnosticsTimeout)
+	defer cancel()
+
+	diag := &PodDiagnostics{
+		Pod: pod,
+	}
+	logs, truncated, err := podLogs(ctx, clientset, namespace, pod, limit)
+	if err != nil {
+		diag.Errors = append(diag.Errors, "fetching logs: "+err.Error())
+	}
+	diag.Logs, diag.LogsTruncated = logs, truncated
+
+	diag.Events, err = podEvents(ctx, clientset, namespace, pod)
+	if err != nil {
+		diag.Errors = append(diag.Errors, "listing events: "+err.Error())
+	}
+	return diag
+}
+
+// podLogs returns the last limit bytes of the extraction container logs, and
+// whether some were cut off before.
+func podLogs(ctx context.Context, clientset kubernetes.Interface, namespace, pod string, limit int64) (string, bool, error) {
+	var logs []byte
+	truncated := false
+	err := retryAPI(ctx, defaultBackoff, func() error {
+		stream, err := clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{
+			Container: "copy",
+		}).Stream(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = stream.Close()
+		}()
+
+		logs, truncated, err = readTail(stream, limit)
+		return err
+	})
+	return string(logs), truncated, err
+}
+
+// readTail reads the stream, keeping its last limit bytes only.
+func readTail(r io.Reader, limit int64) ([]byte, bool, error) {
+	tail := make([]byte, 0, limit)
+	buf := make([]byte, 32<<10)
+	truncated := false
+	for {
+		n, err := r.Read(buf)
+		tail = append(tail, buf[:n]...)
+		if over := int64(len(tail)) - limit; over > 0 {
+			tail = append(tail[:0], tail[over:]...)
+			truncated = true
+		}
+		if err == io.EOF {
+			return tail, truncated, nil
+		}
+		if err != nil {
+			return tail, truncated, err
+		}
+	}
+}
+
+// podEvents returns the most recent events of the Pod, oldest first.
+func podEvents(ctx context.Context, clientset kubernetes.Interface, namespace, pod string) ([]PodEvent, error) {
+	var list *corev1.EventList
+	if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+		list, err = clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+			FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + pod,
+		})
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	events := []PodEvent{}
+	for _, ev := range list.Items {
+		// The field selector is not honored by every API, e.g. fake ones
+		if ev.InvolvedObject.Kind != "Pod" || ev.InvolvedObject.Name != pod {
+			continue
+		}
+		events = append(events, PodEvent{
+			Time:    eventTime(ev),
+			Type:    ev.Type,
+			Reason:  ev.Reason,
+			Message: ev.Message,
+		})
+	}
+	slices.SortStableFunc(events, func(a, b PodEvent) int {
+		return a.Time.Compare(b.Time)
+	})
+	if len(events) > maxDiagnosticsEvents {
+		events = events[len(events)-maxDiagnosticsEvents:]
+	}
+	return events, nil
+}
+
+// eventTime returns when the event last occurred, as set by either the
+// events.k8s.io or the core API.
+func eventTime(ev corev1.Event) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	default:
+		return ev.FirstTimestamp.Time
+	}
+}
@@ -0,0 +1,221 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// podWithEvents returns the clientset of the extraction pod, with an event of
+// its own and one of another object.
+func podWithEvents() *fake.Clientset {
+	return fake.NewClientset(
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: podName},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "extractor.1"},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "Pod",
+				Name: podName,
+			},
+			Type:          corev1.EventTypeWarning,
+			Reason:        "FailedMount",
+			Message:       "MountVolume.SetUp failed for volume \"data\"",
+			LastTimestamp: metav1.Time{Time: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)},
+		},
+		&corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "signals.1"},
+			InvolvedObject: corev1.ObjectReference{
+				Kind: "PersistentVolumeClaim",
+				Name: "signals",
+			},
+			Type:    corev1.EventTypeNormal,
+			Reason:  "Provisioning",
+			Message: "External provisioner is provisioning volume",
+		},
+	)
+}
+
+func Test_U_RunInPod(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		StepsErr error
+	}{
+		"success": {},
+		"failure": {
+			StepsErr: errors.New("pod extractor never got ready"),
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := podWithEvents()
+			dir := t.TempDir()
+			res := &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Directory: dir,
+				StartedAt: time.Now(),
+			}
+			opts := &options{
+				logger:           zap.NewNop(),
+				diagnosticsLimit: defaultDiagnosticsLimit,
+			}
+
+			err := runInPod(context.Background(), clientset, "monitoring", podName, res, opts, func(context.Context) error {
+				return tt.StepsErr
+			})
+			if !errors.Is(err, tt.StepsErr) {
+				t.Fatalf("expected error %v, got %v", tt.StepsErr, err)
+			}
+
+			fetched := false
+			for _, action := range clientset.Actions() {
+				fetched = fetched || action.GetSubresource() == "log"
+			}
+			if tt.StepsErr == nil {
+				// Nothing to explain, the pod is deleted and the report
+				// written afterwards
+				if fetched || res.Diagnostics != nil {
+					t.Errorf("expected no diagnostics on success, got %+v", res.Diagnostics)
+				}
+				if _, err := os.Stat(filepath.Join(dir, ReportFile)); !os.IsNotExist(err) {
+					t.Errorf("expected no report yet, got %v", err)
+				}
+				return
+			}
+
+			rep, err := ReadReport(dir)
+			if err != nil {
+				t.Fatalf("reading failure report: %s", err)
+			}
+			if rep.Error != tt.StepsErr.Error() {
+				t.Errorf("expected report error %q, got %q", tt.StepsErr, rep.Error)
+			}
+			if rep.Diagnostics == nil {
+				t.Fatal("expected diagnostics in the failure report")
+			}
+			if rep.Diagnostics.Logs != "fake logs" {
+				t.Errorf("expected the pod logs in the failure report, got %q", rep.Diagnostics.Logs)
+			}
+			if len(rep.Diagnostics.Events) != 1 || rep.Diagnostics.Events[0].Reason != "FailedMount" {
+				t.Errorf("expected the FailedMount event of the pod only, got %+v", rep.Diagnostics.Events)
+			}
+			if len(rep.Diagnostics.Errors) != 0 {
+				t.Errorf("expected no capture error, got %v", rep.Diagnostics.Errors)
+			}
+		})
+	}
+}
+
+func Test_U_CaptureDiagnostics_Truncated(t *testing.T) {
+	t.Parallel()
+
+	clientset := podWithEvents()
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "log" {
+			return false, nil, nil
+		}
+		return true, &runtime.Unknown{Raw: []byte("starting file server\nmount failed: input/output error\n")}, nil
+	})
+
+	// The end of the logs tells why it failed
+	diag := captureDiagnostics(context.Background(), clientset, "monitoring", podName, 33)
+	if diag.Logs != "mount failed: input/output error\n" || !diag.LogsTruncated {
+		t.Errorf("expected the truncated logs tail, got %q (truncated: %t)", diag.Logs, diag.LogsTruncated)
+	}
+}
+
+func Test_U_CaptureDiagnostics_Canceled(t *testing.T) {
+	t.Parallel()
+
+	// The extraction could have failed on its context, which must not
+	// prevent explaining it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	diag := captureDiagnostics(ctx, podWithEvents(), "monitoring", podName, defaultDiagnosticsLimit)
+	if diag.Logs != "fake logs" || len(diag.Events) != 1 {
+		t.Errorf("expected the diagnostics despite the canceled context, got %+v", diag)
+	}
+}
+
+func Test_U_ReadTail(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Input             string
+		Limit             int64
+		ExpectedTail      string
+		ExpectedTruncated bool
+	}{
+		"under-limit": {
+			Input:        "ready",
+			Limit:        16,
+			ExpectedTail: "ready",
+		},
+		"at-limit": {
+			Input:        "ready",
+			Limit:        5,
+			ExpectedTail: "ready",
+		},
+		"over-limit": {
+			Input:             strings.Repeat("a", 64<<10) + "error",
+			Limit:             5,
+			ExpectedTail:      "error",
+			ExpectedTruncated: true,
+		},
+		"empty": {
+			Limit:        5,
+			ExpectedTail: "",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			tail, truncated, err := readTail(strings.NewReader(tt.Input), tt.Limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(tail) != tt.ExpectedTail || truncated != tt.ExpectedTruncated {
+				t.Errorf("expected %q (truncated: %t), got %q (truncated: %t)", tt.ExpectedTail, tt.ExpectedTruncated, tail, truncated)
+			}
+		})
+	}
+}
+
+func Test_U_DiagnosticsLimit_Validate(t *testing.T) {
+	t.Parallel()
+
+	opts := &options{}
+	WithDiagnosticsLimit(-1).apply(opts)
+	if err := opts.validate(); err == nil {
+		t.Error("expected a negative diagnostics limit to be rejected")
+	}
+
+	opts = &options{}
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if opts.diagnosticsLimit != defaultDiagnosticsLimit {
+		t.Errorf("expected the default diagnostics limit %d, got %d", defaultDiagnosticsLimit, opts.diagnosticsLimit)
+	}
+}
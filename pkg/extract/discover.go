@@ -0,0 +1,130 @@
+package extract
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Target is a PVC of the Monitoring, by default the one holding the
+// OpenTelemetry Collector signals.
+type Target struct {
+	Namespace string
+	PVCName   string
+
+	// Component is the app.kubernetes.io/component label of the PVC.
+	Component string
+}
+
+var (
+	// monitoringSelector matches the resources deployed by the Monitoring.
+	monitoringSelector = labels.Set{
+		"app.kubernetes.io/part-of": "monitoring",
+	}
+
+	// componentLabel tells apart the parts of the Monitoring.
+	componentLabel = "app.kubernetes.io/component"
+
+	// signalsSelector matches the OpenTelemetry Collector signals PVC.
+	signalsSelector = labels.Set{
+		"app.kubernetes.io/part-of": "monitoring",
+		componentLabel:              "otel-collector",
+	}
+)
+
+// Discover lists the OpenTelemetry Collector signals PVCs of the Monitoring
+// namespaces in the cluster. If namespace is not empty, only this one is
+// looked into. Only the kube context option is used.
+func Discover(ctx context.Context, namespace string, opts ...Option) ([]Target, error) {
+	options := &options{}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	clientset, _, err := getClient(options.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return discover(ctx, clientset, namespace)
+}
+
+func discover(ctx context.Context, client kubernetes.Interface, namespace string) ([]Target, error) {
+	namespaces := []string{namespace}
+	if namespace == "" {
+		nss, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+			LabelSelector: monitoringSelector.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		namespaces = make([]string, 0, len(nss.Items))
+		for _, ns := range nss.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	return listTargets(ctx, client, namespaces, signalsSelector.AsSelector())
+}
+
+// DiscoverAll lists every PVC of the Monitoring in the namespace, whatever
+// its component, narrowed down by the PVC selector option if any. Only the
+// kube context and PVC selector options are used.
+func DiscoverAll(ctx context.Context, namespace string, opts ...Option) ([]Target, error) {
+	options := &options{}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	selector, err := allSelector(options.pvcSelector)
+	if err != nil {
+		return nil, err
+	}
+	clientset, _, err := getClient(options.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return listTargets(ctx, clientset, []string{namespace}, selector)
+}
+
+// allSelector returns the selector of the PVCs of the Monitoring which also
+// match the PVC selector, if any.
+func allSelector(pvcSelector string) (labels.Selector, error) {
+	selector := monitoringSelector.AsSelector()
+	if pvcSelector == "" {
+		return selector, nil
+	}
+	sel, err := labels.Parse(pvcSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PVC selector %s: %w", pvcSelector, err)
+	}
+	reqs, _ := sel.Requirements()
+	return selector.Add(reqs...), nil
+}
+
+// listTargets lists the PVCs matching the selector in the namespaces,
+// sorted.
+func listTargets(ctx context.Context, client kubernetes.Interface, namespaces []string, selector labels.Selector) ([]Target, error) {
+	targets := []Target{}
+	for _, ns := range namespaces {
+		pvcs, err := client.CoreV1().PersistentVolumeClaims(ns).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, pvc := range pvcs.Items {
+			targets = append(targets, Target{
+				Namespace: ns,
+				PVCName:   pvc.Name,
+				Component: pvc.Labels[componentLabel],
+			})
+		}
+	}
+	slices.SortFunc(targets, func(a, b Target) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.PVCName, b.PVCName))
+	})
+	return targets, nil
+}
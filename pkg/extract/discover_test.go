@@ -0,0 +1,171 @@
+package extract
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_U_Discover(t *testing.T) {
+	t.Parallel()
+
+	objects := []runtime.Object{
+		namespace("monitoring-aaaaaaaa", monitoringSelector),
+		namespace("monitoring-bbbbbbbb", monitoringSelector),
+		namespace("challenges", nil),
+		pvc("monitoring-aaaaaaaa", "signals-1", signalsSelector),
+		pvc("monitoring-bbbbbbbb", "signals-2", signalsSelector),
+		pvc("monitoring-bbbbbbbb", "other", monitoringSelector),
+		pvc("challenges", "signals-3", signalsSelector),
+	}
+
+	var tests = map[string]struct {
+		Namespace string
+		Expected  []Target
+	}{
+		"all-namespaces": {
+			Namespace: "",
+			Expected: []Target{
+				{Namespace: "monitoring-aaaaaaaa", PVCName: "signals-1", Component: "otel-collector"},
+				{Namespace: "monitoring-bbbbbbbb", PVCName: "signals-2", Component: "otel-collector"},
+			},
+		},
+		"single-namespace": {
+			Namespace: "monitoring-bbbbbbbb",
+			Expected: []Target{
+				{Namespace: "monitoring-bbbbbbbb", PVCName: "signals-2", Component: "otel-collector"},
+			},
+		},
+		"no-match": {
+			Namespace: "default",
+			Expected:  []Target{},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			client := fake.NewClientset(objects...)
+			targets, err := discover(context.Background(), client, tt.Namespace)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !slices.Equal(targets, tt.Expected) {
+				t.Errorf("expected %v, got %v", tt.Expected, targets)
+			}
+		})
+	}
+}
+
+func Test_U_DiscoverAll(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset(
+		pvc("monitoring", "signals", signalsSelector),
+		pvc("monitoring", "jaeger-archive", map[string]string{
+			"app.kubernetes.io/part-of":   "monitoring",
+			"app.kubernetes.io/component": "jaeger",
+		}),
+		pvc("monitoring", "other", monitoringSelector),
+		pvc("monitoring", "foreign", nil),
+	)
+	targets, err := listTargets(context.Background(), client, []string{"monitoring"}, monitoringSelector.AsSelector())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []Target{
+		{Namespace: "monitoring", PVCName: "jaeger-archive", Component: "jaeger"},
+		{Namespace: "monitoring", PVCName: "other"},
+		{Namespace: "monitoring", PVCName: "signals", Component: "otel-collector"},
+	}
+	if !slices.Equal(targets, expected) {
+		t.Errorf("expected %v, got %v", expected, targets)
+	}
+}
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+func pvc(namespace, name string, labels map[string]string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    labels,
+		},
+	}
+}
+
+func Test_U_AllSelector(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		PVCSelector string
+		ExpectErr   bool
+		Expected    []string
+	}{
+		"every": {
+			Expected: []string{"jaeger-archive", "signals"},
+		},
+		"component": {
+			PVCSelector: "app.kubernetes.io/component=jaeger",
+			Expected:    []string{"jaeger-archive"},
+		},
+		"set": {
+			PVCSelector: "app.kubernetes.io/component in (jaeger,otel-collector)",
+			Expected:    []string{"jaeger-archive", "signals"},
+		},
+		"invalid": {
+			PVCSelector: "app.kubernetes.io/component in (",
+			ExpectErr:   true,
+		},
+	}
+
+	client := fake.NewClientset(
+		pvc("monitoring", "signals", signalsSelector),
+		pvc("monitoring", "jaeger-archive", map[string]string{
+			"app.kubernetes.io/part-of":   "monitoring",
+			"app.kubernetes.io/component": "jaeger",
+		}),
+		pvc("monitoring", "foreign", map[string]string{
+			"app.kubernetes.io/component": "jaeger",
+		}),
+	)
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := allSelector(tt.PVCSelector)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+			targets, err := listTargets(context.Background(), client, []string{"monitoring"}, selector)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			names := []string{}
+			for _, tgt := range targets {
+				names = append(names, tgt.PVCName)
+			}
+			if !slices.Equal(names, tt.Expected) {
+				t.Errorf("expected %v, got %v", tt.Expected, names)
+			}
+		})
+	}
+}
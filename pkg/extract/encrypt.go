@@ -0,0 +1,162 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"filippo.io/age"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EncryptedArchiveFile is the file the age-encrypted archive of the PVC
+// lands into, within the directory.
+const EncryptedArchiveFile = "extraction.tar.age"
+
+// ErrAgeMissing is returned when the age image does not provide the age
+// binary to encrypt the archive with.
+var ErrAgeMissing = errors.New("age binary missing from the age image")
+
+const (
+	// DefaultAgeBinary is where the age binary is looked for in the age
+	// image, as installed by the Alpine package.
+	DefaultAgeBinary = "/usr/bin/age"
+
+	ageContainer    = "age"
+	ageVolume       = "age"
+	ageMountPath    = "/opt/age"
+	ageRecipientEnv = "AGE_RECIPIENT"
+)
+
+// AgeEncryption encrypts the archive of the PVC in the extraction Pod, such
+// that only the ciphertext leaves it.
+type AgeEncryption struct {
+	// Recipient is the age X25519 public key, i.e. "age1...", the archive
+	// is encrypted to.
+	Recipient string
+
+	// Image providing a statically-linked age binary, and cp to copy it
+	// into the extraction Pod. It is pulled as is, whatever WithRegistry.
+	Image string
+
+	// Binary is the path of the age binary in the image.
+	// Defaults to DefaultAgeBinary.
+	Binary string
+}
+
+// validate checks the encryption before the Pod is created. The recipient
+// is never part of the errors, as it would end up logged.
+func (enc *AgeEncryption) validate() error {
+	if _, err := age.ParseX25519Recipient(enc.Recipient); err != nil {
+		return errors.New("age recipient is not a valid X25519 public key")
+	}
+	if enc.Image == "" {
+		return errors.New("age encryption requires an image providing the age binary")
+	}
+	if enc.Binary == "" {
+		enc.Binary = DefaultAgeBinary
+	}
+	if !path.IsAbs(enc.Binary) {
+		return fmt.Errorf("age binary %s is not absolute", enc.Binary)
+	}
+	return nil
+}
+
+// addAgeHelper copies the age binary of its image into the Pod through an
+// init container, for the copy one to encrypt with. The recipient is passed
+// as an environment variable rather than on the command line.
+func addAgeHelper(pod *corev1.Pod, enc *AgeEncryption, secctx *corev1.SecurityContext) {
+	mount := corev1.VolumeMount{
+		Name:      ageVolume,
+		MountPath: ageMountPath,
+	}
+	pod.Spec.InitContainers = append(pod.Spec.InitContainers, corev1.Container{
+		Name:            ageContainer,
+		Image:           enc.Image,
+		Command:         []string{"cp", enc.Binary, path.Join(ageMountPath, "age")},
+		VolumeMounts:    []corev1.VolumeMount{mount},
+		SecurityContext: secctx,
+	})
+	ctr := &pod.Spec.Containers[0]
+	ctr.VolumeMounts = append(ctr.VolumeMounts, mount)
+	ctr.Env = append(ctr.Env, corev1.EnvVar{
+		Name:  ageRecipientEnv,
+		Value: enc.Recipient,
+	})
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: ageVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+}
+
+// ageInitError tells whether the Pod never got ready as the age binary
+// could not be copied from its image, i.e. is missing from it.
+func ageInitError(ctx context.Context, clientset kubernetes.Interface, namespace, podName string, err error) error {
+	// The Pod may be gone with the context, look at it anyway
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	pod, gerr := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if gerr != nil {
+		return err
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name != ageContainer {
+			continue
+		}
+		if term := status.State.Terminated; term != nil && term.ExitCode != 0 {
+			return fmt.Errorf("%w: copying it exited with code %d: %w", ErrAgeMissing, term.ExitCode, err)
+		}
+	}
+	return err
+}
+
+// probeAge makes sure the age binary runs in the copy container, before
+// streaming anything.
+func probeAge(ctx context.Context, exec podExecutor) error {
+	if err := exec(ctx, []string{path.Join(ageMountPath, "age"), "--version"}, &bytes.Buffer{}); err != nil {
+		return fmt.Errorf("%w: %w", ErrAgeMissing, err)
+	}
+	return nil
+}
+
+// encryptedTarCommand returns the command archiving the content of podPath
+// encrypted to the recipient of the environment on stdout. Any failure of
+// the archive fails the whole pipe, as there is no telling which files are
+// missing from the ciphertext.
+func encryptedTarCommand(podPath string) []string {
+	return []string{"sh", "-c",
+		"set -o pipefail; " + shellQuote(tarCommand(podPath)) + " | " + path.Join(ageMountPath, "age") + ` -r "$` + ageRecipientEnv + `"`,
+	}
+}
+
+// writeEncrypted writes the encrypted archive as is into the sink.
+func writeEncrypted(ctx context.Context, r io.Reader, sink Sink, progress *Progress) (*untarResult, error) {
+	cr := &countingReader{r: r}
+	now := time.Now()
+	if err := sink.WriteEntry(ctx, Entry{
+		Path:    EncryptedArchiveFile,
+		Mode:    0600,
+		ModTime: now,
+	}, cr); err != nil {
+		return nil, err
+	}
+	progress.add(cr.n)
+	return &untarResult{
+		files: 1,
+		size:  cr.n,
+		inventory: []InventoryFile{{
+			Path:    EncryptedArchiveFile,
+			Bytes:   cr.n,
+			ModTime: now,
+		}},
+	}, nil
+}
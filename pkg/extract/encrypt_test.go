@@ -0,0 +1,320 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_U_AgeEncryption_Validate(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient := identity.Recipient().String()
+
+	var tests = map[string]struct {
+		Opts      []Option
+		ExpectErr bool
+	}{
+		"valid": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age"}),
+			},
+		},
+		"invalid-recipient": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: "age1notakey", Image: "alpine/age"}),
+			},
+			ExpectErr: true,
+		},
+		"identity-as-recipient": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: identity.String(), Image: "alpine/age"}),
+			},
+			ExpectErr: true,
+		},
+		"no-image": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient}),
+			},
+			ExpectErr: true,
+		},
+		"relative-binary": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age", Binary: "bin/age"}),
+			},
+			ExpectErr: true,
+		},
+		"port-forward": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age"}),
+				WithTransport(TransportPortForward),
+			},
+			ExpectErr: true,
+		},
+		"verify-remote": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age"}),
+				WithVerifyRemote(true),
+			},
+			ExpectErr: true,
+		},
+		"decompress": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age"}),
+				WithDecompress(true),
+			},
+			ExpectErr: true,
+		},
+		"record": {
+			Opts: []Option{
+				WithAgeEncryption(AgeEncryption{Recipient: recipient, Image: "alpine/age"}),
+				WithRecord(t.TempDir()),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			opts := &options{}
+			for _, opt := range tt.Opts {
+				opt.apply(opts)
+			}
+			err := opts.validate()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				// The recipient would end up logged along the error
+				if strings.Contains(err.Error(), opts.age.Recipient) {
+					t.Errorf("expected the recipient not to be in the error, got %q", err)
+				}
+				return
+			}
+			if opts.transport != TransportExec {
+				t.Errorf("expected the exec transport, got %s", opts.transport)
+			}
+			if opts.age.Binary != DefaultAgeBinary {
+				t.Errorf("expected the default age binary, got %s", opts.age.Binary)
+			}
+		})
+	}
+}
+
+func Test_U_AgeEncryption_Sink(t *testing.T) {
+	t.Parallel()
+
+	opts := &options{
+		age:  &AgeEncryption{},
+		sink: NewArchiveSink(filepath.Join(t.TempDir(), "extraction.tar.gz")),
+	}
+	if err := opts.checkSink(""); err == nil {
+		t.Error("expected the age encryption to require a directory")
+	}
+}
+
+func Test_U_ExtractorPod_AgeEncryption(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &options{}
+	WithAgeEncryption(AgeEncryption{Recipient: identity.Recipient().String(), Image: "alpine/age:1.2.1"}).apply(opts)
+	if err := opts.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	pod := extractorPod("monitoring", "signals", opts)
+	if len(pod.Spec.InitContainers) != 1 {
+		t.Fatalf("expected the age init container, got %d init containers", len(pod.Spec.InitContainers))
+	}
+	init := pod.Spec.InitContainers[0]
+	if init.Image != "alpine/age:1.2.1" || !slices.Equal(init.Command, []string{"cp", DefaultAgeBinary, "/opt/age/age"}) {
+		t.Errorf("expected the init container to copy the age binary, got %s %v", init.Image, init.Command)
+	}
+	if init.SecurityContext == nil || !*init.SecurityContext.RunAsNonRoot {
+		t.Error("expected the init container to run restricted as the copy one")
+	}
+
+	ctr := pod.Spec.Containers[0]
+	if !slices.ContainsFunc(ctr.Env, func(env corev1.EnvVar) bool {
+		return env.Name == ageRecipientEnv && env.Value == identity.Recipient().String()
+	}) {
+		t.Errorf("expected the recipient as environment variable, got %v", ctr.Env)
+	}
+	if !slices.ContainsFunc(ctr.VolumeMounts, func(vm corev1.VolumeMount) bool {
+		return vm.Name == ageVolume && vm.MountPath == ageMountPath
+	}) {
+		t.Errorf("expected the age volume mounted in the copy container, got %v", ctr.VolumeMounts)
+	}
+	// Only the exec streams through age, no file server
+	if ctr.ReadinessProbe != nil {
+		t.Error("expected no file server along the age encryption")
+	}
+}
+
+func Test_U_CopyFromPod_AgeEncryption(t *testing.T) {
+	t.Parallel()
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, expected := tarFixture(t, 2, 3, 1024)
+
+	// Encrypt as the Pod would, the recipient never being on the command line
+	exec := func(_ context.Context, command []string, stdout io.Writer) error {
+		if !slices.Equal(command, encryptedTarCommand("/data")) {
+			return errors.New("unexpected command")
+		}
+		if strings.Contains(strings.Join(command, " "), identity.Recipient().String()) {
+			return errors.New("recipient on the command line")
+		}
+		w, err := age.Encrypt(stdout, identity.Recipient())
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(archive); err != nil {
+			return err
+		}
+		return w.Close()
+	}
+
+	dir := t.TempDir()
+	res, err := copyFromPod(context.Background(), exec, "/data", NewDirectorySink(dir), &options{
+		logger: zap.NewNop(),
+		age:    &AgeEncryption{Recipient: identity.Recipient().String()},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.files != 1 || len(res.inventory) != 1 || res.inventory[0].Path != EncryptedArchiveFile {
+		t.Errorf("expected the encrypted archive only, got %+v", res.inventory)
+	}
+
+	// Only the ciphertext landed, which decrypts to the archive
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the encrypted archive only, got %d entries", len(entries))
+	}
+	f, err := os.Open(filepath.Join(dir, EncryptedArchiveFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := age.Decrypt(f, identity)
+	if err != nil {
+		t.Fatalf("decrypting: %s", err)
+	}
+	tr := tar.NewReader(r)
+	found := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, expected[filepath.Clean(hdr.Name)]) {
+			t.Errorf("unexpected content for %s", hdr.Name)
+		}
+		found++
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d files in the decrypted archive, got %d", len(expected), found)
+	}
+}
+
+func Test_U_ProbeAge(t *testing.T) {
+	t.Parallel()
+
+	missing := func(_ context.Context, command []string, _ io.Writer) error {
+		return &exitError{command: command, code: 127, stderr: "sh: /opt/age/age: not found"}
+	}
+	if err := probeAge(context.Background(), missing); !errors.Is(err, ErrAgeMissing) {
+		t.Errorf("expected ErrAgeMissing, got %v", err)
+	}
+
+	present := func(_ context.Context, _ []string, stdout io.Writer) error {
+		_, err := stdout.Write([]byte("v1.2.1\n"))
+		return err
+	}
+	if err := probeAge(context.Background(), present); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func Test_U_AgeInitError(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ExitCode      int32
+		ExpectMissing bool
+	}{
+		"copy-failed": {
+			ExitCode:      1,
+			ExpectMissing: true,
+		},
+		"copy-succeeded": {},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: podName},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					InitContainerStatuses: []corev1.ContainerStatus{{
+						Name: ageContainer,
+						State: corev1.ContainerState{
+							Terminated: &corev1.ContainerStateTerminated{ExitCode: tt.ExitCode},
+						},
+					}},
+				},
+			})
+			notReady := errors.New("pod extractor terminated before being ready")
+
+			err := ageInitError(context.Background(), clientset, "monitoring", podName, notReady)
+			if !errors.Is(err, notReady) {
+				t.Errorf("expected the readiness error, got %v", err)
+			}
+			if errors.Is(err, ErrAgeMissing) != tt.ExpectMissing {
+				t.Errorf("expected age missing: %t, got %v", tt.ExpectMissing, err)
+			}
+		})
+	}
+}
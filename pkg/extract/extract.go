@@ -4,12 +4,16 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -20,21 +24,42 @@ import (
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/client-go/util/homedir"
 )
 
 const (
 	img     = "library/busybox:1.37.0"
 	podName = "extractor"
+
+	defaultMountPath = "/data"
+
+	clientQPS   = 5
+	clientBurst = 10
 )
 
 // DumpOTelCollector mounts a temporary container with the PVC, given its namespace and name,
-// and copies all data into the provided directory (creates it if necessary).
+// and copies all data into the provided directory (creates it if necessary), or into the
+// sink set through WithSink with an empty directory.
+// It returns the summary of the extraction, also written as the report file in the directory.
+// A failed extraction returns it partial along the error, with the Pod diagnostics once the
+// Pod created. It is nil only for invalid options.
 func DumpOTelCollector(
 	ctx context.Context,
 	namespace, pvcName, into string,
 	opts ...Option,
-) error {
+) (*Result, error) {
+	return dumpPVC(ctx, SourceOTelCollector, namespace, pvcName, into, opts...)
+}
+
+// dumpPVC copies the data of the PVC into the directory, as the source of
+// the result.
+func dumpPVC(
+	ctx context.Context,
+	source, namespace, pvcName, into string,
+	opts ...Option,
+) (*Result, error) {
 	// Prepare functional options
 	options := &options{
 		logger: zap.NewNop(),
@@ -42,11 +67,32 @@ func DumpOTelCollector(
 	for _, opt := range opts {
 		opt.apply(options)
 	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if err := options.checkSink(into); err != nil {
+		return nil, err
+	}
+	directory, _ := localDirectory(options.sink)
+	if options.record != "" && directory != "" {
+		if err := checkFixtureDir(options.record, directory); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &Result{
+		Source:    source,
+		Namespace: namespace,
+		PVCName:   pvcName,
+		Directory: directory,
+		StartedAt: time.Now(),
+	}
+	res.noteFilesystem(options)
 
 	// Prepare K8s client
-	clientset, config, err := getClient()
+	clientset, config, err := getClient(options.kubeContext)
 	if err != nil {
-		return err
+		return res, err
 	}
 
 	// Create Pod and mount PVC
@@ -54,9 +100,174 @@ func DumpOTelCollector(
 		zap.String("pod", podName),
 		zap.String("namespace", namespace),
 		zap.String("pvc", pvcName),
+		zap.Duration("gc_after", options.gcAfter),
+	)
+	options.progress.phase(PhaseCreatingPod)
+
+	pod, err := createExtractor(ctx, clientset, namespace, pvcName, options)
+	if err != nil {
+		return res, err
+	}
+
+	if err := runInPod(ctx, clientset, namespace, pod, res, options, func(ctx context.Context) error {
+		// Wait for it to be Up & Running
+		options.logger.Info("waiting for the pod to be ready",
+			zap.String("pod", pod),
+			zap.String("namespace", namespace),
+		)
+		options.progress.phase(PhaseWaitingPod)
+		if err := waitForPodReady(ctx, clientset, namespace, pod); err != nil {
+			if options.age != nil {
+				err = ageInitError(ctx, clientset, namespace, pod, err)
+			}
+			return err
+		}
+
+		transport, exec, closeTransport, err := podTransport(ctx, config, clientset, namespace, pod, "copy", options)
+		if err != nil {
+			return err
+		}
+		defer closeTransport()
+		res.Transport = transport
+		if options.customPod() {
+			if err := probeTar(ctx, exec); err != nil {
+				return err
+			}
+		}
+		if options.age != nil {
+			if err := probeAge(ctx, exec); err != nil {
+				return err
+			}
+		}
+
+		// Copy files, recording the pod outputs if requested
+		if options.record != "" {
+			options.logger.Info("recording fixture",
+				zap.String("directory", options.record),
+			)
+			exec, err = recordExecutor(exec, res, pod, "copy", options)
+			if err != nil {
+				return err
+			}
+		}
+		return dumpFromPod(ctx, exec, res, options)
+	}); err != nil {
+		// The pod is likely stuck on the PVC, or could never encrypt nor
+		// archive, don't leave it behind
+		if errors.Is(err, ErrDeadlineExceeded) || errors.Is(err, ErrAgeMissing) ||
+			errors.Is(err, ErrPodCommand) || errors.Is(err, ErrTarMissing) {
+			err = errors.Join(err, deleteExtractor(ctx, clientset, namespace, pod, options))
+		}
+		return res, err
+	}
+
+	// Delete Pod
+	options.logger.Info("deleting pod",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
 	)
+	options.progress.phase(PhaseDeletingPod)
+	if err := deleteExtractor(ctx, clientset, namespace, pod, options); err != nil {
+		if !errors.Is(err, ErrPodLingering) {
+			return res, abortSink(ctx, options.sink, err)
+		}
+		// The files are extracted whole, only the next extraction is at stake
+		options.logger.Warn("pod lingering after deletion",
+			zap.Error(err),
+		)
+		res.Warnings = append(res.Warnings, err.Error())
+	}
+
+	if err := res.finish(ctx, options.sink); err != nil {
+		return res, err
+	}
+	return res, res.verifyFailure()
+}
 
-	if _, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+// dumpFromPod copies the files of the source path through the executor into
+// the sink, then verifies and decompresses them if requested. The sink is
+// aborted on failure.
+func dumpFromPod(ctx context.Context, exec podExecutor, res *Result, options *options) (err error) {
+	if err := options.sink.Open(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = abortSink(ctx, options.sink, err)
+		}
+	}()
+
+	options.logger.Info("copying files",
+		zap.String("sink", options.sink.Report().Location),
+	)
+	copied, err := copyFromPod(ctx, exec, options.sourcePath, options.sink, options)
+	if err != nil {
+		return err
+	}
+	res.Files, res.Bytes = copied.files, copied.size
+	res.Inventory = sortedInventory(copied.inventory)
+	res.noteVanished(copied.vanished)
+	if err := res.notePartials(); err != nil {
+		return err
+	}
+	if options.age != nil {
+		res.Encrypted = EncryptedArchiveFile
+	}
+
+	// Verify files against the PVC ones
+	if options.verifyRemote {
+		options.logger.Info("verifying files against the PVC")
+		options.progress.phase(PhaseVerifying)
+		res.Verify, err = verifyRemote(ctx, exec, options.sourcePath, copied.checksums, options.logger)
+		if err != nil {
+			return err
+		}
+		for _, p := range res.Verify.MissingRemote {
+			res.Warnings = append(res.Warnings, "file no longer on the PVC: "+p)
+		}
+		for _, p := range res.Verify.MissingLocal {
+			res.Warnings = append(res.Warnings, "file created on the PVC after copy: "+p)
+		}
+	}
+
+	// Decompress files, once verified as on the PVC
+	if options.decompress {
+		options.logger.Info("decompressing files")
+		options.progress.phase(PhaseDecompressing)
+		var warns []string
+		res.Decompressed, warns, err = decompressAll(res.Directory, res.Inventory, options.decompressLimit, options.logger)
+		if err != nil {
+			return err
+		}
+		res.Warnings = append(res.Warnings, warns...)
+	}
+	return nil
+}
+
+// extractorPod returns the Pod mounting the PVC to extract data from.
+func extractorPod(namespace, pvcName string, options *options) *corev1.Pod {
+	// The following matches the pod security policy "restricted".
+	// It is not required for the extractor to work, but is a good
+	// practice, plus we don't need large capabilities so it's OK.
+	secctx := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr(false),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+		},
+		RunAsUser:    ptr(int64(1000)), // Don't need to be root !
+		RunAsNonRoot: ptr(true),
+		SeccompProfile: &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+	}
+	if options.seccompProfile != nil {
+		secctx.SeccompProfile = options.seccompProfile
+	}
+	if options.platformUID {
+		secctx.RunAsUser = nil
+	}
+
+	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
 			Name:      podName,
@@ -67,33 +278,21 @@ func DumpOTelCollector(
 				{
 					Name: "copy",
 					Image: func() string {
+						if options.podImage != "" {
+							return options.podImage
+						}
 						if options.registry != "" && !strings.HasSuffix(options.registry, "/") {
 							options.registry += "/"
 						}
 						return options.registry + img
 					}(),
-					Command: []string{"/bin/sh", "-c", "--"},
-					Args:    []string{"sleep infinity"},
 					VolumeMounts: []corev1.VolumeMount{
 						{
 							Name:      "data",
-							MountPath: "/data",
-						},
-					},
-					// The following matches the pod security policy "restricted".
-					// It is not required for the extractor to work, but is a good
-					// practice, plus we don't need large capabilities so it's OK.
-					SecurityContext: &corev1.SecurityContext{
-						AllowPrivilegeEscalation: ptr(false),
-						Capabilities: &corev1.Capabilities{
-							Drop: []corev1.Capability{"ALL"},
-						},
-						RunAsUser:    ptr(int64(1000)), // Don't need to be root !
-						RunAsNonRoot: ptr(true),
-						SeccompProfile: &corev1.SeccompProfile{
-							Type: corev1.SeccompProfileTypeRuntimeDefault,
+							MountPath: options.mountPath,
 						},
 					},
+					SecurityContext: secctx,
 				},
 			},
 			Volumes: []corev1.Volume{
@@ -107,41 +306,81 @@ func DumpOTelCollector(
 				},
 			},
 		},
-	}, metav1.CreateOptions{}); err != nil {
-		return err
 	}
-
-	// Wait for it to be Up & Running
-	options.logger.Info("waiting for the pod to be ready",
-		zap.String("pod", podName),
-		zap.String("namespace", namespace),
-	)
-	if err := waitForPodReady(ctx, clientset, namespace, podName); err != nil {
-		return err
+	ctr := &pod.Spec.Containers[0]
+	ctr.Command, ctr.Args = options.podEntrypoint()
+	if options.runtimeClassName != "" {
+		pod.Spec.RuntimeClassName = ptr(options.runtimeClassName)
+	}
+	if options.age != nil {
+		addAgeHelper(pod, options.age, secctx)
+	}
+	if options.transport != TransportExec {
+		// Serve the files too, in case exec is forbidden
+		ctr.Args = []string{fileServerScript(options.sourcePath)}
+		ctr.ReadinessProbe = fileServerReadiness()
+		ctr.VolumeMounts = append(ctr.VolumeMounts, corev1.VolumeMount{
+			Name:      "server",
+			MountPath: fileServerRoot,
+		})
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: "server",
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
 	}
+	return pod
+}
 
-	// Copy files
-	options.logger.Info("copying files",
-		zap.String("directory", into),
-	)
-	if err := copyFromPod(ctx, config, clientset, namespace, podName, "copy", "/data", into); err != nil {
-		return err
+// verifyRemote compares the checksums of the files just extracted, computed
+// while extracting, with the ones of the PVC. The other files of the
+// destination, e.g. of previous extractions, are not considered.
+func verifyRemote(
+	ctx context.Context,
+	exec podExecutor,
+	podPath string,
+	local map[string]string,
+	logger *zap.Logger,
+) (*VerifyReport, error) {
+	remote, err := remoteChecksums(ctx, exec, podPath)
+	if err != nil {
+		return nil, err
 	}
 
-	// Delete Pod
-	options.logger.Info("deleting pod",
-		zap.String("pod", podName),
-		zap.String("namespace", namespace),
+	rep := CompareChecksums(local, remote)
+	for _, p := range rep.MissingRemote {
+		logger.Warn("file no longer on the PVC", zap.String("file", p))
+	}
+	for _, p := range rep.MissingLocal {
+		logger.Warn("file created on the PVC after copy", zap.String("file", p))
+	}
+	for _, p := range rep.Mismatched {
+		logger.Error("file checksum mismatch", zap.String("file", p))
+	}
+	logger.Info("verification done",
+		zap.Int("matching", len(rep.Matching)),
+		zap.Int("mismatched", len(rep.Mismatched)),
+		zap.Int("missing_remote", len(rep.MissingRemote)),
+		zap.Int("missing_local", len(rep.MissingLocal)),
 	)
-	return clientset.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{})
+	return &rep, nil
 }
 
-func getClient() (*kubernetes.Clientset, *rest.Config, error) {
+// getClient returns the client of the context of the kubeconfig, its
+// current one if empty.
+func getClient(kubeContext string) (*kubernetes.Clientset, *rest.Config, error) {
 	kubeconfig := filepath.Join(homedir.HomeDir(), ".kube", "config")
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
 	if err != nil {
 		return nil, nil, err
 	}
+	// The REST client already waits for the Retry-After of throttled
+	// requests, limit our own rate to avoid being throttled in the first place
+	config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(clientQPS, clientBurst)
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
 		return nil, nil, err
@@ -149,12 +388,23 @@ func getClient() (*kubernetes.Clientset, *rest.Config, error) {
 	return clientset, config, nil
 }
 
-func waitForPodReady(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string) error {
+func waitForPodReady(ctx context.Context, clientset kubernetes.Interface, namespace, podName string) error {
 	return wait.PollUntilContextTimeout(ctx, 2*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
-		pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
-		if err != nil {
+		var pod *corev1.Pod
+		if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+			pod, err = clientset.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+			return
+		}); err != nil {
+			return false, err
+		}
+		// The pod is never restarted, don't wait for it once terminated
+		// Nor once its command failed, it never gets ready
+		if err := podCommandError(pod); err != nil {
 			return false, err
 		}
+		if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			return false, fmt.Errorf("pod %s terminated before being ready: %s %s", podName, pod.Status.Phase, pod.Status.Reason)
+		}
 		for _, cond := range pod.Status.Conditions {
 			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
 				return true, nil
@@ -164,11 +414,159 @@ func waitForPodReady(ctx context.Context, clientset *kubernetes.Clientset, names
 	})
 }
 
+// copyFromPod copies the content of podPath into the sink, within the
+// deadline if any. Once exceeded, the stream is closed whether the exec
+// returns or not, and ErrDeadlineExceeded is returned.
 func copyFromPod(
+	ctx context.Context,
+	exec podExecutor,
+	podPath string,
+	sink Sink,
+	options *options,
+) (res *untarResult, err error) {
+	deadline, err := copyDeadline(ctx, exec, podPath, options)
+	if err != nil {
+		return
+	}
+
+	// Stream the archive from the exec to the untar, without buffering it all
+	pr, pw := io.Pipe()
+	if deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, deadline,
+			fmt.Errorf("%w: copy not completed within %s", ErrDeadlineExceeded, deadline))
+		defer cancel()
+		// A stalled exec may not return on cancellation, unblock the untar
+		stop := context.AfterFunc(ctx, func() {
+			_ = pr.CloseWithError(context.Cause(ctx))
+		})
+		defer stop()
+	}
+	// Only the ciphertext leaves the pod when encrypting, landed as is
+	command := tarCommand(podPath)
+	consume := func(r io.Reader) (*untarResult, error) {
+		return untar(ctx, r, sink, options.workers, options.verifyRemote, options.progress)
+	}
+	if options.age != nil {
+		command = encryptedTarCommand(podPath)
+		consume = func(r io.Reader) (*untarResult, error) {
+			return writeEncrypted(ctx, r, sink, options.progress)
+		}
+	}
+	errc := make(chan error, 1)
+	go func() {
+		err := exec(ctx, command, pw)
+		_ = pw.CloseWithError(err)
+		errc <- err
+	}()
+	// waitExec returns the exec error, or the deadline one once exceeded
+	// without waiting for the exec
+	waitExec := func() error {
+		select {
+		case err := <-errc:
+			if cause := context.Cause(ctx); errors.Is(cause, ErrDeadlineExceeded) {
+				return cause
+			}
+			return err
+		case <-ctx.Done():
+			if cause := context.Cause(ctx); errors.Is(cause, ErrDeadlineExceeded) {
+				return cause
+			}
+			return <-errc
+		}
+	}
+
+	var r io.Reader = pr
+	if options.bandwidthLimit > 0 {
+		r = newRateLimitedReader(ctx, r, options.bandwidthLimit)
+	}
+	cr := &countingReader{r: r}
+
+	// untar into the sink
+	options.progress.phase(PhaseCopying)
+	stopTracking := options.progress.track()
+	defer stopTracking()
+	start := time.Now()
+	res, err = consume(cr)
+	if err != nil {
+		// Close the stream so the exec ends
+		_ = pr.CloseWithError(err)
+		if werr := waitExec(); errors.Is(werr, ErrDeadlineExceeded) {
+			err = werr
+		}
+		return
+	}
+	// Drain the archive trailing padding, then make sure the exec succeeded.
+	// Its error reaches the drain through the pipe too.
+	_, derr := io.Copy(io.Discard, cr)
+	if err = waitExec(); err == nil {
+		err = derr
+	} else if derr == nil || errors.Is(derr, err) {
+		// The files which vanished meanwhile are missing, the others are
+		// extracted whole
+		if vanished, ok := vanishedFiles(err); ok && !options.strict {
+			options.logger.Warn("files vanished during the archive", zap.Strings("files", vanished))
+			res.vanished, err = vanished, nil
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	elapsed := time.Since(start)
+	options.logger.Info("copy done",
+		zap.Int("files", res.files),
+		zap.Int64("bytes", cr.n),
+		zap.Duration("duration", elapsed),
+		zap.String("average_rate", formatRate(cr.n, elapsed)),
+	)
+	return
+}
+
+// formatRate formats the average transfer rate in bytes per second.
+func formatRate(n int64, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return "n/a"
+	}
+	bps := float64(n) / elapsed.Seconds()
+	units := []string{"B/s", "KiB/s", "MiB/s", "GiB/s"}
+	i := 0
+	for bps >= 1024 && i < len(units)-1 {
+		bps /= 1024
+		i++
+	}
+	return fmt.Sprintf("%.2f %s", bps, units[i])
+}
+
+// tarCommand returns the command archiving the content of podPath on stdout.
+func tarCommand(podPath string) []string {
+	return []string{"tar", "cf", "-", "-C", podPath, "."}
+}
+
+// podExecutor runs the command in a container of the pod, and streams its
+// standard output into stdout.
+type podExecutor func(ctx context.Context, command []string, stdout io.Writer) error
+
+// newPodExecutor returns the executor of the container of the pod.
+func newPodExecutor(
+	config *rest.Config,
+	clientset *kubernetes.Clientset,
+	namespace, podName, containerName string,
+) podExecutor {
+	return func(ctx context.Context, command []string, stdout io.Writer) error {
+		return execInPod(ctx, config, clientset, namespace, podName, containerName, command, stdout)
+	}
+}
+
+// execInPod runs the command in the container of the pod, and streams its
+// standard output into stdout.
+func execInPod(
 	ctx context.Context,
 	config *rest.Config,
 	clientset *kubernetes.Clientset,
-	namespace, podName, containerName, podPath, localDir string,
+	namespace, podName, containerName string,
+	command []string,
+	stdout io.Writer,
 ) error {
 	req := clientset.CoreV1().RESTClient().
 		Get().
@@ -178,71 +576,213 @@ func copyFromPod(
 		SubResource("exec").
 		Param("container", containerName).
 		Param("stdout", "true").
-		Param("stderr", "true").
-		Param("command", "tar").
-		Param("command", "cf").
-		Param("command", "-").
-		Param("command", "-C").
-		Param("command", podPath).
-		Param("command", ".")
+		Param("stderr", "true")
+	for _, c := range command {
+		req = req.Param("command", c)
+	}
 
 	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
 	if err != nil {
 		return err
 	}
 
-	var stdout, stderr bytes.Buffer
+	var stderr bytes.Buffer
 	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
-		Stdout: &stdout,
+		Stdout: stdout,
 		Stderr: &stderr,
 		Tty:    false,
 	})
+	if ee := utilexec.ExitError(nil); errors.As(err, &ee) {
+		return &exitError{command: command, code: ee.ExitStatus(), stderr: stderr.String()}
+	}
 	if err != nil {
 		return fmt.Errorf("stream error: %v\nstderr: %s", err, stderr.String())
 	}
+	return nil
+}
+
+// maxBufferedFileSize is the size up to which a file is read in memory for
+// a worker to write it. Bigger files are written by the reader itself, as
+// they are not the bottleneck and would hold too much memory in flight.
+const maxBufferedFileSize = 1 << 20
+
+// untarResult is the outcome of an untar.
+type untarResult struct {
+	files int
+	size  int64
+	// checksums are the SHA256 checksums of the files, indexed by their
+	// path relative to the destination, if requested.
+	checksums map[string]string
+	// vanished are the files removed from the source while archived, hence
+	// not extracted.
+	vanished []string
+	// inventory are the files extracted, in the order they were written.
+	inventory []InventoryFile
+}
 
-	// untar locally
-	return untar(bytes.NewReader(stdout.Bytes()), localDir)
+// untarJob is a file read from the archive, to be written by a worker.
+type untarJob struct {
+	entry   Entry
+	content []byte
 }
 
-func untar(r io.Reader, dest string) error {
+// untar extracts the archive into the sink. Each file is hashed on the fly
+// if checksums are requested, and counted in the progress, whatever the
+// sink.
+//
+// The archive is read sequentially, and directories are written as they are
+// met, before any file under them is dispatched to one of the workers. At
+// most 2*workers+1 files of up to maxBufferedFileSize are held in memory.
+func untar(ctx context.Context, r io.Reader, sink Sink, workers int, checksums bool, progress *Progress) (*untarResult, error) {
+	workers = max(workers, 1)
+	res := &untarResult{}
+	if checksums {
+		res.checksums = map[string]string{}
+	}
+	mu := sync.Mutex{}
+	record := func(entry Entry, n int64, sum string) {
+		mu.Lock()
+		defer mu.Unlock()
+		res.files++
+		res.size += n
+		res.inventory = append(res.inventory, InventoryFile{
+			Path:    entry.Path,
+			Bytes:   n,
+			ModTime: entry.ModTime.UTC(),
+		})
+		if checksums {
+			res.checksums[filepath.FromSlash(entry.Path)] = sum
+		}
+		progress.add(n)
+	}
+
+	// The first failure stops the dispatch, the workers drain the jobs
+	var (
+		failOnce sync.Once
+		failErr  error
+	)
+	failed := make(chan struct{})
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failErr = err
+			close(failed)
+		})
+	}
+
+	jobs := make(chan untarJob, workers)
+	wg := sync.WaitGroup{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				n, sum, err := extractEntry(ctx, sink, job.entry, bytes.NewReader(job.content), checksums)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				record(job.entry, n, sum)
+			}
+		}()
+	}
+
+	if err := readArchive(ctx, r, sink, jobs, failed, checksums, record); err != nil {
+		fail(err)
+	}
+	close(jobs)
+	wg.Wait()
+
+	if failErr != nil {
+		return res, failErr
+	}
+	return res, nil
+}
+
+// readArchive reads the archive entries, writes the directories and
+// dispatches the small files to the jobs, until the end of the archive or
+// a failure.
+func readArchive(
+	ctx context.Context,
+	r io.Reader,
+	sink Sink,
+	jobs chan<- untarJob,
+	failed <-chan struct{},
+	checksums bool,
+	record func(entry Entry, n int64, sum string),
+) error {
 	tr := tar.NewReader(r)
 	for {
+		select {
+		case <-failed:
+			return nil
+		default:
+		}
+
 		hdr, err := tr.Next()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
 			return err
 		}
 
-		target, err := sanitizeArchivePath(dest, hdr.Name)
-		if err != nil {
+		// The archive root, i.e. "./" as tar -C <path> . names it, is the
+		// destination itself
+		name := path.Clean(hdr.Name)
+		if name == "." {
+			continue
+		}
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
 			// tainted path, could be a Path Traversal
-			return err
+			return fmt.Errorf("filepath is tainted: %s", hdr.Name)
+		}
+		entry := Entry{
+			Path:    name,
+			Mode:    hdr.FileInfo().Mode(),
+			ModTime: hdr.ModTime,
 		}
 
 		switch hdr.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(target, 0755); err != nil {
+			if err := sink.WriteEntry(ctx, entry, nil); err != nil {
 				return err
 			}
 		case tar.TypeReg:
-			if err := os.MkdirAll(path.Dir(target), 0755); err != nil {
-				return err
+			entry.Size = hdr.Size
+			if hdr.Size > maxBufferedFileSize {
+				n, sum, err := extractEntry(ctx, sink, entry, tr, checksums)
+				if err != nil {
+					return err
+				}
+				record(entry, n, sum)
+				continue
 			}
-			f, err := os.Create(target)
-			if err != nil {
+			content := make([]byte, hdr.Size)
+			if _, err := io.ReadFull(tr, content); err != nil {
 				return err
 			}
-			if _, err := io.Copy(f, tr); err != nil {
-				f.Close()
-				return err
+			select {
+			case jobs <- untarJob{entry: entry, content: content}:
+			case <-failed:
+				return nil
 			}
-			f.Close()
 		}
 	}
-	return nil
+}
+
+// extractEntry writes the file entry into the sink, and returns its size
+// and, if requested, its SHA256 checksum.
+func extractEntry(ctx context.Context, sink Sink, entry Entry, r io.Reader, checksum bool) (int64, string, error) {
+	cr := &countingReader{r: r}
+	if !checksum {
+		err := sink.WriteEntry(ctx, entry, cr)
+		return cr.n, "", err
+	}
+	h := sha256.New()
+	if err := sink.WriteEntry(ctx, entry, io.TeeReader(cr, h)); err != nil {
+		return cr.n, "", err
+	}
+	return cr.n, hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // Based upon https://security.snyk.io/research/zip-slip-vulnerability#expandable-socPI9fFAJ-title
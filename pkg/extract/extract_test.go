@@ -0,0 +1,414 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func Test_U_ExtractorPod_Security(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Opts                     []Option
+		ExpectErr                bool
+		ExpectedRuntimeClassName string
+		ExpectedSeccompType      corev1.SeccompProfileType
+		ExpectedLocalhostProfile string
+	}{
+		"defaults": {
+			ExpectedSeccompType: corev1.SeccompProfileTypeRuntimeDefault,
+		},
+		"runtime-class": {
+			Opts: []Option{
+				WithRuntimeClass("gvisor"),
+			},
+			ExpectedRuntimeClassName: "gvisor",
+			ExpectedSeccompType:      corev1.SeccompProfileTypeRuntimeDefault,
+		},
+		"localhost": {
+			Opts: []Option{
+				WithRuntimeClass("gvisor"),
+				WithSeccompProfile("Localhost", "profiles/extractor.json"),
+			},
+			ExpectedRuntimeClassName: "gvisor",
+			ExpectedSeccompType:      corev1.SeccompProfileTypeLocalhost,
+			ExpectedLocalhostProfile: "profiles/extractor.json",
+		},
+		"unconfined": {
+			Opts: []Option{
+				WithSeccompProfile("Unconfined", ""),
+			},
+			ExpectedSeccompType: corev1.SeccompProfileTypeUnconfined,
+		},
+		"localhost-without-path": {
+			Opts: []Option{
+				WithSeccompProfile("Localhost", ""),
+			},
+			ExpectErr: true,
+		},
+		"path-without-localhost": {
+			Opts: []Option{
+				WithSeccompProfile("RuntimeDefault", "profiles/extractor.json"),
+			},
+			ExpectErr: true,
+		},
+		"path-without-type": {
+			Opts: []Option{
+				WithSeccompProfile("", "profiles/extractor.json"),
+			},
+			ExpectErr: true,
+		},
+		"unknown-type": {
+			Opts: []Option{
+				WithSeccompProfile("Whatever", ""),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			for _, opt := range tt.Opts {
+				opt.apply(options)
+			}
+			err := options.validate()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			pod := extractorPod("monitoring", "signals", options)
+			if tt.ExpectedRuntimeClassName == "" {
+				if pod.Spec.RuntimeClassName != nil {
+					t.Errorf("expected no runtime class, got %s", *pod.Spec.RuntimeClassName)
+				}
+			} else if pod.Spec.RuntimeClassName == nil || *pod.Spec.RuntimeClassName != tt.ExpectedRuntimeClassName {
+				t.Errorf("expected runtime class %s, got %v", tt.ExpectedRuntimeClassName, pod.Spec.RuntimeClassName)
+			}
+
+			sp := pod.Spec.Containers[0].SecurityContext.SeccompProfile
+			if sp.Type != tt.ExpectedSeccompType {
+				t.Errorf("expected seccomp profile type %s, got %s", tt.ExpectedSeccompType, sp.Type)
+			}
+			if tt.ExpectedLocalhostProfile != "" && (sp.LocalhostProfile == nil || *sp.LocalhostProfile != tt.ExpectedLocalhostProfile) {
+				t.Errorf("expected localhost profile %s, got %v", tt.ExpectedLocalhostProfile, sp.LocalhostProfile)
+			}
+		})
+	}
+}
+
+func Test_U_ExtractorPod_Paths(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Opts               []Option
+		ExpectErr          bool
+		ExpectedMountPath  string
+		ExpectedTarCommand []string
+	}{
+		"defaults": {
+			ExpectedMountPath:  "/data",
+			ExpectedTarCommand: []string{"tar", "cf", "-", "-C", "/data", "."},
+		},
+		"mount-path": {
+			Opts: []Option{
+				WithMountPath("/otel"),
+			},
+			ExpectedMountPath:  "/otel",
+			ExpectedTarCommand: []string{"tar", "cf", "-", "-C", "/otel", "."},
+		},
+		"source-path": {
+			Opts: []Option{
+				WithMountPath("/otel/"),
+				WithSourcePath("/otel/signals/../coldextract"),
+			},
+			ExpectedMountPath:  "/otel",
+			ExpectedTarCommand: []string{"tar", "cf", "-", "-C", "/otel/coldextract", "."},
+		},
+		"relative-mount-path": {
+			Opts: []Option{
+				WithMountPath("data"),
+			},
+			ExpectErr: true,
+		},
+		"relative-source-path": {
+			Opts: []Option{
+				WithSourcePath("coldextract"),
+			},
+			ExpectErr: true,
+		},
+		"source-outside-mount": {
+			Opts: []Option{
+				WithSourcePath("/etc"),
+			},
+			ExpectErr: true,
+		},
+		"source-sibling-of-mount": {
+			Opts: []Option{
+				WithSourcePath("/data2"),
+			},
+			ExpectErr: true,
+		},
+		"source-escaping-mount": {
+			Opts: []Option{
+				WithSourcePath("/data/../etc"),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			for _, opt := range tt.Opts {
+				opt.apply(options)
+			}
+			err := options.validate()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			pod := extractorPod("monitoring", "signals", options)
+			if mp := pod.Spec.Containers[0].VolumeMounts[0].MountPath; mp != tt.ExpectedMountPath {
+				t.Errorf("expected mount path %s, got %s", tt.ExpectedMountPath, mp)
+			}
+			if cmd := tarCommand(options.sourcePath); !slices.Equal(cmd, tt.ExpectedTarCommand) {
+				t.Errorf("expected tar command %v, got %v", tt.ExpectedTarCommand, cmd)
+			}
+		})
+	}
+}
+
+func Test_U_ExtractorPod_PlatformUID(t *testing.T) {
+	t.Parallel()
+
+	options := &options{}
+	if err := options.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	secctx := extractorPod("monitoring", "signals", options).Spec.Containers[0].SecurityContext
+	if secctx.RunAsUser == nil || *secctx.RunAsUser != 1000 {
+		t.Errorf("expected the UID to be pinned by default, got %v", secctx.RunAsUser)
+	}
+
+	WithPlatformUID(true).apply(options)
+	secctx = extractorPod("monitoring", "signals", options).Spec.Containers[0].SecurityContext
+	if secctx.RunAsUser != nil {
+		t.Errorf("expected the UID to be left to the platform, got %d", *secctx.RunAsUser)
+	}
+	if secctx.RunAsNonRoot == nil || !*secctx.RunAsNonRoot {
+		t.Error("expected the pod to still run as non-root")
+	}
+}
+
+func Test_U_Options_Workers(t *testing.T) {
+	t.Parallel()
+
+	options := &options{}
+	if err := options.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if options.workers < 1 {
+		t.Errorf("expected workers to default to GOMAXPROCS, got %d", options.workers)
+	}
+
+	WithWorkers(-1).apply(options)
+	if err := options.validate(); err == nil {
+		t.Error("expected an error on negative workers")
+	}
+}
+
+func Test_U_Untar_Workers(t *testing.T) {
+	t.Parallel()
+
+	// Directories come before their files, as with tar cf
+	archive, expected := tarFixture(t, 4, 10, 64)
+	big := bytes.Repeat([]byte("x"), maxBufferedFileSize+1)
+	archive = appendTar(t, archive, "./big/otel_traces", big)
+	expected[filepath.Join("big", "otel_traces")] = big
+
+	var tests = map[string]struct {
+		Workers int
+	}{
+		"sequential": {
+			Workers: 1,
+		},
+		"parallel": {
+			Workers: 8,
+		},
+		"more-workers-than-files": {
+			Workers: 1024,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			copied, err := untar(context.Background(), bytes.NewReader(archive), NewDirectorySink(dir), tt.Workers, true, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if copied.files != len(expected) {
+				t.Errorf("expected %d files, got %d", len(expected), copied.files)
+			}
+
+			size := int64(0)
+			for rel, content := range expected {
+				size += int64(len(content))
+				got, err := os.ReadFile(filepath.Join(dir, rel))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !bytes.Equal(got, content) {
+					t.Errorf("expected %s to be complete", rel)
+				}
+			}
+			if copied.size != size {
+				t.Errorf("expected %d bytes, got %d", size, copied.size)
+			}
+
+			// The checksums computed on the fly are the ones of the files
+			local, err := localChecksums(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if rep := CompareChecksums(copied.checksums, local); len(rep.Matching) != len(expected) {
+				t.Errorf("expected the checksums to match the files, got %+v", rep)
+			}
+
+			// The inventory lists every file, whatever the worker writing it
+			inventory := sortedInventory(copied.inventory)
+			if len(inventory) != len(expected) {
+				t.Fatalf("expected %d files in the inventory, got %d", len(expected), len(inventory))
+			}
+			for _, f := range inventory {
+				content, ok := expected[filepath.FromSlash(f.Path)]
+				if !ok || f.Bytes != int64(len(content)) {
+					t.Errorf("unexpected inventory file %+v", f)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Untar_Workers_Interrupted(t *testing.T) {
+	t.Parallel()
+
+	archive, _ := tarFixture(t, 4, 10, 64)
+	dir := t.TempDir()
+
+	// The last file is cut, but buffered files are never written partially
+	if _, err := untar(context.Background(), bytes.NewReader(archive[:len(archive)-1024-512+32]), NewDirectorySink(dir), 4, false, nil); err == nil {
+		t.Fatal("expected an error on a truncated archive")
+	}
+	partials, err := findPartials(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(partials) != 0 {
+		t.Errorf("expected no partial file, got %v", partials)
+	}
+}
+
+func Benchmark_Untar(b *testing.B) {
+	archive, _ := tarFixture(b, 4, 100, 512)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(archive)))
+			for b.Loop() {
+				if _, err := untar(context.Background(), bytes.NewReader(archive), NewDirectorySink(b.TempDir()), workers, true, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// tarFixture builds an archive of dirs directories holding files small files
+// each, and returns it along with the files content by relative path.
+func tarFixture(tb testing.TB, dirs, files, size int) ([]byte, map[string][]byte) {
+	tb.Helper()
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	expected := map[string][]byte{}
+	for d := range dirs {
+		dir := fmt.Sprintf("./collector-%d", d)
+		if err := tw.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755}); err != nil {
+			tb.Fatal(err)
+		}
+		for f := range files {
+			name := fmt.Sprintf("%s/otel_traces.%d", dir, f)
+			content := bytes.Repeat([]byte{byte('a' + f%26)}, size)
+			if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(size)}); err != nil {
+				tb.Fatal(err)
+			}
+			if _, err := tw.Write(content); err != nil {
+				tb.Fatal(err)
+			}
+			expected[filepath.Clean(name)] = content
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes(), expected
+}
+
+// appendTar appends a file to the archive, before its end blocks.
+func appendTar(tb testing.TB, archive []byte, name string, content []byte) []byte {
+	tb.Helper()
+
+	buf := bytes.NewBuffer(slices.Clone(archive[:len(archive)-1024]))
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		tb.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		tb.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func Test_U_DumpOTelCollector_PartialResult(t *testing.T) {
+	t.Parallel()
+
+	// The kubeconfig has no such context, failing once the result started
+	dir := t.TempDir()
+	res, err := DumpOTelCollector(context.Background(), "monitoring", "otel-pvc", dir,
+		WithKubeContext("extract-partial-result-missing"),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if res == nil {
+		t.Fatal("expected the partial result along the error")
+	}
+	if res.Namespace != "monitoring" || res.PVCName != "otel-pvc" || res.Directory != dir {
+		t.Errorf("expected the result of monitoring/otel-pvc into %s, got %+v", dir, res)
+	}
+}
@@ -0,0 +1,161 @@
+package extract
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// mountInfoPath lists the mounts seen by the extractor, on Linux only.
+const mountInfoPath = "/proc/self/mountinfo"
+
+// networkFilesystems are the types of the filesystems backed by the
+// network, as named in the mount table, on which a write may only be
+// committed to the server once synced.
+var networkFilesystems = []string{
+	"9p", "afs", "ceph", "cifs", "glusterfs", "lustre", "ncpfs", "nfs", "nfs4", "smb3", "smbfs",
+	"fuse.ceph-fuse", "fuse.gcsfuse", "fuse.glusterfs", "fuse.rclone", "fuse.s3fs", "fuse.sshfs",
+}
+
+// syncDir flushes the entries of the directory to disk, e.g. of a file
+// renamed into it, for them to survive a crash or an unmount.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// noteFilesystem records whether the files are synced, and warns when the
+// directory is on a network filesystem they are not synced onto.
+func (res *Result) noteFilesystem(options *options) {
+	if res.Directory == "" {
+		return
+	}
+	res.Synced = options.fsync
+	fstype, ok := networkFilesystem(res.Directory)
+	if !ok {
+		return
+	}
+	if options.fsync {
+		options.logger.Info("extracting onto a network filesystem, synced",
+			zap.String("directory", res.Directory),
+			zap.String("filesystem", fstype),
+		)
+		return
+	}
+	options.logger.Warn("extracting onto a network filesystem without fsync",
+		zap.String("directory", res.Directory),
+		zap.String("filesystem", fstype),
+	)
+	res.Warnings = append(res.Warnings, fmt.Sprintf("directory %s is on a %s network filesystem, files could be lost on unmount unless extracted with fsync", res.Directory, fstype))
+}
+
+// networkFilesystem returns the type of the filesystem the directory (or
+// its closest existing parent) is on, if backed by the network. It is only
+// detected on Linux, through the procfs mount table.
+func networkFilesystem(dir string) (string, bool) {
+	f, err := os.Open(mountInfoPath)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	dir, err = existingPath(dir)
+	if err != nil {
+		return "", false
+	}
+	fstype, err := mountFilesystem(f, dir)
+	if err != nil || !slices.Contains(networkFilesystems, fstype) {
+		return "", false
+	}
+	return fstype, true
+}
+
+// existingPath returns the absolute path of the closest existing parent of
+// the directory, itself included, with the symbolic links resolved.
+func existingPath(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return resolved, nil
+		}
+		parent := filepath.Dir(dir)
+		if !errors.Is(err, fs.ErrNotExist) || parent == dir {
+			return "", err
+		}
+		dir = parent
+	}
+}
+
+// mountFilesystem returns the type of the filesystem mounted the deepest
+// above the absolute path, given the mountinfo table (see proc(5)).
+func mountFilesystem(r io.Reader, path string) (string, error) {
+	var mountPoint, fstype string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		// 36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw
+		fields := strings.Fields(sc.Text())
+		sep := slices.Index(fields, "-")
+		if sep < 5 || sep+1 >= len(fields) {
+			return "", fmt.Errorf("invalid mountinfo line: %s", sc.Text())
+		}
+		mp := unescapeMountPath(fields[4])
+		if !underMountPoint(path, mp) || len(mp) < len(mountPoint) {
+			continue
+		}
+		// Later mounts over the same point shadow the earlier ones
+		mountPoint, fstype = mp, fields[sep+1]
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	if mountPoint == "" {
+		return "", fmt.Errorf("no mount point above %s", path)
+	}
+	return fstype, nil
+}
+
+// underMountPoint returns whether the path is the mount point or under it.
+func underMountPoint(path, mountPoint string) bool {
+	return mountPoint == "/" || path == mountPoint || strings.HasPrefix(path, mountPoint+"/")
+}
+
+// unescapeMountPath decodes the octal escapes of the mount paths, e.g.
+// \040 for a space.
+func unescapeMountPath(p string) string {
+	if !strings.Contains(p, `\`) {
+		return p
+	}
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '\\' && i+3 < len(p) && isOctal(p[i+1]) && isOctal(p[i+2]) && isOctal(p[i+3]) {
+			b.WriteByte((p[i+1]-'0')<<6 | (p[i+2]-'0')<<3 | (p[i+3] - '0'))
+			i += 3
+			continue
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+func isOctal(c byte) bool {
+	return c >= '0' && c <= '7'
+}
@@ -0,0 +1,138 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mountInfo is a mount table as /proc/self/mountinfo lists it, with an NFS
+// export mounted under the home, and a path escaping a space.
+const mountInfo = `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw
+23 22 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+24 22 0:22 / /home rw,relatime shared:2 - ext4 /dev/nvme0n1p3 rw
+25 24 0:45 / /home/ctf/backup rw,relatime shared:30 - nfs4 nas:/exports/backup rw,vers=4.2
+26 24 0:46 / /home/ctf/cold\040storage rw,relatime shared:31 - cifs //nas/cold rw
+27 22 0:47 / /mnt rw,relatime shared:32 - nfs nas:/exports/mnt rw
+28 22 0:48 / /mnt rw,relatime shared:33 - tmpfs tmpfs rw
+`
+
+func Test_U_MountFilesystem(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Path           string
+		ExpectedFSType string
+	}{
+		"root": {
+			Path:           "/var/lib/extract",
+			ExpectedFSType: "ext4",
+		},
+		"mount-point": {
+			Path:           "/home/ctf/backup",
+			ExpectedFSType: "nfs4",
+		},
+		"under-mount-point": {
+			Path:           "/home/ctf/backup/finals/2026",
+			ExpectedFSType: "nfs4",
+		},
+		"sibling-prefix": {
+			Path:           "/home/ctf/backups",
+			ExpectedFSType: "ext4",
+		},
+		"escaped": {
+			Path:           "/home/ctf/cold storage/finals",
+			ExpectedFSType: "cifs",
+		},
+		"shadowed": {
+			Path:           "/mnt/extract",
+			ExpectedFSType: "tmpfs",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			fstype, err := mountFilesystem(strings.NewReader(mountInfo), tt.Path)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if fstype != tt.ExpectedFSType {
+				t.Errorf("expected filesystem %s, got %s", tt.ExpectedFSType, fstype)
+			}
+		})
+	}
+
+	if _, err := mountFilesystem(strings.NewReader("22 1 259:2 / / rw\n"), "/"); err == nil {
+		t.Error("expected an error on an invalid mount table")
+	}
+}
+
+func Test_U_ExistingPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The destination is created by the extraction, its parent is looked up
+	got, err := existingPath(filepath.Join(dir, "extract", "finals"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != resolved {
+		t.Errorf("expected %s, got %s", resolved, got)
+	}
+}
+
+func Test_U_Untar_Fsync(t *testing.T) {
+	t.Parallel()
+
+	archive, expected := tarFixture(t, 2, 10, 512)
+	dir := filepath.Join(t.TempDir(), "extract")
+	sink := NewDirectorySink(dir)
+	sink.fsync = true
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res, err := untar(context.Background(), bytes.NewReader(archive), sink, 4, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.files != len(expected) {
+		t.Errorf("expected %d files, got %d", len(expected), res.files)
+	}
+	for name, content := range expected {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("reading %s: %s", name, err)
+		}
+		if !bytes.Equal(b, content) {
+			t.Errorf("expected %s to be extracted as is", name)
+		}
+	}
+}
+
+func Benchmark_Untar_Fsync(b *testing.B) {
+	archive, _ := tarFixture(b, 4, 100, 512)
+
+	for _, fsync := range []bool{false, true} {
+		b.Run(fmt.Sprintf("fsync-%t", fsync), func(b *testing.B) {
+			b.SetBytes(int64(len(archive)))
+			for b.Loop() {
+				sink := NewDirectorySink(b.TempDir())
+				sink.fsync = fsync
+				if _, err := untar(context.Background(), bytes.NewReader(archive), sink, 4, false, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	jobName = podName
+
+	// jobTTL is how long the cluster keeps the finished Job, thus its Pod,
+	// before reaping them.
+	jobTTL = 60
+)
+
+// createExtractor creates the Pod mounting the PVC, or the Job controlling
+// it if the cluster is responsible for its deletion, and returns the Pod
+// name once it exists.
+func createExtractor(ctx context.Context, clientset kubernetes.Interface, namespace, pvcName string, options *options) (string, error) {
+	if options.gcAfter == 0 {
+		if err := retryCreate(ctx, defaultBackoff, func() error {
+			_, err := clientset.CoreV1().Pods(namespace).Create(ctx, extractorPod(namespace, pvcName, options), metav1.CreateOptions{})
+			return err
+		}); err != nil {
+			return "", err
+		}
+		return podName, nil
+	}
+
+	if err := retryCreate(ctx, defaultBackoff, func() error {
+		_, err := clientset.BatchV1().Jobs(namespace).Create(ctx, extractorJob(namespace, pvcName, options), metav1.CreateOptions{})
+		return err
+	}); err != nil {
+		return "", err
+	}
+	return waitForJobPod(ctx, clientset, namespace)
+}
+
+// extractorJob returns the Job controlling the extractor Pod, such that the
+// cluster reaps it even if the extractor dies: the Pod fails once its
+// deadline is exceeded, is not restarted, and the finished Job is deleted
+// along with it after its TTL.
+func extractorJob(namespace, pvcName string, options *options) *batchv1.Job {
+	pod := extractorPod(namespace, pvcName, options)
+	pod.Spec.ActiveDeadlineSeconds = ptr(int64(options.gcAfter / time.Second))
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      jobName,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr(int32(0)),
+			TTLSecondsAfterFinished: ptr(int32(jobTTL)),
+			Template: corev1.PodTemplateSpec{
+				Spec: pod.Spec,
+			},
+		},
+	}
+}
+
+// waitForJobPod waits for the Job controller to create the extractor Pod,
+// and returns its name. As the Pod is never restarted, a failed Job is
+// reported at once.
+func waitForJobPod(ctx context.Context, clientset kubernetes.Interface, namespace string) (string, error) {
+	var name string
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		var job *batchv1.Job
+		if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+			job, err = clientset.BatchV1().Jobs(namespace).Get(ctx, jobName, metav1.GetOptions{})
+			return
+		}); err != nil {
+			return false, err
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+				return false, fmt.Errorf("job %s failed: %s", jobName, cond.Message)
+			}
+		}
+
+		var pods *corev1.PodList
+		if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+			pods, err = clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: batchv1.JobNameLabel + "=" + jobName,
+			})
+			return
+		}); err != nil {
+			return false, err
+		}
+		if len(pods.Items) == 0 {
+			return false, nil
+		}
+		name = pods.Items[0].Name
+		return true, nil
+	})
+	return name, err
+}
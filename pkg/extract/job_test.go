@@ -0,0 +1,149 @@
+package extract
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func Test_U_ExtractorJob(t *testing.T) {
+	t.Parallel()
+
+	options := &options{}
+	WithGCAfter(90 * time.Minute).apply(options)
+	if err := options.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	job := extractorJob("monitoring", "signals", options)
+
+	if job.Spec.BackoffLimit == nil || *job.Spec.BackoffLimit != 0 {
+		t.Errorf("expected the pod not to be retried, got backoff limit %v", job.Spec.BackoffLimit)
+	}
+	if job.Spec.TTLSecondsAfterFinished == nil || *job.Spec.TTLSecondsAfterFinished != jobTTL {
+		t.Errorf("expected the job to be reaped after %ds, got %v", jobTTL, job.Spec.TTLSecondsAfterFinished)
+	}
+	spec := job.Spec.Template.Spec
+	if spec.ActiveDeadlineSeconds == nil || *spec.ActiveDeadlineSeconds != 5400 {
+		t.Errorf("expected the pod deadline to be 5400s, got %v", spec.ActiveDeadlineSeconds)
+	}
+	if spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("expected the pod never to restart, got %s", spec.RestartPolicy)
+	}
+	if claim := spec.Volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != "signals" {
+		t.Errorf("expected the pod to mount the PVC signals, got %v", claim)
+	}
+}
+
+func Test_U_GCAfter_Validate(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		GCAfter   time.Duration
+		ExpectErr bool
+	}{
+		"disabled": {
+			GCAfter: 0,
+		},
+		"hour": {
+			GCAfter: time.Hour,
+		},
+		"sub-second": {
+			GCAfter:   500 * time.Millisecond,
+			ExpectErr: true,
+		},
+		"negative": {
+			GCAfter:   -time.Minute,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			WithGCAfter(tt.GCAfter).apply(options)
+			err := options.validate()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_CreateExtractor_Job(t *testing.T) {
+	t.Parallel()
+
+	// The fake clientset has no Job controller, so the pod is already there
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      "extractor-x7k2p",
+			Labels: map[string]string{
+				batchv1.JobNameLabel: jobName,
+			},
+		},
+	})
+	options := &options{}
+	WithGCAfter(time.Hour).apply(options)
+	if err := options.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	name, err := createExtractor(context.Background(), clientset, "monitoring", "signals", options)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if name != "extractor-x7k2p" {
+		t.Errorf("expected the job pod extractor-x7k2p, got %s", name)
+	}
+	if _, err := clientset.BatchV1().Jobs("monitoring").Get(context.Background(), jobName, metav1.GetOptions{}); err != nil {
+		t.Fatalf("expected the job to be created: %s", err)
+	}
+	if _, err := clientset.CoreV1().Pods("monitoring").Get(context.Background(), podName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected no pod to be created by the extractor, got %v", err)
+	}
+
+	// Nor garbage collector, so the job pod is deleted along the job here
+	clientset.PrependReactor("delete", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return false, nil, clientset.Tracker().Delete(corev1.SchemeGroupVersion.WithResource("pods"), "monitoring", name)
+	})
+	if err := deleteExtractor(context.Background(), clientset, "monitoring", name, options); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := clientset.BatchV1().Jobs("monitoring").Get(context.Background(), jobName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the job to be deleted, got %v", err)
+	}
+}
+
+func Test_U_WaitForJobPod_Failed(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewClientset(&batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      jobName,
+		},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{
+				{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "quota exceeded"},
+			},
+		},
+	})
+
+	start := time.Now()
+	if _, err := waitForJobPod(context.Background(), clientset, "monitoring"); err == nil {
+		t.Fatal("expected an error for a failed job")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to fail at once, waited %s", elapsed)
+	}
+}
@@ -1,10 +1,220 @@
 package extract
 
-import "go.uber.org/zap"
+import (
+	"errors"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+)
 
 type options struct {
-	logger   *zap.Logger
-	registry string
+	logger       *zap.Logger
+	kubeContext  string
+	pvcSelector  string
+	registry     string
+	verifyRemote bool
+
+	bandwidthLimit int
+
+	workers int
+
+	maxDuration  time.Duration
+	autoDeadline bool
+
+	runtimeClassName        string
+	seccompType             string
+	seccompLocalhostProfile string
+	seccompProfile          *corev1.SeccompProfile
+
+	mountPath  string
+	sourcePath string
+
+	platformUID bool
+
+	decompress      bool
+	decompressLimit int64
+
+	keepSnapshot bool
+
+	gcAfter time.Duration
+
+	deleteTimeout time.Duration
+	forceDelete   bool
+
+	diagnosticsLimit int64
+
+	record string
+
+	transport string
+
+	podImage   string
+	podCommand []string
+	podArgs    []string
+
+	strict bool
+
+	fsync bool
+
+	age *AgeEncryption
+
+	progress *Progress
+
+	sink Sink
+}
+
+// validate checks the options are consistent, before anything is
+// created in the cluster.
+func (opts *options) validate() error {
+	switch corev1.SeccompProfileType(opts.seccompType) {
+	case "":
+		if opts.seccompLocalhostProfile != "" {
+			return fmt.Errorf("seccomp localhost profile %s requires the %s seccomp profile type",
+				opts.seccompLocalhostProfile, corev1.SeccompProfileTypeLocalhost)
+		}
+	case corev1.SeccompProfileTypeLocalhost:
+		if opts.seccompLocalhostProfile == "" {
+			return fmt.Errorf("seccomp profile type %s requires a localhost profile path", corev1.SeccompProfileTypeLocalhost)
+		}
+		opts.seccompProfile = &corev1.SeccompProfile{
+			Type:             corev1.SeccompProfileTypeLocalhost,
+			LocalhostProfile: &opts.seccompLocalhostProfile,
+		}
+	case corev1.SeccompProfileTypeRuntimeDefault, corev1.SeccompProfileTypeUnconfined:
+		if opts.seccompLocalhostProfile != "" {
+			return fmt.Errorf("seccomp profile type %s does not accept a localhost profile path", opts.seccompType)
+		}
+		opts.seccompProfile = &corev1.SeccompProfile{
+			Type: corev1.SeccompProfileType(opts.seccompType),
+		}
+	default:
+		return fmt.Errorf("unsupported seccomp profile type %s", opts.seccompType)
+	}
+
+	if opts.gcAfter < 0 || (opts.gcAfter > 0 && opts.gcAfter < time.Second) {
+		return fmt.Errorf("gc after %s is not a positive number of seconds", opts.gcAfter)
+	}
+
+	if opts.deleteTimeout < 0 {
+		return fmt.Errorf("delete timeout %s is negative", opts.deleteTimeout)
+	}
+	if opts.deleteTimeout == 0 {
+		opts.deleteTimeout = defaultDeleteTimeout
+	}
+
+	if opts.diagnosticsLimit < 0 {
+		return fmt.Errorf("diagnostics limit %d is negative", opts.diagnosticsLimit)
+	}
+	if opts.diagnosticsLimit == 0 {
+		opts.diagnosticsLimit = defaultDiagnosticsLimit
+	}
+
+	if opts.decompressLimit < 0 {
+		return fmt.Errorf("decompress limit %d is negative", opts.decompressLimit)
+	}
+	if opts.decompressLimit == 0 {
+		opts.decompressLimit = defaultDecompressLimit
+	}
+
+	if opts.maxDuration < 0 {
+		return fmt.Errorf("max duration %s is negative", opts.maxDuration)
+	}
+
+	if opts.workers < 0 {
+		return fmt.Errorf("workers %d is negative", opts.workers)
+	}
+	if opts.workers == 0 {
+		opts.workers = runtime.GOMAXPROCS(0)
+	}
+
+	switch opts.transport {
+	case "":
+		opts.transport = TransportAuto
+	case TransportAuto, TransportExec, TransportPortForward:
+	default:
+		return fmt.Errorf("unsupported transport %s", opts.transport)
+	}
+
+	if opts.age != nil {
+		if err := opts.age.validate(); err != nil {
+			return err
+		}
+		// Only the exec streams the archive through age
+		switch {
+		case opts.transport == TransportPortForward:
+			return errors.New("age encryption requires the exec transport")
+		case opts.verifyRemote:
+			return errors.New("could not verify the encrypted files against the remote")
+		case opts.decompress:
+			return errors.New("could not decompress the encrypted files")
+		case opts.record != "":
+			return errors.New("could not record an encrypted extraction")
+		}
+		opts.transport = TransportExec
+	}
+
+	if opts.customPod() {
+		// The file server runs the busybox shell and httpd
+		if opts.transport == TransportPortForward {
+			return errors.New("a custom pod image or command requires the exec transport")
+		}
+		opts.transport = TransportExec
+	}
+
+	if _, err := allSelector(opts.pvcSelector); err != nil {
+		return err
+	}
+
+	if opts.mountPath == "" {
+		opts.mountPath = defaultMountPath
+	}
+	if !path.IsAbs(opts.mountPath) {
+		return fmt.Errorf("mount path %s is not absolute", opts.mountPath)
+	}
+	opts.mountPath = path.Clean(opts.mountPath)
+	if opts.sourcePath == "" {
+		opts.sourcePath = opts.mountPath
+	}
+	if !path.IsAbs(opts.sourcePath) {
+		return fmt.Errorf("source path %s is not absolute", opts.sourcePath)
+	}
+	opts.sourcePath = path.Clean(opts.sourcePath)
+	if opts.sourcePath != opts.mountPath && !strings.HasPrefix(opts.sourcePath, opts.mountPath+"/") {
+		return fmt.Errorf("source path %s is not under the mount path %s", opts.sourcePath, opts.mountPath)
+	}
+	return nil
+}
+
+// checkSink makes sure the files land either into the directory or the
+// sink, defaulting to a DirectorySink, and that the options working on the
+// extracted files have them locally.
+func (opts *options) checkSink(into string) error {
+	if opts.sink == nil {
+		if into == "" {
+			return errors.New("no directory nor sink to extract into")
+		}
+		opts.sink = NewDirectorySink(into)
+	} else if into != "" {
+		return fmt.Errorf("directory %s and %s sink are mutually exclusive", into, opts.sink.Report().Kind)
+	}
+	if _, ok := localDirectory(opts.sink); !ok && opts.decompress {
+		return fmt.Errorf("decompressing requires the files locally, not into the %s sink", opts.sink.Report().Kind)
+	}
+	if _, ok := localDirectory(opts.sink); !ok && opts.age != nil {
+		return fmt.Errorf("age encryption requires the files locally, not into the %s sink", opts.sink.Report().Kind)
+	}
+	if opts.fsync {
+		ds, ok := opts.sink.(*DirectorySink)
+		if !ok {
+			return fmt.Errorf("fsync requires the files locally, not into the %s sink", opts.sink.Report().Kind)
+		}
+		ds.fsync = true
+	}
+	return nil
 }
 
 // Option is the interface for all extraction-related functional options.
@@ -25,6 +235,32 @@ func WithLogger(logger *zap.Logger) Option {
 	return loggerOption{logger: logger}
 }
 
+type kubeContextOption string
+
+func (opt kubeContextOption) apply(opts *options) {
+	opts.kubeContext = string(opt)
+}
+
+// WithKubeContext sets the context of the kubeconfig to reach the cluster
+// through, e.g. to extract from several clusters.
+// Defaults to the current context.
+func WithKubeContext(kubeContext string) Option {
+	return kubeContextOption(kubeContext)
+}
+
+type pvcSelectorOption string
+
+func (opt pvcSelectorOption) apply(opts *options) {
+	opts.pvcSelector = string(opt)
+}
+
+// WithPVCSelector narrows the PVCs DumpAll extracts down to the ones also
+// matching this label selector (e.g. app.kubernetes.io/component=jaeger).
+// Defaults to every PVC of the Monitoring.
+func WithPVCSelector(selector string) Option {
+	return pvcSelectorOption(selector)
+}
+
 type registryOption string
 
 func (opt registryOption) apply(opts *options) {
@@ -37,3 +273,369 @@ func (opt registryOption) apply(opts *options) {
 func WithRegistry(registry string) Option {
 	return registryOption(registry)
 }
+
+type verifyRemoteOption bool
+
+func (opt verifyRemoteOption) apply(opts *options) {
+	opts.verifyRemote = bool(opt)
+}
+
+// WithVerifyRemote turns on the verification of the extracted files against
+// the PVC ones, by comparing their SHA256 checksums computed in the Pod.
+//
+// Files pruned or rotated in the meantime are reported, but only mismatching
+// files fail the extraction.
+func WithVerifyRemote(verify bool) Option {
+	return verifyRemoteOption(verify)
+}
+
+type bandwidthLimitOption int
+
+func (opt bandwidthLimitOption) apply(opts *options) {
+	opts.bandwidthLimit = int(opt)
+}
+
+// WithBandwidthLimit limits the copy from the Pod to the given number of
+// bytes per second on average, to avoid saturating the Kubernetes API server
+// network. Zero or less means no limit.
+func WithBandwidthLimit(bytesPerSec int) Option {
+	return bandwidthLimitOption(bytesPerSec)
+}
+
+type workersOption int
+
+func (opt workersOption) apply(opts *options) {
+	opts.workers = int(opt)
+}
+
+// WithWorkers sets the number of workers writing and hashing the extracted
+// files, while the archive is read sequentially. It speeds up the extraction
+// of many small files. Defaults to GOMAXPROCS.
+func WithWorkers(workers int) Option {
+	return workersOption(workers)
+}
+
+type maxDurationOption time.Duration
+
+func (opt maxDurationOption) apply(opts *options) {
+	opts.maxDuration = time.Duration(opt)
+}
+
+// WithMaxDuration bounds the copy from the Pod, such that a stalled one
+// (e.g. on an NFS issue) fails with ErrDeadlineExceeded rather than hanging
+// forever. It caps the auto deadline, if any. Zero means no deadline.
+func WithMaxDuration(maxDuration time.Duration) Option {
+	return maxDurationOption(maxDuration)
+}
+
+type autoDeadlineOption bool
+
+func (opt autoDeadlineOption) apply(opts *options) {
+	opts.autoDeadline = bool(opt)
+}
+
+// WithAutoDeadline bounds the copy from the Pod by a deadline estimated from
+// the disk usage of the files to copy, measured in the Pod, and the
+// bandwidth limit if any or a conservative throughput otherwise.
+func WithAutoDeadline(auto bool) Option {
+	return autoDeadlineOption(auto)
+}
+
+type runtimeClassOption string
+
+func (opt runtimeClassOption) apply(opts *options) {
+	opts.runtimeClassName = string(opt)
+}
+
+// WithRuntimeClass sets the RuntimeClass of the Pod, e.g. for clusters
+// mandating a sandboxed runtime like gVisor.
+func WithRuntimeClass(runtimeClassName string) Option {
+	return runtimeClassOption(runtimeClassName)
+}
+
+type seccompProfileOption struct {
+	typ, localhostProfile string
+}
+
+func (opt seccompProfileOption) apply(opts *options) {
+	opts.seccompType = opt.typ
+	opts.seccompLocalhostProfile = opt.localhostProfile
+}
+
+// WithSeccompProfile sets the seccomp profile of the Pod container, in place
+// of RuntimeDefault. The localhost profile path is required by, and only
+// accepted with, the Localhost type.
+func WithSeccompProfile(typ, localhostProfile string) Option {
+	return seccompProfileOption{typ: typ, localhostProfile: localhostProfile}
+}
+
+type mountPathOption string
+
+func (opt mountPathOption) apply(opts *options) {
+	opts.mountPath = string(opt)
+}
+
+// WithMountPath sets where the PVC is mounted in the Pod.
+// Defaults to /data.
+func WithMountPath(mountPath string) Option {
+	return mountPathOption(mountPath)
+}
+
+type sourcePathOption string
+
+func (opt sourcePathOption) apply(opts *options) {
+	opts.sourcePath = string(opt)
+}
+
+// WithSourcePath sets the directory to copy files from in the Pod, which must
+// be under the mount path. Defaults to the mount path.
+func WithSourcePath(sourcePath string) Option {
+	return sourcePathOption(sourcePath)
+}
+
+type platformUIDOption bool
+
+func (opt platformUIDOption) apply(opts *options) {
+	opts.platformUID = bool(opt)
+}
+
+// WithPlatformUID leaves the Pod UID unset so the platform assigns it,
+// e.g. on OpenShift where the restricted SCC rejects pinned UIDs out of
+// the namespace range. The Pod still runs as non-root.
+func WithPlatformUID(platformUID bool) Option {
+	return platformUIDOption(platformUID)
+}
+
+type decompressOption bool
+
+func (opt decompressOption) apply(opts *options) {
+	opts.decompress = bool(opt)
+}
+
+// WithDecompress decompresses the extracted .gz and .zst files locally, with
+// their suffix stripped. Corrupted files are kept as-is with a warning.
+// It happens after the verification, which compares the files as on the PVC.
+func WithDecompress(decompress bool) Option {
+	return decompressOption(decompress)
+}
+
+type decompressLimitOption int64
+
+func (opt decompressLimitOption) apply(opts *options) {
+	opts.decompressLimit = int64(opt)
+}
+
+// WithDecompressLimit sets how many bytes the decompressed files total at
+// most, the files beyond it being kept compressed with a warning. It guards
+// the disk against compression bombs. Defaults to 16 GiB.
+func WithDecompressLimit(bytes int64) Option {
+	return decompressLimitOption(bytes)
+}
+
+type keepSnapshotOption bool
+
+func (opt keepSnapshotOption) apply(opts *options) {
+	opts.keepSnapshot = bool(opt)
+}
+
+// WithKeepSnapshot keeps the Prometheus TSDB snapshot in the pod once
+// extracted, rather than removing it.
+func WithKeepSnapshot(keep bool) Option {
+	return keepSnapshotOption(keep)
+}
+
+type gcAfterOption time.Duration
+
+func (opt gcAfterOption) apply(opts *options) {
+	opts.gcAfter = time.Duration(opt)
+}
+
+// WithGCAfter makes the cluster responsible for the deletion of the Pod,
+// even if the extractor dies: it is controlled by a Job, and fails after
+// the given duration (truncated to the second) to be reaped along with it.
+// Zero means the extractor deletes the Pod itself.
+func WithGCAfter(gcAfter time.Duration) Option {
+	return gcAfterOption(gcAfter)
+}
+
+type deleteTimeoutOption time.Duration
+
+func (opt deleteTimeoutOption) apply(opts *options) {
+	opts.deleteTimeout = time.Duration(opt)
+}
+
+// WithDeleteTimeout sets how long the Pod is awaited to disappear once
+// deleted, e.g. held by a stuck CSI detach, before being reported with
+// ErrPodLingering or force deleted. Defaults to 2 minutes.
+func WithDeleteTimeout(timeout time.Duration) Option {
+	return deleteTimeoutOption(timeout)
+}
+
+type forceDeleteOption bool
+
+func (opt forceDeleteOption) apply(opts *options) {
+	opts.forceDelete = bool(opt)
+}
+
+// WithForceDelete force deletes the Pod, i.e. with a grace period of zero,
+// when still there after the delete timeout. It is then removed from the
+// API server even if its node never confirms its termination.
+func WithForceDelete(force bool) Option {
+	return forceDeleteOption(force)
+}
+
+type diagnosticsLimitOption int64
+
+func (opt diagnosticsLimitOption) apply(opts *options) {
+	opts.diagnosticsLimit = int64(opt)
+}
+
+// WithDiagnosticsLimit sets how many bytes of the extraction Pod logs are
+// kept, the last ones, when the extraction fails (see PodDiagnostics).
+// Defaults to 64 KiB.
+func WithDiagnosticsLimit(bytes int64) Option {
+	return diagnosticsLimitOption(bytes)
+}
+
+type recordOption string
+
+func (opt recordOption) apply(opts *options) {
+	opts.record = string(opt)
+}
+
+// WithRecord records the outputs of the extraction commands run in the Pod
+// (the raw tar stream, the disk usage and the checksums) along the Pod and
+// PVC metadata into the given directory, for ReplayOTelCollector to feed
+// them through the extraction pipeline without a cluster, e.g. to develop
+// the extractor. Only supported with DumpOTelCollector.
+func WithRecord(dir string) Option {
+	return recordOption(dir)
+}
+
+type transportOption string
+
+func (opt transportOption) apply(opts *options) {
+	opts.transport = string(opt)
+}
+
+// WithTransport sets how the extraction commands reach the Pod: exec,
+// port-forward to a file server in the Pod for clusters where exec is
+// disabled, or auto to fall back to the latter once exec is forbidden.
+// Defaults to auto. The Prometheus snapshots always exec.
+func WithTransport(transport string) Option {
+	return transportOption(transport)
+}
+
+type strictOption bool
+
+func (opt strictOption) apply(opts *options) {
+	opts.strict = bool(opt)
+}
+
+// WithStrict fails the extraction when files vanish from the source while
+// archived, e.g. rotated by the OTEL Collector. By default, the others are
+// extracted and the vanished ones reported as warnings.
+func WithStrict(strict bool) Option {
+	return strictOption(strict)
+}
+
+type fsyncOption bool
+
+func (opt fsyncOption) apply(opts *options) {
+	opts.fsync = bool(opt)
+}
+
+// WithFsync syncs the directory of each extracted file once renamed into
+// place, and the parent of each directory created, before counting the file
+// complete. The files themselves are always synced before being renamed,
+// but their directory entries could otherwise be lost on a crash or an
+// unmount, e.g. of an NFS destination. Only supported with the directory
+// sink.
+func WithFsync(fsync bool) Option {
+	return fsyncOption(fsync)
+}
+
+type ageEncryptionOption AgeEncryption
+
+func (opt ageEncryptionOption) apply(opts *options) {
+	enc := AgeEncryption(opt)
+	opts.age = &enc
+}
+
+// WithAgeEncryption encrypts the archive of the PVC with age in the
+// extraction Pod, so that only the ciphertext crosses the exec stream. It
+// lands as EncryptedArchiveFile into the directory, for the holder of the
+// identity to decrypt and untar. It requires the exec transport, and fails
+// with ErrAgeMissing if the image does not provide the age binary. Files
+// vanishing while archived fail the extraction, as WithStrict would.
+// Not supported with DumpPrometheus.
+func WithAgeEncryption(enc AgeEncryption) Option {
+	return ageEncryptionOption(enc)
+}
+
+type podImageOption string
+
+func (opt podImageOption) apply(opts *options) {
+	opts.podImage = string(opt)
+}
+
+// WithPodImage sets the image of the extraction Pod copy container, pulled
+// as is whatever WithRegistry, e.g. a distroless one. It must provide tar,
+// else the extraction fails with ErrTarMissing before copying anything.
+// It requires the exec transport. Defaults to busybox.
+func WithPodImage(image string) Option {
+	return podImageOption(image)
+}
+
+type podCommandOption struct {
+	command []string
+	args    []string
+}
+
+func (opt podCommandOption) apply(opts *options) {
+	opts.podCommand = opt.command
+	opts.podArgs = opt.args
+}
+
+// WithPodCommand sets the command and arguments keeping the extraction Pod
+// copy container alive, for images without a shell. Without a command, the
+// arguments are passed to the default shell one, i.e. "/bin/sh -c --".
+// When the container could not run it, the extraction fails with
+// ErrPodCommand. It requires the exec transport.
+// Defaults to "/bin/sh -c -- 'sleep infinity'", which works for busybox.
+func WithPodCommand(command, args []string) Option {
+	return podCommandOption{
+		command: command,
+		args:    args,
+	}
+}
+
+type progressOption struct {
+	progress *Progress
+}
+
+func (opt progressOption) apply(opts *options) {
+	opts.progress = opt.progress
+}
+
+// WithProgress emits the phases of the extraction and its counters into the
+// progress, e.g. for a UI to follow it. The caller ends it with Done.
+func WithProgress(progress *Progress) Option {
+	return progressOption{progress: progress}
+}
+
+type sinkOption struct {
+	sink Sink
+}
+
+func (opt sinkOption) apply(opts *options) {
+	opts.sink = opt.sink
+}
+
+// WithSink sets where the extracted files land, in place of the directory
+// which must then be empty: e.g. a tar.gz archive, an S3 object or an OCI
+// artifact. The report file is written last into it. Decompressing,
+// encrypting with age and extracting every PVC require a DirectorySink.
+func WithSink(sink Sink) Option {
+	return sinkOption{sink: sink}
+}
@@ -0,0 +1,81 @@
+package extract
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PartialSuffix is appended to the name of the files being extracted, until
+// they are complete. Lingering ones are leftovers of an interrupted
+// extraction, overwritten by the next one into the same directory.
+const PartialSuffix = ".partial"
+
+// writeFile copies r into target through a partial file, renamed into place
+// once complete and flushed to disk. Such that the target is either absent
+// or complete, whatever interrupts the copy.
+func writeFile(target string, r io.Reader, perm fs.FileMode) (int64, error) {
+	partial := target + PartialSuffix
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// Keep the partial file, as a crash would
+		return n, err
+	}
+	return n, os.Rename(partial, target)
+}
+
+// findPartials returns the partial files under dir, relative to it.
+func findPartials(dir string) ([]string, error) {
+	partials := []string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(p, PartialSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		partials = append(partials, rel)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return partials, nil
+	}
+	sort.Strings(partials)
+	return partials, err
+}
+
+// notePartials records the partial files remaining in the extraction
+// directory, i.e. leftovers of an interrupted extraction the copy did not
+// overwrite (e.g. rotated on the PVC since).
+func (res *Result) notePartials() error {
+	// Only a local directory holds partial files
+	if res.Directory == "" {
+		return nil
+	}
+	partials, err := findPartials(res.Directory)
+	if err != nil {
+		return err
+	}
+	res.Partials = partials
+	for _, p := range partials {
+		res.Warnings = append(res.Warnings, "incomplete file left by an interrupted extraction: "+p)
+	}
+	return nil
+}
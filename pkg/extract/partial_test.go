@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func Test_U_Untar_Interrupted(t *testing.T) {
+	t.Parallel()
+
+	// Big enough to be streamed into place rather than buffered
+	line := []byte(`{"resourceSpans":[]}` + "\n")
+	content := bytes.Repeat(line, maxBufferedFileSize/len(line)+1)
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "./collector/otel_traces", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := buf.Bytes()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "collector", "otel_traces")
+
+	// The stream breaks between the write and the rename
+	if _, err := untar(context.Background(), bytes.NewReader(archive[:len(archive)/2]), NewDirectorySink(dir), 1, false, nil); err == nil {
+		t.Fatal("expected an error on a truncated archive")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("expected no truncated file in place, got %v", err)
+	}
+	partials, err := findPartials(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(partials, []string{filepath.Join("collector", "otel_traces"+PartialSuffix)}) {
+		t.Fatalf("expected the partial file to linger, got %v", partials)
+	}
+	local, err := localChecksums(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(local) != 0 {
+		t.Errorf("expected partial files not to be verified, got %v", local)
+	}
+
+	// The next extraction overwrites the leftover
+	copied, err := untar(context.Background(), bytes.NewReader(archive), NewDirectorySink(dir), 1, false, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if copied.files != 1 || copied.size != int64(len(content)) {
+		t.Errorf("expected 1 file of %d bytes, got %d of %d", len(content), copied.files, copied.size)
+	}
+	got, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("expected the file to be complete")
+	}
+	res := &Result{Directory: dir}
+	if err := res.notePartials(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(res.Partials) != 0 || len(res.Warnings) != 0 {
+		t.Errorf("expected no leftover, got %v %v", res.Partials, res.Warnings)
+	}
+}
+
+func Test_U_NotePartials(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "otel_logs"+PartialSuffix), []byte(`{"resour`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "otel_traces"), []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res := &Result{Directory: dir}
+	if err := res.notePartials(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(res.Partials, []string{"otel_logs" + PartialSuffix}) {
+		t.Errorf("expected the leftover to be recorded, got %v", res.Partials)
+	}
+	if len(res.Warnings) != 1 {
+		t.Errorf("expected a warning, got %v", res.Warnings)
+	}
+
+	// Nothing extracted yet
+	res = &Result{Directory: filepath.Join(dir, "missing")}
+	if err := res.notePartials(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	// ErrPodCommand is returned when the copy container could not run the
+	// keep-alive command, e.g. the image has no shell for the default one.
+	ErrPodCommand = errors.New("extraction pod command failed")
+
+	// ErrTarMissing is returned when the image of the copy container does
+	// not provide tar to archive the PVC with.
+	ErrTarMissing = errors.New("tar missing from the extraction pod image")
+)
+
+var (
+	// defaultPodCommand and defaultPodArgs keep the busybox copy container
+	// alive for the commands to exec into it.
+	defaultPodCommand = []string{"/bin/sh", "-c", "--"}
+	defaultPodArgs    = []string{"sleep infinity"}
+)
+
+// podCommandReasons are the reasons of a container which could not start
+// its command.
+var podCommandReasons = []string{
+	"CrashLoopBackOff",
+	"RunContainerError",
+	"CreateContainerError",
+	"StartError",
+}
+
+// customPod returns whether the copy container does not run the default
+// image or command, hence could not serve the files nor be assumed to
+// provide tar.
+func (opts *options) customPod() bool {
+	return opts.podImage != "" || len(opts.podCommand) != 0 || len(opts.podArgs) != 0
+}
+
+// podEntrypoint returns the command and arguments of the copy container, the
+// defaults completing the ones not set.
+func (opts *options) podEntrypoint() (command, args []string) {
+	command, args = defaultPodCommand, defaultPodArgs
+	if len(opts.podCommand) != 0 {
+		// The default arguments are meant for the shell only
+		command, args = opts.podCommand, nil
+	}
+	if len(opts.podArgs) != 0 {
+		args = opts.podArgs
+	}
+	return slices.Clone(command), slices.Clone(args)
+}
+
+// podCommandError tells whether the copy container of the Pod could not run
+// its command, e.g. missing from the image, rather than waiting for it to
+// be ready until the timeout.
+func podCommandError(pod *corev1.Pod) error {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != "copy" {
+			continue
+		}
+		switch waiting, term := status.State.Waiting, status.State.Terminated; {
+		case waiting != nil && slices.Contains(podCommandReasons, waiting.Reason):
+			return fmt.Errorf("%w: %q %s: %s", ErrPodCommand, podContainerCommand(pod), waiting.Reason, waiting.Message)
+		// 126 and 127 are the shell codes of a command not executable or
+		// not found
+		case term != nil && (term.ExitCode == 126 || term.ExitCode == 127 || term.Reason == "StartError"):
+			return fmt.Errorf("%w: %q exited with code %d: %s", ErrPodCommand, podContainerCommand(pod), term.ExitCode, term.Message)
+		}
+	}
+	return nil
+}
+
+// podContainerCommand returns the command and arguments of the copy
+// container of the Pod, for the errors to tell which one failed.
+func podContainerCommand(pod *corev1.Pod) []string {
+	for _, ctr := range pod.Spec.Containers {
+		if ctr.Name == "copy" {
+			return append(slices.Clone(ctr.Command), ctr.Args...)
+		}
+	}
+	return nil
+}
+
+// probeTar makes sure tar runs in the copy container, before streaming
+// anything. Only a command not found fails, as the tar implementations
+// don't agree on the flags printing their version.
+func probeTar(ctx context.Context, exec podExecutor) error {
+	err := exec(ctx, []string{"tar", "--version"}, &bytes.Buffer{})
+	if err == nil {
+		return nil
+	}
+	if ee := (*exitError)(nil); errors.As(err, &ee) {
+		if ee.code != 126 && ee.code != 127 {
+			return nil
+		}
+	} else if !strings.Contains(err.Error(), "executable file not found") && !strings.Contains(err.Error(), "no such file or directory") {
+		return err
+	}
+	return fmt.Errorf("%w, use an image providing it or the default one: %w", ErrTarMissing, err)
+}
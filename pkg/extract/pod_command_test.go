@@ -0,0 +1,254 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_U_ExtractorPod_Command(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Opts              []Option
+		ExpectErr         bool
+		ExpectedImage     string
+		ExpectedCommand   []string
+		ExpectedArgs      []string
+		ExpectedTransport string
+	}{
+		"defaults": {
+			Opts: []Option{
+				WithTransport(TransportExec),
+			},
+			ExpectedImage:     img,
+			ExpectedCommand:   []string{"/bin/sh", "-c", "--"},
+			ExpectedArgs:      []string{"sleep infinity"},
+			ExpectedTransport: TransportExec,
+		},
+		"distroless": {
+			Opts: []Option{
+				WithPodImage("registry.example.com/extractor:distroless"),
+				WithPodCommand([]string{"/busybox/sleep"}, []string{"infinity"}),
+			},
+			ExpectedImage:     "registry.example.com/extractor:distroless",
+			ExpectedCommand:   []string{"/busybox/sleep"},
+			ExpectedArgs:      []string{"infinity"},
+			ExpectedTransport: TransportExec,
+		},
+		"command-without-args": {
+			Opts: []Option{
+				WithPodCommand([]string{"/pause"}, nil),
+			},
+			ExpectedImage:     img,
+			ExpectedCommand:   []string{"/pause"},
+			ExpectedTransport: TransportExec,
+		},
+		"args-only": {
+			Opts: []Option{
+				WithPodCommand(nil, []string{"while true; do sleep 3600; done"}),
+			},
+			ExpectedImage:     img,
+			ExpectedCommand:   []string{"/bin/sh", "-c", "--"},
+			ExpectedArgs:      []string{"while true; do sleep 3600; done"},
+			ExpectedTransport: TransportExec,
+		},
+		"image-only": {
+			Opts: []Option{
+				WithRegistry("registry.example.com"),
+				WithPodImage("alpine:3.22"),
+			},
+			ExpectedImage:     "alpine:3.22",
+			ExpectedCommand:   []string{"/bin/sh", "-c", "--"},
+			ExpectedArgs:      []string{"sleep infinity"},
+			ExpectedTransport: TransportExec,
+		},
+		"port-forward": {
+			Opts: []Option{
+				WithTransport(TransportPortForward),
+				WithPodCommand([]string{"/busybox/sleep"}, []string{"infinity"}),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			for _, opt := range tt.Opts {
+				opt.apply(options)
+			}
+			err := options.validate()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+			if options.transport != tt.ExpectedTransport {
+				t.Errorf("expected transport %s, got %s", tt.ExpectedTransport, options.transport)
+			}
+
+			ctr := extractorPod("monitoring", "signals", options).Spec.Containers[0]
+			if ctr.Image != tt.ExpectedImage {
+				t.Errorf("expected image %s, got %s", tt.ExpectedImage, ctr.Image)
+			}
+			if !slices.Equal(ctr.Command, tt.ExpectedCommand) {
+				t.Errorf("expected command %q, got %q", tt.ExpectedCommand, ctr.Command)
+			}
+			if !slices.Equal(ctr.Args, tt.ExpectedArgs) {
+				t.Errorf("expected args %q, got %q", tt.ExpectedArgs, ctr.Args)
+			}
+		})
+	}
+}
+
+func Test_U_PodCommandError(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		State     corev1.ContainerState
+		ExpectErr bool
+	}{
+		"creating": {
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"},
+			},
+		},
+		"running": {
+			State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{},
+			},
+		},
+		"crash-loop": {
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s restarting failed container"},
+			},
+			ExpectErr: true,
+		},
+		"no-shell": {
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{
+					Reason:   "StartError",
+					ExitCode: 128,
+					Message:  `exec: "/bin/sh": stat /bin/sh: no such file or directory`,
+				},
+			},
+			ExpectErr: true,
+		},
+		"not-found": {
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 127},
+			},
+			ExpectErr: true,
+		},
+		"exited": {
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1},
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			pod := extractorPod("monitoring", "signals", &options{})
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{Name: "copy", State: tt.State},
+			}
+			err := podCommandError(pod)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr && !errors.Is(err, ErrPodCommand) {
+				t.Errorf("expected ErrPodCommand, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_U_WaitForPodReady_BadCommand(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      podName,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name: "copy",
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "RunContainerError"},
+					},
+				},
+			},
+		},
+	})
+
+	start := time.Now()
+	if err := waitForPodReady(context.Background(), clientset, "monitoring", podName); !errors.Is(err, ErrPodCommand) {
+		t.Fatalf("expected ErrPodCommand, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to fail at once, waited %s", elapsed)
+	}
+}
+
+func Test_U_ProbeTar(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Err           error
+		ExpectErr     bool
+		ExpectMissing bool
+	}{
+		"present": {},
+		"unknown-flag": {
+			Err: &exitError{command: []string{"tar"}, code: 1, stderr: "tar: unrecognized option '--version'"},
+		},
+		"not-found": {
+			Err:           &exitError{command: []string{"tar"}, code: 127},
+			ExpectErr:     true,
+			ExpectMissing: true,
+		},
+		"not-in-path": {
+			Err:           errors.New(`stream error: exec: "tar": executable file not found in $PATH`),
+			ExpectErr:     true,
+			ExpectMissing: true,
+		},
+		"stream-error": {
+			Err:       errors.New("stream error: connection reset by peer"),
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			exec := func(_ context.Context, _ []string, _ io.Writer) error {
+				return tt.Err
+			}
+			err := probeTar(context.Background(), exec)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if errors.Is(err, ErrTarMissing) != tt.ExpectMissing {
+				t.Errorf("expected tar missing: %t, got: %v", tt.ExpectMissing, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package extract
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressSchemaVersion is the version of the ProgressEvent schema.
+// Bump it on any breaking change, as the consumers parse the events.
+const ProgressSchemaVersion = 1
+
+// Types of the progress events.
+const (
+	// EventPhase starts a phase of the extraction.
+	EventPhase = "phase"
+	// EventProgress reports the files and bytes extracted so far.
+	EventProgress = "progress"
+	// EventWarning reports a non-fatal issue of the extraction.
+	EventWarning = "warning"
+	// EventDone ends the extraction, successful or not.
+	EventDone = "done"
+)
+
+// Phases of an extraction, in order. Some are skipped depending on the
+// source and options.
+const (
+	PhaseCreatingPod   = "creating-pod"
+	PhaseWaitingPod    = "waiting-pod"
+	PhaseCopying       = "copying"
+	PhaseVerifying     = "verifying"
+	PhaseDecompressing = "decompressing"
+	PhaseDeletingPod   = "deleting-pod"
+)
+
+// ProgressEvent is a line of the progress events stream.
+type ProgressEvent struct {
+	Schema int       `json:"schema"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+
+	// Phase started, for EventPhase.
+	Phase string `json:"phase,omitempty"`
+
+	// Files and Bytes extracted so far, for EventProgress and EventDone.
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+
+	// Message of the EventWarning.
+	Message string `json:"message,omitempty"`
+
+	// Status of the EventDone, either success or failure, with its Error.
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Report is the path to the report file, for EventDone.
+	Report string `json:"report,omitempty"`
+}
+
+// Progress emits the progress events of an extraction as newline-delimited
+// JSON. The extraction emits the phases and counters (see WithProgress),
+// Done ends the stream. A nil Progress emits nothing.
+type Progress struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+
+	files atomic.Int64
+	bytes atomic.Int64
+}
+
+// NewProgress returns the Progress writing into w, with the counters
+// emitted every interval while copying.
+func NewProgress(w io.Writer, interval time.Duration) *Progress {
+	return &Progress{
+		interval: interval,
+		now:      time.Now,
+		enc:      json.NewEncoder(w),
+	}
+}
+
+// emit writes the event, stamped. The first write error is kept for Done to
+// return it, rather than failing the extraction on its progress.
+func (p *Progress) emit(ev ProgressEvent) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return
+	}
+	ev.Schema = ProgressSchemaVersion
+	ev.Time = p.now().UTC()
+	p.err = p.enc.Encode(ev)
+}
+
+func (p *Progress) phase(phase string) {
+	p.emit(ProgressEvent{
+		Type:  EventPhase,
+		Phase: phase,
+	})
+}
+
+// add counts an extracted file of n bytes.
+func (p *Progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.files.Add(1)
+	p.bytes.Add(n)
+}
+
+func (p *Progress) counters() ProgressEvent {
+	return ProgressEvent{
+		Type:  EventProgress,
+		Files: int(p.files.Load()),
+		Bytes: p.bytes.Load(),
+	}
+}
+
+// track emits the counters every interval until the returned function is
+// called, which emits them a last time.
+func (p *Progress) track() func() {
+	if p == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	if p.interval > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ticker := time.NewTicker(p.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.emit(p.counters())
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+	return func() {
+		close(stop)
+		wg.Wait()
+		p.emit(p.counters())
+	}
+}
+
+// Done emits the warnings of the result then the end of the extraction, and
+// returns the first error writing the events. The result could be nil if
+// the extraction failed early.
+func (p *Progress) Done(res *Result, err error) error {
+	if p == nil {
+		return nil
+	}
+	done := ProgressEvent{
+		Type:   EventDone,
+		Status: "success",
+		Files:  int(p.files.Load()),
+		Bytes:  p.bytes.Load(),
+	}
+	if res != nil {
+		for _, w := range res.Warnings {
+			p.emit(ProgressEvent{
+				Type:    EventWarning,
+				Message: w,
+			})
+		}
+		done.Files, done.Bytes, done.Report = res.Files, res.Bytes, res.Report
+	}
+	if err != nil {
+		done.Status = "failure"
+		done.Error = err.Error()
+	}
+	p.emit(done)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
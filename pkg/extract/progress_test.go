@@ -0,0 +1,131 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func Test_U_Progress_Replay(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Opts     []Option
+		Expected []string
+	}{
+		"plain": {
+			Expected: []string{
+				"phase:" + PhaseCopying,
+				EventProgress,
+				EventDone,
+			},
+		},
+		"verify-decompress": {
+			Opts: []Option{
+				WithVerifyRemote(true),
+				WithDecompress(true),
+			},
+			Expected: []string{
+				"phase:" + PhaseCopying,
+				EventProgress,
+				"phase:" + PhaseVerifying,
+				"phase:" + PhaseDecompressing,
+				EventDone,
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			buf := &bytes.Buffer{}
+			progress := NewProgress(buf, 0)
+			res, err := ReplayOTelCollector(context.Background(), fixtureDir, t.TempDir(), append(tt.Opts, WithProgress(progress))...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if err := progress.Done(res, nil); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			events := parseProgressEvents(t, buf)
+			got := []string{}
+			for _, ev := range events {
+				if ev.Schema != ProgressSchemaVersion {
+					t.Errorf("expected schema %d, got %d", ProgressSchemaVersion, ev.Schema)
+				}
+				if ev.Type == EventPhase {
+					got = append(got, "phase:"+ev.Phase)
+					continue
+				}
+				got = append(got, ev.Type)
+			}
+			if !reflect.DeepEqual(got, tt.Expected) {
+				t.Fatalf("expected events %v, got %v", tt.Expected, got)
+			}
+
+			// The counters add up to the result
+			last := events[len(events)-1]
+			if last.Status != "success" || last.Files != res.Files || last.Bytes != res.Bytes || last.Report != res.Report {
+				t.Errorf("expected the done event to match the result %+v, got %+v", res, last)
+			}
+			for _, ev := range events {
+				if ev.Type == EventProgress && (ev.Files != len(fixtureFiles) || ev.Bytes == 0) {
+					t.Errorf("expected the copy counters of %d files, got %+v", len(fixtureFiles), ev)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Progress_Done(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	progress := NewProgress(buf, 0)
+	err := progress.Done(&Result{
+		Warnings: []string{"file no longer on the PVC: otel_logs"},
+	}, errors.New("1 extracted files mismatch the PVC ones: otel_metrics"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	events := parseProgressEvents(t, buf)
+	if len(events) != 2 {
+		t.Fatalf("expected a warning and done events, got %+v", events)
+	}
+	if events[0].Type != EventWarning || events[0].Message != "file no longer on the PVC: otel_logs" {
+		t.Errorf("expected the warning event, got %+v", events[0])
+	}
+	if events[1].Type != EventDone || events[1].Status != "failure" || events[1].Error == "" {
+		t.Errorf("expected the failed done event, got %+v", events[1])
+	}
+
+	// No progress emits nothing
+	var none *Progress
+	none.phase(PhaseCopying)
+	none.track()()
+	if err := none.Done(nil, nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func parseProgressEvents(t *testing.T, buf *bytes.Buffer) []ProgressEvent {
+	t.Helper()
+
+	events := []ProgressEvent{}
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		ev := ProgressEvent{}
+		if err := json.Unmarshal(sc.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid event %s: %s", sc.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
@@ -0,0 +1,245 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	prometheusContainer = "prometheus"
+	prometheusAdminFlag = "--web.enable-admin-api"
+	prometheusTSDBFlag  = "--storage.tsdb.path="
+
+	// defaultTSDBPath is where Prometheus stores its TSDB when not set by
+	// flag, relative to the image working directory /prometheus.
+	defaultTSDBPath = "/prometheus/data"
+)
+
+// prometheusSelector matches the Prometheus pods of the Monitoring.
+var prometheusSelector = labels.Set{
+	"app.kubernetes.io/part-of":   "monitoring",
+	"app.kubernetes.io/component": "prometheus",
+}
+
+// ErrAdminAPIDisabled is returned when extracting a Prometheus which does
+// not serve its admin API, required to take snapshots.
+var ErrAdminAPIDisabled = errors.New("prometheus admin api is disabled, turn on prometheus-admin-api to take snapshots")
+
+// DumpPrometheus takes a snapshot of the Prometheus TSDB of the Monitoring,
+// given its namespace, and copies it into the provided directory (creates it
+// if necessary) or the sink of WithSink. The snapshot is removed afterwards,
+// unless kept through WithKeepSnapshot.
+// It returns the summary of the extraction, also written as the report file
+// in the directory. A failed extraction returns it partial along the error,
+// nil only for invalid options.
+func DumpPrometheus(
+	ctx context.Context,
+	namespace, into string,
+	opts ...Option,
+) (*Result, error) {
+	// Prepare functional options
+	options := &options{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.record != "" {
+		return nil, errors.New("recording is only supported for the otel collector")
+	}
+	if options.age != nil {
+		return nil, errors.New("age encryption is not supported for the prometheus snapshots")
+	}
+	if err := options.checkSink(into); err != nil {
+		return nil, err
+	}
+	directory, _ := localDirectory(options.sink)
+
+	res := &Result{
+		Source:    SourcePrometheus,
+		Namespace: namespace,
+		Directory: directory,
+		StartedAt: time.Now(),
+	}
+	res.noteFilesystem(options)
+
+	// Prepare K8s client
+	clientset, config, err := getClient(options.kubeContext)
+	if err != nil {
+		return res, err
+	}
+
+	// Find the Prometheus pod, and make sure it could take snapshots
+	pod, err := findPrometheusPod(ctx, clientset, namespace)
+	if err != nil {
+		return res, err
+	}
+	ctr := prometheusContainerOf(pod)
+	if !adminAPIEnabled(ctr) {
+		return res, ErrAdminAPIDisabled
+	}
+
+	// Take the snapshot
+	options.logger.Info("taking snapshot",
+		zap.String("pod", pod.Name),
+		zap.String("namespace", namespace),
+	)
+	out := &bytes.Buffer{}
+	if err := execInPod(ctx, config, clientset, namespace, pod.Name, prometheusContainer, snapshotCommand(), out); err != nil {
+		return res, err
+	}
+	res.Snapshot, err = parseSnapshotResponse(out.Bytes())
+	if err != nil {
+		return res, err
+	}
+	snapshotPath := path.Join(tsdbPath(ctr), "snapshots", res.Snapshot)
+
+	// Copy the snapshot, then clean it up whatever happened
+	if err := options.sink.Open(ctx); err != nil {
+		return res, err
+	}
+	copied, err := copySnapshot(ctx, config, clientset, namespace, pod.Name, snapshotPath, options)
+	if !options.keepSnapshot {
+		options.logger.Info("removing snapshot",
+			zap.String("snapshot", snapshotPath),
+		)
+		if rerr := execInPod(ctx, config, clientset, namespace, pod.Name, prometheusContainer, []string{"rm", "-rf", snapshotPath}, &bytes.Buffer{}); rerr != nil {
+			err = errors.Join(err, fmt.Errorf("removing snapshot %s: %w", snapshotPath, rerr))
+		}
+	}
+	if err != nil {
+		return res, abortSink(ctx, options.sink, err)
+	}
+	res.Files, res.Bytes = copied.files, copied.size
+	res.Inventory = sortedInventory(copied.inventory)
+	res.noteVanished(copied.vanished)
+	if err := res.notePartials(); err != nil {
+		return res, abortSink(ctx, options.sink, err)
+	}
+
+	if err := res.finish(ctx, options.sink); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// copySnapshot waits for the snapshot directory to appear, then copies it.
+func copySnapshot(
+	ctx context.Context,
+	config *rest.Config,
+	clientset *kubernetes.Clientset,
+	namespace, podName, snapshotPath string,
+	options *options,
+) (*untarResult, error) {
+	options.logger.Info("waiting for the snapshot",
+		zap.String("snapshot", snapshotPath),
+	)
+	if err := wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		err := execInPod(ctx, config, clientset, namespace, podName, prometheusContainer, []string{"test", "-d", snapshotPath}, &bytes.Buffer{})
+		return err == nil, nil
+	}); err != nil {
+		return nil, fmt.Errorf("snapshot %s did not appear: %w", snapshotPath, err)
+	}
+
+	options.logger.Info("copying files",
+		zap.String("sink", options.sink.Report().Location),
+	)
+	return copyFromPod(ctx, newPodExecutor(config, clientset, namespace, podName, prometheusContainer), snapshotPath, options.sink, options)
+}
+
+// findPrometheusPod returns a ready Prometheus pod of the namespace.
+func findPrometheusPod(ctx context.Context, client kubernetes.Interface, namespace string) (*corev1.Pod, error) {
+	var pods *corev1.PodList
+	if err := retryAPI(ctx, defaultBackoff, func() (err error) {
+		pods, err = client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: prometheusSelector.String(),
+		})
+		return
+	}); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return &pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no ready prometheus pod in namespace %s", namespace)
+}
+
+// prometheusContainerOf returns the Prometheus container of the pod.
+func prometheusContainerOf(pod *corev1.Pod) corev1.Container {
+	for _, ctr := range pod.Spec.Containers {
+		if ctr.Name == prometheusContainer {
+			return ctr
+		}
+	}
+	return corev1.Container{}
+}
+
+// adminAPIEnabled returns whether the Prometheus container serves the admin API.
+func adminAPIEnabled(ctr corev1.Container) bool {
+	return slices.Contains(ctr.Args, prometheusAdminFlag)
+}
+
+// tsdbPath returns the absolute path of the Prometheus TSDB.
+func tsdbPath(ctr corev1.Container) string {
+	for _, arg := range ctr.Args {
+		if p, ok := strings.CutPrefix(arg, prometheusTSDBFlag); ok {
+			if !path.IsAbs(p) {
+				p = path.Join("/prometheus", p)
+			}
+			return path.Clean(p)
+		}
+	}
+	return defaultTSDBPath
+}
+
+// snapshotCommand calls the Prometheus snapshot API from within its pod.
+func snapshotCommand() []string {
+	return []string{"wget", "-qO-", "--post-data=", "http://localhost:9090/api/v1/admin/tsdb/snapshot"}
+}
+
+// parseSnapshotResponse returns the name of the snapshot taken.
+func parseSnapshotResponse(b []byte) (string, error) {
+	resp := struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Name string `json:"name"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return "", fmt.Errorf("invalid snapshot response: %w", err)
+	}
+	if resp.Status != "success" {
+		return "", fmt.Errorf("snapshot failed: %s", resp.Error)
+	}
+	// The name is used as a path, make sure it does not escape the snapshots directory
+	if resp.Data.Name == "" || resp.Data.Name != path.Base(resp.Data.Name) || resp.Data.Name == ".." {
+		return "", fmt.Errorf("invalid snapshot name %q", resp.Data.Name)
+	}
+	return resp.Data.Name, nil
+}
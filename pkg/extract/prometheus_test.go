@@ -0,0 +1,143 @@
+package extract
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_U_ParseSnapshotResponse(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Response  string
+		Expected  string
+		ExpectErr bool
+	}{
+		"success": {
+			Response: `{"status":"success","data":{"name":"20260101T000000Z-2be650b6d019eb54"}}`,
+			Expected: "20260101T000000Z-2be650b6d019eb54",
+		},
+		"admin-api-disabled": {
+			Response:  `{"status":"error","errorType":"unavailable","error":"admin APIs disabled"}`,
+			ExpectErr: true,
+		},
+		"invalid-json": {
+			Response:  `wget: server returned error: HTTP/1.1 405 Method Not Allowed`,
+			ExpectErr: true,
+		},
+		"empty-name": {
+			Response:  `{"status":"success","data":{"name":""}}`,
+			ExpectErr: true,
+		},
+		"path-traversal": {
+			Response:  `{"status":"success","data":{"name":"../../etc"}}`,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			name, err := parseSnapshotResponse([]byte(tt.Response))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if name != tt.Expected {
+				t.Errorf("expected snapshot %s, got %s", tt.Expected, name)
+			}
+		})
+	}
+}
+
+func Test_U_TSDBPath(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args     []string
+		Expected string
+	}{
+		"default": {
+			Args:     []string{"--config.file=/etc/prometheus/config.yaml"},
+			Expected: "/prometheus/data",
+		},
+		"absolute": {
+			Args:     []string{"--storage.tsdb.path=/var/lib/prometheus/"},
+			Expected: "/var/lib/prometheus",
+		},
+		"relative": {
+			Args:     []string{"--storage.tsdb.path=tsdb"},
+			Expected: "/prometheus/tsdb",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if p := tsdbPath(corev1.Container{Args: tt.Args}); p != tt.Expected {
+				t.Errorf("expected TSDB path %s, got %s", tt.Expected, p)
+			}
+		})
+	}
+}
+
+func Test_U_FindPrometheusPod(t *testing.T) {
+	t.Parallel()
+
+	pod := func(name string, ready bool, lbls map[string]string) *corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "monitoring",
+				Name:      name,
+				Labels:    lbls,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: prometheusContainer,
+						Args: []string{prometheusAdminFlag},
+					},
+				},
+			},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: status},
+				},
+			},
+		}
+	}
+
+	clientset := fake.NewClientset(
+		pod("prometheus-starting", false, prometheusSelector),
+		pod("jaeger", true, map[string]string{"app.kubernetes.io/part-of": "monitoring"}),
+		pod("prometheus-ready", true, prometheusSelector),
+	)
+	got, err := findPrometheusPod(context.Background(), clientset, "monitoring")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Name != "prometheus-ready" {
+		t.Errorf("expected the ready prometheus pod, got %s", got.Name)
+	}
+
+	// Admin API detection
+	if !adminAPIEnabled(prometheusContainerOf(got)) {
+		t.Error("expected the admin api to be detected")
+	}
+	if adminAPIEnabled(corev1.Container{Args: []string{"--config.file=/etc/prometheus/config.yaml"}}) {
+		t.Error("expected the admin api to be detected as disabled")
+	}
+
+	if _, err := findPrometheusPod(context.Background(), clientset, "other"); err == nil {
+		t.Error("expected an error without any prometheus pod")
+	}
+}
@@ -0,0 +1,51 @@
+package extract
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitedReader is a token-bucket limited reader, with one token per byte.
+type rateLimitedReader struct {
+	ctx context.Context
+	r   io.Reader
+	lim *rate.Limiter
+}
+
+// newRateLimitedReader wraps r to read at most bytesPerSec on average.
+// The bucket holds one second of bytes, so short bursts are smoothed out.
+func newRateLimitedReader(ctx context.Context, r io.Reader, bytesPerSec int) io.Reader {
+	return &rateLimitedReader{
+		ctx: ctx,
+		r:   r,
+		lim: rate.NewLimiter(rate.Limit(bytesPerSec), bytesPerSec),
+	}
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Never ask for more tokens than the bucket could hold
+	if len(p) > r.lim.Burst() {
+		p = p[:r.lim.Burst()]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.lim.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,48 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_U_RateLimitedReader(t *testing.T) {
+	t.Parallel()
+
+	const (
+		limit = 64 * 1024  // 64KiB/s
+		size  = 192 * 1024 // 3x the limit
+	)
+
+	// Fake exec stream, as fast as possible
+	stream := bytes.NewReader(make([]byte, size))
+	cr := &countingReader{r: newRateLimitedReader(context.Background(), stream, limit)}
+
+	start := time.Now()
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	elapsed := time.Since(start)
+
+	if cr.n != size {
+		t.Errorf("expected %d bytes read, got %d", size, cr.n)
+	}
+	// The bucket starts full (1s worth), so the remaining 2s are throttled
+	if elapsed < 1900*time.Millisecond {
+		t.Errorf("expected the read to take at least 2s, took %s", elapsed)
+	}
+}
+
+func Test_U_RateLimitedReaderCancel(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := newRateLimitedReader(ctx, bytes.NewReader(make([]byte, 1024)), 1)
+	if _, err := io.Copy(io.Discard, r); err == nil {
+		t.Error("expected an error once the context is canceled")
+	}
+}
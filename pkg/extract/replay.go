@@ -0,0 +1,269 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FixtureFile is the metadata of a recorded fixture, at the root of its
+// directory along the recorded outputs.
+const FixtureFile = "fixture.json"
+
+// Recorded outputs of the pod commands, in the fixture directory.
+const (
+	fixtureArchive   = "archive.tar"
+	fixtureDiskUsage = "du.txt"
+	fixtureChecksums = "checksums.txt"
+
+	// fixtureArchiveStatus is the exit status of the archive, recorded
+	// only if it failed (see exitStatus).
+	fixtureArchiveStatus = "archive.status"
+)
+
+// ErrNotRecorded is returned when replaying a command the fixture holds no
+// output of, e.g. the checksums of a fixture recorded without verification.
+var ErrNotRecorded = errors.New("command not recorded in the fixture")
+
+// Fixture describes an extraction recorded from a pod, for it to be replayed
+// without a cluster.
+type Fixture struct {
+	// Extractor is the ReportMarker, identifying the fixtures the extractor
+	// recorded.
+	Extractor string `json:"extractor"`
+
+	Namespace string `json:"namespace"`
+	PVCName   string `json:"pvc_name"`
+	Pod       string `json:"pod"`
+	Container string `json:"container"`
+
+	// MountPath and SourcePath are the ones of the recorded extraction, the
+	// replayed commands are matched against.
+	MountPath  string `json:"mount_path"`
+	SourcePath string `json:"source_path"`
+
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// ReplayOTelCollector feeds the fixture recorded in the given directory (see
+// WithRecord) through the extraction pipeline, as DumpOTelCollector would
+// with the pod: untar, verification, decompression and report. It does not
+// touch any cluster, hence the pod-related options are ignored. The files
+// land into the directory, or the sink of WithSink.
+func ReplayOTelCollector(
+	ctx context.Context,
+	fixtureDir, into string,
+	opts ...Option,
+) (*Result, error) {
+	// Prepare functional options
+	options := &options{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.record != "" {
+		return nil, errors.New("could not record a replayed fixture")
+	}
+	if options.age != nil {
+		return nil, errors.New("could not encrypt a replayed fixture, recorded in plaintext")
+	}
+	if err := options.checkSink(into); err != nil {
+		return nil, err
+	}
+	directory, _ := localDirectory(options.sink)
+	if directory != "" {
+		if err := checkFixtureDir(fixtureDir, directory); err != nil {
+			return nil, err
+		}
+	}
+
+	fx, err := LoadFixture(fixtureDir)
+	if err != nil {
+		return nil, err
+	}
+	// Replay the commands as recorded
+	options.mountPath, options.sourcePath = fx.MountPath, fx.SourcePath
+
+	res := &Result{
+		Source:    SourceOTelCollector,
+		Namespace: fx.Namespace,
+		PVCName:   fx.PVCName,
+		Directory: directory,
+		Fixture:   fixtureDir,
+		StartedAt: time.Now(),
+	}
+	res.noteFilesystem(options)
+
+	options.logger.Info("replaying fixture",
+		zap.String("fixture", fixtureDir),
+		zap.String("namespace", fx.Namespace),
+		zap.String("pvc", fx.PVCName),
+		zap.Time("recorded_at", fx.RecordedAt),
+	)
+	if err := dumpFromPod(ctx, replayExecutor(fixtureDir, fx.SourcePath), res, options); err != nil {
+		return nil, err
+	}
+
+	if err := res.finish(ctx, options.sink); err != nil {
+		return nil, err
+	}
+	return res, res.verifyFailure()
+}
+
+// LoadFixture reads the metadata of the fixture recorded in the directory.
+func LoadFixture(dir string) (*Fixture, error) {
+	b, err := os.ReadFile(filepath.Join(dir, FixtureFile))
+	if err != nil {
+		return nil, err
+	}
+	fx := &Fixture{}
+	if err := json.Unmarshal(b, fx); err != nil {
+		return nil, fmt.Errorf("invalid fixture %s: %w", dir, err)
+	}
+	if fx.Extractor != ReportMarker {
+		return nil, fmt.Errorf("%s is not a fixture recorded by the extractor", dir)
+	}
+	if !strings.HasPrefix(fx.SourcePath, "/") {
+		return nil, fmt.Errorf("invalid fixture %s: source path %q is not absolute", dir, fx.SourcePath)
+	}
+	return fx, nil
+}
+
+// checkFixtureDir makes sure the fixture and extraction directories are
+// apart, such that the fixture files are not taken for extracted ones.
+func checkFixtureDir(fixtureDir, into string) error {
+	fixtureDir, err := filepath.Abs(fixtureDir)
+	if err != nil {
+		return err
+	}
+	into, err = filepath.Abs(into)
+	if err != nil {
+		return err
+	}
+	for _, pair := range [][2]string{{fixtureDir, into}, {into, fixtureDir}} {
+		rel, err := filepath.Rel(pair[0], pair[1])
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("fixture directory %s and extraction directory %s overlap", fixtureDir, into)
+		}
+	}
+	return nil
+}
+
+// fixtureOutput returns the file holding the recorded output of the
+// command, if it is one of the extraction ones.
+func fixtureOutput(command []string, sourcePath string) (string, bool) {
+	switch {
+	case slices.Equal(command, tarCommand(sourcePath)):
+		return fixtureArchive, true
+	case slices.Equal(command, duCommand(sourcePath)):
+		return fixtureDiskUsage, true
+	case slices.Equal(command, checksumCommand(sourcePath)):
+		return fixtureChecksums, true
+	}
+	return "", false
+}
+
+// recordExecutor writes the fixture metadata into the record directory, and
+// returns an executor teeing the outputs of the extraction commands into it.
+// Other commands run unrecorded.
+func recordExecutor(exec podExecutor, res *Result, pod, container string, options *options) (podExecutor, error) {
+	if err := os.MkdirAll(options.record, 0755); err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(Fixture{
+		Extractor:  ReportMarker,
+		Namespace:  res.Namespace,
+		PVCName:    res.PVCName,
+		Pod:        pod,
+		Container:  container,
+		MountPath:  options.mountPath,
+		SourcePath: options.sourcePath,
+		RecordedAt: time.Now().UTC(),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(options.record, FixtureFile), b, 0600); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, command []string, stdout io.Writer) error {
+		name, ok := fixtureOutput(command, options.sourcePath)
+		if !ok {
+			return exec(ctx, command, stdout)
+		}
+		f, err := os.Create(filepath.Join(options.record, name))
+		if err != nil {
+			return err
+		}
+		err = exec(ctx, command, io.MultiWriter(f, stdout))
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		// A failed archive may still be replayed, e.g. with vanished files
+		if ee := (*exitError)(nil); name == fixtureArchive && errors.As(err, &ee) {
+			if werr := os.WriteFile(filepath.Join(options.record, fixtureArchiveStatus), exitStatus(ee.code, ee.stderr), 0600); werr != nil {
+				return errors.Join(err, werr)
+			}
+		}
+		return err
+	}, nil
+}
+
+// replayExecutor returns an executor writing the recorded outputs of the
+// extraction commands from the fixture directory.
+func replayExecutor(dir, sourcePath string) podExecutor {
+	return func(ctx context.Context, command []string, stdout io.Writer) error {
+		name, ok := fixtureOutput(command, sourcePath)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNotRecorded, strings.Join(command, " "))
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrNotRecorded, strings.Join(command, " "))
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		// Stop on cancellation, as the exec stream does
+		if _, err = io.Copy(stdout, &contextReader{ctx: ctx, r: f}); err != nil || name != fixtureArchive {
+			return err
+		}
+		b, err := os.ReadFile(filepath.Join(dir, fixtureArchiveStatus))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return parseExitStatus(command, b)
+	}
+}
+
+// contextReader stops reading once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
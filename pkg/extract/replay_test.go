@@ -0,0 +1,268 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fixtureDir is the fixture recorded from an OTEL Collector PVC, with a
+// rotated compressed logs file.
+var fixtureDir = filepath.Join("testdata", "fixture")
+
+var fixtureFiles = []string{
+	"collector/otel_logs-2026-10-16T01-00-00.000.gz",
+	"collector/otel_metrics",
+	"collector/otel_traces",
+}
+
+func Test_U_Replay(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Opts                 []Option
+		ExpectedVerified     int
+		ExpectedDecompressed []string
+	}{
+		"plain": {},
+		"verify": {
+			Opts: []Option{
+				WithVerifyRemote(true),
+			},
+			ExpectedVerified: 3,
+		},
+		"verify-decompress": {
+			Opts: []Option{
+				WithVerifyRemote(true),
+				WithDecompress(true),
+			},
+			ExpectedVerified:     3,
+			ExpectedDecompressed: []string{"collector/otel_logs-2026-10-16T01-00-00.000"},
+		},
+		"auto-deadline": {
+			Opts: []Option{
+				WithAutoDeadline(true),
+				WithWorkers(1),
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			into := t.TempDir()
+			res, err := ReplayOTelCollector(context.Background(), fixtureDir, into, tt.Opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if res.Namespace != "monitoring-abcdefgh" || res.PVCName != "otel-signals" || res.Fixture != fixtureDir {
+				t.Errorf("expected the fixture metadata, got %+v", res)
+			}
+			if res.Files != len(fixtureFiles) {
+				t.Errorf("expected %d files, got %d", len(fixtureFiles), res.Files)
+			}
+			for _, f := range fixtureFiles {
+				// Decompressed files land with their suffix stripped
+				if trimmed := strings.TrimSuffix(f, ".gz"); slices.Contains(tt.ExpectedDecompressed, trimmed) {
+					f = trimmed
+				}
+				if _, err := os.Stat(filepath.Join(into, f)); err != nil {
+					t.Errorf("expected %s to be extracted: %s", f, err)
+				}
+			}
+
+			if tt.ExpectedVerified == 0 {
+				if res.Verify != nil {
+					t.Errorf("expected no verification, got %+v", res.Verify)
+				}
+			} else if res.Verify == nil || len(res.Verify.Matching) != tt.ExpectedVerified {
+				t.Errorf("expected %d matching files, got %+v", tt.ExpectedVerified, res.Verify)
+			}
+
+			decompressed := []string{}
+			for _, f := range res.Decompressed {
+				decompressed = append(decompressed, f.Path)
+			}
+			if len(tt.ExpectedDecompressed) != 0 && !slices.Equal(decompressed, tt.ExpectedDecompressed) {
+				t.Errorf("expected decompressed files %v, got %v", tt.ExpectedDecompressed, decompressed)
+			}
+
+			// The report tells the fixture apart from the PVC
+			if _, err := os.Stat(filepath.Join(into, ReportFile)); err != nil {
+				t.Errorf("expected a report: %s", err)
+			}
+			if !strings.Contains(res.Summary[0].Text, "fixture "+fixtureDir) {
+				t.Errorf("expected the summary to mention the fixture, got %q", res.Summary[0].Text)
+			}
+		})
+	}
+}
+
+func Test_U_Replay_Resume(t *testing.T) {
+	t.Parallel()
+
+	// Leftovers of an interrupted extraction
+	into := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(into, "collector"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"collector/otel_traces", "collector/otel_logs-rotated"} {
+		if err := os.WriteFile(filepath.Join(into, f+PartialSuffix), []byte("trunc"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := ReplayOTelCollector(context.Background(), fixtureDir, into)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The copied file overwrites its partial, the rotated one is reported
+	expected := []string{"collector/otel_logs-rotated" + PartialSuffix}
+	if !slices.Equal(res.Partials, expected) {
+		t.Errorf("expected partials %v, got %v", expected, res.Partials)
+	}
+	b, err := os.ReadFile(filepath.Join(into, "collector/otel_traces"))
+	if err != nil || bytes.Equal(b, []byte("trunc")) {
+		t.Errorf("expected otel_traces to be complete, got %q (%v)", b, err)
+	}
+}
+
+func Test_U_Replay_Errors(t *testing.T) {
+	t.Parallel()
+
+	// The checksums are only recorded along a verification
+	unverified := t.TempDir()
+	for _, f := range []string{FixtureFile, fixtureArchive, fixtureDiskUsage} {
+		copyFile(t, filepath.Join(fixtureDir, f), filepath.Join(unverified, f))
+	}
+
+	var tests = map[string]struct {
+		Fixture  string
+		Into     func(t *testing.T) string
+		Opts     []Option
+		Expected error
+	}{
+		"not-recorded": {
+			Fixture: unverified,
+			Opts: []Option{
+				WithVerifyRemote(true),
+			},
+			Expected: ErrNotRecorded,
+		},
+		"into-fixture": {
+			Fixture: fixtureDir,
+			Into: func(*testing.T) string {
+				return filepath.Join(fixtureDir, "extracted")
+			},
+		},
+		"not-a-fixture": {
+			Fixture: t.TempDir(),
+		},
+		"record": {
+			Fixture: fixtureDir,
+			Opts: []Option{
+				WithRecord(t.TempDir()),
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			into := t.TempDir()
+			if tt.Into != nil {
+				into = tt.Into(t)
+			}
+			_, err := ReplayOTelCollector(context.Background(), tt.Fixture, into, tt.Opts...)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.Expected != nil && !errors.Is(err, tt.Expected) {
+				t.Errorf("expected %v, got %v", tt.Expected, err)
+			}
+		})
+	}
+}
+
+func Test_U_Record(t *testing.T) {
+	t.Parallel()
+
+	// Serve the fixture outputs as the pod would
+	pod := replayExecutor(fixtureDir, "/data")
+
+	options := &options{
+		logger:       zap.NewNop(),
+		record:       t.TempDir(),
+		verifyRemote: true,
+		autoDeadline: true,
+	}
+	if err := options.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res := &Result{
+		Namespace: "monitoring-abcdefgh",
+		PVCName:   "otel-signals",
+		Directory: t.TempDir(),
+	}
+	options.sink = NewDirectorySink(res.Directory)
+	exec, err := recordExecutor(pod, res, "extractor", "copy", options)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := dumpFromPod(context.Background(), exec, res, options); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The outputs are recorded as served
+	for _, f := range []string{fixtureArchive, fixtureDiskUsage, fixtureChecksums} {
+		expected, err := os.ReadFile(filepath.Join(fixtureDir, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(options.record, f))
+		if err != nil {
+			t.Fatalf("expected %s to be recorded: %s", f, err)
+		}
+		if !bytes.Equal(got, expected) {
+			t.Errorf("expected %s to be recorded as served", f)
+		}
+	}
+
+	// The recording replays the same
+	replayed, err := ReplayOTelCollector(context.Background(), options.record, t.TempDir(), WithVerifyRemote(true))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if replayed.Files != res.Files || replayed.Bytes != res.Bytes || len(replayed.Verify.Matching) != len(res.Verify.Matching) {
+		t.Errorf("expected the replay to match the recording, got %+v and %+v", replayed, res)
+	}
+
+	// Other commands are not recorded
+	if err := exec(context.Background(), []string{"true"}, io.Discard); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected the command to reach the pod, got %v", err)
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+	t.Helper()
+
+	b, err := os.ReadFile(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(to, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,250 @@
+package extract
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReportDiff compares two extraction reports, e.g. of two nightly
+// extractions, to tell how the data grew and whether some was lost.
+type ReportDiff struct {
+	// Old and New are the paths of the reports compared.
+	Old string `json:"old"`
+	New string `json:"new"`
+
+	FilesDelta int   `json:"files_delta"`
+	BytesDelta int64 `json:"bytes_delta"`
+
+	// Added, Removed and Changed are the files of the inventories, sorted
+	// by path. A file changed if its size or modification time did.
+	Added   []InventoryFile `json:"added"`
+	Removed []InventoryFile `json:"removed"`
+	Changed []FileChange    `json:"changed"`
+
+	// Signals are the sizes and time windows of the signals, sorted by
+	// their directory.
+	Signals []SignalDiff `json:"signals"`
+
+	// Summary is the human-readable summary of the comparison.
+	Summary []SummaryLine `json:"summary"`
+}
+
+// FileChange is a file of both inventories, whose size or modification time
+// changed.
+type FileChange struct {
+	Path       string    `json:"path"`
+	OldBytes   int64     `json:"old_bytes"`
+	NewBytes   int64     `json:"new_bytes"`
+	OldModTime time.Time `json:"old_mod_time"`
+	NewModTime time.Time `json:"new_mod_time"`
+}
+
+// SignalDiff compares the files of a signal directory in both inventories.
+// The OTEL Collector files are grouped by signal (e.g. collector/otel_traces
+// along its rotated files), the other ones by their top-level directory.
+type SignalDiff struct {
+	Directory  string `json:"directory"`
+	OldBytes   int64  `json:"old_bytes"`
+	NewBytes   int64  `json:"new_bytes"`
+	BytesDelta int64  `json:"bytes_delta"`
+
+	// OldWindow and NewWindow are the time windows the files cover, nil if
+	// the signal has no file in the inventory.
+	OldWindow *TimeWindow `json:"old_window,omitempty"`
+	NewWindow *TimeWindow `json:"new_window,omitempty"`
+}
+
+// TimeWindow spans the modification times of files, from the oldest one to
+// the latest. As the files are written until rotated, it approximates the
+// time the signal covers.
+type TimeWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// DiffReports compares the older report to the newer one. Only reports
+// listing their inventory could be compared file by file, the ones of
+// earlier extractors only through their totals.
+func DiffReports(older, newer *Result) *ReportDiff {
+	diff := &ReportDiff{
+		Old:        older.Report,
+		New:        newer.Report,
+		FilesDelta: newer.Files - older.Files,
+		BytesDelta: newer.Bytes - older.Bytes,
+		Added:      []InventoryFile{},
+		Removed:    []InventoryFile{},
+		Changed:    []FileChange{},
+		Signals:    []SignalDiff{},
+	}
+
+	olds := inventoryByPath(older.Inventory)
+	news := inventoryByPath(newer.Inventory)
+	for _, f := range newer.Inventory {
+		o, ok := olds[f.Path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, f)
+		case o.Bytes != f.Bytes || !o.ModTime.Equal(f.ModTime):
+			diff.Changed = append(diff.Changed, FileChange{
+				Path:       f.Path,
+				OldBytes:   o.Bytes,
+				NewBytes:   f.Bytes,
+				OldModTime: o.ModTime,
+				NewModTime: f.ModTime,
+			})
+		}
+	}
+	for _, f := range older.Inventory {
+		if _, ok := news[f.Path]; !ok {
+			diff.Removed = append(diff.Removed, f)
+		}
+	}
+
+	oldSignals := signalsOf(older.Inventory)
+	newSignals := signalsOf(newer.Inventory)
+	dirs := map[string]bool{}
+	for dir := range oldSignals {
+		dirs[dir] = true
+	}
+	for dir := range newSignals {
+		dirs[dir] = true
+	}
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		sd := SignalDiff{Directory: dir}
+		if o, ok := oldSignals[dir]; ok {
+			sd.OldBytes, sd.OldWindow = o.bytes, &o.window
+		}
+		if n, ok := newSignals[dir]; ok {
+			sd.NewBytes, sd.NewWindow = n.bytes, &n.window
+		}
+		sd.BytesDelta = sd.NewBytes - sd.OldBytes
+		diff.Signals = append(diff.Signals, sd)
+	}
+
+	diff.Summary = diff.summarize(older, newer)
+	return diff
+}
+
+// summarize returns the human-readable summary of the comparison, warning
+// about the data lost or not comparable.
+func (diff *ReportDiff) summarize(older, newer *Result) []SummaryLine {
+	lines := []SummaryLine{{
+		Level: SummaryInfo,
+		Text: fmt.Sprintf("%d files (%s) to %d files (%s): %+d files, %s",
+			older.Files, formatBytes(older.Bytes), newer.Files, formatBytes(newer.Bytes), diff.FilesDelta, formatBytesDelta(diff.BytesDelta)),
+	}, {
+		Level: SummaryInfo,
+		Text:  fmt.Sprintf("%d added, %d removed, %d changed", len(diff.Added), len(diff.Removed), len(diff.Changed)),
+	}}
+
+	if older.Source != newer.Source || older.Namespace != newer.Namespace || older.PVCName != newer.PVCName {
+		lines = append(lines, SummaryLine{
+			Level: SummaryWarning,
+			Text: fmt.Sprintf("the reports are of different extractions: %s to %s",
+				reportOrigin(older), reportOrigin(newer)),
+		})
+	}
+	for _, rep := range []struct {
+		name string
+		res  *Result
+	}{{"old", older}, {"new", newer}} {
+		if rep.res.Files != 0 && len(rep.res.Inventory) == 0 {
+			lines = append(lines, SummaryLine{
+				Level: SummaryWarning,
+				Text:  fmt.Sprintf("the %s report lists no inventory, its files could not be compared", rep.name),
+			})
+		}
+	}
+	if len(diff.Removed) != 0 {
+		lines = append(lines, SummaryLine{
+			Level: SummaryWarning,
+			Text:  fmt.Sprintf("%d files of the old extraction are missing from the new one", len(diff.Removed)),
+		})
+	}
+	for _, sd := range diff.Signals {
+		if sd.BytesDelta < 0 {
+			lines = append(lines, SummaryLine{
+				Level: SummaryWarning,
+				Text:  fmt.Sprintf("%s shrank by %s", sd.Directory, formatBytes(-sd.BytesDelta)),
+			})
+		}
+	}
+	return lines
+}
+
+// reportOrigin describes what the report extracted.
+func reportOrigin(res *Result) string {
+	origin := res.Source + " " + res.Namespace
+	if res.PVCName != "" {
+		origin += "/" + res.PVCName
+	}
+	return origin
+}
+
+func inventoryByPath(inventory []InventoryFile) map[string]InventoryFile {
+	m := make(map[string]InventoryFile, len(inventory))
+	for _, f := range inventory {
+		m[f.Path] = f
+	}
+	return m
+}
+
+// signalFiles are the size and time window of the files of a signal.
+type signalFiles struct {
+	bytes  int64
+	window TimeWindow
+}
+
+// signalsOf groups the inventory files by signal directory.
+func signalsOf(inventory []InventoryFile) map[string]*signalFiles {
+	signals := map[string]*signalFiles{}
+	for _, f := range inventory {
+		dir := signalDirectory(f.Path)
+		sf, ok := signals[dir]
+		if !ok {
+			sf = &signalFiles{window: TimeWindow{From: f.ModTime, To: f.ModTime}}
+			signals[dir] = sf
+		}
+		sf.bytes += f.Bytes
+		if f.ModTime.Before(sf.window.From) {
+			sf.window.From = f.ModTime
+		}
+		if f.ModTime.After(sf.window.To) {
+			sf.window.To = f.ModTime
+		}
+	}
+	return signals
+}
+
+// signalDirectory returns the signal directory of the file: its OTEL
+// Collector signal (e.g. collector/otel_traces for the rotated
+// collector/otel_traces-2026-10-16T08-00-00.000), else its top-level
+// directory.
+// Example: prometheus/01JA0000000000000000000000/chunks/000001 -> prometheus
+func signalDirectory(p string) string {
+	dir, base := path.Split(p)
+	if strings.HasPrefix(base, "otel_") {
+		if i := strings.IndexAny(base, "-."); i != -1 {
+			base = base[:i]
+		}
+		return path.Join(dir, base)
+	}
+	if top, _, ok := strings.Cut(p, "/"); ok {
+		return top
+	}
+	return "."
+}
+
+// formatBytesDelta formats a size difference in bytes with binary units,
+// signed.
+// Example: -2048 -> -2.0 KiB
+func formatBytesDelta(b int64) string {
+	if b < 0 {
+		return "-" + formatBytes(-b)
+	}
+	return "+" + formatBytes(b)
+}
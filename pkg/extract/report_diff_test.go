@@ -0,0 +1,215 @@
+package extract
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_U_DiffReports(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	older := &Result{
+		Source:    SourceOTelCollector,
+		Namespace: "monitoring",
+		PVCName:   "signals",
+		Files:     3,
+		Bytes:     3072,
+		Inventory: []InventoryFile{
+			{Path: "collector/otel_logs", Bytes: 1024, ModTime: day},
+			{Path: "collector/otel_traces", Bytes: 1024, ModTime: day},
+			{Path: "collector/otel_traces-2026-10-13T00-00-00.000", Bytes: 1024, ModTime: day.Add(-24 * time.Hour)},
+		},
+	}
+
+	var tests = map[string]struct {
+		Newer            *Result
+		ExpectedAdded    []string
+		ExpectedRemoved  []string
+		ExpectedChanged  []string
+		ExpectedSignals  map[string]int64
+		ExpectedWarnings int
+	}{
+		"unchanged": {
+			Newer:           older,
+			ExpectedSignals: map[string]int64{"collector/otel_logs": 0, "collector/otel_traces": 0},
+		},
+		"growth": {
+			Newer: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Files:     4,
+				Bytes:     6144,
+				Inventory: []InventoryFile{
+					{Path: "collector/otel_logs", Bytes: 2048, ModTime: day.Add(24 * time.Hour)},
+					{Path: "collector/otel_traces", Bytes: 1024, ModTime: day.Add(24 * time.Hour)},
+					{Path: "collector/otel_traces-2026-10-13T00-00-00.000", Bytes: 1024, ModTime: day.Add(-24 * time.Hour)},
+					{Path: "collector/otel_traces-2026-10-14T00-00-00.000", Bytes: 2048, ModTime: day},
+				},
+			},
+			ExpectedAdded:   []string{"collector/otel_traces-2026-10-14T00-00-00.000"},
+			ExpectedChanged: []string{"collector/otel_logs", "collector/otel_traces"},
+			ExpectedSignals: map[string]int64{"collector/otel_logs": 1024, "collector/otel_traces": 2048},
+		},
+		"lost": {
+			Newer: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Files:     1,
+				Bytes:     512,
+				Inventory: []InventoryFile{
+					{Path: "collector/otel_traces", Bytes: 512, ModTime: day},
+				},
+			},
+			ExpectedRemoved:  []string{"collector/otel_logs", "collector/otel_traces-2026-10-13T00-00-00.000"},
+			ExpectedChanged:  []string{"collector/otel_traces"},
+			ExpectedSignals:  map[string]int64{"collector/otel_logs": -1024, "collector/otel_traces": -1536},
+			ExpectedWarnings: 3, // removed files, both signals shrank
+		},
+		"other-extraction-without-inventory": {
+			Newer: &Result{
+				Source:    SourcePrometheus,
+				Namespace: "monitoring",
+				Files:     2,
+				Bytes:     4096,
+			},
+			ExpectedRemoved:  []string{"collector/otel_logs", "collector/otel_traces", "collector/otel_traces-2026-10-13T00-00-00.000"},
+			ExpectedSignals:  map[string]int64{"collector/otel_logs": -1024, "collector/otel_traces": -2048},
+			ExpectedWarnings: 5, // other extraction, no inventory, removed files, both signals shrank
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			diff := DiffReports(older, tt.Newer)
+			if got := inventoryPaths(diff.Added); !slices.Equal(got, tt.ExpectedAdded) {
+				t.Errorf("expected added %v, got %v", tt.ExpectedAdded, got)
+			}
+			if got := inventoryPaths(diff.Removed); !slices.Equal(got, tt.ExpectedRemoved) {
+				t.Errorf("expected removed %v, got %v", tt.ExpectedRemoved, got)
+			}
+			changed := []string{}
+			for _, c := range diff.Changed {
+				changed = append(changed, c.Path)
+			}
+			if !slices.Equal(changed, nonNil(tt.ExpectedChanged)) {
+				t.Errorf("expected changed %v, got %v", tt.ExpectedChanged, changed)
+			}
+			if len(diff.Signals) != len(tt.ExpectedSignals) {
+				t.Fatalf("expected %d signals, got %+v", len(tt.ExpectedSignals), diff.Signals)
+			}
+			for _, sd := range diff.Signals {
+				if delta, ok := tt.ExpectedSignals[sd.Directory]; !ok || delta != sd.BytesDelta {
+					t.Errorf("unexpected signal %+v", sd)
+				}
+			}
+			warnings := 0
+			for _, line := range diff.Summary {
+				if line.Level == SummaryWarning {
+					warnings++
+				}
+			}
+			if warnings != tt.ExpectedWarnings {
+				t.Errorf("expected %d warnings, got %+v", tt.ExpectedWarnings, diff.Summary)
+			}
+		})
+	}
+}
+
+func Test_U_DiffReports_Window(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	older := &Result{
+		Inventory: []InventoryFile{
+			{Path: "collector/otel_traces", Bytes: 1024, ModTime: day},
+			{Path: "collector/otel_traces-2026-10-13T00-00-00.000", Bytes: 1024, ModTime: day.Add(-24 * time.Hour)},
+		},
+	}
+	newer := &Result{
+		Inventory: []InventoryFile{
+			{Path: "collector/otel_traces", Bytes: 1024, ModTime: day.Add(24 * time.Hour)},
+			{Path: "collector/otel_traces-2026-10-14T00-00-00.000", Bytes: 1024, ModTime: day},
+		},
+	}
+
+	diff := DiffReports(older, newer)
+	if len(diff.Signals) != 1 {
+		t.Fatalf("expected a single signal, got %+v", diff.Signals)
+	}
+	sd := diff.Signals[0]
+	if sd.OldWindow == nil || !sd.OldWindow.From.Equal(day.Add(-24*time.Hour)) || !sd.OldWindow.To.Equal(day) {
+		t.Errorf("unexpected old window %+v", sd.OldWindow)
+	}
+	if sd.NewWindow == nil || !sd.NewWindow.From.Equal(day) || !sd.NewWindow.To.Equal(day.Add(24*time.Hour)) {
+		t.Errorf("unexpected new window %+v", sd.NewWindow)
+	}
+}
+
+func Test_U_SignalDirectory(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Path     string
+		Expected string
+	}{
+		"signal": {
+			Path:     "collector/otel_traces",
+			Expected: "collector/otel_traces",
+		},
+		"rotated": {
+			Path:     "collector/otel_traces-2026-10-16T08-00-00.000",
+			Expected: "collector/otel_traces",
+		},
+		"spill": {
+			Path:     "collector/otel_traces_spill",
+			Expected: "collector/otel_traces_spill",
+		},
+		"tenant": {
+			Path:     "collector/ctf-2026/otel_logs.json",
+			Expected: "collector/ctf-2026/otel_logs",
+		},
+		"snapshot": {
+			Path:     "01JA0000000000000000000000/chunks/000001",
+			Expected: "01JA0000000000000000000000",
+		},
+		"root": {
+			Path:     "otel_metrics",
+			Expected: "otel_metrics",
+		},
+		"root-other": {
+			Path:     "lost+found",
+			Expected: ".",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if got := signalDirectory(tt.Path); got != tt.Expected {
+				t.Errorf("expected %s, got %s", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func inventoryPaths(files []InventoryFile) []string {
+	paths := []string{}
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
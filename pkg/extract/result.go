@@ -0,0 +1,206 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ReportFile is the name of the extraction report, written at the root of
+// the extraction directory.
+const ReportFile = "report.json"
+
+// Sources of the extracted data.
+const (
+	SourceOTelCollector = "otel"
+	SourcePrometheus    = "prometheus"
+
+	// SourcePVC is any other PVC of the Monitoring, e.g. the Jaeger archive.
+	SourcePVC = "pvc"
+
+	// SourceAll is every PVC of the Monitoring namespace, each extracted
+	// into its own subdirectory.
+	SourceAll = "all"
+)
+
+// Result summarizes an extraction.
+type Result struct {
+	// Extractor is the ReportMarker, identifying the directories the
+	// extractor created.
+	Extractor string `json:"extractor"`
+
+	// Source of the extracted data, i.e. the OTEL Collector signals PVC
+	// or a Prometheus TSDB snapshot.
+	Source    string `json:"source"`
+	Namespace string `json:"namespace"`
+	PVCName   string `json:"pvc_name"`
+	Directory string `json:"directory"`
+
+	// Snapshot is the name of the Prometheus TSDB snapshot extracted.
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// Fixture is the directory of the recorded fixture replayed, rather
+	// than the PVC, if any.
+	Fixture string `json:"fixture,omitempty"`
+
+	// Transport is how the extraction commands reached the Pod, if any.
+	Transport string `json:"transport,omitempty"`
+
+	// Files is the number of files extracted.
+	Files int `json:"files"`
+	// Bytes is the total size of the files extracted.
+	Bytes int64 `json:"bytes"`
+
+	// Synced tells the directories of the files were synced before they
+	// were counted (see WithFsync).
+	Synced bool `json:"synced,omitempty"`
+
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+
+	// Warnings are non-fatal issues that occurred during the extraction.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Error is why the extraction failed, if it did once the Pod created.
+	Error string `json:"error,omitempty"`
+
+	// Diagnostics are the logs and events of the extraction Pod, captured
+	// when the extraction failed.
+	Diagnostics *PodDiagnostics `json:"diagnostics,omitempty"`
+
+	// Verify is the comparison with the PVC files, if verified.
+	Verify *VerifyReport `json:"verify,omitempty"`
+
+	// Partials are the incomplete files left in the directory by an
+	// interrupted extraction, which this one did not overwrite.
+	Partials []string `json:"partials,omitempty"`
+
+	// Inventory are the files extracted, sorted by path, for extractions to
+	// be compared (see DiffReports). They are listed as copied, before any
+	// decompression.
+	Inventory []InventoryFile `json:"inventory,omitempty"`
+
+	// Encrypted is the file the age-encrypted archive of the PVC landed
+	// into, if encrypted in the Pod: the files are within.
+	Encrypted string `json:"encrypted,omitempty"`
+
+	// Decompressed are the files landed decompressed, if requested.
+	Decompressed []DecompressedFile `json:"decompressed,omitempty"`
+
+	// Components are the PVCs extracted along the namespace, keyed by
+	// their component label, when extracting them all.
+	Components map[string][]PVCResult `json:"components,omitempty"`
+
+	// Sink is where the files landed, if not into the directory. As the
+	// report file is written into it, its own report is the one before.
+	Sink *SinkReport `json:"sink,omitempty"`
+
+	// Summary is the human-readable summary of the extraction, as printed
+	// by the extractor.
+	Summary []SummaryLine `json:"summary"`
+
+	// Report is the path to the report file, if written locally.
+	Report string `json:"-"`
+}
+
+// InventoryFile is a file of the extraction.
+type InventoryFile struct {
+	// Path of the file, slash-separated and relative to the extraction
+	// directory.
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+
+	// ModTime is the modification time of the file on the PVC.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// sortedInventory sorts the inventory by path, as the files are recorded in
+// the order the workers write them.
+func sortedInventory(inventory []InventoryFile) []InventoryFile {
+	slices.SortFunc(inventory, func(a, b InventoryFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return inventory
+}
+
+// writeReport writes the result as the report file at the root of the
+// extraction, into the sink.
+func (res *Result) writeReport(ctx context.Context, sink Sink) error {
+	res.Extractor = ReportMarker
+	if _, ok := localDirectory(sink); !ok {
+		rep := sink.Report()
+		res.Sink = &rep
+	}
+	res.Summary = res.summarize()
+
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := sink.WriteEntry(ctx, Entry{
+		Path:    ReportFile,
+		Size:    int64(len(b)),
+		Mode:    0600,
+		ModTime: time.Now(),
+	}, bytes.NewReader(b)); err != nil {
+		return err
+	}
+	if dir, ok := localDirectory(sink); ok {
+		res.Report = filepath.Join(dir, ReportFile)
+	}
+	return nil
+}
+
+// finish times the extraction, writes its report and closes the sink, or
+// aborts it on failure.
+func (res *Result) finish(ctx context.Context, sink Sink) error {
+	res.Duration = time.Since(res.StartedAt)
+	if err := res.writeReport(ctx, sink); err != nil {
+		return abortSink(ctx, sink, err)
+	}
+	if err := sink.Close(ctx); err != nil {
+		return abortSink(ctx, sink, err)
+	}
+	if res.Sink != nil {
+		rep := sink.Report()
+		res.Sink = &rep
+	}
+	return nil
+}
+
+// verifyFailure returns the error of the extracted files mismatching the PVC
+// ones, if verified.
+func (res *Result) verifyFailure() error {
+	if res.Verify != nil && res.Verify.Failed() {
+		return fmt.Errorf("%d extracted files mismatch the PVC ones: %s",
+			len(res.Verify.Mismatched), strings.Join(res.Verify.Mismatched, ", "))
+	}
+	return nil
+}
+
+// ReadReport reads the report written at the root of the extraction
+// directory.
+func ReadReport(dir string) (*Result, error) {
+	return ReadReportFile(filepath.Join(dir, ReportFile))
+}
+
+// ReadReportFile reads the report file, e.g. one kept apart from its
+// extraction directory.
+func ReadReportFile(file string) (*Result, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if err := json.Unmarshal(b, res); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", file, err)
+	}
+	res.Report = file
+	return res, nil
+}
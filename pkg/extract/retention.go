@@ -0,0 +1,211 @@
+package extract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ReportMarker identifies the reports written by the extractor, hence the
+// directories it created. Only those are candidates to the retention.
+const ReportMarker = "ctfer-io/monitoring/extractor"
+
+// RunDirectoryLayout is the name of the timestamped directories, in UTC,
+// the runs extract into when kept apart.
+// Example: 20261016T020000Z
+const RunDirectoryLayout = "20060102T150405Z"
+
+type (
+	// Retention bounds the local extraction directories. Zero values are
+	// unbounded.
+	Retention struct {
+		// MaxSize is the total size of the previous runs, in bytes.
+		MaxSize int64
+
+		// MaxRuns is the number of runs to keep, including the upcoming one.
+		MaxRuns int
+	}
+
+	// LocalRun is a previous extraction directory.
+	LocalRun struct {
+		Directory string
+		StartedAt time.Time
+		// Bytes is the size of the directory on disk, report included.
+		Bytes int64
+	}
+)
+
+// RunDirectory returns the timestamped directory of a run started at the
+// given time, under root.
+func RunDirectory(root string, startedAt time.Time) string {
+	return filepath.Join(root, startedAt.UTC().Format(RunDirectoryLayout))
+}
+
+// Validate checks the retention bounds.
+func (r Retention) Validate() error {
+	if r.MaxSize < 0 {
+		return errors.New("max local size could not be negative")
+	}
+	if r.MaxRuns < 0 {
+		return errors.New("max local runs could not be negative")
+	}
+	return nil
+}
+
+// Enabled returns whether the retention bounds anything.
+func (r Retention) Enabled() bool {
+	return r.MaxSize != 0 || r.MaxRuns != 0
+}
+
+// ListLocalRuns returns the previous runs in the direct subdirectories of
+// root, oldest first. Only the directories holding a report of the
+// extractor are considered, such that the others (e.g. manual backups) are
+// never purged.
+func ListLocalRuns(root string) ([]LocalRun, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	runs := []LocalRun{}
+	for _, entry := range entries {
+		// Don't follow symlinks, they could point anywhere
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		startedAt, ok, err := readRunReport(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		size, err := dirSize(dir)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, LocalRun{
+			Directory: dir,
+			StartedAt: startedAt,
+			Bytes:     size,
+		})
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.Before(runs[j].StartedAt)
+		}
+		return runs[i].Directory < runs[j].Directory
+	})
+	return runs, nil
+}
+
+// Select returns the runs to purge, oldest first, for the remaining ones to
+// fit the retention once the upcoming run is added. The runs must be sorted
+// oldest first, as ListLocalRuns returns them.
+func (r Retention) Select(runs []LocalRun) []LocalRun {
+	total := int64(0)
+	for _, run := range runs {
+		total += run.Bytes
+	}
+
+	purge := []LocalRun{}
+	for _, run := range runs {
+		kept := len(runs) - len(purge)
+		overRuns := r.MaxRuns != 0 && kept+1 > r.MaxRuns
+		overSize := r.MaxSize != 0 && total > r.MaxSize
+		if !overRuns && !overSize {
+			break
+		}
+		purge = append(purge, run)
+		total -= run.Bytes
+	}
+	return purge
+}
+
+// PurgeLocalRuns deletes the oldest runs under root to fit the retention,
+// and returns them. On a dry run, they are only returned.
+func PurgeLocalRuns(root string, retention Retention, dryRun bool) ([]LocalRun, error) {
+	if err := retention.Validate(); err != nil {
+		return nil, err
+	}
+	if !retention.Enabled() {
+		return nil, nil
+	}
+
+	runs, err := ListLocalRuns(root)
+	if err != nil {
+		return nil, err
+	}
+	purge := retention.Select(runs)
+	if dryRun {
+		return purge, nil
+	}
+	for i, run := range purge {
+		// Check the marker again right before deleting, in case the
+		// directory was replaced meanwhile
+		if _, ok, err := readRunReport(run.Directory); err != nil || !ok {
+			return purge[:i], fmt.Errorf("%s is no longer an extraction directory", run.Directory)
+		}
+		if err := os.RemoveAll(run.Directory); err != nil {
+			return purge[:i], err
+		}
+	}
+	return purge, nil
+}
+
+// readRunReport reads the report at the root of the directory, and returns
+// the start of its run if written by the extractor.
+func readRunReport(dir string) (time.Time, bool, error) {
+	info, err := os.Lstat(filepath.Join(dir, ReportFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	if !info.Mode().IsRegular() {
+		return time.Time{}, false, nil
+	}
+	b, err := os.ReadFile(filepath.Join(dir, ReportFile))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	report := struct {
+		Extractor string    `json:"extractor"`
+		StartedAt time.Time `json:"started_at"`
+	}{}
+	if err := json.Unmarshal(b, &report); err != nil {
+		// Not one of ours
+		return time.Time{}, false, nil
+	}
+	return report.StartedAt, report.Extractor == ReportMarker, nil
+}
+
+// dirSize returns the size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	size := int64(0)
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,197 @@
+package extract
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_U_Retention_Select(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time {
+		return time.Date(2026, time.October, d, 2, 0, 0, 0, time.UTC)
+	}
+	runs := []LocalRun{
+		{Directory: "1", StartedAt: day(1), Bytes: 100},
+		{Directory: "2", StartedAt: day(2), Bytes: 200},
+		{Directory: "3", StartedAt: day(3), Bytes: 300},
+		{Directory: "4", StartedAt: day(4), Bytes: 400},
+	}
+
+	var tests = map[string]struct {
+		Retention Retention
+		Expected  []string
+	}{
+		"unbounded": {
+			Expected: []string{},
+		},
+		"within-runs": {
+			Retention: Retention{MaxRuns: 5},
+			Expected:  []string{},
+		},
+		"max-runs": {
+			Retention: Retention{MaxRuns: 2},
+			Expected:  []string{"1", "2", "3"},
+		},
+		"single-run": {
+			Retention: Retention{MaxRuns: 1},
+			Expected:  []string{"1", "2", "3", "4"},
+		},
+		"within-size": {
+			Retention: Retention{MaxSize: 1000},
+			Expected:  []string{},
+		},
+		"max-size": {
+			Retention: Retention{MaxSize: 750},
+			Expected:  []string{"1", "2"},
+		},
+		"size-too-small": {
+			Retention: Retention{MaxSize: 10},
+			Expected:  []string{"1", "2", "3", "4"},
+		},
+		"both": {
+			Retention: Retention{MaxSize: 950, MaxRuns: 4},
+			Expected:  []string{"1"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			purged := []string{}
+			for _, run := range tt.Retention.Select(runs) {
+				purged = append(purged, run.Directory)
+			}
+			if !slices.Equal(purged, tt.Expected) {
+				t.Errorf("expected to purge %v, got %v", tt.Expected, purged)
+			}
+		})
+	}
+}
+
+func Test_U_ListLocalRuns_Marker(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeRun := func(name string, startedAt time.Time) {
+		res := &Result{
+			Directory: filepath.Join(root, name),
+			StartedAt: startedAt,
+		}
+		if err := res.writeReport(context.Background(), NewDirectorySink(res.Directory)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	writeFile := func(name, content string) {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Runs of the extractor, listed oldest first whatever their names
+	writeRun("b", time.Date(2026, time.October, 1, 2, 0, 0, 0, time.UTC))
+	writeRun("a", time.Date(2026, time.October, 2, 2, 0, 0, 0, time.UTC))
+
+	// Not created by the extractor
+	writeFile(filepath.Join("backup", "otel_traces"), "{}")
+	writeFile(filepath.Join("foreign", ReportFile), `{"started_at":"2026-09-01T00:00:00Z"}`)
+	writeFile(filepath.Join("garbage", ReportFile), `not json`)
+	writeFile(ReportFile, `{"extractor":"`+ReportMarker+`"}`)
+	if err := os.Mkdir(filepath.Join(root, "symlinked"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "a", ReportFile), filepath.Join(root, "symlinked", ReportFile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "a"), filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := ListLocalRuns(root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	dirs := []string{}
+	for _, run := range runs {
+		dirs = append(dirs, filepath.Base(run.Directory))
+		if run.Bytes == 0 {
+			t.Errorf("expected the size of %s, got 0", run.Directory)
+		}
+	}
+	if !slices.Equal(dirs, []string{"b", "a"}) {
+		t.Fatalf("expected only the extractor runs oldest first, got %v", dirs)
+	}
+
+	// Nothing to list in a missing directory
+	if runs, err := ListLocalRuns(filepath.Join(root, "missing")); err != nil || len(runs) != 0 {
+		t.Errorf("expected no run in a missing directory, got %v, %v", runs, err)
+	}
+}
+
+func Test_U_PurgeLocalRuns(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for i, name := range []string{"20261001T020000Z", "20261002T020000Z", "20261003T020000Z"} {
+		res := &Result{
+			Directory: filepath.Join(root, name),
+			StartedAt: time.Date(2026, time.October, i+1, 2, 0, 0, 0, time.UTC),
+		}
+		if err := res.writeReport(context.Background(), NewDirectorySink(res.Directory)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "manual"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(root, name))
+		return err == nil
+	}
+
+	// A dry run only reports what would be purged
+	purged, err := PurgeLocalRuns(root, Retention{MaxRuns: 2}, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(purged) != 2 || !exists("20261001T020000Z") || !exists("20261002T020000Z") {
+		t.Fatalf("expected the dry run to report 2 runs and purge none, got %v", purged)
+	}
+
+	purged, err = PurgeLocalRuns(root, Retention{MaxRuns: 2}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(purged) != 2 {
+		t.Fatalf("expected 2 runs to be purged, got %v", purged)
+	}
+	for name, expected := range map[string]bool{
+		"20261001T020000Z": false,
+		"20261002T020000Z": false,
+		"20261003T020000Z": true,
+		"manual":           true,
+	} {
+		if exists(name) != expected {
+			t.Errorf("expected %s to exist %t", name, expected)
+		}
+	}
+}
+
+func Test_U_RunDirectory(t *testing.T) {
+	t.Parallel()
+
+	paris := time.FixedZone("CEST", 2*60*60)
+	dir := RunDirectory("extract", time.Date(2026, time.October, 16, 4, 0, 0, 0, paris))
+	if expected := filepath.Join("extract", "20261016T020000Z"); dir != expected {
+		t.Errorf("expected %s, got %s", expected, dir)
+	}
+}
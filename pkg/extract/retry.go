@@ -0,0 +1,90 @@
+package extract
+
+import (
+	"context"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// defaultBackoff retries the API calls for about 30 seconds, as busy shared
+// clusters could throttle the extractor for a while.
+var defaultBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.2,
+	Steps:    8,
+	Cap:      30 * time.Second,
+}
+
+// retryAPI calls fn until it succeeds, fails with a non-transient error, or
+// the backoff is exhausted.
+// It waits the Retry-After the API server suggests, if longer than the
+// backoff one, but never more than the backoff cap.
+func retryAPI(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil || !isTransient(err) || backoff.Steps <= 1 {
+			return err
+		}
+
+		delay := backoff.Step()
+		if secs, ok := apierrors.SuggestsClientDelay(err); ok {
+			delay = max(delay, time.Duration(secs)*time.Second)
+		}
+		if backoff.Cap > 0 {
+			delay = min(delay, backoff.Cap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryCreate retries the creation as retryAPI does. As a timed out attempt
+// could still have gone through, the object already existing on a retry is
+// a success, but not on the first attempt, where it is another one's.
+func retryCreate(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	return retryIdempotent(ctx, backoff, fn, apierrors.IsAlreadyExists)
+}
+
+// retryDelete retries the deletion as retryAPI does. As a timed out attempt
+// could still have gone through, the object being not found on a retry is a
+// success.
+func retryDelete(ctx context.Context, backoff wait.Backoff, fn func() error) error {
+	return retryIdempotent(ctx, backoff, fn, apierrors.IsNotFound)
+}
+
+// retryIdempotent retries fn as retryAPI does, the errors matching done on
+// a retry meaning a previous attempt succeeded.
+func retryIdempotent(ctx context.Context, backoff wait.Backoff, fn func() error, done func(error) bool) error {
+	attempts := 0
+	return retryAPI(ctx, backoff, func() error {
+		attempts++
+		err := fn()
+		if attempts > 1 && done(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// isTransient returns whether the API error is worth retrying, i.e. the
+// API server is throttling or temporarily unable to serve.
+func isTransient(err error) bool {
+	if apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) {
+		return true
+	}
+	if status, ok := err.(apierrors.APIStatus); ok {
+		return status.Status().Code >= 500
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var testBackoff = wait.Backoff{
+	Duration: time.Millisecond,
+	Factor:   2,
+	Steps:    5,
+	Cap:      10 * time.Millisecond,
+}
+
+// failingFirst fails the first n calls of the verb on pods with err.
+func failingFirst(clientset *fake.Clientset, verb string, n int, err error) *int {
+	calls := 0
+	clientset.PrependReactor(verb, "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		if calls <= n {
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &calls
+}
+
+func Test_U_RetryAPI(t *testing.T) {
+	t.Parallel()
+
+	gr := schema.GroupResource{Resource: "pods"}
+	var tests = map[string]struct {
+		Failures      int
+		Err           error
+		ExpectErr     bool
+		ExpectedCalls int
+	}{
+		"no-failure": {
+			Failures:      0,
+			ExpectedCalls: 1,
+		},
+		"too-many-requests": {
+			Failures:      3,
+			Err:           apierrors.NewTooManyRequests("throttled", 1),
+			ExpectedCalls: 4,
+		},
+		"service-unavailable": {
+			Failures:      2,
+			Err:           apierrors.NewServiceUnavailable("unavailable"),
+			ExpectedCalls: 3,
+		},
+		"exhausted": {
+			Failures:      10,
+			Err:           apierrors.NewTooManyRequests("throttled", 0),
+			ExpectErr:     true,
+			ExpectedCalls: 5,
+		},
+		"not-transient": {
+			Failures:      1,
+			Err:           apierrors.NewForbidden(gr, podName, errors.New("forbidden")),
+			ExpectErr:     true,
+			ExpectedCalls: 1,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewClientset()
+			calls := failingFirst(clientset, "create", tt.Failures, tt.Err)
+
+			err := retryAPI(context.Background(), testBackoff, func() error {
+				_, err := clientset.CoreV1().Pods("monitoring").Create(context.Background(), &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "monitoring",
+						Name:      podName,
+					},
+				}, metav1.CreateOptions{})
+				return err
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if *calls != tt.ExpectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.ExpectedCalls, *calls)
+			}
+		})
+	}
+}
+
+func Test_U_RetryAPI_Delete(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      podName,
+		},
+	})
+	calls := failingFirst(clientset, "delete", 2, apierrors.NewInternalError(errors.New("etcd leader changed")))
+
+	if err := retryAPI(context.Background(), testBackoff, func() error {
+		return clientset.CoreV1().Pods("monitoring").Delete(context.Background(), podName, metav1.DeleteOptions{})
+	}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 calls, got %d", *calls)
+	}
+}
+
+// goneThroughFirst applies the first call of the verb on pods, yet fails it
+// with err as if its response was lost.
+func goneThroughFirst(clientset *fake.Clientset, verb string, err error) *int {
+	calls := 0
+	clientset.PrependReactor(verb, "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		calls++
+		if calls == 1 {
+			_, _, _ = k8stesting.ObjectReaction(clientset.Tracker())(action)
+			return true, nil, err
+		}
+		return false, nil, nil
+	})
+	return &calls
+}
+
+func Test_U_RetryCreate(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Existing      bool
+		GoneThrough   bool
+		ExpectErr     bool
+		ExpectedCalls int
+	}{
+		"created": {
+			ExpectedCalls: 1,
+		},
+		"created-on-timeout": {
+			GoneThrough:   true,
+			ExpectedCalls: 2,
+		},
+		"another-one": {
+			Existing:      true,
+			ExpectErr:     true,
+			ExpectedCalls: 1,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "monitoring",
+					Name:      podName,
+				},
+			}
+			clientset := fake.NewClientset()
+			if tt.Existing {
+				clientset = fake.NewClientset(pod.DeepCopy())
+			}
+			calls := failingFirst(clientset, "create", 0, nil)
+			if tt.GoneThrough {
+				calls = goneThroughFirst(clientset, "create", apierrors.NewServerTimeout(schema.GroupResource{Resource: "pods"}, "create", 1))
+			}
+
+			err := retryCreate(context.Background(), testBackoff, func() error {
+				_, err := clientset.CoreV1().Pods("monitoring").Create(context.Background(), pod.DeepCopy(), metav1.CreateOptions{})
+				return err
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr && !apierrors.IsAlreadyExists(err) {
+				t.Errorf("expected an already exists error, got %v", err)
+			}
+			if *calls != tt.ExpectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.ExpectedCalls, *calls)
+			}
+		})
+	}
+}
+
+func Test_U_RetryDelete(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Missing       bool
+		GoneThrough   bool
+		ExpectErr     bool
+		ExpectedCalls int
+	}{
+		"deleted": {
+			ExpectedCalls: 1,
+		},
+		"deleted-on-timeout": {
+			GoneThrough:   true,
+			ExpectedCalls: 2,
+		},
+		"missing": {
+			Missing:       true,
+			ExpectErr:     true,
+			ExpectedCalls: 1,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			clientset := fake.NewClientset(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "monitoring",
+					Name:      podName,
+				},
+			})
+			if tt.Missing {
+				clientset = fake.NewClientset()
+			}
+			calls := failingFirst(clientset, "delete", 0, nil)
+			if tt.GoneThrough {
+				calls = goneThroughFirst(clientset, "delete", apierrors.NewInternalError(errors.New("etcd leader changed")))
+			}
+
+			err := retryDelete(context.Background(), testBackoff, func() error {
+				return clientset.CoreV1().Pods("monitoring").Delete(context.Background(), podName, metav1.DeleteOptions{})
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if *calls != tt.ExpectedCalls {
+				t.Errorf("expected %d calls, got %d", tt.ExpectedCalls, *calls)
+			}
+		})
+	}
+}
+
+func Test_U_WaitForPodReady_Throttled(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      podName,
+		},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		},
+	})
+	calls := failingFirst(clientset, "get", 1, apierrors.NewTooManyRequests("throttled", 0))
+
+	if err := waitForPodReady(context.Background(), clientset, "monitoring", podName); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected 2 calls, got %d", *calls)
+	}
+}
+
+func Test_U_WaitForPodReady_Terminated(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "monitoring",
+			Name:      podName,
+		},
+		Status: corev1.PodStatus{
+			Phase:  corev1.PodFailed,
+			Reason: "DeadlineExceeded",
+		},
+	})
+
+	start := time.Now()
+	if err := waitForPodReady(context.Background(), clientset, "monitoring", podName); err == nil {
+		t.Fatal("expected an error for a terminated pod")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected to fail at once, waited %s", elapsed)
+	}
+}
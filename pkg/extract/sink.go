@@ -0,0 +1,229 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Kinds of the sinks, as reported.
+const (
+	SinkDirectory = "directory"
+	SinkArchive   = "archive"
+	SinkStream    = "stream"
+	SinkS3        = "s3"
+	SinkOCI       = "oci"
+)
+
+// Entry is a file or directory of the extraction, as handed to a Sink.
+type Entry struct {
+	// Path is the slash-separated path of the entry, relative to the root
+	// of the extraction. It is checked not to escape it.
+	Path string
+
+	// Size of the file content, zero for a directory.
+	Size int64
+
+	// Mode holds the permissions of the entry, along fs.ModeDir for a
+	// directory.
+	Mode fs.FileMode
+
+	ModTime time.Time
+}
+
+// IsDir returns whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// Sink is where the extracted files land. The extraction reads the archive,
+// checks the paths, hashes the files and counts them in the progress, then
+// hands them to the sink: a directory by default, or any other through
+// WithSink, e.g. one provided by a program embedding the package.
+type Sink interface {
+	// Open prepares the sink, before any entry is written.
+	Open(ctx context.Context) error
+
+	// WriteEntry writes the entry, its content read from r (nil for a
+	// directory). The workers call it concurrently (see WithWorkers), hence
+	// the sinks writing a single stream serialize the entries themselves.
+	// Directories come before the files under them.
+	WriteEntry(ctx context.Context, entry Entry, r io.Reader) error
+
+	// Close completes the sink once every entry is written, the report file
+	// being the last one, e.g. uploads what remains.
+	Close(ctx context.Context) error
+
+	// Report describes where the entries landed, once closed.
+	Report() SinkReport
+}
+
+// SinkAborter is a Sink discarding what it was written when the extraction
+// fails, rather than being closed, e.g. not to complete an upload of part
+// of the files.
+type SinkAborter interface {
+	Sink
+
+	Abort(ctx context.Context) error
+}
+
+// SinkReport describes where the extracted files landed.
+type SinkReport struct {
+	// Kind of the sink, e.g. SinkS3.
+	Kind string `json:"kind"`
+
+	// Location of the files, e.g. s3://bucket/key.
+	Location string `json:"location"`
+
+	// Entries is the number of files written into the sink, the report
+	// file included.
+	Entries int `json:"entries"`
+
+	// Bytes is the size written into the sink, compressed if it is.
+	Bytes int64 `json:"bytes"`
+
+	// Digest of the content, if the sink addresses it so.
+	Digest string `json:"digest,omitempty"`
+}
+
+// abortSink aborts the sink the extraction failed into, if supported. The
+// abort is not cancelled along the extraction, as it cleans up after it.
+func abortSink(ctx context.Context, sink Sink, err error) error {
+	aborter, ok := sink.(SinkAborter)
+	if !ok {
+		return err
+	}
+	if aerr := aborter.Abort(context.WithoutCancel(ctx)); aerr != nil {
+		return errors.Join(err, fmt.Errorf("aborting %s sink: %w", sink.Report().Kind, aerr))
+	}
+	return err
+}
+
+// localDirectory returns the directory the files land into, if the sink is
+// a DirectorySink. Only then are they decompressed, and the partial files
+// looked for.
+func localDirectory(sink Sink) (string, bool) {
+	ds, ok := sink.(*DirectorySink)
+	if !ok {
+		return "", false
+	}
+	return ds.dir, true
+}
+
+// DirectorySink writes the files into a local directory, each through a
+// partial file (see PartialSuffix) such that it is either absent or
+// complete.
+type DirectorySink struct {
+	dir string
+
+	// fsync syncs the directories once a file is renamed or a directory
+	// created into them (see WithFsync).
+	fsync bool
+
+	mu      sync.Mutex
+	dirs    map[string]struct{}
+	entries int
+	bytes   int64
+}
+
+var _ Sink = (*DirectorySink)(nil)
+
+// NewDirectorySink returns the sink writing into the directory, created if
+// necessary.
+func NewDirectorySink(dir string) *DirectorySink {
+	return &DirectorySink{
+		dir:  dir,
+		dirs: map[string]struct{}{},
+	}
+}
+
+func (s *DirectorySink) Open(context.Context) error {
+	return s.mkdir(s.dir)
+}
+
+func (s *DirectorySink) WriteEntry(_ context.Context, entry Entry, r io.Reader) error {
+	target, err := sanitizeArchivePath(s.dir, filepath.FromSlash(entry.Path))
+	if err != nil {
+		// tainted path, could be a Path Traversal
+		return err
+	}
+	if entry.IsDir() {
+		return s.mkdir(target)
+	}
+
+	if err := s.mkdir(filepath.Dir(target)); err != nil {
+		return err
+	}
+	perm := entry.Mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+	n, err := writeFile(target, r, perm)
+	if err != nil {
+		return err
+	}
+	if s.fsync {
+		if err := syncDir(filepath.Dir(target)); err != nil {
+			return fmt.Errorf("syncing directory of %s: %w", entry.Path, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries++
+	s.bytes += n
+	return nil
+}
+
+// mkdir creates the directory once, as many files share it.
+func (s *DirectorySink) mkdir(dir string) error {
+	s.mu.Lock()
+	_, ok := s.dirs[dir]
+	s.mu.Unlock()
+	if ok {
+		return nil
+	}
+	// The directories created are synced into their parent
+	var created []string
+	if s.fsync {
+		for d := dir; ; d = filepath.Dir(d) {
+			if _, err := os.Stat(d); err == nil || filepath.Dir(d) == d {
+				break
+			}
+			created = append(created, d)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, d := range created {
+		if err := syncDir(filepath.Dir(d)); err != nil {
+			return fmt.Errorf("syncing directory of %s: %w", d, err)
+		}
+	}
+	s.mu.Lock()
+	s.dirs[dir] = struct{}{}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *DirectorySink) Close(context.Context) error {
+	return nil
+}
+
+func (s *DirectorySink) Report() SinkReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SinkReport{
+		Kind:     SinkDirectory,
+		Location: s.dir,
+		Entries:  s.entries,
+		Bytes:    s.bytes,
+	}
+}
@@ -0,0 +1,210 @@
+package extract
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// tarStream writes the entries as a tar stream, gzipped if requested. The
+// entries handed concurrently by the workers are written one at a time.
+type tarStream struct {
+	mu      sync.Mutex
+	w       *countingWriter
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	entries int
+}
+
+func newTarStream(w io.Writer, compress bool) *tarStream {
+	ts := &tarStream{
+		w: &countingWriter{w: w},
+	}
+	var out io.Writer = ts.w
+	if compress {
+		ts.gz = gzip.NewWriter(ts.w)
+		out = ts.gz
+	}
+	ts.tw = tar.NewWriter(out)
+	return ts
+}
+
+func (ts *tarStream) write(entry Entry, r io.Reader) error {
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     entry.Path,
+		Size:     entry.Size,
+		Mode:     int64(entry.Mode.Perm()),
+		ModTime:  entry.ModTime,
+	}
+	if entry.IsDir() {
+		hdr.Typeflag = tar.TypeDir
+		hdr.Name += "/"
+		hdr.Size = 0
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if err := ts.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return nil
+	}
+	n, err := io.Copy(ts.tw, r)
+	if err != nil {
+		return err
+	}
+	if n != entry.Size {
+		return fmt.Errorf("entry %s is %d bytes long rather than %d", entry.Path, n, entry.Size)
+	}
+	ts.entries++
+	return nil
+}
+
+// close writes the end of the archive, and of the compression if any.
+func (ts *tarStream) close() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if err := ts.tw.Close(); err != nil {
+		return err
+	}
+	if ts.gz != nil {
+		return ts.gz.Close()
+	}
+	return nil
+}
+
+func (ts *tarStream) report(kind, location string) SinkReport {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return SinkReport{
+		Kind:     kind,
+		Location: location,
+		Entries:  ts.entries,
+		Bytes:    ts.w.n,
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// ArchiveSink writes the files into a local tar.gz archive, through a
+// partial file (see PartialSuffix) renamed into place once complete.
+type ArchiveSink struct {
+	path string
+	f    *os.File
+	ts   *tarStream
+}
+
+var _ SinkAborter = (*ArchiveSink)(nil)
+
+// NewArchiveSink returns the sink writing the tar.gz archive at the path,
+// its directory created if necessary.
+func NewArchiveSink(path string) *ArchiveSink {
+	return &ArchiveSink{
+		path: path,
+	}
+}
+
+func (s *ArchiveSink) Open(context.Context) (err error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	s.f, err = os.Create(s.path + PartialSuffix)
+	if err != nil {
+		return err
+	}
+	s.ts = newTarStream(s.f, true)
+	return nil
+}
+
+func (s *ArchiveSink) WriteEntry(_ context.Context, entry Entry, r io.Reader) error {
+	return s.ts.write(entry, r)
+}
+
+func (s *ArchiveSink) Close(context.Context) error {
+	err := s.ts.close()
+	if err == nil {
+		err = s.f.Sync()
+	}
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.f.Name(), s.path)
+}
+
+// Abort removes the partial archive, as it misses files.
+func (s *ArchiveSink) Abort(context.Context) error {
+	if s.f == nil {
+		return nil
+	}
+	_ = s.f.Close()
+	if err := os.Remove(s.f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *ArchiveSink) Report() SinkReport {
+	if s.ts == nil {
+		return SinkReport{Kind: SinkArchive, Location: s.path}
+	}
+	return s.ts.report(SinkArchive, s.path)
+}
+
+// StreamSink writes the files as a tar stream into a writer, e.g. stdout
+// for the extractor output to be piped into another program.
+type StreamSink struct {
+	name string
+	w    io.Writer
+	ts   *tarStream
+}
+
+var _ Sink = (*StreamSink)(nil)
+
+// NewStreamSink returns the sink writing the tar stream into w, the name
+// describing it in the report (e.g. stdout). The writer is not closed.
+func NewStreamSink(name string, w io.Writer) *StreamSink {
+	return &StreamSink{
+		name: name,
+		w:    w,
+	}
+}
+
+func (s *StreamSink) Open(context.Context) error {
+	s.ts = newTarStream(s.w, false)
+	return nil
+}
+
+func (s *StreamSink) WriteEntry(_ context.Context, entry Entry, r io.Reader) error {
+	return s.ts.write(entry, r)
+}
+
+func (s *StreamSink) Close(context.Context) error {
+	return s.ts.close()
+}
+
+func (s *StreamSink) Report() SinkReport {
+	if s.ts == nil {
+		return SinkReport{Kind: SinkStream, Location: s.name}
+	}
+	return s.ts.report(SinkStream, s.name)
+}
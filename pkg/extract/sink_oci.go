@@ -0,0 +1,217 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// OCIArtifactType is the artifact type of the extractions pushed by the
+// OCISink, for registries and clients to tell them apart from images.
+const OCIArtifactType = "application/vnd.ctfer-io.monitoring.extraction.v1"
+
+// ociLayerTitle names the layer, e.g. for oras to pull it as a file.
+const ociLayerTitle = "extraction.tar.gz"
+
+// OCIConfig locates the OCI artifact to push the extraction as, and the
+// credentials to push it with.
+type OCIConfig struct {
+	// Reference of the artifact, as registry/repository[:tag], e.g.
+	// registry.example.com/ctf/extractions:2026-10-16. The tag defaults to
+	// latest.
+	Reference string
+
+	// Username and Password authenticate to the registry, or to its token
+	// service, if set.
+	Username string
+	Password string
+
+	// PlainHTTP reaches the registry without TLS, e.g. a local one.
+	PlainHTTP bool
+
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// OCISink pushes the files as an OCI artifact through oras, its single
+// layer being the tar.gz archive of the files. The layer is spooled into a
+// temporary file, as its digest is required to push it.
+type OCISink struct {
+	cfg OCIConfig
+
+	repo     *remote.Repository
+	f        *os.File
+	digester digest.Digester
+	ts       *tarStream
+
+	manifestDigest string
+}
+
+var _ SinkAborter = (*OCISink)(nil)
+
+// NewOCISink returns the sink pushing the OCI artifact.
+func NewOCISink(cfg OCIConfig) *OCISink {
+	return &OCISink{
+		cfg: cfg,
+	}
+}
+
+func (s *OCISink) Open(context.Context) error {
+	ref, err := parseOCIReference(s.cfg.Reference)
+	if err != nil {
+		return err
+	}
+	client := &auth.Client{
+		Client: s.cfg.Client,
+		Cache:  auth.NewCache(),
+	}
+	if s.cfg.Username != "" {
+		client.Credential = auth.StaticCredential(ref.Registry, auth.Credential{
+			Username: s.cfg.Username,
+			Password: s.cfg.Password,
+		})
+	}
+	s.repo = &remote.Repository{
+		Reference: ref,
+		PlainHTTP: s.cfg.PlainHTTP,
+		Client:    client,
+	}
+
+	s.f, err = os.CreateTemp("", "extraction-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	s.digester = digest.SHA256.Digester()
+	s.ts = newTarStream(io.MultiWriter(s.f, s.digester.Hash()), true)
+	return nil
+}
+
+func (s *OCISink) WriteEntry(_ context.Context, entry Entry, r io.Reader) error {
+	return s.ts.write(entry, r)
+}
+
+// Close pushes the layer and the empty config blobs, then the manifest
+// tagging them. The manifest is packed aside, to be pushed by its tag.
+func (s *OCISink) Close(ctx context.Context) error {
+	defer s.removeLayer()
+	if err := s.ts.close(); err != nil {
+		return err
+	}
+
+	layer := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayerGzip,
+		Digest:    s.digester.Digest(),
+		Size:      s.ts.report(SinkOCI, "").Bytes,
+		Annotations: map[string]string{
+			ocispec.AnnotationTitle: ociLayerTitle,
+		},
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := s.pushBlob(ctx, layer, s.f); err != nil {
+		return err
+	}
+	config := ocispec.DescriptorEmptyJSON
+	if err := s.pushBlob(ctx, config, bytes.NewReader(config.Data)); err != nil {
+		return err
+	}
+
+	store := memory.New()
+	manifest, err := oras.PackManifest(ctx, store, oras.PackManifestVersion1_1, OCIArtifactType, oras.PackManifestOptions{
+		Layers: []ocispec.Descriptor{layer},
+	})
+	if err != nil {
+		return fmt.Errorf("oci pack manifest: %w", err)
+	}
+	b, err := content.FetchAll(ctx, store, manifest)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.PushReference(ctx, manifest, bytes.NewReader(b), s.repo.Reference.Reference); err != nil {
+		return fmt.Errorf("oci push manifest: %w", err)
+	}
+	s.manifestDigest = manifest.Digest.String()
+	return nil
+}
+
+// pushBlob pushes the blob, unless the registry already holds it.
+func (s *OCISink) pushBlob(ctx context.Context, desc ocispec.Descriptor, r io.Reader) error {
+	exists, err := s.repo.Exists(ctx, desc)
+	if err != nil {
+		return fmt.Errorf("oci blob %s: %w", desc.Digest, err)
+	}
+	if exists {
+		return nil
+	}
+	if err := s.repo.Push(ctx, desc, r); err != nil {
+		return fmt.Errorf("oci push blob %s: %w", desc.Digest, err)
+	}
+	return nil
+}
+
+// Abort removes the layer, nothing being pushed before it is complete.
+func (s *OCISink) Abort(context.Context) error {
+	if s.f == nil {
+		return nil
+	}
+	return s.removeLayer()
+}
+
+func (s *OCISink) removeLayer() error {
+	_ = s.f.Close()
+	if err := os.Remove(s.f.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *OCISink) Report() SinkReport {
+	location := s.cfg.Reference
+	if s.repo != nil {
+		location = s.repo.Reference.String()
+	}
+	if s.ts == nil {
+		return SinkReport{Kind: SinkOCI, Location: location}
+	}
+	rep := s.ts.report(SinkOCI, location)
+	rep.Digest = s.manifestDigest
+	return rep
+}
+
+// parseOCIReference parses the reference, its tag defaulting to latest. The
+// registry is required, as no default one is, and the reference could not
+// be a digest as it is pushed.
+// Example: registry.example.com/ctf/extractions:v1 -> registry.example.com, ctf/extractions, v1
+func parseOCIReference(ref string) (registry.Reference, error) {
+	r, err := registry.ParseReference(ref)
+	if err != nil {
+		return registry.Reference{}, fmt.Errorf("invalid oci reference %s: %w", ref, err)
+	}
+	if !strings.ContainsAny(r.Registry, ".:") && r.Registry != "localhost" {
+		return registry.Reference{}, fmt.Errorf("oci reference %s does not start with a registry", ref)
+	}
+	if r.ValidateReferenceAsDigest() == nil {
+		return registry.Reference{}, fmt.Errorf("oci reference %s could not be a digest, as it is pushed", ref)
+	}
+	if strings.HasSuffix(ref, ":") {
+		return registry.Reference{}, fmt.Errorf("invalid oci reference %s: empty tag", ref)
+	}
+	if r.Reference == "" {
+		r.Reference = "latest"
+	}
+	return r, nil
+}
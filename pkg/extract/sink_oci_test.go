@@ -0,0 +1,178 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func Test_U_ParseOCIReference(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Reference          string
+		ExpectedRegistry   string
+		ExpectedRepository string
+		ExpectedTag        string
+		ExpectedError      bool
+	}{
+		"tagged": {
+			Reference:          "registry.example.com/ctf/extractions:2026-10-16",
+			ExpectedRegistry:   "registry.example.com",
+			ExpectedRepository: "ctf/extractions",
+			ExpectedTag:        "2026-10-16",
+		},
+		"port-latest": {
+			Reference:          "localhost:5000/extractions",
+			ExpectedRegistry:   "localhost:5000",
+			ExpectedRepository: "extractions",
+			ExpectedTag:        "latest",
+		},
+		"no-registry": {
+			Reference:     "ctf/extractions:v1",
+			ExpectedError: true,
+		},
+		"digest": {
+			Reference:     "registry.example.com/extractions@sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a",
+			ExpectedError: true,
+		},
+		"empty-tag": {
+			Reference:     "registry.example.com/extractions:",
+			ExpectedError: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			ref, err := parseOCIReference(tt.Reference)
+			if (err != nil) != tt.ExpectedError {
+				t.Fatalf("expected error: %t, got %v", tt.ExpectedError, err)
+			}
+			if ref.Registry != tt.ExpectedRegistry || ref.Repository != tt.ExpectedRepository || ref.Reference != tt.ExpectedTag {
+				t.Errorf("expected %s %s %s, got %s %s %s", tt.ExpectedRegistry, tt.ExpectedRepository, tt.ExpectedTag, ref.Registry, ref.Repository, ref.Reference)
+			}
+		})
+	}
+}
+
+// fakeRegistry serves the OCI distribution API pushes, behind a token
+// service.
+type fakeRegistry struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+}
+
+const (
+	fakeRegistryUser  = "ctfer"
+	fakeRegistryToken = "token-1"
+)
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	t.Helper()
+
+	reg := &fakeRegistry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+	}
+	reg.Server = httptest.NewServer(http.HandlerFunc(reg.serve))
+	t.Cleanup(reg.Close)
+	return reg
+}
+
+func (reg *fakeRegistry) config(repository string) OCIConfig {
+	return OCIConfig{
+		Reference: strings.TrimPrefix(reg.URL, "http://") + "/" + repository,
+		Username:  fakeRegistryUser,
+		Password:  "password",
+		PlainHTTP: true,
+	}
+}
+
+func (reg *fakeRegistry) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if user, _, ok := r.BasicAuth(); !ok || user != fakeRegistryUser || r.URL.Query().Get("scope") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token":%q}`, fakeRegistryToken)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+fakeRegistryToken {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, reg.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	p := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case r.Method == http.MethodHead && strings.Contains(p, "/blobs/"):
+		if _, ok := reg.blobs[p[strings.LastIndex(p, "/")+1:]]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPost && strings.HasSuffix(p, "/blobs/uploads/"):
+		w.Header().Set("Location", "/v2/"+p+"session-1?state=abc")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && strings.HasSuffix(p, "/blobs/uploads/session-1"):
+		dgst := r.URL.Query().Get("digest")
+		if r.URL.Query().Get("state") != "abc" || digest.FromBytes(body).String() != dgst {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"errors":[{"code":"DIGEST_INVALID","message":"digest mismatch"}]}`)
+			return
+		}
+		reg.blobs[dgst] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && strings.Contains(p, "/manifests/"):
+		if r.Header.Get("Content-Type") != ocispec.MediaTypeImageManifest {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reg.manifests[p] = body
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// layer returns the layer of the artifact pushed with the tag, checking its
+// manifest refers to blobs pushed.
+func (reg *fakeRegistry) layer(t *testing.T, repository, tag string) []byte {
+	t.Helper()
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	b, ok := reg.manifests[repository+"/manifests/"+tag]
+	if !ok {
+		t.Fatalf("expected a manifest for %s:%s", repository, tag)
+	}
+	manifest := ocispec.Manifest{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		t.Fatalf("invalid manifest: %s", err)
+	}
+	if manifest.ArtifactType != OCIArtifactType || len(manifest.Layers) != 1 {
+		t.Fatalf("expected an extraction artifact of a single layer, got %s", b)
+	}
+	if _, ok := reg.blobs[manifest.Config.Digest.String()]; !ok {
+		t.Errorf("expected the config blob %s to be pushed", manifest.Config.Digest)
+	}
+	layer, ok := reg.blobs[manifest.Layers[0].Digest.String()]
+	if !ok || int64(len(layer)) != manifest.Layers[0].Size {
+		t.Fatalf("expected the layer blob %s of %d bytes to be pushed", manifest.Layers[0].Digest, manifest.Layers[0].Size)
+	}
+	return layer
+}
@@ -0,0 +1,176 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+const (
+	defaultS3Region   = "us-east-1"
+	defaultS3PartSize = 8 << 20
+)
+
+// errS3Aborted fails the upload of an aborted S3Sink.
+var errS3Aborted = errors.New("s3 upload aborted")
+
+// S3Config locates the S3 object to upload the extraction to, and the
+// credentials to sign the requests with.
+type S3Config struct {
+	// Endpoint is the base URL of the S3 API, e.g. https://s3.eu-west-3.amazonaws.com
+	// or the one of a MinIO. The bucket is addressed path-style.
+	Endpoint string
+
+	// Region to sign the requests for. Defaults to us-east-1.
+	Region string
+
+	Bucket string
+	Key    string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken of temporary credentials, if any.
+	SessionToken string
+
+	// PartSize is the size of the parts uploaded, at least 5 MiB as S3
+	// requires. Defaults to 8 MiB.
+	PartSize int64
+
+	// Client sends the requests. Defaults to the one of the AWS SDK.
+	Client *http.Client
+}
+
+// S3Sink uploads the files as a tar.gz object, part after part as the
+// archive is written through the upload manager of the AWS SDK, such that
+// it is never held whole. The upload is aborted on failure, leaving no
+// object.
+type S3Sink struct {
+	cfg S3Config
+
+	// mu serializes the writes into the pipe the upload reads
+	mu sync.Mutex
+	ts *tarStream
+	pw *io.PipeWriter
+
+	// done is closed once the upload is over, err being its error
+	done chan struct{}
+	err  error
+}
+
+var _ SinkAborter = (*S3Sink)(nil)
+
+// NewS3Sink returns the sink uploading the tar.gz object.
+func NewS3Sink(cfg S3Config) *S3Sink {
+	if cfg.Region == "" {
+		cfg.Region = defaultS3Region
+	}
+	if cfg.PartSize == 0 {
+		cfg.PartSize = defaultS3PartSize
+	}
+	return &S3Sink{
+		cfg: cfg,
+	}
+}
+
+// Open starts the upload, reading the archive as it is written. It is not
+// cancelled along the extraction, for the manager to abort the multipart
+// upload once its pipe is closed.
+func (s *S3Sink) Open(ctx context.Context) error {
+	switch {
+	case s.cfg.Endpoint == "":
+		return errors.New("s3 endpoint is required")
+	case s.cfg.Bucket == "" || s.cfg.Key == "":
+		return errors.New("s3 bucket and key are required")
+	case s.cfg.AccessKeyID == "" || s.cfg.SecretAccessKey == "":
+		return errors.New("s3 credentials are required")
+	case s.cfg.PartSize < manager.MinUploadPartSize:
+		return fmt.Errorf("s3 part size %d is below the %d bytes minimum", s.cfg.PartSize, manager.MinUploadPartSize)
+	}
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(s.cfg.Endpoint),
+		Region:       s.cfg.Region,
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider(s.cfg.AccessKeyID, s.cfg.SecretAccessKey, s.cfg.SessionToken),
+		HTTPClient:   s.httpClient(),
+		// Only the checksums S3 requires, as S3-compatible stores may not
+		// support the others
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+	})
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = s.cfg.PartSize
+	})
+
+	pr, pw := io.Pipe()
+	s.pw = pw
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		_, err := uploader.Upload(context.WithoutCancel(ctx), &s3.PutObjectInput{
+			Bucket: aws.String(s.cfg.Bucket),
+			Key:    aws.String(s.cfg.Key),
+			Body:   pr,
+		})
+		if err != nil {
+			s.err = fmt.Errorf("s3 upload: %w", err)
+		}
+		// Unblock the writes, the upload being over
+		_ = pr.CloseWithError(s.err)
+	}()
+	s.ts = newTarStream(pw, true)
+	return nil
+}
+
+func (s *S3Sink) httpClient() s3.HTTPClient {
+	if s.cfg.Client == nil {
+		return nil
+	}
+	return s.cfg.Client
+}
+
+func (s *S3Sink) WriteEntry(_ context.Context, entry Entry, r io.Reader) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ts.write(entry, r)
+}
+
+// Close writes the end of the archive, then waits for its upload to
+// complete.
+func (s *S3Sink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.ts.close(); err != nil {
+		return err
+	}
+	_ = s.pw.Close()
+	<-s.done
+	return s.err
+}
+
+// Abort fails the upload unless over, for the manager to abort the
+// multipart upload and S3 to discard its parts. The manager does so on any
+// failure, ignoring the errors of the abort itself.
+func (s *S3Sink) Abort(context.Context) error {
+	if s.pw == nil {
+		return nil
+	}
+	_ = s.pw.CloseWithError(errS3Aborted)
+	<-s.done
+	return nil
+}
+
+func (s *S3Sink) Report() SinkReport {
+	location := "s3://" + s.cfg.Bucket + "/" + s.cfg.Key
+	if s.ts == nil {
+		return SinkReport{Kind: SinkS3, Location: location}
+	}
+	return s.ts.report(SinkS3, location)
+}
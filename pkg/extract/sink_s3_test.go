@@ -0,0 +1,188 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func Test_U_S3Sink_Parts(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeS3(t)
+	sink := NewS3Sink(srv.config())
+	data := writeLargeEntry(t, sink)
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if srv.parts < 2 {
+		t.Errorf("expected the archive to be uploaded in parts, got %d", srv.parts)
+	}
+	files := readTar(t, bytes.NewReader(srv.object(t, "extractions", "run-1/extraction.tar.gz")), true)
+	if len(files) != 1 || !bytes.Equal(files[0].Content, data) {
+		t.Errorf("expected the entry to land whole, got %d files", len(files))
+	}
+}
+
+func Test_U_S3Sink_Abort(t *testing.T) {
+	t.Parallel()
+
+	srv := newFakeS3(t)
+	srv.failComplete = true
+	sink := NewS3Sink(srv.config())
+	_ = writeLargeEntry(t, sink)
+
+	err := sink.Close(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "NoSuchUpload") {
+		t.Fatalf("expected the completion error, got %v", err)
+	}
+	if err := sink.Abort(context.Background()); err != nil {
+		t.Fatalf("unexpected abort error: %s", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !srv.aborted {
+		t.Error("expected the upload to be aborted")
+	}
+}
+
+// writeLargeEntry opens the sink and writes an incompressible entry into
+// it, spanning parts of the minimum size.
+func writeLargeEntry(t *testing.T, sink *S3Sink) []byte {
+	t.Helper()
+
+	data := make([]byte, 2*manager.MinUploadPartSize+1)
+	_, _ = rand.Read(data)
+	ctx := context.Background()
+	if err := sink.Open(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := sink.WriteEntry(ctx, Entry{
+		Path:    "otel_traces",
+		Size:    int64(len(data)),
+		Mode:    0o644,
+		ModTime: time.Now(),
+	}, bytes.NewReader(data)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return data
+}
+
+// fakeS3 serves the uploads of a single object, in one or multiple parts.
+type fakeS3 struct {
+	*httptest.Server
+
+	failComplete bool
+
+	mu      sync.Mutex
+	pending map[int][]byte
+	parts   int
+	objects map[string][]byte
+	aborted bool
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	t.Helper()
+
+	s3 := &fakeS3{
+		pending: map[int][]byte{},
+		objects: map[string][]byte{},
+	}
+	s3.Server = httptest.NewServer(http.HandlerFunc(s3.serve))
+	t.Cleanup(s3.Close)
+	return s3
+}
+
+func (s3 *fakeS3) config() S3Config {
+	return S3Config{
+		Endpoint:        s3.URL,
+		Region:          "eu-west-3",
+		Bucket:          "extractions",
+		Key:             "run-1/extraction.tar.gz",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		PartSize:        manager.MinUploadPartSize,
+	}
+}
+
+func (s3 *fakeS3) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		fmt.Fprint(w, `<InitiateMultipartUploadResult><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Get("uploadId") == "upload-1":
+		var number int
+		fmt.Sscan(query.Get("partNumber"), &number)
+		s3.pending[number] = body
+		s3.parts++
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, number))
+	case r.Method == http.MethodPost && query.Get("uploadId") == "upload-1":
+		if s3.failComplete {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code><Message>upload expired</Message></Error>`)
+			return
+		}
+		complete := struct {
+			Parts []s3Part `xml:"Part"`
+		}{}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		object := []byte{}
+		for i, part := range complete.Parts {
+			if part.PartNumber != i+1 || part.ETag != fmt.Sprintf(`"etag-%d"`, i+1) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			object = append(object, s3.pending[part.PartNumber]...)
+		}
+		s3.objects[strings.TrimPrefix(r.URL.Path, "/")] = object
+		fmt.Fprint(w, `<CompleteMultipartUploadResult></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodPut && !query.Has("uploadId"):
+		s3.objects[strings.TrimPrefix(r.URL.Path, "/")] = body
+	case r.Method == http.MethodDelete && query.Get("uploadId") == "upload-1":
+		s3.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// s3Part is an uploaded part, as completing the upload lists them.
+type s3Part struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+func (s3 *fakeS3) object(t *testing.T, bucket, key string) []byte {
+	t.Helper()
+
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	object, ok := s3.objects[bucket+"/"+key]
+	if !ok {
+		t.Fatalf("expected object %s/%s to be uploaded", bucket, key)
+	}
+	return object
+}
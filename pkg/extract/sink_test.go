@@ -0,0 +1,297 @@
+package extract
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func Test_U_Sinks(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		// Sink returns the sink, and the function reading back the files
+		// landed into it, in order
+		Sink         func(t *testing.T) (Sink, func(t *testing.T) []tarFile)
+		ExpectedKind string
+	}{
+		"directory": {
+			Sink: func(t *testing.T) (Sink, func(t *testing.T) []tarFile) {
+				dir := t.TempDir()
+				return NewDirectorySink(dir), func(t *testing.T) []tarFile {
+					return readDirectory(t, dir)
+				}
+			},
+		},
+		"archive": {
+			Sink: func(t *testing.T) (Sink, func(t *testing.T) []tarFile) {
+				p := filepath.Join(t.TempDir(), "runs", "extraction.tar.gz")
+				return NewArchiveSink(p), func(t *testing.T) []tarFile {
+					f, err := os.Open(p)
+					if err != nil {
+						t.Fatalf("expected the archive: %s", err)
+					}
+					defer f.Close()
+					return readTar(t, f, true)
+				}
+			},
+			ExpectedKind: SinkArchive,
+		},
+		"stream": {
+			Sink: func(t *testing.T) (Sink, func(t *testing.T) []tarFile) {
+				buf := &bytes.Buffer{}
+				return NewStreamSink("stdout", buf), func(t *testing.T) []tarFile {
+					return readTar(t, buf, false)
+				}
+			},
+			ExpectedKind: SinkStream,
+		},
+		"s3": {
+			Sink: func(t *testing.T) (Sink, func(t *testing.T) []tarFile) {
+				srv := newFakeS3(t)
+				sink := NewS3Sink(srv.config())
+				return sink, func(t *testing.T) []tarFile {
+					return readTar(t, bytes.NewReader(srv.object(t, "extractions", "run-1/extraction.tar.gz")), true)
+				}
+			},
+			ExpectedKind: SinkS3,
+		},
+		"oci": {
+			Sink: func(t *testing.T) (Sink, func(t *testing.T) []tarFile) {
+				reg := newFakeRegistry(t)
+				sink := NewOCISink(reg.config("ctf/extractions:run-1"))
+				return sink, func(t *testing.T) []tarFile {
+					return readTar(t, bytes.NewReader(reg.layer(t, "ctf/extractions", "run-1")), true)
+				}
+			},
+			ExpectedKind: SinkOCI,
+		},
+	}
+
+	expected := fixtureContents(t)
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			sink, read := tt.Sink(t)
+			res, err := ReplayOTelCollector(context.Background(), fixtureDir, "",
+				WithSink(sink),
+				WithVerifyRemote(true),
+			)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// Checksums are computed above the sink, whatever it is
+			if res.Files != len(fixtureFiles) || res.Verify == nil || len(res.Verify.Matching) != len(fixtureFiles) {
+				t.Errorf("expected %d files verified, got %d files and %+v", len(fixtureFiles), res.Files, res.Verify)
+			}
+
+			files := read(t)
+			got := map[string][]byte{}
+			for _, f := range files {
+				got[f.Name] = f.Content
+			}
+			for name, content := range expected {
+				if !bytes.Equal(got[name], content) {
+					t.Errorf("expected %s to land whole, got %q", name, got[name])
+				}
+			}
+
+			// The report comes last, describing where the files land
+			last := files[len(files)-1]
+			if last.Name != ReportFile {
+				t.Fatalf("expected the report last, got %s", last.Name)
+			}
+			rep := &Result{}
+			if err := json.Unmarshal(last.Content, rep); err != nil {
+				t.Fatalf("invalid report: %s", err)
+			}
+			if rep.Files != len(fixtureFiles) {
+				t.Errorf("expected the report to count %d files, got %d", len(fixtureFiles), rep.Files)
+			}
+			switch {
+			case tt.ExpectedKind == "" && (res.Sink != nil || res.Report == ""):
+				t.Errorf("expected the report locally, got %+v and %q", res.Sink, res.Report)
+			case tt.ExpectedKind != "" && (res.Sink == nil || res.Sink.Kind != tt.ExpectedKind || rep.Sink == nil):
+				t.Errorf("expected the %s sink to be reported, got %+v", tt.ExpectedKind, res.Sink)
+			case tt.ExpectedKind != "" && res.Sink.Entries != len(fixtureFiles)+1:
+				t.Errorf("expected the sink to hold %d entries, got %d", len(fixtureFiles)+1, res.Sink.Entries)
+			}
+			if tt.ExpectedKind != "" && !strings.Contains(res.Summary[0].Text, res.Sink.Location) {
+				t.Errorf("expected the summary to mention the sink, got %q", res.Summary[0].Text)
+			}
+		})
+	}
+}
+
+func Test_U_Options_Sink(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Into          string
+		Opts          []Option
+		ExpectedError bool
+		ExpectedLocal bool
+	}{
+		"directory": {
+			Into:          "out",
+			ExpectedLocal: true,
+		},
+		"directory-sink": {
+			Opts:          []Option{WithSink(NewDirectorySink("out")), WithDecompress(true)},
+			ExpectedLocal: true,
+		},
+		"archive": {
+			Opts: []Option{WithSink(NewArchiveSink("out.tar.gz"))},
+		},
+		"none": {
+			ExpectedError: true,
+		},
+		"directory-and-sink": {
+			Into:          "out",
+			Opts:          []Option{WithSink(NewArchiveSink("out.tar.gz"))},
+			ExpectedError: true,
+		},
+		"decompress-archive": {
+			Opts:          []Option{WithSink(NewArchiveSink("out.tar.gz")), WithDecompress(true)},
+			ExpectedError: true,
+		},
+		"fsync-directory": {
+			Into:          "out",
+			Opts:          []Option{WithFsync(true)},
+			ExpectedLocal: true,
+		},
+		"fsync-archive": {
+			Opts:          []Option{WithSink(NewArchiveSink("out.tar.gz")), WithFsync(true)},
+			ExpectedError: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			for _, opt := range tt.Opts {
+				opt.apply(options)
+			}
+			err := options.checkSink(tt.Into)
+			if (err != nil) != tt.ExpectedError {
+				t.Fatalf("expected error: %t, got %v", tt.ExpectedError, err)
+			}
+			if err != nil {
+				return
+			}
+			if _, ok := localDirectory(options.sink); ok != tt.ExpectedLocal {
+				t.Errorf("expected local files: %t", tt.ExpectedLocal)
+			}
+			if ds, ok := options.sink.(*DirectorySink); ok && ds.fsync != options.fsync {
+				t.Errorf("expected the directory sink fsync to be %t", options.fsync)
+			}
+		})
+	}
+}
+
+func Test_U_ArchiveSink_Abort(t *testing.T) {
+	t.Parallel()
+
+	// The checksums are not recorded, failing the verification once copied
+	fixture := t.TempDir()
+	for _, f := range []string{FixtureFile, fixtureArchive, fixtureDiskUsage} {
+		copyFile(t, filepath.Join(fixtureDir, f), filepath.Join(fixture, f))
+	}
+
+	p := filepath.Join(t.TempDir(), "extraction.tar.gz")
+	_, err := ReplayOTelCollector(context.Background(), fixture, "",
+		WithSink(NewArchiveSink(p)),
+		WithVerifyRemote(true),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, f := range []string{p, p + PartialSuffix} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("expected no %s once aborted, got %v", f, err)
+		}
+	}
+}
+
+// tarFile is a file read back from an archive.
+type tarFile struct {
+	Name    string
+	Content []byte
+}
+
+// readTar reads the files of the archive, in order.
+func readTar(t *testing.T, r io.Reader, compressed bool) []tarFile {
+	t.Helper()
+
+	if compressed {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("invalid gzip: %s", err)
+		}
+		r = gz
+	}
+	files := []tarFile{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("invalid archive: %s", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("invalid archive: %s", err)
+		}
+		files = append(files, tarFile{Name: path.Clean(hdr.Name), Content: b})
+	}
+}
+
+// readDirectory reads the files of the directory, the report last.
+func readDirectory(t *testing.T, dir string) []tarFile {
+	t.Helper()
+
+	files := []tarFile{}
+	for _, f := range slices.Concat(fixtureFiles, []string{ReportFile}) {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			t.Fatalf("expected %s: %s", f, err)
+		}
+		files = append(files, tarFile{Name: f, Content: b})
+	}
+	return files
+}
+
+// fixtureContents returns the content of the fixture files, by path.
+func fixtureContents(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join(fixtureDir, fixtureArchive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	contents := map[string][]byte{}
+	for _, file := range readTar(t, f, false) {
+		contents[file.Name] = file.Content
+	}
+	return contents
+}
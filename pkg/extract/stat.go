@@ -0,0 +1,258 @@
+package extract
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PVCStat is the usage of a PVC at some point in time, as measured in an
+// extraction Pod.
+type PVCStat struct {
+	Namespace string    `json:"namespace"`
+	PVCName   string    `json:"pvc_name"`
+	Time      time.Time `json:"time"`
+
+	// CapacityBytes, UsedBytes and AvailableBytes are the ones of the
+	// filesystem of the volume, as df reports them.
+	CapacityBytes  int64 `json:"capacity_bytes"`
+	UsedBytes      int64 `json:"used_bytes"`
+	AvailableBytes int64 `json:"available_bytes"`
+
+	// SourceBytes and Files are the disk usage and number of files under
+	// the source path, i.e. the ones an extraction would copy.
+	SourceBytes int64 `json:"source_bytes"`
+	Files       int64 `json:"files"`
+}
+
+// PVCGrowth is the growth of a PVC between two stats.
+type PVCGrowth struct {
+	// Since is the time of the previous stat.
+	Since time.Time `json:"since"`
+
+	BytesPerHour float64 `json:"bytes_per_hour"`
+	FilesPerHour float64 `json:"files_per_hour"`
+
+	// TimeToFull is the time the available bytes last at this growth
+	// rate, and FullAt when they run out. Not set if the PVC does not grow.
+	TimeToFull *time.Duration `json:"-"`
+	FullAt     *time.Time     `json:"full_at,omitempty"`
+}
+
+// UsedPercent returns the percentage of the capacity used.
+func (st PVCStat) UsedPercent() float64 {
+	if st.CapacityBytes == 0 {
+		return 0
+	}
+	return float64(st.UsedBytes) / float64(st.CapacityBytes) * 100
+}
+
+// ComputeGrowth returns the growth from the previous stat of the same PVC
+// to the current one. The used bytes of the filesystem are considered,
+// rather than the source ones, as they are the ones filling it up.
+func ComputeGrowth(prev, cur PVCStat) (*PVCGrowth, error) {
+	if prev.Namespace != cur.Namespace || prev.PVCName != cur.PVCName {
+		return nil, fmt.Errorf("previous stat is of PVC %s/%s, not %s/%s",
+			prev.Namespace, prev.PVCName, cur.Namespace, cur.PVCName)
+	}
+	elapsed := cur.Time.Sub(prev.Time)
+	if elapsed <= 0 {
+		return nil, fmt.Errorf("previous stat at %s is not before the current one at %s",
+			prev.Time.Format(time.RFC3339), cur.Time.Format(time.RFC3339))
+	}
+
+	hours := elapsed.Hours()
+	growth := &PVCGrowth{
+		Since:        prev.Time,
+		BytesPerHour: float64(cur.UsedBytes-prev.UsedBytes) / hours,
+		FilesPerHour: float64(cur.Files-prev.Files) / hours,
+	}
+	if growth.BytesPerHour > 0 {
+		ttf := time.Duration(float64(cur.AvailableBytes) / growth.BytesPerHour * float64(time.Hour))
+		fullAt := cur.Time.Add(ttf)
+		growth.TimeToFull, growth.FullAt = &ttf, &fullAt
+	}
+	return growth, nil
+}
+
+// ReadStat reads the stat saved in the file, e.g. by a previous run.
+func ReadStat(file string) (*PVCStat, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	st := &PVCStat{}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("invalid stat %s: %w", file, err)
+	}
+	return st, nil
+}
+
+// WriteStat saves the stat in the file, through a temporary one such that a
+// previous stat is never left truncated.
+func WriteStat(file string, st *PVCStat) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// StatPVC measures the usage of the PVC, given its namespace and name, in
+// an extraction Pod: the filesystem of its volume, and the source path
+// the extraction would copy.
+func StatPVC(
+	ctx context.Context,
+	namespace, pvcName string,
+	opts ...Option,
+) (*PVCStat, error) {
+	// Prepare functional options
+	options := &options{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.record != "" {
+		return nil, errors.New("could not record a stat")
+	}
+
+	// Prepare K8s client
+	clientset, config, err := getClient(options.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Pod and mount PVC
+	options.logger.Info("creating Pod",
+		zap.String("pod", podName),
+		zap.String("namespace", namespace),
+		zap.String("pvc", pvcName),
+		zap.Duration("gc_after", options.gcAfter),
+	)
+	pod, err := createExtractor(ctx, clientset, namespace, pvcName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	options.logger.Info("waiting for the pod to be ready",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+	)
+	st, err := func() (*PVCStat, error) {
+		if err := waitForPodReady(ctx, clientset, namespace, pod); err != nil {
+			return nil, err
+		}
+		_, exec, closeTransport, err := podTransport(ctx, config, clientset, namespace, pod, "copy", options)
+		if err != nil {
+			return nil, err
+		}
+		defer closeTransport()
+		return statPVC(ctx, exec, options.sourcePath)
+	}()
+
+	// Delete Pod, whether measured or not
+	options.logger.Info("deleting pod",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+	)
+	if derr := deleteExtractor(ctx, clientset, namespace, pod, options); derr != nil {
+		if err != nil || !errors.Is(derr, ErrPodLingering) {
+			return nil, errors.Join(err, derr)
+		}
+		options.logger.Warn("pod lingering after deletion",
+			zap.Error(derr),
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st.Namespace, st.PVCName = namespace, pvcName
+	return st, nil
+}
+
+// statPVC measures the usage of the filesystem of podPath, and of podPath
+// itself, through the executor.
+func statPVC(ctx context.Context, exec podExecutor, podPath string) (*PVCStat, error) {
+	out := &bytes.Buffer{}
+	if err := exec(ctx, statCommand(podPath), out); err != nil {
+		return nil, err
+	}
+	st, err := parseStat(out.String())
+	if err != nil {
+		return nil, err
+	}
+	st.Time = time.Now().UTC()
+	return st, nil
+}
+
+// statCommand returns the command printing, one per line, the df -Pk line
+// of the filesystem of podPath, its disk usage as du -sk does, then its
+// number of files.
+func statCommand(podPath string) []string {
+	return []string{"sh", "-c", fmt.Sprintf("df -Pk %[1]q | tail -n 1 && du -sk %[1]q && find %[1]q -type f | wc -l", podPath)}
+}
+
+// parseStat parses the output of the stat command, the sizes being in KiB.
+func parseStat(out string) (*PVCStat, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("expected the df, du and file count lines, got %q", out)
+	}
+
+	// Filesystem 1024-blocks Used Available Capacity Mounted-on
+	df := strings.Fields(lines[0])
+	if len(df) < 6 {
+		return nil, fmt.Errorf("invalid df line %q", lines[0])
+	}
+	sizes := [3]int64{}
+	for i, field := range df[1:4] {
+		kib, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || kib < 0 {
+			return nil, fmt.Errorf("invalid df line %q", lines[0])
+		}
+		sizes[i] = kib << 10
+	}
+
+	usage, err := parseDiskUsage(lines[1])
+	if err != nil {
+		return nil, err
+	}
+	files, err := strconv.ParseInt(strings.TrimSpace(lines[2]), 10, 64)
+	if err != nil || files < 0 {
+		return nil, fmt.Errorf("invalid file count %q", lines[2])
+	}
+
+	return &PVCStat{
+		CapacityBytes:  sizes[0],
+		UsedBytes:      sizes[1],
+		AvailableBytes: sizes[2],
+		SourceBytes:    usage,
+		Files:          files,
+	}, nil
+}
@@ -0,0 +1,239 @@
+package extract
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_U_ParseStat(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Output    string
+		Expected  *PVCStat
+		ExpectErr bool
+	}{
+		"valid": {
+			Output: "/dev/sdb 1024000 256000 768000 25% /data\n204800\t/data\n42\n",
+			Expected: &PVCStat{
+				CapacityBytes:  1024000 << 10,
+				UsedBytes:      256000 << 10,
+				AvailableBytes: 768000 << 10,
+				SourceBytes:    204800 << 10,
+				Files:          42,
+			},
+		},
+		"padded-count": {
+			// busybox wc pads the count
+			Output: "overlay 100 10 90 10% /data\n8\t/data\n       3\n",
+			Expected: &PVCStat{
+				CapacityBytes:  100 << 10,
+				UsedBytes:      10 << 10,
+				AvailableBytes: 90 << 10,
+				SourceBytes:    8 << 10,
+				Files:          3,
+			},
+		},
+		"missing-line": {
+			Output:    "/dev/sdb 1024000 256000 768000 25% /data\n204800\t/data\n",
+			ExpectErr: true,
+		},
+		"truncated-df": {
+			Output:    "/dev/sdb 1024000 256000\n204800\t/data\n42\n",
+			ExpectErr: true,
+		},
+		"invalid-df": {
+			Output:    "/dev/sdb 1024000 lots 768000 25% /data\n204800\t/data\n42\n",
+			ExpectErr: true,
+		},
+		"invalid-count": {
+			Output:    "/dev/sdb 1024000 256000 768000 25% /data\n204800\t/data\nmany\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			st, err := parseStat(tt.Output)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err == nil && !reflect.DeepEqual(st, tt.Expected) {
+				t.Errorf("expected stat %+v, got %+v", tt.Expected, st)
+			}
+		})
+	}
+}
+
+func Test_U_StatPVC(t *testing.T) {
+	t.Parallel()
+
+	exec := func(_ context.Context, command []string, stdout io.Writer) error {
+		if !slices.Equal(command, statCommand("/data/collector")) {
+			t.Errorf("unexpected command %v", command)
+		}
+		_, err := io.WriteString(stdout, "/dev/sdb 2048 1024 1024 50% /data\n512\t/data/collector\n7\n")
+		return err
+	}
+
+	st, err := statPVC(context.Background(), exec, "/data/collector")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st.UsedBytes != 1<<20 || st.SourceBytes != 512<<10 || st.Files != 7 {
+		t.Errorf("unexpected stat %+v", st)
+	}
+	if st.Time.IsZero() {
+		t.Error("expected the stat to be timed")
+	}
+}
+
+func Test_U_ComputeGrowth(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	stat := func(after time.Duration, used, available, files int64) PVCStat {
+		return PVCStat{
+			Namespace:      "monitoring",
+			PVCName:        "otel-signals",
+			Time:           at.Add(after),
+			CapacityBytes:  used + available,
+			UsedBytes:      used,
+			AvailableBytes: available,
+			Files:          files,
+		}
+	}
+
+	var tests = map[string]struct {
+		Prev               PVCStat
+		Cur                PVCStat
+		ExpectedBytes      float64
+		ExpectedFiles      float64
+		ExpectedTimeToFull *time.Duration
+		ExpectErr          bool
+	}{
+		"growing": {
+			// 100MiB in 2h, 500MiB left: 10h to full
+			Prev:               stat(0, 100<<20, 600<<20, 10),
+			Cur:                stat(2*time.Hour, 200<<20, 500<<20, 30),
+			ExpectedBytes:      50 << 20,
+			ExpectedFiles:      10,
+			ExpectedTimeToFull: ptr(10 * time.Hour),
+		},
+		"steady": {
+			Prev: stat(0, 100<<20, 600<<20, 10),
+			Cur:  stat(time.Hour, 100<<20, 600<<20, 10),
+		},
+		"shrinking": {
+			// e.g. rotated files
+			Prev:          stat(0, 200<<20, 500<<20, 30),
+			Cur:           stat(time.Hour, 100<<20, 600<<20, 20),
+			ExpectedBytes: -100 << 20,
+			ExpectedFiles: -10,
+		},
+		"other-pvc": {
+			Prev: func() PVCStat {
+				st := stat(0, 100<<20, 600<<20, 10)
+				st.PVCName = "prometheus"
+				return st
+			}(),
+			Cur:       stat(time.Hour, 200<<20, 500<<20, 30),
+			ExpectErr: true,
+		},
+		"not-before": {
+			Prev:      stat(time.Hour, 100<<20, 600<<20, 10),
+			Cur:       stat(time.Hour, 200<<20, 500<<20, 30),
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			growth, err := ComputeGrowth(tt.Prev, tt.Cur)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if growth.BytesPerHour != tt.ExpectedBytes || growth.FilesPerHour != tt.ExpectedFiles {
+				t.Errorf("expected %f B/h and %f files/h, got %f and %f", tt.ExpectedBytes, tt.ExpectedFiles, growth.BytesPerHour, growth.FilesPerHour)
+			}
+			if !growth.Since.Equal(tt.Prev.Time) {
+				t.Errorf("expected the growth since %s, got %s", tt.Prev.Time, growth.Since)
+			}
+			switch {
+			case tt.ExpectedTimeToFull == nil && growth.TimeToFull != nil:
+				t.Errorf("expected no time to full, got %s", *growth.TimeToFull)
+			case tt.ExpectedTimeToFull != nil && growth.TimeToFull == nil:
+				t.Errorf("expected time to full %s, got none", *tt.ExpectedTimeToFull)
+			case tt.ExpectedTimeToFull != nil:
+				if *growth.TimeToFull != *tt.ExpectedTimeToFull {
+					t.Errorf("expected time to full %s, got %s", *tt.ExpectedTimeToFull, *growth.TimeToFull)
+				}
+				if fullAt := tt.Cur.Time.Add(*tt.ExpectedTimeToFull); !growth.FullAt.Equal(fullAt) {
+					t.Errorf("expected full at %s, got %s", fullAt, growth.FullAt)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Stat_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "stat.json")
+	if _, err := ReadStat(file); !os.IsNotExist(err) {
+		t.Fatalf("expected no stat yet, got %v", err)
+	}
+
+	st := &PVCStat{
+		Namespace:      "monitoring",
+		PVCName:        "otel-signals",
+		Time:           time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+		CapacityBytes:  1 << 30,
+		UsedBytes:      1 << 20,
+		AvailableBytes: 1<<30 - 1<<20,
+		SourceBytes:    512 << 10,
+		Files:          3,
+	}
+	for range 2 {
+		// Overwritten as is
+		if err := WriteStat(file, st); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	read, err := ReadStat(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(read, st) {
+		t.Errorf("expected stat %+v, got %+v", st, read)
+	}
+
+	// No temporary file is left
+	entries, err := os.ReadDir(filepath.Dir(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the stat file, got %d entries", len(entries))
+	}
+
+	if err := os.WriteFile(file, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadStat(file); err == nil {
+		t.Error("expected an invalid stat to fail")
+	}
+}
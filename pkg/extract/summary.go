@@ -0,0 +1,129 @@
+package extract
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"time"
+)
+
+// Levels of the summary lines.
+const (
+	SummaryInfo    = "info"
+	SummaryWarning = "warning"
+	SummaryError   = "error"
+)
+
+// SummaryLine is a fact of the human-readable summary of an extraction.
+type SummaryLine struct {
+	Level string `json:"level"`
+	Text  string `json:"text"`
+}
+
+// summarize returns the human-readable summary of the extraction: what was
+// copied, where, how big, and the warnings.
+func (res *Result) summarize() []SummaryLine {
+	from := "PVC " + path.Join(res.Namespace, res.PVCName)
+	switch res.Source {
+	case SourcePrometheus:
+		from = "Prometheus snapshot " + res.Snapshot + " in " + res.Namespace
+	case SourceAll:
+		from = fmt.Sprintf("%d PVCs of namespace %s", res.pvcs(), res.Namespace)
+	}
+	if res.Fixture != "" {
+		from = "fixture " + res.Fixture + " of " + from
+	}
+	to := res.Directory
+	if res.Sink != nil {
+		to = res.Sink.Kind + " " + res.Sink.Location
+	}
+	lines := []SummaryLine{{
+		Level: SummaryInfo,
+		Text: fmt.Sprintf("copied %d files (%s) from %s to %s in %s",
+			res.Files, formatBytes(res.Bytes), from, to, res.Duration.Round(time.Millisecond)),
+	}}
+
+	for _, component := range slices.Sorted(maps.Keys(res.Components)) {
+		for _, pvc := range res.Components[component] {
+			line := SummaryLine{
+				Level: SummaryInfo,
+				Text:  fmt.Sprintf("%s PVC %s: %d files (%s)", component, pvc.PVCName, pvc.Files, formatBytes(pvc.Bytes)),
+			}
+			if pvc.Error != "" {
+				line = SummaryLine{
+					Level: SummaryError,
+					Text:  fmt.Sprintf("%s PVC %s: %s", component, pvc.PVCName, pvc.Error),
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if res.Encrypted != "" {
+		lines = append(lines, SummaryLine{
+			Level: SummaryInfo,
+			Text:  "encrypted with age in the Pod into " + res.Encrypted + ", decrypt it with the recipient identity",
+		})
+	}
+
+	if len(res.Decompressed) != 0 {
+		var original, decompressed int64
+		for _, f := range res.Decompressed {
+			original += f.OriginalBytes
+			decompressed += f.DecompressedBytes
+		}
+		lines = append(lines, SummaryLine{
+			Level: SummaryInfo,
+			Text: fmt.Sprintf("decompressed %d files (%s to %s)",
+				len(res.Decompressed), formatBytes(original), formatBytes(decompressed)),
+		})
+	}
+
+	if res.Verify != nil {
+		level := SummaryInfo
+		if len(res.Verify.MissingRemote) != 0 || len(res.Verify.MissingLocal) != 0 {
+			level = SummaryWarning
+		}
+		if res.Verify.Failed() {
+			level = SummaryError
+		}
+		lines = append(lines, SummaryLine{
+			Level: level,
+			Text: fmt.Sprintf("verified against the PVC: %d matching, %d mismatching, %d no longer on the PVC, %d not extracted",
+				len(res.Verify.Matching), len(res.Verify.Mismatched), len(res.Verify.MissingRemote), len(res.Verify.MissingLocal)),
+		})
+	}
+
+	for _, warn := range res.Warnings {
+		lines = append(lines, SummaryLine{
+			Level: SummaryWarning,
+			Text:  warn,
+		})
+	}
+	return lines
+}
+
+// pvcs returns the number of PVCs extracted along the namespace.
+func (res *Result) pvcs() int {
+	n := 0
+	for _, pvcs := range res.Components {
+		n += len(pvcs)
+	}
+	return n
+}
+
+// formatBytes formats a size in bytes with binary units.
+// Example: 2048 -> 2.0 KiB
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,145 @@
+package extract
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_U_Summarize(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Result   *Result
+		Expected []SummaryLine
+	}{
+		"otel": {
+			Result: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Directory: "extract",
+				Files:     3,
+				Bytes:     2048,
+				Duration:  1500 * time.Millisecond,
+				Warnings:  []string{"file no longer on the PVC: otel_logs"},
+			},
+			Expected: []SummaryLine{
+				{SummaryInfo, "copied 3 files (2.0 KiB) from PVC monitoring/signals to extract in 1.5s"},
+				{SummaryWarning, "file no longer on the PVC: otel_logs"},
+			},
+		},
+		"otel-decompressed-verified": {
+			Result: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Directory: "extract",
+				Files:     2,
+				Bytes:     3 << 20,
+				Duration:  time.Minute,
+				Decompressed: []DecompressedFile{
+					{Path: "otel_traces", OriginalBytes: 1 << 20, DecompressedBytes: 10 << 20},
+				},
+				Verify: &VerifyReport{
+					Matching:      []string{"otel_traces.gz"},
+					MissingRemote: []string{"otel_logs"},
+				},
+			},
+			Expected: []SummaryLine{
+				{SummaryInfo, "copied 2 files (3.0 MiB) from PVC monitoring/signals to extract in 1m0s"},
+				{SummaryInfo, "decompressed 1 files (1.0 MiB to 10.0 MiB)"},
+				{SummaryWarning, "verified against the PVC: 1 matching, 0 mismatching, 1 no longer on the PVC, 0 not extracted"},
+			},
+		},
+		"otel-encrypted": {
+			Result: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Directory: "extract",
+				Files:     1,
+				Bytes:     4096,
+				Duration:  time.Second,
+				Encrypted: EncryptedArchiveFile,
+			},
+			Expected: []SummaryLine{
+				{SummaryInfo, "copied 1 files (4.0 KiB) from PVC monitoring/signals to extract in 1s"},
+				{SummaryInfo, "encrypted with age in the Pod into extraction.tar.age, decrypt it with the recipient identity"},
+			},
+		},
+		"otel-mismatch": {
+			Result: &Result{
+				Source:    SourceOTelCollector,
+				Namespace: "monitoring",
+				PVCName:   "signals",
+				Directory: "extract",
+				Files:     1,
+				Bytes:     512,
+				Duration:  time.Second,
+				Verify: &VerifyReport{
+					Mismatched: []string{"otel_traces"},
+				},
+			},
+			Expected: []SummaryLine{
+				{SummaryInfo, "copied 1 files (512 B) from PVC monitoring/signals to extract in 1s"},
+				{SummaryError, "verified against the PVC: 0 matching, 1 mismatching, 0 no longer on the PVC, 0 not extracted"},
+			},
+		},
+		"prometheus": {
+			Result: &Result{
+				Source:    SourcePrometheus,
+				Namespace: "monitoring",
+				Directory: "extract",
+				Snapshot:  "20260101T000000Z-0123456789abcdef",
+				Files:     12,
+				Bytes:     5 << 30,
+				Duration:  90 * time.Second,
+			},
+			Expected: []SummaryLine{
+				{SummaryInfo, "copied 12 files (5.0 GiB) from Prometheus snapshot 20260101T000000Z-0123456789abcdef in monitoring to extract in 1m30s"},
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.Result.summarize(); !reflect.DeepEqual(got, tt.Expected) {
+				t.Errorf("expected %v, got %v", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_WriteReport_Summary(t *testing.T) {
+	t.Parallel()
+
+	res := &Result{
+		Source:    SourceOTelCollector,
+		Namespace: "monitoring",
+		PVCName:   "signals",
+		Directory: t.TempDir(),
+		Warnings:  []string{"file no longer on the PVC: otel_logs"},
+	}
+	if err := res.writeReport(context.Background(), NewDirectorySink(res.Directory)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The report holds the same facts as printed
+	b, err := os.ReadFile(res.Report)
+	if err != nil {
+		t.Fatalf("reading report: %s", err)
+	}
+	report := Result{}
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatalf("invalid report: %s", err)
+	}
+	if len(report.Summary) == 0 || !reflect.DeepEqual(report.Summary, res.Summary) {
+		t.Errorf("expected the report summary %v, got %v", res.Summary, report.Summary)
+	}
+}
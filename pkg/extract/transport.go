@@ -0,0 +1,293 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// Transports of the extraction commands to the Pod.
+const (
+	// TransportAuto execs into the Pod, unless the exec subresource is
+	// forbidden in which case it falls back to TransportPortForward.
+	TransportAuto = "auto"
+	// TransportExec runs the commands through the exec subresource.
+	TransportExec = "exec"
+	// TransportPortForward runs the commands through a file server in the
+	// Pod, bound to its localhost and reached by port-forward, for clusters
+	// where exec is disabled.
+	TransportPortForward = "port-forward"
+)
+
+const (
+	// fileServerPort is the port the file server listens on, on the Pod
+	// localhost only.
+	fileServerPort = 8080
+
+	// fileServerRoot is where the file server scripts are written, on an
+	// emptyDir as the container filesystem may be read-only.
+	fileServerRoot = "/srv"
+)
+
+// serverEndpoint is a command the file server runs on request.
+type serverEndpoint struct {
+	name    string
+	command []string
+	// stream writes the output as the command produces it, rather than once
+	// it succeeded. Its exit status is then served apart, see statusFile.
+	stream bool
+}
+
+// statusFile is the file the exit status of the streamed endpoint is served
+// at, formatted as by exitStatus.
+func statusFile(endpoint string) string {
+	return endpoint + ".status"
+}
+
+// serverEndpoints returns the extraction commands the file server runs.
+func serverEndpoints(sourcePath string) []serverEndpoint {
+	return []serverEndpoint{
+		{name: "tar", command: tarCommand(sourcePath), stream: true},
+		{name: "du", command: duCommand(sourcePath)},
+		{name: "checksums", command: checksumCommand(sourcePath)},
+		{name: "stat", command: statCommand(sourcePath)},
+	}
+}
+
+// fileServerScript returns the script of the extractor container serving the
+// extraction commands as CGI scripts of a busybox httpd, plus the readiness
+// file.
+func fileServerScript(sourcePath string) string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "mkdir -p %[1]s/www/cgi-bin && : > %[1]s/www/ready\n", fileServerRoot)
+	for _, ep := range serverEndpoints(sourcePath) {
+		fmt.Fprintf(sb, "cat > %s/www/cgi-bin/%s <<'EOF'\n#!/bin/sh\n", fileServerRoot, ep.name)
+		if ep.stream {
+			// The status is written before the response ends, for the client
+			// to read it once the stream is complete
+			errFile := fmt.Sprintf("%s/%s.err", fileServerRoot, ep.name)
+			fmt.Fprintf(sb, "printf 'Content-Type: application/octet-stream\\r\\n\\r\\n'\n%s 2>%s\n", shellQuote(ep.command), errFile)
+			fmt.Fprintf(sb, "{ echo $?; cat %s; } > %s/www/%s\n", errFile, fileServerRoot, statusFile(ep.name))
+		} else {
+			errFile := fmt.Sprintf("%s/%s.err", fileServerRoot, ep.name)
+			fmt.Fprintf(sb, "if out=$(%s 2>%s); then\n", shellQuote(ep.command), errFile)
+			sb.WriteString("  printf 'Content-Type: text/plain\\r\\n\\r\\n%s\\n' \"$out\"\nelse\n")
+			fmt.Fprintf(sb, "  printf 'Status: 500 Internal Server Error\\r\\nContent-Type: text/plain\\r\\n\\r\\n'; cat %s\nfi\n", errFile)
+		}
+		sb.WriteString("EOF\n")
+	}
+	fmt.Fprintf(sb, "chmod +x %[1]s/www/cgi-bin/*\nexec httpd -f -p 127.0.0.1:%[2]d -h %[1]s/www\n", fileServerRoot, fileServerPort)
+	return sb.String()
+}
+
+// fileServerReadiness returns the probe of the file server, as the
+// container is started before it listens.
+func fileServerReadiness() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"wget", "-q", "-O", "/dev/null", fmt.Sprintf("http://127.0.0.1:%d/ready", fileServerPort)},
+			},
+		},
+		PeriodSeconds: 1,
+	}
+}
+
+// shellQuote quotes the command for sh to run it as is.
+func shellQuote(command []string) string {
+	quoted := make([]string, 0, len(command))
+	for _, arg := range command {
+		quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
+
+// selectTransport returns the transport to use, probing the exec one if
+// automatic: only a Forbidden error selects the port-forward, any other
+// is left for the exec itself to report.
+func selectTransport(ctx context.Context, transport string, probe func(context.Context) error) (string, error) {
+	if transport != TransportAuto && transport != "" {
+		return transport, nil
+	}
+	err := probe(ctx)
+	if apierrors.IsForbidden(err) {
+		return TransportPortForward, nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return "", ctxErr
+	}
+	return TransportExec, nil
+}
+
+// probeExec requests the exec subresource of the container without
+// upgrading the connection: the API server authorizes and admits it before
+// rejecting it as not upgraded, so nothing runs in the Pod, and a Forbidden
+// error tells exec is disabled.
+func probeExec(ctx context.Context, clientset kubernetes.Interface, namespace, podName, containerName string) error {
+	return clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(podName).
+		SubResource("exec").
+		Param("container", containerName).
+		Param("stdout", "true").
+		Param("command", "true").
+		Do(ctx).
+		Error()
+}
+
+// podTransport returns the selected transport to the container of the
+// extractor Pod, its executor and the function closing it.
+func podTransport(
+	ctx context.Context,
+	config *rest.Config,
+	clientset *kubernetes.Clientset,
+	namespace, podName, containerName string,
+	options *options,
+) (string, podExecutor, func(), error) {
+	transport, err := selectTransport(ctx, options.transport, func(ctx context.Context) error {
+		return probeExec(ctx, clientset, namespace, podName, containerName)
+	})
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if transport == TransportExec {
+		return transport, newPodExecutor(config, clientset, namespace, podName, containerName), func() {}, nil
+	}
+
+	if options.transport != TransportPortForward {
+		options.logger.Warn("exec is forbidden, falling back to port-forward",
+			zap.String("pod", podName),
+			zap.String("namespace", namespace),
+		)
+	}
+	localPort, stop, err := portForward(ctx, config, clientset, namespace, podName, fileServerPort)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	options.logger.Info("forwarding the file server port",
+		zap.String("pod", podName),
+		zap.Uint16("local_port", localPort),
+	)
+	return transport, httpExecutor(http.DefaultClient, fmt.Sprintf("http://127.0.0.1:%d", localPort), options.sourcePath), stop, nil
+}
+
+// portForward forwards a random local port to the one of the Pod, until the
+// returned function is called.
+func portForward(
+	ctx context.Context,
+	config *rest.Config,
+	clientset *kubernetes.Clientset,
+	namespace, podName string,
+	port int,
+) (uint16, func(), error) {
+	rt, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return 0, nil, err
+	}
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(podName).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: rt}, http.MethodPost, req.URL())
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		return 0, nil, err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fw.ForwardPorts()
+	}()
+	stop := func() {
+		close(stopCh)
+		<-errc
+	}
+
+	select {
+	case <-readyCh:
+	case err := <-errc:
+		return 0, nil, fmt.Errorf("port-forward to pod %s: %w", podName, err)
+	case <-ctx.Done():
+		stop()
+		return 0, nil, ctx.Err()
+	}
+	ports, err := fw.GetPorts()
+	if err != nil {
+		stop()
+		return 0, nil, err
+	}
+	return ports[0].Local, stop, nil
+}
+
+// httpExecutor returns an executor downloading the outputs of the extraction
+// commands from the file server at baseURL, e.g. port-forwarded.
+func httpExecutor(client *http.Client, baseURL, sourcePath string) podExecutor {
+	endpoints := serverEndpoints(sourcePath)
+	return func(ctx context.Context, command []string, stdout io.Writer) error {
+		i := slices.IndexFunc(endpoints, func(ep serverEndpoint) bool {
+			return slices.Equal(ep.command, command)
+		})
+		if i < 0 {
+			return fmt.Errorf("command not served over %s: %s", TransportPortForward, strings.Join(command, " "))
+		}
+		ep := endpoints[i]
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/cgi-bin/"+ep.name, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			return fmt.Errorf("%s endpoint: %s\nstderr: %s", ep.name, resp.Status, msg)
+		}
+		if _, err = io.Copy(stdout, resp.Body); err != nil || !ep.stream {
+			return err
+		}
+		return streamStatus(ctx, client, baseURL, ep)
+	}
+}
+
+// streamStatus returns the error of the streamed endpoint command, from the
+// exit status it served.
+func streamStatus(ctx context.Context, client *http.Client, baseURL string, ep serverEndpoint) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/"+statusFile(ep.name), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s endpoint status: %s", ep.name, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	return parseExitStatus(ep.command, b)
+}
@@ -0,0 +1,250 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func Test_U_SelectTransport(t *testing.T) {
+	t.Parallel()
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "pods/exec"}, "extractor", errors.New("exec is disabled"))
+
+	var tests = map[string]struct {
+		Transport         string
+		ProbeErr          error
+		ExpectedProbed    bool
+		ExpectedTransport string
+	}{
+		"auto-allowed": {
+			Transport:         TransportAuto,
+			ProbeErr:          apierrors.NewBadRequest("upgrade request required"),
+			ExpectedProbed:    true,
+			ExpectedTransport: TransportExec,
+		},
+		"auto-forbidden": {
+			Transport:         TransportAuto,
+			ProbeErr:          forbidden,
+			ExpectedProbed:    true,
+			ExpectedTransport: TransportPortForward,
+		},
+		"auto-other-error": {
+			Transport:         TransportAuto,
+			ProbeErr:          apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, "extractor"),
+			ExpectedProbed:    true,
+			ExpectedTransport: TransportExec,
+		},
+		"unset": {
+			ProbeErr:          forbidden,
+			ExpectedProbed:    true,
+			ExpectedTransport: TransportPortForward,
+		},
+		"forced-exec": {
+			Transport:         TransportExec,
+			ProbeErr:          forbidden,
+			ExpectedTransport: TransportExec,
+		},
+		"forced-port-forward": {
+			Transport:         TransportPortForward,
+			ExpectedTransport: TransportPortForward,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			probed := false
+			transport, err := selectTransport(context.Background(), tt.Transport, func(context.Context) error {
+				probed = true
+				return tt.ProbeErr
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if probed != tt.ExpectedProbed {
+				t.Errorf("expected probed: %t, got %t", tt.ExpectedProbed, probed)
+			}
+			if transport != tt.ExpectedTransport {
+				t.Errorf("expected transport %s, got %s", tt.ExpectedTransport, transport)
+			}
+		})
+	}
+}
+
+func Test_U_Options_Transport(t *testing.T) {
+	t.Parallel()
+
+	defaults := &options{}
+	if err := defaults.validate(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if defaults.transport != TransportAuto {
+		t.Errorf("expected transport to default to %s, got %s", TransportAuto, defaults.transport)
+	}
+
+	unsupported := &options{}
+	WithTransport("rsync").apply(unsupported)
+	if err := unsupported.validate(); err == nil {
+		t.Error("expected an unsupported transport to fail")
+	}
+}
+
+func Test_U_ExtractorPod_Transport(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Transport      string
+		ExpectedServer bool
+	}{
+		"auto": {
+			Transport:      TransportAuto,
+			ExpectedServer: true,
+		},
+		"exec": {
+			Transport: TransportExec,
+		},
+		"port-forward": {
+			Transport:      TransportPortForward,
+			ExpectedServer: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			options := &options{}
+			WithTransport(tt.Transport).apply(options)
+			WithSourcePath("/data/coldextract").apply(options)
+			if err := options.validate(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			pod := extractorPod("monitoring", "signals", options)
+			ctr := pod.Spec.Containers[0]
+			if !tt.ExpectedServer {
+				if ctr.Args[0] != "sleep infinity" || ctr.ReadinessProbe != nil || len(pod.Spec.Volumes) != 1 {
+					t.Errorf("expected the pod to only sleep, got args %q and %d volumes", ctr.Args, len(pod.Spec.Volumes))
+				}
+				return
+			}
+
+			// The server only runs the extraction commands, on the Pod localhost
+			script := ctr.Args[0]
+			for _, expected := range []string{
+				"httpd -f -p 127.0.0.1:8080",
+				"'tar' 'cf' '-' '-C' '/data/coldextract' '.' 2>/srv/tar.err",
+				"'du' '-sk' '/data/coldextract'",
+			} {
+				if !strings.Contains(script, expected) {
+					t.Errorf("expected the server script to contain %q, got:\n%s", expected, script)
+				}
+			}
+			if ctr.ReadinessProbe == nil {
+				t.Error("expected the server readiness to be probed")
+			}
+			if len(pod.Spec.Volumes) != 2 || pod.Spec.Volumes[1].EmptyDir == nil {
+				t.Errorf("expected the server scripts on an emptyDir, got volumes %+v", pod.Spec.Volumes)
+			}
+			if mp := ctr.VolumeMounts[0].MountPath; mp != "/data" {
+				t.Errorf("expected the PVC mounted first at /data, got %s", mp)
+			}
+		})
+	}
+}
+
+// fixtureServer serves the fixture outputs as the file server of the Pod
+// would.
+func fixtureServer(t *testing.T, dir, sourcePath string) *httptest.Server {
+	mux := http.NewServeMux()
+	for _, ep := range serverEndpoints(sourcePath) {
+		name, _ := fixtureOutput(ep.command, sourcePath)
+		mux.HandleFunc("/cgi-bin/"+ep.name, func(w http.ResponseWriter, _ *http.Request) {
+			f, err := os.Open(filepath.Join(dir, name))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer f.Close()
+			_, _ = io.Copy(w, f)
+		})
+	}
+	// The archive exit status, successful unless recorded otherwise
+	mux.HandleFunc("/"+statusFile("tar"), func(w http.ResponseWriter, _ *http.Request) {
+		b, err := os.ReadFile(filepath.Join(dir, fixtureArchiveStatus))
+		if os.IsNotExist(err) {
+			b = exitStatus(0, "")
+		}
+		_, _ = w.Write(b)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func Test_U_HTTPExecutor(t *testing.T) {
+	t.Parallel()
+
+	fx, err := LoadFixture(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := fixtureServer(t, fixtureDir, fx.SourcePath)
+
+	// The checksums and report are the same through both transports
+	dump := func(exec podExecutor) *Result {
+		options := &options{logger: zap.NewNop()}
+		WithVerifyRemote(true).apply(options)
+		WithAutoDeadline(true).apply(options)
+		WithSourcePath(fx.SourcePath).apply(options)
+		if err := options.validate(); err != nil {
+			t.Fatal(err)
+		}
+		res := &Result{Directory: t.TempDir()}
+		options.sink = NewDirectorySink(res.Directory)
+		if err := dumpFromPod(context.Background(), exec, res, options); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return res
+	}
+	replayed := dump(replayExecutor(fixtureDir, fx.SourcePath))
+	served := dump(httpExecutor(srv.Client(), srv.URL, fx.SourcePath))
+
+	if served.Files != replayed.Files || served.Bytes != replayed.Bytes {
+		t.Errorf("expected %d files of %d bytes, got %d of %d", replayed.Files, replayed.Bytes, served.Files, served.Bytes)
+	}
+	if !reflect.DeepEqual(served.Verify, replayed.Verify) {
+		t.Errorf("expected verification %+v, got %+v", replayed.Verify, served.Verify)
+	}
+	if len(served.Verify.Matching) != len(fixtureFiles) {
+		t.Errorf("expected %d matching files, got %+v", len(fixtureFiles), served.Verify)
+	}
+
+	// Other commands are not served
+	exec := httpExecutor(srv.Client(), srv.URL, fx.SourcePath)
+	if err := exec(context.Background(), []string{"rm", "-rf", "/data"}, io.Discard); err == nil {
+		t.Error("expected an unserved command to fail")
+	}
+	// A failing command is reported
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "du: /data: Permission denied", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	exec = httpExecutor(failing.Client(), failing.URL, fx.SourcePath)
+	if err := exec(context.Background(), duCommand(fx.SourcePath), io.Discard); err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("expected the failing command error, got %v", err)
+	}
+}
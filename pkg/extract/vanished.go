@@ -0,0 +1,99 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// exitError is the non-zero exit of a command run in the Pod, along its
+// standard error.
+type exitError struct {
+	command []string
+	code    int
+	stderr  string
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("%s exited with code %d\nstderr: %s", e.command[0], e.code, e.stderr)
+}
+
+// exitStatus formats the exit code of the command on the first line, then
+// its standard error, as the file server and the fixtures record it.
+func exitStatus(code int, stderr string) []byte {
+	return []byte(strconv.Itoa(code) + "\n" + stderr)
+}
+
+// parseExitStatus returns the exitError of the command from its recorded
+// exit status, or nil if it succeeded.
+func parseExitStatus(command []string, b []byte) error {
+	line, stderr, _ := bytes.Cut(b, []byte("\n"))
+	code, err := strconv.Atoi(strings.TrimSpace(string(line)))
+	if err != nil {
+		return fmt.Errorf("invalid exit status of %s: %w", command[0], err)
+	}
+	if code == 0 {
+		return nil
+	}
+	return &exitError{command: command, code: code, stderr: string(stderr)}
+}
+
+// vanishedFileErrors match the tar errors of a file removed between the
+// listing of its directory and its reading, e.g. by a log rotation, by
+// busybox and GNU tar. Both keep archiving the others, and the stream
+// remains valid.
+var vanishedFileErrors = []*regexp.Regexp{
+	regexp.MustCompile(`^tar: can't open '(.+)': No such file or directory$`),
+	regexp.MustCompile(`^tar: (.+): Warning: Cannot stat: No such file or directory$`),
+	regexp.MustCompile(`^tar: (.+): Cannot (?:stat|open): No such file or directory$`),
+	regexp.MustCompile(`^tar: (.+): File removed before we read it$`),
+	regexp.MustCompile(`^tar: (.+): No such file or directory$`),
+}
+
+// tarExitTrailer is the last line GNU tar prints once any error occurred.
+const tarExitTrailer = "tar: Exiting with failure status due to previous errors"
+
+// vanishedFiles returns the paths of the files which vanished during the
+// archive, if the error is a tar exit due to them only. Any other tar error
+// is not one to recover from.
+func vanishedFiles(err error) ([]string, bool) {
+	var ee *exitError
+	if !errors.As(err, &ee) || ee.command[0] != "tar" {
+		return nil, false
+	}
+	vanished := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(ee.stderr), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == tarExitTrailer {
+			continue
+		}
+		p, ok := vanishedFile(line)
+		if !ok {
+			return nil, false
+		}
+		vanished = append(vanished, p)
+	}
+	return vanished, len(vanished) != 0
+}
+
+// vanishedFile returns the path of the file the tar error line reports
+// vanished, relative to the archive root.
+func vanishedFile(line string) (string, bool) {
+	for _, re := range vanishedFileErrors {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return path.Clean(m[1]), true
+		}
+	}
+	return "", false
+}
+
+// noteVanished warns about the files which vanished during the archive.
+func (res *Result) noteVanished(vanished []string) {
+	for _, p := range vanished {
+		res.Warnings = append(res.Warnings, "file vanished during the archive: "+p)
+	}
+}
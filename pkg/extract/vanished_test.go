@@ -0,0 +1,210 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_U_VanishedFiles(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Err      error
+		Expected []string
+	}{
+		"busybox": {
+			Err: &exitError{
+				command: tarCommand("/data"),
+				code:    1,
+				stderr:  "tar: ./collector/otel_logs-2026-10-16T00-00-00.000.gz: No such file or directory\ntar: can't open './collector/rotated': No such file or directory\n",
+			},
+			Expected: []string{"collector/otel_logs-2026-10-16T00-00-00.000.gz", "collector/rotated"},
+		},
+		"gnu": {
+			Err: &exitError{
+				command: tarCommand("/data"),
+				code:    1,
+				stderr:  "tar: ./collector/otel_traces.1: File removed before we read it\ntar: ./collector/otel_traces.2: Warning: Cannot stat: No such file or directory\ntar: Exiting with failure status due to previous errors\n",
+			},
+			Expected: []string{"collector/otel_traces.1", "collector/otel_traces.2"},
+		},
+		"other-error": {
+			Err: &exitError{
+				command: tarCommand("/data"),
+				code:    1,
+				stderr:  "tar: ./collector/otel_traces: Permission denied\n",
+			},
+		},
+		"mixed": {
+			Err: &exitError{
+				command: tarCommand("/data"),
+				code:    1,
+				stderr:  "tar: ./collector/otel_traces.1: No such file or directory\ntar: ./collector/otel_traces: Permission denied\n",
+			},
+		},
+		"no-stderr": {
+			Err: &exitError{
+				command: tarCommand("/data"),
+				code:    1,
+			},
+		},
+		"not-tar": {
+			Err: &exitError{
+				command: duCommand("/data"),
+				code:    1,
+				stderr:  "du: ./collector/otel_traces.1: No such file or directory\n",
+			},
+		},
+		"stream-error": {
+			Err: errors.New("stream error: connection reset by peer"),
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			vanished, ok := vanishedFiles(tt.Err)
+			if ok != (tt.Expected != nil) {
+				t.Fatalf("expected recoverable: %t, got %t", tt.Expected != nil, ok)
+			}
+			if !slices.Equal(vanished, tt.Expected) {
+				t.Errorf("expected vanished files %v, got %v", tt.Expected, vanished)
+			}
+		})
+	}
+}
+
+func Test_U_Replay_Vanished(t *testing.T) {
+	t.Parallel()
+
+	const vanished = "tar: ./collector/otel_logs-2026-10-16T00-00-00.000.gz: No such file or directory\n"
+
+	var tests = map[string]struct {
+		Stderr           string
+		Strict           bool
+		ExpectErr        bool
+		ExpectedWarnings []string
+	}{
+		"vanished": {
+			Stderr:           vanished,
+			ExpectedWarnings: []string{"file vanished during the archive: collector/otel_logs-2026-10-16T00-00-00.000.gz"},
+		},
+		"vanished-strict": {
+			Stderr:    vanished,
+			Strict:    true,
+			ExpectErr: true,
+		},
+		"other-error": {
+			Stderr:    "tar: ./collector/otel_traces: Permission denied\n",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			// Inject the tar warnings into the recorded stream
+			fixture := t.TempDir()
+			for _, f := range []string{FixtureFile, fixtureArchive, fixtureDiskUsage, fixtureChecksums} {
+				copyFile(t, filepath.Join(fixtureDir, f), filepath.Join(fixture, f))
+			}
+			if err := os.WriteFile(filepath.Join(fixture, fixtureArchiveStatus), exitStatus(1, tt.Stderr), 0600); err != nil {
+				t.Fatal(err)
+			}
+			fx, err := LoadFixture(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := fixtureServer(t, fixture, fx.SourcePath)
+
+			// Both the replayed and the served streams are classified alike
+			for name, exec := range map[string]podExecutor{
+				"replay": replayExecutor(fixture, fx.SourcePath),
+				"http":   httpExecutor(srv.Client(), srv.URL, fx.SourcePath),
+			} {
+				options := &options{logger: zap.NewNop()}
+				WithStrict(tt.Strict).apply(options)
+				WithSourcePath(fx.SourcePath).apply(options)
+				if err := options.validate(); err != nil {
+					t.Fatal(err)
+				}
+				res := &Result{Directory: t.TempDir()}
+				options.sink = NewDirectorySink(res.Directory)
+				err := dumpFromPod(context.Background(), exec, res, options)
+				if (err != nil) != tt.ExpectErr {
+					t.Fatalf("%s: expected error: %t, got: %v", name, tt.ExpectErr, err)
+				}
+				if err != nil {
+					if ee := (*exitError)(nil); !errors.As(err, &ee) || ee.stderr != tt.Stderr {
+						t.Errorf("%s: expected the tar exit error, got %v", name, err)
+					}
+					continue
+				}
+				if res.Files != len(fixtureFiles) {
+					t.Errorf("%s: expected the %d other files to be extracted, got %d", name, len(fixtureFiles), res.Files)
+				}
+				if !slices.Equal(res.Warnings, tt.ExpectedWarnings) {
+					t.Errorf("%s: expected warnings %v, got %v", name, tt.ExpectedWarnings, res.Warnings)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Record_Vanished(t *testing.T) {
+	t.Parallel()
+
+	// The pod archive fails on a vanished file
+	const stderr = "tar: ./collector/otel_traces.1: No such file or directory\n"
+	pod := func(ctx context.Context, command []string, stdout io.Writer) error {
+		if err := replayExecutor(fixtureDir, "/data")(ctx, command, stdout); err != nil {
+			return err
+		}
+		if slices.Equal(command, tarCommand("/data")) {
+			return &exitError{command: command, code: 1, stderr: stderr}
+		}
+		return nil
+	}
+
+	options := &options{
+		logger: zap.NewNop(),
+		record: t.TempDir(),
+	}
+	if err := options.validate(); err != nil {
+		t.Fatal(err)
+	}
+	res := &Result{Directory: t.TempDir()}
+	options.sink = NewDirectorySink(res.Directory)
+	exec, err := recordExecutor(pod, res, "extractor", "copy", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dumpFromPod(context.Background(), exec, res, options); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The exit status is recorded, and replayed
+	b, err := os.ReadFile(filepath.Join(options.record, fixtureArchiveStatus))
+	if err != nil {
+		t.Fatalf("expected the archive status to be recorded: %s", err)
+	}
+	if string(b) != string(exitStatus(1, stderr)) {
+		t.Errorf("unexpected archive status %q", b)
+	}
+	replayed, err := ReplayOTelCollector(context.Background(), options.record, t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(replayed.Warnings, res.Warnings) || len(res.Warnings) != 1 {
+		t.Errorf("expected the replay to warn as the recording, got %v and %v", replayed.Warnings, res.Warnings)
+	}
+}
@@ -0,0 +1,323 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// VerifyReport is the per-file result of the comparison between the
+// extracted files and the ones remaining on the PVC.
+type VerifyReport struct {
+	// Matching files have the same content locally and on the PVC.
+	Matching []string `json:"matching"`
+	// Mismatched files have a different content locally and on the PVC.
+	Mismatched []string `json:"mismatched"`
+	// MissingRemote files were extracted but are no longer on the PVC,
+	// e.g. pruned or rotated between the copy and the verification.
+	MissingRemote []string `json:"missing_remote"`
+	// MissingLocal files are on the PVC but were not extracted, e.g.
+	// created between the copy and the verification.
+	MissingLocal []string `json:"missing_local"`
+}
+
+// Failed returns whether the extracted files differ from the PVC ones.
+// Files missing on either side are not considered a failure as they
+// could be legitimately rotated.
+func (rep VerifyReport) Failed() bool {
+	return len(rep.Mismatched) != 0
+}
+
+// CompareChecksums compares the local and remote SHA256 checksums, indexed
+// by the files path relative to the extraction root.
+func CompareChecksums(local, remote map[string]string) VerifyReport {
+	rep := VerifyReport{}
+	for p, lsum := range local {
+		rsum, ok := remote[p]
+		switch {
+		case !ok:
+			rep.MissingRemote = append(rep.MissingRemote, p)
+		case rsum != lsum:
+			rep.Mismatched = append(rep.Mismatched, p)
+		default:
+			rep.Matching = append(rep.Matching, p)
+		}
+	}
+	for p := range remote {
+		if _, ok := local[p]; !ok {
+			rep.MissingLocal = append(rep.MissingLocal, p)
+		}
+	}
+	slices.Sort(rep.Matching)
+	slices.Sort(rep.Mismatched)
+	slices.Sort(rep.MissingRemote)
+	slices.Sort(rep.MissingLocal)
+	return rep
+}
+
+// CompareExtraction compares the files of a previous extraction with the
+// ones remaining on the PVC. Only the extracted files are classified: the
+// ones created on the PVC since are not part of the extraction, hence never
+// reported as MissingLocal.
+func CompareExtraction(local, remote map[string]string) VerifyReport {
+	rep := CompareChecksums(local, remote)
+	rep.MissingLocal = nil
+	return rep
+}
+
+// VerifyDirectory compares the files of a previous extraction in dir with
+// the ones remaining on the PVC, given its namespace and name, by computing
+// their SHA256 checksums in an extraction Pod. The mount and source paths
+// options must match the ones of the extraction.
+// It returns an error if any file mismatches, not when missing on the PVC
+// as it could have been rotated away since.
+func VerifyDirectory(
+	ctx context.Context,
+	namespace, pvcName, dir string,
+	opts ...Option,
+) (*VerifyReport, error) {
+	// Prepare functional options
+	options := &options{
+		logger: zap.NewNop(),
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+	if options.record != "" {
+		return nil, errors.New("could not record a verification")
+	}
+
+	local, err := extractedChecksums(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare K8s client
+	clientset, config, err := getClient(options.kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create Pod and mount PVC
+	options.logger.Info("creating Pod",
+		zap.String("pod", podName),
+		zap.String("namespace", namespace),
+		zap.String("pvc", pvcName),
+		zap.Duration("gc_after", options.gcAfter),
+	)
+	pod, err := createExtractor(ctx, clientset, namespace, pvcName, options)
+	if err != nil {
+		return nil, err
+	}
+
+	options.logger.Info("waiting for the pod to be ready",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+	)
+	rep, err := func() (*VerifyReport, error) {
+		if err := waitForPodReady(ctx, clientset, namespace, pod); err != nil {
+			return nil, err
+		}
+		options.logger.Info("verifying files against the PVC",
+			zap.String("directory", dir),
+		)
+		_, exec, closeTransport, err := podTransport(ctx, config, clientset, namespace, pod, "copy", options)
+		if err != nil {
+			return nil, err
+		}
+		defer closeTransport()
+		return verifyExtraction(ctx, exec, options.sourcePath, local, options.logger)
+	}()
+
+	// Delete Pod, whether verified or not
+	options.logger.Info("deleting pod",
+		zap.String("pod", pod),
+		zap.String("namespace", namespace),
+	)
+	if derr := deleteExtractor(ctx, clientset, namespace, pod, options); derr != nil {
+		if err != nil || !errors.Is(derr, ErrPodLingering) {
+			return nil, errors.Join(err, derr)
+		}
+		options.logger.Warn("pod lingering after deletion",
+			zap.Error(derr),
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rep.Failed() {
+		return rep, fmt.Errorf("%d extracted files mismatch the PVC ones: %s",
+			len(rep.Mismatched), strings.Join(rep.Mismatched, ", "))
+	}
+	return rep, nil
+}
+
+// verifyExtraction compares the local checksums with the ones of the files
+// under podPath computed through the executor.
+func verifyExtraction(
+	ctx context.Context,
+	exec podExecutor,
+	podPath string,
+	local map[string]string,
+	logger *zap.Logger,
+) (*VerifyReport, error) {
+	remote, err := remoteChecksums(ctx, exec, podPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := CompareExtraction(local, remote)
+	for _, p := range rep.MissingRemote {
+		logger.Warn("file no longer on the PVC", zap.String("file", p))
+	}
+	for _, p := range rep.Mismatched {
+		logger.Error("file checksum mismatch", zap.String("file", p))
+	}
+	logger.Info("verification done",
+		zap.Int("matching", len(rep.Matching)),
+		zap.Int("mismatched", len(rep.Mismatched)),
+		zap.Int("missing_remote", len(rep.MissingRemote)),
+	)
+	return &rep, nil
+}
+
+// extractedChecksums computes the SHA256 checksums of the files extracted in
+// dir, as listed by the inventory of its report. Without one, every file of
+// dir is considered. The files landed decompressed, as listed by the report,
+// are skipped as they no longer match the PVC ones.
+func extractedChecksums(dir string) (map[string]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	res, err := ReadReport(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return localChecksums(dir)
+		}
+		return nil, err
+	}
+	var local map[string]string
+	if len(res.Inventory) != 0 {
+		local, err = inventoryChecksums(dir, res.Inventory)
+	} else {
+		local, err = localChecksums(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range res.Decompressed {
+		delete(local, filepath.Clean(f.Path))
+	}
+	return local, nil
+}
+
+// remoteChecksums computes the SHA256 checksums of the files under podPath,
+// inside the pod.
+func remoteChecksums(ctx context.Context, exec podExecutor, podPath string) (map[string]string, error) {
+	var stdout bytes.Buffer
+	if err := exec(ctx, checksumCommand(podPath), &stdout); err != nil {
+		return nil, err
+	}
+	return parseChecksums(&stdout)
+}
+
+// checksumCommand returns the command printing the SHA256 checksums of the
+// files under podPath, as sha256sum does.
+func checksumCommand(podPath string) []string {
+	return []string{"sh", "-c", fmt.Sprintf("cd %q && find . -type f -exec sha256sum {} +", podPath)}
+}
+
+// parseChecksums parses the output of sha256sum, i.e. lines formatted as
+// "<sum>  <path>", into checksums indexed by cleaned path.
+func parseChecksums(r io.Reader) (map[string]string, error) {
+	sums := map[string]string{}
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			continue
+		}
+		sum, p, ok := strings.Cut(line, "  ")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line: %q", line)
+		}
+		sums[filepath.Clean(p)] = sum
+	}
+	return sums, sc.Err()
+}
+
+// localChecksums computes the SHA256 checksums of the files under dir,
+// indexed by their path relative to it. The report file and the partial
+// ones are skipped.
+func localChecksums(dir string) (map[string]string, error) {
+	sums := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == ReportFile || strings.HasSuffix(rel, PartialSuffix) {
+			return nil
+		}
+		sum, err := fileChecksum(p)
+		if err != nil {
+			return err
+		}
+		sums[rel] = sum
+		return nil
+	})
+	return sums, err
+}
+
+// inventoryChecksums computes the SHA256 checksums of the inventory files
+// under dir, indexed by their path relative to it, ignoring the other files
+// of dir, e.g. those of previous extractions.
+func inventoryChecksums(dir string, inventory []InventoryFile) (map[string]string, error) {
+	sums := make(map[string]string, len(inventory))
+	for _, f := range inventory {
+		rel := filepath.FromSlash(f.Path)
+		sum, err := fileChecksum(filepath.Join(dir, rel))
+		if err != nil {
+			return nil, err
+		}
+		sums[rel] = sum
+	}
+	return sums, nil
+}
+
+func fileChecksum(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
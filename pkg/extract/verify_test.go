@@ -0,0 +1,252 @@
+package extract
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func Test_U_CompareChecksums(t *testing.T) {
+	t.Parallel()
+
+	local := map[string]string{
+		"otel_traces":  "aaaa",
+		"otel_metrics": "bbbb",
+		"otel_logs":    "cccc",
+	}
+	remote := map[string]string{
+		"otel_traces":  "aaaa",
+		"otel_metrics": "ffff",
+		"otel_logs.1":  "dddd",
+	}
+
+	rep := CompareChecksums(local, remote)
+	expected := VerifyReport{
+		Matching:      []string{"otel_traces"},
+		Mismatched:    []string{"otel_metrics"},
+		MissingRemote: []string{"otel_logs"},
+		MissingLocal:  []string{"otel_logs.1"},
+	}
+	if !reflect.DeepEqual(rep, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rep)
+	}
+	if !rep.Failed() {
+		t.Error("expected the report to fail")
+	}
+
+	// Rotated files only should not fail
+	delete(local, "otel_metrics")
+	delete(remote, "otel_metrics")
+	if rep := CompareChecksums(local, remote); rep.Failed() {
+		t.Errorf("expected the report not to fail, got %+v", rep)
+	}
+}
+
+func Test_U_Checksums(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "collector"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "collector", "otel_traces"), []byte("traces\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := localChecksums(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Output of sha256sum run through find in the Pod
+	remote, err := parseChecksums(strings.NewReader(
+		"0e6d3ec2fa1fc1bc0a0cd4a1a1e3de1fd8e6a3a0cc1e1bd4ed5e4bd7a9d8a0c1  ./collector/otel_traces\n",
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := remote["collector/otel_traces"]; !ok {
+		t.Fatalf("expected the remote path to be cleaned, got %v", remote)
+	}
+	if _, ok := local["collector/otel_traces"]; !ok {
+		t.Fatalf("expected the local path to be relative, got %v", local)
+	}
+
+	if _, err := parseChecksums(strings.NewReader("invalid\n")); err == nil {
+		t.Error("expected an error on invalid line")
+	}
+}
+
+func Test_U_CompareExtraction(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Local, Remote map[string]string
+		Expected      VerifyReport
+		ExpectFailed  bool
+	}{
+		"matching": {
+			Local:  map[string]string{"otel_traces": "aaaa"},
+			Remote: map[string]string{"otel_traces": "aaaa"},
+			Expected: VerifyReport{
+				Matching: []string{"otel_traces"},
+			},
+		},
+		"rotated-away": {
+			Local:  map[string]string{"otel_traces": "aaaa", "otel_logs": "cccc"},
+			Remote: map[string]string{"otel_traces": "aaaa"},
+			Expected: VerifyReport{
+				Matching:      []string{"otel_traces"},
+				MissingRemote: []string{"otel_logs"},
+			},
+		},
+		"created-since": {
+			Local:  map[string]string{"otel_traces": "aaaa"},
+			Remote: map[string]string{"otel_traces": "aaaa", "otel_traces.1": "dddd"},
+			Expected: VerifyReport{
+				Matching: []string{"otel_traces"},
+			},
+		},
+		"mismatched": {
+			Local:  map[string]string{"otel_traces": "aaaa", "otel_metrics": "bbbb"},
+			Remote: map[string]string{"otel_traces": "aaaa", "otel_metrics": "ffff"},
+			Expected: VerifyReport{
+				Matching:   []string{"otel_traces"},
+				Mismatched: []string{"otel_metrics"},
+			},
+			ExpectFailed: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			rep := CompareExtraction(tt.Local, tt.Remote)
+			if !reflect.DeepEqual(rep, tt.Expected) {
+				t.Errorf("expected %+v, got %+v", tt.Expected, rep)
+			}
+			if rep.Failed() != tt.ExpectFailed {
+				t.Errorf("expected failed %t, got %t", tt.ExpectFailed, rep.Failed())
+			}
+		})
+	}
+}
+
+func Test_U_VerifyExtraction(t *testing.T) {
+	t.Parallel()
+
+	// A previous extraction, with a file landed decompressed and a partial one
+	dir := t.TempDir()
+	files := map[string]string{
+		"collector/otel_traces":                "traces\n",
+		"collector/otel_metrics":               "metrics\n",
+		"collector/otel_logs":                  "logs\n",
+		"collector/otel_logs.1":                "decompressed\n",
+		"collector/otel_spans" + PartialSuffix: "partial",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := json.Marshal(&Result{
+		Extractor: ReportMarker,
+		Decompressed: []DecompressedFile{
+			{Path: "collector/otel_logs.1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ReportFile), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The PVC rotated the logs away, and the metrics changed since
+	sum := func(content string) string {
+		h := sha256.Sum256([]byte(content))
+		return hex.EncodeToString(h[:])
+	}
+	exec := func(_ context.Context, command []string, stdout io.Writer) error {
+		if !slices.Equal(command, checksumCommand("/data")) {
+			t.Errorf("unexpected command %v", command)
+		}
+		_, err := fmt.Fprintf(stdout, "%s  ./collector/otel_traces\n%s  ./collector/otel_metrics\n%s  ./collector/otel_logs.1.gz\n",
+			sum("traces\n"), sum("tampered\n"), sum("compressed"))
+		return err
+	}
+
+	local, err := extractedChecksums(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rep, err := verifyExtraction(context.Background(), exec, "/data", local, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := &VerifyReport{
+		Matching:      []string{"collector/otel_traces"},
+		Mismatched:    []string{"collector/otel_metrics"},
+		MissingRemote: []string{"collector/otel_logs"},
+	}
+	if !reflect.DeepEqual(rep, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rep)
+	}
+}
+
+func Test_U_ExtractedChecksums_Inventory(t *testing.T) {
+	t.Parallel()
+
+	// The destination holds the files of an older extraction too
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"collector/otel_traces":     "traces\n",
+		"older/collector/otel_logs": "older\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := json.Marshal(&Result{
+		Extractor: ReportMarker,
+		Inventory: []InventoryFile{
+			{Path: "collector/otel_traces"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ReportFile), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	local, err := extractedChecksums(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	h := sha256.Sum256([]byte("traces\n"))
+	expected := map[string]string{
+		filepath.Join("collector", "otel_traces"): hex.EncodeToString(h[:]),
+	}
+	if !reflect.DeepEqual(local, expected) {
+		t.Errorf("expected only the inventory files %v, got %v", expected, local)
+	}
+}
@@ -0,0 +1,63 @@
+// Package rollout resumes the Monitoring Deployments paused during a live
+// event (see the pause-rollouts configuration), for the changes recorded
+// since to roll out, e.g. in a maintenance window.
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/internal/kubeclient"
+)
+
+// monitoringSelector matches the resources deployed by the Monitoring.
+var monitoringSelector = labels.Set{
+	"app.kubernetes.io/part-of": "monitoring",
+}
+
+// resumePatch unpauses a Deployment, as kubectl rollout resume does.
+var resumePatch = []byte(`{"spec":{"paused":false}}`)
+
+// Resume unpauses the paused Deployments of the Monitoring in the namespace,
+// and returns their names, sorted. It is the equivalent of
+//
+//	kubectl rollout resume deployment -n <namespace> -l app.kubernetes.io/part-of=monitoring
+//
+// The cluster is reached through the context of the kubeconfig, its current
+// one if empty. The next update pauses them again unless pause-rollouts is
+// turned off.
+func Resume(ctx context.Context, kubeContext, namespace string) ([]string, error) {
+	client, err := kubeclient.New(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return resume(ctx, client, namespace)
+}
+
+func resume(ctx context.Context, client kubernetes.Interface, namespace string) ([]string, error) {
+	deps, err := client.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: monitoringSelector.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resumed := []string{}
+	for _, dep := range deps.Items {
+		if !dep.Spec.Paused {
+			continue
+		}
+		if _, err := client.AppsV1().Deployments(namespace).Patch(ctx, dep.Name, types.MergePatchType, resumePatch, metav1.PatchOptions{}); err != nil {
+			return resumed, fmt.Errorf("resuming deployment %s: %w", dep.Name, err)
+		}
+		resumed = append(resumed, dep.Name)
+	}
+	slices.Sort(resumed)
+	return resumed, nil
+}
@@ -0,0 +1,68 @@
+package rollout
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func deployment(name string, labels map[string]string, paused bool) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "monitoring",
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Paused: paused,
+		},
+	}
+}
+
+func Test_U_Resume(t *testing.T) {
+	t.Parallel()
+
+	monitoring := map[string]string{"app.kubernetes.io/part-of": "monitoring"}
+	client := fake.NewClientset(
+		deployment("prometheus", monitoring, true),
+		deployment("otel", monitoring, true),
+		deployment("jaeger", monitoring, false),
+		deployment("other", nil, true),
+	)
+
+	resumed, err := resume(context.Background(), client, "monitoring")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !slices.Equal(resumed, []string{"otel", "prometheus"}) {
+		t.Errorf("expected otel and prometheus to be resumed, got %v", resumed)
+	}
+
+	for name, paused := range map[string]bool{
+		"prometheus": false,
+		"otel":       false,
+		"jaeger":     false,
+		"other":      true,
+	} {
+		dep, err := client.AppsV1().Deployments("monitoring").Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if dep.Spec.Paused != paused {
+			t.Errorf("expected deployment %s paused: %t, got %t", name, paused, dep.Spec.Paused)
+		}
+	}
+
+	// Nothing left to resume
+	resumed, err = resume(context.Background(), client, "monitoring")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resumed) != 0 {
+		t.Errorf("expected nothing to resume, got %v", resumed)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.uber.org/multierr"
+)
+
+// compatibilityRule is a combination of arguments known to conflict, which
+// would otherwise only fail at runtime.
+type compatibilityRule struct {
+	// Name identifies the rule, each one being covered by the tests.
+	Name string
+
+	// Conflicts tells whether the defaulted arguments combine into the
+	// conflict.
+	Conflicts func(args *MonitoringArgs) bool
+
+	// Message explains the conflict, then how to fix it.
+	Message string
+}
+
+// compatibilityRules is the matrix of the arguments conflicting with each
+// other. A new argument registers here the combinations it does not support,
+// rather than checking them on its own.
+var compatibilityRules = []compatibilityRule{
+	{
+		Name: "prometheus-agent-mode-jaeger-spm",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.PrometheusAgentMode && !args.DisableJaegerSPM
+		},
+		Message: "prometheus agent mode does not support querying, which is required by Jaeger SPM: disable it",
+	},
+	{
+		Name: "prometheus-remote-write-basic-auth-jaeger-spm",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.PrometheusRemoteWriteBasicAuth && !args.DisableJaegerSPM
+		},
+		Message: "prometheus remote write basic auth applies to the queries too, which Jaeger SPM could not authenticate: disable it",
+	},
+	{
+		Name: "prometheus-remote-write-basic-auth-secret",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.PrometheusRemoteWriteBasicAuthSecret != nil && !args.PrometheusRemoteWriteBasicAuth
+		},
+		Message: "prometheus remote write basic auth secret requires the remote write basic auth",
+	},
+	{
+		Name: "prometheus-agent-mode-otlp-ingestion",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.PrometheusAgentMode && args.PrometheusOTLPIngestion
+		},
+		Message: "prometheus agent mode could not receive metrics, it scrapes those of the OTEL Collector: disable the prometheus OTLP ingestion",
+	},
+	{
+		Name: "prometheus-agent-mode-remote-write-basic-auth",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.PrometheusAgentMode && args.PrometheusRemoteWriteBasicAuth
+		},
+		Message: "prometheus agent mode has no receiver to authenticate, it scrapes the metrics of the OTEL Collector: disable the remote write basic auth",
+	},
+	{
+		Name: "otel-remote-write-wal-prometheus-agent-mode",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.OTELRemoteWriteWAL != nil && args.PrometheusAgentMode
+		},
+		Message: "otel remote write WAL buffers the prometheusremotewrite exporter, which the prometheus agent mode replaces by scraping: disable either",
+	},
+	{
+		Name: "log-shipper-otel-receiver-tls",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.LogShipper != nil && args.OTELReceiverTLS != nil
+		},
+		Message: "log shipper does not support the OTEL receiver TLS",
+	},
+	{
+		Name: "log-shipper-openshift",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.LogShipper != nil && args.OpenShift != nil
+		},
+		Message: "log shipper mounts a host path, which is not supported on OpenShift",
+	},
+	{
+		Name: "cold-extract-read-write-once-otel-replicas",
+		Conflicts: func(args *MonitoringArgs) bool {
+			// ReadWriteOncePod too, even more restrictive
+			modes, _ := literalAccessModes(args.PVCAccessModes)
+			return args.ColdExtract && args.OTELReplicas > 1 && slices.ContainsFunc(modes, func(mode string) bool {
+				return strings.HasPrefix(mode, "ReadWriteOnce")
+			})
+		},
+		Message: "cold extract on a ReadWriteOnce PVC could only be mounted by the OTEL Collector pods of a single node, the other replicas never starting: use ReadWriteMany or a single OTEL replica",
+	},
+	{
+		Name: "otel-ingestion-quotas-resources",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.OTELIngestionQuotas != nil && args.OTELResources == nil
+		},
+		Message: "otel ingestion quotas are shares of the OTEL Collector memory limit, which is not set: set the OTEL resources or a preset",
+	},
+	{
+		Name: "otel-remote-write-wal-prometheus-otlp-ingestion",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.OTELRemoteWriteWAL != nil && args.PrometheusOTLPIngestion
+		},
+		Message: "otel remote write WAL buffers the prometheusremotewrite exporter, which the prometheus OTLP ingestion replaces: disable either",
+	},
+	{
+		Name: "cold-extract-tenant-routing",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.ColdExtractTenantRouting != nil && !args.ColdExtract
+		},
+		Message: "cold extract tenant routing routes the cold extract signals, which are not written: enable the cold extract",
+	},
+	{
+		Name: "traces-failover-cold-extract",
+		Conflicts: func(args *MonitoringArgs) bool {
+			return args.TracesFailover && !args.ColdExtract
+		},
+		Message: "traces failover spills the traces on the cold extract PVC, which is not provisioned: enable the cold extract",
+	},
+}
+
+// checkCompatibility rejects the combinations of arguments of the
+// compatibility matrix, each with its own message.
+func checkCompatibility(args *MonitoringArgs) (merr error) {
+	for _, rule := range compatibilityRules {
+		if rule.Conflicts(args) {
+			merr = multierr.Append(merr, errors.New(rule.Message))
+		}
+	}
+	return
+}
+
+// literalAccessModes returns the access modes, if literal. Access modes
+// given as outputs are only known once resolved, so are not checked.
+func literalAccessModes(modes pulumi.StringArrayInput) ([]string, bool) {
+	arr, ok := modes.(pulumi.StringArray)
+	if !ok {
+		return nil, false
+	}
+	out := make([]string, 0, len(arr))
+	for _, mode := range arr {
+		str, ok := mode.(pulumi.String)
+		if !ok {
+			return nil, false
+		}
+		out = append(out, string(str))
+	}
+	return out, true
+}
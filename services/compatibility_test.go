@@ -0,0 +1,241 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.uber.org/multierr"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+var compatibilityTests = map[string]struct {
+	Args *MonitoringArgs
+	// ExpectedRules are the names of the rules the arguments conflict with
+	ExpectedRules []string
+}{
+	"defaults": {
+		Args: &MonitoringArgs{},
+	},
+	"prometheus-agent-mode-jaeger-spm": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode: true,
+		},
+		ExpectedRules: []string{"prometheus-agent-mode-jaeger-spm"},
+	},
+	"prometheus-agent-mode-without-spm": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode: true,
+			DisableJaegerSPM:    true,
+		},
+	},
+	"prometheus-remote-write-basic-auth-jaeger-spm": {
+		Args: &MonitoringArgs{
+			PrometheusRemoteWriteBasicAuth: true,
+		},
+		ExpectedRules: []string{"prometheus-remote-write-basic-auth-jaeger-spm"},
+	},
+	"prometheus-remote-write-basic-auth-secret": {
+		Args: &MonitoringArgs{
+			PrometheusRemoteWriteBasicAuthSecret: &parts.ExistingSecretArgs{
+				Name: pulumi.String("prometheus-credentials"),
+			},
+		},
+		ExpectedRules: []string{"prometheus-remote-write-basic-auth-secret"},
+	},
+	"prometheus-agent-mode-otlp-ingestion": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode:     true,
+			DisableJaegerSPM:        true,
+			PrometheusOTLPIngestion: true,
+		},
+		ExpectedRules: []string{"prometheus-agent-mode-otlp-ingestion"},
+	},
+	"prometheus-agent-mode-remote-write-basic-auth": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode:            true,
+			DisableJaegerSPM:               true,
+			PrometheusRemoteWriteBasicAuth: true,
+		},
+		ExpectedRules: []string{"prometheus-agent-mode-remote-write-basic-auth"},
+	},
+	"otel-remote-write-wal-prometheus-agent-mode": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode: true,
+			DisableJaegerSPM:    true,
+			OTELRemoteWriteWAL:  &parts.RemoteWriteWALArgs{},
+		},
+		ExpectedRules: []string{"otel-remote-write-wal-prometheus-agent-mode"},
+	},
+	"log-shipper-otel-receiver-tls": {
+		Args: &MonitoringArgs{
+			LogShipper:      &parts.LogShipperArgs{},
+			OTELReceiverTLS: &parts.ReceiverTLSArgs{},
+		},
+		ExpectedRules: []string{"log-shipper-otel-receiver-tls"},
+	},
+	"log-shipper-openshift": {
+		Args: &MonitoringArgs{
+			LogShipper: &parts.LogShipperArgs{},
+			OpenShift:  &OpenShiftArgs{},
+		},
+		ExpectedRules: []string{"log-shipper-openshift"},
+	},
+	"cold-extract-read-write-once-otel-replicas": {
+		Args: &MonitoringArgs{
+			ColdExtract:    true,
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteOnce"}),
+			OTELReplicas:   2,
+		},
+		ExpectedRules: []string{"cold-extract-read-write-once-otel-replicas"},
+	},
+	"cold-extract-read-write-once-pod-otel-replicas": {
+		Args: &MonitoringArgs{
+			ColdExtract:    true,
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteOncePod"}),
+			OTELReplicas:   3,
+		},
+		ExpectedRules: []string{"cold-extract-read-write-once-otel-replicas"},
+	},
+	"cold-extract-dev-mode-otel-replicas": {
+		// Dev mode defaults to ReadWriteOnce
+		Args: &MonitoringArgs{
+			ColdExtract:  true,
+			DevMode:      true,
+			OTELReplicas: 2,
+		},
+		ExpectedRules: []string{"cold-extract-read-write-once-otel-replicas"},
+	},
+	"cold-extract-read-write-many-otel-replicas": {
+		Args: &MonitoringArgs{
+			ColdExtract:    true,
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteMany"}),
+			OTELReplicas:   2,
+		},
+	},
+	"cold-extract-read-write-once-single-otel-replica": {
+		Args: &MonitoringArgs{
+			ColdExtract:    true,
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteOnce"}),
+		},
+	},
+	"read-write-once-otel-replicas-without-cold-extract": {
+		Args: &MonitoringArgs{
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteOnce"}),
+			OTELReplicas:   2,
+		},
+	},
+	"cold-extract-output-access-modes-otel-replicas": {
+		// Not literal, hence not checked
+		Args: &MonitoringArgs{
+			ColdExtract:    true,
+			PVCAccessModes: pulumi.ToStringArray([]string{"ReadWriteOnce"}).ToStringArrayOutput(),
+			OTELReplicas:   2,
+		},
+	},
+	"otel-ingestion-quotas-resources": {
+		Args: &MonitoringArgs{
+			OTELIngestionQuotas: &parts.IngestionQuotasArgs{
+				Quotas: []parts.TenantQuotaArgs{{Tenant: "team-a"}},
+			},
+		},
+		ExpectedRules: []string{"otel-ingestion-quotas-resources"},
+	},
+	"otel-ingestion-quotas-preset": {
+		Args: &MonitoringArgs{
+			Preset: PresetSmall,
+			OTELIngestionQuotas: &parts.IngestionQuotasArgs{
+				Quotas: []parts.TenantQuotaArgs{{Tenant: "team-a"}},
+			},
+		},
+	},
+	"otel-remote-write-wal-prometheus-otlp-ingestion": {
+		Args: &MonitoringArgs{
+			OTELRemoteWriteWAL:      &parts.RemoteWriteWALArgs{},
+			PrometheusOTLPIngestion: true,
+		},
+		ExpectedRules: []string{"otel-remote-write-wal-prometheus-otlp-ingestion"},
+	},
+	"otel-remote-write-wal": {
+		Args: &MonitoringArgs{
+			OTELRemoteWriteWAL: &parts.RemoteWriteWALArgs{},
+		},
+	},
+	"cold-extract-tenant-routing": {
+		Args: &MonitoringArgs{
+			ColdExtractTenantRouting: &parts.TenantRoutingArgs{},
+		},
+		ExpectedRules: []string{"cold-extract-tenant-routing"},
+	},
+	"traces-failover-cold-extract": {
+		Args: &MonitoringArgs{
+			TracesFailover: true,
+		},
+		ExpectedRules: []string{"traces-failover-cold-extract"},
+	},
+	"several-conflicts": {
+		Args: &MonitoringArgs{
+			PrometheusAgentMode: true,
+			TracesFailover:      true,
+			LogShipper:          &parts.LogShipperArgs{},
+			OpenShift:           &OpenShiftArgs{},
+		},
+		ExpectedRules: []string{"prometheus-agent-mode-jaeger-spm", "log-shipper-openshift", "traces-failover-cold-extract"},
+	},
+}
+
+func Test_U_CheckCompatibility(t *testing.T) {
+	t.Parallel()
+
+	for testname, tt := range compatibilityTests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			mon := &Monitoring{}
+			err := checkCompatibility(mon.defaults(tt.Args))
+
+			expected := []string{}
+			for _, rule := range compatibilityRules {
+				if slices.Contains(tt.ExpectedRules, rule.Name) {
+					expected = append(expected, rule.Message)
+				}
+			}
+			got := []string{}
+			for _, err := range multierr.Errors(err) {
+				got = append(got, err.Error())
+			}
+			if !slices.Equal(got, expected) {
+				t.Errorf("expected errors %q, got %q", expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_CompatibilityRules(t *testing.T) {
+	t.Parallel()
+
+	// Every rule is covered by a test, and has a unique name
+	names := map[string]bool{}
+	for _, rule := range compatibilityRules {
+		if names[rule.Name] {
+			t.Errorf("duplicated rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		covered := false
+		for _, tt := range compatibilityTests {
+			covered = covered || slices.Contains(tt.ExpectedRules, rule.Name)
+		}
+		if !covered {
+			t.Errorf("rule %s is not covered by any test", rule.Name)
+		}
+	}
+	for testname, tt := range compatibilityTests {
+		for _, name := range tt.ExpectedRules {
+			if !names[name] {
+				t.Errorf("test %s expects the unknown rule %s", testname, name)
+			}
+		}
+	}
+}
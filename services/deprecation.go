@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+)
+
+// deprecatedArg is a field of the MonitoringArgs renamed or restructured,
+// kept for a release for the consumers to migrate.
+type deprecatedArg struct {
+	// Field is the deprecated field, Replacement the one it maps onto.
+	Field       string
+	Replacement string
+
+	// migrate maps the deprecated field onto its replacement, and reports
+	// whether it was set, and whether the replacement was set too, i.e.
+	// took precedence.
+	migrate func(args *MonitoringArgs) (used, overridden bool)
+}
+
+// deprecatedArgs are the deprecated fields of the MonitoringArgs, none so
+// far. A renamed or restructured field is kept for a release, marked
+// Deprecated, with its migration added here.
+var deprecatedArgs = []deprecatedArg{}
+
+// normalizeArgs maps the deprecated fields onto their replacements, such
+// that the parts are only given the latter, and records a warning for each
+// one used.
+func normalizeArgs(args *MonitoringArgs, deps []deprecatedArg) {
+	args.deprecated = nil
+	for _, dep := range deps {
+		used, overridden := dep.migrate(args)
+		if !used {
+			continue
+		}
+		warning := fmt.Sprintf("MonitoringArgs.%s is deprecated, use %s", dep.Field, dep.Replacement)
+		if overridden {
+			warning += fmt.Sprintf(" (ignored, as %s is set)", dep.Replacement)
+		}
+		args.deprecated = append(args.deprecated, warning)
+	}
+}
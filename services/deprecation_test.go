@@ -0,0 +1,75 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func Test_U_NormalizeArgs(t *testing.T) {
+	t.Parallel()
+
+	// A deprecated replicas count, mapped onto OTELReplicas
+	const legacyReplicas = 3
+	deps := []deprecatedArg{
+		{
+			Field:       "Replicas",
+			Replacement: "OTELReplicas",
+			migrate: func(args *MonitoringArgs) (bool, bool) {
+				if args.OTELReplicas == 0 {
+					args.OTELReplicas = legacyReplicas
+				}
+				return true, args.OTELReplicas != legacyReplicas
+			},
+		},
+	}
+
+	var tests = map[string]struct {
+		Args             *MonitoringArgs
+		Deps             []deprecatedArg
+		ExpectedReplicas int
+		ExpectedWarnings []string
+	}{
+		"none": {
+			Args: &MonitoringArgs{},
+			Deps: deprecatedArgs,
+		},
+		"deprecated": {
+			Args:             &MonitoringArgs{},
+			Deps:             deps,
+			ExpectedReplicas: legacyReplicas,
+			ExpectedWarnings: []string{
+				"MonitoringArgs.Replicas is deprecated, use OTELReplicas",
+			},
+		},
+		"overridden": {
+			Args: &MonitoringArgs{
+				OTELReplicas: 2,
+			},
+			Deps:             deps,
+			ExpectedReplicas: 2,
+			ExpectedWarnings: []string{
+				"MonitoringArgs.Replicas is deprecated, use OTELReplicas (ignored, as OTELReplicas is set)",
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			normalizeArgs(tt.Args, tt.Deps)
+			if tt.Args.OTELReplicas != tt.ExpectedReplicas {
+				t.Errorf("expected %d replicas, got %d", tt.ExpectedReplicas, tt.Args.OTELReplicas)
+			}
+			if !slices.Equal(tt.Args.deprecated, tt.ExpectedWarnings) {
+				t.Errorf("expected warnings %v, got %v", tt.ExpectedWarnings, tt.Args.deprecated)
+			}
+
+			// Normalizing is idempotent, e.g. when rendering then deploying
+			normalizeArgs(tt.Args, tt.Deps)
+			if !slices.Equal(tt.Args.deprecated, tt.ExpectedWarnings) {
+				t.Errorf("expected the same warnings once normalized again, got %v", tt.Args.deprecated)
+			}
+		})
+	}
+}
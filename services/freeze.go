@@ -0,0 +1,157 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/multierr"
+)
+
+// freezeHookName is the name of the resource hook refusing the changes
+// within a freeze window.
+const freezeHookName = "monitoring-freeze-window"
+
+// frozenProperties are the properties of the resources which changes are
+// refused within a freeze window, as they roll the monitoring pods, keyed
+// by resource type.
+var frozenProperties = map[string][][]string{
+	"kubernetes:apps/v1:Deployment":  {{"spec", "template"}},
+	"kubernetes:apps/v1:StatefulSet": {{"spec", "template"}},
+	"kubernetes:apps/v1:DaemonSet":   {{"spec", "template"}},
+	"kubernetes:core/v1:ConfigMap":   {{"data"}, {"binaryData"}},
+}
+
+// freezeWindow is a period during which the changes rolling the monitoring
+// pods are refused, either a fixed interval or a recurring one.
+type freezeWindow struct {
+	spec string
+
+	// start and end of the fixed interval.
+	start, end time.Time
+
+	// schedule starts the recurring window, lasting for the duration.
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// parseFreezeWindows parses the freeze windows specifications, each being
+// either an RFC3339 interval (e.g. "2026-10-17T08:00:00Z/2026-10-18T20:00:00Z")
+// or a cron expression followed by the duration of the window (e.g.
+// "CRON_TZ=Europe/Paris 0 8 * * SAT 36h").
+func parseFreezeWindows(specs []string) (windows []freezeWindow, merr error) {
+	for _, spec := range specs {
+		w, err := parseFreezeWindow(spec)
+		if err != nil {
+			merr = multierr.Append(merr, err)
+			continue
+		}
+		windows = append(windows, w)
+	}
+	return
+}
+
+func parseFreezeWindow(spec string) (freezeWindow, error) {
+	w := freezeWindow{spec: spec}
+	if from, to, ok := strings.Cut(spec, "/"); ok && !strings.Contains(to, " ") {
+		var err error
+		if w.start, err = time.Parse(time.RFC3339, strings.TrimSpace(from)); err != nil {
+			return w, errors.Wrapf(err, "invalid freeze window %q start", spec)
+		}
+		if w.end, err = time.Parse(time.RFC3339, strings.TrimSpace(to)); err != nil {
+			return w, errors.Wrapf(err, "invalid freeze window %q end", spec)
+		}
+		if !w.end.After(w.start) {
+			return w, errors.Errorf("freeze window %q ends before it starts", spec)
+		}
+		return w, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) < 2 {
+		return w, errors.Errorf("freeze window %q is neither an RFC3339 interval nor a cron expression followed by a duration", spec)
+	}
+	var err error
+	if w.duration, err = time.ParseDuration(fields[len(fields)-1]); err != nil {
+		return w, errors.Wrapf(err, "invalid freeze window %q duration", spec)
+	}
+	if w.duration <= 0 {
+		return w, errors.Errorf("freeze window %q duration is not positive", spec)
+	}
+	if w.schedule, err = cron.ParseStandard(strings.Join(fields[:len(fields)-1], " ")); err != nil {
+		return w, errors.Wrapf(err, "invalid freeze window %q schedule", spec)
+	}
+	return w, nil
+}
+
+// contains returns whether the time falls within the window, and when the
+// window ends if so.
+func (w freezeWindow) contains(t time.Time) (time.Time, bool) {
+	if w.schedule == nil {
+		return w.end, !t.Before(w.start) && t.Before(w.end)
+	}
+	// The first start after the window began, if any, is the ongoing one
+	start := w.schedule.Next(t.Add(-w.duration))
+	if start.IsZero() || start.After(t) {
+		return time.Time{}, false
+	}
+	return start.Add(w.duration), true
+}
+
+// activeFreezeWindow returns the first window the time falls within.
+func activeFreezeWindow(windows []freezeWindow, t time.Time) (freezeWindow, time.Time, bool) {
+	for _, w := range windows {
+		if end, ok := w.contains(t); ok {
+			return w, end, true
+		}
+	}
+	return freezeWindow{}, time.Time{}, false
+}
+
+// freezeHook returns the resource hook refusing the updates of the
+// resources which would roll the monitoring pods, until the window ends.
+// It runs on previews too, for them to fail beforehand.
+func freezeHook(w freezeWindow, end time.Time) pulumi.ResourceHookFunction {
+	return func(args *pulumi.ResourceHookArgs) error {
+		changed := frozenChanges(string(args.Type), args.OldInputs, args.NewInputs)
+		if len(changed) == 0 {
+			return nil
+		}
+		typ := string(args.Type)
+		return errors.Errorf("monitoring is frozen until %s by window %q: %s %s would change its %s, apply it after the window or set freeze-override",
+			end.Format(time.RFC3339), w.spec, typ[strings.LastIndex(typ, ":")+1:], args.Name, strings.Join(changed, ", "))
+	}
+}
+
+// frozenChanges returns the frozen properties of the resource type which
+// differ between the inputs. An unknown value, as in a preview, is one.
+func frozenChanges(typ string, olds, news resource.PropertyMap) []string {
+	changed := []string{}
+	for _, keys := range frozenProperties[typ] {
+		if !propertyAt(olds, keys).DeepEquals(propertyAt(news, keys)) {
+			changed = append(changed, strings.Join(keys, "."))
+		}
+	}
+	return changed
+}
+
+// propertyAt returns the nested property, or null if missing.
+func propertyAt(m resource.PropertyMap, keys []string) resource.PropertyValue {
+	v := resource.NewObjectProperty(m)
+	for _, key := range keys {
+		if v.IsSecret() {
+			v = v.SecretValue().Element
+		}
+		if !v.IsObject() {
+			return resource.NewNullProperty()
+		}
+		var ok bool
+		if v, ok = v.ObjectValue()[resource.PropertyKey(key)]; !ok {
+			return resource.NewNullProperty()
+		}
+	}
+	return v
+}
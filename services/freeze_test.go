@@ -0,0 +1,291 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_ParseFreezeWindows(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Specs     []string
+		ExpectErr bool
+	}{
+		"none": {},
+		"interval": {
+			Specs: []string{"2026-10-17T08:00:00Z/2026-10-18T20:00:00Z"},
+		},
+		"interval-with-offset": {
+			Specs: []string{"2026-10-17T10:00:00+02:00/2026-10-18T22:00:00+02:00"},
+		},
+		"cron": {
+			Specs: []string{"0 8 * * SAT 36h"},
+		},
+		"cron-with-step": {
+			Specs: []string{"*/30 8-20 * * * 10m"},
+		},
+		"cron-with-timezone": {
+			Specs: []string{"CRON_TZ=UTC 0 8 * * SAT 36h"},
+		},
+		"interval-reversed": {
+			Specs:     []string{"2026-10-18T20:00:00Z/2026-10-17T08:00:00Z"},
+			ExpectErr: true,
+		},
+		"interval-not-rfc3339": {
+			Specs:     []string{"2026-10-17/2026-10-18"},
+			ExpectErr: true,
+		},
+		"cron-without-duration": {
+			Specs:     []string{"0 8 * * SAT"},
+			ExpectErr: true,
+		},
+		"cron-negative-duration": {
+			Specs:     []string{"0 8 * * SAT -1h"},
+			ExpectErr: true,
+		},
+		"cron-invalid": {
+			Specs:     []string{"0 25 * * SAT 1h"},
+			ExpectErr: true,
+		},
+		"garbage": {
+			Specs:     []string{"during the CTF"},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			windows, err := parseFreezeWindows(tt.Specs)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err == nil && len(windows) != len(tt.Specs) {
+				t.Errorf("expected %d windows, got %d", len(tt.Specs), len(windows))
+			}
+		})
+	}
+}
+
+func Test_U_FreezeWindow_Contains(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Spec        string
+		Time        time.Time
+		ExpectIn    bool
+		ExpectedEnd time.Time
+	}{
+		"interval-before": {
+			Spec: "2026-10-17T08:00:00Z/2026-10-18T20:00:00Z",
+			Time: time.Date(2026, 10, 17, 7, 59, 0, 0, time.UTC),
+		},
+		"interval-start": {
+			Spec:        "2026-10-17T08:00:00Z/2026-10-18T20:00:00Z",
+			Time:        time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+			ExpectIn:    true,
+			ExpectedEnd: time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		},
+		"interval-end": {
+			Spec: "2026-10-17T08:00:00Z/2026-10-18T20:00:00Z",
+			Time: time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		},
+		"cron-within": {
+			// 2026-10-17 is a Saturday
+			Spec:        "0 8 * * SAT 36h",
+			Time:        time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			ExpectIn:    true,
+			ExpectedEnd: time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+		},
+		"cron-before": {
+			Spec: "0 8 * * SAT 36h",
+			Time: time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC),
+		},
+		"cron-after": {
+			Spec: "0 8 * * SAT 36h",
+			Time: time.Date(2026, 10, 18, 20, 30, 0, 0, time.UTC),
+		},
+		"cron-timezone": {
+			// 08:00 in Paris is 06:00 UTC in summer time
+			Spec:        "CRON_TZ=Europe/Paris 0 8 * * SAT 1h",
+			Time:        time.Date(2026, 6, 20, 6, 30, 0, 0, time.UTC),
+			ExpectIn:    true,
+			ExpectedEnd: time.Date(2026, 6, 20, 7, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			w, err := parseFreezeWindow(tt.Spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			end, in := w.contains(tt.Time)
+			if in != tt.ExpectIn {
+				t.Fatalf("expected within: %t, got: %t", tt.ExpectIn, in)
+			}
+			if in && !end.Equal(tt.ExpectedEnd) {
+				t.Errorf("expected the window to end at %s, got %s", tt.ExpectedEnd, end)
+			}
+		})
+	}
+}
+
+func Test_U_FreezeHook(t *testing.T) {
+	t.Parallel()
+
+	template := func(image string) resource.PropertyMap {
+		return resource.NewPropertyMapFromMap(map[string]any{
+			"spec": map[string]any{
+				"replicas": 1,
+				"template": map[string]any{
+					"spec": map[string]any{
+						"containers": []any{map[string]any{"image": image}},
+					},
+				},
+			},
+		})
+	}
+	scaled := template("otel/opentelemetry-collector-contrib:0.143.0")
+	scaled["spec"].ObjectValue()["replicas"] = resource.NewNumberProperty(3)
+	unknown := template("")
+	unknown["spec"].ObjectValue()["template"] = resource.MakeComputed(resource.NewStringProperty(""))
+
+	var tests = map[string]struct {
+		Type      string
+		Olds      resource.PropertyMap
+		News      resource.PropertyMap
+		ExpectErr bool
+	}{
+		"deployment-template": {
+			Type:      "kubernetes:apps/v1:Deployment",
+			Olds:      template("otel/opentelemetry-collector-contrib:0.142.0"),
+			News:      template("otel/opentelemetry-collector-contrib:0.143.0"),
+			ExpectErr: true,
+		},
+		"deployment-unknown-template": {
+			Type:      "kubernetes:apps/v1:Deployment",
+			Olds:      template("otel/opentelemetry-collector-contrib:0.143.0"),
+			News:      unknown,
+			ExpectErr: true,
+		},
+		"deployment-scaled": {
+			Type: "kubernetes:apps/v1:Deployment",
+			Olds: template("otel/opentelemetry-collector-contrib:0.143.0"),
+			News: scaled,
+		},
+		"statefulset-template": {
+			Type:      "kubernetes:apps/v1:StatefulSet",
+			Olds:      template("otel/opentelemetry-collector-contrib:0.142.0"),
+			News:      template("otel/opentelemetry-collector-contrib:0.143.0"),
+			ExpectErr: true,
+		},
+		"configmap-data": {
+			Type: "kubernetes:core/v1:ConfigMap",
+			Olds: resource.NewPropertyMapFromMap(map[string]any{
+				"data": map[string]any{"config.yaml": "receivers: {}"},
+			}),
+			News: resource.NewPropertyMapFromMap(map[string]any{
+				"data": map[string]any{"config.yaml": "receivers: {otlp: {}}"},
+			}),
+			ExpectErr: true,
+		},
+		"configmap-labels": {
+			Type: "kubernetes:core/v1:ConfigMap",
+			Olds: resource.NewPropertyMapFromMap(map[string]any{
+				"metadata": map[string]any{"labels": map[string]any{"version": "v1"}},
+				"data":     map[string]any{"config.yaml": "receivers: {}"},
+			}),
+			News: resource.NewPropertyMapFromMap(map[string]any{
+				"metadata": map[string]any{"labels": map[string]any{"version": "v2"}},
+				"data":     map[string]any{"config.yaml": "receivers: {}"},
+			}),
+		},
+		"service": {
+			Type: "kubernetes:core/v1:Service",
+			Olds: resource.NewPropertyMapFromMap(map[string]any{
+				"spec": map[string]any{"type": "ClusterIP"},
+			}),
+			News: resource.NewPropertyMapFromMap(map[string]any{
+				"spec": map[string]any{"type": "NodePort"},
+			}),
+		},
+	}
+
+	w, err := parseFreezeWindow("2026-10-17T08:00:00Z/2026-10-18T20:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook := freezeHook(w, w.end)
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := hook(&pulumi.ResourceHookArgs{
+				Name:      "otel",
+				Type:      tokens.Type(tt.Type),
+				OldInputs: tt.Olds,
+				NewInputs: tt.News,
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			// The blocked resource is named for the operator to review it
+			if err != nil && !strings.Contains(err.Error(), "otel") {
+				t.Errorf("expected the blocked resource in the error, got %q", err)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_FreezeWindows(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	ongoing := now.Add(-time.Hour).Format(time.RFC3339) + "/" + now.Add(time.Hour).Format(time.RFC3339)
+	past := now.Add(-2*time.Hour).Format(time.RFC3339) + "/" + now.Add(-time.Hour).Format(time.RFC3339)
+
+	var tests = map[string]struct {
+		Windows  []string
+		Override bool
+	}{
+		"out-of-window": {
+			Windows: []string{past},
+		},
+		"overridden": {
+			Windows:  []string{ongoing},
+			Override: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			// No hook is registered, which the mocks would not support
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					FreezeWindows:  tt.Windows,
+					FreezeOverride: tt.Override,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+const (
+	// GrafanaDatasourcesConfigMapName is the ConfigMap the Grafana
+	// datasources provisioning is recorded in.
+	GrafanaDatasourcesConfigMapName = "monitoring-grafana-datasources"
+
+	// GrafanaDatasourcesKey is the key of the provisioning file in the
+	// ConfigMap.
+	GrafanaDatasourcesKey = "monitoring-datasources.yaml"
+
+	// GrafanaPrometheusPasswordEnv is the environment variable Grafana
+	// expands the Prometheus basic auth password from, when required.
+	GrafanaPrometheusPasswordEnv = "MONITORING_PROMETHEUS_PASSWORD"
+
+	// grafanaDatasourceLabel is the label the kube-prometheus-stack Grafana
+	// sidecar discovers the datasources ConfigMaps by.
+	grafanaDatasourceLabel = "grafana_datasource"
+
+	// grafanaUIDMaxLength is the maximum length of a datasource UID.
+	grafanaUIDMaxLength = 40
+)
+
+type (
+	// GrafanaDatasourcesArgs renders the Grafana provisioning of the
+	// Prometheus and Jaeger datasources, for an existing Grafana to query
+	// them.
+	GrafanaDatasourcesArgs struct {
+		// ConfigMap also records the provisioning in a ConfigMap labeled
+		// grafana_datasource=1, for the kube-prometheus-stack Grafana sidecar
+		// to load it.
+		ConfigMap bool
+
+		// ConfigMapNamespace is the namespace of the ConfigMap, e.g. the one
+		// the sidecar watches. Defaults to the Monitoring one.
+		ConfigMapNamespace string
+	}
+
+	// grafanaDatasourcesEndpoints are the URLs the datasources query, only
+	// known once deployed.
+	grafanaDatasourcesEndpoints struct {
+		Namespace  string
+		Prometheus string
+		Jaeger     string
+	}
+
+	grafanaProvisioning struct {
+		APIVersion  int                 `yaml:"apiVersion"`
+		Datasources []grafanaDatasource `yaml:"datasources"`
+	}
+
+	grafanaDatasource struct {
+		Name           string            `yaml:"name"`
+		UID            string            `yaml:"uid"`
+		Type           string            `yaml:"type"`
+		Access         string            `yaml:"access"`
+		URL            string            `yaml:"url"`
+		Editable       bool              `yaml:"editable"`
+		BasicAuth      bool              `yaml:"basicAuth,omitempty"`
+		BasicAuthUser  string            `yaml:"basicAuthUser,omitempty"`
+		JSONData       map[string]any    `yaml:"jsonData,omitempty"`
+		SecureJSONData map[string]string `yaml:"secureJsonData,omitempty"`
+	}
+)
+
+// checkGrafanaDatasources validates the namespace of the ConfigMap, if set.
+func checkGrafanaDatasources(args *GrafanaDatasourcesArgs) error {
+	if args == nil || args.ConfigMapNamespace == "" {
+		return nil
+	}
+	if !args.ConfigMap {
+		return errors.New("grafana datasources configmap namespace is set but the configmap is not enabled")
+	}
+	if errs := validation.IsDNS1123Label(args.ConfigMapNamespace); len(errs) != 0 {
+		return fmt.Errorf("grafana datasources configmap namespace %s: %s", args.ConfigMapNamespace, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// grafanaDatasources renders the Grafana provisioning of the datasources,
+// and records it in a ConfigMap if requested. The datasources query the
+// exposed URLs, the in-cluster ones otherwise.
+func (mon *Monitoring) grafanaDatasources(
+	ctx *pulumi.Context,
+	args *MonitoringArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	mon.GrafanaDatasources = pulumi.String("").ToStringOutput()
+	if args.GrafanaDatasources == nil {
+		return
+	}
+
+	prometheus := mon.prom.URL
+	if mon.promRoute != nil {
+		prometheus = routeURL(mon.promRoute)
+	}
+	jaeger := pulumi.All(mon.jaeger.UIServiceName, mon.ns.Name).ApplyT(func(all []any) string {
+		return serviceURL(all[0].(string), all[1].(string), args.ClusterDomain, parts.JaegerUIPort)
+	}).(pulumi.StringOutput)
+	if mon.jgrRoute != nil {
+		jaeger = routeURL(mon.jgrRoute)
+	}
+	mon.GrafanaDatasources = pulumi.All(mon.ns.Name, prometheus, jaeger).ApplyT(func(all []any) (string, error) {
+		return renderGrafanaDatasources(args, grafanaDatasourcesEndpoints{
+			Namespace:  all[0].(string),
+			Prometheus: all[1].(string),
+			Jaeger:     all[2].(string),
+		})
+	}).(pulumi.StringOutput)
+	if !args.GrafanaDatasources.ConfigMap {
+		return
+	}
+
+	namespace := mon.ns.Name
+	if ns := args.GrafanaDatasources.ConfigMapNamespace; ns != "" {
+		namespace = pulumi.String(ns).ToStringOutput()
+	}
+	mon.grafanacm, err = corev1.NewConfigMap(ctx, "grafana-datasources", &corev1.ConfigMapArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Name:      pulumi.String(GrafanaDatasourcesConfigMapName),
+			Namespace: namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("grafana-datasources"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				grafanaDatasourceLabel:        pulumi.String("1"),
+			},
+		},
+		Data: pulumi.StringMap{
+			GrafanaDatasourcesKey: mon.GrafanaDatasources,
+		},
+	}, opts...)
+	return
+}
+
+// renderGrafanaDatasources renders the Grafana provisioning file of the
+// Prometheus and Jaeger datasources. The Prometheus password is never part
+// of it, Grafana expands it from GrafanaPrometheusPasswordEnv.
+// See https://grafana.com/docs/grafana/latest/administration/provisioning/#data-sources
+func renderGrafanaDatasources(args *MonitoringArgs, edps grafanaDatasourcesEndpoints) (string, error) {
+	prom := grafanaDatasource{
+		Name:     fmt.Sprintf("Prometheus (%s)", edps.Namespace),
+		UID:      grafanaUID("prometheus", edps.Namespace),
+		Type:     "prometheus",
+		Access:   "proxy",
+		URL:      edps.Prometheus,
+		JSONData: map[string]any{"httpMethod": "POST"},
+	}
+	if args.PrometheusRemoteWriteBasicAuth {
+		prom.BasicAuth = true
+		prom.BasicAuthUser = parts.PrometheusBasicAuthUsername
+		prom.SecureJSONData = map[string]string{
+			"basicAuthPassword": "${" + GrafanaPrometheusPasswordEnv + "}",
+		}
+	}
+	jgr := grafanaDatasource{
+		Name:   fmt.Sprintf("Jaeger (%s)", edps.Namespace),
+		UID:    grafanaUID("jaeger", edps.Namespace),
+		Type:   "jaeger",
+		Access: "proxy",
+		URL:    edps.Jaeger,
+	}
+	if args.Exemplars {
+		// Jump from the exemplars to their trace
+		prom.JSONData["exemplarTraceIdDestinations"] = []map[string]string{
+			{"name": "trace_id", "datasourceUid": jgr.UID},
+		}
+	}
+
+	b := &bytes.Buffer{}
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(grafanaProvisioning{
+		APIVersion:  1,
+		Datasources: []grafanaDatasource{prom, jgr},
+	}); err != nil {
+		return "", errors.Wrap(err, "encoding grafana datasources")
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.Wrap(err, "encoding grafana datasources")
+	}
+	return b.String(), nil
+}
+
+// grafanaUID returns the UID of the datasource of the part, unique per
+// Monitoring namespace, truncated to the length Grafana accepts.
+func grafanaUID(part, namespace string) string {
+	uid := part + "-" + namespace
+	if len(uid) > grafanaUIDMaxLength {
+		uid = uid[:grafanaUIDMaxLength]
+	}
+	return uid
+}
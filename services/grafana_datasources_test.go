@@ -0,0 +1,187 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_GrafanaDatasources_Golden(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *MonitoringArgs
+		Endpoints grafanaDatasourcesEndpoints
+		Golden    string
+	}{
+		"internal": {
+			Args: &MonitoringArgs{},
+			Endpoints: grafanaDatasourcesEndpoints{
+				Namespace:  "monitoring-abcdefgh",
+				Prometheus: "http://prometheus.monitoring-abcdefgh:9090",
+				Jaeger:     serviceURL("jaeger-ui", "monitoring-abcdefgh", "", 16686),
+			},
+			Golden: "grafana-datasources-internal.golden.yaml",
+		},
+		"external": {
+			Args: &MonitoringArgs{
+				PrometheusRemoteWriteBasicAuth: true,
+				Exemplars:                      true,
+			},
+			Endpoints: grafanaDatasourcesEndpoints{
+				Namespace:  "monitoring-abcdefgh",
+				Prometheus: "https://prom.finals.ctfer.io",
+				Jaeger:     "https://jaeger.finals.ctfer.io",
+			},
+			Golden: "grafana-datasources-external.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := renderGrafanaDatasources(tt.Args, tt.Endpoints)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			expected, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			if got != string(expected) {
+				t.Errorf("expected datasources:\n%s\ngot:\n%s", expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_GrafanaDatasources_UID(t *testing.T) {
+	t.Parallel()
+
+	uid := grafanaUID("prometheus", "monitoring-"+strings.Repeat("a", 40))
+	if len(uid) != grafanaUIDMaxLength {
+		t.Errorf("expected the uid to be truncated to %d, got %s", grafanaUIDMaxLength, uid)
+	}
+}
+
+func Test_U_Monitoring_GrafanaDatasources(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args              *MonitoringArgs
+		ExpectErr         bool
+		ExpectConfigMap   bool
+		ExpectedNamespace string
+		ExpectedURLs      []string
+	}{
+		"disabled": {
+			Args: &MonitoringArgs{},
+		},
+		"internal": {
+			Args: &MonitoringArgs{
+				GrafanaDatasources: &GrafanaDatasourcesArgs{},
+			},
+			ExpectedURLs: []string{"url: http://prometheus", "url: http://jaeger-ui"},
+		},
+		"external-configmap": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{
+					Routes: true,
+				},
+				Hosts: &HostsArgs{
+					BaseDomain:       "finals.ctfer.io",
+					ExposePrometheus: true,
+				},
+				GrafanaDatasources: &GrafanaDatasourcesArgs{
+					ConfigMap:          true,
+					ConfigMapNamespace: "grafana",
+				},
+			},
+			ExpectConfigMap:   true,
+			ExpectedNamespace: "grafana",
+			ExpectedURLs:      []string{"url: https://prom.finals.ctfer.io", "url: https://jaeger.finals.ctfer.io"},
+		},
+		"namespace-without-configmap": {
+			Args: &MonitoringArgs{
+				GrafanaDatasources: &GrafanaDatasourcesArgs{
+					ConfigMapNamespace: "grafana",
+				},
+			},
+			ExpectErr: true,
+		},
+		"invalid-namespace": {
+			Args: &MonitoringArgs{
+				GrafanaDatasources: &GrafanaDatasourcesArgs{
+					ConfigMap:          true,
+					ConfigMapNamespace: "Grafana",
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var got string
+			wg := sync.WaitGroup{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				if err != nil {
+					return err
+				}
+				wg.Add(1)
+				mon.GrafanaDatasources.ApplyT(func(ds string) error {
+					defer wg.Done()
+					got = ds
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+			wg.Wait()
+
+			if tt.Args.GrafanaDatasources == nil && got != "" {
+				t.Errorf("expected no datasources, got:\n%s", got)
+			}
+			for _, url := range tt.ExpectedURLs {
+				if !strings.Contains(got, url) {
+					t.Errorf("expected %q in:\n%s", url, got)
+				}
+			}
+
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "grafana-datasources")
+			if (cm != nil) != tt.ExpectConfigMap {
+				t.Fatalf("expected the Grafana datasources ConfigMap: %t", tt.ExpectConfigMap)
+			}
+			if cm == nil {
+				return
+			}
+			meta := cm["metadata"].ObjectValue()
+			if ns := meta["namespace"].StringValue(); ns != tt.ExpectedNamespace {
+				t.Errorf("expected namespace %s, got %s", tt.ExpectedNamespace, ns)
+			}
+			if label := meta["labels"].ObjectValue()["grafana_datasource"].StringValue(); label != "1" {
+				t.Errorf("expected the grafana_datasource=1 label, got %q", label)
+			}
+			if data := cm["data"].ObjectValue()[GrafanaDatasourcesKey].StringValue(); data != got {
+				t.Errorf("expected the ConfigMap to record the datasources, got:\n%s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+type (
+	// HostsArgs derives the hosts the UIs are exposed at from the base
+	// domain of the event, e.g. jaeger.<event>.ctfer.io, rather than setting
+	// each by hand. They are set on the OpenShift Routes.
+	HostsArgs struct {
+		// BaseDomain the hosts derive from, e.g. <event>.ctfer.io.
+		BaseDomain string
+
+		// JaegerPrefix, PersesPrefix and PrometheusPrefix are prepended to the
+		// BaseDomain. Default to jaeger, perses and prom.
+		JaegerPrefix     string
+		PersesPrefix     string
+		PrometheusPrefix string
+
+		// JaegerHost, PersesHost and PrometheusHost take precedence over the
+		// derived hosts, e.g. for a UI out of the BaseDomain.
+		JaegerHost     string
+		PersesHost     string
+		PrometheusHost string
+
+		// ExposePrometheus also exposes the Prometheus UI. Anyone reaching it
+		// could query it, unless PrometheusRemoteWriteBasicAuth.
+		ExposePrometheus bool
+
+		// ExternalDNS annotates the Routes with their host, for ExternalDNS to
+		// create the DNS records.
+		ExternalDNS bool
+	}
+)
+
+const (
+	defaultJaegerHostPrefix     = "jaeger"
+	defaultPersesHostPrefix     = "perses"
+	defaultPrometheusHostPrefix = "prom"
+
+	// externalDNSHostnameAnnotation is the annotation ExternalDNS creates
+	// the records of.
+	externalDNSHostnameAnnotation = "external-dns.alpha.kubernetes.io/hostname"
+)
+
+func hostsDefaults(args *HostsArgs) {
+	if args.JaegerPrefix == "" {
+		args.JaegerPrefix = defaultJaegerHostPrefix
+	}
+	if args.PersesPrefix == "" {
+		args.PersesPrefix = defaultPersesHostPrefix
+	}
+	if args.PrometheusPrefix == "" {
+		args.PrometheusPrefix = defaultPrometheusHostPrefix
+	}
+}
+
+// hosts returns the host of each exposed UI, keyed by part. A UI is not
+// exposed at any if it has neither a host nor a BaseDomain to derive it
+// from, which check rejects.
+func (args *HostsArgs) hosts() map[string]string {
+	hosts := map[string]string{
+		partJaeger: hostOf(args.JaegerHost, args.JaegerPrefix, args.BaseDomain),
+		partPerses: hostOf(args.PersesHost, args.PersesPrefix, args.BaseDomain),
+	}
+	if args.ExposePrometheus {
+		hosts[partPrometheus] = hostOf(args.PrometheusHost, args.PrometheusPrefix, args.BaseDomain)
+	}
+	return hosts
+}
+
+// hostOf returns the host, or the one derived from the prefix and the base
+// domain, normalized as DNS names are.
+func hostOf(host, prefix, baseDomain string) string {
+	if host == "" {
+		baseDomain = strings.Trim(baseDomain, ".")
+		if baseDomain == "" {
+			return ""
+		}
+		host = prefix + "." + baseDomain
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// checkHosts validates the hosts are DNS names, and set on Routes.
+func checkHosts(args *MonitoringArgs) (merr error) {
+	if args.Hosts == nil {
+		return nil
+	}
+	if args.OpenShift == nil || !args.OpenShift.Routes {
+		return errors.New("hosts are set on the OpenShift Routes, which are not enabled")
+	}
+	hosts := args.Hosts.hosts()
+	for _, part := range []string{partJaeger, partPerses, partPrometheus} {
+		host, ok := hosts[part]
+		if !ok {
+			continue
+		}
+		if host == "" {
+			merr = multierr.Append(merr, fmt.Errorf("%s host: neither set nor derived from a base domain", part))
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(host); len(errs) != 0 {
+			merr = multierr.Append(merr, fmt.Errorf("%s host %s: %s", part, host, strings.Join(errs, ", ")))
+		}
+	}
+	return
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"maps"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Hosts(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args     *HostsArgs
+		Expected map[string]string
+	}{
+		"derived": {
+			Args: &HostsArgs{
+				BaseDomain: "finals.ctfer.io",
+			},
+			Expected: map[string]string{
+				partJaeger: "jaeger.finals.ctfer.io",
+				partPerses: "perses.finals.ctfer.io",
+			},
+		},
+		"prometheus": {
+			Args: &HostsArgs{
+				BaseDomain:       "finals.ctfer.io",
+				ExposePrometheus: true,
+			},
+			Expected: map[string]string{
+				partJaeger:     "jaeger.finals.ctfer.io",
+				partPerses:     "perses.finals.ctfer.io",
+				partPrometheus: "prom.finals.ctfer.io",
+			},
+		},
+		"prefixes": {
+			Args: &HostsArgs{
+				BaseDomain:   "finals.ctfer.io",
+				JaegerPrefix: "traces",
+				PersesPrefix: "dashboards",
+			},
+			Expected: map[string]string{
+				partJaeger: "traces.finals.ctfer.io",
+				partPerses: "dashboards.finals.ctfer.io",
+			},
+		},
+		"host-over-prefix": {
+			Args: &HostsArgs{
+				BaseDomain:   "finals.ctfer.io",
+				JaegerPrefix: "traces",
+				JaegerHost:   "jaeger.internal.ctfer.io",
+			},
+			Expected: map[string]string{
+				partJaeger: "jaeger.internal.ctfer.io",
+				partPerses: "perses.finals.ctfer.io",
+			},
+		},
+		"normalized": {
+			Args: &HostsArgs{
+				BaseDomain: ".Finals.CTFer.io.",
+				PersesHost: "Perses.CTFer.io.",
+			},
+			Expected: map[string]string{
+				partJaeger: "jaeger.finals.ctfer.io",
+				partPerses: "perses.ctfer.io",
+			},
+		},
+		"hosts-only": {
+			Args: &HostsArgs{
+				JaegerHost: "jaeger.ctfer.io",
+			},
+			Expected: map[string]string{
+				partJaeger: "jaeger.ctfer.io",
+				partPerses: "",
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			hostsDefaults(tt.Args)
+			if got := tt.Args.hosts(); !maps.Equal(got, tt.Expected) {
+				t.Errorf("expected %v, got %v", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_CheckHosts(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *MonitoringArgs
+		ExpectErr bool
+	}{
+		"none": {
+			Args: &MonitoringArgs{},
+		},
+		"routes": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{Routes: true},
+				Hosts:     &HostsArgs{BaseDomain: "finals.ctfer.io"},
+			},
+		},
+		"without-routes": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{},
+				Hosts:     &HostsArgs{BaseDomain: "finals.ctfer.io"},
+			},
+			ExpectErr: true,
+		},
+		"undefined-host": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{Routes: true},
+				Hosts:     &HostsArgs{JaegerHost: "jaeger.ctfer.io"},
+			},
+			ExpectErr: true,
+		},
+		"invalid-host": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{Routes: true},
+				Hosts:     &HostsArgs{BaseDomain: "finals_ctfer.io"},
+			},
+			ExpectErr: true,
+		},
+		"invalid-prefix": {
+			Args: &MonitoringArgs{
+				OpenShift: &OpenShiftArgs{Routes: true},
+				Hosts: &HostsArgs{
+					BaseDomain:       "finals.ctfer.io",
+					PrometheusPrefix: "prom-",
+					ExposePrometheus: true,
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			mon := &Monitoring{}
+			err := mon.check(mon.defaults(tt.Args))
+			if (err != nil) != tt.ExpectErr {
+				t.Errorf("expected error: %t, got %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_Hosts(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Hosts               *HostsArgs
+		ExpectedHosts       map[string]string
+		ExpectedExternalDNS bool
+	}{
+		"router-assigned": {
+			Hosts:         nil,
+			ExpectedHosts: map[string]string{},
+		},
+		"derived": {
+			Hosts: &HostsArgs{
+				BaseDomain: "finals.ctfer.io",
+			},
+			ExpectedHosts: map[string]string{
+				"jaeger-ui": "jaeger.finals.ctfer.io",
+				"perses":    "perses.finals.ctfer.io",
+			},
+		},
+		"prometheus-external-dns": {
+			Hosts: &HostsArgs{
+				BaseDomain:       "finals.ctfer.io",
+				ExposePrometheus: true,
+				ExternalDNS:      true,
+			},
+			ExpectedHosts: map[string]string{
+				"jaeger-ui":  "jaeger.finals.ctfer.io",
+				"perses":     "perses.finals.ctfer.io",
+				"prometheus": "prom.finals.ctfer.io",
+			},
+			ExpectedExternalDNS: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var outputs map[string]string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					OpenShift: &OpenShiftArgs{Routes: true},
+					Hosts:     tt.Hosts,
+				})
+				if err != nil {
+					return err
+				}
+				mon.Hosts.ApplyT(func(hosts map[string]string) error {
+					defer wg.Done()
+					outputs = hosts
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			routes := m.ByType("kubernetes:route.openshift.io/v1:Route")
+			if len(routes) != max(2, len(tt.ExpectedHosts)) {
+				t.Fatalf("expected %d routes, got %d", max(2, len(tt.ExpectedHosts)), len(routes))
+			}
+			for name, host := range tt.ExpectedHosts {
+				route := m.ByName("kubernetes:route.openshift.io/v1:Route", name)
+				if route == nil {
+					t.Fatalf("expected the %s route", name)
+				}
+				if got := route["spec"].ObjectValue()["host"]; !got.IsString() || got.StringValue() != host {
+					t.Errorf("expected the %s route at %s, got %v", name, host, got)
+				}
+				annotations := map[string]string{}
+				if md := route["metadata"].ObjectValue(); md["annotations"].IsObject() {
+					for k, v := range md["annotations"].ObjectValue() {
+						annotations[string(k)] = v.StringValue()
+					}
+				}
+				if got, ok := annotations[externalDNSHostnameAnnotation]; ok != tt.ExpectedExternalDNS || (ok && got != host) {
+					t.Errorf("expected the %s route ExternalDNS annotation: %t, got %v", name, tt.ExpectedExternalDNS, annotations)
+				}
+			}
+			expected := map[string]string{}
+			if tt.Hosts != nil {
+				expected = tt.Hosts.hosts()
+			}
+			if !maps.Equal(outputs, expected) {
+				t.Errorf("expected the hosts output %v, got %v", expected, outputs)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	yamlv2 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml/v2"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// provisionLogShipper deploys the log shipper toward the OTEL Collector, and
+// allows it to reach the collector and the API server.
+func (mon *Monitoring) provisionLogShipper(
+	ctx *pulumi.Context,
+	args *MonitoringArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	mon.shipper, err = parts.NewLogShipper(ctx, "log-shipper", logShipperArgs(args, mon.otel.Endpoint, mon.ns.Name), opts...)
+	if err != nil {
+		return
+	}
+
+	// Allow the log shipper to send the logs to the OTEL Collector
+	mon.shipperntp, err = netwv1.NewNetworkPolicy(ctx, "log-shipper-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Namespace: mon.shipper.Namespace,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Egress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: mon.shipper.PodLabels,
+			},
+			// Log shipper -> OTEL Collector
+			Egress: parts.EgressRules(mon.flows, "log-shipper-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// Isolated from in-otel-ntp such that the logs keep flowing whatever the
+	// ingress peers, but for DenyAllIngress.
+	if !args.DenyAllIngress {
+		mon.inshipperntp, err = netwv1.NewNetworkPolicy(ctx, "in-otel-logshipper-ntp", &netwv1.NetworkPolicyArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+				Namespace: mon.ns.Name,
+			},
+			Spec: netwv1.NetworkPolicySpecArgs{
+				PolicyTypes: pulumi.ToStringArray([]string{
+					"Ingress",
+				}),
+				PodSelector: metav1.LabelSelectorArgs{
+					MatchLabels: mon.otel.PodLabels,
+				},
+				// Log shipper -> OTEL Collector
+				Ingress: parts.IngressRules(mon.flows, "in-otel-logshipper-ntp", mon.partSelectors()),
+			},
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
+	// The k8sattributes processor watches the pods through the API server
+	mon.shipperToAPI, err = yamlv2.NewConfigGroup(ctx, "log-shipper-to-apiserver-netpol", &yamlv2.ConfigGroupArgs{
+		Yaml: pulumi.All(args.netpolToAPIServerTemplate, mon.shipper.Namespace, mon.shipper.PodLabels).
+			ApplyT(func(all []any) (string, error) {
+				return renderNetpolToAPIServer(
+					all[0].(string),
+					"allow-log-shipper-to-apiserver-"+ctx.Stack(),
+					all[1].(string),
+					all[2].(map[string]string),
+				)
+			}).(pulumi.StringOutput),
+	}, opts...)
+	return
+}
+
+// logShipperArgs maps the arguments to the LogShipper ones, shipping to the
+// OTEL Collector endpoint all the logs but the monitoring namespace ones,
+// for the collector not to loop on its own debug exporter.
+func logShipperArgs(args *MonitoringArgs, endpoint, namespace pulumi.StringInput) *parts.LogShipperArgs {
+	lsArgs := *args.LogShipper
+	lsArgs.Registry = args.Registry
+	lsArgs.MonitoringVersion = args.BuildInfo.Version
+	lsArgs.Endpoint = endpoint
+	lsArgs.ExcludeNamespaces = pulumi.StringArray{namespace}
+	if args.LogShipper.ExcludeNamespaces != nil {
+		lsArgs.ExcludeNamespaces = pulumi.All(args.LogShipper.ExcludeNamespaces, namespace).ApplyT(func(all []any) []string {
+			return append(all[0].([]string), all[1].(string))
+		}).(pulumi.StringArrayOutput)
+	}
+	if lsArgs.Mesh == nil {
+		lsArgs.Mesh = args.Mesh
+	}
+	if lsArgs.ExporterRetry == nil && args.ExporterRetry != nil {
+		retry := *args.ExporterRetry
+		lsArgs.ExporterRetry = &retry
+	}
+	return &lsArgs
+}
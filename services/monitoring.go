@@ -2,14 +2,16 @@ package services
 
 import (
 	"bytes"
-	"net/url"
-	"strconv"
-	"strings"
+	"fmt"
+	"slices"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
 	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
 	yamlv2 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml/v2"
@@ -29,20 +31,101 @@ type (
 		jaeger *parts.Jaeger
 		prom   *parts.Prometheus
 
-		inotelntp *netwv1.NetworkPolicy
-		otelntp   *netwv1.NetworkPolicy
-		prsToAPI  *yamlv2.ConfigGroup
-		jgrntp    *netwv1.NetworkPolicy
-		promntp   *netwv1.NetworkPolicy
+		// Log shipping, if enabled
+		shipper      *parts.LogShipper
+		shipperntp   *netwv1.NetworkPolicy
+		inshipperntp *netwv1.NetworkPolicy
+		shipperToAPI *yamlv2.ConfigGroup
 
-		Namespace pulumi.StringOutput
-		OTEL      MonitoringOTELOutput
+		inotelntp       *netwv1.NetworkPolicy
+		inotelscrapentp *netwv1.NetworkPolicy
+		otelntp         *netwv1.NetworkPolicy
+		prsToAPI        *yamlv2.ConfigGroup
+		prsbootntp      *netwv1.NetworkPolicy
+		inprsbootntp    *netwv1.NetworkPolicy
+		jgrntp          *netwv1.NetworkPolicy
+		promntp         *netwv1.NetworkPolicy
+		promegressntp   *netwv1.NetworkPolicy
+		promToAPI       *yamlv2.ConfigGroup
+		buildinfo       *corev1.ConfigMap
+		lifecycle       *corev1.Event
+		flowscm         *corev1.ConfigMap
+		grafanacm       *corev1.ConfigMap
+
+		// flows the network policies allow
+		flows []parts.NetworkFlow
+
+		// OpenShift specifics
+		jgrRoute     *apiextensions.CustomResource
+		prsRoute     *apiextensions.CustomResource
+		promRoute    *apiextensions.CustomResource
+		routerntp    *netwv1.NetworkPolicy
+		prsScrapentp *netwv1.NetworkPolicy
+
+		Namespace  pulumi.StringOutput
+		OTEL       MonitoringOTELOutput
+		LogShipper MonitoringLogShipperOutput
+
+		// PrometheusURL is the in-cluster URL of the Prometheus HTTP API.
+		PrometheusURL pulumi.StringOutput
+
+		// DashboardDiscovery is the contract for dashboards to be discovered
+		// by Perses, e.g. through parts.NewDashboard.
+		DashboardDiscovery parts.DashboardDiscovery
+
+		// Version of the program that deployed the Monitoring.
+		Version pulumi.StringOutput
+
+		// Summary is a JSON Summary of the parts and their features, for
+		// downstream automation.
+		Summary pulumi.StringOutput
+
+		// Ready resolves to true once every part rolled out, for downstream
+		// stacks to wait for the Monitoring to serve before emitting telemetry.
+		Ready pulumi.BoolOutput
+
+		// NetworkFlows is the Markdown matrix of the flows the network
+		// policies allow (source, destination, ports and policy).
+		NetworkFlows pulumi.StringOutput
+
+		// Hosts the UIs are exposed at, keyed by part, when derived from
+		// the Hosts arguments. Empty otherwise.
+		Hosts pulumi.StringMapOutput
+
+		// GrafanaDatasources is the Grafana provisioning YAML of the
+		// Prometheus and Jaeger datasources, if GrafanaDatasources. Empty
+		// otherwise.
+		GrafanaDatasources pulumi.StringOutput
 	}
 
 	MonitoringOTELOutput struct {
 		Endpoint           pulumi.StringOutput
 		ColdExtractPVCName pulumi.StringPtrOutput
 		PodLabels          pulumi.StringMapOutput
+
+		// ColdExtractLayout describes where the cold extract signals land on
+		// the PVC, relatively to its root, indexed by tenant.
+		ColdExtractLayout pulumi.StringMapOutput
+
+		// Pipelines summarizes the active pipelines of the OTEL Collector, as
+		// a JSON array of parts.PipelineSpec.
+		Pipelines pulumi.StringOutput
+
+		// PodEndpoints are the endpoints of each OTEL Collector pod when
+		// scaled, for clients to pin to a specific one. Empty otherwise.
+		PodEndpoints pulumi.StringArrayOutput
+
+		// CASecretName is the Secret containing the CA of the receiver TLS
+		// certificates, if enabled.
+		CASecretName pulumi.StringPtrOutput
+	}
+
+	MonitoringLogShipperOutput struct {
+		// Namespace of the log shipper, empty if disabled.
+		Namespace pulumi.StringOutput
+
+		// PodLabels of the log shipper, empty if disabled.
+		PodLabels pulumi.StringMapOutput
 	}
 
 	MonitoringArgs struct {
@@ -58,10 +141,385 @@ type (
 		netpolToAPIServerTemplate pulumi.StringOutput
 
 		ColdExtract bool
+
+		// ColdExtractTenantRouting routes the cold extract signals into a
+		// directory per tenant, e.g. when several events share the cluster.
+		// Requires ColdExtract.
+		ColdExtractTenantRouting *parts.TenantRoutingArgs
+
+		// PublishNotReadyAddresses makes the Jaeger and Prometheus headless
+		// Services resolve to their pods before they are ready.
+		PublishNotReadyAddresses pulumi.BoolInput
+
+		// ExporterRetry tunes the OTEL Collector exporters retries toward
+		// Jaeger and Prometheus.
+		ExporterRetry *parts.ExporterRetryArgs
+
+		// OTELReplicas of the OTEL Collector pods, run as a StatefulSet when
+		// more than one. Defaults to 1.
+		OTELReplicas int
+
+		// OTELAwaitBackends creates the OTEL Collector workload once the
+		// Jaeger and Prometheus rollouts are awaited, for its exporters not
+		// to retry (and log it) while they start. Ignored with PauseRollouts,
+		// as the paused rollouts are not awaited.
+		OTELAwaitBackends bool
+
+		// OTELReceiverTLS serves the OTEL Collector receiver over TLS, and
+		// optionally requires senders to present a client certificate.
+		// Requires cert-manager in the cluster.
+		OTELReceiverTLS *parts.ReceiverTLSArgs
+
+		// OTELStatsdReceiver and OTELSyslogReceiver receive statsd metrics
+		// (UDP 8125 by default) and syslog messages (TCP and UDP 514 by
+		// default) in the OTEL
+		// Collector, e.g. from legacy hosts not speaking OTLP. They are
+		// plain, ReceiverTLS only applies to the OTLP receiver.
+		OTELStatsdReceiver bool
+		OTELSyslogReceiver *parts.SyslogReceiverArgs
+
+		// OTELPorts are the ports of the OTEL Collector receivers, e.g. when
+		// the defaults clash with other agents. The Services, NetworkPolicies
+		// and endpoints follow them.
+		OTELPorts *parts.OtelPortsArgs
+
+		// OTELRedaction scrubs the signals attributes in the OTEL Collector,
+		// e.g. player usernames, IPs or flags, before they are stored in
+		// Jaeger and Prometheus, and on the cold extract PVC.
+		OTELRedaction *parts.RedactionArgs
+
+		// OTELKafka streams the signals into Kafka topics off-cluster, e.g.
+		// for custom analytics. The OTEL Collector NetworkPolicy lets it
+		// reach the brokers.
+		OTELKafka *parts.KafkaExporterArgs
+
+		// OTELMetricsConversion converts the delta temporality metrics and
+		// exponential histograms Prometheus could not ingest, and counts
+		// them.
+		OTELMetricsConversion *parts.MetricsConversionArgs
+
+		// OTELLogsMetrics counts the log records received into metrics sent
+		// to Prometheus, e.g. the errors per challenge, without a log
+		// backend.
+		OTELLogsMetrics *parts.LogsMetricsArgs
+
+		// LogShipper ships the containers logs of every node into the logs
+		// pipeline of the OTEL Collector, but for the monitoring ones. It runs
+		// in its own privileged namespace as it mounts a host path, and does
+		// not support OTELReceiverTLS. Opt-in.
+		LogShipper *parts.LogShipperArgs
+
+		// OTELCollectorImage overrides the OTEL Collector image, e.g. a leaner
+		// one built with the OpenTelemetry Collector Builder.
+		OTELCollectorImage string
+
+		// OTELCollectorComponents are the components built in the OTEL
+		// Collector image. Required for images which components are not known.
+		OTELCollectorComponents *parts.CollectorComponents
+
+		// OTELValidateConfig validates the OTEL Collector configuration with
+		// the collector itself, in a Job, before rolling it out. Opt-in, for
+		// clusters where extra Jobs are unwanted.
+		OTELValidateConfig bool
+
+		// OTELResources and OTELQueueSize size the OTEL Collector, and its
+		// exporters queues to the backends. Default to the Preset ones.
+		OTELResources corev1.ResourceRequirementsInput
+		OTELQueueSize int
+
+		// OTELIngestionQuotas limits the signals of the noisy tenants (e.g.
+		// the namespaces of a shared training platform) to their share of the
+		// OTEL Collector memory, for the others not to be starved. It requires
+		// a memory limit in the OTELResources, as the Presets set.
+		OTELIngestionQuotas *parts.IngestionQuotasArgs
+
+		// OTELRemoteWriteWAL buffers the metrics the OTEL Collector remote
+		// writes to Prometheus in a write-ahead log, for them to survive a
+		// Prometheus restart. Not supported along PrometheusOTLPIngestion.
+		OTELRemoteWriteWAL *parts.RemoteWriteWALArgs
+
+		// OTELMemoryLimitPercent is the percentage of the OTEL Collector
+		// memory limit its Go runtime and memory_limiter processor are bound
+		// to. Defaults to parts.DefaultMemoryLimitPercent.
+		OTELMemoryLimitPercent int
+
+		// OTELHeadSamplingPercent is the percentage of the traces the OTEL
+		// Collector keeps, from 0 to 100. Every one is kept if unset or 100.
+		OTELHeadSamplingPercent *int
+
+		// PrometheusAgentMode runs Prometheus as an agent forwarding metrics to
+		// the PrometheusRemoteWriteURLs, without local querying.
+		// It is incompatible with Jaeger SPM, so requires DisableJaegerSPM.
+		// Having no receiver, it scrapes the metrics of the OTEL Collector,
+		// and Perses gets no datasource to query.
+		PrometheusAgentMode       bool
+		PrometheusRemoteWriteURLs pulumi.StringArrayInput
+
+		// PrometheusRemoteWriteBasicAuth requires the OTEL Collector to
+		// authenticate its metrics remote write, with generated credentials.
+		// The NetworkPolicy remains the primary guard, this one is in depth.
+		// As Prometheus then requires it of every querier, it requires
+		// DisableJaegerSPM, and Perses proxies the datasource queries to
+		// authenticate them.
+		PrometheusRemoteWriteBasicAuth bool
+
+		// PrometheusRemoteWriteBasicAuthSecret is the pre-existing Secret of
+		// the basic auth, rather than generated credentials, e.g. synced by an
+		// ExternalSecret. It holds the parts.PrometheusWebConfigKey for
+		// Prometheus, and the password of parts.PrometheusBasicAuthUsername
+		// for the OTEL Collector. Requires PrometheusRemoteWriteBasicAuth.
+		PrometheusRemoteWriteBasicAuthSecret *parts.ExistingSecretArgs
+
+		// PrometheusOTLPIngestion pushes the metrics of the OTEL Collector to
+		// the native OTLP receiver of Prometheus, rather than remote writing
+		// them. Their names then keep their dots (e.g. traces.span.metrics),
+		// which the dashboards and Jaeger SPM querying the remote written
+		// names do not expect yet.
+		PrometheusOTLPIngestion bool
+
+		// PrometheusAdminAPI turns on the Prometheus admin API, for the
+		// extractor to take TSDB snapshots.
+		PrometheusAdminAPI bool
+
+		// PrometheusQueryLog logs every PromQL query in the Prometheus
+		// container, at parts.PrometheusQueryLogFile.
+		PrometheusQueryLog bool
+
+		// PrometheusQueryTimeout and PrometheusQueryMaxConcurrency limit the
+		// PromQL queries. Default to the Prometheus ones.
+		PrometheusQueryTimeout        time.Duration
+		PrometheusQueryMaxConcurrency int
+
+		// PrometheusRetention and PrometheusResources size Prometheus, the
+		// retention being a Prometheus duration (e.g. 15d). Default to the
+		// Preset ones, or the Prometheus ones if custom.
+		PrometheusRetention string
+		PrometheusResources corev1.ResourceRequirementsInput
+
+		// PrometheusPort is the port Prometheus serves on.
+		// Defaults to 9090.
+		PrometheusPort int
+
+		// PrometheusExtraScrapeConfigs are additional Prometheus scrape jobs,
+		// e.g. to scrape the challenges metrics. The "prometheus", "jaeger" and
+		// "perses" job names are reserved to scrape their own health.
+		// Each one declares the Destinations of its targets, Prometheus
+		// egress being denied elsewhere.
+		PrometheusExtraScrapeConfigs []parts.ScrapeConfig
+
+		// PrometheusAnnotationScrape scrapes the pods annotated with
+		// prometheus.io/scrape (e.g. by the challenges charts) of the allowed
+		// namespaces, without hand-written scrape configs. Prometheus is
+		// granted to discover them and to reach them, on any port.
+		PrometheusAnnotationScrape *parts.AnnotationScrapeArgs
+
+		// PrometheusAlertRules ships the default alerts on the Monitoring
+		// health (OTEL Collector exporter failures, scrape failures, nearly
+		// full PVCs, Jaeger down), evaluated by Prometheus or, as a
+		// PrometheusRule, by the Prometheus Operator of the cluster.
+		PrometheusAlertRules *parts.AlertRulesArgs
+
+		// PersesWaitsForPrometheus deploys Perses once Prometheus rolled out,
+		// rather than only its global datasource.
+		PersesWaitsForPrometheus bool
+
+		// PersesReplicas, PersesResources and PersesDisruptionBudget size
+		// Perses. Default to a single replica with the chart resources.
+		PersesReplicas         int
+		PersesResources        corev1.ResourceRequirementsInput
+		PersesDisruptionBudget *parts.DisruptionBudgetArgs
+
+		// PersesAccess turns on the Perses authentication, with the roles of
+		// the organizers and spectators. Defaults to anonymous access.
+		PersesAccess *parts.PersesAccessArgs
+
+		// PersesBootstrap awaits Perses to serve the global datasource in a
+		// Job, failing the update if it does not in time.
+		PersesBootstrap *parts.PersesBootstrapArgs
+
+		// PersesExtraValues are deep merged into the Perses chart values.
+		// The overrides of the values the component depends on are warned
+		// about, and the ones of the dashboard discovery label refused unless
+		// PersesForceExtraValues.
+		PersesExtraValues      map[string]any
+		PersesForceExtraValues bool
+
+		// DisableJaegerSPM turns off the Jaeger Service Performance Monitoring.
+		DisableJaegerSPM bool
+
+		// JaegerArchive turns on the Jaeger archive storage, for the traces
+		// pinned from the UI (e.g. cheating investigations) to survive the
+		// in-memory retention.
+		JaegerArchive *parts.JaegerArchiveArgs
+
+		// JaegerQuery and JaegerResources bound the load Jaeger accepts, e.g.
+		// from a dashboard searching over a long lookback. Default to the
+		// Jaeger limits, without resources.
+		JaegerQuery     *parts.JaegerQueryArgs
+		JaegerResources corev1.ResourceRequirementsInput
+
+		// JaegerMemoryMaxTraces is the most traces Jaeger keeps in memory,
+		// to fit its JaegerResources. Defaults to the Preset one.
+		JaegerMemoryMaxTraces int
+
+		// DependencyGraph computes the service graph metrics from the traces
+		// in the OTEL Collector (traces_service_graph_* series in Prometheus).
+		DependencyGraph bool
+
+		// Exemplars links the spanmetrics latency histograms to the trace
+		// IDs, stored by Prometheus along the samples, for the dashboards to
+		// jump from a latency bucket into the traces in Jaeger.
+		Exemplars bool
+
+		// TracesFailover spills the traces on the cold extract PVC while
+		// Jaeger is unavailable. Requires ColdExtract.
+		TracesFailover bool
+
+		// IngressPeers restricts the sources allowed to send telemetry to the
+		// OTEL Collector. If none set, every source is allowed.
+		IngressPeers []IngressPeer
+
+		// DenyAllIngress blocks every source from sending telemetry to the
+		// OTEL Collector, whatever the IngressPeers. Mostly for testing purposes.
+		DenyAllIngress bool
+
+		// BuildInfo of the program deploying the Monitoring, stamped on the
+		// resources and recorded in the monitoring-buildinfo ConfigMap.
+		BuildInfo *BuildInfo
+
+		// EventLog emits a Kubernetes Event in the Monitoring namespace each
+		// time it is deployed or reconfigured, describing the version and key
+		// settings. Note the API server garbage collects Events after its
+		// --event-ttl (defaults to 1h), so ship them to a long-term storage
+		// for auditing purposes.
+		EventLog bool
+
+		// NetworkFlowsConfigMap records the matrix of the flows the network
+		// policies allow in the monitoring-network-flows ConfigMap, for the
+		// cluster operators to review them without access to the stack.
+		NetworkFlowsConfigMap bool
+
+		// SpreadAcrossZones spreads the pods of the components across the
+		// zones when they run more than one replica.
+		SpreadAcrossZones bool
+
+		// ClusterDomain renders the endpoints and URLs fully-qualified in
+		// this cluster domain (e.g. cluster.local), for senders whose DNS
+		// search path does not resolve the short form (e.g. dnsPolicy=None).
+		// Defaults to the short form.
+		ClusterDomain string
+
+		// IPFamily of the Services, for IPv6 single-stack or dual-stack
+		// clusters. Defaults to the cluster ones.
+		IPFamily *parts.IPFamilyArgs
+
+		// Mesh annotates the pods of every part for the service mesh of the
+		// cluster (Istio or Linkerd), to keep them out of it or inject its
+		// sidecars. Defaults to no annotation.
+		Mesh *parts.MeshArgs
+
+		// OpenShift adapts the Monitoring to OpenShift. Opt-in.
+		OpenShift *OpenShiftArgs
+
+		// Hosts derives the hosts of the UIs from a base domain. Requires the
+		// OpenShift Routes, assigned by the router otherwise.
+		Hosts *HostsArgs
+
+		// GrafanaDatasources renders the Grafana provisioning of the
+		// Prometheus and Jaeger datasources, for an existing Grafana (e.g.
+		// out of the cluster) to query them. Opt-in.
+		GrafanaDatasources *GrafanaDatasourcesArgs
+
+		// Preset sizes the Monitoring for the expected load of the event,
+		// among PresetSmall, PresetMedium, PresetLarge and PresetCustom.
+		// It sets coherent resources, queues, retention and PVC size, each
+		// still overridable by its own argument.
+		// Defaults to PresetCustom, which sets nothing.
+		Preset string
+
+		// DevMode fits the Monitoring to a single-node lab cluster (e.g. k3d,
+		// kind): the PVC defaults to the local-path storage class and the
+		// ReadWriteOnce access mode, every part requests tiny resources,
+		// Jaeger keeps fewer traces in memory and the Prometheus probes are
+		// relaxed. Nothing else persists but the cold extract PVC, as in
+		// production.
+		// Not intended for production.
+		DevMode bool
+
+		// ConfigDriftAnnotations stamps the hash of the OTEL Collector and
+		// Prometheus configurations on their ConfigMaps, for the ones
+		// hot-patched in the cluster to be reported before an update reverts
+		// them (see the drift package).
+		ConfigDriftAnnotations bool
+
+		// Protect the stateful resources from being deleted, e.g. by a
+		// mistyped destroy during the event: the namespace, the cold extract
+		// PVC and the Jaeger archive one. They must be unprotected (then
+		// updated) before the teardown.
+		Protect bool
+
+		// PauseRollouts pauses the Deployments, such that the changes applied
+		// during a live event (e.g. a new configuration) are recorded but
+		// only rolled out once resumed (see the rollout package).
+		PauseRollouts bool
+
+		// MinReadySeconds a new pod must be ready for before its rollout goes
+		// on. Defaults to 0.
+		MinReadySeconds int
+
+		// FreezeWindows are the periods (e.g. the competition hours) during
+		// which the updates rolling the monitoring pods are refused, the
+		// preview failing on them: the pod templates and the ConfigMaps. Each
+		// is either an RFC3339 interval, e.g.
+		// "2026-10-17T08:00:00Z/2026-10-18T20:00:00Z", or a cron expression
+		// followed by the duration of the window, e.g. "0 8 * * SAT 36h"
+		// (with an optional CRON_TZ=<zone> prefix, UTC otherwise).
+		FreezeWindows []string
+
+		// FreezeOverride applies the updates within a freeze window anyway.
+		FreezeOverride bool
+
+		// deprecated are the warnings of the deprecated fields used.
+		deprecated []string
+
+		// freezeWindows are the FreezeWindows, once parsed.
+		freezeWindows []freezeWindow
+	}
+
+	// BuildInfo describes a build of the program, as set through ldflags.
+	BuildInfo struct {
+		Version string
+		Commit  string
+		Date    string
+		BuiltBy string
+	}
+
+	// IngressPeer is a source allowed to send telemetry to the OTEL Collector.
+	// Either CIDR or the labels could be set, not both.
+	IngressPeer struct {
+		// NamespaceLabels selects the namespaces allowed to send telemetry.
+		NamespaceLabels map[string]string
+
+		// PodLabels selects the pods allowed to send telemetry. If no
+		// NamespaceLabels are set, only the monitoring namespace is considered.
+		PodLabels map[string]string
+
+		// CIDR is an IP block allowed to send telemetry.
+		CIDR string
 	}
 )
 
 const (
+	defaultVersion = "dev"
+
+	devStorageClassName      = "local-path"
+	devPVCAccessMode         = "ReadWriteOnce"
+	devJaegerMemoryMaxTraces = 10000
+
+	// BuildInfoConfigMapName is the name of the ConfigMap containing the
+	// build info, in the Monitoring namespace.
+	BuildInfoConfigMapName = "monitoring-buildinfo"
+
 	defaultNetpolAPIServerTemplate = `
 apiVersion: cilium.io/v2
 kind: CiliumNetworkPolicy
@@ -99,11 +557,16 @@ func NewMonitoring(
 	if err := ctx.RegisterComponentResource("ctfer-io:monitoring:monitoring", name, mon, opts...); err != nil {
 		return nil, err
 	}
+	for _, warning := range args.deprecated {
+		if err := ctx.Log.Warn(warning, &pulumi.LogArgs{Resource: mon}); err != nil {
+			return nil, err
+		}
+	}
 	opts = append(opts, pulumi.Parent(mon))
 	if err := mon.provision(ctx, args, opts...); err != nil {
 		return nil, err
 	}
-	if err := mon.outputs(ctx); err != nil {
+	if err := mon.outputs(ctx, args); err != nil {
 		return nil, err
 	}
 
@@ -114,6 +577,30 @@ func (*Monitoring) defaults(args *MonitoringArgs) *MonitoringArgs {
 	if args == nil {
 		args = &MonitoringArgs{}
 	}
+	normalizeArgs(args, deprecatedArgs)
+
+	if args.BuildInfo == nil {
+		args.BuildInfo = &BuildInfo{}
+	}
+	if args.BuildInfo.Version == "" {
+		args.BuildInfo.Version = defaultVersion
+	}
+
+	if args.DevMode {
+		devDefaults(args)
+	}
+	if args.Preset == "" {
+		args.Preset = PresetCustom
+	}
+	presetDefaults(args)
+
+	if args.Hosts != nil {
+		hostsDefaults(args.Hosts)
+	}
+
+	if args.Protect && args.JaegerArchive != nil && args.JaegerArchive.Badger != nil {
+		args.JaegerArchive.Badger.Protect = true
+	}
 
 	args.netpolToAPIServerTemplate = pulumi.String(defaultNetpolAPIServerTemplate).ToStringOutput()
 	if args.NetpolAPIServerTemplate != nil {
@@ -129,7 +616,90 @@ func (*Monitoring) defaults(args *MonitoringArgs) *MonitoringArgs {
 	return args
 }
 
+// devDefaults switches the defaults to the lab ones, leaving the values set
+// untouched.
+func devDefaults(args *MonitoringArgs) {
+	if args.StorageClassName == nil {
+		args.StorageClassName = pulumi.String(devStorageClassName)
+	} else {
+		args.StorageClassName = args.StorageClassName.ToStringOutput().ApplyT(func(scn string) string {
+			if scn == "" {
+				return devStorageClassName
+			}
+			return scn
+		}).(pulumi.StringOutput)
+	}
+
+	// Literal access modes are kept literal, for the compatibility matrix to
+	// check them
+	modes, literal := literalAccessModes(args.PVCAccessModes)
+	switch {
+	case args.PVCAccessModes == nil || (literal && unsetAccessModes(modes)):
+		args.PVCAccessModes = pulumi.ToStringArray([]string{devPVCAccessMode})
+	case !literal:
+		args.PVCAccessModes = args.PVCAccessModes.ToStringArrayOutput().ApplyT(func(slc []string) []string {
+			if unsetAccessModes(slc) {
+				return []string{devPVCAccessMode}
+			}
+			return slc
+		}).(pulumi.StringArrayOutput)
+	}
+
+	// Every part requests tiny resources, Jaeger keeping fewer traces to
+	// fit them
+	for _, res := range []*corev1.ResourceRequirementsInput{
+		&args.OTELResources,
+		&args.JaegerResources,
+		&args.PrometheusResources,
+		&args.PersesResources,
+	} {
+		if *res == nil {
+			*res = devResources()
+		}
+	}
+	if args.JaegerMemoryMaxTraces == 0 {
+		args.JaegerMemoryMaxTraces = devJaegerMemoryMaxTraces
+	}
+}
+
+// devResources returns the resources the parts request in dev mode.
+func devResources() corev1.ResourceRequirementsArgs {
+	return corev1.ResourceRequirementsArgs{
+		Requests: pulumi.StringMap{
+			"cpu":    pulumi.String("10m"),
+			"memory": pulumi.String("64Mi"),
+		},
+	}
+}
+
+// unsetAccessModes tells whether the access modes are left to the default.
+func unsetAccessModes(slc []string) bool {
+	return len(slc) == 0 || (len(slc) == 1 && slc[0] == "")
+}
+
 func (mon *Monitoring) check(args *MonitoringArgs) error {
+	// First-level checks
+	if _, ok := presets[args.Preset]; !ok && args.Preset != PresetCustom {
+		return errors.Errorf("unsupported preset %s, must be %s, %s, %s or %s", args.Preset, PresetSmall, PresetMedium, PresetLarge, PresetCustom)
+	}
+	if err := checkCompatibility(args); err != nil {
+		return err
+	}
+	if err := checkIngressPeers(args.IngressPeers); err != nil {
+		return err
+	}
+	if err := checkHosts(args); err != nil {
+		return err
+	}
+	if err := checkGrafanaDatasources(args.GrafanaDatasources); err != nil {
+		return err
+	}
+	windows, err := parseFreezeWindows(args.FreezeWindows)
+	if err != nil {
+		return err
+	}
+	args.freezeWindows = windows
+
 	wg := &sync.WaitGroup{}
 	checks := 1 // number of checks to perform
 	wg.Add(checks)
@@ -161,59 +731,184 @@ func (mon *Monitoring) provision(
 	args *MonitoringArgs,
 	opts ...pulumi.ResourceOption,
 ) (err error) {
+	// The flows the network policies allow, all built from them
+	mon.flows = networkFlows(args)
+
+	// Refuse the updates rolling the pods within a freeze window
+	if w, end, ok := activeFreezeWindow(args.freezeWindows, time.Now()); ok {
+		if args.FreezeOverride {
+			if err := ctx.Log.Warn(fmt.Sprintf("freeze window %q is active until %s, overridden", w.spec, end.Format(time.RFC3339)), nil); err != nil {
+				return err
+			}
+		} else {
+			if err := ctx.Log.Info(fmt.Sprintf("freeze window %q is active until %s, refusing the updates rolling the pods", w.spec, end.Format(time.RFC3339)), nil); err != nil {
+				return err
+			}
+			hook, err := ctx.RegisterResourceHook(freezeHookName, freezeHook(w, end), &pulumi.ResourceHookOptions{
+				OnDryRun: true,
+			})
+			if err != nil {
+				return err
+			}
+			opts = append(slices.Clone(opts), pulumi.ResourceHooks(&pulumi.ResourceHookBinding{
+				BeforeUpdate: []*pulumi.ResourceHook{hook},
+			}))
+		}
+	}
+
 	// Kubernetes namespace
 	mon.ns, err = parts.NewNamespace(ctx, "monitoring", &parts.NamespaceArgs{
-		Name: pulumi.String("monitoring"),
+		Name:                  pulumi.String("monitoring"),
+		SkipPodSecurityLabels: args.OpenShift != nil,
+		Protect:               args.Protect,
 		AdditionalLabels: pulumi.StringMap{
-			"app.kubernetes.io/part-of": pulumi.String("monitoring"),
-			"ctfer.io/stack-name":       pulumi.String(ctx.Stack()),
+			"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+			"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+			"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 		},
 	}, opts...)
 	if err != nil {
 		return
 	}
 
+	// Record the build info for operators to know which version deployed it
+	mon.buildinfo, err = corev1.NewConfigMap(ctx, "buildinfo", &corev1.ConfigMapArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Name:      pulumi.String(BuildInfoConfigMapName),
+			Namespace: mon.ns.Name,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("buildinfo"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Data: pulumi.StringMap{
+			"version": pulumi.String(args.BuildInfo.Version),
+			"commit":  pulumi.String(args.BuildInfo.Commit),
+			"date":    pulumi.String(args.BuildInfo.Date),
+			"builtBy": pulumi.String(args.BuildInfo.BuiltBy),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// Trail the deployment settings for auditing purposes. The Event is replaced
+	// whenever they change such that its creation timestamp records it.
+	if args.EventLog {
+		mon.lifecycle, err = corev1.NewEvent(ctx, "lifecycle", &corev1.EventArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: mon.ns.Name,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("lifecycle"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			InvolvedObject: corev1.ObjectReferenceArgs{
+				ApiVersion: pulumi.String("v1"),
+				Kind:       pulumi.String("ConfigMap"),
+				Name:       mon.buildinfo.Metadata.Name(),
+				Namespace:  mon.ns.Name,
+			},
+			Type:    pulumi.String("Normal"),
+			Reason:  pulumi.String("Deployed"),
+			Message: pulumi.String(lifecycleMessage(args)),
+			Source: corev1.EventSourceArgs{
+				Component: pulumi.String("monitoring"),
+			},
+			ReportingComponent: pulumi.String("ctfer.io/monitoring"),
+			ReportingInstance:  pulumi.String(ctx.Stack()),
+		}, append(slices.Clone(opts), pulumi.ReplaceOnChanges([]string{"message"}))...)
+		if err != nil {
+			return
+		}
+	}
+
 	// Create parts of the component
 	// => Prometheus, at the root of every others
-	mon.prom, err = parts.NewPrometheus(ctx, "prometheus", &parts.PrometheusArgs{
-		Namespace: mon.ns.Name,
-		Registry:  args.Registry,
-	}, opts...)
+	promArgs := prometheusArgs(args)
+	promArgs.Namespace = mon.ns.Name
+	mon.prom, err = parts.NewPrometheus(ctx, "prometheus", promArgs, opts...)
 	if err != nil {
 		return
 	}
 
 	// => Perse for dashboards
-	mon.perses, err = parts.NewPerses(ctx, "perses", &parts.PersesArgs{
-		Namespace:     mon.ns.Name,
-		Registry:      args.Registry,
-		PrometheusURL: mon.prom.URL,
-	}, opts...)
+	persesArgs := &parts.PersesArgs{
+		Namespace:               mon.ns.Name,
+		MonitoringVersion:       args.BuildInfo.Version,
+		Registry:                args.Registry,
+		PrometheusURL:           mon.prom.URL,
+		PrometheusDependsOn:     []pulumi.Resource{mon.prom},
+		ChartWaitsForPrometheus: args.PersesWaitsForPrometheus,
+		DisableDatasource:       args.PrometheusAgentMode, // could not be queried
+		Replicas:                args.PersesReplicas,
+		Resources:               args.PersesResources,
+		DisruptionBudget:        args.PersesDisruptionBudget,
+		Access:                  args.PersesAccess,
+		Bootstrap:               args.PersesBootstrap,
+		Mesh:                    args.Mesh,
+		ExtraValues:             args.PersesExtraValues,
+		ForceExtraValues:        args.PersesForceExtraValues,
+	}
+	if args.PrometheusRemoteWriteBasicAuth {
+		persesArgs.PrometheusBasicAuth = &parts.BasicAuthArgs{
+			Username:           parts.PrometheusBasicAuthUsername,
+			PasswordSecretName: mon.prom.BasicAuthSecretName.Elem(),
+		}
+		if s := args.PrometheusRemoteWriteBasicAuthSecret; s != nil {
+			persesArgs.PrometheusBasicAuth.PasswordSecretKey = s.Key
+		}
+	}
+	mon.perses, err = parts.NewPerses(ctx, "perses", persesArgs, opts...)
 	if err != nil {
 		return
 	}
 
 	// => Jaeger to analyze the state of the system
 	mon.jaeger, err = parts.NewJaeger(ctx, "jaeger", &parts.JaegerArgs{
-		Namespace:     mon.ns.Name,
-		PrometheusURL: mon.prom.URL,
-		Registry:      args.Registry,
+		Namespace:                mon.ns.Name,
+		MonitoringVersion:        args.BuildInfo.Version,
+		PrometheusURL:            mon.prom.URL,
+		DisableSPM:               args.DisableJaegerSPM,
+		Archive:                  args.JaegerArchive,
+		Query:                    args.JaegerQuery,
+		Resources:                args.JaegerResources,
+		MemoryMaxTraces:          args.JaegerMemoryMaxTraces,
+		Rollout:                  rolloutArgs(args),
+		Registry:                 args.Registry,
+		PublishNotReadyAddresses: args.PublishNotReadyAddresses,
+		SpreadAcrossZones:        args.SpreadAcrossZones,
+		ClusterDomain:            args.ClusterDomain,
+		IPFamily:                 args.IPFamily,
+		Mesh:                     args.Mesh,
+		PlatformIDs:              args.OpenShift != nil,
 	}, opts...)
 	if err != nil {
 		return
 	}
 
 	// => OTEL Collector to collect all signals
-	mon.otel, err = parts.NewOtelCollector(ctx, "otel", &parts.OtelCollectorArgs{
-		Namespace:        mon.ns.Name,
-		JaegerURL:        mon.jaeger.URL,
-		PrometheusURL:    mon.prom.URL,
-		ColdExtract:      args.ColdExtract,
-		Registry:         args.Registry,
-		StorageClassName: args.StorageClassName,
-		StorageSize:      args.StorageSize,
-		PVCAccessModes:   args.PVCAccessModes,
-	}, opts...)
+	otelArgs := otelCollectorArgs(args)
+	otelArgs.Namespace = mon.ns.Name
+	otelArgs.JaegerURL = mon.jaeger.URL
+	otelArgs.PrometheusURL = mon.prom.URL
+	if otelArgs.PrometheusBasicAuth != nil {
+		otelArgs.PrometheusBasicAuth.PasswordSecretName = mon.prom.BasicAuthSecretName.Elem()
+	}
+	if args.OTELAwaitBackends {
+		if args.PauseRollouts {
+			if err = ctx.Log.Warn("the OTEL Collector could not await the paused Jaeger and Prometheus rollouts, created along them", &pulumi.LogArgs{Resource: mon}); err != nil {
+				return
+			}
+		} else {
+			otelArgs.BackendsDependsOn = []pulumi.Resource{mon.jaeger, mon.prom}
+		}
+	}
+	mon.otel, err = parts.NewOtelCollector(ctx, "otel", otelArgs, opts...)
 	if err != nil {
 		return
 	}
@@ -223,8 +918,9 @@ func (mon *Monitoring) provision(
 	mon.inotelntp, err = netwv1.NewNetworkPolicy(ctx, "in-otel-ntp", &netwv1.NetworkPolicyArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Labels: pulumi.StringMap{
-				"app.kubernetes.io/part-of": pulumi.String("monitoring"),
-				"ctfer.io/stack-name":       pulumi.String(ctx.Stack()),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 			Namespace: mon.ns.Name,
 		},
@@ -235,28 +931,50 @@ func (mon *Monitoring) provision(
 			PodSelector: metav1.LabelSelectorArgs{
 				MatchLabels: mon.otel.PodLabels,
 			},
-			Ingress: netwv1.NetworkPolicyIngressRuleArray{
-				// * -> OTEL Collector
-				netwv1.NetworkPolicyIngressRuleArgs{
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parsePort(mon.otel.Endpoint),
-						},
-					},
-				},
-			},
+			// * -> OTEL Collector, or only the ingress peers
+			Ingress: parts.IngressRules(mon.flows, "in-otel-ntp", mon.partSelectors()),
 		},
 	}, opts...)
 	if err != nil {
 		return
 	}
 
-	// Allow OTEL Collector to send data to Jaeger and Prometheus.
+	// Isolated from in-otel-ntp such that an agent Prometheus keeps scraping
+	// the metrics whatever the ingress peers.
+	if args.PrometheusAgentMode {
+		mon.inotelscrapentp, err = netwv1.NewNetworkPolicy(ctx, "in-otel-scrape-ntp", &netwv1.NetworkPolicyArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+				Namespace: mon.ns.Name,
+			},
+			Spec: netwv1.NetworkPolicySpecArgs{
+				PolicyTypes: pulumi.ToStringArray([]string{
+					"Ingress",
+				}),
+				PodSelector: metav1.LabelSelectorArgs{
+					MatchLabels: mon.otel.PodLabels,
+				},
+				// Prometheus -> OTEL Collector
+				Ingress: parts.IngressRules(mon.flows, "in-otel-scrape-ntp", mon.partSelectors()),
+			},
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
+	// Allow OTEL Collector to send data to Jaeger and Prometheus, and to the
+	// Kafka brokers if streaming to them.
 	mon.otelntp, err = netwv1.NewNetworkPolicy(ctx, "otel-ntp", &netwv1.NetworkPolicyArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Labels: pulumi.StringMap{
-				"app.kubernetes.io/part-of": pulumi.String("monitoring"),
-				"ctfer.io/stack-name":       pulumi.String(ctx.Stack()),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 			Namespace: mon.ns.Name,
 		},
@@ -267,48 +985,8 @@ func (mon *Monitoring) provision(
 			PodSelector: metav1.LabelSelectorArgs{
 				MatchLabels: mon.otel.PodLabels,
 			},
-			Egress: netwv1.NetworkPolicyEgressRuleArray{
-				// OTEL Collector -> Prometheus
-				netwv1.NetworkPolicyEgressRuleArgs{
-					To: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.prom.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.prom.URL),
-						},
-					},
-				},
-				// OTEL Collector -> Jaeger
-				netwv1.NetworkPolicyEgressRuleArgs{
-					To: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.jaeger.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.jaeger.URL),
-						},
-					},
-				},
-			},
+			// OTEL Collector -> Prometheus, Jaeger and the Kafka brokers
+			Egress: parts.EgressRules(mon.flows, "otel-ntp", mon.partSelectors()),
 		},
 	}, opts...)
 	if err != nil {
@@ -319,35 +997,32 @@ func (mon *Monitoring) provision(
 	mon.prsToAPI, err = yamlv2.NewConfigGroup(ctx, "perses-to-apiserver-netpol", &yamlv2.ConfigGroupArgs{
 		Yaml: pulumi.All(args.netpolToAPIServerTemplate, mon.ns.Name, mon.perses.PodLabels).
 			ApplyT(func(all []any) (string, error) {
-				persesToAPIServerTemplate := all[0].(string)
-				namespace := all[1].(string)
-				podLabels := all[2].(map[string]string)
-
-				tmpl, _ := template.New("perses-to-apiserver").
-					Funcs(sprig.FuncMap()).
-					Parse(persesToAPIServerTemplate)
-
-				buf := &bytes.Buffer{}
-				if err := tmpl.Execute(buf, map[string]any{
-					"Name":      "allow-perses-to-apiserver-" + ctx.Stack(),
-					"Namespace": namespace,
-					"PodLabels": podLabels,
-				}); err != nil {
-					return "", err
-				}
-				return buf.String(), nil
+				return renderNetpolToAPIServer(
+					all[0].(string),
+					"allow-perses-to-apiserver-"+ctx.Stack(),
+					all[1].(string),
+					all[2].(map[string]string),
+				)
 			}).(pulumi.StringOutput),
 	}, opts...)
 	if err != nil {
 		return
 	}
 
-	// Allow Jaeger to receive data from OTEL Collector and read data from Prometheus.
+	if args.PersesBootstrap != nil {
+		if err = mon.provisionPersesBootstrap(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
+	// Allow Jaeger to receive data from OTEL Collector, be scraped by Prometheus
+	// and read data from Prometheus.
 	mon.jgrntp, err = netwv1.NewNetworkPolicy(ctx, "jaeger-ntp", &netwv1.NetworkPolicyArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Labels: pulumi.StringMap{
-				"app.kubernetes.io/part-of": pulumi.String("monitoring"),
-				"ctfer.io/stack-name":       pulumi.String(ctx.Stack()),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 			Namespace: mon.ns.Name,
 		},
@@ -359,50 +1034,10 @@ func (mon *Monitoring) provision(
 			PodSelector: metav1.LabelSelectorArgs{
 				MatchLabels: mon.jaeger.PodLabels,
 			},
-			Ingress: netwv1.NetworkPolicyIngressRuleArray{
-				// OTEL Collector -> Jaeger
-				netwv1.NetworkPolicyIngressRuleArgs{
-					From: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.otel.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.jaeger.URL),
-						},
-					},
-				},
-			},
-			Egress: netwv1.NetworkPolicyEgressRuleArray{
-				// Jaeger -> Prometheus
-				netwv1.NetworkPolicyEgressRuleArgs{
-					To: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.prom.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.prom.URL),
-						},
-					},
-				},
-			},
+			// OTEL Collector -> Jaeger, Prometheus -> Jaeger (metrics)
+			Ingress: parts.IngressRules(mon.flows, "jaeger-ntp", mon.partSelectors()),
+			// Jaeger -> Prometheus
+			Egress: parts.EgressRules(mon.flows, "jaeger-ntp", mon.partSelectors()),
 		},
 	}, opts...)
 	if err != nil {
@@ -410,11 +1045,14 @@ func (mon *Monitoring) provision(
 	}
 
 	// Allow Prometheus to receive traffic from the OTEL Collector and Jaeger.
+	// It is the primary guard of the remote write or OTLP receiver, whatever
+	// the basic auth.
 	mon.promntp, err = netwv1.NewNetworkPolicy(ctx, "prom-ntp", &netwv1.NetworkPolicyArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Labels: pulumi.StringMap{
-				"app.kubernetes.io/part-of": pulumi.String("monitoring"),
-				"ctfer.io/stack-name":       pulumi.String(ctx.Stack()),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 			Namespace: mon.ns.Name,
 		},
@@ -425,113 +1063,327 @@ func (mon *Monitoring) provision(
 			PodSelector: metav1.LabelSelectorArgs{
 				MatchLabels: mon.prom.PodLabels,
 			},
-			Ingress: netwv1.NetworkPolicyIngressRuleArray{
-				// OTEL Collector -> Prometheus
-				netwv1.NetworkPolicyIngressRuleArgs{
-					From: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.otel.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.prom.URL),
-						},
-					},
-				},
-				// Jaeger -> Prometheus
-				netwv1.NetworkPolicyIngressRuleArgs{
-					From: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.jaeger.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.prom.URL),
-						},
-					},
-				},
-				// Perses -> Prometheus
-				netwv1.NetworkPolicyIngressRuleArgs{
-					From: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": mon.ns.Name,
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: mon.perses.PodLabels,
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port: parseURLPort(mon.prom.URL),
-						},
-					},
-				},
+			// OTEL Collector, Jaeger and Perses -> Prometheus
+			Ingress: parts.IngressRules(mon.flows, "prom-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// Allow Prometheus to scrape the targets of its jobs, where they are
+	// declared to be.
+	mon.promegressntp, err = netwv1.NewNetworkPolicy(ctx, "prom-egress-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+			Namespace: mon.ns.Name,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Egress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: mon.prom.PodLabels,
+			},
+			// Prometheus -> scrape targets
+			Egress: parts.EgressRules(mon.flows, "prom-egress-ntp", mon.partSelectors()),
 		},
 	}, opts...)
+	if err != nil {
+		return
+	}
 
-	return
+	// The annotated pods are discovered through the API server
+	if args.PrometheusAnnotationScrape != nil {
+		mon.promToAPI, err = yamlv2.NewConfigGroup(ctx, "prometheus-to-apiserver-netpol", &yamlv2.ConfigGroupArgs{
+			Yaml: pulumi.All(args.netpolToAPIServerTemplate, mon.ns.Name, mon.prom.PodLabels).
+				ApplyT(func(all []any) (string, error) {
+					return renderNetpolToAPIServer(
+						all[0].(string),
+						"allow-prometheus-to-apiserver-"+ctx.Stack(),
+						all[1].(string),
+						all[2].(map[string]string),
+					)
+				}).(pulumi.StringOutput),
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
+	if args.LogShipper != nil {
+		if err = mon.provisionLogShipper(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
+	if args.OpenShift != nil {
+		if err = mon.provisionOpenShift(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
+	if err = mon.grafanaDatasources(ctx, args, opts...); err != nil {
+		return
+	}
+
+	return mon.networkFlowsMatrix(ctx, args, opts...)
 }
 
-func (mon *Monitoring) outputs(ctx *pulumi.Context) (err error) {
+func (mon *Monitoring) outputs(ctx *pulumi.Context, args *MonitoringArgs) (err error) {
 	mon.Namespace = mon.ns.Name
+	mon.Version = pulumi.String(args.BuildInfo.Version).ToStringOutput()
 	mon.OTEL.Endpoint = mon.otel.Endpoint
 	mon.OTEL.ColdExtractPVCName = mon.otel.ColdExtractPVCName
 	mon.OTEL.PodLabels = mon.otel.PodLabels
+	mon.OTEL.ColdExtractLayout = mon.otel.ColdExtractLayout
+	mon.OTEL.Pipelines = mon.otel.Pipelines
+	mon.OTEL.PodEndpoints = mon.otel.PodEndpoints
+	mon.OTEL.CASecretName = mon.otel.CASecretName
+	mon.PrometheusURL = mon.prom.URL
+	mon.DashboardDiscovery = mon.perses.Discovery
+	mon.LogShipper.Namespace = pulumi.String("").ToStringOutput()
+	mon.LogShipper.PodLabels = pulumi.StringMap{}.ToStringMapOutput()
+	ready := []any{
+		mon.otel.Ready,
+		mon.jaeger.Ready,
+		mon.prom.Ready,
+		mon.perses.Ready,
+	}
+	if mon.shipper != nil {
+		mon.LogShipper.Namespace = mon.shipper.Namespace
+		mon.LogShipper.PodLabels = mon.shipper.PodLabels
+		ready = append(ready, mon.shipper.Ready)
+	}
+	mon.Hosts = pulumi.StringMap{}.ToStringMapOutput()
+	if args.Hosts != nil {
+		mon.Hosts = pulumi.ToStringMap(args.Hosts.hosts()).ToStringMapOutput()
+	}
+	mon.Summary = mon.summary(args)
+	mon.Ready = pulumi.All(ready...).ApplyT(func(all []any) bool {
+		for _, r := range all {
+			if !r.(bool) {
+				return false
+			}
+		}
+		return true
+	}).(pulumi.BoolOutput)
 
 	return ctx.RegisterResourceOutputs(mon, pulumi.Map{
 		"namespace":               mon.Namespace,
 		"otel.endpoint":           mon.OTEL.Endpoint,
 		"otel.coldExtractPVCName": mon.OTEL.ColdExtractPVCName,
 		"otel.podLabels":          mon.OTEL.PodLabels,
+		"otel.coldExtractLayout":  mon.OTEL.ColdExtractLayout,
+		"otel.pipelines":          mon.OTEL.Pipelines,
+		"otel.podEndpoints":       mon.OTEL.PodEndpoints,
+		"otel.caSecretName":       mon.OTEL.CASecretName,
+		"logShipper.namespace":    mon.LogShipper.Namespace,
+		"logShipper.podLabels":    mon.LogShipper.PodLabels,
+		"prometheus.url":          mon.PrometheusURL,
+		"dashboardDiscovery":      mon.DashboardDiscovery.ToMap(),
+		"version":                 mon.Version,
+		"summary":                 mon.Summary,
+		"ready":                   mon.Ready,
+		"networkFlows":            mon.NetworkFlows,
+		"hosts":                   mon.Hosts,
+		"grafanaDatasources":      mon.GrafanaDatasources,
 	})
 }
 
-// parsePort cuts the input endpoint to return its port.
-// Example: some.thing:port -> port
-func parsePort(edp pulumi.StringInput) pulumi.IntOutput {
-	return edp.ToStringOutput().ApplyT(func(edp string) (int, error) {
-		_, pStr, _ := strings.Cut(edp, ":")
-		p, err := strconv.Atoi(pStr)
-		if err != nil {
-			return 0, errors.Wrapf(err, "parsing endpoint %s for port", edp)
-		}
-		return p, nil
-	}).(pulumi.IntOutput)
+// rolloutArgs maps the arguments to the rollouts of the Deployments, or nil
+// if they roll out as Kubernetes defaults to.
+func rolloutArgs(args *MonitoringArgs) *parts.RolloutArgs {
+	if !args.PauseRollouts && args.MinReadySeconds == 0 {
+		return nil
+	}
+	return &parts.RolloutArgs{
+		Paused:          args.PauseRollouts,
+		MinReadySeconds: args.MinReadySeconds,
+	}
 }
 
-// parseURLPort parses the input endpoint formatted as a URL to return its port.
-// Example: http://some.thing:port -> port
-func parseURLPort(edp pulumi.StringOutput) pulumi.IntOutput {
-	return edp.ToStringOutput().ApplyT(func(edp string) (int, error) {
-		u, err := url.Parse(edp)
-		if err != nil {
-			return 0, errors.Wrapf(err, "parsing endpoint %s as a URL", edp)
+// prometheusArgs maps the arguments to the Prometheus ones, but for the
+// namespace only known once deployed. The enabled parts are scraped along
+// the extra scrape configs.
+func prometheusArgs(args *MonitoringArgs) *parts.PrometheusArgs {
+	return &parts.PrometheusArgs{
+		MonitoringVersion:          args.BuildInfo.Version,
+		Registry:                   args.Registry,
+		PublishNotReadyAddresses:   args.PublishNotReadyAddresses,
+		AgentMode:                  args.PrometheusAgentMode,
+		AdminAPI:                   args.PrometheusAdminAPI,
+		ExemplarStorage:            args.Exemplars,
+		ConfigHashAnnotation:       args.ConfigDriftAnnotations,
+		Rollout:                    rolloutArgs(args),
+		QueryLog:                   args.PrometheusQueryLog,
+		QueryTimeout:               args.PrometheusQueryTimeout,
+		QueryMaxConcurrency:        args.PrometheusQueryMaxConcurrency,
+		RemoteWriteReceiver:        !args.PrometheusOTLPIngestion && !args.PrometheusAgentMode, // the OTEL Collector pushes the metrics, but to an agent
+		OTLPReceiver:               args.PrometheusOTLPIngestion,
+		RemoteWriteBasicAuth:       args.PrometheusRemoteWriteBasicAuth,
+		RemoteWriteBasicAuthSecret: args.PrometheusRemoteWriteBasicAuthSecret,
+		RemoteWriteURLs:            args.PrometheusRemoteWriteURLs,
+		ScrapeTargets:              scrapeTargets(args),
+		ExtraScrapeConfigs:         args.PrometheusExtraScrapeConfigs,
+		AnnotationScrape:           args.PrometheusAnnotationScrape,
+		SpreadAcrossZones:          args.SpreadAcrossZones,
+		ClusterDomain:              args.ClusterDomain,
+		IPFamily:                   args.IPFamily,
+		Mesh:                       args.Mesh,
+		Port:                       args.PrometheusPort,
+		Resources:                  args.PrometheusResources,
+		Retention:                  args.PrometheusRetention,
+		RelaxedProbes:              args.DevMode,
+		AlertRules:                 args.PrometheusAlertRules,
+	}
+}
+
+// scrapeTargets are the metrics endpoints of the enabled parts, such that
+// the Prometheus scrape configuration follows them. Jaeger and Perses are
+// always deployed, the OTEL Collector is scraped by an agent Prometheus.
+func scrapeTargets(args *MonitoringArgs) []parts.ScrapeTarget {
+	sts := []parts.ScrapeTarget{
+		parts.JaegerScrapeTarget(),
+		parts.PersesScrapeTarget(),
+	}
+	if args.PrometheusAgentMode {
+		sts = append(sts, parts.OtelScrapeTarget())
+	}
+	return sts
+}
+
+// prometheusScrapeConfigs returns the scrape jobs of Prometheus but its
+// self-scraping one, i.e. those of the parts, the extra ones then the
+// annotated pods one.
+func prometheusScrapeConfigs(args *MonitoringArgs) []parts.ScrapeConfig {
+	scs := []parts.ScrapeConfig{}
+	for _, st := range scrapeTargets(args) {
+		scs = append(scs, st.ScrapeConfig())
+	}
+	scs = append(scs, args.PrometheusExtraScrapeConfigs...)
+	if args.PrometheusAnnotationScrape != nil {
+		scs = append(scs, args.PrometheusAnnotationScrape.ScrapeConfig())
+	}
+	return scs
+}
+
+// otelCollectorArgs maps the arguments to the OTEL Collector ones, but for
+// the namespace, URLs and Prometheus password Secret only known once deployed.
+func otelCollectorArgs(args *MonitoringArgs) *parts.OtelCollectorArgs {
+	otelArgs := &parts.OtelCollectorArgs{
+		MonitoringVersion:    args.BuildInfo.Version,
+		ColdExtract:          args.ColdExtract,
+		ProtectPVC:           args.Protect,
+		DependencyGraph:      args.DependencyGraph,
+		Exemplars:            args.Exemplars,
+		TracesFailover:       args.TracesFailover,
+		TenantRouting:        args.ColdExtractTenantRouting,
+		Registry:             args.Registry,
+		StorageClassName:     args.StorageClassName,
+		StorageSize:          args.StorageSize,
+		PVCAccessModes:       args.PVCAccessModes,
+		ExporterRetry:        args.ExporterRetry,
+		Replicas:             args.OTELReplicas,
+		SpreadAcrossZones:    args.SpreadAcrossZones,
+		ReceiverTLS:          args.OTELReceiverTLS,
+		PlatformIDs:          args.OpenShift != nil, // assigned by the SCC
+		StatsdReceiver:       args.OTELStatsdReceiver,
+		SyslogReceiver:       args.OTELSyslogReceiver,
+		Ports:                args.OTELPorts,
+		Redaction:            args.OTELRedaction,
+		Kafka:                args.OTELKafka,
+		MetricsConversion:    args.OTELMetricsConversion,
+		LogsMetrics:          args.OTELLogsMetrics,
+		ConfigHashAnnotation: args.ConfigDriftAnnotations,
+		Image:                args.OTELCollectorImage,
+		Components:           args.OTELCollectorComponents,
+		ValidateConfig:       args.OTELValidateConfig,
+		Rollout:              rolloutArgs(args),
+		ClusterDomain:        args.ClusterDomain,
+		IPFamily:             args.IPFamily,
+		Mesh:                 args.Mesh,
+		Resources:            args.OTELResources,
+		QueueSize:            args.OTELQueueSize,
+		HeadSamplingPercent:  args.OTELHeadSamplingPercent,
+		IngestionQuotas:      args.OTELIngestionQuotas,
+		RemoteWriteWAL:       args.OTELRemoteWriteWAL,
+		MemoryLimitPercent:   args.OTELMemoryLimitPercent,
+		PrometheusOTLP:       args.PrometheusOTLPIngestion,
+		PrometheusScrape:     args.PrometheusAgentMode,
+	}
+	if args.PrometheusRemoteWriteBasicAuth {
+		otelArgs.PrometheusBasicAuth = &parts.BasicAuthArgs{
+			Username: parts.PrometheusBasicAuthUsername,
 		}
-		p, err := strconv.Atoi(u.Port())
-		if err != nil {
-			return 0, errors.Wrapf(err, "parsing endpoint %s for port", edp)
+		if s := args.PrometheusRemoteWriteBasicAuthSecret; s != nil {
+			otelArgs.PrometheusBasicAuth.PasswordSecretKey = s.Key
+		}
+	}
+	return otelArgs
+}
+
+// lifecycleMessage describes the version and key settings of a deployment.
+func lifecycleMessage(args *MonitoringArgs) string {
+	version := args.BuildInfo.Version
+	if args.BuildInfo.Commit != "" {
+		version += " (" + args.BuildInfo.Commit + ")"
+	}
+	return fmt.Sprintf("Deployed monitoring %s: cold extract %s, prometheus agent mode %s, jaeger SPM %s",
+		version,
+		enabled(args.ColdExtract),
+		enabled(args.PrometheusAgentMode),
+		enabled(!args.DisableJaegerSPM),
+	)
+}
+
+func enabled(b bool) string {
+	if b {
+		return "enabled"
+	}
+	return "disabled"
+}
+
+// checkIngressPeers validates the ingress peers could be turned into
+// NetworkPolicy peers.
+func checkIngressPeers(peers []IngressPeer) (merr error) {
+	for i, peer := range peers {
+		hasLabels := len(peer.NamespaceLabels) != 0 || len(peer.PodLabels) != 0
+		switch {
+		case peer.CIDR != "" && hasLabels:
+			merr = multierr.Append(merr, fmt.Errorf("ingress peer %d: cidr and labels are mutually exclusive", i))
+		case peer.CIDR != "":
+			if err := parts.CheckCIDR(peer.CIDR); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "ingress peer %d", i))
+			}
+		case !hasLabels:
+			merr = multierr.Append(merr, fmt.Errorf("ingress peer %d: neither cidr nor labels are set", i))
 		}
-		return p, nil
-	}).(pulumi.IntOutput)
+	}
+	return
+}
+
+// renderNetpolToAPIServer renders the NetworkPolicy template granting the
+// selected pods access to the API server.
+func renderNetpolToAPIServer(netpolTemplate, name, namespace string, podLabels map[string]string) (string, error) {
+	tmpl, err := template.New("to-apiserver").
+		Funcs(sprig.FuncMap()).
+		Parse(netpolTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, map[string]any{
+		"Name":      name,
+		"Namespace": namespace,
+		"PodLabels": podLabels,
+	}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
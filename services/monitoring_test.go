@@ -0,0 +1,1922 @@
+package services
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+	"github.com/ctfer-io/monitoring/pkg/drift"
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+func Test_U_Monitoring_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *MonitoringArgs
+		ExpectErr bool
+	}{
+		"defaults": {
+			Args:      &MonitoringArgs{},
+			ExpectErr: false,
+		},
+		"agent-mode-with-spm": {
+			Args: &MonitoringArgs{
+				PrometheusAgentMode:       true,
+				PrometheusRemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+			},
+			ExpectErr: true,
+		},
+		"agent-mode-without-spm": {
+			Args: &MonitoringArgs{
+				PrometheusAgentMode:       true,
+				PrometheusRemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+				DisableJaegerSPM:          true,
+			},
+			ExpectErr: false,
+		},
+		"remote-write-basic-auth-with-spm": {
+			Args: &MonitoringArgs{
+				PrometheusRemoteWriteBasicAuth: true,
+			},
+			ExpectErr: true,
+		},
+		"remote-write-basic-auth-secret-without-basic-auth": {
+			Args: &MonitoringArgs{
+				PrometheusRemoteWriteBasicAuthSecret: &parts.ExistingSecretArgs{
+					Name: pulumi.String("prometheus-credentials"),
+				},
+				DisableJaegerSPM: true,
+			},
+			ExpectErr: true,
+		},
+		"remote-write-basic-auth-without-spm": {
+			Args: &MonitoringArgs{
+				PrometheusRemoteWriteBasicAuth: true,
+				DisableJaegerSPM:               true,
+			},
+			ExpectErr: false,
+		},
+		"log-shipper": {
+			Args: &MonitoringArgs{
+				LogShipper: &parts.LogShipperArgs{},
+			},
+			ExpectErr: false,
+		},
+		"log-shipper-with-receiver-tls": {
+			Args: &MonitoringArgs{
+				LogShipper:      &parts.LogShipperArgs{},
+				OTELReceiverTLS: &parts.ReceiverTLSArgs{},
+			},
+			ExpectErr: true,
+		},
+		"log-shipper-on-openshift": {
+			Args: &MonitoringArgs{
+				LogShipper: &parts.LogShipperArgs{},
+				OpenShift:  &OpenShiftArgs{},
+			},
+			ExpectErr: true,
+		},
+		"ingress-peers": {
+			Args: &MonitoringArgs{
+				IngressPeers: []IngressPeer{
+					{NamespaceLabels: map[string]string{"kubernetes.io/metadata.name": "challenges"}},
+					{CIDR: "10.42.0.0/16"},
+				},
+			},
+			ExpectErr: false,
+		},
+		"ingress-peer-empty": {
+			Args: &MonitoringArgs{
+				IngressPeers: []IngressPeer{{}},
+			},
+			ExpectErr: true,
+		},
+		"ingress-peer-invalid-cidr": {
+			Args: &MonitoringArgs{
+				IngressPeers: []IngressPeer{{CIDR: "10.42.0.0"}},
+			},
+			ExpectErr: true,
+		},
+		"ingress-peer-cidr-and-labels": {
+			Args: &MonitoringArgs{
+				IngressPeers: []IngressPeer{{
+					CIDR:      "10.42.0.0/16",
+					PodLabels: map[string]string{"app": "challenge"},
+				}},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			mon := &Monitoring{}
+			err := mon.check(mon.defaults(tt.Args))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_OtelIngressRules(t *testing.T) {
+	t.Parallel()
+
+	ports := []parts.FlowPort{{Port: 4317}}
+
+	// Default: allow all sources on the port
+	rules := parts.IngressRules(otelIngressFlows(nil, false, ports), "in-otel-ntp", nil)
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(rules))
+	}
+	rule := rules[0].(netwv1.NetworkPolicyIngressRuleArgs)
+	if rule.From != nil {
+		t.Errorf("expected no source restriction, got %v", rule.From)
+	}
+	if len(rule.Ports.(netwv1.NetworkPolicyPortArray)) != 1 {
+		t.Errorf("expected the rule to restrict the port")
+	}
+
+	// Deny all, whatever the peers
+	rules = parts.IngressRules(otelIngressFlows([]IngressPeer{{CIDR: "10.42.0.0/16"}}, true, ports), "in-otel-ntp", nil)
+	if len(rules) != 0 {
+		t.Fatalf("expected no rule, got %d", len(rules))
+	}
+
+	// Peers
+	rules = parts.IngressRules(otelIngressFlows([]IngressPeer{
+		{NamespaceLabels: map[string]string{"kubernetes.io/metadata.name": "challenges"}},
+		{PodLabels: map[string]string{"app": "scoreboard"}},
+		{CIDR: "10.42.0.0/16"},
+	}, false, ports), "in-otel-ntp", nil)
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(rules))
+	}
+	from := rules[0].(netwv1.NetworkPolicyIngressRuleArgs).From.(netwv1.NetworkPolicyPeerArray)
+	if len(from) != 3 {
+		t.Fatalf("expected 3 peers, got %d", len(from))
+	}
+	nsPeer := from[0].(netwv1.NetworkPolicyPeerArgs)
+	if nsPeer.NamespaceSelector == nil || nsPeer.PodSelector != nil || nsPeer.IpBlock != nil {
+		t.Errorf("expected a namespace-only peer, got %+v", nsPeer)
+	}
+	if ml := nsPeer.NamespaceSelector.(metav1.LabelSelectorArgs).MatchLabels.(pulumi.StringMap); ml["kubernetes.io/metadata.name"] != pulumi.String("challenges") {
+		t.Errorf("unexpected namespace selector %v", ml)
+	}
+	podPeer := from[1].(netwv1.NetworkPolicyPeerArgs)
+	if podPeer.NamespaceSelector != nil || podPeer.PodSelector == nil || podPeer.IpBlock != nil {
+		t.Errorf("expected a pod-only peer, got %+v", podPeer)
+	}
+	cidrPeer := from[2].(netwv1.NetworkPolicyPeerArgs)
+	if cidrPeer.NamespaceSelector != nil || cidrPeer.PodSelector != nil || cidrPeer.IpBlock == nil {
+		t.Errorf("expected a cidr-only peer, got %+v", cidrPeer)
+	}
+}
+
+func Test_U_Monitoring_KafkaEgressRules(t *testing.T) {
+	t.Parallel()
+
+	// Without Kafka, no rule
+	if rules := parts.EgressRules(kafkaEgressFlows(nil), "otel-ntp", nil); len(rules) != 0 {
+		t.Fatalf("expected no rule, got %d", len(rules))
+	}
+
+	rules := parts.EgressRules(kafkaEgressFlows(&parts.KafkaExporterArgs{
+		Brokers: []string{"kafka.example.com:9093", "10.0.0.12:9092", "[fd00::12]:9092"},
+	}), "otel-ntp", nil)
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+
+	// Hostnames are not resolved, only the port is restricted
+	host := rules[0].(netwv1.NetworkPolicyEgressRuleArgs)
+	if host.To != nil {
+		t.Errorf("expected no destination restriction, got %v", host.To)
+	}
+	if port := host.Ports.(netwv1.NetworkPolicyPortArray)[0].(netwv1.NetworkPolicyPortArgs).Port; port != pulumi.Int(9093) {
+		t.Errorf("expected the broker port, got %v", port)
+	}
+
+	// IPs are restricted to themselves
+	for i, expected := range []string{"10.0.0.12/32", "fd00::12/128"} {
+		to := rules[i+1].(netwv1.NetworkPolicyEgressRuleArgs).To.(netwv1.NetworkPolicyPeerArray)
+		cidr := to[0].(netwv1.NetworkPolicyPeerArgs).IpBlock.(netwv1.IPBlockArgs).Cidr
+		if cidr != pulumi.String(expected) {
+			t.Errorf("expected the broker CIDR %s, got %v", expected, cidr)
+		}
+	}
+}
+
+func Test_U_Monitoring_BuildInfo(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			BuildInfo: &BuildInfo{
+				Version: "v1.2.3",
+				Commit:  "abcdef",
+				Date:    "2026-01-01T00:00:00Z",
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cm := m.ByName("kubernetes:core/v1:ConfigMap", "buildinfo")
+	if cm == nil {
+		t.Fatal("buildinfo configmap not found")
+	}
+	if name := cm["metadata"].ObjectValue()["name"].StringValue(); name != BuildInfoConfigMapName {
+		t.Errorf("expected configmap name %s, got %s", BuildInfoConfigMapName, name)
+	}
+	if got := cm["metadata"].ObjectValue()["labels"].ObjectValue()["ctfer.io/monitoring-version"]; !got.IsString() || got.StringValue() != "v1.2.3" {
+		t.Errorf("expected the buildinfo configmap to be labeled with the version, got %v", got)
+	}
+	data := cm["data"].ObjectValue()
+	for k, v := range map[string]string{
+		"version": "v1.2.3",
+		"commit":  "abcdef",
+		"date":    "2026-01-01T00:00:00Z",
+	} {
+		if got := data[resource.PropertyKey(k)].StringValue(); got != v {
+			t.Errorf("expected %s to be %s, got %s", k, v, got)
+		}
+	}
+
+	for _, typ := range []string{
+		"kubernetes:core/v1:Namespace",
+		"kubernetes:networking.k8s.io/v1:NetworkPolicy",
+		"kubernetes:core/v1:ConfigMap",
+		"kubernetes:core/v1:Service",
+		"kubernetes:apps/v1:Deployment",
+	} {
+		for _, res := range m.ByType(typ) {
+			labels := res["metadata"].ObjectValue()["labels"].ObjectValue()
+			if got := labels["ctfer.io/monitoring-version"]; !got.IsString() || got.StringValue() != "v1.2.3" {
+				t.Errorf("expected %s to be labeled with the version, got %v", typ, labels)
+			}
+		}
+	}
+
+	// The parts workloads are labeled, but neither their pods, for a new
+	// version not to roll them out, nor their selectors
+	for _, name := range []string{"otel", "prometheus", "jaeger"} {
+		dep := m.ByName("kubernetes:apps/v1:Deployment", name)
+		if dep == nil {
+			t.Fatalf("deployment %s not found", name)
+		}
+		labels := dep["metadata"].ObjectValue()["labels"].ObjectValue()
+		if got := labels["ctfer.io/monitoring-version"]; !got.IsString() || got.StringValue() != "v1.2.3" {
+			t.Errorf("expected deployment %s to be labeled with the version, got %v", name, labels)
+		}
+		spec := dep["spec"].ObjectValue()
+		for field, labels := range map[string]resource.PropertyMap{
+			"selector": spec["selector"].ObjectValue()["matchLabels"].ObjectValue(),
+			"template": spec["template"].ObjectValue()["metadata"].ObjectValue()["labels"].ObjectValue(),
+		} {
+			if _, ok := labels["ctfer.io/monitoring-version"]; ok {
+				t.Errorf("expected the deployment %s %s not to be labeled with the version", name, field)
+			}
+		}
+	}
+	for _, name := range []string{"otel-config", "prometheus-conf", "spm-config"} {
+		cm := m.ByName("kubernetes:core/v1:ConfigMap", name)
+		if cm == nil {
+			t.Fatalf("configmap %s not found", name)
+		}
+	}
+}
+
+func Test_U_Monitoring_ConfigDriftAnnotations(t *testing.T) {
+	t.Parallel()
+
+	for _, enabled := range []bool{false, true} {
+		m := &mocks.Mocks{}
+		err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+			_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+				ConfigDriftAnnotations: enabled,
+			})
+			return err
+		}, pulumi.WithMocks("monitoring", "test", m))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		for _, name := range []string{"otel-config", "prometheus-conf"} {
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", name)
+			meta := cm["metadata"].ObjectValue()
+			var hash resource.PropertyValue
+			if meta["annotations"].IsObject() {
+				hash = meta["annotations"].ObjectValue()[drift.HashAnnotation]
+			}
+			if !enabled {
+				if hash.IsString() {
+					t.Errorf("expected %s not to be stamped, got %s", name, hash.StringValue())
+				}
+				continue
+			}
+
+			// The hash is the one of the data, as the drift check computes it
+			data := map[string]string{}
+			for k, v := range cm["data"].ObjectValue() {
+				data[string(k)] = v.StringValue()
+			}
+			if !hash.IsString() || hash.StringValue() != drift.Hash(data) {
+				t.Errorf("expected %s to be stamped with %s, got %v", name, drift.Hash(data), hash)
+			}
+		}
+	}
+}
+
+func Test_U_Monitoring_EventLog(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args            *MonitoringArgs
+		ExpectEvent     bool
+		ExpectedMessage string
+	}{
+		"disabled": {
+			Args:        &MonitoringArgs{},
+			ExpectEvent: false,
+		},
+		"defaults": {
+			Args: &MonitoringArgs{
+				EventLog: true,
+			},
+			ExpectEvent:     true,
+			ExpectedMessage: "Deployed monitoring dev: cold extract disabled, prometheus agent mode disabled, jaeger SPM enabled",
+		},
+		"settings": {
+			Args: &MonitoringArgs{
+				EventLog:                  true,
+				ColdExtract:               true,
+				PrometheusAgentMode:       true,
+				PrometheusRemoteWriteURLs: pulumi.ToStringArray([]string{"http://prometheus.example.com/api/v1/write"}),
+				DisableJaegerSPM:          true,
+				BuildInfo: &BuildInfo{
+					Version: "v1.2.3",
+					Commit:  "abcdef",
+				},
+			},
+			ExpectEvent:     true,
+			ExpectedMessage: "Deployed monitoring v1.2.3 (abcdef): cold extract enabled, prometheus agent mode enabled, jaeger SPM disabled",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			evt := m.ByName("kubernetes:core/v1:Event", "lifecycle")
+			if (evt != nil) != tt.ExpectEvent {
+				t.Fatalf("expected event: %t, got %v", tt.ExpectEvent, evt)
+			}
+			if !tt.ExpectEvent {
+				return
+			}
+
+			for k, v := range map[string]string{
+				"type":               "Normal",
+				"reason":             "Deployed",
+				"message":            tt.ExpectedMessage,
+				"reportingComponent": "ctfer.io/monitoring",
+				"reportingInstance":  "test",
+			} {
+				if got := evt[resource.PropertyKey(k)].StringValue(); got != v {
+					t.Errorf("expected %s to be %q, got %q", k, v, got)
+				}
+			}
+
+			obj := evt["involvedObject"].ObjectValue()
+			if kind := obj["kind"].StringValue(); kind != "ConfigMap" {
+				t.Errorf("expected involved object kind ConfigMap, got %s", kind)
+			}
+			if name := obj["name"].StringValue(); name != BuildInfoConfigMapName {
+				t.Errorf("expected involved object name %s, got %s", BuildInfoConfigMapName, name)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_AgentMode(t *testing.T) {
+	t.Parallel()
+
+	for _, agent := range []bool{false, true} {
+		m := &mocks.Mocks{}
+		err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+			_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+				PrometheusAgentMode:       agent,
+				PrometheusRemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+				DisableJaegerSPM:          true,
+			})
+			return err
+		}, pulumi.WithMocks("monitoring", "test", m))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// An agent Prometheus receives nothing
+		prom := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+		ctr := prom["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+		receiver := false
+		for _, f := range ctr["args"].ArrayValue() {
+			receiver = receiver || f.StringValue() == "--web.enable-remote-write-receiver"
+		}
+		if receiver == agent {
+			t.Errorf("agent %t: expected the remote write receiver %t", agent, !agent)
+		}
+
+		// Nor could it be queried by Perses
+		if ds := m.ByName("kubernetes:core/v1:ConfigMap", "global-datasource"); (ds != nil) == agent {
+			t.Errorf("agent %t: expected the perses global datasource %t", agent, !agent)
+		}
+
+		// Rather, it scrapes the OTEL Collector metrics
+		cm := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")
+		cfg := cm["data"].ObjectValue()["config"].StringValue()
+		if strings.Contains(cfg, "\n  prometheus:\n") != agent || strings.Contains(cfg, "prometheusremotewrite:") == agent {
+			t.Errorf("agent %t: unexpected prometheus exporters in:\n%s", agent, cfg)
+		}
+		if svc := m.ByName("kubernetes:core/v1:Service", "otel-metrics"); (svc != nil) != agent {
+			t.Errorf("agent %t: expected the otel metrics service %t", agent, agent)
+		}
+		if ntp := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "in-otel-scrape-ntp"); (ntp != nil) != agent {
+			t.Errorf("agent %t: expected the otel scrape network policy %t", agent, agent)
+		}
+		promCfg := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")["data"].ObjectValue()["config"].StringValue()
+		if strings.Contains(promCfg, "job_name: otel-collector") != agent {
+			t.Errorf("agent %t: expected the otel-collector scrape job %t, got:\n%s", agent, agent, promCfg)
+		}
+	}
+}
+
+func Test_U_Monitoring_OTELReplicas(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			OTELReplicas:      3,
+			SpreadAcrossZones: true,
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Scaled, the OTEL Collector runs as a StatefulSet
+	sts := m.ByName("kubernetes:apps/v1:StatefulSet", "otel")
+	if sts == nil {
+		t.Fatal("otel statefulset not found")
+	}
+	spec := sts["spec"].ObjectValue()
+	if replicas := int(spec["replicas"].NumberValue()); replicas != 3 {
+		t.Errorf("expected 3 replicas, got %d", replicas)
+	}
+	podSpec := spec["template"].ObjectValue()["spec"].ObjectValue()
+	if tscs, ok := podSpec["topologySpreadConstraints"]; !ok || !tscs.IsArray() || len(tscs.ArrayValue()) != 1 {
+		t.Errorf("expected the otel pods to be spread across the zones, got %v", tscs)
+	}
+}
+
+func Test_U_Monitoring_OpenShift(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		OpenShift      *OpenShiftArgs
+		ExpectedRoutes int
+		ExpectPSA      bool
+	}{
+		"disabled": {
+			OpenShift:      nil,
+			ExpectedRoutes: 0,
+			ExpectPSA:      true,
+		},
+		"without-routes": {
+			OpenShift:      &OpenShiftArgs{},
+			ExpectedRoutes: 0,
+			ExpectPSA:      false,
+		},
+		"routes": {
+			OpenShift: &OpenShiftArgs{
+				Routes: true,
+			},
+			ExpectedRoutes: 2,
+			ExpectPSA:      false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					OpenShift: tt.OpenShift,
+					JaegerArchive: &parts.JaegerArchiveArgs{
+						Badger: &parts.JaegerBadgerArgs{},
+					},
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The restricted SCC assigns the fsGroup of the Badger archive
+			podSpec := m.ByName("kubernetes:apps/v1:Deployment", "jaeger")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			_, pinned := podSpec["securityContext"]
+			if pinned != (tt.OpenShift == nil) {
+				t.Errorf("expected the jaeger fsGroup pinned: %t, got %v", tt.OpenShift == nil, podSpec["securityContext"])
+			}
+
+			routes := m.ByType("kubernetes:route.openshift.io/v1:Route")
+			if len(routes) != tt.ExpectedRoutes {
+				t.Fatalf("expected %d routes, got %d", tt.ExpectedRoutes, len(routes))
+			}
+			for _, route := range routes {
+				spec := route["spec"].ObjectValue()
+				if kind := spec["to"].ObjectValue()["kind"].StringValue(); kind != "Service" {
+					t.Errorf("expected the route to target a Service, got %s", kind)
+				}
+				if term := spec["tls"].ObjectValue()["termination"].StringValue(); term != "edge" {
+					t.Errorf("expected edge termination, got %s", term)
+				}
+			}
+			if rtr := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "router-ntp"); (rtr != nil) != (tt.ExpectedRoutes != 0) {
+				t.Errorf("expected router network policy: %t", tt.ExpectedRoutes != 0)
+			}
+			// The router policy isolates Perses, which Prometheus scrapes
+			if scr := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "perses-scrape-ntp"); (scr != nil) != (tt.ExpectedRoutes != 0) {
+				t.Errorf("expected perses scrape network policy: %t", tt.ExpectedRoutes != 0)
+			}
+
+			labels := m.ByType("kubernetes:core/v1:Namespace")[0]["metadata"].ObjectValue()["labels"].ObjectValue()
+			if _, ok := labels["pod-security.kubernetes.io/enforce"]; ok != tt.ExpectPSA {
+				t.Errorf("expected pod security labels: %t, got %v", tt.ExpectPSA, labels)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_Ready(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		NotReady   []string
+		LogShipper *parts.LogShipperArgs
+		Expected   bool
+	}{
+		"all-ready": {
+			NotReady: nil,
+			Expected: true,
+		},
+		"log-shipper-ready": {
+			LogShipper: &parts.LogShipperArgs{},
+			Expected:   true,
+		},
+		"log-shipper-not-ready": {
+			NotReady:   []string{"log-shipper"},
+			LogShipper: &parts.LogShipperArgs{},
+			Expected:   false,
+		},
+		"otel-not-ready": {
+			NotReady: []string{"otel"},
+			Expected: false,
+		},
+		"jaeger-not-ready": {
+			NotReady: []string{"jaeger"},
+			Expected: false,
+		},
+		"prometheus-not-ready": {
+			NotReady: []string{"prometheus"},
+			Expected: false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{
+				NotReady: tt.NotReady,
+			}
+			var got bool
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					LogShipper: tt.LogShipper,
+				})
+				if err != nil {
+					return err
+				}
+				mon.Ready.ApplyT(func(ready bool) error {
+					defer wg.Done()
+					got = ready
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			if got != tt.Expected {
+				t.Errorf("expected ready: %t, got %t", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_LogShipper(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		LogShipper      *parts.LogShipperArgs
+		DenyAllIngress  bool
+		ExpectShipper   bool
+		ExpectIngress   bool
+		ExpectedExclude []string
+	}{
+		"disabled": {
+			LogShipper:    nil,
+			ExpectShipper: false,
+		},
+		"enabled": {
+			LogShipper:      &parts.LogShipperArgs{},
+			ExpectShipper:   true,
+			ExpectIngress:   true,
+			ExpectedExclude: []string{"log-shipper-abcdefgh", "monitoring-abcdefgh"},
+		},
+		"exclude-namespaces": {
+			LogShipper: &parts.LogShipperArgs{
+				ExcludeNamespaces: pulumi.ToStringArray([]string{"kube-system"}),
+			},
+			ExpectShipper:   true,
+			ExpectIngress:   true,
+			ExpectedExclude: []string{"kube-system", "log-shipper-abcdefgh", "monitoring-abcdefgh"},
+		},
+		"deny-all-ingress": {
+			LogShipper:      &parts.LogShipperArgs{},
+			DenyAllIngress:  true,
+			ExpectShipper:   true,
+			ExpectIngress:   false,
+			ExpectedExclude: []string{"log-shipper-abcdefgh", "monitoring-abcdefgh"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var podLabels map[string]string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					LogShipper:     tt.LogShipper,
+					DenyAllIngress: tt.DenyAllIngress,
+				})
+				if err != nil {
+					return err
+				}
+				mon.LogShipper.PodLabels.ApplyT(func(labels map[string]string) error {
+					defer wg.Done()
+					podLabels = labels
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			if (len(podLabels) != 0) != tt.ExpectShipper {
+				t.Errorf("expected log shipper pod labels: %t, got %v", tt.ExpectShipper, podLabels)
+			}
+			if ds := m.ByName("kubernetes:apps/v1:DaemonSet", "log-shipper"); (ds != nil) != tt.ExpectShipper {
+				t.Fatalf("expected log shipper: %t", tt.ExpectShipper)
+			}
+			if egress := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "log-shipper-ntp"); (egress != nil) != tt.ExpectShipper {
+				t.Errorf("expected log shipper egress network policy: %t", tt.ExpectShipper)
+			}
+			if toAPI := m.ByName("kubernetes:yaml/v2:ConfigGroup", "log-shipper-to-apiserver-netpol"); (toAPI != nil) != tt.ExpectShipper {
+				t.Errorf("expected log shipper to apiserver network policy: %t", tt.ExpectShipper)
+			}
+			if ingress := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "in-otel-logshipper-ntp"); (ingress != nil) != tt.ExpectIngress {
+				t.Errorf("expected log shipper ingress network policy: %t", tt.ExpectIngress)
+			}
+			if !tt.ExpectShipper {
+				return
+			}
+
+			// The monitoring logs are not shipped back to the collector
+			cfg := m.ByName("kubernetes:core/v1:ConfigMap", "log-shipper-config")["data"].ObjectValue()["config"].StringValue()
+			exclude := []string{}
+			for _, line := range strings.Split(cfg, "\n") {
+				line = strings.TrimSpace(line)
+				if ns, ok := strings.CutPrefix(line, "- "+parts.LogShipperLogsPath+"/"); ok && strings.HasSuffix(ns, "_*/*/*.log") {
+					exclude = append(exclude, strings.TrimSuffix(ns, "_*/*/*.log"))
+				}
+			}
+			if !slices.Equal(exclude, tt.ExpectedExclude) {
+				t.Errorf("expected excluded namespaces %v, got %v", tt.ExpectedExclude, exclude)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_PersesWaitsForPrometheus(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		PersesWaitsForPrometheus bool
+		ExpectChartDependency    bool
+	}{
+		"datasource-only": {
+			PersesWaitsForPrometheus: false,
+			ExpectChartDependency:    false,
+		},
+		"chart": {
+			PersesWaitsForPrometheus: true,
+			ExpectChartDependency:    true,
+		},
+	}
+
+	// dependsOnPrometheus returns whether the URNs contain the Prometheus
+	// Deployment, whose rollout is awaited.
+	dependsOnPrometheus := func(urns []string) bool {
+		return slices.ContainsFunc(urns, func(urn string) bool {
+			return strings.HasSuffix(urn, "$kubernetes:apps/v1:Deployment::prometheus")
+		})
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					PersesWaitsForPrometheus: tt.PersesWaitsForPrometheus,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !dependsOnPrometheus(m.Dependencies("kubernetes:core/v1:ConfigMap", "global-datasource")) {
+				t.Error("expected the global datasource to depend on the prometheus deployment")
+			}
+			if got := dependsOnPrometheus(m.Dependencies("kubernetes:helm.sh/v4:Chart", "perses")); got != tt.ExpectChartDependency {
+				t.Errorf("expected the perses chart to depend on the prometheus deployment: %t, got %t", tt.ExpectChartDependency, got)
+			}
+
+			// The rollout only completes once Prometheus serves
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			if _, ok := ctr["readinessProbe"]; !ok {
+				t.Error("expected a readiness probe on the prometheus container")
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_OTELAwaitBackends(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args             *MonitoringArgs
+		ExpectDependency bool
+	}{
+		"default": {
+			Args: &MonitoringArgs{},
+		},
+		"await": {
+			Args: &MonitoringArgs{
+				OTELAwaitBackends: true,
+			},
+			ExpectDependency: true,
+		},
+		"await-scaled": {
+			Args: &MonitoringArgs{
+				OTELAwaitBackends: true,
+				OTELReplicas:      3,
+			},
+			ExpectDependency: true,
+		},
+		"paused": {
+			Args: &MonitoringArgs{
+				OTELAwaitBackends: true,
+				PauseRollouts:     true,
+			},
+		},
+	}
+
+	// dependsOn returns whether the URNs contain the Deployment, whose
+	// rollout is awaited.
+	dependsOn := func(urns []string, name string) bool {
+		return slices.ContainsFunc(urns, func(urn string) bool {
+			return strings.HasSuffix(urn, "$kubernetes:apps/v1:Deployment::"+name)
+		})
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			workload := "kubernetes:apps/v1:Deployment"
+			if tt.Args.OTELReplicas > 1 {
+				workload = "kubernetes:apps/v1:StatefulSet"
+			}
+			deps := m.Dependencies(workload, "otel")
+			for _, backend := range []string{"jaeger", "prometheus"} {
+				if got := dependsOn(deps, backend); got != tt.ExpectDependency {
+					t.Errorf("expected the otel collector to depend on the %s deployment: %t, got %t", backend, tt.ExpectDependency, got)
+				}
+			}
+
+			// The exporters back off while the backends start anyway
+			cfg := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+			for _, setting := range []string{"multiplier: 2", "randomization_factor: 0.5"} {
+				if !strings.Contains(cfg, setting) {
+					t.Errorf("expected %q in the otel collector configuration", setting)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_JaegerQuery(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			JaegerQuery: &parts.JaegerQueryArgs{
+				HTTPWriteTimeout: time.Minute,
+				MaxLookback:      "12h",
+			},
+			JaegerResources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("1Gi"),
+				},
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()
+	if cfg := data["config.yaml"].StringValue(); !strings.Contains(cfg, "write_timeout: 1m0s") {
+		t.Errorf("expected the query write timeout, got:\n%s", cfg)
+	}
+	if ui := data["jaeger-ui.json"].StringValue(); !strings.Contains(ui, `"maxLookback"`) {
+		t.Errorf("expected the ui max lookback, got:\n%s", ui)
+	}
+	ctr := m.ByName("kubernetes:apps/v1:Deployment", "jaeger")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+	if mem := ctr["resources"].ObjectValue()["limits"].ObjectValue()["memory"].StringValue(); mem != "1Gi" {
+		t.Errorf("expected the jaeger memory limit, got %q", mem)
+	}
+
+	// Invalid limits are reported
+	err = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			JaegerQuery: &parts.JaegerQueryArgs{
+				MaxTraces: -1,
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+	if err == nil {
+		t.Error("expected an error on negative max traces")
+	}
+}
+
+func Test_U_Monitoring_ClusterDomain(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ClusterDomain    string
+		ExpectedEndpoint string
+		ExpectedJaeger   string
+		ExpectedProm     string
+		ExpectErr        bool
+	}{
+		"short": {
+			ClusterDomain:    "",
+			ExpectedEndpoint: "otlp-grpc.monitoring-abcdefgh:4317",
+			ExpectedJaeger:   "http://jaeger-grpc:4317",
+			ExpectedProm:     "http://prometheus-metrics:9090",
+		},
+		"fully-qualified": {
+			ClusterDomain:    "cluster.local",
+			ExpectedEndpoint: "otlp-grpc.monitoring-abcdefgh.svc.cluster.local:4317",
+			ExpectedJaeger:   "http://jaeger-grpc.monitoring-abcdefgh.svc.cluster.local:4317",
+			ExpectedProm:     "http://prometheus-metrics.monitoring-abcdefgh.svc.cluster.local:9090",
+		},
+		"invalid": {
+			ClusterDomain: "Cluster.Local.",
+			ExpectErr:     true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var endpoint string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					ClusterDomain: tt.ClusterDomain,
+				})
+				if err != nil {
+					wg.Done()
+					return err
+				}
+				mon.OTEL.Endpoint.ApplyT(func(edp string) error {
+					defer wg.Done()
+					endpoint = edp
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			wg.Wait()
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if endpoint != tt.ExpectedEndpoint {
+				t.Errorf("expected otel endpoint %s, got %s", tt.ExpectedEndpoint, endpoint)
+			}
+
+			// The URLs reach the collector and Jaeger configurations
+			otelCfg := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+			for _, u := range []string{tt.ExpectedJaeger, tt.ExpectedProm} {
+				if !strings.Contains(otelCfg, u) {
+					t.Errorf("expected otel collector config to contain %s, got:\n%s", u, otelCfg)
+				}
+			}
+			jgrCfg := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()["config.yaml"].StringValue()
+			if !strings.Contains(jgrCfg, tt.ExpectedProm) {
+				t.Errorf("expected jaeger config to contain %s, got:\n%s", tt.ExpectedProm, jgrCfg)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_SelfScrape(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Prometheus scrapes the parts
+	cm := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")
+	cfg := cm["data"].ObjectValue()["config"].StringValue()
+	for _, job := range []string{"jaeger", "perses"} {
+		if !strings.Contains(cfg, "job_name: "+job) {
+			t.Errorf("expected prometheus to scrape %s, got:\n%s", job, cfg)
+		}
+	}
+
+	// Jaeger lets Prometheus scrape its admin port
+	ntp := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "jaeger-ntp")
+	found := false
+	for _, rule := range ntp["spec"].ObjectValue()["ingress"].ArrayValue() {
+		for _, port := range rule.ObjectValue()["ports"].ArrayValue() {
+			if p := port.ObjectValue()["port"]; p.IsNumber() && p.NumberValue() == parts.JaegerAdminPort {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected jaeger to accept the scraping on port %d", parts.JaegerAdminPort)
+	}
+}
+
+func Test_U_Monitoring_DevMode(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args                     *MonitoringArgs
+		ExpectedStorageClassName string
+		ExpectedAccessModes      []string
+		ExpectedCPU              string
+		ExpectedPartsCPU         string
+		ExpectedMaxTraces        float64
+		ExpectedProbeTimeout     float64
+	}{
+		"production": {
+			Args: &MonitoringArgs{
+				ColdExtract: true,
+			},
+			ExpectedStorageClassName: "",
+			ExpectedAccessModes:      []string{"ReadWriteMany"},
+			ExpectedCPU:              "100m",
+			ExpectedPartsCPU:         "",
+			ExpectedMaxTraces:        100000,
+			ExpectedProbeTimeout:     0,
+		},
+		"dev": {
+			Args: &MonitoringArgs{
+				ColdExtract: true,
+				DevMode:     true,
+			},
+			ExpectedStorageClassName: "local-path",
+			ExpectedAccessModes:      []string{"ReadWriteOnce"},
+			ExpectedCPU:              "10m",
+			ExpectedPartsCPU:         "10m",
+			ExpectedMaxTraces:        10000,
+			ExpectedProbeTimeout:     5,
+		},
+		"dev-empty-values": {
+			// As set by the Pulumi program when not configured
+			Args: &MonitoringArgs{
+				ColdExtract:      true,
+				DevMode:          true,
+				StorageClassName: pulumi.String(""),
+				PVCAccessModes:   pulumi.StringArray{},
+			},
+			ExpectedStorageClassName: "local-path",
+			ExpectedAccessModes:      []string{"ReadWriteOnce"},
+			ExpectedCPU:              "10m",
+			ExpectedPartsCPU:         "10m",
+			ExpectedMaxTraces:        10000,
+			ExpectedProbeTimeout:     5,
+		},
+		"dev-overridden": {
+			Args: &MonitoringArgs{
+				ColdExtract:      true,
+				DevMode:          true,
+				StorageClassName: pulumi.String("standard"),
+				PVCAccessModes:   pulumi.ToStringArray([]string{"ReadWriteMany"}),
+			},
+			ExpectedStorageClassName: "standard",
+			ExpectedAccessModes:      []string{"ReadWriteMany"},
+			ExpectedCPU:              "10m",
+			ExpectedPartsCPU:         "10m",
+			ExpectedMaxTraces:        10000,
+			ExpectedProbeTimeout:     5,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			pvc := m.ByName("kubernetes:core/v1:PersistentVolumeClaim", "signals")
+			if pvc == nil {
+				t.Fatal("signals PVC not found")
+			}
+			spec := pvc["spec"].ObjectValue()
+			scn := ""
+			if v := spec["storageClassName"]; v.IsString() {
+				scn = v.StringValue()
+			}
+			if scn != tt.ExpectedStorageClassName {
+				t.Errorf("expected storage class name %q, got %q", tt.ExpectedStorageClassName, scn)
+			}
+			var modes []string
+			for _, mode := range spec["accessModes"].ArrayValue() {
+				modes = append(modes, mode.StringValue())
+			}
+			if !slices.Equal(modes, tt.ExpectedAccessModes) {
+				t.Errorf("expected access modes %v, got %v", tt.ExpectedAccessModes, modes)
+			}
+
+			// The other parts are sized alike in dev mode
+			for _, name := range []string{"otel", "jaeger"} {
+				dep := m.ByName("kubernetes:apps/v1:Deployment", name)
+				if dep == nil {
+					t.Fatalf("%s deployment not found", name)
+				}
+				ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+				cpu := ""
+				if res, ok := ctr["resources"]; ok {
+					cpu = res.ObjectValue()["requests"].ObjectValue()["cpu"].StringValue()
+				}
+				if cpu != tt.ExpectedPartsCPU {
+					t.Errorf("expected %s to request %q CPU, got %q", name, tt.ExpectedPartsCPU, cpu)
+				}
+			}
+			cpu := ""
+			if req, ok := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()["resources"].ObjectValue()["requests"]; ok {
+				cpu = req.ObjectValue()["cpu"].StringValue()
+			}
+			if cpu != tt.ExpectedPartsCPU {
+				t.Errorf("expected perses to request %q CPU, got %q", tt.ExpectedPartsCPU, cpu)
+			}
+			maxTraces := 0.
+			cfg := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()["config.yaml"].StringValue()
+			if i := strings.Index(cfg, "max_traces: "); i >= 0 {
+				_, _ = fmt.Sscanf(cfg[i:], "max_traces: %g", &maxTraces)
+			}
+			if maxTraces != tt.ExpectedMaxTraces {
+				t.Errorf("expected jaeger to keep %v traces, got %v", tt.ExpectedMaxTraces, maxTraces)
+			}
+
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			if dep == nil {
+				t.Fatal("prometheus deployment not found")
+			}
+			ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			if cpu := ctr["resources"].ObjectValue()["requests"].ObjectValue()["cpu"].StringValue(); cpu != tt.ExpectedCPU {
+				t.Errorf("expected prometheus to request %s CPU, got %s", tt.ExpectedCPU, cpu)
+			}
+			timeout := 0.
+			if v := ctr["readinessProbe"].ObjectValue()["timeoutSeconds"]; v.IsNumber() {
+				timeout = v.NumberValue()
+			}
+			if timeout != tt.ExpectedProbeTimeout {
+				t.Errorf("expected prometheus readiness probe timeout %vs, got %vs", tt.ExpectedProbeTimeout, timeout)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_Preset(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args                 *MonitoringArgs
+		ExpectErr            bool
+		ExpectedStorageSize  string
+		ExpectedOTELMemory   string
+		ExpectedQueueSize    string
+		ExpectedRetention    string
+		ExpectedPromMemory   string
+		ExpectedJaegerMemory string
+		ExpectedJaegerTraces string
+	}{
+		"custom": {
+			Args:                 &MonitoringArgs{},
+			ExpectedStorageSize:  "50M",
+			ExpectedPromMemory:   "256Mi",
+			ExpectedJaegerTraces: "100000",
+		},
+		"small": {
+			Args: &MonitoringArgs{
+				Preset: PresetSmall,
+			},
+			ExpectedStorageSize:  "5Gi",
+			ExpectedOTELMemory:   "256Mi",
+			ExpectedQueueSize:    "1000",
+			ExpectedRetention:    "7d",
+			ExpectedPromMemory:   "512Mi",
+			ExpectedJaegerMemory: "256Mi",
+			ExpectedJaegerTraces: "50000",
+		},
+		"medium": {
+			Args: &MonitoringArgs{
+				Preset: PresetMedium,
+			},
+			ExpectedStorageSize:  "20Gi",
+			ExpectedOTELMemory:   "512Mi",
+			ExpectedQueueSize:    "5000",
+			ExpectedRetention:    "15d",
+			ExpectedPromMemory:   "1Gi",
+			ExpectedJaegerMemory: "1Gi",
+			ExpectedJaegerTraces: "200000",
+		},
+		"large": {
+			Args: &MonitoringArgs{
+				Preset: PresetLarge,
+			},
+			ExpectedStorageSize:  "100Gi",
+			ExpectedOTELMemory:   "1Gi",
+			ExpectedQueueSize:    "20000",
+			ExpectedRetention:    "30d",
+			ExpectedPromMemory:   "4Gi",
+			ExpectedJaegerMemory: "4Gi",
+			ExpectedJaegerTraces: "500000",
+		},
+		"large-overridden": {
+			// Explicit arguments win over the preset
+			Args: &MonitoringArgs{
+				Preset:      PresetLarge,
+				StorageSize: pulumi.String("1Gi"),
+				OTELResources: corev1.ResourceRequirementsArgs{
+					Requests: pulumi.StringMap{
+						"memory": pulumi.String("3Gi"),
+					},
+				},
+				OTELQueueSize:       42,
+				PrometheusRetention: "90d",
+				PrometheusResources: corev1.ResourceRequirementsArgs{
+					Requests: pulumi.StringMap{
+						"memory": pulumi.String("16Gi"),
+					},
+				},
+				JaegerResources: corev1.ResourceRequirementsArgs{
+					Requests: pulumi.StringMap{
+						"memory": pulumi.String("12Gi"),
+					},
+				},
+				JaegerMemoryMaxTraces: 1234,
+			},
+			ExpectedStorageSize:  "1Gi",
+			ExpectedOTELMemory:   "3Gi",
+			ExpectedQueueSize:    "42",
+			ExpectedRetention:    "90d",
+			ExpectedPromMemory:   "16Gi",
+			ExpectedJaegerMemory: "12Gi",
+			ExpectedJaegerTraces: "1234",
+		},
+		"small-empty-storage-size": {
+			// As set by the Pulumi program when not configured
+			Args: &MonitoringArgs{
+				Preset:      PresetSmall,
+				StorageSize: pulumi.String(""),
+			},
+			ExpectedStorageSize:  "5Gi",
+			ExpectedOTELMemory:   "256Mi",
+			ExpectedQueueSize:    "1000",
+			ExpectedRetention:    "7d",
+			ExpectedPromMemory:   "512Mi",
+			ExpectedJaegerMemory: "256Mi",
+			ExpectedJaegerTraces: "50000",
+		},
+		"unknown": {
+			Args: &MonitoringArgs{
+				Preset: "huge",
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			tt.Args.ColdExtract = true
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			pvc := m.ByName("kubernetes:core/v1:PersistentVolumeClaim", "signals")
+			if size := pvc["spec"].ObjectValue()["resources"].ObjectValue()["requests"].ObjectValue()["storage"].StringValue(); size != tt.ExpectedStorageSize {
+				t.Errorf("expected storage size %s, got %s", tt.ExpectedStorageSize, size)
+			}
+
+			otel := containerOf(m.ByName("kubernetes:apps/v1:Deployment", "otel"))
+			if mem := requestedMemory(otel); mem != tt.ExpectedOTELMemory {
+				t.Errorf("expected the otel collector to request %q memory, got %q", tt.ExpectedOTELMemory, mem)
+			}
+			otelCfg := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+			if tt.ExpectedQueueSize == "" {
+				if strings.Contains(otelCfg, "queue_size") {
+					t.Errorf("expected the exporters default queue size, got:\n%s", otelCfg)
+				}
+			} else if !strings.Contains(otelCfg, "queue_size: "+tt.ExpectedQueueSize) {
+				t.Errorf("expected queue size %s, got:\n%s", tt.ExpectedQueueSize, otelCfg)
+			}
+
+			prom := containerOf(m.ByName("kubernetes:apps/v1:Deployment", "prometheus"))
+			if mem := requestedMemory(prom); mem != tt.ExpectedPromMemory {
+				t.Errorf("expected prometheus to request %q memory, got %q", tt.ExpectedPromMemory, mem)
+			}
+			retention := ""
+			for _, arg := range prom["args"].ArrayValue() {
+				if r, ok := strings.CutPrefix(arg.StringValue(), "--storage.tsdb.retention.time="); ok {
+					retention = r
+				}
+			}
+			if retention != tt.ExpectedRetention {
+				t.Errorf("expected retention %q, got %q", tt.ExpectedRetention, retention)
+			}
+
+			jgr := containerOf(m.ByName("kubernetes:apps/v1:Deployment", "jaeger"))
+			if mem := requestedMemory(jgr); mem != tt.ExpectedJaegerMemory {
+				t.Errorf("expected jaeger to request %q memory, got %q", tt.ExpectedJaegerMemory, mem)
+			}
+			jgrCfg := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()["config.yaml"].StringValue()
+			if !strings.Contains(jgrCfg, "max_traces: "+tt.ExpectedJaegerTraces) {
+				t.Errorf("expected max traces %s, got:\n%s", tt.ExpectedJaegerTraces, jgrCfg)
+			}
+		})
+	}
+}
+
+// containerOf returns the first container of the Deployment.
+func containerOf(dep resource.PropertyMap) resource.PropertyMap {
+	return dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+}
+
+// requestedMemory returns the memory request of the container, if any.
+func requestedMemory(ctr resource.PropertyMap) string {
+	res := ctr["resources"]
+	if !res.IsObject() || !res.ObjectValue()["requests"].IsObject() {
+		return ""
+	}
+	if v := res.ObjectValue()["requests"].ObjectValue()["memory"]; v.IsString() {
+		return v.StringValue()
+	}
+	return ""
+}
+
+func Test_U_Monitoring_RemoteWriteBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Secret            *parts.ExistingSecretArgs
+		ExpectedSecret    string
+		ExpectedSecretKey string
+	}{
+		"generated": {
+			ExpectedSecret:    "prometheus-web-config",
+			ExpectedSecretKey: parts.BasicAuthPasswordKey,
+		},
+		"existing": {
+			Secret: &parts.ExistingSecretArgs{
+				Name: pulumi.String("prometheus-credentials"),
+				Key:  "remote-write-password",
+			},
+			ExpectedSecret:    "prometheus-credentials",
+			ExpectedSecretKey: "remote-write-password",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					PrometheusRemoteWriteBasicAuth:       true,
+					PrometheusRemoteWriteBasicAuthSecret: tt.Secret,
+					DisableJaegerSPM:                     true,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The credentials are only generated when none is referenced
+			generated := m.ByName("kubernetes:core/v1:Secret", "prometheus-web-config") != nil
+			if generated != (tt.Secret == nil) {
+				t.Errorf("expected the web config secret to be generated %t, got %t", tt.Secret == nil, generated)
+			}
+
+			// The OTEL Collector is given the password of Prometheus
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "otel")
+			if dep == nil {
+				t.Fatal("otel collector deployment not found")
+			}
+			ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			env := ctr["env"].ArrayValue()
+			if len(env) != 1 {
+				t.Fatalf("expected the password environment variable, got %v", env)
+			}
+			ref := env[0].ObjectValue()["valueFrom"].ObjectValue()["secretKeyRef"].ObjectValue()
+			if name, key := ref["name"].StringValue(), ref["key"].StringValue(); name != tt.ExpectedSecret || key != tt.ExpectedSecretKey {
+				t.Errorf("expected the password from %s/%s, got %s/%s", tt.ExpectedSecret, tt.ExpectedSecretKey, name, key)
+			}
+
+			// Prometheus mounts the same Secret
+			prom := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			for _, vol := range prom["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["volumes"].ArrayValue() {
+				if vol.ObjectValue()["name"].StringValue() != "web-config" {
+					continue
+				}
+				if name := vol.ObjectValue()["secret"].ObjectValue()["secretName"].StringValue(); name != tt.ExpectedSecret {
+					t.Errorf("expected prometheus to mount the %s secret, got %s", tt.ExpectedSecret, name)
+				}
+			}
+
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")
+			if cfg := cm["data"].ObjectValue()["config"].StringValue(); !strings.Contains(cfg, "authenticator: basicauth/prometheus") {
+				t.Errorf("expected the remote write to authenticate, got:\n%s", cfg)
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_ScrapeEgress(t *testing.T) {
+	t.Parallel()
+
+	args := &MonitoringArgs{
+		PrometheusExtraScrapeConfigs: []parts.ScrapeConfig{
+			{
+				JobName: "challenges",
+				Destinations: []parts.ScrapeDestination{
+					{
+						NamespaceLabels: map[string]string{"ctfer.io/kind": "challenge"},
+						PodLabels:       map[string]string{"app": "scoreboard"},
+						Ports:           []int{8080, 9100},
+					},
+					{
+						NamespaceLabels: map[string]string{"kubernetes.io/metadata.name": "ctfd"},
+					},
+				},
+			},
+			{
+				JobName: "node-exporter",
+				Destinations: []parts.ScrapeDestination{
+					{CIDR: "10.0.12.0/24", Ports: []int{9100}},
+				},
+			},
+		},
+	}
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", args)
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Every destination of every job, the parts ones included, is allowed
+	// by a rule of its own
+	expected := []string{}
+	for _, sc := range prometheusScrapeConfigs(args) {
+		if len(sc.Destinations) == 0 {
+			t.Errorf("expected job %s to declare a destination", sc.JobName)
+		}
+		for _, dest := range sc.Destinations {
+			expected = append(expected, fmt.Sprintf("cidr=%s ns=%v pods=%v ports=%v", dest.CIDR, dest.NamespaceLabels, dest.PodLabels, dest.Ports))
+		}
+	}
+	spec := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", "prom-egress-ntp")["spec"].ObjectValue()
+	if pt := spec["policyTypes"].ArrayValue(); len(pt) != 1 || pt[0].StringValue() != "Egress" {
+		t.Errorf("expected an egress policy, got %v", pt)
+	}
+	generated := []string{}
+	for _, rule := range spec["egress"].ArrayValue() {
+		r := rule.ObjectValue()
+		var ports []int
+		if ps, ok := r["ports"]; ok {
+			for _, p := range ps.ArrayValue() {
+				ports = append(ports, int(p.ObjectValue()["port"].NumberValue()))
+			}
+		}
+		for _, peer := range r["to"].ArrayValue() {
+			pr := peer.ObjectValue()
+			var (
+				cidr     string
+				ns, pods map[string]string
+			)
+			if ipb, ok := pr["ipBlock"]; ok {
+				cidr = ipb.ObjectValue()["cidr"].StringValue()
+			}
+			if sel, ok := pr["namespaceSelector"]; ok {
+				ns = stringMap(sel.ObjectValue()["matchLabels"])
+			}
+			if sel, ok := pr["podSelector"]; ok {
+				pods = stringMap(sel.ObjectValue()["matchLabels"])
+			}
+			generated = append(generated, fmt.Sprintf("cidr=%s ns=%v pods=%v ports=%v", cidr, ns, pods, ports))
+		}
+	}
+	slices.Sort(expected)
+	slices.Sort(generated)
+	if !slices.Equal(expected, generated) {
+		t.Errorf("expected egress rules:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(generated, "\n"))
+	}
+
+	// A job without destination is refused
+	err = pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			PrometheusExtraScrapeConfigs: []parts.ScrapeConfig{
+				{JobName: "challenges"},
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+	if err == nil {
+		t.Error("expected the job without destination to be refused")
+	}
+}
+
+// stringMap converts the labels property to a map, nil if not set.
+func stringMap(v resource.PropertyValue) map[string]string {
+	if !v.IsObject() {
+		return nil
+	}
+	m := map[string]string{}
+	for k, val := range v.ObjectValue() {
+		m[string(k)] = val.StringValue()
+	}
+	return m
+}
+
+func Test_U_Monitoring_NetworkPolicyPorts(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args *MonitoringArgs
+	}{
+		"default": {
+			Args: &MonitoringArgs{},
+		},
+		"cluster-domain": {
+			Args: &MonitoringArgs{
+				ClusterDomain: "cluster.local",
+			},
+		},
+		"cold-extract": {
+			Args: &MonitoringArgs{
+				ColdExtract: true,
+			},
+		},
+		"legacy-receivers": {
+			Args: &MonitoringArgs{
+				OTELStatsdReceiver: true,
+				OTELSyslogReceiver: &parts.SyslogReceiverArgs{},
+			},
+		},
+		"custom-ports": {
+			Args: &MonitoringArgs{
+				OTELStatsdReceiver: true,
+				OTELSyslogReceiver: &parts.SyslogReceiverArgs{},
+				OTELPorts: &parts.OtelPortsArgs{
+					OTLP:   14317,
+					Statsd: 18125,
+					Syslog: 10514,
+				},
+				PrometheusPort: 19090,
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The ports the pods serve on, as the Services selecting them
+			// declare them, along their protocol (e.g. 8125/UDP).
+			svcs := m.ByType("kubernetes:core/v1:Service")
+			servedPorts := func(podLabels resource.PropertyMap) []string {
+				ports := []string{}
+				for _, svc := range svcs {
+					spec := svc["spec"].ObjectValue()
+					if !selects(spec["selector"].ObjectValue(), podLabels) {
+						continue
+					}
+					for _, p := range spec["ports"].ArrayValue() {
+						port := p.ObjectValue()["port"]
+						if tp, ok := p.ObjectValue()["targetPort"]; ok && tp.IsNumber() {
+							port = tp
+						}
+						ports = append(ports, portProtocol(port, p.ObjectValue()["protocol"]))
+					}
+				}
+				return ports
+			}
+			check := func(policy string, podLabels resource.PropertyMap, ports []resource.PropertyValue) int {
+				served := servedPorts(podLabels)
+				if len(served) == 0 {
+					// Not one of ours, e.g. the cluster DNS
+					return 0
+				}
+				allowed := []string{}
+				for _, p := range ports {
+					port := p.ObjectValue()["port"]
+					if !port.IsNumber() {
+						continue
+					}
+					allowed = append(allowed, portProtocol(port, p.ObjectValue()["protocol"]))
+				}
+				for _, port := range allowed {
+					if !slices.Contains(served, port) {
+						t.Errorf("network policy %s allows port %s, but the pods %v only serve %v", policy, port, podLabels.Mappable(), served)
+					}
+				}
+				// The receivers are all reachable, whatever their protocol
+				if policy == "in-otel-ntp" {
+					for _, port := range served {
+						if !slices.Contains(allowed, port) {
+							t.Errorf("network policy %s does not allow port %s served by the pods %v", policy, port, podLabels.Mappable())
+						}
+					}
+				}
+				return 1
+			}
+
+			checked := 0
+			for _, name := range m.Names("kubernetes:networking.k8s.io/v1:NetworkPolicy") {
+				spec := m.ByName("kubernetes:networking.k8s.io/v1:NetworkPolicy", name)["spec"].ObjectValue()
+
+				// Ingress ports are those of the selected pods
+				if target, ok := matchLabels(spec["podSelector"]); ok {
+					if ingress, ok := spec["ingress"]; ok {
+						for _, rule := range ingress.ArrayValue() {
+							checked += check(name, target, rule.ObjectValue()["ports"].ArrayValue())
+						}
+					}
+				}
+				// Egress ports are those of the peer pods
+				if egress, ok := spec["egress"]; ok {
+					for _, rule := range egress.ArrayValue() {
+						to, ok := rule.ObjectValue()["to"]
+						if !ok {
+							continue
+						}
+						for _, peer := range to.ArrayValue() {
+							if labels, ok := matchLabels(peer.ObjectValue()["podSelector"]); ok {
+								checked += check(name, labels, rule.ObjectValue()["ports"].ArrayValue())
+							}
+						}
+					}
+				}
+			}
+			if checked == 0 {
+				t.Fatal("expected network policies to be checked")
+			}
+		})
+	}
+}
+
+// portProtocol formats the port along its protocol, which defaults to TCP.
+// Example: 8125/UDP
+func portProtocol(port, protocol resource.PropertyValue) string {
+	proto := "TCP"
+	if protocol.IsString() && protocol.StringValue() != "" {
+		proto = protocol.StringValue()
+	}
+	return fmt.Sprintf("%d/%s", int(port.NumberValue()), proto)
+}
+
+// matchLabels returns the labels a selector matches, if it matches on
+// labels only.
+func matchLabels(selector resource.PropertyValue) (resource.PropertyMap, bool) {
+	if !selector.IsObject() {
+		return nil, false
+	}
+	labels, ok := selector.ObjectValue()["matchLabels"]
+	if !ok || !labels.IsObject() || len(labels.ObjectValue()) == 0 {
+		return nil, false
+	}
+	return labels.ObjectValue(), true
+}
+
+// selects returns whether the Service selector selects the pod labels.
+func selects(selector, podLabels resource.PropertyMap) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if pv, ok := podLabels[k]; !ok || pv.StringValue() != v.StringValue() {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_U_Monitoring_Protect(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Protect bool
+	}{
+		"unprotected": {
+			Protect: false,
+		},
+		"protected": {
+			Protect: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					ColdExtract: true,
+					JaegerArchive: &parts.JaegerArchiveArgs{
+						Badger: &parts.JaegerBadgerArgs{},
+					},
+					Protect: tt.Protect,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			for _, r := range []struct{ typ, name string }{
+				{"kubernetes:core/v1:Namespace", "ns"},
+				{"kubernetes:core/v1:PersistentVolumeClaim", "signals"},
+				{"kubernetes:core/v1:PersistentVolumeClaim", "jaeger-archive"},
+			} {
+				if got := m.Protected(r.typ, r.name); got != tt.Protect {
+					t.Errorf("expected %s %s to be protected: %t, got %t", r.typ, r.name, tt.Protect, got)
+				}
+			}
+
+			// The stateless resources are always deleted along
+			if m.Protected("kubernetes:apps/v1:Deployment", "otel") {
+				t.Error("expected the OTEL Collector deployment not to be protected")
+			}
+		})
+	}
+}
+
+func Test_U_Monitoring_PauseRollouts(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+			PauseRollouts:   true,
+			MinReadySeconds: 20,
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, name := range []string{"otel", "jaeger", "prometheus"} {
+		dep := m.ByName("kubernetes:apps/v1:Deployment", name)
+		if dep == nil {
+			t.Fatalf("expected a %s deployment", name)
+		}
+		spec := dep["spec"].ObjectValue()
+		if paused := spec["paused"]; !paused.IsBool() || !paused.BoolValue() {
+			t.Errorf("expected the %s deployment to be paused, got %v", name, paused)
+		}
+		if mrs := spec["minReadySeconds"]; !mrs.IsNumber() || mrs.NumberValue() != 20 {
+			t.Errorf("expected the %s deployment min ready seconds to be 20, got %v", name, mrs)
+		}
+	}
+}
+
+func Test_U_Monitoring_Mesh(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mesh        *parts.MeshArgs
+		ExpectedPod map[string]string
+		ExpectedJob map[string]string
+	}{
+		"none": {},
+		"istio-out": {
+			Mesh:        &parts.MeshArgs{Provider: parts.MeshIstio},
+			ExpectedPod: map[string]string{"sidecar.istio.io/inject": "false"},
+			ExpectedJob: map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+		"linkerd-partial": {
+			Mesh: &parts.MeshArgs{Provider: parts.MeshLinkerd, Inject: true, ExcludeInboundPorts: []int{4317}},
+			ExpectedPod: map[string]string{
+				"linkerd.io/inject":                    "enabled",
+				"config.linkerd.io/skip-inbound-ports": "4317",
+			},
+			ExpectedJob: map[string]string{"linkerd.io/inject": "disabled"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewMonitoring(ctx, "monitoring", &MonitoringArgs{
+					Mesh:               tt.Mesh,
+					LogShipper:         &parts.LogShipperArgs{},
+					OTELValidateConfig: true,
+					PersesBootstrap:    &parts.PersesBootstrapArgs{},
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// Only the mesh annotations are compared, not the config checksums
+			templateAnnotations := func(res resource.PropertyMap) map[string]string {
+				out := map[string]string{}
+				meta := res["spec"].ObjectValue()["template"].ObjectValue()["metadata"]
+				if !meta.IsObject() || !meta.ObjectValue()["annotations"].IsObject() {
+					return out
+				}
+				for k, v := range meta.ObjectValue()["annotations"].ObjectValue() {
+					if strings.Contains(string(k), "istio.io/") || strings.Contains(string(k), "linkerd.io/") {
+						out[string(k)] = v.StringValue()
+					}
+				}
+				return out
+			}
+			expectedPod, expectedJob := tt.ExpectedPod, tt.ExpectedJob
+			if expectedPod == nil {
+				expectedPod, expectedJob = map[string]string{}, map[string]string{}
+			}
+
+			for _, name := range []string{"otel", "jaeger", "prometheus"} {
+				got := templateAnnotations(m.ByName("kubernetes:apps/v1:Deployment", name))
+				if !maps.Equal(got, expectedPod) {
+					t.Errorf("expected the %s pods annotations %v, got %v", name, expectedPod, got)
+				}
+			}
+			if got := templateAnnotations(m.ByName("kubernetes:apps/v1:DaemonSet", "log-shipper")); !maps.Equal(got, expectedPod) {
+				t.Errorf("expected the log shipper pods annotations %v, got %v", expectedPod, got)
+			}
+			for _, name := range []string{"otel-config-validation", "perses-bootstrap"} {
+				got := templateAnnotations(m.ByName("kubernetes:batch/v1:Job", name))
+				if !maps.Equal(got, expectedJob) {
+					t.Errorf("expected the %s pods annotations %v, got %v", name, expectedJob, got)
+				}
+			}
+
+			values := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()
+			got := map[string]string{}
+			if values["podAnnotations"].IsObject() {
+				for k, v := range values["podAnnotations"].ObjectValue() {
+					got[string(k)] = v.StringValue()
+				}
+			}
+			if !maps.Equal(got, expectedPod) {
+				t.Errorf("expected the perses pods annotations %v, got %v", expectedPod, got)
+			}
+		})
+	}
+}
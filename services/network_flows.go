@@ -0,0 +1,360 @@
+package services
+
+import (
+	"fmt"
+	"net"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+const (
+	// NetworkFlowsConfigMapName is the name of the ConfigMap containing the
+	// flow matrix, in the Monitoring namespace.
+	NetworkFlowsConfigMapName = "monitoring-network-flows"
+
+	partOTEL       = "otel-collector"
+	partJaeger     = "jaeger"
+	partPrometheus = "prometheus"
+	partPerses     = "perses"
+	partPersesBoot = "perses-bootstrap"
+	partLogShipper = "log-shipper"
+)
+
+// networkFlows returns the flows the network policies of the (defaulted)
+// arguments allow, the namespace ones first. The NetworkPolicies rules are
+// built from them, and the flow matrix rendered.
+func networkFlows(args *MonitoringArgs) []parts.NetworkFlow {
+	otelPorts := parts.OtelReceiverPorts(otelCollectorArgs(args))
+	otlpPort := otelPorts[:1]
+	promPort := []parts.FlowPort{{Port: prometheusPort(args)}}
+	jaegerPort := []parts.FlowPort{{Port: parts.JaegerOTLPPort}}
+	persesPort := []parts.FlowPort{{Port: parts.PersesPort}}
+
+	flows := parts.NamespaceFlows()
+
+	// OTEL Collector, reached by the ingress peers
+	flows = append(flows, otelIngressFlows(args.IngressPeers, args.DenyAllIngress, otelPorts)...)
+	// The metrics are pushed on the Prometheus port, whatever the receiver,
+	// but for an agent Prometheus scraping them
+	if args.PrometheusAgentMode {
+		flows = append(flows, ingressFlow("in-otel-scrape-ntp", partOTEL, partPrometheus, []parts.FlowPort{{Port: parts.OtelMetricsPort}}))
+	} else {
+		metricsPush := egressFlow("otel-ntp", partOTEL, partPrometheus, promPort)
+		metricsPush.Note = metricsPushNote(args)
+		flows = append(flows, metricsPush)
+	}
+	flows = append(flows, egressFlow("otel-ntp", partOTEL, partJaeger, jaegerPort))
+	flows = append(flows, kafkaEgressFlows(args.OTELKafka)...)
+
+	// Perses and the log shipper watch the API server through a policy of
+	// the template, only described
+	flows = append(flows, apiServerFlow("perses-to-apiserver-netpol", partPerses))
+	if args.PersesBootstrap != nil {
+		flows = append(flows,
+			egressFlow("perses-bootstrap-ntp", partPersesBoot, partPerses, persesPort),
+			ingressFlow("in-perses-bootstrap-ntp", partPerses, partPersesBoot, persesPort),
+		)
+	}
+
+	// Jaeger
+	flows = append(flows,
+		ingressFlow("jaeger-ntp", partJaeger, partOTEL, jaegerPort),
+		ingressFlow("jaeger-ntp", partJaeger, partPrometheus, []parts.FlowPort{{Port: parts.JaegerAdminPort}}),
+		egressFlow("jaeger-ntp", partJaeger, partPrometheus, promPort),
+	)
+
+	// Prometheus, then the destinations of its scrape jobs
+	// Neither receiving nor queried by Perses when running as an agent
+	if !args.PrometheusAgentMode {
+		metricsReceive := ingressFlow("prom-ntp", partPrometheus, partOTEL, promPort)
+		metricsReceive.Note = metricsPushNote(args)
+		flows = append(flows, metricsReceive)
+	}
+	flows = append(flows, ingressFlow("prom-ntp", partPrometheus, partJaeger, promPort))
+	if !args.PrometheusAgentMode {
+		flows = append(flows, ingressFlow("prom-ntp", partPrometheus, partPerses, promPort))
+	}
+	flows = append(flows, scrapeEgressFlows(prometheusScrapeConfigs(args))...)
+	if args.PrometheusAnnotationScrape != nil {
+		flows = append(flows, apiServerFlow("prometheus-to-apiserver-netpol", partPrometheus))
+	}
+
+	if args.LogShipper != nil {
+		flows = append(flows, egressFlow("log-shipper-ntp", partLogShipper, partOTEL, otlpPort))
+		if !args.DenyAllIngress {
+			flows = append(flows, ingressFlow("in-otel-logshipper-ntp", partOTEL, partLogShipper, otlpPort))
+		}
+		flows = append(flows, apiServerFlow("log-shipper-to-apiserver-netpol", partLogShipper))
+	}
+
+	if args.OpenShift != nil && args.OpenShift.Routes {
+		router := []parts.FlowPeer{
+			{
+				Name: "openshift router",
+				NamespaceLabels: map[string]string{
+					routerNamespaceLabel: "",
+				},
+			},
+		}
+		flows = append(flows,
+			parts.NetworkFlow{
+				Policy:    "router-ntp",
+				Direction: parts.FlowIngress,
+				Part:      partJaeger,
+				Peers:     router,
+				Ports:     []parts.FlowPort{{Port: parts.JaegerUIPort}},
+			},
+			parts.NetworkFlow{
+				Policy:    "router-ntp",
+				Direction: parts.FlowIngress,
+				Part:      partPerses,
+				Peers:     router,
+				Ports:     persesPort,
+			},
+			ingressFlow("perses-scrape-ntp", partPerses, partPrometheus, persesPort),
+		)
+		if args.Hosts != nil && args.Hosts.ExposePrometheus {
+			flows = append(flows, parts.NetworkFlow{
+				Policy:    "router-ntp",
+				Direction: parts.FlowIngress,
+				Part:      partPrometheus,
+				Peers:     router,
+				Ports:     promPort,
+			})
+		}
+	}
+
+	return meshNotes(flows, args.Mesh)
+}
+
+// meshNotes notes the flows between the parts whether the injected sidecars
+// secure them with mTLS, or are bypassed by the excluded ports. The rules
+// are the same, the sidecars keeping the pods IPs and ports.
+func meshNotes(flows []parts.NetworkFlow, mesh *parts.MeshArgs) []parts.NetworkFlow {
+	if !mesh.Injected() {
+		return flows
+	}
+	for i, flow := range flows {
+		if !betweenParts(flow) {
+			continue
+		}
+		note := mesh.Provider + " mTLS"
+		for _, port := range flow.Ports {
+			if mesh.Excluded(port.Port) {
+				note = "bypasses the " + mesh.Provider + " sidecars"
+				break
+			}
+		}
+		if flow.Note != "" {
+			note = flow.Note + ", " + note
+		}
+		flows[i].Note = note
+	}
+	return flows
+}
+
+// betweenParts returns whether the flow is between parts of the Monitoring
+// only, hence between sidecars.
+func betweenParts(flow parts.NetworkFlow) bool {
+	if flow.Part == parts.AllPods || len(flow.Peers) == 0 {
+		return false
+	}
+	for _, peer := range flow.Peers {
+		if peer.Part == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ingressFlow is the flow from a part to another one, allowed on the ingress
+// of the latter by the policy.
+func ingressFlow(policy, part, from string, ports []parts.FlowPort) parts.NetworkFlow {
+	return parts.NetworkFlow{
+		Policy:    policy,
+		Direction: parts.FlowIngress,
+		Part:      part,
+		Peers:     []parts.FlowPeer{{Part: from}},
+		Ports:     ports,
+	}
+}
+
+// egressFlow is the flow from a part to another one, allowed on the egress
+// of the former by the policy.
+func egressFlow(policy, part, to string, ports []parts.FlowPort) parts.NetworkFlow {
+	return parts.NetworkFlow{
+		Policy:    policy,
+		Direction: parts.FlowEgress,
+		Part:      part,
+		Peers:     []parts.FlowPeer{{Part: to}},
+		Ports:     ports,
+	}
+}
+
+func apiServerFlow(policy, part string) parts.NetworkFlow {
+	return parts.NetworkFlow{
+		Policy:    policy,
+		Direction: parts.FlowEgress,
+		Part:      part,
+		Peers:     []parts.FlowPeer{{Name: "kube-apiserver"}},
+		Note:      "rendered from the API server policy template",
+	}
+}
+
+// prometheusPort is the port Prometheus serves on, defaulted.
+func prometheusPort(args *MonitoringArgs) int {
+	if args.PrometheusPort == 0 {
+		return parts.DefaultPrometheusPort
+	}
+	return args.PrometheusPort
+}
+
+// metricsPushNote describes the path the OTEL Collector pushes the metrics
+// to, as NetworkPolicies could not restrict it.
+func metricsPushNote(args *MonitoringArgs) string {
+	if args.PrometheusOTLPIngestion {
+		return "metrics otlp /api/v1/otlp"
+	}
+	return "metrics remote write /api/v1/write"
+}
+
+// otelIngressFlows returns the flow toward the OTEL Collector ports. Without
+// peers, every source is allowed.
+func otelIngressFlows(peers []IngressPeer, denyAll bool, ports []parts.FlowPort) []parts.NetworkFlow {
+	if denyAll {
+		return nil
+	}
+
+	flow := parts.NetworkFlow{
+		Policy:    "in-otel-ntp",
+		Direction: parts.FlowIngress,
+		Part:      partOTEL,
+		Ports:     ports,
+	}
+	for _, peer := range peers {
+		flow.Peers = append(flow.Peers, parts.FlowPeer{
+			NamespaceLabels: peer.NamespaceLabels,
+			PodLabels:       peer.PodLabels,
+			CIDR:            peer.CIDR,
+		})
+	}
+	return []parts.NetworkFlow{flow}
+}
+
+// kafkaEgressFlows returns the flows toward the Kafka brokers, if any. The
+// brokers given by IP are restricted to it, the ones by hostname only to
+// their port as NetworkPolicies do not resolve names.
+func kafkaEgressFlows(kafka *parts.KafkaExporterArgs) []parts.NetworkFlow {
+	if kafka == nil {
+		return nil
+	}
+
+	flows := []parts.NetworkFlow{}
+	for _, broker := range kafka.BrokerEndpoints() {
+		flow := parts.NetworkFlow{
+			Policy:    "otel-ntp",
+			Direction: parts.FlowEgress,
+			Part:      partOTEL,
+			Ports: []parts.FlowPort{
+				{Port: broker.Port, Protocol: "TCP"},
+			},
+			Note: "kafka broker " + net.JoinHostPort(broker.Host, fmt.Sprint(broker.Port)),
+		}
+		if ip := net.ParseIP(broker.Host); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			flow.Peers = []parts.FlowPeer{
+				{CIDR: fmt.Sprintf("%s/%d", ip.String(), bits)},
+			}
+		}
+		flows = append(flows, flow)
+	}
+	return flows
+}
+
+// scrapeEgressFlows returns the flows toward the destinations of the scrape
+// jobs, one per destination.
+func scrapeEgressFlows(scs []parts.ScrapeConfig) []parts.NetworkFlow {
+	flows := []parts.NetworkFlow{}
+	for _, sc := range scs {
+		for _, dest := range sc.Destinations {
+			flow := parts.NetworkFlow{
+				Policy:    "prom-egress-ntp",
+				Direction: parts.FlowEgress,
+				Part:      partPrometheus,
+				Peers: []parts.FlowPeer{
+					{
+						NamespaceLabels: dest.NamespaceLabels,
+						PodLabels:       dest.PodLabels,
+						CIDR:            dest.CIDR,
+					},
+				},
+				Note: "scrape job " + sc.JobName,
+			}
+			for _, port := range dest.Ports {
+				flow.Ports = append(flow.Ports, parts.FlowPort{Port: port, Protocol: "TCP"})
+			}
+			flows = append(flows, flow)
+		}
+	}
+	return flows
+}
+
+// partSelectors selects the pods of the deployed parts, for the flows
+// between them.
+func (mon *Monitoring) partSelectors() map[string]parts.PartSelector {
+	sels := map[string]parts.PartSelector{}
+	if mon.otel != nil {
+		sels[partOTEL] = parts.PartSelector{Namespace: mon.ns.Name, PodLabels: mon.otel.PodLabels}
+	}
+	if mon.jaeger != nil {
+		sels[partJaeger] = parts.PartSelector{Namespace: mon.ns.Name, PodLabels: mon.jaeger.PodLabels}
+	}
+	if mon.prom != nil {
+		sels[partPrometheus] = parts.PartSelector{Namespace: mon.ns.Name, PodLabels: mon.prom.PodLabels}
+	}
+	if mon.perses != nil {
+		sels[partPerses] = parts.PartSelector{Namespace: mon.ns.Name, PodLabels: mon.perses.PodLabels}
+		sels[partPersesBoot] = parts.PartSelector{Namespace: mon.ns.Name, PodLabels: mon.perses.BootstrapPodLabels}
+	}
+	if mon.shipper != nil {
+		sels[partLogShipper] = parts.PartSelector{Namespace: mon.shipper.Namespace, PodLabels: mon.shipper.PodLabels}
+	}
+	return sels
+}
+
+// networkFlowsMatrix renders the flow matrix, and records it in a ConfigMap
+// if requested.
+func (mon *Monitoring) networkFlowsMatrix(
+	ctx *pulumi.Context,
+	args *MonitoringArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	mon.NetworkFlows = pulumi.String(parts.RenderNetworkFlows(mon.flows)).ToStringOutput()
+	if !args.NetworkFlowsConfigMap {
+		return
+	}
+
+	mon.flowscm, err = corev1.NewConfigMap(ctx, "network-flows", &corev1.ConfigMapArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Name:      pulumi.String(NetworkFlowsConfigMapName),
+			Namespace: mon.ns.Name,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("network-flows"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Data: pulumi.StringMap{
+			"flows.md": mon.NetworkFlows,
+		},
+	}, opts...)
+	return
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+type (
+	// OpenShiftArgs adapts the Monitoring to OpenShift.
+	//
+	// No component pins its UIDs nor fsGroup so the restricted SCC assigns
+	// them, and the Pod Security Admission labels are left to the OpenShift
+	// synchronization. The extractor needs its --platform-uid flag.
+	OpenShiftArgs struct {
+		// Routes exposes the Jaeger and Perses UIs through edge-terminated
+		// Routes, with hosts assigned by the router unless derived from the
+		// Hosts arguments.
+		Routes bool
+	}
+)
+
+const (
+	// routerNamespaceLabel selects the OpenShift router namespace.
+	routerNamespaceLabel = "policy-group.network.openshift.io/ingress"
+)
+
+// provisionOpenShift creates the Routes of the UIs and allows the router
+// to reach them, and Prometheus to keep scraping Perses.
+func (mon *Monitoring) provisionOpenShift(
+	ctx *pulumi.Context,
+	args *MonitoringArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	if !args.OpenShift.Routes {
+		return
+	}
+
+	hosts := map[string]string{}
+	if args.Hosts != nil {
+		hosts = args.Hosts.hosts()
+	}
+	exposed := []string{"jaeger", "perses"}
+
+	mon.jgrRoute, err = newRoute(ctx, "jaeger-ui", mon.ns.Name, mon.jaeger.UIServiceName, pulumi.String("ui"), hosts[partJaeger], args.Hosts, opts...)
+	if err != nil {
+		return
+	}
+	mon.prsRoute, err = newRoute(ctx, "perses", mon.ns.Name, mon.perses.ServiceName, pulumi.Int(parts.PersesPort), hosts[partPerses], args.Hosts, opts...)
+	if err != nil {
+		return
+	}
+	if host, ok := hosts[partPrometheus]; ok {
+		mon.promRoute, err = newRoute(ctx, "prometheus", mon.ns.Name, mon.prom.Service.Name, mon.prom.Port, host, args.Hosts, opts...)
+		if err != nil {
+			return
+		}
+		exposed = append(exposed, "prometheus")
+	}
+
+	// Allow the router to reach the UIs
+	mon.routerntp, err = netwv1.NewNetworkPolicy(ctx, "router-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Namespace: mon.ns.Name,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Ingress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchExpressions: metav1.LabelSelectorRequirementArray{
+					metav1.LabelSelectorRequirementArgs{
+						Key:      pulumi.String("app.kubernetes.io/name"),
+						Operator: pulumi.String("In"),
+						Values:   pulumi.ToStringArray(exposed),
+					},
+				},
+			},
+			// Router -> Jaeger UI, Perses and Prometheus if exposed
+			Ingress: parts.IngressRules(mon.flows, "router-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// The router policy isolates Perses, let Prometheus scrape it still
+	mon.prsScrapentp, err = netwv1.NewNetworkPolicy(ctx, "perses-scrape-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Namespace: mon.ns.Name,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Ingress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: mon.perses.PodLabels,
+			},
+			// Prometheus -> Perses (metrics)
+			Ingress: parts.IngressRules(mon.flows, "perses-scrape-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	return
+}
+
+// newRoute exposes the Service port through an edge-terminated OpenShift Route,
+// at the host if any, else at the one assigned by the router.
+func newRoute(
+	ctx *pulumi.Context,
+	name string,
+	namespace, service pulumi.StringInput,
+	targetPort pulumi.Input,
+	host string,
+	hosts *HostsArgs,
+	opts ...pulumi.ResourceOption,
+) (*apiextensions.CustomResource, error) {
+	spec := pulumi.Map{
+		"to": pulumi.Map{
+			"kind": pulumi.String("Service"),
+			"name": service,
+		},
+		"port": pulumi.Map{
+			"targetPort": targetPort,
+		},
+		"tls": pulumi.Map{
+			"termination":                   pulumi.String("edge"),
+			"insecureEdgeTerminationPolicy": pulumi.String("Redirect"),
+		},
+	}
+	var annotations pulumi.StringMap
+	if host != "" {
+		spec["host"] = pulumi.String(host)
+		if hosts != nil && hosts.ExternalDNS {
+			annotations = pulumi.StringMap{
+				externalDNSHostnameAnnotation: pulumi.String(host),
+			}
+		}
+	}
+
+	return apiextensions.NewCustomResource(ctx, name, &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("route.openshift.io/v1"),
+		Kind:       pulumi.String("Route"),
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String(name),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Annotations: annotations,
+		},
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": spec,
+		},
+	}, opts...)
+}
+
+// routeURL returns the URL of the Route, once its host is assigned by the
+// router. Empty until then.
+func routeURL(route *apiextensions.CustomResource) pulumi.StringOutput {
+	return route.OtherFields.ApplyT(func(fields map[string]any) string {
+		spec, ok := fields["spec"].(map[string]any)
+		if !ok {
+			return ""
+		}
+		host, ok := spec["host"].(string)
+		if !ok || host == "" {
+			return ""
+		}
+		// The Routes are edge-terminated
+		return "https://" + host
+	}).(pulumi.StringOutput)
+}
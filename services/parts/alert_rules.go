@@ -0,0 +1,201 @@
+package parts
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// AlertRulesArgs ships the default alerts on the health of the
+	// Monitoring itself, see DefaultAlerts.
+	AlertRulesArgs struct {
+		// PrometheusRule renders the alerts as a PrometheusRule, for the
+		// Prometheus Operator of the cluster to evaluate them (e.g. along
+		// its Alertmanager), rather than into the rule files of the bundled
+		// Prometheus. Requires the PrometheusRule CRD.
+		PrometheusRule bool
+
+		// PrometheusRuleLabels label the PrometheusRule, e.g. for the
+		// ruleSelector of the Prometheus Operator to match it.
+		// Requires PrometheusRule.
+		PrometheusRuleLabels map[string]string
+
+		// Disabled alerts, by name.
+		Disabled []string
+
+		// PVCFullPercent is the used space of a PVC, in percent, above which
+		// it is nearly full.
+		// Defaults to 85.
+		PVCFullPercent int
+
+		// For is how long a condition lasts before its alert fires, as a
+		// Prometheus duration.
+		// Defaults to 5m.
+		For string
+	}
+
+	ruleGroups struct {
+		Groups []ruleGroup `yaml:"groups" json:"groups"`
+	}
+
+	ruleGroup struct {
+		Name  string      `yaml:"name" json:"name"`
+		Rules []alertRule `yaml:"rules" json:"rules"`
+	}
+
+	alertRule struct {
+		Alert       string            `yaml:"alert" json:"alert"`
+		Expr        string            `yaml:"expr" json:"expr"`
+		For         string            `yaml:"for,omitempty" json:"for,omitempty"`
+		Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+		Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	}
+)
+
+const (
+	// AlertCollectorExporterFailures fires while the OTEL Collector fails to
+	// export, given its self-metrics.
+	AlertCollectorExporterFailures = "MonitoringCollectorExporterFailures"
+
+	// AlertPrometheusScrapeFailures fires while a scrape target is down.
+	AlertPrometheusScrapeFailures = "MonitoringPrometheusScrapeFailures"
+
+	// AlertPVCNearlyFull fires while a PVC of the namespace is above
+	// PVCFullPercent, given the kubelet volume stats.
+	AlertPVCNearlyFull = "MonitoringPVCNearlyFull"
+
+	// AlertJaegerDown fires while the jaeger job is down or missing.
+	AlertJaegerDown = "MonitoringJaegerDown"
+
+	defaultPVCFullPercent = 85
+	defaultAlertFor       = "5m"
+
+	// PrometheusRulesFile is where the alert rules land in the Prometheus
+	// container, when rendered into its rule files.
+	PrometheusRulesFile = "/etc/prometheus/rules.yaml"
+)
+
+// DefaultAlerts are the names of the alerts the Monitoring ships.
+var DefaultAlerts = []string{
+	AlertCollectorExporterFailures,
+	AlertPrometheusScrapeFailures,
+	AlertPVCNearlyFull,
+	AlertJaegerDown,
+}
+
+//go:embed prometheus-alerts.yaml.tmpl
+var prometheusAlerts string
+var prometheusAlertsTemplate *template.Template
+
+func init() {
+	tmpl, err := template.New("prometheus-alerts").Parse(prometheusAlerts)
+	if err != nil {
+		panic(fmt.Errorf("invalid Prometheus alerts template: %s", err))
+	}
+	prometheusAlertsTemplate = tmpl
+}
+
+// alertRulesDefaults returns a copy of the alert rules arguments, defaulted.
+func alertRulesDefaults(args *AlertRulesArgs) *AlertRulesArgs {
+	if args == nil {
+		return nil
+	}
+	cpy := *args
+	if cpy.PVCFullPercent == 0 {
+		cpy.PVCFullPercent = defaultPVCFullPercent
+	}
+	if cpy.For == "" {
+		cpy.For = defaultAlertFor
+	}
+	return &cpy
+}
+
+// checkAlertRules validates the (defaulted) alert rules arguments, if any.
+func checkAlertRules(args *AlertRulesArgs) error {
+	if args == nil {
+		return nil
+	}
+	if len(args.PrometheusRuleLabels) != 0 && !args.PrometheusRule {
+		return errors.New("alert rules labels require the prometheus rule")
+	}
+	for _, name := range args.Disabled {
+		if !slices.Contains(DefaultAlerts, name) {
+			return errors.Errorf("unknown alert %q to disable, expected one of %v", name, DefaultAlerts)
+		}
+	}
+	if args.PVCFullPercent < 1 || args.PVCFullPercent > 100 {
+		return errors.Errorf("pvc full percent %d is out of the 1-100 range", args.PVCFullPercent)
+	}
+	if !promDurationRegex.MatchString(args.For) {
+		return errors.Errorf("invalid alert duration %q, expected a Prometheus duration (e.g. 5m)", args.For)
+	}
+	return nil
+}
+
+// alertRuleGroups returns the rule groups of the (defaulted) arguments, the
+// PVCs being the ones of the namespace. Disabled alerts are filtered out.
+func alertRuleGroups(args *AlertRulesArgs, namespace string) (*ruleGroups, error) {
+	buf := &bytes.Buffer{}
+	if err := prometheusAlertsTemplate.Execute(buf, map[string]any{
+		"Namespace":      namespace,
+		"PVCFullPercent": args.PVCFullPercent,
+		"For":            args.For,
+	}); err != nil {
+		return nil, err
+	}
+
+	groups := &ruleGroups{}
+	if err := yaml.Unmarshal(buf.Bytes(), groups); err != nil {
+		return nil, errors.Wrap(err, "invalid alert rules")
+	}
+	for i, g := range groups.Groups {
+		groups.Groups[i].Rules = slices.DeleteFunc(g.Rules, func(r alertRule) bool {
+			return slices.Contains(args.Disabled, r.Alert)
+		})
+	}
+	return groups, nil
+}
+
+// renderAlertRules renders the Prometheus rule file of the (defaulted)
+// arguments.
+func renderAlertRules(args *AlertRulesArgs, namespace string) (string, error) {
+	groups, err := alertRuleGroups(args, namespace)
+	if err != nil {
+		return "", err
+	}
+	b := &bytes.Buffer{}
+	enc := yaml.NewEncoder(b)
+	enc.SetIndent(2)
+	if err := enc.Encode(groups); err != nil {
+		return "", errors.Wrap(err, "encoding alert rules")
+	}
+	if err := enc.Close(); err != nil {
+		return "", errors.Wrap(err, "encoding alert rules")
+	}
+	return b.String(), nil
+}
+
+// prometheusRuleSpec returns the PrometheusRule spec of the (defaulted)
+// arguments, as its CRD expects it.
+func prometheusRuleSpec(args *AlertRulesArgs, namespace string) (map[string]any, error) {
+	groups, err := alertRuleGroups(args, namespace)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(groups)
+	if err != nil {
+		return nil, err
+	}
+	spec := map[string]any{}
+	if err := json.Unmarshal(b, &spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
@@ -0,0 +1,347 @@
+package parts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+var (
+	alertNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	labelMatchRegex = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=|!=|=~|!~)\s*"(?:[^"\\]|\\.)*"\s*$`)
+)
+
+// checkRuleFile validates the rule file as Prometheus loads it: no unknown
+// field, valid names and durations, and expressions whose brackets, strings
+// and label matchers are well-formed.
+func checkRuleFile(t *testing.T, content string) ruleGroups {
+	t.Helper()
+
+	groups := ruleGroups{}
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&groups); err != nil {
+		t.Fatalf("invalid rule file: %s", err)
+	}
+	for _, g := range groups.Groups {
+		if g.Name == "" {
+			t.Error("expected the rule group to be named")
+		}
+		for _, r := range g.Rules {
+			if !alertNameRegex.MatchString(r.Alert) {
+				t.Errorf("invalid alert name %q", r.Alert)
+			}
+			if r.For != "" && !promDurationRegex.MatchString(r.For) {
+				t.Errorf("%s: invalid for duration %q", r.Alert, r.For)
+			}
+			for k := range r.Labels {
+				if !labelNameRegex.MatchString(k) {
+					t.Errorf("%s: invalid label name %q", r.Alert, k)
+				}
+			}
+			if err := checkPromQL(r.Expr); err != "" {
+				t.Errorf("%s: invalid expression %q: %s", r.Alert, r.Expr, err)
+			}
+		}
+	}
+	return groups
+}
+
+// checkPromQL returns what is wrong with the expression brackets, strings
+// and label matchers, if anything.
+func checkPromQL(expr string) string {
+	if strings.TrimSpace(expr) == "" {
+		return "empty expression"
+	}
+	stack := []rune{}
+	matchers := []*strings.Builder{}
+	inString := false
+	for i := 0; i < len(expr); i++ {
+		c := rune(expr[i])
+		if len(matchers) != 0 && c != '}' {
+			matchers[len(matchers)-1].WriteRune(c)
+		}
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+				if len(matchers) != 0 && i < len(expr) {
+					matchers[len(matchers)-1].WriteByte(expr[i])
+				}
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+			if c == '{' {
+				matchers = append(matchers, &strings.Builder{})
+			}
+		case c == ')' || c == ']' || c == '}':
+			open := map[rune]rune{')': '(', ']': '[', '}': '{'}[c]
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return "unbalanced " + string(c)
+			}
+			stack = stack[:len(stack)-1]
+			if c == '}' {
+				m := matchers[len(matchers)-1]
+				matchers = matchers[:len(matchers)-1]
+				for _, lm := range strings.Split(m.String(), ",") {
+					if !labelMatchRegex.MatchString(lm) {
+						return "invalid label matcher " + lm
+					}
+				}
+			}
+		}
+	}
+	if inString {
+		return "unterminated string"
+	}
+	if len(stack) != 0 {
+		return "unclosed " + string(stack[len(stack)-1])
+	}
+	return ""
+}
+
+func Test_U_AlertRules_Render(t *testing.T) {
+	t.Parallel()
+
+	args := alertRulesDefaults(&AlertRulesArgs{})
+	if err := checkAlertRules(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rules, err := renderAlertRules(args, "monitoring")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join("testdata", "prometheus-alerts.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	if !bytes.Equal([]byte(rules), expected) {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, rules)
+	}
+
+	// Every default alert is shipped, and only them
+	alerts := []string{}
+	for _, g := range checkRuleFile(t, rules).Groups {
+		for _, r := range g.Rules {
+			alerts = append(alerts, r.Alert)
+		}
+	}
+	if !slices.Equal(alerts, DefaultAlerts) {
+		t.Errorf("expected alerts %v, got %v", DefaultAlerts, alerts)
+	}
+}
+
+func Test_U_AlertRules_Thresholds(t *testing.T) {
+	t.Parallel()
+
+	args := alertRulesDefaults(&AlertRulesArgs{
+		Disabled:       []string{AlertJaegerDown, AlertCollectorExporterFailures},
+		PVCFullPercent: 70,
+		For:            "15m",
+	})
+	if err := checkAlertRules(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rules, err := renderAlertRules(args, "monitoring-abcd")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	groups := checkRuleFile(t, rules)
+	alerts := []string{}
+	for _, r := range groups.Groups[0].Rules {
+		alerts = append(alerts, r.Alert)
+		if r.For != "15m" {
+			t.Errorf("%s: expected to fire after 15m, got %s", r.Alert, r.For)
+		}
+		if r.Alert == AlertPVCNearlyFull && r.Expr != `100 * kubelet_volume_stats_used_bytes{namespace="monitoring-abcd"} / kubelet_volume_stats_capacity_bytes{namespace="monitoring-abcd"} > 70` {
+			t.Errorf("unexpected pvc expression %s", r.Expr)
+		}
+	}
+	if !slices.Equal(alerts, []string{AlertPrometheusScrapeFailures, AlertPVCNearlyFull}) {
+		t.Errorf("expected the disabled alerts to be filtered out, got %v", alerts)
+	}
+}
+
+func Test_U_AlertRules_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *AlertRulesArgs
+		ExpectErr bool
+	}{
+		"none": {},
+		"defaults": {
+			Args: &AlertRulesArgs{},
+		},
+		"prometheus-rule-labels": {
+			Args: &AlertRulesArgs{
+				PrometheusRule:       true,
+				PrometheusRuleLabels: map[string]string{"release": "kube-prometheus-stack"},
+			},
+		},
+		"labels-without-prometheus-rule": {
+			Args: &AlertRulesArgs{
+				PrometheusRuleLabels: map[string]string{"release": "kube-prometheus-stack"},
+			},
+			ExpectErr: true,
+		},
+		"unknown-alert": {
+			Args: &AlertRulesArgs{
+				Disabled: []string{"MonitoringDiskFull"},
+			},
+			ExpectErr: true,
+		},
+		"percent-out-of-range": {
+			Args: &AlertRulesArgs{
+				PVCFullPercent: 101,
+			},
+			ExpectErr: true,
+		},
+		"go-duration": {
+			Args: &AlertRulesArgs{
+				For: "1.5m",
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkAlertRules(alertRulesDefaults(tt.Args))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_AlertRules(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		AgentMode            bool
+		AlertRules           *AlertRulesArgs
+		ExpectRuleFiles      bool
+		ExpectPrometheusRule bool
+		ExpectErr            bool
+	}{
+		"none": {},
+		"rule-files": {
+			AlertRules:      &AlertRulesArgs{},
+			ExpectRuleFiles: true,
+		},
+		"prometheus-rule": {
+			AlertRules: &AlertRulesArgs{
+				PrometheusRule:       true,
+				PrometheusRuleLabels: map[string]string{"release": "kube-prometheus-stack"},
+			},
+			ExpectPrometheusRule: true,
+		},
+		"agent-prometheus-rule": {
+			AgentMode: true,
+			AlertRules: &AlertRulesArgs{
+				PrometheusRule: true,
+			},
+			ExpectPrometheusRule: true,
+		},
+		"agent-rule-files": {
+			AgentMode:  true,
+			AlertRules: &AlertRulesArgs{},
+			ExpectErr:  true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := &PrometheusArgs{
+				AgentMode:  tt.AgentMode,
+				AlertRules: tt.AlertRules,
+			}
+			if tt.AgentMode {
+				args.RemoteWriteURLs = pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"})
+			}
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args.Namespace = pulumi.String("monitoring")
+				_, err := NewPrometheus(ctx, "prometheus", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// The rule file is loaded by Prometheus, from its ConfigMap
+			data := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")["data"].ObjectValue()
+			rules, ok := data["rules"]
+			if ok != tt.ExpectRuleFiles {
+				t.Fatalf("expected the rules presence to be %t", tt.ExpectRuleFiles)
+			}
+			cfg := struct {
+				RuleFiles []string `yaml:"rule_files"`
+			}{}
+			if err := yaml.Unmarshal([]byte(data["config"].StringValue()), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			if (len(cfg.RuleFiles) != 0) != tt.ExpectRuleFiles {
+				t.Errorf("expected the rule_files presence to be %t, got %v", tt.ExpectRuleFiles, cfg.RuleFiles)
+			}
+			if tt.ExpectRuleFiles {
+				checkRuleFile(t, rules.StringValue())
+
+				dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+				vol := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["volumes"].ArrayValue()[0].ObjectValue()
+				paths := []string{}
+				for _, item := range vol["configMap"].ObjectValue()["items"].ArrayValue() {
+					paths = append(paths, item.ObjectValue()["path"].StringValue())
+				}
+				if !slices.Contains(paths, "rules.yaml") || !slices.Contains(cfg.RuleFiles, PrometheusRulesFile) {
+					t.Errorf("expected the rules to be mounted at %s, got %v and %v", PrometheusRulesFile, paths, cfg.RuleFiles)
+				}
+			}
+
+			// Or the Prometheus Operator evaluates them
+			rule := m.ByName("kubernetes:monitoring.coreos.com/v1:PrometheusRule", "prometheus-alerts")
+			if (rule != nil) != tt.ExpectPrometheusRule {
+				t.Fatalf("expected the prometheus rule presence to be %t", tt.ExpectPrometheusRule)
+			}
+			if rule == nil {
+				return
+			}
+			labels := rule["metadata"].ObjectValue()["labels"].ObjectValue()
+			for k, v := range tt.AlertRules.PrometheusRuleLabels {
+				if labels[resource.PropertyKey(k)].StringValue() != v {
+					t.Errorf("expected the prometheus rule label %s=%s, got %v", k, v, labels)
+				}
+			}
+			groups := rule["spec"].ObjectValue()["groups"].ArrayValue()
+			if len(groups) != 1 || len(groups[0].ObjectValue()["rules"].ArrayValue()) != len(DefaultAlerts) {
+				t.Errorf("expected the %d default alerts in the prometheus rule, got %v", len(DefaultAlerts), groups)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package parts
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.uber.org/multierr"
+)
+
+type (
+	// AnnotationScrapeArgs discovers the pods to scrape through the
+	// Kubernetes API, keyed on the standard prometheus.io annotations:
+	//   - prometheus.io/scrape: "true" to be scraped;
+	//   - prometheus.io/port: the port the metrics are served on, the
+	//     declared container ones otherwise;
+	//   - prometheus.io/path: the metrics path, /metrics otherwise;
+	//   - prometheus.io/scheme: http (default) or https.
+	// Prometheus is granted to list and watch the pods of the namespaces,
+	// and to reach them on any port.
+	AnnotationScrapeArgs struct {
+		// Namespaces the annotated pods are discovered into, e.g. the ones
+		// of the challenges. At least one is required.
+		Namespaces []string
+	}
+)
+
+const (
+	// AnnotationScrapeJobName is the job name of the annotated pods,
+	// reserved when the annotation scrape is turned on.
+	AnnotationScrapeJobName = "kubernetes-pods"
+
+	// namespaceNameLabel is the label Kubernetes sets on every namespace,
+	// with its name.
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+// namespaceRegex matches a namespace name, i.e. a DNS label as per RFC 1123.
+var namespaceRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// checkAnnotationScrape validates the namespaces to discover the pods into.
+func checkAnnotationScrape(as *AnnotationScrapeArgs) (merr error) {
+	if len(as.Namespaces) == 0 {
+		return errors.New("annotation scrape requires at least one namespace")
+	}
+	seen := map[string]struct{}{}
+	for _, ns := range as.Namespaces {
+		if len(ns) > 63 || !namespaceRegex.MatchString(ns) {
+			merr = multierr.Append(merr, errors.Errorf("annotation scrape namespace %q is not a valid namespace name", ns))
+		}
+		if _, ok := seen[ns]; ok {
+			merr = multierr.Append(merr, errors.Errorf("annotation scrape namespace %s is duplicated", ns))
+		}
+		seen[ns] = struct{}{}
+	}
+	return
+}
+
+// ScrapeConfig returns the scrape job of the annotated pods, reached in
+// their namespaces.
+func (as *AnnotationScrapeArgs) ScrapeConfig() ScrapeConfig {
+	dests := make([]ScrapeDestination, 0, len(as.Namespaces))
+	for _, ns := range as.Namespaces {
+		// The port is annotated, hence not known beforehand
+		dests = append(dests, ScrapeDestination{
+			NamespaceLabels: map[string]string{
+				namespaceNameLabel: ns,
+			},
+		})
+	}
+	addr := "$1:$2"
+	return ScrapeConfig{
+		JobName: AnnotationScrapeJobName,
+		KubernetesSDConfigs: []KubernetesSDConfig{
+			{
+				Role: "pod",
+				Namespaces: &KubernetesSDNamespaces{
+					Names: as.Namespaces,
+				},
+			},
+		},
+		RelabelConfigs: []RelabelConfig{
+			{
+				SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_scrape"},
+				Regex:        "true",
+				Action:       "keep",
+			},
+			// The completed pods (e.g. Jobs) do not serve anymore
+			{
+				SourceLabels: []string{"__meta_kubernetes_pod_phase"},
+				Regex:        "Pending|Succeeded|Failed",
+				Action:       "drop",
+			},
+			{
+				SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_scheme"},
+				Regex:        "(https?)",
+				TargetLabel:  "__scheme__",
+			},
+			{
+				SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_path"},
+				Regex:        "(.+)",
+				TargetLabel:  "__metrics_path__",
+			},
+			{
+				SourceLabels: []string{"__address__", "__meta_kubernetes_pod_annotation_prometheus_io_port"},
+				Regex:        `([^:]+)(?::\d+)?;(\d+)`,
+				TargetLabel:  "__address__",
+				Replacement:  &addr,
+			},
+			{
+				Regex:  "__meta_kubernetes_pod_label_(.+)",
+				Action: "labelmap",
+			},
+			{
+				SourceLabels: []string{"__meta_kubernetes_namespace"},
+				TargetLabel:  "namespace",
+			},
+			{
+				SourceLabels: []string{"__meta_kubernetes_pod_name"},
+				TargetLabel:  "pod",
+			},
+		},
+		Destinations: dests,
+	}
+}
+
+// provisionAnnotationScrape grants the Prometheus ServiceAccount to discover
+// the pods of the namespaces, through a Role in each one rather than a
+// ClusterRole, and returns its name.
+func (prom *Prometheus) provisionAnnotationScrape(ctx *pulumi.Context, args *PrometheusArgs, opts ...pulumi.ResourceOption) (pulumi.StringPtrOutput, error) {
+	var err error
+	prom.sa, err = corev1.NewServiceAccount(ctx, "prometheus-sa", &corev1.ServiceAccountArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("prometheus"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return pulumi.StringPtrOutput{}, err
+	}
+
+	for _, ns := range args.AnnotationScrape.Namespaces {
+		role, err := rbacv1.NewRole(ctx, "prometheus-sd-role-"+ns, &rbacv1.RoleArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: pulumi.String(ns),
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("prometheus"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			Rules: rbacv1.PolicyRuleArray{
+				rbacv1.PolicyRuleArgs{
+					ApiGroups: pulumi.ToStringArray([]string{""}),
+					Resources: pulumi.ToStringArray([]string{"pods"}),
+					Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch"}),
+				},
+			},
+		}, opts...)
+		if err != nil {
+			return pulumi.StringPtrOutput{}, err
+		}
+		prom.roles = append(prom.roles, role)
+
+		binding, err := rbacv1.NewRoleBinding(ctx, "prometheus-sd-binding-"+ns, &rbacv1.RoleBindingArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: pulumi.String(ns),
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("prometheus"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			RoleRef: rbacv1.RoleRefArgs{
+				ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+				Kind:     pulumi.String("Role"),
+				Name:     role.Metadata.Name().Elem(),
+			},
+			Subjects: rbacv1.SubjectArray{
+				rbacv1.SubjectArgs{
+					Kind:      pulumi.String("ServiceAccount"),
+					Name:      prom.sa.Metadata.Name().Elem(),
+					Namespace: args.Namespace,
+				},
+			},
+		}, opts...)
+		if err != nil {
+			return pulumi.StringPtrOutput{}, err
+		}
+		prom.bindings = append(prom.bindings, binding)
+	}
+	return prom.sa.Metadata.Name(), nil
+}
@@ -0,0 +1,197 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Prometheus_AnnotationScrape(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := RenderPrometheusConfig(&PrometheusArgs{
+		ScrapeTargets: []ScrapeTarget{JaegerScrapeTarget()},
+		AnnotationScrape: &AnnotationScrapeArgs{
+			Namespaces: []string{"challenges", "ctfd"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out := struct {
+		ScrapeConfigs []map[string]any `yaml:"scrape_configs"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cfg), &out); err != nil {
+		t.Fatalf("invalid configuration: %s", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join("testdata", "prometheus-annotation-scrape.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+
+	// Rendered after the other jobs
+	last := out.ScrapeConfigs[len(out.ScrapeConfigs)-1]
+	if !reflect.DeepEqual(last, expected) {
+		t.Errorf("expected scrape config %v, got %v", expected, last)
+	}
+}
+
+func Test_U_Prometheus_AnnotationScrape_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		AnnotationScrape   *AnnotationScrapeArgs
+		ExtraScrapeConfigs []ScrapeConfig
+		ExpectErr          bool
+	}{
+		"namespaces": {
+			AnnotationScrape: &AnnotationScrapeArgs{Namespaces: []string{"challenges", "ctfd"}},
+		},
+		"no-namespace": {
+			AnnotationScrape: &AnnotationScrapeArgs{},
+			ExpectErr:        true,
+		},
+		"invalid-namespace": {
+			AnnotationScrape: &AnnotationScrapeArgs{Namespaces: []string{"Challenges"}},
+			ExpectErr:        true,
+		},
+		"duplicated-namespace": {
+			AnnotationScrape: &AnnotationScrapeArgs{Namespaces: []string{"challenges", "challenges"}},
+			ExpectErr:        true,
+		},
+		"reserved-job": {
+			AnnotationScrape: &AnnotationScrapeArgs{Namespaces: []string{"challenges"}},
+			ExtraScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      AnnotationScrapeJobName,
+					Destinations: []ScrapeDestination{{Ports: []int{8080}}},
+				},
+			},
+			ExpectErr: true,
+		},
+		"job-name-free-without": {
+			ExtraScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      AnnotationScrapeJobName,
+					Destinations: []ScrapeDestination{{Ports: []int{8080}}},
+				},
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := RenderPrometheusConfig(&PrometheusArgs{
+				AnnotationScrape:   tt.AnnotationScrape,
+				ExtraScrapeConfigs: tt.ExtraScrapeConfigs,
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_AnnotationScrape_RBAC(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		AnnotationScrape   *AnnotationScrapeArgs
+		ExpectedNamespaces []string
+	}{
+		"disabled": {},
+		"namespaces": {
+			AnnotationScrape:   &AnnotationScrapeArgs{Namespaces: []string{"challenges", "ctfd"}},
+			ExpectedNamespaces: []string{"challenges", "ctfd"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+					Namespace:        pulumi.String("monitoring"),
+					AnnotationScrape: tt.AnnotationScrape,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			sas := m.ByType("kubernetes:core/v1:ServiceAccount")
+			if tt.AnnotationScrape == nil {
+				// The default ServiceAccount, granted nothing
+				if len(sas) != 0 {
+					t.Errorf("expected no ServiceAccount, got %d", len(sas))
+				}
+				if _, ok := spec["serviceAccountName"]; ok {
+					t.Errorf("expected the default ServiceAccount, got %v", spec["serviceAccountName"])
+				}
+				return
+			}
+
+			if len(sas) != 1 {
+				t.Fatalf("expected 1 ServiceAccount, got %d", len(sas))
+			}
+			if name := spec["serviceAccountName"].StringValue(); name != "prometheus-sa" {
+				t.Errorf("expected the pods to run as prometheus-sa, got %s", name)
+			}
+
+			// A Role in each namespace, never a ClusterRole
+			if crs := m.ByType("kubernetes:rbac.authorization.k8s.io/v1:ClusterRole"); len(crs) != 0 {
+				t.Errorf("expected no ClusterRole, got %d", len(crs))
+			}
+			namespaces := []string{}
+			for _, role := range m.ByType("kubernetes:rbac.authorization.k8s.io/v1:Role") {
+				namespaces = append(namespaces, role["metadata"].ObjectValue()["namespace"].StringValue())
+				rules := role["rules"].ArrayValue()
+				if len(rules) != 1 {
+					t.Fatalf("expected 1 rule, got %d", len(rules))
+				}
+				rule := rules[0].ObjectValue()
+				if res := rule["resources"].ArrayValue(); len(res) != 1 || res[0].StringValue() != "pods" {
+					t.Errorf("expected the role to only grant the pods, got %v", res)
+				}
+				for _, verb := range rule["verbs"].ArrayValue() {
+					if !slices.Contains([]string{"get", "list", "watch"}, verb.StringValue()) {
+						t.Errorf("expected the role to be read-only, got verb %s", verb.StringValue())
+					}
+				}
+			}
+			slices.Sort(namespaces)
+			if !slices.Equal(namespaces, tt.ExpectedNamespaces) {
+				t.Errorf("expected roles in %v, got %v", tt.ExpectedNamespaces, namespaces)
+			}
+
+			for _, binding := range m.ByType("kubernetes:rbac.authorization.k8s.io/v1:RoleBinding") {
+				subjects := binding["subjects"].ArrayValue()
+				if len(subjects) != 1 {
+					t.Fatalf("expected 1 subject, got %d", len(subjects))
+				}
+				subject := subjects[0].ObjectValue()
+				if subject["name"].StringValue() != "prometheus-sa" || subject["namespace"].StringValue() != "monitoring" {
+					t.Errorf("expected the binding to the Prometheus ServiceAccount, got %v", subject)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package parts
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/pkg/drift"
+)
+
+// configHashAnnotations stamps the hash of the ConfigMap data, such that the
+// edits made in the cluster could be told (see drift.Compare). Returns nil if
+// not enabled.
+func configHashAnnotations(enabled bool, data pulumi.StringMapOutput) pulumi.StringMapInput {
+	if !enabled {
+		return nil
+	}
+	return pulumi.StringMap{
+		drift.HashAnnotation: data.ApplyT(func(data map[string]string) string {
+			return drift.Hash(data)
+		}).(pulumi.StringOutput),
+	}
+}
+
+// ConfigChecksumAnnotation is the annotation of the pod templates with the
+// hash of the configuration they mount, such that a change of it is
+// recorded as a change of the template, i.e. rolled out, even while paused.
+const ConfigChecksumAnnotation = "ctfer.io/config-checksum"
+
+// configChecksumAnnotations stamps the hash of the configuration data on a
+// pod template.
+func configChecksumAnnotations(data pulumi.StringMapOutput) pulumi.StringMap {
+	return pulumi.StringMap{
+		ConfigChecksumAnnotation: data.ApplyT(func(data map[string]string) string {
+			return drift.Hash(data)
+		}).(pulumi.StringOutput),
+	}
+}
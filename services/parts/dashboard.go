@@ -0,0 +1,58 @@
+package parts
+
+import (
+	"github.com/pkg/errors"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type (
+	DashboardArgs struct {
+		// Namespace of the dashboard ConfigMap. Must be the Perses one if
+		// the discovery is not in all namespaces.
+		Namespace pulumi.StringInput
+
+		// Discovery is the contract of the Perses sidecar, e.g. the Monitoring
+		// DashboardDiscovery (perses-dashboard-discovery stack output).
+		// Defaults to the Perses one.
+		Discovery *DashboardDiscovery
+
+		// Dashboard is the Perses dashboard manifest, in JSON.
+		// See https://perses.dev/perses/docs/api/dashboard/
+		Dashboard pulumi.StringInput
+	}
+)
+
+// NewDashboard provisions a Perses dashboard as a ConfigMap, labeled for the
+// Perses sidecar to discover it.
+func NewDashboard(
+	ctx *pulumi.Context,
+	name string,
+	args *DashboardArgs,
+	opts ...pulumi.ResourceOption,
+) (*corev1.ConfigMap, error) {
+	if args == nil {
+		args = &DashboardArgs{}
+	}
+	discovery := persesDashboardDiscovery
+	if args.Discovery != nil {
+		discovery = *args.Discovery
+	}
+	if discovery.LabelKey == "" {
+		return nil, errors.New("dashboard discovery label key is not provided")
+	}
+	if args.Dashboard == nil {
+		return nil, errors.New("dashboard is not provided")
+	}
+
+	return corev1.NewConfigMap(ctx, name, &corev1.ConfigMapArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels:    discovery.Labels(),
+		},
+		Data: pulumi.StringMap{
+			name + ".json": args.Dashboard,
+		},
+	}, opts...)
+}
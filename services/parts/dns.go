@@ -0,0 +1,22 @@
+package parts
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultClusterDomain = "cluster.local"
+)
+
+// clusterDomainRegex matches a DNS subdomain, as per RFC 1123.
+var clusterDomainRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// checkClusterDomain validates the cluster domain, if any.
+func checkClusterDomain(clusterDomain string) error {
+	if clusterDomain != "" && !clusterDomainRegex.MatchString(clusterDomain) {
+		return errors.Errorf("invalid cluster domain %q", clusterDomain)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package parts
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// normalizeDocument normalizes the whitespaces of a rendered document, such
+// that the optional template sections do not leave traces: no trailing
+// spaces, no leading nor consecutive blank lines, and a single final newline.
+// The ConfigMaps are immutable, so any byte change replaces them and rolls
+// out their pods.
+func normalizeDocument(doc string) string {
+	lines := strings.Split(doc, "\n")
+	out := make([]string, 0, len(lines))
+	blank := true // drop the leading blank lines
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n") + "\n"
+}
+
+// marshalDocument encodes the value in JSON deterministically: the map keys
+// are sorted, the HTML characters not escaped (e.g. the & of URLs), and the
+// output is optionally indented.
+func marshalDocument(v any, indent bool) (string, error) {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,190 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+func Test_U_NormalizeDocument(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Doc      string
+		Expected string
+	}{
+		"empty": {
+			Doc:      "",
+			Expected: "\n",
+		},
+		"normalized": {
+			Doc:      "a:\n  b: c\n\nd: e\n",
+			Expected: "a:\n  b: c\n\nd: e\n",
+		},
+		"leading-blank-lines": {
+			Doc:      "\n\na: b\n",
+			Expected: "a: b\n",
+		},
+		"consecutive-blank-lines": {
+			Doc:      "a: b\n\n\n\nc: d\n",
+			Expected: "a: b\n\nc: d\n",
+		},
+		"trailing-spaces": {
+			Doc:      "a: b  \n  \nc: d\t\r\n",
+			Expected: "a: b\n\nc: d\n",
+		},
+		"final-newlines": {
+			Doc:      "a: b",
+			Expected: "a: b\n",
+		},
+		"trailing-blank-lines": {
+			Doc:      "a: b\n\n\n",
+			Expected: "a: b\n",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if doc := normalizeDocument(tt.Doc); doc != tt.Expected {
+				t.Errorf("expected %q, got %q", tt.Expected, doc)
+			}
+		})
+	}
+}
+
+// Test_U_Documents_Deterministic checks the generated documents are the same
+// byte for byte from one rendering to another, and from one process to
+// another through the golden files, such that no-op updates do not replace
+// their ConfigMaps.
+func Test_U_Documents_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Render func() (string, error)
+		Golden string
+	}{
+		"otel-config": {
+			Render: func() (string, error) {
+				args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+					ColdExtract: true,
+					TenantRouting: &TenantRoutingArgs{
+						Tenants: []string{"ctf-b", "ctf-a"},
+					},
+					DependencyGraph: true,
+					TracesFailover:  true,
+					PrometheusBasicAuth: &BasicAuthArgs{
+						Username:           PrometheusBasicAuthUsername,
+						PasswordSecretName: pulumi.String("prometheus-web-config"),
+					},
+					ReceiverTLS: &ReceiverTLSArgs{
+						RequireClientCertificate: true,
+					},
+					StatsdReceiver: true,
+					SyslogReceiver: &SyslogReceiverArgs{},
+				})
+				return renderOtelConfig(args, "http://jaeger-collector:4317", "http://prometheus:9090")
+			},
+			Golden: "document-otel-config.golden.yaml",
+		},
+		"prometheus-config": {
+			Render: func() (string, error) {
+				args := (&Prometheus{}).defaults(&PrometheusArgs{
+					RemoteWriteReceiver:  true,
+					RemoteWriteBasicAuth: true,
+					QueryLog:             true,
+					ExtraScrapeConfigs: []ScrapeConfig{
+						{
+							JobName: "challenges",
+							StaticConfigs: []StaticConfig{
+								{
+									Targets: []string{"scoreboard:8080"},
+									Labels: map[string]string{
+										"team":  "blue",
+										"event": "ctf-2026",
+										"tier":  "web",
+									},
+								},
+							},
+						},
+					},
+				})
+				return renderPrometheusConfig(args, []string{"http://mimir:9009/api/v1/push"})
+			},
+			Golden: "document-prometheus-config.golden.yaml",
+		},
+		"jaeger-config": {
+			Render: func() (string, error) {
+				args := (&Jaeger{}).defaults(&JaegerArgs{
+					Archive: &JaegerArchiveArgs{
+						Elasticsearch: &JaegerElasticsearchArgs{
+							ServerURLs: []string{"https://es-0.example.com:9200", "https://es-1.example.com:9200"},
+							Username:   "jaeger",
+							Password:   pulumi.String("s3cr3t"),
+						},
+					},
+				})
+				return renderJaegerConfig(args, "http://prometheus:9090")
+			},
+			Golden: "document-jaeger-config.golden.yaml",
+		},
+		"jaeger-ui": {
+			Render: func() (string, error) {
+				args := (&Jaeger{}).defaults(&JaegerArgs{
+					Archive: &JaegerArchiveArgs{
+						Badger: &JaegerBadgerArgs{},
+					},
+				})
+				return renderJaegerUIConfig(args)
+			},
+			Golden: "document-jaeger-ui.golden.json",
+		},
+		"perses-global-datasource": {
+			Render: func() (string, error) {
+				return renderPersesGlobalDatasource("http://prometheus:9090?tenant=ctf&env=prod", false)
+			},
+			Golden: "document-perses-global-datasource.golden.json",
+		},
+		"perses-global-datasource-basic-auth": {
+			Render: func() (string, error) {
+				return renderPersesGlobalDatasource("http://prometheus:9090", true)
+			},
+			Golden: "document-perses-global-datasource-basic-auth.golden.json",
+		},
+		"perses-global-secret": {
+			Render: func() (string, error) {
+				return renderPersesGlobalSecret(PrometheusBasicAuthUsername)
+			},
+			Golden: "document-perses-global-secret.golden.json",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			first, err := tt.Render()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			second, err := tt.Render()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if first != second {
+				t.Fatalf("expected the renderings to be equal, got:\n%s\nthen:\n%s", first, second)
+			}
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			if first != string(b) {
+				t.Errorf("expected the golden document:\n%s\ngot:\n%s", b, first)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package parts
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// IngestionQuotasArgs routes the signals received by tenant, identified
+	// by a resource attribute, such that the Quotas tenants are refused past
+	// their share of the collector memory rather than starving the others.
+	// Each quota is a memory_limiter of its own, refusing the tenant data
+	// while the collector memory exceeds its share; the other tenants are
+	// only refused by the shared one, as without quotas.
+	//
+	// The signals of every tenant are counted when received, and the ones of
+	// the Quotas tenants once accepted, in the monitoring.quota.* metrics
+	// sent to Prometheus: the difference was refused.
+	// Requires a memory limit of the collector, the shares being of it.
+	IngestionQuotasArgs struct {
+		// Attribute is the resource attribute identifying the tenant.
+		// Defaults to k8s.namespace.name.
+		Attribute string
+
+		// Quotas of the limited tenants.
+		Quotas []TenantQuotaArgs
+	}
+
+	// TenantQuotaArgs limits the signals of a tenant.
+	TenantQuotaArgs struct {
+		// Tenant is the attribute value, e.g. the namespace of a team.
+		Tenant string
+
+		// MemoryPercent is the share of the memory_limiter limit past which
+		// the signals of the tenant are refused, from 1 to 100.
+		// Defaults to 50.
+		MemoryPercent int
+	}
+
+	// tenantLimiter is the memory_limiter of a tenant, as rendered.
+	tenantLimiter struct {
+		Tenant        string
+		LimitMiB      int64
+		SpikeLimitMiB int64
+	}
+)
+
+const (
+	defaultQuotaAttribute     = "k8s.namespace.name"
+	defaultQuotaMemoryPercent = 50
+
+	quotaReceivedCount = "count/quota/received"
+	quotaAcceptedCount = "count/quota/accepted"
+)
+
+// quotaDerivedReceivers are the receivers of the signals derived in the
+// collector, which are not routed by tenant.
+var quotaDerivedReceivers = []string{"spanmetrics", "servicegraph"}
+
+func (iq *IngestionQuotasArgs) defaults() {
+	if iq.Attribute == "" {
+		iq.Attribute = defaultQuotaAttribute
+	}
+	for i := range iq.Quotas {
+		if iq.Quotas[i].MemoryPercent == 0 {
+			iq.Quotas[i].MemoryPercent = defaultQuotaMemoryPercent
+		}
+	}
+}
+
+// checkIngestionQuotas validates the quotas before they are rendered, the
+// memory limit they share being derived from the runtime limits.
+func checkIngestionQuotas(iq *IngestionQuotasArgs, rl otelRuntimeLimits) (merr error) {
+	if !attributeRegex.MatchString(iq.Attribute) {
+		merr = multierr.Append(merr, errors.Errorf("invalid quota attribute %q", iq.Attribute))
+	}
+	if len(iq.Quotas) == 0 {
+		merr = multierr.Append(merr, errors.New("ingestion quotas require at least one tenant"))
+	}
+	if rl.MemoryLimitMiB == 0 {
+		merr = multierr.Append(merr, errors.New("ingestion quotas require a memory limit of the collector, their shares being of it"))
+	}
+	for i, q := range iq.Quotas {
+		if !tenantRegex.MatchString(q.Tenant) {
+			merr = multierr.Append(merr, errors.Errorf("invalid quota tenant %q", q.Tenant))
+		}
+		if slices.ContainsFunc(iq.Quotas[:i], func(other TenantQuotaArgs) bool { return other.Tenant == q.Tenant }) {
+			merr = multierr.Append(merr, errors.Errorf("duplicated quota tenant %s", q.Tenant))
+		}
+		if q.MemoryPercent < 1 || q.MemoryPercent > 100 {
+			merr = multierr.Append(merr, errors.Errorf("quota memory percent %d of tenant %s is out of the 1-100 range", q.MemoryPercent, q.Tenant))
+		} else if rl.MemoryLimitMiB != 0 && rl.MemoryLimitMiB*int64(q.MemoryPercent)/100 == 0 {
+			merr = multierr.Append(merr, errors.Errorf("quota of tenant %s is too low, %d%% of %dMiB", q.Tenant, q.MemoryPercent, rl.MemoryLimitMiB))
+		}
+	}
+	return
+}
+
+// limiters returns the memory_limiter of each tenant, shares of the collector
+// one.
+func (iq *IngestionQuotasArgs) limiters(rl otelRuntimeLimits) []tenantLimiter {
+	limiters := make([]tenantLimiter, 0, len(iq.Quotas))
+	for _, q := range iq.Quotas {
+		limit := rl.MemoryLimitMiB * int64(q.MemoryPercent) / 100
+		limiters = append(limiters, tenantLimiter{
+			Tenant:        q.Tenant,
+			LimitMiB:      limit,
+			SpikeLimitMiB: limit * spikeLimitPercent / 100,
+		})
+	}
+	return limiters
+}
+
+// routed moves the receivers of the signals sent to the collector off the
+// pipeline, for them to be routed by tenant before reaching it. It returns
+// the receivers moved.
+func (iq *IngestionQuotasArgs) routed(p *PipelineSpec) []string {
+	var ingested, derived []string
+	for _, rcv := range p.Receivers {
+		if slices.Contains(quotaDerivedReceivers, rcv) {
+			derived = append(derived, rcv)
+		} else {
+			ingested = append(ingested, rcv)
+		}
+	}
+	p.Receivers = append([]string{"forward/quota/" + p.Name}, derived...)
+	return ingested
+}
+
+// pipelines returns the pipelines routing the signal by tenant: the ingested
+// one counts and routes them, the Quotas tenants ones limit them, then all
+// are forwarded to the pipeline of the signal. The limiter is the shared
+// memory_limiter, first of the ingested one.
+func (iq *IngestionQuotasArgs) pipelines(signal string, receivers, limiter []string) []PipelineSpec {
+	forward := "forward/quota/" + signal
+	routing := "routing/quota/" + signal
+	pipelines := []PipelineSpec{
+		{
+			Name:       signal + "/ingest",
+			Receivers:  receivers,
+			Processors: limiter,
+			Exporters:  []string{routing, quotaReceivedCount},
+		},
+		{
+			Name:      signal + "/quota",
+			Receivers: []string{routing},
+			Exporters: []string{forward},
+		},
+	}
+	for _, q := range iq.Quotas {
+		pipelines = append(pipelines, PipelineSpec{
+			Name:       signal + "/quota/" + q.Tenant,
+			Receivers:  []string{routing},
+			Processors: []string{memoryLimiterProcessor + "/" + q.Tenant},
+			Exporters:  []string{forward, quotaAcceptedCount},
+		})
+	}
+	return pipelines
+}
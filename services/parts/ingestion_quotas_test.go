@@ -0,0 +1,197 @@
+package parts
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_OtelCollector_IngestionQuotas(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		Resources: corev1.ResourceRequirementsArgs{
+			Limits: pulumi.StringMap{
+				"memory": pulumi.String("1Gi"),
+			},
+		},
+		IngestionQuotas: &IngestionQuotasArgs{
+			Quotas: []TenantQuotaArgs{
+				{Tenant: "team-a"},
+				{Tenant: "team-b", MemoryPercent: 25},
+			},
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-ingestion-quotas.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	for _, key := range []string{"processors", "connectors", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+}
+
+func Test_U_OtelCollector_IngestionQuotas_Pipelines(t *testing.T) {
+	t.Parallel()
+
+	// The quotas route the signals received before the cold extract ones
+	// are split apart, which keep being fed by the same receivers
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		ColdExtract:    true,
+		StatsdReceiver: true,
+		Resources: corev1.ResourceRequirementsArgs{
+			Limits: pulumi.StringMap{
+				"memory": pulumi.String("1Gi"),
+			},
+		},
+		Redaction: &RedactionArgs{
+			DeleteKeys:     []string{"enduser.id"},
+			RawColdExtract: true,
+		},
+		IngestionQuotas: &IngestionQuotasArgs{
+			Quotas: []TenantQuotaArgs{{Tenant: "team-a"}},
+		},
+	})
+	pipelines := map[string]PipelineSpec{}
+	for _, p := range otelPipelines(args) {
+		pipelines[p.Name] = p
+	}
+
+	for name, expected := range map[string]PipelineSpec{
+		"metrics": {
+			Receivers:  []string{"forward/quota/metrics", "spanmetrics"},
+			Processors: []string{redactionProcessor},
+		},
+		"metrics/cold": {
+			Receivers: []string{"forward/quota/metrics", "spanmetrics"},
+		},
+		"metrics/ingest": {
+			Receivers:  []string{"otlp", "statsd"},
+			Processors: []string{memoryLimiterProcessor},
+		},
+		"metrics/quota/team-a": {
+			Receivers:  []string{"routing/quota/metrics"},
+			Processors: []string{"memory_limiter/team-a"},
+		},
+	} {
+		p, ok := pipelines[name]
+		if !ok {
+			t.Errorf("expected pipeline %s, got %v", name, slices.Sorted(maps.Keys(pipelines)))
+			continue
+		}
+		if !slices.Equal(p.Receivers, expected.Receivers) {
+			t.Errorf("expected %s receivers %v, got %v", name, expected.Receivers, p.Receivers)
+		}
+		if !slices.Equal(p.Processors, expected.Processors) {
+			t.Errorf("expected %s processors %v, got %v", name, expected.Processors, p.Processors)
+		}
+	}
+}
+
+func Test_U_OtelCollector_IngestionQuotas_Check(t *testing.T) {
+	t.Parallel()
+
+	memoryLimit := corev1.ResourceRequirementsArgs{
+		Limits: pulumi.StringMap{
+			"memory": pulumi.String("1Gi"),
+		},
+	}
+
+	var tests = map[string]struct {
+		Resources corev1.ResourceRequirementsInput
+		Quotas    *IngestionQuotasArgs
+		ExpectErr bool
+	}{
+		"valid": {
+			Resources: memoryLimit,
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{
+					{Tenant: "team-a", MemoryPercent: 30},
+					{Tenant: "team-b"},
+				},
+			},
+		},
+		"custom-attribute": {
+			Resources: memoryLimit,
+			Quotas: &IngestionQuotasArgs{
+				Attribute: "ctfer.io/stack-name",
+				Quotas:    []TenantQuotaArgs{{Tenant: "ctf-a"}},
+			},
+		},
+		"without-memory-limit": {
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{{Tenant: "team-a"}},
+			},
+			ExpectErr: true,
+		},
+		"no-quota": {
+			Resources: memoryLimit,
+			Quotas:    &IngestionQuotasArgs{},
+			ExpectErr: true,
+		},
+		"duplicated-tenant": {
+			Resources: memoryLimit,
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{{Tenant: "team-a"}, {Tenant: "team-a"}},
+			},
+			ExpectErr: true,
+		},
+		"quoting-tenant": {
+			Resources: memoryLimit,
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{{Tenant: `team" or true or "`}},
+			},
+			ExpectErr: true,
+		},
+		"memory-percent-out-of-range": {
+			Resources: memoryLimit,
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{{Tenant: "team-a", MemoryPercent: 120}},
+			},
+			ExpectErr: true,
+		},
+		"memory-percent-too-low": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("2Mi"),
+				},
+			},
+			Quotas: &IngestionQuotasArgs{
+				Quotas: []TenantQuotaArgs{{Tenant: "team-a", MemoryPercent: 1}},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:       pulumi.String("http://jaeger:4317"),
+				PrometheusURL:   pulumi.String("http://prometheus:9090"),
+				Resources:       tt.Resources,
+				IngestionQuotas: tt.Quotas,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
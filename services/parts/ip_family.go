@@ -0,0 +1,115 @@
+package parts
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// IP families and policies of the Services.
+// See https://kubernetes.io/docs/concepts/services-networking/dual-stack/#services
+const (
+	IPv4 = "IPv4"
+	IPv6 = "IPv6"
+
+	IPFamilyPolicySingleStack      = "SingleStack"
+	IPFamilyPolicyPreferDualStack  = "PreferDualStack"
+	IPFamilyPolicyRequireDualStack = "RequireDualStack"
+)
+
+// IPFamilyArgs are the IP families of the Services, for IPv6 single-stack or
+// dual-stack clusters. Their pods are reached through the records of the
+// primary family.
+type IPFamilyArgs struct {
+	// Policy is SingleStack, PreferDualStack or RequireDualStack.
+	// Defaults to the cluster one, i.e. SingleStack.
+	Policy string
+
+	// Families are IPv4 and/or IPv6, the first one being the primary.
+	// Defaults to the cluster ones.
+	Families []string
+}
+
+func checkIPFamily(ipf *IPFamilyArgs) error {
+	if ipf == nil {
+		return nil
+	}
+	switch ipf.Policy {
+	case "", IPFamilyPolicySingleStack, IPFamilyPolicyPreferDualStack, IPFamilyPolicyRequireDualStack:
+	default:
+		return fmt.Errorf("unsupported policy %q, expected %s, %s or %s", ipf.Policy, IPFamilyPolicySingleStack, IPFamilyPolicyPreferDualStack, IPFamilyPolicyRequireDualStack)
+	}
+	if len(ipf.Families) > 2 {
+		return fmt.Errorf("expected at most 2 families, got %d", len(ipf.Families))
+	}
+	for _, f := range ipf.Families {
+		if f != IPv4 && f != IPv6 {
+			return fmt.Errorf("unsupported family %q, expected %s or %s", f, IPv4, IPv6)
+		}
+	}
+	if len(ipf.Families) == 2 && ipf.Families[0] == ipf.Families[1] {
+		return fmt.Errorf("family %s is set twice", ipf.Families[0])
+	}
+	if len(ipf.Families) == 2 && (ipf.Policy == "" || ipf.Policy == IPFamilyPolicySingleStack) {
+		return errors.New("two families require a dual-stack policy")
+	}
+	return nil
+}
+
+// primaryIPv6 returns whether the primary family is IPv6, the cluster one
+// being assumed IPv4 when not set.
+func (ipf *IPFamilyArgs) primaryIPv6() bool {
+	return ipf != nil && len(ipf.Families) != 0 && ipf.Families[0] == IPv6
+}
+
+// DNSSDType returns the DNS records type resolving the pods of a headless
+// Service, in the primary family.
+func (ipf *IPFamilyArgs) DNSSDType() string {
+	if ipf.primaryIPv6() {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// policy returns the ipFamilyPolicy of the Services, the cluster default one
+// if not set.
+func (ipf *IPFamilyArgs) policy() pulumi.StringPtrInput {
+	if ipf == nil || ipf.Policy == "" {
+		return nil
+	}
+	return pulumi.StringPtr(ipf.Policy)
+}
+
+// families returns the ipFamilies of the Services, the cluster default ones
+// if not set.
+func (ipf *IPFamilyArgs) families() pulumi.StringArrayInput {
+	if ipf == nil || len(ipf.Families) == 0 {
+		return nil
+	}
+	return pulumi.ToStringArray(slices.Clone(ipf.Families))
+}
+
+// CheckCIDR validates an IP block of a NetworkPolicy peer, either IPv4 or
+// IPv6. The IPv4-mapped IPv6 ones are refused, as the pods are matched by
+// the IP of their family, so they would never match.
+func CheckCIDR(cidr string) error {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return errors.Wrapf(err, "invalid cidr %q", cidr)
+	}
+	if prefix.Addr().Is4In6() {
+		return fmt.Errorf("cidr %s is IPv4-mapped, use the IPv4 one %s", cidr, netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0)))
+	}
+	return nil
+}
+
+// hostPort returns the host and port as an endpoint, the IPv6 literals being
+// bracketed.
+// Example: otel-0.otlp-grpc.monitoring:4317, [fd00::1]:4317
+func hostPort(host string, port int) string {
+	return net.JoinHostPort(host, fmt.Sprint(port))
+}
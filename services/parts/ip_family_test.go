@@ -0,0 +1,243 @@
+package parts
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_CheckIPFamily(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		IPFamily  *IPFamilyArgs
+		ExpectErr bool
+	}{
+		"none": {},
+		"cluster-defaults": {
+			IPFamily: &IPFamilyArgs{},
+		},
+		"ipv6-single-stack": {
+			IPFamily: &IPFamilyArgs{Policy: IPFamilyPolicySingleStack, Families: []string{IPv6}},
+		},
+		"dual-stack": {
+			IPFamily: &IPFamilyArgs{Policy: IPFamilyPolicyRequireDualStack, Families: []string{IPv6, IPv4}},
+		},
+		"prefer-dual-stack": {
+			IPFamily: &IPFamilyArgs{Policy: IPFamilyPolicyPreferDualStack},
+		},
+		"unknown-policy": {
+			IPFamily:  &IPFamilyArgs{Policy: "DualStack"},
+			ExpectErr: true,
+		},
+		"unknown-family": {
+			IPFamily:  &IPFamilyArgs{Families: []string{"ipv6"}},
+			ExpectErr: true,
+		},
+		"duplicated-family": {
+			IPFamily:  &IPFamilyArgs{Policy: IPFamilyPolicyPreferDualStack, Families: []string{IPv4, IPv4}},
+			ExpectErr: true,
+		},
+		"two-families-single-stack": {
+			IPFamily:  &IPFamilyArgs{Families: []string{IPv4, IPv6}},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkIPFamily(tt.IPFamily)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_CheckCIDR(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		CIDR      string
+		ExpectErr bool
+	}{
+		"ipv4": {
+			CIDR: "10.42.0.0/16",
+		},
+		"ipv6": {
+			CIDR: "fd00:10:244::/56",
+		},
+		"ipv6-host": {
+			CIDR: "2001:db8::12/128",
+		},
+		"ipv6-any": {
+			CIDR: "::/0",
+		},
+		"ipv4-mapped": {
+			CIDR:      "::ffff:10.42.0.0/112",
+			ExpectErr: true,
+		},
+		"bracketed": {
+			CIDR:      "[fd00::]/8",
+			ExpectErr: true,
+		},
+		"no-prefix": {
+			CIDR:      "fd00::12",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := CheckCIDR(tt.CIDR)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_HostPort(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Host     string
+		Port     int
+		Expected string
+	}{
+		"hostname": {
+			Host:     "otel-0.otlp-grpc.monitoring",
+			Port:     4317,
+			Expected: "otel-0.otlp-grpc.monitoring:4317",
+		},
+		"ipv4": {
+			Host:     "10.96.0.12",
+			Port:     9090,
+			Expected: "10.96.0.12:9090",
+		},
+		"ipv6": {
+			Host:     "fd00:10:96::c",
+			Port:     9090,
+			Expected: "[fd00:10:96::c]:9090",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hostPort(tt.Host, tt.Port); got != tt.Expected {
+				t.Errorf("expected %s, got %s", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_IPFamily_Services(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		IPFamily         *IPFamilyArgs
+		ExpectedPolicy   string
+		ExpectedFamilies []string
+		ExpectedDNSType  string
+	}{
+		"cluster-defaults": {
+			ExpectedDNSType: "A",
+		},
+		"ipv6-single-stack": {
+			IPFamily:         &IPFamilyArgs{Policy: IPFamilyPolicySingleStack, Families: []string{IPv6}},
+			ExpectedPolicy:   IPFamilyPolicySingleStack,
+			ExpectedFamilies: []string{IPv6},
+			ExpectedDNSType:  "AAAA",
+		},
+		"dual-stack-ipv4-primary": {
+			IPFamily:         &IPFamilyArgs{Policy: IPFamilyPolicyPreferDualStack, Families: []string{IPv4, IPv6}},
+			ExpectedPolicy:   IPFamilyPolicyPreferDualStack,
+			ExpectedFamilies: []string{IPv4, IPv6},
+			ExpectedDNSType:  "A",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				if _, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+					Namespace:     pulumi.String("monitoring"),
+					ScrapeTargets: []ScrapeTarget{JaegerScrapeTarget()},
+					IPFamily:      tt.IPFamily,
+				}); err != nil {
+					return err
+				}
+				_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+					Namespace:     pulumi.String("monitoring"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					IPFamily:      tt.IPFamily,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			svcs := m.ByType("kubernetes:core/v1:Service")
+			if len(svcs) == 0 {
+				t.Fatal("expected Services")
+			}
+			for _, svc := range svcs {
+				spec := svc["spec"].ObjectValue()
+				policy := ""
+				if v, ok := spec["ipFamilyPolicy"]; ok {
+					policy = v.StringValue()
+				}
+				if policy != tt.ExpectedPolicy {
+					t.Errorf("expected ip family policy %q, got %q", tt.ExpectedPolicy, policy)
+				}
+				families := []string{}
+				if v, ok := spec["ipFamilies"]; ok {
+					for _, f := range v.ArrayValue() {
+						families = append(families, f.StringValue())
+					}
+				}
+				if !slices.Equal(families, tt.ExpectedFamilies) {
+					t.Errorf("expected ip families %v, got %v", tt.ExpectedFamilies, families)
+				}
+			}
+
+			// The headless Jaeger admin Service is resolved in the primary family
+			cfg, err := RenderPrometheusConfig(&PrometheusArgs{
+				ScrapeTargets: []ScrapeTarget{JaegerScrapeTarget()},
+				IPFamily:      tt.IPFamily,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			out := struct {
+				ScrapeConfigs []ScrapeConfig `yaml:"scrape_configs"`
+			}{}
+			if err := yaml.Unmarshal([]byte(cfg), &out); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			idx := slices.IndexFunc(out.ScrapeConfigs, func(sc ScrapeConfig) bool {
+				return sc.JobName == "jaeger"
+			})
+			if idx == -1 || len(out.ScrapeConfigs[idx].DNSSDConfigs) != 1 {
+				t.Fatalf("expected the jaeger job to be discovered through DNS, got %v", out.ScrapeConfigs)
+			}
+			if typ := out.ScrapeConfigs[idx].DNSSDConfigs[0].Type; typ != tt.ExpectedDNSType {
+				t.Errorf("expected %s records, got %s", tt.ExpectedDNSType, typ)
+			}
+		})
+	}
+}
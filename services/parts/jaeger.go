@@ -3,10 +3,14 @@ package parts
 import (
 	"bytes"
 	_ "embed"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
 	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
@@ -20,33 +24,214 @@ type (
 	Jaeger struct {
 		pulumi.ResourceState
 
-		cfg *corev1.ConfigMap
-		dep *appsv1.Deployment
+		cfg        *corev1.ConfigMap
+		archivePvc *corev1.PersistentVolumeClaim
+		archiveSec *corev1.Secret
+		dep        *appsv1.Deployment
 		// Split UI and gRPC API services to enable separating concerns properly.
 		// Ths UI svc could be port forwarded if necessary or exposed through an
 		// Ingress, but we don't want the gRPC API to be so.
 		svcui   *corev1.Service
 		svcgrpc *corev1.Service
+		// The admin svc exposes the Jaeger own metrics, for Prometheus to
+		// scrape its health.
+		svcadmin *corev1.Service
+
+		// Service references the gRPC API Service.
+		Service *ServiceRef
 
 		// URL to reach out the Jaeger UI
 		URL       pulumi.StringOutput
 		PodLabels pulumi.StringMapOutput
+
+		// Port is the gRPC port Jaeger receives the traces on, through its
+		// Service and pods.
+		Port pulumi.IntOutput
+
+		// UIServiceName is the name of the Service exposing the Jaeger UI.
+		UIServiceName pulumi.StringOutput
+
+		// Ready resolves to true once Jaeger rolled out.
+		Ready pulumi.BoolOutput
 	}
 
 	JaegerArgs struct {
 		Namespace pulumi.StringInput
 
+		// MonitoringVersion is labeled on the resources, as the
+		// OtelCollectorArgs one.
+		MonitoringVersion string
+
 		Registry pulumi.StringInput
 		registry pulumi.StringOutput
 
+		// PrometheusURL is the Prometheus endpoint to query for the Service
+		// Performance Monitoring (SPM). Not required if DisableSPM is set.
 		PrometheusURL pulumi.StringInput
+
+		// DisableSPM turns off the Service Performance Monitoring, e.g. when
+		// Prometheus could not be queried (agent mode).
+		DisableSPM bool
+
+		// PublishNotReadyAddresses makes the headless Services resolve to the
+		// Jaeger pod before its readiness probe passes.
+		// Defaults to false.
+		PublishNotReadyAddresses pulumi.BoolInput
+		publishNotReadyAddresses pulumi.BoolOutput
+
+		// ClusterDomain renders the URL fully-qualified in this cluster domain
+		// (e.g. cluster.local), for clients not resolving it through the
+		// default DNS search path. Defaults to the short form.
+		ClusterDomain string
+
+		// IPFamily of the Services, for IPv6 single-stack or dual-stack
+		// clusters. Defaults to the cluster ones.
+		IPFamily *IPFamilyArgs
+
+		// Mesh annotates the pods for the service mesh of the cluster.
+		// Defaults to no annotation.
+		Mesh *MeshArgs
+
+		// PlatformIDs leaves the user and group IDs to the platform, e.g. the
+		// restricted SCC of OpenShift, rather than pinning the fsGroup of
+		// the Jaeger image the Badger database is written by.
+		PlatformIDs bool
+
+		// Replicas of the Jaeger pods.
+		// Defaults to 1.
+		Replicas int
+
+		// SpreadAcrossZones spreads the Jaeger pods across the zones, on
+		// a best-effort basis. Only applies with more than one replica.
+		SpreadAcrossZones bool
+
+		// TopologySpreadConstraints of the Jaeger pods, taking precedence over
+		// SpreadAcrossZones. Only applies with more than one replica.
+		TopologySpreadConstraints corev1.TopologySpreadConstraintArrayInput
+
+		// Archive turns on the archive storage, for the traces pinned from
+		// the UI to survive the retention of the in-memory one. Opt-in.
+		Archive *JaegerArchiveArgs
+
+		// Query bounds the load the query service accepts.
+		// Defaults to the Jaeger ones.
+		Query *JaegerQueryArgs
+
+		// Resources of the Jaeger container.
+		// Defaults to none.
+		Resources corev1.ResourceRequirementsInput
+
+		// MemoryMaxTraces is the most traces the in-memory storage keeps,
+		// the oldest evicted first. It should fit the memory resources.
+		// Defaults to 100000.
+		MemoryMaxTraces int
+
+		// Rollout stages the rollouts of the Deployment.
+		Rollout *RolloutArgs
+	}
+
+	// JaegerQueryArgs bounds the load the query service accepts, e.g. from a
+	// dashboard searching over a long lookback. Zero values keep the Jaeger
+	// defaults.
+	JaegerQueryArgs struct {
+		// MaxClockSkewAdjust bounds the adjustment of the spans clock skew.
+		MaxClockSkewAdjust time.Duration
+
+		// MaxTraces is the most traces a search of the UI could return.
+		// Jaeger UI defaults to 1500.
+		// The query service has no such limit, an API search being only
+		// bounded by the in-memory storage (see JaegerArgs.MemoryMaxTraces).
+		MaxTraces int
+
+		// MaxLookback is the longest lookback a search of the UI could
+		// span, as a Jaeger UI duration (e.g. 12h, 2d), the search form
+		// defaulting to the last hour.
+		// Jaeger UI defaults to 2d.
+		// The query service has no such limit, an API search being only
+		// bounded by the timeouts.
+		MaxLookback string
+
+		// HTTPReadTimeout and HTTPWriteTimeout bound the reading of the HTTP
+		// requests, and the writing of their responses.
+		HTTPReadTimeout  time.Duration
+		HTTPWriteTimeout time.Duration
+
+		// GRPCMaxConnectionAge closes the gRPC connections after it, letting
+		// their in-flight queries the same duration to complete.
+		GRPCMaxConnectionAge time.Duration
+	}
+
+	// JaegerArchiveArgs configures the storage of the archived traces.
+	// Badger and Elasticsearch are mutually exclusive, one is required.
+	JaegerArchiveArgs struct {
+		Badger        *JaegerBadgerArgs
+		Elasticsearch *JaegerElasticsearchArgs
+	}
+
+	// JaegerBadgerArgs stores the archived traces in a Badger database on a
+	// PVC. As the database is owned by a single process, it requires a
+	// single Jaeger replica.
+	JaegerBadgerArgs struct {
+		StorageClassName pulumi.StringInput
+		storageClassName pulumi.StringPtrOutput
+
+		// StorageSize of the PVC.
+		// Defaults to 1Gi.
+		StorageSize pulumi.StringInput
+		storageSize pulumi.StringOutput
+
+		// Protect the PVC from being deleted, along the archived traces,
+		// until unprotected.
+		Protect bool
+	}
+
+	// JaegerElasticsearchArgs stores the archived traces in an Elasticsearch
+	// (or OpenSearch) cluster, reached through the internet NetworkPolicy
+	// hence out of the private IP ranges.
+	JaegerElasticsearchArgs struct {
+		ServerURLs []string
+
+		// IndexPrefix of the archive indices.
+		// Defaults to "jaeger-archive".
+		IndexPrefix string
+
+		// Username authenticates to Elasticsearch, with either the inline
+		// Password, stored in a generated Secret, or the one of the existing
+		// PasswordSecret (at BasicAuthPasswordKey by default). Optional.
+		Username       string
+		Password       pulumi.StringInput
+		PasswordSecret *ExistingSecretArgs
 	}
 )
 
 const (
-	jaegerVersion = "2.14.1"
+	// JaegerVersion is the version of the Jaeger image.
+	JaegerVersion = "2.14.1"
+
+	// JaegerAdminServiceName is the name of the Service exposing the Jaeger
+	// own metrics. It is fixed such that Prometheus, deployed before Jaeger,
+	// could scrape it.
+	JaegerAdminServiceName = "jaeger-admin"
+
+	// JaegerAdminPort is the port Jaeger serves its own metrics on.
+	JaegerAdminPort = 14269
+
+	// JaegerOTLPPort is the gRPC port Jaeger receives the traces on.
+	JaegerOTLPPort = 4317
+
+	// JaegerUIPort is the port the Jaeger UI is served on.
+	JaegerUIPort = 16686
+
+	defaultMemoryMaxTraces = 100000
+
+	defaultArchiveStorageSize = "1Gi"
+	defaultArchiveIndexPrefix = "jaeger-archive"
+	jaegerArchiveDir          = "/badger/archive"
 )
 
+// lookbackRegex matches the Jaeger UI lookbacks, e.g. 12h or 2d.
+var lookbackRegex = regexp.MustCompile(`^[1-9][0-9]*[mhd]$`)
+
 //go:embed jaeger-ui.json
 var jaegerUI string
 
@@ -81,7 +266,7 @@ func NewJaeger(
 	if err := jgr.provision(ctx, args, opts...); err != nil {
 		return nil, err
 	}
-	if err := jgr.outputs(ctx); err != nil {
+	if err := jgr.outputs(ctx, args); err != nil {
 		return nil, err
 	}
 
@@ -111,10 +296,81 @@ func (*Jaeger) defaults(args *JaegerArgs) *JaegerArgs {
 		}).(pulumi.StringOutput)
 	}
 
+	args.publishNotReadyAddresses = pulumi.Bool(false).ToBoolOutput()
+	if args.PublishNotReadyAddresses != nil {
+		args.publishNotReadyAddresses = args.PublishNotReadyAddresses.ToBoolOutput()
+	}
+
+	if args.Replicas == 0 {
+		args.Replicas = 1
+	}
+
+	if args.MemoryMaxTraces == 0 {
+		args.MemoryMaxTraces = defaultMemoryMaxTraces
+	}
+
+	if args.Archive != nil {
+		if badger := args.Archive.Badger; badger != nil {
+			// Don't default storage class name -> will select the default one
+			// on the K8s cluster.
+			if badger.StorageClassName != nil {
+				badger.storageClassName = badger.StorageClassName.ToStringOutput().ApplyT(func(scn string) *string {
+					if scn == "" {
+						return nil
+					}
+					return &scn
+				}).(pulumi.StringPtrOutput)
+			}
+
+			badger.storageSize = pulumi.String(defaultArchiveStorageSize).ToStringOutput()
+			if badger.StorageSize != nil {
+				badger.storageSize = badger.StorageSize.ToStringOutput().ApplyT(func(size string) string {
+					if size == "" {
+						return defaultArchiveStorageSize
+					}
+					return size
+				}).(pulumi.StringOutput)
+			}
+		}
+		if es := args.Archive.Elasticsearch; es != nil && es.IndexPrefix == "" {
+			es.IndexPrefix = defaultArchiveIndexPrefix
+		}
+	}
+
 	return args
 }
 
 func (jgr *Jaeger) check(args *JaegerArgs) (merr error) {
+	if args.Replicas < 0 {
+		return errors.New("replicas could not be negative")
+	}
+	if err := checkClusterDomain(args.ClusterDomain); err != nil {
+		return err
+	}
+	if err := checkIPFamily(args.IPFamily); err != nil {
+		return errors.Wrap(err, "invalid ip family")
+	}
+	if err := checkMesh(args.Mesh); err != nil {
+		return errors.Wrap(err, "invalid mesh")
+	}
+	if err := checkJaegerArchive(args.Archive, args.Replicas); err != nil {
+		return errors.Wrap(err, "invalid archive")
+	}
+	if args.MemoryMaxTraces < 0 {
+		return errors.New("memory max traces could not be negative")
+	}
+	if err := checkJaegerQuery(args.Query); err != nil {
+		return errors.Wrap(err, "invalid query")
+	}
+	if err := checkRollout(args.Rollout); err != nil {
+		return err
+	}
+
+	// Without SPM, Prometheus is not used
+	if args.DisableSPM {
+		return
+	}
+
 	// First-level checks
 	if args.PrometheusURL == nil {
 		merr = multierr.Append(merr, errors.New("prometheus url is not provided"))
@@ -148,28 +404,32 @@ func (jgr *Jaeger) check(args *JaegerArgs) (merr error) {
 }
 
 func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulumi.ResourceOption) (err error) {
+	prometheusURL := pulumi.String("").ToStringOutput()
+	if args.PrometheusURL != nil {
+		prometheusURL = args.PrometheusURL.ToStringOutput()
+	}
+	ui, err := renderJaegerUIConfig(args)
+	if err != nil {
+		return
+	}
+
 	// Create the configuration map for Prometheus-backed monitoring
 	jgr.cfg, err = corev1.NewConfigMap(ctx, "spm-config", &corev1.ConfigMapArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/name":      pulumi.String("jaeger"),
-				"app.kubernetes.io/version":   pulumi.String(jaegerVersion),
+				"app.kubernetes.io/version":   pulumi.String(JaegerVersion),
 				"app.kubernetes.io/component": pulumi.String("jaeger"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 			Namespace: args.Namespace,
 		},
 		Data: pulumi.StringMap{
-			"jaeger-ui.json": pulumi.String(jaegerUI),
-			"config.yaml": args.PrometheusURL.ToStringOutput().ApplyT(func(prometheusUrl string) (string, error) {
-				buf := &bytes.Buffer{}
-				if err := jaegerTemplate.Execute(buf, map[string]any{
-					"PrometheusURL": prometheusUrl,
-				}); err != nil {
-					return "", err
-				}
-				return buf.String(), nil
+			"jaeger-ui.json": pulumi.String(ui),
+			"config.yaml": prometheusURL.ApplyT(func(prometheusURL string) (string, error) {
+				return renderJaegerConfig(args, prometheusURL)
 			}).(pulumi.StringOutput),
 		},
 	}, opts...)
@@ -177,86 +437,180 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 		return
 	}
 
+	vmounts := corev1.VolumeMountArray{
+		corev1.VolumeMountArgs{
+			Name:      pulumi.String("config-volume"),
+			MountPath: pulumi.String("/etc/jaeger"),
+			ReadOnly:  pulumi.Bool(true),
+		},
+	}
+	volumes := corev1.VolumeArray{
+		corev1.VolumeArgs{
+			Name: pulumi.String("config-volume"),
+			ConfigMap: corev1.ConfigMapVolumeSourceArgs{
+				Name:        jgr.cfg.Metadata.Name(),
+				DefaultMode: pulumi.Int(0644),
+				Items: corev1.KeyToPathArray{
+					corev1.KeyToPathArgs{
+						Key:  pulumi.String("jaeger-ui.json"),
+						Path: pulumi.String("jaeger-ui.json"),
+					},
+					corev1.KeyToPathArgs{
+						Key:  pulumi.String("config.yaml"),
+						Path: pulumi.String("config.yaml"),
+					},
+				},
+			},
+		},
+	}
+	// The rolling update would not release the archive PVC to the new pod
+	strategy := appsv1.DeploymentStrategyArgs{}
+	var podSecurityContext corev1.PodSecurityContextPtrInput
+
+	// Archive storage on a PVC, if any
+	if args.Archive != nil && args.Archive.Badger != nil {
+		jgr.archivePvc, err = corev1.NewPersistentVolumeClaim(ctx, "jaeger-archive", &corev1.PersistentVolumeClaimArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("jaeger"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpecArgs{
+				StorageClassName: args.Archive.Badger.storageClassName,
+				AccessModes: pulumi.ToStringArray([]string{
+					"ReadWriteOnce",
+				}),
+				Resources: corev1.VolumeResourceRequirementsArgs{
+					Requests: pulumi.StringMap{
+						"storage": args.Archive.Badger.storageSize,
+					},
+				},
+			},
+		}, append(slices.Clone(opts), pulumi.Protect(args.Archive.Badger.Protect))...)
+		if err != nil {
+			return
+		}
+
+		vmounts = append(vmounts, corev1.VolumeMountArgs{
+			Name:      pulumi.String("archive"),
+			MountPath: pulumi.String(jaegerArchiveDir),
+		})
+		volumes = append(volumes, corev1.VolumeArgs{
+			Name: pulumi.String("archive"),
+			PersistentVolumeClaim: corev1.PersistentVolumeClaimVolumeSourceArgs{
+				ClaimName: jgr.archivePvc.Metadata.Name().Elem(),
+			},
+		})
+		strategy.Type = pulumi.String("Recreate")
+		// The Jaeger image runs as 10001, let it write the database. The
+		// platform assigning the IDs also sets the fsGroup.
+		if !args.PlatformIDs {
+			podSecurityContext = corev1.PodSecurityContextArgs{
+				FsGroup: pulumi.Int(10001),
+			}
+		}
+	}
+
+	// Inline Elasticsearch password, only referenced by the configuration
+	if args.Archive != nil && args.Archive.Elasticsearch != nil && args.Archive.Elasticsearch.Password != nil {
+		jgr.archiveSec, err = corev1.NewSecret(ctx, "jaeger-archive-es", &corev1.SecretArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("jaeger"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			StringData: pulumi.StringMap{
+				BasicAuthPasswordKey: args.Archive.Elasticsearch.Password,
+			},
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
 	// Deployment
 	jgr.dep, err = appsv1.NewDeployment(ctx, "jaeger", &appsv1.DeploymentArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: args.Namespace,
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/name":      pulumi.String("jaeger"),
-				"app.kubernetes.io/version":   pulumi.String(jaegerVersion),
+				"app.kubernetes.io/version":   pulumi.String(JaegerVersion),
 				"app.kubernetes.io/component": pulumi.String("jaeger"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+			Annotations: rolloutAnnotations(args.Rollout),
 		},
 		Spec: appsv1.DeploymentSpecArgs{
 			Selector: metav1.LabelSelectorArgs{
 				MatchLabels: pulumi.StringMap{
 					"app.kubernetes.io/name":      pulumi.String("jaeger"),
-					"app.kubernetes.io/version":   pulumi.String(jaegerVersion),
+					"app.kubernetes.io/version":   pulumi.String(JaegerVersion),
 					"app.kubernetes.io/component": pulumi.String("jaeger"),
 					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 				},
 			},
-			Replicas: pulumi.Int(1),
+			Replicas:        pulumi.Int(args.Replicas),
+			Paused:          rolloutPaused(args.Rollout),
+			MinReadySeconds: rolloutMinReadySeconds(args.Rollout),
+			Strategy:        strategy,
 			Template: corev1.PodTemplateSpecArgs{
 				Metadata: metav1.ObjectMetaArgs{
 					Namespace: args.Namespace,
 					Labels: pulumi.StringMap{
 						"app.kubernetes.io/name":      pulumi.String("jaeger"),
-						"app.kubernetes.io/version":   pulumi.String(jaegerVersion),
+						"app.kubernetes.io/version":   pulumi.String(JaegerVersion),
 						"app.kubernetes.io/component": pulumi.String("jaeger"),
 						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 					},
+					Annotations: meshAnnotations(configChecksumAnnotations(jgr.cfg.Data), args.Mesh.PodAnnotations()),
 				},
 				Spec: corev1.PodSpecArgs{
+					SecurityContext: podSecurityContext,
+					TopologySpreadConstraints: topologySpreadConstraints(args.Replicas, args.SpreadAcrossZones, args.TopologySpreadConstraints, pulumi.StringMap{
+						"app.kubernetes.io/name":      pulumi.String("jaeger"),
+						"app.kubernetes.io/component": pulumi.String("jaeger"),
+						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+					}),
 					Containers: corev1.ContainerArray{
 						corev1.ContainerArgs{
 							Name:  pulumi.String("jaeger"),
-							Image: pulumi.Sprintf("%sjaegertracing/jaeger:%s", args.registry, jaegerVersion),
+							Image: pulumi.Sprintf("%sjaegertracing/jaeger:%s", args.registry, JaegerVersion),
 							Args: pulumi.ToStringArray([]string{
 								"--config=/etc/jaeger/config.yaml",
 							}),
 							Ports: corev1.ContainerPortArray{
 								corev1.ContainerPortArgs{
 									Name:          pulumi.String("ui"),
-									ContainerPort: pulumi.Int(16686),
+									ContainerPort: pulumi.Int(JaegerUIPort),
 								},
 								corev1.ContainerPortArgs{
 									Name:          pulumi.String("grpc"),
-									ContainerPort: pulumi.Int(4317),
+									ContainerPort: pulumi.Int(JaegerOTLPPort),
 								},
-							},
-							VolumeMounts: corev1.VolumeMountArray{
-								corev1.VolumeMountArgs{
-									Name:      pulumi.String("config-volume"),
-									MountPath: pulumi.String("/etc/jaeger"),
-									ReadOnly:  pulumi.Bool(true),
-								},
-							},
-						},
-					},
-					Volumes: corev1.VolumeArray{
-						corev1.VolumeArgs{
-							Name: pulumi.String("config-volume"),
-							ConfigMap: corev1.ConfigMapVolumeSourceArgs{
-								Name:        jgr.cfg.Metadata.Name(),
-								DefaultMode: pulumi.Int(0644),
-								Items: corev1.KeyToPathArray{
-									corev1.KeyToPathArgs{
-										Key:  pulumi.String("jaeger-ui.json"),
-										Path: pulumi.String("jaeger-ui.json"),
-									},
-									corev1.KeyToPathArgs{
-										Key:  pulumi.String("config.yaml"),
-										Path: pulumi.String("config.yaml"),
-									},
+								corev1.ContainerPortArgs{
+									Name:          pulumi.String("admin"),
+									ContainerPort: pulumi.Int(JaegerAdminPort),
 								},
 							},
+							Env:          jgr.jaegerEnv(args),
+							VolumeMounts: vmounts,
+							Resources:    containerResources(args.Resources),
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
@@ -273,6 +627,7 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/component": pulumi.String("jaeger"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 		},
@@ -282,11 +637,14 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
-			ClusterIP: pulumi.String("None"), // Headless, for DNS purposes
+			ClusterIP:                pulumi.String("None"), // Headless, for DNS purposes
+			PublishNotReadyAddresses: args.publishNotReadyAddresses,
+			IpFamilyPolicy:           args.IPFamily.policy(),
+			IpFamilies:               args.IPFamily.families(),
 			Ports: corev1.ServicePortArray{
 				corev1.ServicePortArgs{
 					Name: pulumi.String("ui"),
-					Port: pulumi.Int(16686),
+					Port: pulumi.Int(JaegerUIPort),
 				},
 			},
 		},
@@ -302,6 +660,7 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/component": pulumi.String("jaeger"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 		},
@@ -311,11 +670,48 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
-			ClusterIP: pulumi.String("None"), // Headless, for DNS purposes
+			ClusterIP:                pulumi.String("None"), // Headless, for DNS purposes
+			PublishNotReadyAddresses: args.publishNotReadyAddresses,
+			IpFamilyPolicy:           args.IPFamily.policy(),
+			IpFamilies:               args.IPFamily.families(),
 			Ports: corev1.ServicePortArray{
 				corev1.ServicePortArgs{
 					Name: pulumi.String("grpc"),
-					Port: pulumi.Int(4317),
+					Port: pulumi.Int(JaegerOTLPPort),
+				},
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// => The admin endpoint to scrape the Jaeger metrics from
+	jgr.svcadmin, err = corev1.NewService(ctx, "jaeger-admin", &corev1.ServiceArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Name:      pulumi.String(JaegerAdminServiceName),
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("jaeger"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Spec: corev1.ServiceSpecArgs{
+			Selector: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("jaeger"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			ClusterIP:                pulumi.String("None"), // Headless, for each pod to be scraped
+			PublishNotReadyAddresses: args.publishNotReadyAddresses,
+			IpFamilyPolicy:           args.IPFamily.policy(),
+			IpFamilies:               args.IPFamily.families(),
+			Ports: corev1.ServicePortArray{
+				corev1.ServicePortArgs{
+					Name: pulumi.String("admin"),
+					Port: pulumi.Int(JaegerAdminPort),
 				},
 			},
 		},
@@ -327,16 +723,186 @@ func (jgr *Jaeger) provision(ctx *pulumi.Context, args *JaegerArgs, opts ...pulu
 	return
 }
 
-func (jgr *Jaeger) outputs(ctx *pulumi.Context) error {
-	jgr.URL = pulumi.Sprintf(
-		"http://%s:%d",
-		jgr.svcgrpc.Metadata.Name().Elem(),
-		jgr.svcgrpc.Spec.Ports().Index(pulumi.Int(0)).Port(),
-	)
+func (jgr *Jaeger) outputs(ctx *pulumi.Context, args *JaegerArgs) error {
+	jgr.Service = NewServiceRef(jgr.svcgrpc)
+	jgr.Port = jgr.Service.Port
+	jgr.URL = jgr.Service.URL("http", args.ClusterDomain)
 	jgr.PodLabels = jgr.dep.Spec.Template().Metadata().Labels()
+	jgr.UIServiceName = jgr.svcui.Metadata.Name().Elem()
+	jgr.Ready = rolledOut(jgr.dep.Spec.Replicas(), jgr.dep.Status.ReadyReplicas())
 
 	return ctx.RegisterResourceOutputs(jgr, pulumi.Map{
-		"url":       jgr.URL,
-		"podLabels": jgr.PodLabels,
+		"url":           jgr.URL,
+		"port":          jgr.Port,
+		"podLabels":     jgr.PodLabels,
+		"uiServiceName": jgr.UIServiceName,
+		"ready":         jgr.Ready,
 	})
 }
+
+// checkJaegerQuery validates the query limits, which are rendered as-is.
+func checkJaegerQuery(query *JaegerQueryArgs) error {
+	if query == nil {
+		return nil
+	}
+	for _, d := range []struct {
+		Name  string
+		Value time.Duration
+	}{
+		{"max clock skew adjust", query.MaxClockSkewAdjust},
+		{"http read timeout", query.HTTPReadTimeout},
+		{"http write timeout", query.HTTPWriteTimeout},
+		{"grpc max connection age", query.GRPCMaxConnectionAge},
+	} {
+		if d.Value < 0 {
+			return fmt.Errorf("%s could not be negative, got %s", d.Name, d.Value)
+		}
+	}
+	if query.MaxTraces < 0 {
+		return fmt.Errorf("max traces could not be negative, got %d", query.MaxTraces)
+	}
+	if query.MaxLookback != "" && !lookbackRegex.MatchString(query.MaxLookback) {
+		return fmt.Errorf("invalid max lookback %q, expected a number of minutes, hours or days (e.g. 12h, 2d)", query.MaxLookback)
+	}
+	return nil
+}
+
+// checkJaegerArchive validates the archive storage, if any, could be used
+// by the given number of replicas.
+func checkJaegerArchive(archive *JaegerArchiveArgs, replicas int) error {
+	if archive == nil {
+		return nil
+	}
+	switch {
+	case archive.Badger == nil && archive.Elasticsearch == nil:
+		return errors.New("either badger or elasticsearch is required")
+	case archive.Badger != nil && archive.Elasticsearch != nil:
+		return errors.New("badger and elasticsearch are mutually exclusive")
+	case archive.Badger != nil && replicas > 1:
+		return fmt.Errorf("badger requires a single replica, got %d", replicas)
+	}
+
+	if es := archive.Elasticsearch; es != nil {
+		if len(es.ServerURLs) == 0 {
+			return errors.New("elasticsearch requires at least one server url")
+		}
+		for _, u := range es.ServerURLs {
+			if err := checkValidURL(u); err != nil {
+				return errors.Wrapf(err, "invalid elasticsearch url %s", u)
+			}
+		}
+		if es.Password != nil && es.PasswordSecret != nil {
+			return errors.New("elasticsearch password and password secret are mutually exclusive")
+		}
+		if (es.Username == "") != (es.Password == nil && es.PasswordSecret == nil) {
+			return errors.New("elasticsearch username and password go together")
+		}
+		if err := checkExistingSecret(es.PasswordSecret); err != nil {
+			return errors.Wrap(err, "invalid elasticsearch password secret")
+		}
+	}
+	return nil
+}
+
+// renderJaegerConfig renders the Jaeger configuration of the given (defaulted)
+// arguments.
+func renderJaegerConfig(args *JaegerArgs, prometheusURL string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := jaegerTemplate.Execute(buf, map[string]any{
+		"PrometheusURL": prometheusURL,
+		"SPM":           !args.DisableSPM,
+		"AdminPort":     JaegerAdminPort,
+		"Archive":       args.Archive,
+		"ArchiveDir":    jaegerArchiveDir,
+		"Query":         args.Query,
+		"MaxTraces":     args.MemoryMaxTraces,
+	}); err != nil {
+		return "", err
+	}
+	return normalizeDocument(buf.String()), nil
+}
+
+// renderJaegerUIConfig renders the Jaeger UI configuration, with the archive
+// button if the archive storage is turned on, and the search limits if any.
+func renderJaegerUIConfig(args *JaegerArgs) (string, error) {
+	search := map[string]any{}
+	if q := args.Query; q != nil {
+		if q.MaxTraces != 0 {
+			search["maxLimit"] = q.MaxTraces
+		}
+		if q.MaxLookback != "" {
+			search["maxLookback"] = map[string]any{
+				"label": q.MaxLookback,
+				"value": q.MaxLookback,
+			}
+		}
+	}
+	if args.Archive == nil && len(search) == 0 {
+		return jaegerUI, nil
+	}
+
+	ui := map[string]any{}
+	if err := json.Unmarshal([]byte(jaegerUI), &ui); err != nil {
+		return "", err
+	}
+	if args.Archive != nil {
+		ui["archiveEnabled"] = true
+	}
+	if len(search) != 0 {
+		ui["search"] = search
+	}
+	return marshalDocument(ui, true)
+}
+
+// containerResources returns the resources of a container, if any.
+func containerResources(res corev1.ResourceRequirementsInput) corev1.ResourceRequirementsPtrInput {
+	if res == nil {
+		return nil
+	}
+	return res.ToResourceRequirementsOutput().ToResourceRequirementsPtrOutput()
+}
+
+// jaegerEnv returns the environment variables of the Jaeger container, the
+// configuration refers to.
+func (jgr *Jaeger) jaegerEnv(args *JaegerArgs) corev1.EnvVarArray {
+	env := corev1.EnvVarArray{}
+	if args.Archive == nil || args.Archive.Elasticsearch == nil {
+		return env
+	}
+
+	es := args.Archive.Elasticsearch
+	var ref *corev1.SecretKeySelectorArgs
+	switch {
+	case es.PasswordSecret != nil:
+		ref = &corev1.SecretKeySelectorArgs{
+			Name: es.PasswordSecret.Name,
+			Key:  pulumi.String(es.PasswordSecret.key(BasicAuthPasswordKey)),
+		}
+	case es.Password != nil:
+		ref = &corev1.SecretKeySelectorArgs{
+			Name: jgr.archiveSec.Metadata.Name().Elem(),
+			Key:  pulumi.String(BasicAuthPasswordKey),
+		}
+	}
+	if ref != nil {
+		env = append(env, corev1.EnvVarArgs{
+			Name: pulumi.String("ARCHIVE_ES_PASSWORD"),
+			ValueFrom: corev1.EnvVarSourceArgs{
+				SecretKeyRef: ref,
+			},
+		})
+	}
+	return env
+}
+
+// JaegerScrapeTarget is the Prometheus scrape target of the Jaeger own
+// metrics. The admin Service being headless, every Jaeger pod is discovered
+// through its DNS records.
+func JaegerScrapeTarget() ScrapeTarget {
+	return ScrapeTarget{
+		JobName:  "jaeger",
+		Service:  JaegerAdminServiceName,
+		Port:     JaegerAdminPort,
+		Headless: true,
+	}
+}
@@ -0,0 +1,563 @@
+package parts
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Jaeger_PublishNotReadyAddresses(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Input    pulumi.BoolInput
+		Expected bool
+	}{
+		"default": {
+			Input:    nil,
+			Expected: false,
+		},
+		"enabled": {
+			Input:    pulumi.Bool(true),
+			Expected: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+					Namespace:                pulumi.String("monitoring"),
+					PrometheusURL:            pulumi.String("http://prometheus:9090"),
+					PublishNotReadyAddresses: tt.Input,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			svcs := m.ByType("kubernetes:core/v1:Service")
+			if len(svcs) != 3 {
+				t.Fatalf("expected 3 services, got %d", len(svcs))
+			}
+			for _, svc := range svcs {
+				got := svc["spec"].ObjectValue()["publishNotReadyAddresses"]
+				if !got.IsBool() || got.BoolValue() != tt.Expected {
+					t.Fatalf("expected publishNotReadyAddresses to be %t, got %v", tt.Expected, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Jaeger_DisableSPM(t *testing.T) {
+	t.Parallel()
+
+	for _, disable := range []bool{false, true} {
+		cfg, err := renderJaegerConfig(&JaegerArgs{DisableSPM: disable}, "http://prometheus:9090")
+		if err != nil {
+			t.Fatalf("rendering configuration: %s", err)
+		}
+		if strings.Contains(cfg, "metric_backends") == disable {
+			t.Errorf("with SPM disabled=%t, got configuration:\n%s", disable, cfg)
+		}
+	}
+}
+
+func Test_U_Jaeger_StorageExporter(t *testing.T) {
+	t.Parallel()
+
+	// The received traces must reach the storage jaeger_query reads, for it
+	// to serve them and derive the dependency links of System Architecture.
+	out, err := renderJaegerConfig(&JaegerArgs{}, "http://prometheus:9090")
+	if err != nil {
+		t.Fatalf("rendering configuration: %s", err)
+	}
+	cfg := map[string]any{}
+	if err := yaml.Unmarshal([]byte(out), &cfg); err != nil {
+		t.Fatalf("invalid configuration: %s", err)
+	}
+
+	pipeline := cfg["service"].(map[string]any)["pipelines"].(map[string]any)["traces"].(map[string]any)
+	if exporters := pipeline["exporters"]; !reflect.DeepEqual(exporters, []any{"jaeger_storage_exporter"}) {
+		t.Errorf("expected the traces to be exported to the storage, got %v", exporters)
+	}
+	exporter := cfg["exporters"].(map[string]any)["jaeger_storage_exporter"].(map[string]any)
+	query := cfg["extensions"].(map[string]any)["jaeger_query"].(map[string]any)
+	if got, want := exporter["trace_storage"], query["storage"].(map[string]any)["traces"]; got != want {
+		t.Errorf("expected the traces to be stored in %v, got %v", want, got)
+	}
+}
+
+func Test_U_Jaeger_AdminPort(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+			Namespace:     pulumi.String("monitoring"),
+			PrometheusURL: pulumi.String("http://prometheus:9090"),
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The container exposes the admin port
+	dep := m.ByName("kubernetes:apps/v1:Deployment", "jaeger")
+	ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+	found := false
+	for _, p := range ctr["ports"].ArrayValue() {
+		port := p.ObjectValue()
+		if port["name"].StringValue() == "admin" && port["containerPort"].NumberValue() == JaegerAdminPort {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected the admin container port, got %v", ctr["ports"])
+	}
+
+	// The Service, scraped by Prometheus, has a fixed name
+	svc := m.ByName("kubernetes:core/v1:Service", "jaeger-admin")
+	if svc == nil {
+		t.Fatal("jaeger admin service not found")
+	}
+	if name := svc["metadata"].ObjectValue()["name"].StringValue(); name != JaegerAdminServiceName {
+		t.Errorf("expected service name %s, got %s", JaegerAdminServiceName, name)
+	}
+	ports := svc["spec"].ObjectValue()["ports"].ArrayValue()
+	if len(ports) != 1 || ports[0].ObjectValue()["port"].NumberValue() != JaegerAdminPort {
+		t.Errorf("expected the service to expose port %d, got %v", JaegerAdminPort, ports)
+	}
+
+	// Jaeger serves its metrics on it
+	cm := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")
+	cfg := cm["data"].ObjectValue()["config.yaml"].StringValue()
+	if !strings.Contains(cfg, "port: 14269") {
+		t.Errorf("expected the metrics to be served on the admin port, got:\n%s", cfg)
+	}
+}
+
+func Test_U_Jaeger_Archive(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Archive         *JaegerArchiveArgs
+		PlatformIDs     bool
+		ExpectedBackend string
+		ExpectPVC       bool
+		ExpectedFsGroup float64
+		// ExpectedSecret and ExpectedSecretKey are the reference of the
+		// password, if any.
+		ExpectedSecret    string
+		ExpectedSecretKey string
+	}{
+		"disabled": {
+			Archive: nil,
+		},
+		"badger": {
+			Archive: &JaegerArchiveArgs{
+				Badger: &JaegerBadgerArgs{},
+			},
+			ExpectedBackend: "badger",
+			ExpectPVC:       true,
+			ExpectedFsGroup: 10001,
+		},
+		"badger-openshift": {
+			Archive: &JaegerArchiveArgs{
+				Badger: &JaegerBadgerArgs{},
+			},
+			PlatformIDs:     true,
+			ExpectedBackend: "badger",
+			ExpectPVC:       true,
+		},
+		"elasticsearch": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+				},
+			},
+			ExpectedBackend: "elasticsearch",
+		},
+		"elasticsearch-inline-password": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+					Username:   "jaeger",
+					Password:   pulumi.String("s3cr3t"),
+				},
+			},
+			ExpectedBackend:   "elasticsearch",
+			ExpectedSecret:    "jaeger-archive-es",
+			ExpectedSecretKey: BasicAuthPasswordKey,
+		},
+		"elasticsearch-existing-secret": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+					Username:   "jaeger",
+					PasswordSecret: &ExistingSecretArgs{
+						Name: pulumi.String("es-credentials"),
+						Key:  "es-password",
+					},
+				},
+			},
+			ExpectedBackend:   "elasticsearch",
+			ExpectedSecret:    "es-credentials",
+			ExpectedSecretKey: "es-password",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+					Namespace:   pulumi.String("monitoring"),
+					DisableSPM:  true,
+					Archive:     tt.Archive,
+					PlatformIDs: tt.PlatformIDs,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The query serves the archive storage, with the UI button
+			data := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()
+			cfg := map[string]any{}
+			if err := yaml.Unmarshal([]byte(data["config.yaml"].StringValue()), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			exts := cfg["extensions"].(map[string]any)
+			storage := exts["jaeger_query"].(map[string]any)["storage"].(map[string]any)
+			if _, ok := storage["traces_archive"]; ok != (tt.Archive != nil) {
+				t.Errorf("expected the archive storage to be %t, got %v", tt.Archive != nil, storage)
+			}
+			backends := exts["jaeger_storage"].(map[string]any)["backends"].(map[string]any)
+			if tt.ExpectedBackend != "" {
+				archive, _ := backends["archive"].(map[string]any)
+				if _, ok := archive[tt.ExpectedBackend]; !ok {
+					t.Errorf("expected the archive to be stored in %s, got %v", tt.ExpectedBackend, archive)
+				}
+			}
+			ui := map[string]any{}
+			if err := json.Unmarshal([]byte(data["jaeger-ui.json"].StringValue()), &ui); err != nil {
+				t.Fatalf("invalid ui configuration: %s", err)
+			}
+			if enabled, _ := ui["archiveEnabled"].(bool); enabled != (tt.Archive != nil) {
+				t.Errorf("expected the archive button to be %t, got %v", tt.Archive != nil, ui["archiveEnabled"])
+			}
+
+			// The Badger database is persisted, and not shared during rollouts
+			pvc := m.ByName("kubernetes:core/v1:PersistentVolumeClaim", "jaeger-archive")
+			if (pvc != nil) != tt.ExpectPVC {
+				t.Errorf("expected archive pvc %t, got %v", tt.ExpectPVC, pvc)
+			}
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "jaeger")["spec"].ObjectValue()
+			strategy := ""
+			if s, ok := spec["strategy"].ObjectValue()["type"]; ok {
+				strategy = s.StringValue()
+			}
+			if (strategy == "Recreate") != tt.ExpectPVC {
+				t.Errorf("expected the recreate strategy %t, got %q", tt.ExpectPVC, strategy)
+			}
+
+			// The database is writable by the image group, unless the
+			// platform assigns it
+			podSpec := spec["template"].ObjectValue()["spec"].ObjectValue()
+			fsGroup := 0.
+			if sc, ok := podSpec["securityContext"]; ok {
+				if g, ok := sc.ObjectValue()["fsGroup"]; ok {
+					fsGroup = g.NumberValue()
+				}
+			}
+			if fsGroup != tt.ExpectedFsGroup {
+				t.Errorf("expected fsGroup %v, got %v", tt.ExpectedFsGroup, fsGroup)
+			}
+
+			// The Elasticsearch password is referenced from the Secret, only
+			// generated for an inline one
+			ctr := spec["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			secret, key := "", ""
+			if env, ok := ctr["env"]; ok {
+				for _, e := range env.ArrayValue() {
+					if e.ObjectValue()["name"].StringValue() == "ARCHIVE_ES_PASSWORD" {
+						ref := e.ObjectValue()["valueFrom"].ObjectValue()["secretKeyRef"].ObjectValue()
+						secret, key = ref["name"].StringValue(), ref["key"].StringValue()
+					}
+				}
+			}
+			if secret != tt.ExpectedSecret || key != tt.ExpectedSecretKey {
+				t.Errorf("expected the password from %s/%s, got %s/%s", tt.ExpectedSecret, tt.ExpectedSecretKey, secret, key)
+			}
+			generated := m.ByName("kubernetes:core/v1:Secret", "jaeger-archive-es")
+			if (generated != nil) != (tt.ExpectedSecret == "jaeger-archive-es") {
+				t.Errorf("expected the password secret to be generated %t, got %v", tt.ExpectedSecret == "jaeger-archive-es", generated)
+			}
+		})
+	}
+}
+
+func Test_U_Jaeger_ArchiveCheck(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Archive   *JaegerArchiveArgs
+		Replicas  int
+		ExpectErr bool
+	}{
+		"disabled": {
+			Archive:  nil,
+			Replicas: 3,
+		},
+		"empty": {
+			Archive:   &JaegerArchiveArgs{},
+			ExpectErr: true,
+		},
+		"both": {
+			Archive: &JaegerArchiveArgs{
+				Badger: &JaegerBadgerArgs{},
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+				},
+			},
+			ExpectErr: true,
+		},
+		"badger-replicas": {
+			Archive: &JaegerArchiveArgs{
+				Badger: &JaegerBadgerArgs{},
+			},
+			Replicas:  2,
+			ExpectErr: true,
+		},
+		"elasticsearch-replicas": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+				},
+			},
+			Replicas: 2,
+		},
+		"elasticsearch-no-url": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{},
+			},
+			ExpectErr: true,
+		},
+		"elasticsearch-password-both": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+					Username:   "jaeger",
+					Password:   pulumi.String("s3cr3t"),
+					PasswordSecret: &ExistingSecretArgs{
+						Name: pulumi.String("es-credentials"),
+					},
+				},
+			},
+			ExpectErr: true,
+		},
+		"elasticsearch-password-only": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+					Password:   pulumi.String("s3cr3t"),
+				},
+			},
+			ExpectErr: true,
+		},
+		"elasticsearch-secret-without-name": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs:     []string{"https://es.example.com:9200"},
+					Username:       "jaeger",
+					PasswordSecret: &ExistingSecretArgs{},
+				},
+			},
+			ExpectErr: true,
+		},
+		"elasticsearch-username-only": {
+			Archive: &JaegerArchiveArgs{
+				Elasticsearch: &JaegerElasticsearchArgs{
+					ServerURLs: []string{"https://es.example.com:9200"},
+					Username:   "jaeger",
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkJaegerArchive(tt.Archive, tt.Replicas)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Jaeger_Query(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Query     *JaegerQueryArgs
+		Resources corev1.ResourceRequirementsInput
+		// ExpectedQuery are the jaeger_query settings, but for the storage
+		// and ui ones.
+		ExpectedQuery    map[string]any
+		ExpectedSearch   any
+		ExpectedCPULimit string
+	}{
+		"defaults": {
+			Query:         nil,
+			ExpectedQuery: map[string]any{},
+		},
+		"limits": {
+			Query: &JaegerQueryArgs{
+				MaxClockSkewAdjust:   time.Second,
+				MaxTraces:            200,
+				MaxLookback:          "12h",
+				HTTPReadTimeout:      10 * time.Second,
+				HTTPWriteTimeout:     time.Minute,
+				GRPCMaxConnectionAge: 5 * time.Minute,
+			},
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"cpu": pulumi.String("1"),
+				},
+			},
+			ExpectedQuery: map[string]any{
+				"max_clock_skew_adjust": "1s",
+				"http": map[string]any{
+					"read_timeout":  "10s",
+					"write_timeout": "1m0s",
+				},
+				"grpc": map[string]any{
+					"keepalive": map[string]any{
+						"server_parameters": map[string]any{
+							"max_connection_age":       "5m0s",
+							"max_connection_age_grace": "5m0s",
+						},
+					},
+				},
+			},
+			ExpectedSearch: map[string]any{
+				"maxLimit": float64(200),
+				"maxLookback": map[string]any{
+					"label": "12h",
+					"value": "12h",
+				},
+			},
+			ExpectedCPULimit: "1",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+					Namespace:  pulumi.String("monitoring"),
+					DisableSPM: true,
+					Query:      tt.Query,
+					Resources:  tt.Resources,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			data := m.ByName("kubernetes:core/v1:ConfigMap", "spm-config")["data"].ObjectValue()
+			cfg := map[string]any{}
+			if err := yaml.Unmarshal([]byte(data["config.yaml"].StringValue()), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			query := cfg["extensions"].(map[string]any)["jaeger_query"].(map[string]any)
+			delete(query, "storage")
+			delete(query, "ui")
+			if !reflect.DeepEqual(query, tt.ExpectedQuery) {
+				t.Errorf("expected jaeger_query %v, got %v", tt.ExpectedQuery, query)
+			}
+
+			ui := map[string]any{}
+			if err := json.Unmarshal([]byte(data["jaeger-ui.json"].StringValue()), &ui); err != nil {
+				t.Fatalf("invalid ui configuration: %s", err)
+			}
+			if !reflect.DeepEqual(ui["search"], tt.ExpectedSearch) {
+				t.Errorf("expected ui search %v, got %v", tt.ExpectedSearch, ui["search"])
+			}
+
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "jaeger")["spec"].ObjectValue()
+			ctr := spec["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			cpu := ""
+			if res, ok := ctr["resources"]; ok {
+				cpu = res.ObjectValue()["limits"].ObjectValue()["cpu"].StringValue()
+			}
+			if cpu != tt.ExpectedCPULimit {
+				t.Errorf("expected cpu limit %q, got %q", tt.ExpectedCPULimit, cpu)
+			}
+		})
+	}
+}
+
+func Test_U_Jaeger_QueryCheck(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Query     *JaegerQueryArgs
+		ExpectErr bool
+	}{
+		"defaults": {
+			Query: &JaegerQueryArgs{},
+		},
+		"negative-timeout": {
+			Query:     &JaegerQueryArgs{HTTPWriteTimeout: -time.Second},
+			ExpectErr: true,
+		},
+		"negative-max-traces": {
+			Query:     &JaegerQueryArgs{MaxTraces: -1},
+			ExpectErr: true,
+		},
+		"days-lookback": {
+			Query: &JaegerQueryArgs{MaxLookback: "7d"},
+		},
+		"go-duration-lookback": {
+			Query:     &JaegerQueryArgs{MaxLookback: "1h30m"},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkJaegerQuery(tt.Query)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
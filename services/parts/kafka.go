@@ -0,0 +1,189 @@
+package parts
+
+import (
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.uber.org/multierr"
+)
+
+type (
+	// KafkaExporterArgs streams the signals into Kafka topics as OTLP, e.g.
+	// for custom analytics off-cluster.
+	KafkaExporterArgs struct {
+		// Brokers are the host:port of the Kafka brokers to bootstrap from.
+		Brokers []string
+
+		// Topics of the signals, only the signals with a topic are streamed.
+		Topics KafkaTopicsArgs
+
+		// Encoding of the messages, otlp_proto or otlp_json.
+		// Defaults to otlp_proto.
+		Encoding string
+
+		// TLS connects to the brokers over TLS.
+		TLS *KafkaTLSArgs
+
+		// SASL authenticates to the brokers.
+		SASL *KafkaSASLArgs
+	}
+
+	// KafkaTopicsArgs are the topics of the signals.
+	KafkaTopicsArgs struct {
+		Traces  string
+		Metrics string
+		Logs    string
+	}
+
+	// KafkaTLSArgs configures the TLS toward the brokers.
+	KafkaTLSArgs struct {
+		// SecretName is the Secret in the collector namespace holding the CA
+		// the brokers certificates are trusted with, at the ca.crt key, and
+		// the client certificate at the tls.crt and tls.key ones if
+		// presented (e.g. as issued by cert-manager).
+		SecretName pulumi.StringInput
+
+		// ClientCertificate presents the client certificate of the Secret
+		// to the brokers, for mutual TLS.
+		ClientCertificate bool
+	}
+
+	// KafkaSASLArgs configures the SASL authentication to the brokers.
+	KafkaSASLArgs struct {
+		Username string
+
+		// Mechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+		// Defaults to SCRAM-SHA-512.
+		Mechanism string
+
+		// PasswordSecretName is the Secret containing the password, at
+		// PasswordSecretKey, in the collector namespace.
+		PasswordSecretName pulumi.StringInput
+
+		// PasswordSecretKey defaults to BasicAuthPasswordKey.
+		PasswordSecretKey string
+	}
+)
+
+const (
+	defaultKafkaEncoding      = "otlp_proto"
+	defaultKafkaSASLMechanism = "SCRAM-SHA-512"
+
+	// otelKafkaTLSPath is where the Kafka TLS Secret is mounted.
+	otelKafkaTLSPath = "/etc/otel-collector/kafka-tls"
+
+	// otelKafkaPasswordEnv is the environment variable of the Kafka SASL
+	// password, referenced by the configuration.
+	otelKafkaPasswordEnv = "KAFKA_PASSWORD"
+
+	// kafkaExporter is the exporter of the signals streamed into Kafka.
+	kafkaExporter = "kafka"
+)
+
+var (
+	kafkaEncodings      = []string{"otlp_proto", "otlp_json"}
+	kafkaSASLMechanisms = []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}
+)
+
+func (k *KafkaExporterArgs) defaults() {
+	if k.Encoding == "" {
+		k.Encoding = defaultKafkaEncoding
+	}
+	if k.SASL != nil {
+		if k.SASL.Mechanism == "" {
+			k.SASL.Mechanism = defaultKafkaSASLMechanism
+		}
+		if k.SASL.PasswordSecretKey == "" {
+			k.SASL.PasswordSecretKey = BasicAuthPasswordKey
+		}
+	}
+}
+
+// checkKafkaExporter validates the Kafka exporter before it is rendered,
+// such that errors are reported at preview time rather than by the collector.
+func checkKafkaExporter(k *KafkaExporterArgs) (merr error) {
+	if len(k.Brokers) == 0 {
+		merr = multierr.Append(merr, errors.New("kafka exporter requires at least one broker"))
+	}
+	for _, broker := range k.Brokers {
+		if _, _, err := splitBroker(broker); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+	if len(k.topics()) == 0 {
+		merr = multierr.Append(merr, errors.New("kafka exporter requires at least one topic"))
+	}
+	if !slices.Contains(kafkaEncodings, k.Encoding) {
+		merr = multierr.Append(merr, errors.Errorf("unsupported kafka encoding %s, must be one of %v", k.Encoding, kafkaEncodings))
+	}
+	if k.TLS != nil && k.TLS.SecretName == nil {
+		merr = multierr.Append(merr, errors.New("kafka tls secret name is not provided"))
+	}
+	if k.SASL != nil {
+		if k.SASL.Username == "" {
+			merr = multierr.Append(merr, errors.New("kafka sasl username is not provided"))
+		}
+		if k.SASL.PasswordSecretName == nil {
+			merr = multierr.Append(merr, errors.New("kafka sasl password secret name is not provided"))
+		}
+		if !slices.Contains(kafkaSASLMechanisms, k.SASL.Mechanism) {
+			merr = multierr.Append(merr, errors.Errorf("unsupported kafka sasl mechanism %s, must be one of %v", k.SASL.Mechanism, kafkaSASLMechanisms))
+		}
+	}
+	return
+}
+
+// topics returns the topics by signal, only for the signals streamed.
+func (k *KafkaExporterArgs) topics() map[string]string {
+	topics := map[string]string{}
+	for signal, topic := range map[string]string{
+		"traces":  k.Topics.Traces,
+		"metrics": k.Topics.Metrics,
+		"logs":    k.Topics.Logs,
+	} {
+		if topic != "" {
+			topics[signal] = topic
+		}
+	}
+	return topics
+}
+
+// BrokerEndpoints returns the hosts and ports of the brokers, e.g. for the
+// NetworkPolicies to let the collector reach them. Invalid brokers are
+// skipped, as reported by the collector checks.
+func (k *KafkaExporterArgs) BrokerEndpoints() []KafkaBrokerEndpoint {
+	edps := []KafkaBrokerEndpoint{}
+	for _, broker := range k.Brokers {
+		host, port, err := splitBroker(broker)
+		if err != nil {
+			continue
+		}
+		edps = append(edps, KafkaBrokerEndpoint{
+			Host: host,
+			Port: port,
+		})
+	}
+	return edps
+}
+
+// KafkaBrokerEndpoint is the host and port of a Kafka broker.
+type KafkaBrokerEndpoint struct {
+	Host string
+	Port int
+}
+
+// splitBroker splits the broker into its host and port.
+func splitBroker(broker string) (string, int, error) {
+	host, p, err := net.SplitHostPort(broker)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "invalid kafka broker %q", broker)
+	}
+	port, err := strconv.Atoi(p)
+	if err != nil || port < 1 || port > 65535 || host == "" {
+		return "", 0, errors.Errorf("invalid kafka broker %q, expected host:port", broker)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,441 @@
+package parts
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type (
+	// LogShipper tails the container logs of every node, and forwards them
+	// to the central OTEL Collector over OTLP. It is the OpenTelemetry
+	// Collector in daemonset mode, restricted to the filelog receiver, such
+	// that the central one does not need to run on every node.
+	//
+	// It mounts the /var/log/pods host path read-only, hence runs in its own
+	// namespace enforcing the privileged Pod Security Standard, rather than
+	// relaxing the one of the monitoring.
+	LogShipper struct {
+		pulumi.ResourceState
+
+		ns      *Namespace
+		cfg     *corev1.ConfigMap
+		sa      *corev1.ServiceAccount
+		role    *rbacv1.ClusterRole
+		binding *rbacv1.ClusterRoleBinding
+		ds      *appsv1.DaemonSet
+
+		// Namespace the LogShipper runs into.
+		Namespace pulumi.StringOutput
+
+		PodLabels pulumi.StringMapOutput
+
+		// Ready resolves to true once the pods are ready on every node
+		// they are scheduled on.
+		Ready pulumi.BoolOutput
+	}
+
+	LogShipperArgs struct {
+		Registry pulumi.StringInput
+		registry pulumi.StringOutput
+
+		// MonitoringVersion is labeled on the resources, as the
+		// OtelCollectorArgs one.
+		MonitoringVersion string
+
+		// Endpoint is the OTLP gRPC endpoint of the central OTEL Collector,
+		// e.g. its OtelCollector.Endpoint. It must not require TLS.
+		Endpoint pulumi.StringInput
+
+		// ExcludeNamespaces are not shipped, e.g. the monitoring one for the
+		// collector debug exporter not to loop on its own logs. The namespace
+		// of the LogShipper is always excluded.
+		ExcludeNamespaces pulumi.StringArrayInput
+
+		// Tolerations of the pods, to ship the logs of tainted nodes too.
+		// Defaults to tolerate every taint.
+		Tolerations corev1.TolerationArrayInput
+
+		// ExporterRetry tunes how the logs are retried toward the central
+		// collector. Zero values are defaulted.
+		ExporterRetry *ExporterRetryArgs
+
+		// Mesh annotates the pods for the service mesh of the cluster.
+		// Defaults to no annotation.
+		Mesh *MeshArgs
+	}
+)
+
+const (
+	// LogShipperLogsPath is the host path the container runtime writes the
+	// pods logs into, as <namespace>_<pod>_<uid>/<container>/<restart>.log.
+	LogShipperLogsPath = "/var/log/pods"
+
+	logShipperNodeEnv = "K8S_NODE_NAME"
+)
+
+// logShipperComponents are the components the log shipper configuration
+// requires in the collector image. Keep it in sync with
+// log-shipper-config.yaml.tmpl.
+var logShipperComponents = CollectorComponents{
+	Receivers:  []string{"filelog"},
+	Processors: []string{"k8sattributes", "batch"},
+	Exporters:  []string{"otlp"},
+}
+
+//go:embed log-shipper-config.yaml.tmpl
+var logShipperConfig string
+var logShipperTemplate *template.Template
+
+func init() {
+	tmpl, err := template.New("log-shipper-config").
+		Parse(logShipperConfig)
+	if err != nil {
+		panic(fmt.Errorf("invalid log shipper configuration template: %s", err))
+	}
+	logShipperTemplate = tmpl
+}
+
+func NewLogShipper(
+	ctx *pulumi.Context,
+	name string,
+	args *LogShipperArgs,
+	opts ...pulumi.ResourceOption,
+) (*LogShipper, error) {
+	ls := &LogShipper{}
+
+	args = ls.defaults(args)
+	if err := ls.check(args); err != nil {
+		return nil, err
+	}
+	if err := ctx.RegisterComponentResource("ctfer-io:monitoring:log-shipper", name, ls, opts...); err != nil {
+		return nil, err
+	}
+	opts = append(opts, pulumi.Parent(ls))
+	if err := ls.provision(ctx, args, opts...); err != nil {
+		return nil, err
+	}
+	if err := ls.outputs(ctx); err != nil {
+		return nil, err
+	}
+
+	return ls, nil
+}
+
+func (*LogShipper) defaults(args *LogShipperArgs) *LogShipperArgs {
+	if args == nil {
+		args = &LogShipperArgs{}
+	}
+
+	// Define private registry if any
+	args.registry = pulumi.String("").ToStringOutput()
+	if args.Registry != nil {
+		args.registry = args.Registry.ToStringPtrOutput().ApplyT(func(in *string) string {
+			// No private registry -> defaults to Docker Hub
+			if in == nil {
+				return ""
+			}
+
+			str := *in
+			// If one set, make sure it ends with one '/'
+			if str != "" && !strings.HasSuffix(str, "/") {
+				str = str + "/"
+			}
+			return str
+		}).(pulumi.StringOutput)
+	}
+
+	if args.ExcludeNamespaces == nil {
+		args.ExcludeNamespaces = pulumi.StringArray{}
+	}
+
+	if args.Tolerations == nil {
+		args.Tolerations = corev1.TolerationArray{
+			corev1.TolerationArgs{
+				Operator: pulumi.String("Exists"),
+			},
+		}
+	}
+
+	args.ExporterRetry = exporterRetryDefaults(args.ExporterRetry)
+
+	return args
+}
+
+func (*LogShipper) check(args *LogShipperArgs) error {
+	if args.Endpoint == nil {
+		return errors.New("log shipper endpoint is not provided")
+	}
+	if err := checkMesh(args.Mesh); err != nil {
+		return errors.Wrap(err, "invalid mesh")
+	}
+	return checkExporterRetry(args.ExporterRetry)
+}
+
+func (ls *LogShipper) provision(
+	ctx *pulumi.Context,
+	args *LogShipperArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	ls.ns, err = NewNamespace(ctx, "log-shipper", &NamespaceArgs{
+		Name:               pulumi.String("log-shipper"),
+		PodSecurityEnforce: "privileged",
+		AdditionalLabels: pulumi.StringMap{
+			"app.kubernetes.io/component": pulumi.String("log-shipper"),
+			"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+			"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+			"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	ls.cfg, err = corev1.NewConfigMap(ctx, "log-shipper-config", &corev1.ConfigMapArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: ls.ns.Name,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("log-shipper"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Data: pulumi.StringMap{
+			"config": pulumi.All(args.Endpoint, args.ExcludeNamespaces, ls.ns.Name).ApplyT(func(all []any) (string, error) {
+				// Always exclude our own namespace
+				exclude := excludedNamespaces(all[1].([]string), all[2].(string))
+				return renderLogShipperConfig(args, all[0].(string), exclude)
+			}).(pulumi.StringOutput),
+		},
+		Immutable: pulumi.Bool(true),
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	ls.sa, err = corev1.NewServiceAccount(ctx, "log-shipper-sa", &corev1.ServiceAccountArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: ls.ns.Name,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("log-shipper"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// The k8sattributes processor enriches the logs with the pods metadata,
+	// including the tenant label
+	ls.role, err = rbacv1.NewClusterRole(ctx, "log-shipper-role", &rbacv1.ClusterRoleArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("log-shipper"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Rules: rbacv1.PolicyRuleArray{
+			rbacv1.PolicyRuleArgs{
+				ApiGroups: pulumi.ToStringArray([]string{""}),
+				Resources: pulumi.ToStringArray([]string{"pods", "namespaces"}),
+				Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch"}),
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	ls.binding, err = rbacv1.NewClusterRoleBinding(ctx, "log-shipper-binding", &rbacv1.ClusterRoleBindingArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("log-shipper"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		RoleRef: rbacv1.RoleRefArgs{
+			ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+			Kind:     pulumi.String("ClusterRole"),
+			Name:     ls.role.Metadata.Name().Elem(),
+		},
+		Subjects: rbacv1.SubjectArray{
+			rbacv1.SubjectArgs{
+				Kind:      pulumi.String("ServiceAccount"),
+				Name:      ls.sa.Metadata.Name().Elem(),
+				Namespace: ls.ns.Name,
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	ls.ds, err = appsv1.NewDaemonSet(ctx, "log-shipper", &appsv1.DaemonSetArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: ls.ns.Name,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+				"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+				"app.kubernetes.io/component": pulumi.String("log-shipper"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Spec: appsv1.DaemonSetSpecArgs{
+			Selector: metav1.LabelSelectorArgs{
+				MatchLabels: pulumi.StringMap{
+					"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+					"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+					"app.kubernetes.io/component": pulumi.String("log-shipper"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			Template: corev1.PodTemplateSpecArgs{
+				Metadata: metav1.ObjectMetaArgs{
+					Namespace: ls.ns.Name,
+					Labels: pulumi.StringMap{
+						"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+						"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+						"app.kubernetes.io/component": pulumi.String("log-shipper"),
+						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+					},
+					Annotations: meshAnnotations(nil, args.Mesh.PodAnnotations()),
+				},
+				Spec: corev1.PodSpecArgs{
+					ServiceAccountName: ls.sa.Metadata.Name(),
+					Tolerations:        args.Tolerations,
+					Containers: corev1.ContainerArray{
+						corev1.ContainerArgs{
+							Name:  pulumi.String("log-shipper"),
+							Image: pulumi.Sprintf("%s%s", args.registry, defaultOtelImage),
+							Args: pulumi.ToStringArray([]string{
+								"--config=/etc/otel-collector/config.yaml",
+							}),
+							Env: corev1.EnvVarArray{
+								corev1.EnvVarArgs{
+									Name: pulumi.String(logShipperNodeEnv),
+									ValueFrom: corev1.EnvVarSourceArgs{
+										FieldRef: corev1.ObjectFieldSelectorArgs{
+											FieldPath: pulumi.String("spec.nodeName"),
+										},
+									},
+								},
+							},
+							VolumeMounts: corev1.VolumeMountArray{
+								corev1.VolumeMountArgs{
+									Name:      pulumi.String("config-volume"),
+									MountPath: pulumi.String("/etc/otel-collector"),
+									ReadOnly:  pulumi.Bool(true),
+								},
+								corev1.VolumeMountArgs{
+									Name:      pulumi.String("pods-logs"),
+									MountPath: pulumi.String(LogShipperLogsPath),
+									ReadOnly:  pulumi.Bool(true),
+								},
+							},
+							// The logs are only readable by root, but nothing
+							// else is granted
+							SecurityContext: corev1.SecurityContextArgs{
+								RunAsUser:                pulumi.Int(0),
+								RunAsNonRoot:             pulumi.Bool(false),
+								AllowPrivilegeEscalation: pulumi.Bool(false),
+								ReadOnlyRootFilesystem:   pulumi.Bool(true),
+								Capabilities: corev1.CapabilitiesArgs{
+									Drop: pulumi.ToStringArray([]string{"ALL"}),
+								},
+							},
+						},
+					},
+					Volumes: corev1.VolumeArray{
+						corev1.VolumeArgs{
+							Name: pulumi.String("config-volume"),
+							ConfigMap: corev1.ConfigMapVolumeSourceArgs{
+								Name:        ls.cfg.Metadata.Name(),
+								DefaultMode: pulumi.Int(0644),
+								Items: corev1.KeyToPathArray{
+									corev1.KeyToPathArgs{
+										Key:  pulumi.String("config"),
+										Path: pulumi.String("config.yaml"),
+									},
+								},
+							},
+						},
+						corev1.VolumeArgs{
+							Name: pulumi.String("pods-logs"),
+							HostPath: corev1.HostPathVolumeSourceArgs{
+								Path: pulumi.String(LogShipperLogsPath),
+								Type: pulumi.String("Directory"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (ls *LogShipper) outputs(ctx *pulumi.Context) error {
+	ls.Namespace = ls.ns.Name
+	ls.PodLabels = ls.ds.Spec.Template().Metadata().Labels()
+	ls.Ready = rolledOut(ls.ds.Status.DesiredNumberScheduled(), ls.ds.Status.NumberReady())
+
+	return ctx.RegisterResourceOutputs(ls, pulumi.Map{
+		"namespace": ls.Namespace,
+		"podLabels": ls.PodLabels,
+		"ready":     ls.Ready,
+	})
+}
+
+// excludedNamespaces returns the sorted namespaces not to ship the logs of,
+// including the one of the LogShipper.
+func excludedNamespaces(exclude []string, namespace string) []string {
+	out := slices.Clone(exclude)
+	if namespace != "" {
+		out = append(out, namespace)
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// renderLogShipperConfig renders the log shipper configuration of the given
+// (defaulted) arguments.
+func renderLogShipperConfig(args *LogShipperArgs, endpoint string, exclude []string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := logShipperTemplate.Execute(buf, map[string]any{
+		"Endpoint":          endpoint,
+		"ExcludeNamespaces": exclude,
+		"LogsPath":          LogShipperLogsPath,
+		"NodeEnv":           logShipperNodeEnv,
+		"TenantLabel":       defaultTenantAttribute,
+		"Retry":             args.ExporterRetry,
+	}); err != nil {
+		return "", err
+	}
+	return normalizeDocument(buf.String()), nil
+}
@@ -0,0 +1,161 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_LogShipper(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Tolerations         corev1.TolerationArrayInput
+		ExpectedTolerations []string
+	}{
+		"default-tolerations": {
+			Tolerations:         nil,
+			ExpectedTolerations: []string{"/Exists"},
+		},
+		"tolerations": {
+			Tolerations: corev1.TolerationArray{
+				corev1.TolerationArgs{
+					Key:      pulumi.String("node-role.kubernetes.io/control-plane"),
+					Operator: pulumi.String("Exists"),
+				},
+			},
+			ExpectedTolerations: []string{"node-role.kubernetes.io/control-plane/Exists"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewLogShipper(ctx, "log-shipper", &LogShipperArgs{
+					Endpoint:    pulumi.String("otlp-grpc.monitoring:4317"),
+					Tolerations: tt.Tolerations,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The host path requires a privileged namespace of its own
+			nss := m.ByType("kubernetes:core/v1:Namespace")
+			if len(nss) != 1 {
+				t.Fatalf("expected 1 namespace, got %d", len(nss))
+			}
+			labels := nss[0]["metadata"].ObjectValue()["labels"].ObjectValue()
+			if enforce := labels["pod-security.kubernetes.io/enforce"].StringValue(); enforce != "privileged" {
+				t.Errorf("expected the privileged pod security standard, got %s", enforce)
+			}
+
+			spec := m.ByName("kubernetes:apps/v1:DaemonSet", "log-shipper")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+
+			tolerations := []string{}
+			for _, tol := range spec["tolerations"].ArrayValue() {
+				obj := tol.ObjectValue()
+				key := ""
+				if k, ok := obj["key"]; ok {
+					key = k.StringValue()
+				}
+				tolerations = append(tolerations, key+"/"+obj["operator"].StringValue())
+			}
+			if !slices.Equal(tolerations, tt.ExpectedTolerations) {
+				t.Errorf("expected tolerations %v, got %v", tt.ExpectedTolerations, tolerations)
+			}
+
+			// The pods logs are mounted read-only
+			hostPaths := 0
+			for _, v := range spec["volumes"].ArrayValue() {
+				if hp, ok := v.ObjectValue()["hostPath"]; ok {
+					hostPaths++
+					if path := hp.ObjectValue()["path"].StringValue(); path != LogShipperLogsPath {
+						t.Errorf("expected host path %s, got %s", LogShipperLogsPath, path)
+					}
+				}
+			}
+			if hostPaths != 1 {
+				t.Errorf("expected 1 host path volume, got %d", hostPaths)
+			}
+			ctr := spec["containers"].ArrayValue()[0].ObjectValue()
+			for _, vm := range ctr["volumeMounts"].ArrayValue() {
+				if !vm.ObjectValue()["readOnly"].BoolValue() {
+					t.Errorf("expected volume mount %s to be read-only", vm.ObjectValue()["name"].StringValue())
+				}
+			}
+			sc := ctr["securityContext"].ObjectValue()
+			if sc["allowPrivilegeEscalation"].BoolValue() {
+				t.Error("expected no privilege escalation")
+			}
+			if drop := sc["capabilities"].ObjectValue()["drop"].ArrayValue(); len(drop) != 1 || drop[0].StringValue() != "ALL" {
+				t.Errorf("expected all capabilities to be dropped, got %v", drop)
+			}
+
+			// The k8sattributes processor only reads the pods and namespaces
+			role := m.ByName("kubernetes:rbac.authorization.k8s.io/v1:ClusterRole", "log-shipper-role")
+			if role == nil {
+				t.Fatal("expected a cluster role")
+			}
+			for _, rule := range role["rules"].ArrayValue() {
+				for _, verb := range rule.ObjectValue()["verbs"].ArrayValue() {
+					if !slices.Contains([]string{"get", "list", "watch"}, verb.StringValue()) {
+						t.Errorf("expected read-only verbs, got %s", verb.StringValue())
+					}
+				}
+			}
+			binding := m.ByName("kubernetes:rbac.authorization.k8s.io/v1:ClusterRoleBinding", "log-shipper-binding")
+			if binding == nil {
+				t.Fatal("expected a cluster role binding")
+			}
+			subject := binding["subjects"].ArrayValue()[0].ObjectValue()
+			if ns := subject["namespace"].StringValue(); ns != "log-shipper-abcdefgh" {
+				t.Errorf("expected the service account of the log shipper namespace, got %s", ns)
+			}
+		})
+	}
+}
+
+func Test_U_LogShipper_Config(t *testing.T) {
+	t.Parallel()
+
+	args := (&LogShipper{}).defaults(&LogShipperArgs{})
+	exclude := excludedNamespaces([]string{"monitoring-abcdefgh", "kube-system"}, "log-shipper-abcdefgh")
+	cfg, err := renderLogShipperConfig(args, "otlp-grpc.monitoring-abcdefgh:4317", exclude)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join("testdata", "log-shipper-config.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	if cfg != string(b) {
+		t.Errorf("expected configuration:\n%s\ngot:\n%s", b, cfg)
+	}
+
+	// The components are built in the image
+	available := knownCollectorComponents[imageRepository(defaultOtelImage)]
+	if missing := logShipperComponents.missing(&available); len(missing) != 0 {
+		t.Errorf("expected the components to be built in %s, missing %v", defaultOtelImage, missing)
+	}
+}
+
+func Test_U_LogShipper_Check(t *testing.T) {
+	t.Parallel()
+
+	err := (&LogShipper{}).check((&LogShipper{}).defaults(&LogShipperArgs{}))
+	if err == nil {
+		t.Fatal("expected an error without endpoint")
+	}
+}
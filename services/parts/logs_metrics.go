@@ -0,0 +1,121 @@
+package parts
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// LogsMetricsArgs derives metrics from the log records received, e.g.
+	// the errors per challenge per minute, without a log backend: the
+	// records at or above MinSeverity are counted by the Attributes in the
+	// LogRecordsMetric metric, sent to Prometheus whatever the logs become.
+	LogsMetricsArgs struct {
+		// Attributes are the resource attributes the records are counted
+		// by, e.g. the one identifying the challenge.
+		// Defaults to service.name.
+		Attributes []string
+
+		// MinSeverity is the lowest severity counted, among TRACE, DEBUG,
+		// INFO, WARN, ERROR and FATAL.
+		// Defaults to ERROR.
+		MinSeverity string
+
+		// Dedup deduplicates the identical records over DedupInterval
+		// before they are counted, e.g. for a crash-looping challenge to
+		// count once per interval rather than once per restart. Only the
+		// counted records are deduplicated, not the ones exported.
+		Dedup bool
+
+		// DedupInterval is the interval the identical records are
+		// deduplicated over. Requires Dedup.
+		// Defaults to 1m.
+		DedupInterval time.Duration
+	}
+)
+
+const (
+	defaultLogsMetricsAttribute   = "service.name"
+	defaultLogsMetricsMinSeverity = "ERROR"
+	defaultLogsDedupInterval      = time.Minute
+
+	// LogRecordsMetric counts the log records at or above the minimum
+	// severity, by attributes, as named in Prometheus.
+	LogRecordsMetric = "monitoring_log_records_total"
+
+	logsMetricsCount   = "count/logs"
+	logsMetricsForward = "forward/logs"
+	logDedupProcessor  = "logdedup"
+)
+
+// logSeverities are the severities the records could be counted from, in
+// increasing order.
+var logSeverities = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+func (lm *LogsMetricsArgs) defaults() {
+	if len(lm.Attributes) == 0 {
+		lm.Attributes = []string{defaultLogsMetricsAttribute}
+	}
+	if lm.MinSeverity == "" {
+		lm.MinSeverity = defaultLogsMetricsMinSeverity
+	}
+	if lm.Dedup && lm.DedupInterval == 0 {
+		lm.DedupInterval = defaultLogsDedupInterval
+	}
+}
+
+// checkLogsMetrics validates the logs metrics before they are rendered.
+func checkLogsMetrics(lm *LogsMetricsArgs) (merr error) {
+	for i, attr := range lm.Attributes {
+		if !attributeRegex.MatchString(attr) {
+			merr = multierr.Append(merr, errors.Errorf("invalid logs metrics attribute %q", attr))
+		}
+		if slices.Contains(lm.Attributes[:i], attr) {
+			merr = multierr.Append(merr, errors.Errorf("duplicated logs metrics attribute %s", attr))
+		}
+	}
+	if !slices.Contains(logSeverities, lm.MinSeverity) {
+		merr = multierr.Append(merr, errors.Errorf("unsupported logs metrics severity %s, must be one of %s", lm.MinSeverity, strings.Join(logSeverities, ", ")))
+	}
+	if lm.DedupInterval < 0 {
+		merr = multierr.Append(merr, errors.New("logs dedup interval could not be negative"))
+	}
+	if !lm.Dedup && lm.DedupInterval != 0 {
+		merr = multierr.Append(merr, errors.New("logs dedup interval requires dedup"))
+	}
+	return
+}
+
+// exporter returns the exporter of the logs pipeline the records are
+// counted from.
+func (lm *LogsMetricsArgs) exporter() string {
+	if lm.Dedup {
+		return logsMetricsForward
+	}
+	return logsMetricsCount
+}
+
+// pipelines returns the pipelines the records are deduplicated in, if
+// so, then counted into the metrics sent to Prometheus.
+func (lm *LogsMetricsArgs) pipelines(prometheusExporter string) []PipelineSpec {
+	var pipelines []PipelineSpec
+	if lm.Dedup {
+		pipelines = append(pipelines, PipelineSpec{
+			Name:       "logs/metrics",
+			Receivers:  []string{logsMetricsForward},
+			Processors: []string{logDedupProcessor},
+			Exporters:  []string{logsMetricsCount},
+		})
+	}
+	// The counts are deltas, which Prometheus could not ingest
+	return append(pipelines, PipelineSpec{
+		Name:       "metrics/logs",
+		Receivers:  []string{logsMetricsCount},
+		Processors: []string{deltaToCumulativeProcessor},
+		Exporters:  []string{prometheusExporter},
+	})
+}
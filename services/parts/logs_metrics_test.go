@@ -0,0 +1,157 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_OtelCollector_LogsMetrics_Golden(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		LogsMetrics *LogsMetricsArgs
+		Golden      string
+	}{
+		"count": {
+			LogsMetrics: &LogsMetricsArgs{
+				Attributes:  []string{"service.name", "ctfer.io/challenge-id"},
+				MinSeverity: "WARN",
+			},
+			Golden: "otel-logs-metrics.golden.yaml",
+		},
+		"dedup": {
+			LogsMetrics: &LogsMetricsArgs{
+				Dedup: true,
+			},
+			Golden: "otel-logs-metrics-dedup.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				LogsMetrics: tt.LogsMetrics,
+			})
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for _, key := range []string{"processors", "connectors", "service"} {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_LogsMetrics_Pipelines(t *testing.T) {
+	t.Parallel()
+
+	// The records are counted once, from the pipeline of the logs exported
+	// to the backends, whatever the cold extract ones
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		ColdExtract: true,
+		Redaction: &RedactionArgs{
+			DeleteKeys:     []string{"password"},
+			RawColdExtract: true,
+		},
+		PrometheusOTLP: true,
+		LogsMetrics:    &LogsMetricsArgs{},
+	})
+	counted := []string{}
+	for _, p := range otelPipelines(args) {
+		if slices.Contains(p.Exporters, logsMetricsCount) {
+			counted = append(counted, p.Name)
+		}
+		if p.Name == "metrics/logs" && !slices.Equal(p.Exporters, []string{prometheusOTLPExporter}) {
+			t.Errorf("expected the logs metrics to be exported to %s, got %v", prometheusOTLPExporter, p.Exporters)
+		}
+	}
+	if !slices.Equal(counted, []string{"logs"}) {
+		t.Errorf("expected the records to be counted from the logs pipeline only, got %v", counted)
+	}
+}
+
+func Test_U_OtelCollector_LogsMetrics_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		LogsMetrics *LogsMetricsArgs
+		ExpectErr   bool
+	}{
+		"defaults": {
+			LogsMetrics: &LogsMetricsArgs{},
+		},
+		"dedup-interval": {
+			LogsMetrics: &LogsMetricsArgs{
+				Attributes:    []string{"k8s.namespace.name"},
+				MinSeverity:   "FATAL",
+				Dedup:         true,
+				DedupInterval: 30 * time.Second,
+			},
+		},
+		"invalid-attribute": {
+			LogsMetrics: &LogsMetricsArgs{
+				Attributes: []string{"service name"},
+			},
+			ExpectErr: true,
+		},
+		"duplicated-attribute": {
+			LogsMetrics: &LogsMetricsArgs{
+				Attributes: []string{"service.name", "service.name"},
+			},
+			ExpectErr: true,
+		},
+		"unsupported-severity": {
+			LogsMetrics: &LogsMetricsArgs{
+				MinSeverity: "error",
+			},
+			ExpectErr: true,
+		},
+		"negative-dedup-interval": {
+			LogsMetrics: &LogsMetricsArgs{
+				Dedup:         true,
+				DedupInterval: -time.Second,
+			},
+			ExpectErr: true,
+		},
+		"dedup-interval-without-dedup": {
+			LogsMetrics: &LogsMetricsArgs{
+				DedupInterval: time.Minute,
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:     pulumi.String("http://jaeger:4317"),
+				PrometheusURL: pulumi.String("http://prometheus:9090"),
+				LogsMetrics:   tt.LogsMetrics,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
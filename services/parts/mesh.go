@@ -0,0 +1,133 @@
+package parts
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Service meshes the pods are annotated for.
+const (
+	MeshIstio   = "istio"
+	MeshLinkerd = "linkerd"
+)
+
+// MeshArgs annotates the pods for the service mesh of the cluster, either
+// to keep them out of it (the default), or to inject its sidecars.
+type MeshArgs struct {
+	// Provider is istio or linkerd.
+	Provider string
+
+	// Inject the sidecars into the pods, their flows then being mTLS.
+	// The Jobs are kept out of the mesh anyway, as a sidecar never exits.
+	// Defaults to keep the pods out of the mesh, as the sidecars break
+	// the headless gRPC flows.
+	Inject bool
+
+	// ExcludeInboundPorts and ExcludeOutboundPorts bypass the sidecars for
+	// partial meshing, e.g. the OTLP gRPC port of the OTEL Collector.
+	// Require Inject.
+	ExcludeInboundPorts  []int
+	ExcludeOutboundPorts []int
+}
+
+func checkMesh(mesh *MeshArgs) error {
+	if mesh == nil {
+		return nil
+	}
+	if mesh.Provider != MeshIstio && mesh.Provider != MeshLinkerd {
+		return fmt.Errorf("unsupported provider %q, expected %s or %s", mesh.Provider, MeshIstio, MeshLinkerd)
+	}
+	if !mesh.Inject && (len(mesh.ExcludeInboundPorts) != 0 || len(mesh.ExcludeOutboundPorts) != 0) {
+		return errors.New("excluded ports require the sidecars injection")
+	}
+	for _, port := range slices.Concat(mesh.ExcludeInboundPorts, mesh.ExcludeOutboundPorts) {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("excluded port %d is out of the 1-65535 range", port)
+		}
+	}
+	return nil
+}
+
+// Injected returns whether the sidecars are injected into the pods.
+func (mesh *MeshArgs) Injected() bool {
+	return mesh != nil && mesh.Inject
+}
+
+// Excluded returns whether the port bypasses the sidecars, inbound or
+// outbound.
+func (mesh *MeshArgs) Excluded(port int) bool {
+	return mesh != nil && (slices.Contains(mesh.ExcludeInboundPorts, port) || slices.Contains(mesh.ExcludeOutboundPorts, port))
+}
+
+// PodAnnotations returns the annotations of the long-running pods, nil
+// without mesh.
+// See https://istio.io/latest/docs/reference/config/annotations/ and
+// https://linkerd.io/2/reference/proxy-configuration/
+func (mesh *MeshArgs) PodAnnotations() map[string]string {
+	if mesh == nil {
+		return nil
+	}
+	switch mesh.Provider {
+	case MeshIstio:
+		annotations := map[string]string{
+			"sidecar.istio.io/inject": strconv.FormatBool(mesh.Inject),
+		}
+		setPorts(annotations, "traffic.sidecar.istio.io/excludeInboundPorts", mesh.ExcludeInboundPorts)
+		setPorts(annotations, "traffic.sidecar.istio.io/excludeOutboundPorts", mesh.ExcludeOutboundPorts)
+		return annotations
+	case MeshLinkerd:
+		annotations := map[string]string{
+			"linkerd.io/inject": "disabled",
+		}
+		if mesh.Inject {
+			annotations["linkerd.io/inject"] = "enabled"
+		}
+		setPorts(annotations, "config.linkerd.io/skip-inbound-ports", mesh.ExcludeInboundPorts)
+		setPorts(annotations, "config.linkerd.io/skip-outbound-ports", mesh.ExcludeOutboundPorts)
+		return annotations
+	}
+	return nil
+}
+
+// JobAnnotations returns the annotations of the Jobs pods, always kept out
+// of the mesh for them to complete, nil without mesh.
+func (mesh *MeshArgs) JobAnnotations() map[string]string {
+	if mesh == nil {
+		return nil
+	}
+	out := *mesh
+	out.Inject, out.ExcludeInboundPorts, out.ExcludeOutboundPorts = false, nil, nil
+	return out.PodAnnotations()
+}
+
+func setPorts(annotations map[string]string, key string, ports []int) {
+	if len(ports) == 0 {
+		return
+	}
+	strs := make([]string, 0, len(ports))
+	for _, port := range ports {
+		strs = append(strs, strconv.Itoa(port))
+	}
+	annotations[key] = strings.Join(strs, ",")
+}
+
+// meshAnnotations adds the mesh annotations to the ones of a pod template,
+// nil if none.
+func meshAnnotations(base pulumi.StringMap, annotations map[string]string) pulumi.StringMapInput {
+	if len(base) == 0 && len(annotations) == 0 {
+		return nil
+	}
+	out := pulumi.StringMap{}
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range annotations {
+		out[k] = pulumi.String(v)
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package parts
+
+import (
+	"maps"
+	"testing"
+)
+
+func Test_U_CheckMesh(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mesh      *MeshArgs
+		ExpectErr bool
+	}{
+		"none": {},
+		"istio-out": {
+			Mesh: &MeshArgs{Provider: MeshIstio},
+		},
+		"linkerd-partial": {
+			Mesh: &MeshArgs{Provider: MeshLinkerd, Inject: true, ExcludeInboundPorts: []int{4317}, ExcludeOutboundPorts: []int{4317}},
+		},
+		"unknown-provider": {
+			Mesh:      &MeshArgs{Provider: "consul"},
+			ExpectErr: true,
+		},
+		"excluded-without-injection": {
+			Mesh:      &MeshArgs{Provider: MeshIstio, ExcludeInboundPorts: []int{4317}},
+			ExpectErr: true,
+		},
+		"excluded-out-of-range": {
+			Mesh:      &MeshArgs{Provider: MeshIstio, Inject: true, ExcludeOutboundPorts: []int{0}},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkMesh(tt.Mesh)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_MeshAnnotations(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Mesh        *MeshArgs
+		ExpectedPod map[string]string
+		ExpectedJob map[string]string
+	}{
+		"none": {},
+		"istio-out": {
+			Mesh:        &MeshArgs{Provider: MeshIstio},
+			ExpectedPod: map[string]string{"sidecar.istio.io/inject": "false"},
+			ExpectedJob: map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+		"istio-partial": {
+			Mesh: &MeshArgs{Provider: MeshIstio, Inject: true, ExcludeInboundPorts: []int{4317, 4318}, ExcludeOutboundPorts: []int{4317}},
+			ExpectedPod: map[string]string{
+				"sidecar.istio.io/inject":                       "true",
+				"traffic.sidecar.istio.io/excludeInboundPorts":  "4317,4318",
+				"traffic.sidecar.istio.io/excludeOutboundPorts": "4317",
+			},
+			ExpectedJob: map[string]string{"sidecar.istio.io/inject": "false"},
+		},
+		"linkerd-out": {
+			Mesh:        &MeshArgs{Provider: MeshLinkerd},
+			ExpectedPod: map[string]string{"linkerd.io/inject": "disabled"},
+			ExpectedJob: map[string]string{"linkerd.io/inject": "disabled"},
+		},
+		"linkerd-partial": {
+			Mesh: &MeshArgs{Provider: MeshLinkerd, Inject: true, ExcludeOutboundPorts: []int{9090}},
+			ExpectedPod: map[string]string{
+				"linkerd.io/inject":                     "enabled",
+				"config.linkerd.io/skip-outbound-ports": "9090",
+			},
+			ExpectedJob: map[string]string{"linkerd.io/inject": "disabled"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.Mesh.PodAnnotations(); !maps.Equal(got, tt.ExpectedPod) {
+				t.Errorf("expected pod annotations %v, got %v", tt.ExpectedPod, got)
+			}
+			if got := tt.Mesh.JobAnnotations(); !maps.Equal(got, tt.ExpectedJob) {
+				t.Errorf("expected job annotations %v, got %v", tt.ExpectedJob, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package parts
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// MetricsConversionArgs converts the metrics that would not make it into
+	// Prometheus, e.g. from SDKs defaulting to the delta temporality: the
+	// prometheusremotewrite exporter drops the delta ones, and Prometheus
+	// rejects the native histograms the exponential ones are sent as. The
+	// Kafka and cold extract metrics are left as received.
+	//
+	// Whatever the conversions, the incompatible datapoints received are
+	// counted in the ConvertedDeltaMetric and ConvertedExponentialMetric
+	// metrics, sent to Prometheus too: those not converted were dropped.
+	MetricsConversionArgs struct {
+		// DeltaToCumulative accumulates the delta temporality sums and
+		// histograms into cumulative ones.
+		DeltaToCumulative bool
+
+		// MaxStale is how long a delta stream is accumulated without a new
+		// datapoint before it is forgotten. Defaults to 5m.
+		MaxStale time.Duration
+
+		// ExponentialHistograms converts the exponential histograms into
+		// explicit bucket ones, with the Buckets upper bounds.
+		ExponentialHistograms bool
+
+		// Buckets are the upper bounds of the explicit bucket histograms the
+		// exponential ones are converted into, increasing.
+		// Defaults to the Prometheus ones.
+		Buckets []float64
+	}
+)
+
+const (
+	defaultDeltaMaxStale = 5 * time.Minute
+
+	// ConvertedDeltaMetric counts the delta temporality datapoints received,
+	// as named in Prometheus.
+	ConvertedDeltaMetric = "monitoring_incompatible_delta_datapoints_total"
+
+	// ConvertedExponentialMetric counts the exponential histograms datapoints
+	// received, as named in Prometheus.
+	ConvertedExponentialMetric = "monitoring_incompatible_exponential_histogram_datapoints_total"
+
+	deltaToCumulativeProcessor = "deltatocumulative"
+	histogramsProcessor        = "transform/histograms"
+	prometheusForward          = "forward/prometheus"
+	conversionCount            = "count/conversion"
+)
+
+// defaultHistogramBuckets are the default buckets of the Prometheus client
+// libraries.
+var defaultHistogramBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+func (mc *MetricsConversionArgs) defaults() {
+	if mc.MaxStale == 0 {
+		mc.MaxStale = defaultDeltaMaxStale
+	}
+	if len(mc.Buckets) == 0 {
+		mc.Buckets = defaultHistogramBuckets
+	}
+}
+
+// checkMetricsConversion validates the conversions before they are rendered.
+func checkMetricsConversion(mc *MetricsConversionArgs) (merr error) {
+	if mc.MaxStale < 0 {
+		merr = multierr.Append(merr, errors.New("delta max stale could not be negative"))
+	}
+	for i := 1; i < len(mc.Buckets); i++ {
+		if mc.Buckets[i] <= mc.Buckets[i-1] {
+			merr = multierr.Append(merr, errors.Errorf("histogram buckets must be increasing, got %v after %v", mc.Buckets[i], mc.Buckets[i-1]))
+			break
+		}
+	}
+	return
+}
+
+// processors returns the processors of the metrics sent to Prometheus.
+func (mc *MetricsConversionArgs) processors() []string {
+	var processors []string
+	if mc.DeltaToCumulative {
+		processors = append(processors, deltaToCumulativeProcessor)
+	}
+	if mc.ExponentialHistograms {
+		processors = append(processors, histogramsProcessor)
+	}
+	return processors
+}
+
+// bounds renders the buckets as an OTTL list.
+func (mc *MetricsConversionArgs) bounds() string {
+	bounds := make([]string, 0, len(mc.Buckets))
+	for _, b := range mc.Buckets {
+		bounds = append(bounds, strconv.FormatFloat(b, 'g', -1, 64))
+	}
+	return "[" + strings.Join(bounds, ", ") + "]"
+}
@@ -2,6 +2,7 @@ package parts
 
 import (
 	"fmt"
+	"slices"
 
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
@@ -42,10 +43,27 @@ type (
 
 		// AdditionalLabels to pass to the namespace, mostly for filtering purposes.
 		AdditionalLabels pulumi.StringMapInput
+
+		// SkipPodSecurityLabels does not set the Pod Security Admission labels,
+		// e.g. on OpenShift where they are synchronized from the SCCs.
+		SkipPodSecurityLabels bool
+
+		// PodSecurityEnforce is the enforced Pod Security Standard, e.g.
+		// privileged for the node agents mounting host paths.
+		// Defaults to baseline.
+		PodSecurityEnforce string
+
+		// Protect the namespace from being deleted, along everything in it,
+		// until unprotected.
+		Protect bool
 	}
 )
 
-const podSecurityVersion = "latest"
+const (
+	podSecurityVersion = "latest"
+
+	defaultPodSecurityEnforce = "baseline"
+)
 
 // NewNamespace creates a new [*Namespace].
 func NewNamespace(
@@ -83,6 +101,10 @@ func (ns *Namespace) defaults(args *NamespaceArgs) *NamespaceArgs {
 		args.AdditionalLabels = pulumi.StringMap{}.ToStringMapOutput()
 	}
 
+	if args.PodSecurityEnforce == "" {
+		args.PodSecurityEnforce = defaultPodSecurityEnforce
+	}
+
 	return args
 }
 
@@ -115,16 +137,19 @@ func (ns *Namespace) provision(
 			}).(pulumi.StringOutput),
 			Labels: args.AdditionalLabels.ToStringMapOutput().ApplyT(func(labels map[string]string) map[string]string {
 				// Use the additional labels as a base, add/overwrite our own labels
+				if args.SkipPodSecurityLabels {
+					return labels
+				}
 				labels["pod-security.kubernetes.io/audit"] = "restricted"
 				labels["pod-security.kubernetes.io/audit-version"] = podSecurityVersion
-				labels["pod-security.kubernetes.io/enforce"] = "baseline"
+				labels["pod-security.kubernetes.io/enforce"] = args.PodSecurityEnforce
 				labels["pod-security.kubernetes.io/enforce-version"] = podSecurityVersion
 				labels["pod-security.kubernetes.io/warn"] = "restricted"
 				labels["pod-security.kubernetes.io/warn-version"] = podSecurityVersion
 				return labels
 			}).(pulumi.StringMapOutput),
 		},
-	}, opts...)
+	}, append(slices.Clone(opts), pulumi.Protect(args.Protect))...)
 	if err != nil {
 		return
 	}
@@ -158,34 +183,7 @@ func (ns *Namespace) provision(
 				"Egress",
 			}),
 			PodSelector: metav1.LabelSelectorArgs{},
-			Egress: netwv1.NetworkPolicyEgressRuleArray{
-				netwv1.NetworkPolicyEgressRuleArgs{
-					To: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							NamespaceSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"kubernetes.io/metadata.name": pulumi.String("kube-system"),
-								},
-							},
-							PodSelector: metav1.LabelSelectorArgs{
-								MatchLabels: pulumi.StringMap{
-									"k8s-app": pulumi.String("kube-dns"),
-								},
-							},
-						},
-					},
-					Ports: netwv1.NetworkPolicyPortArray{
-						netwv1.NetworkPolicyPortArgs{
-							Port:     pulumi.Int(53),
-							Protocol: pulumi.String("UDP"),
-						},
-						netwv1.NetworkPolicyPortArgs{
-							Port:     pulumi.Int(53),
-							Protocol: pulumi.String("TCP"),
-						},
-					},
-				},
-			},
+			Egress:      EgressRules(NamespaceFlows(), "dns", nil),
 		},
 	}, opts...)
 	if err != nil {
@@ -193,8 +191,7 @@ func (ns *Namespace) provision(
 	}
 
 	// For dependencies resolution and the use of external services, grant
-	// access to internet, i.e. all IP ranges except private ones
-	// (https://en.wikipedia.org/wiki/Private_network#Private_IPv4_addresses).
+	// access to internet
 	ns.internetpol, err = netwv1.NewNetworkPolicy(ctx, "internet", &netwv1.NetworkPolicyArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: ns.ns.Metadata.Name(),
@@ -205,22 +202,7 @@ func (ns *Namespace) provision(
 			PolicyTypes: pulumi.ToStringArray([]string{
 				"Egress",
 			}),
-			Egress: netwv1.NetworkPolicyEgressRuleArray{
-				netwv1.NetworkPolicyEgressRuleArgs{
-					To: netwv1.NetworkPolicyPeerArray{
-						netwv1.NetworkPolicyPeerArgs{
-							IpBlock: netwv1.IPBlockArgs{
-								Cidr: pulumi.String("0.0.0.0/0"),
-								Except: pulumi.ToStringArray([]string{
-									"10.0.0.0/8",     // internal Kubernetes cluster IP range
-									"172.16.0.0/12",  // common internal IP range
-									"192.168.0.0/16", // common internal IP range
-								}),
-							},
-						},
-					},
-				},
-			},
+			Egress: EgressRules(NamespaceFlows(), "internet", nil),
 		},
 	}, opts...)
 	if err != nil {
@@ -239,3 +221,54 @@ func (ns *Namespace) outputs(ctx *pulumi.Context) error {
 		"labels": ns.Labels,
 	})
 }
+
+// NamespaceFlows are the flows the Namespace grants to all of its pods, i.e.
+// the DNS resolution and the internet access, all IP ranges except private
+// ones (https://en.wikipedia.org/wiki/Private_network#Private_IPv4_addresses).
+func NamespaceFlows() []NetworkFlow {
+	return []NetworkFlow{
+		{
+			Policy:    "dns",
+			Direction: FlowEgress,
+			Part:      AllPods,
+			Peers: []FlowPeer{
+				{
+					Name: "kube-dns",
+					NamespaceLabels: map[string]string{
+						"kubernetes.io/metadata.name": "kube-system",
+					},
+					PodLabels: map[string]string{
+						"k8s-app": "kube-dns",
+					},
+				},
+			},
+			Ports: []FlowPort{
+				{Port: 53, Protocol: "UDP"},
+				{Port: 53, Protocol: "TCP"},
+			},
+		},
+		{
+			Policy:    "internet",
+			Direction: FlowEgress,
+			Part:      AllPods,
+			Peers: []FlowPeer{
+				{
+					Name: "internet",
+					CIDR: "0.0.0.0/0",
+					Except: []string{
+						"10.0.0.0/8",     // internal Kubernetes cluster IP range
+						"172.16.0.0/12",  // common internal IP range
+						"192.168.0.0/16", // common internal IP range
+					},
+				},
+				{
+					Name: "internet-ipv6",
+					CIDR: "::/0",
+					Except: []string{
+						"fc00::/7", // unique local range, of the dual-stack clusters
+					},
+				},
+			},
+		},
+	}
+}
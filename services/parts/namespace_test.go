@@ -0,0 +1,82 @@
+package parts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Namespace_PodSecurityLabels(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Skip            bool
+		Enforce         string
+		Expected        bool
+		ExpectedEnforce string
+	}{
+		"default": {
+			Skip:            false,
+			Expected:        true,
+			ExpectedEnforce: "baseline",
+		},
+		"privileged": {
+			Skip:            false,
+			Enforce:         "privileged",
+			Expected:        true,
+			ExpectedEnforce: "privileged",
+		},
+		"skipped": {
+			Skip:     true,
+			Expected: false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewNamespace(ctx, "monitoring", &NamespaceArgs{
+					Name: pulumi.String("monitoring"),
+					AdditionalLabels: pulumi.StringMap{
+						"app.kubernetes.io/part-of": pulumi.String("monitoring"),
+					},
+					SkipPodSecurityLabels: tt.Skip,
+					PodSecurityEnforce:    tt.Enforce,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			nss := m.ByType("kubernetes:core/v1:Namespace")
+			if len(nss) != 1 {
+				t.Fatalf("expected 1 namespace, got %d", len(nss))
+			}
+			labels := nss[0]["metadata"].ObjectValue()["labels"].ObjectValue()
+			if _, ok := labels["app.kubernetes.io/part-of"]; !ok {
+				t.Errorf("expected the additional labels to be kept, got %v", labels)
+			}
+			psa := false
+			for k := range labels {
+				if strings.HasPrefix(string(k), "pod-security.kubernetes.io/") {
+					psa = true
+				}
+			}
+			if psa != tt.Expected {
+				t.Errorf("expected pod security labels: %t, got %v", tt.Expected, labels)
+			}
+			if tt.ExpectedEnforce != "" {
+				if enforce := labels["pod-security.kubernetes.io/enforce"].StringValue(); enforce != tt.ExpectedEnforce {
+					t.Errorf("expected enforce %s, got %s", tt.ExpectedEnforce, enforce)
+				}
+			}
+		})
+	}
+}
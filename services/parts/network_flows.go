@@ -0,0 +1,282 @@
+package parts
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type (
+	// NetworkFlow is a flow a network policy allows, i.e. one of its rules.
+	// The NetworkPolicies rules are built from the flows, and the flows
+	// rendered as a matrix documenting them, such that both stay in sync.
+	NetworkFlow struct {
+		// Policy is the name of the network policy allowing the flow.
+		Policy string
+
+		// Direction of the flow, FlowIngress or FlowEgress, relatively to
+		// the pods the policy applies to.
+		Direction string
+
+		// Part the policy applies to the pods of, e.g. prometheus, or
+		// AllPods for every pod of the namespace.
+		Part string
+
+		// Peers are the sources of an ingress flow, or the destinations of
+		// an egress one. Any if none set.
+		Peers []FlowPeer
+
+		// Ports of the flow, any if none set.
+		Ports []FlowPort
+
+		// Note completes the flow description in the matrix, e.g. the
+		// hostname of a destination the policy could not restrict to.
+		Note string
+	}
+
+	// FlowPeer is a source or destination of a NetworkFlow, either a part
+	// of the Monitoring, a selection by labels or an IP block.
+	FlowPeer struct {
+		// Part of the Monitoring, selected by its pod labels in its namespace.
+		Part string
+
+		// Name describes the peer in the matrix, e.g. kube-dns.
+		Name string
+
+		// NamespaceLabels selects the namespaces of the peer, the one of the
+		// policy if not set.
+		NamespaceLabels map[string]string
+
+		// PodLabels selects the pods of the peer, every one of the namespaces
+		// if not set.
+		PodLabels map[string]string
+
+		// CIDR is the IP block of the peer, but the Except ones.
+		CIDR   string
+		Except []string
+	}
+
+	// FlowPort is a port of a NetworkFlow, along its protocol.
+	FlowPort struct {
+		Port int
+
+		// Protocol of the port, TCP if not set.
+		Protocol string
+	}
+
+	// PartSelector selects the pods of a part, for the flows between them.
+	PartSelector struct {
+		Namespace pulumi.StringInput
+		PodLabels pulumi.StringMapInput
+	}
+)
+
+const (
+	FlowIngress = "Ingress"
+	FlowEgress  = "Egress"
+
+	// AllPods is the part standing for every pod of the namespace.
+	AllPods = "*"
+)
+
+// IngressRules returns the ingress rules of the policy, one per flow. The
+// parts peers are resolved through their selectors.
+func IngressRules(flows []NetworkFlow, policy string, selectors map[string]PartSelector) netwv1.NetworkPolicyIngressRuleArray {
+	rules := netwv1.NetworkPolicyIngressRuleArray{}
+	for _, flow := range flows {
+		if flow.Policy != policy || flow.Direction != FlowIngress {
+			continue
+		}
+		rules = append(rules, netwv1.NetworkPolicyIngressRuleArgs{
+			From:  policyPeers(flow.Peers, selectors),
+			Ports: policyPorts(flow.Ports),
+		})
+	}
+	return rules
+}
+
+// EgressRules returns the egress rules of the policy, one per flow. The
+// parts peers are resolved through their selectors.
+func EgressRules(flows []NetworkFlow, policy string, selectors map[string]PartSelector) netwv1.NetworkPolicyEgressRuleArray {
+	rules := netwv1.NetworkPolicyEgressRuleArray{}
+	for _, flow := range flows {
+		if flow.Policy != policy || flow.Direction != FlowEgress {
+			continue
+		}
+		rules = append(rules, netwv1.NetworkPolicyEgressRuleArgs{
+			To:    policyPeers(flow.Peers, selectors),
+			Ports: policyPorts(flow.Ports),
+		})
+	}
+	return rules
+}
+
+// policyPeers returns the NetworkPolicy peers, nil for any.
+func policyPeers(peers []FlowPeer, selectors map[string]PartSelector) netwv1.NetworkPolicyPeerArrayInput {
+	if len(peers) == 0 {
+		return nil
+	}
+	out := netwv1.NetworkPolicyPeerArray{}
+	for _, peer := range peers {
+		out = append(out, policyPeer(peer, selectors))
+	}
+	return out
+}
+
+func policyPeer(peer FlowPeer, selectors map[string]PartSelector) netwv1.NetworkPolicyPeerArgs {
+	if peer.Part != "" {
+		sel := selectors[peer.Part]
+		return netwv1.NetworkPolicyPeerArgs{
+			NamespaceSelector: metav1.LabelSelectorArgs{
+				MatchLabels: pulumi.StringMap{
+					"kubernetes.io/metadata.name": sel.Namespace,
+				},
+			},
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: sel.PodLabels,
+			},
+		}
+	}
+
+	if peer.CIDR != "" {
+		ipb := netwv1.IPBlockArgs{
+			Cidr: pulumi.String(peer.CIDR),
+		}
+		if len(peer.Except) != 0 {
+			ipb.Except = pulumi.ToStringArray(peer.Except)
+		}
+		return netwv1.NetworkPolicyPeerArgs{
+			IpBlock: ipb,
+		}
+	}
+
+	p := netwv1.NetworkPolicyPeerArgs{}
+	if len(peer.NamespaceLabels) != 0 {
+		p.NamespaceSelector = metav1.LabelSelectorArgs{
+			MatchLabels: pulumi.ToStringMap(peer.NamespaceLabels),
+		}
+	}
+	// Without namespace labels, the pods of the policy namespace
+	if len(peer.PodLabels) != 0 || len(peer.NamespaceLabels) == 0 {
+		p.PodSelector = metav1.LabelSelectorArgs{
+			MatchLabels: pulumi.ToStringMap(peer.PodLabels),
+		}
+	}
+	return p
+}
+
+// policyPorts returns the NetworkPolicy ports, nil for any.
+func policyPorts(ports []FlowPort) netwv1.NetworkPolicyPortArrayInput {
+	if len(ports) == 0 {
+		return nil
+	}
+	out := netwv1.NetworkPolicyPortArray{}
+	for _, port := range ports {
+		p := netwv1.NetworkPolicyPortArgs{
+			Port: pulumi.Int(port.Port),
+		}
+		if port.Protocol != "" {
+			p.Protocol = pulumi.String(port.Protocol)
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// RenderNetworkFlows renders the flows as a Markdown matrix, one row per
+// flow, in order.
+// Example: | prometheus | jaeger | 14269/TCP | jaeger-ntp (ingress) | |
+func RenderNetworkFlows(flows []NetworkFlow) string {
+	b := &strings.Builder{}
+	b.WriteString("| Source | Destination | Ports | Policy | Note |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, flow := range flows {
+		src, dst := describePart(flow.Part), describePeers(flow.Peers)
+		if flow.Direction == FlowIngress {
+			src, dst = dst, src
+		}
+		fmt.Fprintf(b, "| %s | %s | %s | %s (%s) | %s |\n",
+			src, dst, describePorts(flow.Ports), flow.Policy, strings.ToLower(flow.Direction), flow.Note)
+	}
+	b.WriteString("\nAny other flow is denied.\n")
+	return b.String()
+}
+
+func describePart(part string) string {
+	if part == AllPods {
+		return "all pods"
+	}
+	return part
+}
+
+func describePeers(peers []FlowPeer) string {
+	if len(peers) == 0 {
+		return "any"
+	}
+	out := make([]string, 0, len(peers))
+	for _, peer := range peers {
+		out = append(out, describePeer(peer))
+	}
+	return strings.Join(out, ", ")
+}
+
+// describePeer describes the peer by its part, else by its name and what
+// selects it.
+// Example: kube-dns (namespaces{kubernetes.io/metadata.name=kube-system} pods{k8s-app=kube-dns})
+func describePeer(peer FlowPeer) string {
+	if peer.Part != "" {
+		return peer.Part
+	}
+
+	sels := []string{}
+	if peer.CIDR != "" {
+		sels = append(sels, peer.CIDR)
+		if len(peer.Except) != 0 {
+			sels = append(sels, "except "+strings.Join(peer.Except, ", "))
+		}
+	}
+	if len(peer.NamespaceLabels) != 0 {
+		sels = append(sels, "namespaces"+describeLabels(peer.NamespaceLabels))
+	}
+	if len(peer.PodLabels) != 0 {
+		sels = append(sels, "pods"+describeLabels(peer.PodLabels))
+	}
+	desc := strings.Join(sels, " ")
+	switch {
+	case peer.Name != "" && desc != "":
+		return fmt.Sprintf("%s (%s)", peer.Name, desc)
+	case peer.Name != "":
+		return peer.Name
+	case desc != "":
+		return desc
+	}
+	return "all pods"
+}
+
+func describeLabels(labels map[string]string) string {
+	kvs := []string{}
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		kvs = append(kvs, k+"="+labels[k])
+	}
+	return "{" + strings.Join(kvs, ",") + "}"
+}
+
+func describePorts(ports []FlowPort) string {
+	if len(ports) == 0 {
+		return "any"
+	}
+	out := make([]string, 0, len(ports))
+	for _, port := range ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		out = append(out, fmt.Sprintf("%d/%s", port.Port, protocol))
+	}
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,70 @@
+package parts
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_U_RenderNetworkFlows(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Flow        NetworkFlow
+		ExpectedRow string
+	}{
+		"dns": {
+			Flow:        NamespaceFlows()[0],
+			ExpectedRow: "| all pods | kube-dns (namespaces{kubernetes.io/metadata.name=kube-system} pods{k8s-app=kube-dns}) | 53/UDP, 53/TCP | dns (egress) |  |",
+		},
+		"internet": {
+			Flow:        NamespaceFlows()[1],
+			ExpectedRow: "| all pods | internet (0.0.0.0/0 except 10.0.0.0/8, 172.16.0.0/12, 192.168.0.0/16), internet-ipv6 (::/0 except fc00::/7) | any | internet (egress) |  |",
+		},
+		"ingress-any": {
+			Flow: NetworkFlow{
+				Policy:    "in-otel-ntp",
+				Direction: FlowIngress,
+				Part:      "otel-collector",
+				Ports:     []FlowPort{{Port: 4317, Protocol: "TCP"}, {Port: 8125, Protocol: "UDP"}},
+			},
+			ExpectedRow: "| any | otel-collector | 4317/TCP, 8125/UDP | in-otel-ntp (ingress) |  |",
+		},
+		"ingress-peers": {
+			Flow: NetworkFlow{
+				Policy:    "in-otel-ntp",
+				Direction: FlowIngress,
+				Part:      "otel-collector",
+				Peers: []FlowPeer{
+					{NamespaceLabels: map[string]string{"b": "2", "a": "1"}},
+					{PodLabels: map[string]string{"app": "scoreboard"}},
+					{CIDR: "10.42.0.0/16"},
+				},
+				Ports: []FlowPort{{Port: 4317}},
+			},
+			ExpectedRow: "| namespaces{a=1,b=2}, pods{app=scoreboard}, 10.42.0.0/16 | otel-collector | 4317/TCP | in-otel-ntp (ingress) |  |",
+		},
+		"egress-part": {
+			Flow: NetworkFlow{
+				Policy:    "jaeger-ntp",
+				Direction: FlowEgress,
+				Part:      "jaeger",
+				Peers:     []FlowPeer{{Part: "prometheus"}},
+				Ports:     []FlowPort{{Port: 9090}},
+				Note:      "metrics",
+			},
+			ExpectedRow: "| jaeger | prometheus | 9090/TCP | jaeger-ntp (egress) | metrics |",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			matrix := RenderNetworkFlows([]NetworkFlow{tt.Flow})
+			lines := strings.Split(matrix, "\n")
+			if len(lines) < 3 || lines[2] != tt.ExpectedRow {
+				t.Errorf("expected row:\n%s\ngot:\n%s", tt.ExpectedRow, matrix)
+			}
+		})
+	}
+}
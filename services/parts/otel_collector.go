@@ -2,15 +2,23 @@ package parts
 
 import (
 	"bytes"
+	"cmp"
 	_ "embed"
 	"fmt"
+	"maps"
 	"net/url"
+	"path"
+	"slices"
 	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
 	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -21,22 +29,80 @@ type (
 	OtelCollector struct {
 		pulumi.ResourceState
 
+		// name of the component, the TLS Secrets being named after it
+		name string
+
 		cfg        *corev1.ConfigMap
+		validation *batchv1.Job
 		dep        *appsv1.Deployment
+		sts        *appsv1.StatefulSet
 		svcotel    *corev1.Service
+		svcmetrics *corev1.Service
 		signalsPvc *corev1.PersistentVolumeClaim
 
+		// Receiver TLS, issued by cert-manager
+		selfsigned *apiextensions.CustomResource
+		ca         *apiextensions.CustomResource
+		caIssuer   *apiextensions.CustomResource
+		serverCert *apiextensions.CustomResource
+
+		// Service references the Service of the receivers.
+		Service *ServiceRef
+
 		Endpoint           pulumi.StringOutput
 		ColdExtractPVCName pulumi.StringPtrOutput
 		PodLabels          pulumi.StringMapOutput
+
+		// Port is the port the OTEL Collector receives the signals on,
+		// through its Service and pods.
+		Port pulumi.IntOutput
+
+		// Ports are all the ports of the receivers, with their protocol,
+		// e.g. along the OTLP one the UDP port of the statsd receiver.
+		Ports corev1.ServicePortArrayOutput
+
+		// PodEndpoints are the endpoints of each collector pod, when scaled
+		// to more than one replica. Empty otherwise.
+		PodEndpoints pulumi.StringArrayOutput
+
+		// CASecretName is the Secret containing the CA of the receiver TLS
+		// certificates, if enabled. Sender namespaces could use it to issue
+		// their client certificates.
+		CASecretName pulumi.StringPtrOutput
+
+		// ColdExtractLayout describes where the cold extract signals land on
+		// the PVC, relatively to its root, indexed by tenant. Empty without
+		// cold extract.
+		ColdExtractLayout pulumi.StringMapOutput
+
+		// Pipelines summarizes the active pipelines of the configuration, as
+		// a JSON array of PipelineSpec.
+		Pipelines pulumi.StringOutput
+
+		// Ready resolves to true once the OTEL Collector rolled out.
+		Ready pulumi.BoolOutput
 	}
 
 	OtelCollectorArgs struct {
 		Namespace pulumi.StringInput
 
+		// MonitoringVersion is the version of the Monitoring deploying the
+		// part, labeled as ctfer.io/monitoring-version on its resources. The
+		// pods are not, for a new version not to roll them out by itself.
+		MonitoringVersion string
+
 		Registry pulumi.StringInput
 		registry pulumi.StringOutput
 
+		// Image of the OpenTelemetry Collector, pulled from the Registry.
+		// Defaults to the pinned otel/opentelemetry-collector-contrib one.
+		Image string
+
+		// Components built in the Image, checked to contain the ones the
+		// configuration requires. Defaults to the known ones of the Image,
+		// must be set for custom images.
+		Components *CollectorComponents
+
 		StorageClassName pulumi.StringInput
 		storageClassName pulumi.StringPtrOutput
 
@@ -48,23 +114,300 @@ type (
 
 		ColdExtract bool
 
+		// ProtectPVC protects the cold extract PVC from being deleted, along
+		// the signals it holds, until unprotected.
+		ProtectPVC bool
+
+		// TenantRouting routes the cold extract signals into a directory
+		// per tenant. Requires ColdExtract.
+		TenantRouting *TenantRoutingArgs
+
+		// DependencyGraph computes the service graph metrics from the traces,
+		// and sends them to Prometheus along the other metrics.
+		DependencyGraph bool
+
+		// Exemplars attaches the trace IDs to the spanmetrics histograms
+		// samples, for the dashboards to jump from a latency bucket into the
+		// traces in Jaeger. Prometheus must store them (see ExemplarStorage).
+		Exemplars bool
+
+		// MetricsConversion converts the metrics Prometheus could not ingest,
+		// rather than the prometheusremotewrite exporter dropping them.
+		MetricsConversion *MetricsConversionArgs
+
+		// LogsMetrics counts the log records into metrics sent to
+		// Prometheus, e.g. the errors per challenge.
+		LogsMetrics *LogsMetricsArgs
+
+		// TracesFailover spills the traces to the cold extract PVC while
+		// Jaeger is unavailable, rather than dropping them once the retries
+		// are exhausted. Requires ColdExtract.
+		TracesFailover bool
+
 		JaegerURL     pulumi.StringInput
 		PrometheusURL pulumi.StringInput
+
+		// PrometheusBasicAuth authenticates the metrics remote write to
+		// Prometheus, e.g. when its RemoteWriteBasicAuth is required.
+		PrometheusBasicAuth *BasicAuthArgs
+
+		// PrometheusOTLP exports the metrics to the native OTLP receiver of
+		// Prometheus through the otlphttp exporter, rather than remote writing
+		// them. Prometheus must receive them (see OTLPReceiver).
+		PrometheusOTLP bool
+
+		// PrometheusScrape exposes the metrics on the prometheus exporter,
+		// on OtelMetricsPort, for Prometheus to scrape them rather than
+		// receiving them, e.g. when running as an agent.
+		// Conflicts with PrometheusOTLP and PrometheusBasicAuth.
+		PrometheusScrape bool
+
+		// ExporterRetry tunes how the Jaeger and Prometheus exporters retry
+		// on failure, e.g. while their backends are rolling out.
+		// Zero values are defaulted.
+		ExporterRetry *ExporterRetryArgs
+
+		// BackendsDependsOn are the resources to wait for before creating the
+		// workload, e.g. the Jaeger and Prometheus awaited rollouts, for the
+		// exporters not to retry while they start.
+		BackendsDependsOn []pulumi.Resource
+
+		// ReceiverTLS serves the OTLP receiver over TLS, with certificates
+		// issued by cert-manager (must be installed in the cluster).
+		ReceiverTLS *ReceiverTLSArgs
+
+		// PlatformIDs leaves the user and group IDs to the platform, e.g. the
+		// restricted SCC of OpenShift, rather than pinning the ones of the
+		// collector image: the fsGroup the TLS Secrets are readable by, and
+		// the user creating the tenant directories.
+		PlatformIDs bool
+
+		// StatsdReceiver receives statsd metrics over UDP, e.g. from legacy
+		// hosts not speaking OTLP, into the metrics pipeline.
+		StatsdReceiver bool
+
+		// Resources of the OTEL Collector container.
+		// Defaults to none.
+		Resources corev1.ResourceRequirementsInput
+
+		// MemoryLimitPercent is the percentage of the memory limit of the
+		// Resources the Go runtime (GOMEMLIMIT) and the memory_limiter
+		// processor are bound to, for the collector to refuse data and
+		// collect its garbage before being OOM-killed. The CPU limit sets
+		// GOMAXPROCS. Only literal limits are derived from.
+		// Defaults to DefaultMemoryLimitPercent.
+		MemoryLimitPercent int
+
+		// QueueSize is the number of batches the Jaeger and Prometheus
+		// exporters queue while their backends are slow or unavailable.
+		// Defaults to the exporters ones.
+		QueueSize int
+
+		// RemoteWriteWAL buffers the metrics remote written to Prometheus in
+		// a write-ahead log on a volume, for them to survive a Prometheus
+		// restart. Does not apply to PrometheusOTLP.
+		RemoteWriteWAL *RemoteWriteWALArgs
+
+		// HeadSamplingPercent is the percentage of the traces kept, from 0
+		// to 100, as decided on their trace ID when received, through the
+		// probabilistic_sampler processor. The metrics and logs are not
+		// sampled. Unset and 100 keep them all, without the processor, while
+		// 0 drops them all.
+		HeadSamplingPercent *int
+
+		// IngestionQuotas routes the signals received by tenant, for each
+		// limited one to be refused past its share of the collector memory.
+		// Requires a memory limit in the Resources.
+		IngestionQuotas *IngestionQuotasArgs
+
+		// SyslogReceiver receives syslog messages over TCP and UDP into the
+		// logs pipeline.
+		SyslogReceiver *SyslogReceiverArgs
+
+		// Ports the receivers listen on, through the Service and pods, e.g.
+		// when the defaults clash with other agents.
+		// Zero values are defaulted.
+		Ports *OtelPortsArgs
+
+		// Kafka streams the signals into Kafka topics, along the Jaeger and
+		// Prometheus ones, e.g. for custom analytics off-cluster.
+		Kafka *KafkaExporterArgs
+
+		// Redaction scrubs the signals attributes before they are stored,
+		// independently for the Jaeger and Prometheus path and the cold
+		// extract one.
+		Redaction *RedactionArgs
+
+		// ClusterDomain renders the endpoints fully-qualified in this cluster
+		// domain (e.g. cluster.local), for clients not resolving them through
+		// the default DNS search path. Defaults to the short form.
+		ClusterDomain string
+
+		// IPFamily of the Service, for IPv6 single-stack or dual-stack
+		// clusters. Defaults to the cluster ones.
+		IPFamily *IPFamilyArgs
+
+		// Mesh annotates the pods for the service mesh of the cluster.
+		// Defaults to no annotation.
+		Mesh *MeshArgs
+
+		// ConfigHashAnnotation stamps the hash of the configuration on its
+		// ConfigMap, for the edits made in the cluster to be detected.
+		ConfigHashAnnotation bool
+
+		// ValidateConfig runs the collector validate command against the
+		// configuration in a Job before rolling it out, such that a
+		// configuration it rejects fails the update with the collector error
+		// rather than crash-looping the pods.
+		ValidateConfig bool
+
+		// Rollout stages the rollouts of the Deployment. Pausing does not
+		// apply to the StatefulSet, i.e. with more than one replica.
+		Rollout *RolloutArgs
+
+		// Replicas of the OTEL Collector pods.
+		// Defaults to 1.
+		Replicas int
+
+		// SpreadAcrossZones spreads the OTEL Collector pods across the zones, on
+		// a best-effort basis. Only applies with more than one replica.
+		SpreadAcrossZones bool
+
+		// TopologySpreadConstraints of the OTEL Collector pods, taking precedence over
+		// SpreadAcrossZones. Only applies with more than one replica.
+		TopologySpreadConstraints corev1.TopologySpreadConstraintArrayInput
+	}
+
+	// ExporterRetryArgs maps to the retry_on_failure settings of the
+	// OpenTelemetry Collector exporters.
+	ExporterRetryArgs struct {
+		InitialInterval time.Duration
+		MaxInterval     time.Duration
+		MaxElapsedTime  time.Duration
+
+		// Multiplier the interval grows by after each retry, at least 1.
+		// Defaults to 2 if unset, higher than the upstream one (1.5), for a
+		// backend unavailable while starting to be retried, hence logged,
+		// less often.
+		Multiplier *float64
+
+		// RandomizationFactor jitters the intervals, within [0, 1], for the
+		// exporters not to retry all at once. Defaults to 0.5 if unset, 0
+		// disabling the jitter.
+		RandomizationFactor *float64
+	}
+
+	// BasicAuthArgs are the basic auth credentials of an exporter.
+	BasicAuthArgs struct {
+		Username string
+
+		// PasswordSecretName is the Secret containing the password, at
+		// PasswordSecretKey, in the collector namespace.
+		PasswordSecretName pulumi.StringInput
+
+		// PasswordSecretKey defaults to BasicAuthPasswordKey.
+		PasswordSecretKey string
+	}
+
+	// ReceiverTLSArgs configures the TLS of the OTLP receiver.
+	ReceiverTLSArgs struct {
+		// RequireClientCertificate enforces mutual TLS: only the senders
+		// holding a client certificate signed by the CA could send telemetry.
+		RequireClientCertificate bool
+	}
+
+	// SyslogReceiverArgs configures the syslog receivers.
+	SyslogReceiverArgs struct {
+		// Protocol of the syslog messages, either rfc3164 or rfc5424.
+		// Defaults to rfc5424.
+		Protocol string
+	}
+
+	// OtelPortsArgs are the ports of the OTEL Collector receivers.
+	OtelPortsArgs struct {
+		// OTLP is the OTLP gRPC port. Defaults to 4317.
+		OTLP int
+
+		// Statsd is the UDP port of the statsd receiver, if turned on.
+		// Defaults to 8125.
+		Statsd int
+
+		// Syslog is the TCP and UDP port of the syslog receivers, if turned
+		// on. Defaults to 514.
+		Syslog int
+	}
+
+	// otelPort is a port the OTEL Collector receives signals on.
+	otelPort struct {
+		Name     string
+		Protocol string
+		Port     int
 	}
 )
 
 const (
 	defaultStorageSize = "50M"
 
-	otelVersion = "0.143.0"
+	// Retry defaults are more tolerant than the upstream ones (5m elapsed)
+	// as the backends could take time to be ready during a rollout.
+	defaultRetryInitialInterval = 5 * time.Second
+	defaultRetryMaxInterval     = 30 * time.Second
+	defaultRetryMaxElapsedTime  = 10 * time.Minute
+
+	defaultRetryMultiplier          = 2
+	defaultRetryRandomizationFactor = 0.5
+
+	// OtelCollectorVersion is the version of the OpenTelemetry Collector image.
+	OtelCollectorVersion = "0.143.0"
+
+	otelTLSPath = "/etc/otel-collector/tls"
+
+	// otelGID is the user and group the collector image runs as.
+	otelGID = 10001
+
+	// otelInitImage creates the tenant directories, as the collector image
+	// has no shell nor mkdir.
+	otelInitImage = "library/busybox:1.37.0"
+
+	otelPrometheusPasswordEnv = "PROMETHEUS_PASSWORD"
+
+	defaultOTLPPort   = 4317
+	defaultStatsdPort = 8125
+	defaultSyslogPort = 514
+
+	// privilegedPorts are the ports below it, which non-root processes
+	// could not bind by default.
+	privilegedPorts = 1024
+
+	defaultSyslogProtocol = "rfc5424"
+
+	headSamplingProcessor = "probabilistic_sampler"
+
+	prometheusRemoteWriteExporter = "prometheusremotewrite"
+	prometheusOTLPExporter        = "otlphttp/prometheus"
+	prometheusScrapeExporter      = "prometheus"
+
+	// OtelMetricsServiceName is the name of the Service exposing the
+	// metrics with PrometheusScrape. It is fixed such that Prometheus,
+	// deployed before the OTEL Collector, could scrape it.
+	OtelMetricsServiceName = "otel-metrics"
+
+	// OtelMetricsPort is the port the prometheus exporter serves the
+	// metrics on, with PrometheusScrape.
+	OtelMetricsPort = 8889
 )
 
+// otelSignals are the signals the OTEL Collector handles.
+var otelSignals = []string{"traces", "metrics", "logs"}
+
 //go:embed otel-config.yaml.tmpl
 var otelConfig string
 var otelTemplate *template.Template
 
 func init() {
-	tmpl, err := template.New("otel-config").Parse(otelConfig)
+	tmpl, err := template.New("otel-config").
+		Funcs(template.FuncMap{"join": strings.Join}).
+		Parse(otelConfig)
 	if err != nil {
 		panic(fmt.Errorf("invalid OTEL configuration template: %s", err))
 	}
@@ -77,7 +420,9 @@ func NewOtelCollector(
 	args *OtelCollectorArgs,
 	opts ...pulumi.ResourceOption,
 ) (*OtelCollector, error) {
-	otel := &OtelCollector{}
+	otel := &OtelCollector{
+		name: name,
+	}
 
 	args = otel.defaults(args)
 	if err := otel.check(args); err != nil {
@@ -156,17 +501,198 @@ func (*OtelCollector) defaults(args *OtelCollectorArgs) *OtelCollectorArgs {
 		}).(pulumi.StringArrayOutput)
 	}
 
+	if args.MemoryLimitPercent == 0 {
+		args.MemoryLimitPercent = DefaultMemoryLimitPercent
+	}
+
+	if args.PrometheusBasicAuth != nil && args.PrometheusBasicAuth.PasswordSecretKey == "" {
+		args.PrometheusBasicAuth.PasswordSecretKey = BasicAuthPasswordKey
+	}
+
+	// Default exporters retry settings
+	args.ExporterRetry = exporterRetryDefaults(args.ExporterRetry)
+
+	if args.Image == "" {
+		args.Image = defaultOtelImage
+	}
+
+	if args.Replicas == 0 {
+		args.Replicas = 1
+	}
+
+	if args.TenantRouting != nil && args.TenantRouting.Attribute == "" {
+		args.TenantRouting.Attribute = defaultTenantAttribute
+	}
+
+	if args.SyslogReceiver != nil && args.SyslogReceiver.Protocol == "" {
+		args.SyslogReceiver.Protocol = defaultSyslogProtocol
+	}
+
+	if args.Kafka != nil {
+		args.Kafka.defaults()
+	}
+
+	if args.MetricsConversion != nil {
+		args.MetricsConversion.defaults()
+	}
+
+	if args.LogsMetrics != nil {
+		args.LogsMetrics.defaults()
+	}
+
+	if args.IngestionQuotas != nil {
+		args.IngestionQuotas.defaults()
+	}
+
+	if args.RemoteWriteWAL != nil {
+		args.RemoteWriteWAL.defaults()
+	}
+
+	// Default receivers ports
+	args.Ports = otelPortsDefaults(args.Ports)
+
 	return args
 }
 
+// otelPortsDefaults returns a copy of the receivers ports, defaulted.
+func otelPortsDefaults(ports *OtelPortsArgs) *OtelPortsArgs {
+	cpy := OtelPortsArgs{}
+	if ports != nil {
+		cpy = *ports
+	}
+	if cpy.OTLP == 0 {
+		cpy.OTLP = defaultOTLPPort
+	}
+	if cpy.Statsd == 0 {
+		cpy.Statsd = defaultStatsdPort
+	}
+	if cpy.Syslog == 0 {
+		cpy.Syslog = defaultSyslogPort
+	}
+	return &cpy
+}
+
+// exporterRetryDefaults returns a copy of the exporters retry settings,
+// their unset values defaulted.
+func exporterRetryDefaults(retry *ExporterRetryArgs) *ExporterRetryArgs {
+	cpy := ExporterRetryArgs{}
+	if retry != nil {
+		cpy = *retry
+	}
+	if cpy.InitialInterval == 0 {
+		cpy.InitialInterval = defaultRetryInitialInterval
+	}
+	if cpy.MaxInterval == 0 {
+		cpy.MaxInterval = defaultRetryMaxInterval
+	}
+	if cpy.MaxElapsedTime == 0 {
+		cpy.MaxElapsedTime = defaultRetryMaxElapsedTime
+	}
+	if cpy.Multiplier == nil {
+		cpy.Multiplier = new(float64(defaultRetryMultiplier))
+	}
+	if cpy.RandomizationFactor == nil {
+		cpy.RandomizationFactor = new(float64(defaultRetryRandomizationFactor))
+	}
+	return &cpy
+}
+
+// checkExporterRetry validates the (defaulted) exporters retry settings.
+func checkExporterRetry(retry *ExporterRetryArgs) (merr error) {
+	if retry.InitialInterval > retry.MaxInterval {
+		merr = multierr.Append(merr, errors.New("exporter retry initial interval is greater than max interval"))
+	}
+	if *retry.Multiplier < 1 {
+		merr = multierr.Append(merr, fmt.Errorf("exporter retry multiplier %g is lower than 1", *retry.Multiplier))
+	}
+	if *retry.RandomizationFactor < 0 || *retry.RandomizationFactor > 1 {
+		merr = multierr.Append(merr, fmt.Errorf("exporter retry randomization factor %g is not within [0, 1]", *retry.RandomizationFactor))
+	}
+	return
+}
+
 func (*OtelCollector) check(args *OtelCollectorArgs) (merr error) {
 	// First-level checks
+	if args.Replicas < 0 {
+		merr = multierr.Append(merr, errors.New("replicas could not be negative"))
+	}
+	if err := checkExporterRetry(args.ExporterRetry); err != nil {
+		merr = multierr.Append(merr, err)
+	}
 	if args.JaegerURL == nil {
 		merr = multierr.Append(merr, errors.New("jaeger url is not provided"))
 	}
 	if args.PrometheusURL == nil {
 		merr = multierr.Append(merr, errors.New("prometheus url is not provided"))
 	}
+	if args.TenantRouting != nil {
+		if !args.ColdExtract {
+			merr = multierr.Append(merr, errors.New("tenant routing requires cold extract"))
+		}
+		merr = multierr.Append(merr, checkTenantRouting(args.TenantRouting))
+	}
+	if args.TracesFailover && !args.ColdExtract {
+		merr = multierr.Append(merr, errors.New("traces failover requires cold extract"))
+	}
+	if args.QueueSize < 0 {
+		merr = multierr.Append(merr, errors.New("queue size could not be negative"))
+	}
+	if p := args.HeadSamplingPercent; p != nil && (*p < 0 || *p > 100) {
+		merr = multierr.Append(merr, errors.Errorf("head sampling percent %d is out of the 0-100 range", *p))
+	}
+	if args.MemoryLimitPercent < 1 || args.MemoryLimitPercent > 100 {
+		merr = multierr.Append(merr, errors.Errorf("memory limit percent %d is out of the 1-100 range", args.MemoryLimitPercent))
+	} else if _, err := runtimeLimits(args); err != nil {
+		merr = multierr.Append(merr, err)
+	}
+	if args.SyslogReceiver != nil && !slices.Contains([]string{"rfc3164", "rfc5424"}, args.SyslogReceiver.Protocol) {
+		merr = multierr.Append(merr, errors.Errorf("unsupported syslog protocol %s, must be rfc3164 or rfc5424", args.SyslogReceiver.Protocol))
+	}
+	if args.Redaction != nil {
+		merr = multierr.Append(merr, checkRedaction(args.Redaction, args.ColdExtract))
+	}
+	if args.Kafka != nil {
+		merr = multierr.Append(merr, checkKafkaExporter(args.Kafka))
+	}
+	if args.MetricsConversion != nil {
+		merr = multierr.Append(merr, checkMetricsConversion(args.MetricsConversion))
+	}
+	if args.LogsMetrics != nil {
+		merr = multierr.Append(merr, checkLogsMetrics(args.LogsMetrics))
+	}
+	if args.IngestionQuotas != nil {
+		rl, _ := runtimeLimits(args)
+		merr = multierr.Append(merr, checkIngestionQuotas(args.IngestionQuotas, rl))
+	}
+	if args.RemoteWriteWAL != nil {
+		merr = multierr.Append(merr, checkRemoteWriteWAL(args.RemoteWriteWAL, prometheusExporter(args)))
+	}
+	if args.PrometheusScrape {
+		if args.PrometheusOTLP {
+			merr = multierr.Append(merr, errors.New("prometheus scrape conflicts with prometheus otlp, the metrics being either scraped or pushed"))
+		}
+		if args.PrometheusBasicAuth != nil {
+			merr = multierr.Append(merr, errors.New("prometheus basic auth requires the metrics to be pushed, not scraped"))
+		}
+	}
+	merr = multierr.Append(merr, checkOtelPorts(otelServedPorts(args)))
+	if args.PrometheusBasicAuth != nil {
+		if args.PrometheusBasicAuth.Username == "" {
+			merr = multierr.Append(merr, errors.New("prometheus basic auth username is not provided"))
+		}
+		if args.PrometheusBasicAuth.PasswordSecretName == nil {
+			merr = multierr.Append(merr, errors.New("prometheus basic auth password secret name is not provided"))
+		}
+	}
+	merr = multierr.Append(merr, checkClusterDomain(args.ClusterDomain))
+	if err := checkIPFamily(args.IPFamily); err != nil {
+		merr = multierr.Append(merr, errors.Wrap(err, "invalid ip family"))
+	}
+	if err := checkMesh(args.Mesh); err != nil {
+		merr = multierr.Append(merr, errors.Wrap(err, "invalid mesh"))
+	}
+	merr = multierr.Append(merr, checkCollectorComponents(args))
+	merr = multierr.Append(merr, checkRollout(args.Rollout))
 	if merr != nil {
 		return
 	}
@@ -208,28 +734,23 @@ func (otel *OtelCollector) provision(
 	args *OtelCollectorArgs,
 	opts ...pulumi.ResourceOption,
 ) (err error) {
+	data := pulumi.StringMap{
+		"config": pulumi.All(args.JaegerURL, args.PrometheusURL).ApplyT(func(all []any) (string, error) {
+			return renderOtelConfig(args, all[0].(string), all[1].(string))
+		}).(pulumi.StringOutput),
+	}
 	otel.cfg, err = corev1.NewConfigMap(ctx, "otel-config", &corev1.ConfigMapArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: args.Namespace,
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/component": pulumi.String("otel-collector"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+			Annotations: configHashAnnotations(args.ConfigHashAnnotation, data.ToStringMapOutput()),
 		},
-		Data: pulumi.StringMap{
-			"config": pulumi.All(args.JaegerURL, args.PrometheusURL).ApplyT(func(all []any) (string, error) {
-				buf := &bytes.Buffer{}
-				if err := otelTemplate.Execute(buf, map[string]any{
-					"JaegerURL":     all[0].(string),
-					"PrometheusURL": all[1].(string),
-					"ColdExtract":   args.ColdExtract,
-				}); err != nil {
-					return "", err
-				}
-				return buf.String(), nil
-			}).(pulumi.StringOutput),
-		},
+		Data:      data,
 		Immutable: pulumi.Bool(true),
 	}, opts...)
 	if err != nil {
@@ -243,6 +764,7 @@ func (otel *OtelCollector) provision(
 				Labels: pulumi.StringMap{
 					"app.kubernetes.io/component": pulumi.String("otel-collector"),
 					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 				},
 			},
@@ -255,7 +777,7 @@ func (otel *OtelCollector) provision(
 					},
 				},
 			},
-		}, opts...)
+		}, append(slices.Clone(opts), pulumi.Protect(args.ProtectPVC))...)
 		if err != nil {
 			return
 		}
@@ -300,87 +822,411 @@ func (otel *OtelCollector) provision(
 		)
 	}
 
-	otel.dep, err = appsv1.NewDeployment(ctx, "otel", &appsv1.DeploymentArgs{
+	// The TLS Secret is issued by cert-manager, the pod waits for it to mount
+	if args.ReceiverTLS != nil {
+		vmounts = append(vmounts,
+			corev1.VolumeMountArgs{
+				Name:      pulumi.String("tls"),
+				MountPath: pulumi.String(otelTLSPath),
+				ReadOnly:  pulumi.Bool(true),
+			},
+		)
+		vs = append(vs,
+			corev1.VolumeArgs{
+				Name: pulumi.String("tls"),
+				Secret: corev1.SecretVolumeSourceArgs{
+					SecretName:  pulumi.String(otel.serverTLSSecretName()),
+					DefaultMode: pulumi.Int(0440), // readable by the fsGroup only
+				},
+			},
+		)
+	}
+
+	// The WAL only has to survive Prometheus restarts, not the pod ones
+	if wal := args.RemoteWriteWAL; wal != nil {
+		vmounts = append(vmounts,
+			corev1.VolumeMountArgs{
+				Name:      pulumi.String(otelWALVolume),
+				MountPath: pulumi.String(wal.Directory),
+			},
+		)
+		vs = append(vs,
+			corev1.VolumeArgs{
+				Name: pulumi.String(otelWALVolume),
+				EmptyDir: corev1.EmptyDirVolumeSourceArgs{
+					SizeLimit: pulumi.String(wal.SizeLimit),
+				},
+			},
+		)
+	}
+
+	if args.Kafka != nil && args.Kafka.TLS != nil {
+		vmounts = append(vmounts,
+			corev1.VolumeMountArgs{
+				Name:      pulumi.String("kafka-tls"),
+				MountPath: pulumi.String(otelKafkaTLSPath),
+				ReadOnly:  pulumi.Bool(true),
+			},
+		)
+		vs = append(vs,
+			corev1.VolumeArgs{
+				Name: pulumi.String("kafka-tls"),
+				Secret: corev1.SecretVolumeSourceArgs{
+					SecretName: args.Kafka.TLS.SecretName,
+					// The client key is only readable by the collector group
+					DefaultMode: pulumi.Int(0440),
+				},
+			},
+		)
+	}
+
+	// The secret values are only referenced by the configuration
+	envs, _ := otelSecrets(args)
+	env := envs.envVars()
+	// The Go runtime is not aware of the cgroup limits
+	rl, _ := runtimeLimits(args)
+	env = append(env, rl.env()...)
+
+	// The receivers ports, the OTLP one first
+	servicePorts := corev1.ServicePortArray{}
+	containerPorts := corev1.ContainerPortArray{}
+	for _, p := range otelPorts(args) {
+		servicePorts = append(servicePorts, corev1.ServicePortArgs{
+			Name:     pulumi.String(p.Name),
+			Protocol: pulumi.String(p.Protocol),
+			Port:     pulumi.Int(p.Port),
+		})
+		containerPorts = append(containerPorts, corev1.ContainerPortArgs{
+			Name:          pulumi.String(p.Name),
+			Protocol:      pulumi.String(p.Protocol),
+			ContainerPort: pulumi.Int(p.Port),
+		})
+	}
+
+	otel.svcotel, err = corev1.NewService(ctx, "otlp-grpc", &corev1.ServiceArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("otel-collector"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Spec: corev1.ServiceSpecArgs{
+			Selector: pulumi.StringMap{
+				"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+				"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+				"app.kubernetes.io/component": pulumi.String("otel-collector"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			ClusterIP:      pulumi.String("None"), // Headless, for DNS purposes
+			IpFamilyPolicy: args.IPFamily.policy(),
+			IpFamilies:     args.IPFamily.families(),
+			Ports:          servicePorts,
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// => The metrics endpoint for Prometheus to scrape
+	if args.PrometheusScrape {
+		containerPorts = append(containerPorts, corev1.ContainerPortArgs{
+			Name:          pulumi.String("metrics"),
+			Protocol:      pulumi.String("TCP"),
+			ContainerPort: pulumi.Int(OtelMetricsPort),
+		})
+		otel.svcmetrics, err = corev1.NewService(ctx, "otel-metrics", &corev1.ServiceArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Name:      pulumi.String(OtelMetricsServiceName),
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("otel-collector"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			Spec: corev1.ServiceSpecArgs{
+				Selector: pulumi.StringMap{
+					"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+					"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+					"app.kubernetes.io/component": pulumi.String("otel-collector"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+				ClusterIP:      pulumi.String("None"), // Headless, for each pod to be scraped
+				IpFamilyPolicy: args.IPFamily.policy(),
+				IpFamilies:     args.IPFamily.families(),
+				Ports: corev1.ServicePortArray{
+					corev1.ServicePortArgs{
+						Name: pulumi.String("metrics"),
+						Port: pulumi.Int(OtelMetricsPort),
+					},
+				},
+			},
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
+	// Pod template, common to the Deployment and the StatefulSet
+	template := corev1.PodTemplateSpecArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: args.Namespace,
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/name":      pulumi.String("otel-collector"),
-				"app.kubernetes.io/version":   pulumi.String(otelVersion),
+				"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+				"app.kubernetes.io/component": pulumi.String("otel-collector"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Annotations: meshAnnotations(configChecksumAnnotations(data.ToStringMapOutput()), args.Mesh.PodAnnotations()),
+		},
+		Spec: corev1.PodSpecArgs{
+			TopologySpreadConstraints: topologySpreadConstraints(args.Replicas, args.SpreadAcrossZones, args.TopologySpreadConstraints, pulumi.StringMap{
+				"app.kubernetes.io/name":      pulumi.String("otel-collector"),
 				"app.kubernetes.io/component": pulumi.String("otel-collector"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			}),
+			Containers: corev1.ContainerArray{
+				corev1.ContainerArgs{
+					Name:  pulumi.String("otel"),
+					Image: pulumi.Sprintf("%s%s", args.registry, args.Image),
+					Args: pulumi.ToStringArray([]string{
+						"--config=/etc/otel-collector/config.yaml",
+					}),
+					Ports:        containerPorts,
+					Env:          env,
+					VolumeMounts: vmounts,
+					Resources:    containerResources(args.Resources),
+				},
 			},
+			InitContainers:  otelInitContainers(args),
+			Volumes:         vs,
+			SecurityContext: otelSecurityContext(args),
+		},
+	}
+
+	// The workload only rolls out once the configuration is validated, and
+	// the backends rolled out if requested
+	wopts := slices.Clone(opts)
+	if args.ValidateConfig {
+		if err = otel.provisionValidation(ctx, args, env, opts...); err != nil {
+			return
+		}
+		wopts = append(wopts, pulumi.DependsOn([]pulumi.Resource{otel.validation}))
+	}
+	if len(args.BackendsDependsOn) != 0 {
+		wopts = append(wopts, pulumi.DependsOn(args.BackendsDependsOn))
+	}
+
+	selector := metav1.LabelSelectorArgs{
+		MatchLabels: pulumi.StringMap{
+			"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+			"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+			"app.kubernetes.io/component": pulumi.String("otel-collector"),
+			"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+			"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 		},
-		Spec: appsv1.DeploymentSpecArgs{
-			Replicas: pulumi.Int(1),
-			Selector: metav1.LabelSelectorArgs{
-				MatchLabels: pulumi.StringMap{
+	}
+
+	if args.Replicas > 1 {
+		// Scaled collectors run as a StatefulSet, such that each pod has a
+		// stable DNS name through the headless Service (e.g. for sticky gRPC).
+		otel.sts, err = appsv1.NewStatefulSet(ctx, "otel", &appsv1.StatefulSetArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/name":      pulumi.String("otel-collector"),
+					"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
+					"app.kubernetes.io/component": pulumi.String("otel-collector"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+				},
+			},
+			Spec: appsv1.StatefulSetSpecArgs{
+				ServiceName:         otel.svcotel.Metadata.Name().Elem(),
+				Replicas:            pulumi.Int(args.Replicas),
+				PodManagementPolicy: pulumi.String("Parallel"),
+				MinReadySeconds:     rolloutMinReadySeconds(args.Rollout),
+				Selector:            selector,
+				Template:            template,
+			},
+		}, wopts...)
+		if err != nil {
+			return
+		}
+	} else {
+		otel.dep, err = appsv1.NewDeployment(ctx, "otel", &appsv1.DeploymentArgs{
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
 					"app.kubernetes.io/name":      pulumi.String("otel-collector"),
-					"app.kubernetes.io/version":   pulumi.String(otelVersion),
+					"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
 					"app.kubernetes.io/component": pulumi.String("otel-collector"),
 					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 				},
+				Annotations: rolloutAnnotations(args.Rollout),
+			},
+			Spec: appsv1.DeploymentSpecArgs{
+				Replicas:        pulumi.Int(args.Replicas),
+				Paused:          rolloutPaused(args.Rollout),
+				MinReadySeconds: rolloutMinReadySeconds(args.Rollout),
+				Selector:        selector,
+				Template:        template,
+			},
+		}, wopts...)
+		if err != nil {
+			return
+		}
+	}
+
+	if args.ReceiverTLS != nil {
+		if err = otel.provisionTLS(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// provisionValidation runs the collector validate command against the
+// configuration in a Job, awaited by the provider until it completes. The
+// Job is replaced along the immutable ConfigMap, i.e. on every change of the
+// configuration. Its pod only mounts the configuration, as the command does
+// not start the components.
+func (otel *OtelCollector) provisionValidation(
+	ctx *pulumi.Context,
+	args *OtelCollectorArgs,
+	env corev1.EnvVarArray,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	otel.validation, err = batchv1.NewJob(ctx, "otel-config-validation", &batchv1.JobArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("otel-collector"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+		},
+		Spec: batchv1.JobSpecArgs{
+			BackoffLimit: pulumi.Int(0),
 			Template: corev1.PodTemplateSpecArgs{
 				Metadata: metav1.ObjectMetaArgs{
-					Namespace: args.Namespace,
 					Labels: pulumi.StringMap{
-						"app.kubernetes.io/name":      pulumi.String("otel-collector"),
-						"app.kubernetes.io/version":   pulumi.String(otelVersion),
+						"app.kubernetes.io/name":      pulumi.String("otel-config-validation"),
+						"app.kubernetes.io/version":   pulumi.String(OtelCollectorVersion),
 						"app.kubernetes.io/component": pulumi.String("otel-collector"),
 						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 					},
+					Annotations: meshAnnotations(nil, args.Mesh.JobAnnotations()),
 				},
 				Spec: corev1.PodSpecArgs{
+					RestartPolicy: pulumi.String("Never"),
 					Containers: corev1.ContainerArray{
 						corev1.ContainerArgs{
-							Name:  pulumi.String("otel"),
-							Image: pulumi.Sprintf("%sotel/opentelemetry-collector-contrib:%s", args.registry, otelVersion),
+							Name:  pulumi.String("validate"),
+							Image: pulumi.Sprintf("%s%s", args.registry, args.Image),
 							Args: pulumi.ToStringArray([]string{
+								"validate",
 								"--config=/etc/otel-collector/config.yaml",
 							}),
-							Ports: corev1.ContainerPortArray{
-								corev1.ContainerPortArgs{
-									Name:          pulumi.String("otlp-grpc"),
-									ContainerPort: pulumi.Int(4317),
+							Env: env,
+							VolumeMounts: corev1.VolumeMountArray{
+								corev1.VolumeMountArgs{
+									Name:      pulumi.String("config-volume"),
+									MountPath: pulumi.String("/etc/otel-collector"),
+									ReadOnly:  pulumi.Bool(true),
+								},
+							},
+							// Surface the collector error in the Job failure
+							TerminationMessagePolicy: pulumi.String("FallbackToLogsOnError"),
+						},
+					},
+					Volumes: corev1.VolumeArray{
+						corev1.VolumeArgs{
+							Name: pulumi.String("config-volume"),
+							ConfigMap: corev1.ConfigMapVolumeSourceArgs{
+								Name:        otel.cfg.Metadata.Name(),
+								DefaultMode: pulumi.Int(0644),
+								Items: corev1.KeyToPathArray{
+									corev1.KeyToPathArgs{
+										Key:  pulumi.String("config"),
+										Path: pulumi.String("config.yaml"),
+									},
 								},
 							},
-							VolumeMounts: vmounts,
 						},
 					},
-					Volumes: vs,
+					SecurityContext: otelSecurityContext(args),
 				},
 			},
 		},
 	}, opts...)
+	return
+}
+
+// provisionTLS issues the OTLP receiver certificates through cert-manager:
+// a self-signed CA, and a server certificate signed by it.
+func (otel *OtelCollector) provisionTLS(
+	ctx *pulumi.Context,
+	args *OtelCollectorArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	labels := pulumi.StringMap{
+		"app.kubernetes.io/component": pulumi.String("otel-collector"),
+		"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+		"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+		"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+	}
+
+	otel.selfsigned, err = apiextensions.NewCustomResource(ctx, "otel-selfsigned", &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("cert-manager.io/v1"),
+		Kind:       pulumi.String("Issuer"),
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels:    labels,
+		},
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": pulumi.Map{
+				"selfSigned": pulumi.Map{},
+			},
+		},
+	}, opts...)
 	if err != nil {
 		return
 	}
 
-	otel.svcotel, err = corev1.NewService(ctx, "otlp-grpc", &corev1.ServiceArgs{
+	otel.ca, err = apiextensions.NewCustomResource(ctx, "otel-ca", &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("cert-manager.io/v1"),
+		Kind:       pulumi.String("Certificate"),
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: args.Namespace,
-			Labels: pulumi.StringMap{
-				"app.kubernetes.io/component": pulumi.String("otel-collector"),
-				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
-				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
-			},
+			Labels:    labels,
 		},
-		Spec: corev1.ServiceSpecArgs{
-			Selector: pulumi.StringMap{
-				"app.kubernetes.io/name":      pulumi.String("otel-collector"),
-				"app.kubernetes.io/version":   pulumi.String(otelVersion),
-				"app.kubernetes.io/component": pulumi.String("otel-collector"),
-				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
-				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
-			},
-			ClusterIP: pulumi.String("None"), // Headless, for DNS purposes
-			Ports: corev1.ServicePortArray{
-				corev1.ServicePortArgs{
-					Name: pulumi.String("otlp-grpc"),
-					Port: pulumi.Int(4317),
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": pulumi.Map{
+				"isCA":       pulumi.Bool(true),
+				"commonName": pulumi.String("monitoring-otel-ca"),
+				"secretName": pulumi.String(otel.caSecretName()),
+				"privateKey": pulumi.Map{
+					"algorithm": pulumi.String("ECDSA"),
+					"size":      pulumi.Int(256),
+				},
+				"issuerRef": pulumi.Map{
+					"kind": pulumi.String("Issuer"),
+					"name": otel.selfsigned.Metadata.Name().Elem(),
 				},
 			},
 		},
@@ -389,28 +1235,484 @@ func (otel *OtelCollector) provision(
 		return
 	}
 
+	otel.caIssuer, err = apiextensions.NewCustomResource(ctx, "otel-ca-issuer", &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("cert-manager.io/v1"),
+		Kind:       pulumi.String("Issuer"),
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels:    labels,
+		},
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": pulumi.Map{
+				"ca": pulumi.Map{
+					"secretName": pulumi.String(otel.caSecretName()),
+				},
+			},
+		},
+	}, append(slices.Clone(opts), pulumi.DependsOn([]pulumi.Resource{otel.ca}))...)
+	if err != nil {
+		return
+	}
+
+	// The server certificate is valid for the headless Service DNS names
+	otel.serverCert, err = apiextensions.NewCustomResource(ctx, "otel-server", &apiextensions.CustomResourceArgs{
+		ApiVersion: pulumi.String("cert-manager.io/v1"),
+		Kind:       pulumi.String("Certificate"),
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels:    labels,
+		},
+		OtherFields: kubernetes.UntypedArgs{
+			"spec": pulumi.Map{
+				"secretName": pulumi.String(otel.serverTLSSecretName()),
+				"dnsNames": pulumi.All(otel.svcotel.Metadata.Name().Elem(), args.Namespace).ApplyT(func(all []any) []string {
+					return otelDNSNames(all[0].(string), all[1].(string), args.ClusterDomain)
+				}).(pulumi.StringArrayOutput),
+				"usages": pulumi.ToStringArray([]string{
+					"server auth",
+				}),
+				"issuerRef": pulumi.Map{
+					"kind": pulumi.String("Issuer"),
+					"name": otel.caIssuer.Metadata.Name().Elem(),
+				},
+			},
+		},
+	}, opts...)
+	return
+}
+
+// otelPorts returns the ports of the enabled receivers, the OTLP one first.
+func otelPorts(args *OtelCollectorArgs) []otelPort {
+	ports := []otelPort{
+		{Name: "otlp-grpc", Protocol: "TCP", Port: args.Ports.OTLP},
+	}
+	if args.StatsdReceiver {
+		ports = append(ports, otelPort{Name: "statsd", Protocol: "UDP", Port: args.Ports.Statsd})
+	}
+	if args.SyslogReceiver != nil {
+		ports = append(ports,
+			otelPort{Name: "syslog-tcp", Protocol: "TCP", Port: args.Ports.Syslog},
+			otelPort{Name: "syslog-udp", Protocol: "UDP", Port: args.Ports.Syslog},
+		)
+	}
+	return ports
+}
+
+// OtelScrapeTarget is the Prometheus scrape target of the metrics the OTEL
+// Collector exposes with PrometheusScrape. The Service being headless, every
+// replica is discovered through its DNS records.
+func OtelScrapeTarget() ScrapeTarget {
+	return ScrapeTarget{
+		JobName:  "otel-collector",
+		Service:  OtelMetricsServiceName,
+		Port:     OtelMetricsPort,
+		Headless: true,
+	}
+}
+
+// otelServedPorts returns the receivers ports, then the metrics one when
+// scraped by Prometheus.
+func otelServedPorts(args *OtelCollectorArgs) []otelPort {
+	ports := otelPorts(args)
+	if args.PrometheusScrape {
+		ports = append(ports, otelPort{Name: "metrics", Protocol: "TCP", Port: OtelMetricsPort})
+	}
+	return ports
+}
+
+// headSamplingPercent returns the percentage of the traces the head sampling
+// keeps, nil if it keeps them all.
+func headSamplingPercent(args *OtelCollectorArgs) *int {
+	if args.HeadSamplingPercent == nil || *args.HeadSamplingPercent >= 100 {
+		return nil
+	}
+	return args.HeadSamplingPercent
+}
+
+// OtelReceiverPorts returns the ports the OTEL Collector of the arguments
+// receives the signals on, along their protocol, the OTLP one first. The
+// arguments are not modified.
+func OtelReceiverPorts(args *OtelCollectorArgs) []FlowPort {
+	cpy := OtelCollectorArgs{}
+	if args != nil {
+		cpy = *args
+	}
+	cpy.Ports = otelPortsDefaults(cpy.Ports)
+
+	ports := []FlowPort{}
+	for _, p := range otelPorts(&cpy) {
+		ports = append(ports, FlowPort{Port: p.Port, Protocol: p.Protocol})
+	}
+	return ports
+}
+
+// checkOtelPorts validates the receivers ports are valid, and do not collide
+// for a given protocol.
+func checkOtelPorts(ports []otelPort) (merr error) {
+	bound := map[string]string{}
+	for _, p := range ports {
+		if p.Port < 1 || p.Port > 65535 {
+			merr = multierr.Append(merr, errors.Errorf("%s port %d is out of the 1-65535 range", p.Name, p.Port))
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", p.Port, p.Protocol)
+		if other, ok := bound[key]; ok {
+			merr = multierr.Append(merr, errors.Errorf("%s port collides with the %s one on %s", p.Name, other, key))
+			continue
+		}
+		bound[key] = p.Name
+	}
 	return
 }
 
+// otelSecurityContext returns the pod security context of the collector.
+// The syslog port is privileged by default while the collector runs as
+// non-root, so it is allowed to bind it through the (namespaced, safe)
+// sysctl, as the headless Service could not remap it. The receiver and Kafka
+// TLS Secrets are only readable by the group of the collector, pinned unless
+// PlatformIDs.
+func otelSecurityContext(args *OtelCollectorArgs) corev1.PodSecurityContextPtrInput {
+	sc := corev1.PodSecurityContextArgs{}
+	set := false
+	if args.SyslogReceiver != nil && args.Ports.Syslog < privilegedPorts {
+		sc.Sysctls = corev1.SysctlArray{
+			corev1.SysctlArgs{
+				Name:  pulumi.String("net.ipv4.ip_unprivileged_port_start"),
+				Value: pulumi.Sprintf("%d", args.Ports.Syslog),
+			},
+		}
+		set = true
+	}
+	tls := args.ReceiverTLS != nil || (args.Kafka != nil && args.Kafka.TLS != nil)
+	if tls && !args.PlatformIDs {
+		sc.FsGroup = pulumi.Int(otelGID)
+		sc.FsGroupChangePolicy = pulumi.String("OnRootMismatch")
+		set = true
+	}
+	if !set {
+		return nil
+	}
+	return sc
+}
+
+// otelInitContainers returns the init containers of the collector: with
+// tenant routing, one creating the tenant directories on the PVC, as the
+// file exporters do not create the directories of their path.
+func otelInitContainers(args *OtelCollectorArgs) corev1.ContainerArrayInput {
+	if !args.ColdExtract || args.TenantRouting == nil {
+		return nil
+	}
+
+	layout := ColdExtractLayout(args.TenantRouting)
+	cmd := []string{"mkdir", "-p"}
+	for _, tenant := range slices.Sorted(maps.Keys(layout)) {
+		cmd = append(cmd, path.Join(coldExtractMountPath, layout[tenant]))
+	}
+	sc := corev1.SecurityContextArgs{
+		AllowPrivilegeEscalation: pulumi.Bool(false),
+		ReadOnlyRootFilesystem:   pulumi.Bool(true),
+		RunAsNonRoot:             pulumi.Bool(true),
+		Capabilities: corev1.CapabilitiesArgs{
+			Drop: pulumi.ToStringArray([]string{"ALL"}),
+		},
+		SeccompProfile: corev1.SeccompProfileArgs{
+			Type: pulumi.String("RuntimeDefault"),
+		},
+	}
+	// The directories are owned by the user the collector runs as
+	if !args.PlatformIDs {
+		sc.RunAsUser = pulumi.Int(otelGID)
+		sc.RunAsGroup = pulumi.Int(otelGID)
+	}
+	return corev1.ContainerArray{
+		corev1.ContainerArgs{
+			Name:    pulumi.String("tenant-dirs"),
+			Image:   pulumi.Sprintf("%s%s", args.registry, otelInitImage),
+			Command: pulumi.ToStringArray(cmd),
+			VolumeMounts: corev1.VolumeMountArray{
+				corev1.VolumeMountArgs{
+					Name:      pulumi.String("signals"),
+					MountPath: pulumi.String("/data/collector"),
+				},
+			},
+			SecurityContext: sc,
+		},
+	}
+}
+
+// caSecretName is the name of the Secret of the receiver CA, after the
+// component one.
+func (otel *OtelCollector) caSecretName() string {
+	return otel.name + "-ca"
+}
+
+// serverTLSSecretName is the name of the Secret of the receiver server
+// certificate, after the component one.
+func (otel *OtelCollector) serverTLSSecretName() string {
+	return otel.name + "-server-tls"
+}
+
+// otelDNSNames returns the DNS names of the OTLP receiver Service, and of its
+// pods when scaled.
+func otelDNSNames(svc, namespace, clusterDomain string) []string {
+	clusterDomain = cmp.Or(clusterDomain, defaultClusterDomain)
+	return []string{
+		svc + "." + namespace,
+		svc + "." + namespace + ".svc",
+		svc + "." + namespace + ".svc." + clusterDomain,
+		"*." + svc + "." + namespace,
+		"*." + svc + "." + namespace + ".svc",
+		"*." + svc + "." + namespace + ".svc." + clusterDomain,
+	}
+}
+
+// podEndpoints returns the endpoint of each StatefulSet pod, resolved
+// through the headless Service, fully-qualified if a cluster domain is set.
+// Example: otel-0.otlp-grpc.monitoring:4317
+func podEndpoints(sts, svc, namespace, clusterDomain string, port, replicas int) []string {
+	host := svc + "." + namespace
+	if clusterDomain != "" {
+		host += ".svc." + clusterDomain
+	}
+	edps := make([]string, 0, replicas)
+	for i := range replicas {
+		edps = append(edps, hostPort(fmt.Sprintf("%s-%d.%s", sts, i, host), port))
+	}
+	return edps
+}
+
 func (otel *OtelCollector) outputs(ctx *pulumi.Context, args *OtelCollectorArgs) error {
-	otel.Endpoint = pulumi.Sprintf(
-		"%s.%s:%d",
-		otel.svcotel.Metadata.Name().Elem(),
-		otel.svcotel.Metadata.Namespace().Elem(),
-		otel.svcotel.Spec.Ports().Index(pulumi.Int(0)).Port(),
-	)
+	otel.Service = NewServiceRef(otel.svcotel)
+	otel.Ports = otel.svcotel.Spec.Ports()
+	otel.Port = otel.Service.Port
+	otel.Endpoint = otel.Service.Endpoint(args.ClusterDomain)
 	if args.ColdExtract {
 		otel.ColdExtractPVCName = otel.signalsPvc.Metadata.Name()
 	}
-	otel.PodLabels = otel.dep.Spec.Template().Metadata().Labels()
+	if otel.sts != nil {
+		otel.PodLabels = otel.sts.Spec.Template().Metadata().Labels()
+		otel.Ready = rolledOut(otel.sts.Spec.Replicas(), otel.sts.Status.ReadyReplicas())
+		otel.PodEndpoints = pulumi.All(
+			otel.sts.Metadata.Name().Elem(),
+			otel.Service.Name,
+			otel.Service.Namespace,
+			otel.Service.Port,
+		).ApplyT(func(all []any) []string {
+			return podEndpoints(all[0].(string), all[1].(string), all[2].(string), args.ClusterDomain, all[3].(int), args.Replicas)
+		}).(pulumi.StringArrayOutput)
+	} else {
+		otel.PodLabels = otel.dep.Spec.Template().Metadata().Labels()
+		otel.Ready = rolledOut(otel.dep.Spec.Replicas(), otel.dep.Status.ReadyReplicas())
+		otel.PodEndpoints = pulumi.StringArray{}.ToStringArrayOutput()
+	}
+	if args.ReceiverTLS != nil {
+		otel.CASecretName = pulumi.StringPtr(otel.caSecretName()).ToStringPtrOutput()
+	}
+	otel.ColdExtractLayout = pulumi.StringMap{}.ToStringMapOutput()
+	if args.ColdExtract {
+		otel.ColdExtractLayout = pulumi.ToStringMap(ColdExtractLayout(args.TenantRouting)).ToStringMapOutput()
+	}
+
+	otel.Pipelines = pulumi.String(pipelinesSummary(otelPipelines(args))).ToStringOutput()
 
 	return ctx.RegisterResourceOutputs(otel, pulumi.Map{
 		"endpoint":           otel.Endpoint,
+		"port":               otel.Port,
+		"ports":              otel.Ports,
 		"coldExtractPVCName": otel.ColdExtractPVCName,
 		"podLabels":          otel.PodLabels,
+		"podEndpoints":       otel.PodEndpoints,
+		"caSecretName":       otel.CASecretName,
+		"coldExtractLayout":  otel.ColdExtractLayout,
+		"pipelines":          otel.Pipelines,
+		"ready":              otel.Ready,
 	})
 }
 
+// renderOtelConfig renders the OpenTelemetry Collector configuration of
+// the given (defaulted) arguments.
+func renderOtelConfig(args *OtelCollectorArgs, jaegerURL, prometheusURL string) (string, error) {
+	var routes []string
+	if args.TenantRouting != nil {
+		routes = args.TenantRouting.routes()
+	}
+	var redaction []string
+	if args.Redaction != nil {
+		redaction = args.Redaction.statements()
+	}
+	var bounds string
+	maxStale := defaultDeltaMaxStale
+	if args.MetricsConversion != nil {
+		bounds = args.MetricsConversion.bounds()
+		maxStale = args.MetricsConversion.MaxStale
+	}
+
+	_, secrets := otelSecrets(args)
+	var memoryLimiter *otelRuntimeLimits
+	rl, _ := runtimeLimits(args)
+	if rl.MemoryLimitMiB != 0 {
+		memoryLimiter = &rl
+	}
+	var quotas map[string]any
+	if iq := args.IngestionQuotas; iq != nil {
+		quotas = map[string]any{
+			"Attribute": iq.Attribute,
+			"Limiters":  iq.limiters(rl),
+			"Counts":    []string{"received", "accepted"},
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := otelTemplate.Execute(buf, map[string]any{
+		"JaegerURL":       jaegerURL,
+		"PrometheusURL":   prometheusURL,
+		"ColdExtract":     args.ColdExtract,
+		"DependencyGraph": args.DependencyGraph,
+		"Exemplars":       args.Exemplars,
+		"TracesFailover":  args.TracesFailover,
+		"QueueSize":       args.QueueSize,
+		"WAL":             args.RemoteWriteWAL,
+		"HeadSampling":    headSamplingPercent(args),
+		"Quotas":          quotas,
+		"MemoryLimiter":   memoryLimiter,
+		"Routing":         args.TenantRouting,
+		"Routes":          routes,
+		"Signals":         otelSignals,
+		"Pipelines":       otelPipelines(args),
+		"Redaction":       redaction,
+		"RedactionCtxs":   redactionContexts,
+		"Retry":           args.ExporterRetry,
+		"TLS":             args.ReceiverTLS,
+		"TLSPath":         otelTLSPath,
+		"OTLPPort":        args.Ports.OTLP,
+		"Statsd":          args.StatsdReceiver,
+		"StatsdPort":      args.Ports.Statsd,
+		"Syslog":          args.SyslogReceiver,
+		"SyslogPort":      args.Ports.Syslog,
+		"BasicAuth":       args.PrometheusBasicAuth,
+		"PrometheusOTLP":  args.PrometheusOTLP,
+		"Scrape":          args.PrometheusScrape,
+		"MetricsPort":     OtelMetricsPort,
+		"Secrets":         secrets,
+		"Kafka":           args.Kafka,
+		"KafkaTLSPath":    otelKafkaTLSPath,
+		"Conversion":      args.MetricsConversion,
+		"LogsMetrics":     args.LogsMetrics,
+		"HistogramBounds": bounds,
+		"DeltaMaxStale":   maxStale,
+	}); err != nil {
+		return "", err
+	}
+	return normalizeDocument(buf.String()), nil
+}
+
+// otelSecretRefs are the placeholders of the secret values the OpenTelemetry
+// Collector configuration embeds, empty when not used.
+type otelSecretRefs struct {
+	PrometheusPassword string
+	KafkaPassword      string
+}
+
+// otelSecrets declares the secret-backed environment variables of the
+// OpenTelemetry Collector, and returns the placeholders of their values.
+// Secret values must go through it rather than being inlined in the
+// configuration, which ends up in a ConfigMap.
+func otelSecrets(args *OtelCollectorArgs) (secretEnvs, otelSecretRefs) {
+	envs := secretEnvs{}
+	refs := otelSecretRefs{}
+	if ba := args.PrometheusBasicAuth; ba != nil {
+		refs.PrometheusPassword = envs.add(otelPrometheusPasswordEnv, ba.PasswordSecretName, ba.PasswordSecretKey)
+	}
+	if args.Kafka != nil && args.Kafka.SASL != nil {
+		sasl := args.Kafka.SASL
+		refs.KafkaPassword = envs.add(otelKafkaPasswordEnv, sasl.PasswordSecretName, sasl.PasswordSecretKey)
+	}
+	return envs, refs
+}
+
+// RenderOtelConfig renders the OpenTelemetry Collector configuration of the
+// arguments offline, i.e. without the Pulumi engine, with the given Jaeger
+// and Prometheus URLs in place of the JaegerURL and PrometheusURL ones.
+// The arguments are checked as when deploying, but are not modified.
+func RenderOtelConfig(args *OtelCollectorArgs, jaegerURL, prometheusURL string) (string, error) {
+	otel := &OtelCollector{}
+	cpy := OtelCollectorArgs{}
+	if args != nil {
+		cpy = *args
+	}
+	if cpy.ExporterRetry != nil {
+		retry := *cpy.ExporterRetry
+		cpy.ExporterRetry = &retry
+	}
+	if cpy.TenantRouting != nil {
+		tr := *cpy.TenantRouting
+		cpy.TenantRouting = &tr
+	}
+	if cpy.SyslogReceiver != nil {
+		sr := *cpy.SyslogReceiver
+		cpy.SyslogReceiver = &sr
+	}
+	if cpy.Redaction != nil {
+		r := *cpy.Redaction
+		cpy.Redaction = &r
+	}
+	if cpy.Ports != nil {
+		ports := *cpy.Ports
+		cpy.Ports = &ports
+	}
+	if cpy.PrometheusBasicAuth != nil && cpy.PrometheusBasicAuth.PasswordSecretName == nil {
+		// Not rendered, the password is only referenced
+		ba := *cpy.PrometheusBasicAuth
+		ba.PasswordSecretName = pulumi.String("")
+		cpy.PrometheusBasicAuth = &ba
+	}
+	if cpy.Kafka != nil {
+		// Not rendered, the Secrets are only referenced
+		k := *cpy.Kafka
+		if k.TLS != nil {
+			tls := *k.TLS
+			if tls.SecretName == nil {
+				tls.SecretName = pulumi.String("")
+			}
+			k.TLS = &tls
+		}
+		if k.SASL != nil {
+			sasl := *k.SASL
+			if sasl.PasswordSecretName == nil {
+				sasl.PasswordSecretName = pulumi.String("")
+			}
+			k.SASL = &sasl
+		}
+		cpy.Kafka = &k
+	}
+	if cpy.MetricsConversion != nil {
+		mc := *cpy.MetricsConversion
+		cpy.MetricsConversion = &mc
+	}
+	if cpy.LogsMetrics != nil {
+		lm := *cpy.LogsMetrics
+		lm.Attributes = slices.Clone(lm.Attributes)
+		cpy.LogsMetrics = &lm
+	}
+	if cpy.IngestionQuotas != nil {
+		iq := *cpy.IngestionQuotas
+		iq.Quotas = slices.Clone(iq.Quotas)
+		cpy.IngestionQuotas = &iq
+	}
+	if cpy.RemoteWriteWAL != nil {
+		wal := *cpy.RemoteWriteWAL
+		cpy.RemoteWriteWAL = &wal
+	}
+	cpy.JaegerURL = pulumi.String(jaegerURL)
+	cpy.PrometheusURL = pulumi.String(prometheusURL)
+
+	args = otel.defaults(&cpy)
+	if err := otel.check(args); err != nil {
+		return "", err
+	}
+	return renderOtelConfig(args, jaegerURL, prometheusURL)
+}
+
 func checkValidURL(u string) error {
 	_, err := url.Parse(u)
 	return err
@@ -0,0 +1,1642 @@
+package parts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_OtelCollector_Retry(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Retry    *ExporterRetryArgs
+		Expected map[string]any
+	}{
+		"defaults": {
+			Retry: nil,
+			Expected: map[string]any{
+				"enabled":              true,
+				"initial_interval":     "5s",
+				"max_interval":         "30s",
+				"max_elapsed_time":     "10m0s",
+				"multiplier":           2,
+				"randomization_factor": 0.5,
+			},
+		},
+		"partial": {
+			Retry: &ExporterRetryArgs{
+				MaxElapsedTime: time.Hour,
+			},
+			Expected: map[string]any{
+				"enabled":              true,
+				"initial_interval":     "5s",
+				"max_interval":         "30s",
+				"max_elapsed_time":     "1h0m0s",
+				"multiplier":           2,
+				"randomization_factor": 0.5,
+			},
+		},
+		"startup-tuned": {
+			Retry: &ExporterRetryArgs{
+				InitialInterval:     10 * time.Second,
+				MaxInterval:         time.Minute,
+				Multiplier:          new(3.0),
+				RandomizationFactor: new(0.2),
+			},
+			Expected: map[string]any{
+				"enabled":              true,
+				"initial_interval":     "10s",
+				"max_interval":         "1m0s",
+				"max_elapsed_time":     "10m0s",
+				"multiplier":           3,
+				"randomization_factor": 0.2,
+			},
+		},
+		"no-jitter": {
+			Retry: &ExporterRetryArgs{
+				RandomizationFactor: new(0.0),
+			},
+			Expected: map[string]any{
+				"multiplier":           2,
+				"randomization_factor": 0,
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			var before ExporterRetryArgs
+			if tt.Retry != nil {
+				before = *tt.Retry
+			}
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				ExporterRetry: tt.Retry,
+			})
+			if tt.Retry != nil && *tt.Retry != before {
+				t.Errorf("expected the exporter retry not to be defaulted in place, got %+v", *tt.Retry)
+			}
+			cfg := renderOtelConfigT(t, args)
+
+			exporters := cfg["exporters"].(map[string]any)
+			for _, exp := range []string{"otlp", "prometheusremotewrite"} {
+				retry := exporters[exp].(map[string]any)["retry_on_failure"].(map[string]any)
+				for k, v := range tt.Expected {
+					if retry[k] != v {
+						t.Errorf("exporter %s: expected %s to be %v, got %v", exp, k, v, retry[k])
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_U_CheckExporterRetry(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Retry     *ExporterRetryArgs
+		ExpectErr bool
+	}{
+		"defaults": {},
+		"no-jitter-upper-bound": {
+			Retry: &ExporterRetryArgs{RandomizationFactor: new(1.0)},
+		},
+		"no-jitter": {
+			Retry: &ExporterRetryArgs{RandomizationFactor: new(0.0)},
+		},
+		"initial-above-max": {
+			Retry:     &ExporterRetryArgs{InitialInterval: time.Minute, MaxInterval: time.Second},
+			ExpectErr: true,
+		},
+		"shrinking": {
+			Retry:     &ExporterRetryArgs{Multiplier: new(0.5)},
+			ExpectErr: true,
+		},
+		"multiplier-zero": {
+			Retry:     &ExporterRetryArgs{Multiplier: new(0.0)},
+			ExpectErr: true,
+		},
+		"randomization-above-1": {
+			Retry:     &ExporterRetryArgs{RandomizationFactor: new(1.5)},
+			ExpectErr: true,
+		},
+		"randomization-negative": {
+			Retry:     &ExporterRetryArgs{RandomizationFactor: new(-0.1)},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkExporterRetry(exporterRetryDefaults(tt.Retry))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_ReceiverTLS(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		TLS    *ReceiverTLSArgs
+		Golden string
+	}{
+		"plain": {
+			TLS:    nil,
+			Golden: "otel-receivers-plain.golden.yaml",
+		},
+		"tls": {
+			TLS:    &ReceiverTLSArgs{},
+			Golden: "otel-receivers-tls.golden.yaml",
+		},
+		"mtls": {
+			TLS: &ReceiverTLSArgs{
+				RequireClientCertificate: true,
+			},
+			Golden: "otel-receivers-mtls.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				ReceiverTLS: tt.TLS,
+			})
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			if !reflect.DeepEqual(cfg["receivers"], expected) {
+				t.Fatalf("expected receivers %v, got %v", expected, cfg["receivers"])
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_ReceiverTLS_Secrets(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		PlatformIDs     bool
+		ExpectedFsGroup bool
+	}{
+		"pinned": {
+			ExpectedFsGroup: true,
+		},
+		"platform": {
+			PlatformIDs:     true,
+			ExpectedFsGroup: false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewOtelCollector(ctx, "collector", &OtelCollectorArgs{
+					Namespace:     pulumi.String("monitoring"),
+					JaegerURL:     pulumi.String("http://jaeger:4317"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					ReceiverTLS:   &ReceiverTLSArgs{},
+					PlatformIDs:   tt.PlatformIDs,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// The Secrets are named after the component
+			for cert, secret := range map[string]string{
+				"otel-ca":     "collector-ca",
+				"otel-server": "collector-server-tls",
+			} {
+				res := m.ByName("kubernetes:cert-manager.io/v1:Certificate", cert)
+				if res == nil {
+					t.Fatalf("certificate %s not found", cert)
+				}
+				if got := res["spec"].ObjectValue()["secretName"].StringValue(); got != secret {
+					t.Errorf("expected certificate %s in secret %s, got %s", cert, secret, got)
+				}
+			}
+
+			// The server key is only readable by the group of the collector
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "otel")
+			spec := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			mounted := false
+			for _, vol := range spec["volumes"].ArrayValue() {
+				if vol.ObjectValue()["name"].StringValue() != "tls" {
+					continue
+				}
+				mounted = true
+				sec := vol.ObjectValue()["secret"].ObjectValue()
+				if name := sec["secretName"].StringValue(); name != "collector-server-tls" {
+					t.Errorf("expected the collector-server-tls secret to be mounted, got %s", name)
+				}
+				if mode := sec["defaultMode"].NumberValue(); mode != 0440 {
+					t.Errorf("expected mode 0440, got %#o", int(mode))
+				}
+			}
+			if !mounted {
+				t.Error("expected the tls volume to be mounted")
+			}
+			sc, ok := spec["securityContext"]
+			fsGroup := ok && sc.IsObject() && sc.ObjectValue()["fsGroup"].IsNumber()
+			if fsGroup != tt.ExpectedFsGroup {
+				t.Errorf("expected the fsGroup to be pinned %t, got %v", tt.ExpectedFsGroup, sc)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_DependencyGraph(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		DependencyGraph bool
+		ColdExtract     bool
+		Golden          string
+	}{
+		"default": {
+			Golden: "otel-pipelines-default.golden.yaml",
+		},
+		"servicegraph": {
+			DependencyGraph: true,
+			Golden:          "otel-pipelines-servicegraph.golden.yaml",
+		},
+		"servicegraph-coldextract": {
+			DependencyGraph: true,
+			ColdExtract:     true,
+			Golden:          "otel-pipelines-servicegraph-coldextract.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				DependencyGraph: tt.DependencyGraph,
+				ColdExtract:     tt.ColdExtract,
+			})
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for _, key := range []string{"connectors", "service"} {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_Exemplars(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		Exemplars: true,
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-exemplars.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	for _, key := range []string{"connectors", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+}
+
+func Test_U_OtelCollector_MetricsConversion(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Conversion *MetricsConversionArgs
+		ExpectErr  bool
+		// Processors of the metrics sent to Prometheus
+		Processors []any
+	}{
+		"delta-to-cumulative": {
+			Conversion: &MetricsConversionArgs{
+				DeltaToCumulative: true,
+			},
+			Processors: []any{"deltatocumulative"},
+		},
+		"exponential-histograms": {
+			Conversion: &MetricsConversionArgs{
+				ExponentialHistograms: true,
+			},
+			Processors: []any{"transform/histograms"},
+		},
+		"count-only": {
+			Conversion: &MetricsConversionArgs{},
+		},
+		"decreasing-buckets": {
+			Conversion: &MetricsConversionArgs{
+				ExponentialHistograms: true,
+				Buckets:               []float64{1, 0.5},
+			},
+			ExpectErr: true,
+		},
+		"negative-max-stale": {
+			Conversion: &MetricsConversionArgs{
+				DeltaToCumulative: true,
+				MaxStale:          -time.Minute,
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := &OtelCollectorArgs{
+				MetricsConversion: tt.Conversion,
+			}
+			str, err := RenderOtelConfig(args, "http://jaeger:4317", "http://prometheus:9090")
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+			cfg := map[string]any{}
+			if err := yaml.Unmarshal([]byte(str), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+
+			pipelines := cfg["service"].(map[string]any)["pipelines"].(map[string]any)
+			prom := pipelines["metrics/prometheus"].(map[string]any)
+			processors, _ := prom["processors"].([]any)
+			if !reflect.DeepEqual(processors, tt.Processors) {
+				t.Errorf("expected processors %v, got %v", tt.Processors, processors)
+			}
+			// The incompatible datapoints are counted whatever the conversions
+			if _, ok := pipelines["metrics/conversion"]; !ok {
+				t.Error("expected the conversion counts pipeline")
+			}
+			// Only the converted metrics reach Prometheus
+			metrics := pipelines["metrics"].(map[string]any)
+			if slices.Contains(metrics["exporters"].([]any), "prometheusremotewrite") {
+				t.Error("expected the unconverted metrics not to be sent to Prometheus")
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_MetricsConversion_Golden(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		MetricsConversion: &MetricsConversionArgs{
+			DeltaToCumulative:     true,
+			ExponentialHistograms: true,
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-metrics-conversion.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	for _, key := range []string{"processors", "connectors", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+}
+
+func Test_U_OtelCollector_Kafka(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	var args *OtelCollectorArgs
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		args = &OtelCollectorArgs{
+			Namespace:     pulumi.String("monitoring"),
+			JaegerURL:     pulumi.String("http://jaeger:4317"),
+			PrometheusURL: pulumi.String("http://prometheus:9090"),
+			Kafka: &KafkaExporterArgs{
+				Brokers: []string{"kafka-0.example.com:9093", "10.0.0.12:9093"},
+				Topics: KafkaTopicsArgs{
+					Traces: "ctf-traces",
+					Logs:   "ctf-logs",
+				},
+				TLS: &KafkaTLSArgs{
+					SecretName:        pulumi.String("kafka-tls"),
+					ClientCertificate: true,
+				},
+				SASL: &KafkaSASLArgs{
+					Username:           "monitoring #ctf", // a comment unless quoted
+					PasswordSecretName: pulumi.String("kafka-sasl"),
+				},
+			},
+		}
+		_, err := NewOtelCollector(ctx, "otel", args)
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-kafka.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	kafka := cfg["exporters"].(map[string]any)["kafka"]
+	if exp := expected["exporters"].(map[string]any)["kafka"]; !reflect.DeepEqual(kafka, exp) {
+		t.Errorf("expected kafka exporter %v, got %v", exp, kafka)
+	}
+	if !reflect.DeepEqual(cfg["service"], expected["service"]) {
+		t.Errorf("expected service %v, got %v", expected["service"], cfg["service"])
+	}
+
+	// The TLS Secret is mounted only readable by the collector group, and
+	// the password referenced from its own
+	podSpec := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+	for _, v := range podSpec["volumes"].ArrayValue() {
+		if v.ObjectValue()["name"].StringValue() != "kafka-tls" {
+			continue
+		}
+		if mode := v.ObjectValue()["secret"].ObjectValue()["defaultMode"].NumberValue(); mode != 0440 {
+			t.Errorf("expected the kafka TLS secret mode 0440, got %o", int(mode))
+		}
+	}
+	if sc, ok := podSpec["securityContext"]; !ok || sc.ObjectValue()["fsGroup"].NumberValue() != otelGID {
+		t.Errorf("expected fsGroup %d, got %v", otelGID, podSpec["securityContext"])
+	}
+	ctr := podSpec["containers"].ArrayValue()[0].ObjectValue()
+	mounted := false
+	for _, vm := range ctr["volumeMounts"].ArrayValue() {
+		if vm.ObjectValue()["mountPath"].StringValue() == otelKafkaTLSPath {
+			mounted = true
+		}
+	}
+	if !mounted {
+		t.Errorf("expected the kafka TLS secret to be mounted at %s", otelKafkaTLSPath)
+	}
+	referenced := false
+	for _, env := range ctr["env"].ArrayValue() {
+		e := env.ObjectValue()
+		if e["name"].StringValue() != otelKafkaPasswordEnv {
+			continue
+		}
+		ref := e["valueFrom"].ObjectValue()["secretKeyRef"].ObjectValue()
+		referenced = ref["name"].StringValue() == "kafka-sasl" && ref["key"].StringValue() == BasicAuthPasswordKey
+	}
+	if !referenced {
+		t.Errorf("expected the kafka password to be referenced from its secret, got %v", ctr["env"])
+	}
+}
+
+func Test_U_OtelCollector_Kafka_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Kafka     KafkaExporterArgs
+		ExpectErr bool
+	}{
+		"valid": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:9092", "[fd00::12]:9092"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+			},
+		},
+		"no-broker": {
+			Kafka: KafkaExporterArgs{
+				Topics: KafkaTopicsArgs{Metrics: "ctf-metrics"},
+			},
+			ExpectErr: true,
+		},
+		"no-port": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+			},
+			ExpectErr: true,
+		},
+		"invalid-port": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:90920"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+			},
+			ExpectErr: true,
+		},
+		"no-topic": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:9092"},
+			},
+			ExpectErr: true,
+		},
+		"unknown-encoding": {
+			Kafka: KafkaExporterArgs{
+				Brokers:  []string{"kafka:9092"},
+				Topics:   KafkaTopicsArgs{Metrics: "ctf-metrics"},
+				Encoding: "jaeger_proto",
+			},
+			ExpectErr: true,
+		},
+		"tls-no-secret": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:9092"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+				TLS:     &KafkaTLSArgs{},
+			},
+			ExpectErr: true,
+		},
+		"sasl-no-password": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:9092"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+				SASL: &KafkaSASLArgs{
+					Username: "monitoring",
+				},
+			},
+			ExpectErr: true,
+		},
+		"sasl-unknown-mechanism": {
+			Kafka: KafkaExporterArgs{
+				Brokers: []string{"kafka:9092"},
+				Topics:  KafkaTopicsArgs{Metrics: "ctf-metrics"},
+				SASL: &KafkaSASLArgs{
+					Username:           "monitoring",
+					Mechanism:          "GSSAPI",
+					PasswordSecretName: pulumi.String("kafka-sasl"),
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:     pulumi.String("http://jaeger:4317"),
+				PrometheusURL: pulumi.String("http://prometheus:9090"),
+				Kafka:         &tt.Kafka,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_LegacyReceivers(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Statsd        bool
+		Syslog        *SyslogReceiverArgs
+		Golden        string
+		ExpectedPorts []string
+		ExpectSysctl  bool
+	}{
+		"statsd": {
+			Statsd:        true,
+			Golden:        "otel-receivers-statsd.golden.yaml",
+			ExpectedPorts: []string{"otlp-grpc/TCP/4317", "statsd/UDP/8125"},
+		},
+		"syslog": {
+			Syslog:        &SyslogReceiverArgs{Protocol: "rfc3164"},
+			Golden:        "otel-receivers-syslog.golden.yaml",
+			ExpectedPorts: []string{"otlp-grpc/TCP/4317", "syslog-tcp/TCP/514", "syslog-udp/UDP/514"},
+			ExpectSysctl:  true,
+		},
+		"statsd-syslog": {
+			Statsd:        true,
+			Syslog:        &SyslogReceiverArgs{},
+			Golden:        "otel-receivers-statsd-syslog.golden.yaml",
+			ExpectedPorts: []string{"otlp-grpc/TCP/4317", "statsd/UDP/8125", "syslog-tcp/TCP/514", "syslog-udp/UDP/514"},
+			ExpectSysctl:  true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var args *OtelCollectorArgs
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args = &OtelCollectorArgs{
+					Namespace:      pulumi.String("monitoring"),
+					JaegerURL:      pulumi.String("http://jaeger:4317"),
+					PrometheusURL:  pulumi.String("http://prometheus:9090"),
+					StatsdReceiver: tt.Statsd,
+					SyslogReceiver: tt.Syslog,
+				}
+				_, err := NewOtelCollector(ctx, "otel", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for _, key := range []string{"receivers", "service"} {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+
+			// The Service and the pods expose the receivers with their protocol
+			svc := m.ByName("kubernetes:core/v1:Service", "otlp-grpc")
+			ports := []string{}
+			for _, p := range svc["spec"].ObjectValue()["ports"].ArrayValue() {
+				port := p.ObjectValue()
+				ports = append(ports, fmt.Sprintf("%s/%s/%d", port["name"].StringValue(), port["protocol"].StringValue(), int(port["port"].NumberValue())))
+			}
+			if !slices.Equal(ports, tt.ExpectedPorts) {
+				t.Errorf("expected the service ports %v, got %v", tt.ExpectedPorts, ports)
+			}
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			ctrPorts := spec["containers"].ArrayValue()[0].ObjectValue()["ports"].ArrayValue()
+			if len(ctrPorts) != len(tt.ExpectedPorts) {
+				t.Errorf("expected %d container ports, got %d", len(tt.ExpectedPorts), len(ctrPorts))
+			}
+
+			// The non-root collector binds the privileged syslog port
+			_, sysctl := spec["securityContext"]
+			if sysctl != tt.ExpectSysctl {
+				t.Errorf("expected the unprivileged port sysctl %t, got %t", tt.ExpectSysctl, sysctl)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_SyslogReceiver_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Protocol  string
+		ExpectErr bool
+	}{
+		"default": {},
+		"rfc3164": {
+			Protocol: "rfc3164",
+		},
+		"rfc5424": {
+			Protocol: "rfc5424",
+		},
+		"unknown": {
+			Protocol:  "rfc1234",
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:      pulumi.String("http://jaeger:4317"),
+				PrometheusURL:  pulumi.String("http://prometheus:9090"),
+				SyslogReceiver: &SyslogReceiverArgs{Protocol: tt.Protocol},
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_Ports(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Ports         *OtelPortsArgs
+		ExpectedPorts []string
+		ExpectSysctl  bool
+		ExpectErr     bool
+	}{
+		"defaults": {
+			Ports:         nil,
+			ExpectedPorts: []string{"otlp-grpc/TCP/4317", "statsd/UDP/8125", "syslog-tcp/TCP/514", "syslog-udp/UDP/514"},
+			ExpectSysctl:  true,
+		},
+		"custom": {
+			Ports: &OtelPortsArgs{
+				OTLP:   14317,
+				Statsd: 18125,
+				Syslog: 10514,
+			},
+			ExpectedPorts: []string{"otlp-grpc/TCP/14317", "statsd/UDP/18125", "syslog-tcp/TCP/10514", "syslog-udp/UDP/10514"},
+			ExpectSysctl:  false,
+		},
+		"same-port-other-protocol": {
+			Ports: &OtelPortsArgs{
+				OTLP:   5000,
+				Statsd: 5000,
+				Syslog: 5514,
+			},
+			ExpectedPorts: []string{"otlp-grpc/TCP/5000", "statsd/UDP/5000", "syslog-tcp/TCP/5514", "syslog-udp/UDP/5514"},
+		},
+		"out-of-range": {
+			Ports: &OtelPortsArgs{
+				OTLP: 65536,
+			},
+			ExpectErr: true,
+		},
+		"negative": {
+			Ports: &OtelPortsArgs{
+				Statsd: -1,
+			},
+			ExpectErr: true,
+		},
+		"tcp-collision": {
+			Ports: &OtelPortsArgs{
+				OTLP:   5514,
+				Syslog: 5514,
+			},
+			ExpectErr: true,
+		},
+		"udp-collision": {
+			Ports: &OtelPortsArgs{
+				Statsd: 5514,
+				Syslog: 5514,
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var args *OtelCollectorArgs
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args = &OtelCollectorArgs{
+					Namespace:      pulumi.String("monitoring"),
+					JaegerURL:      pulumi.String("http://jaeger:4317"),
+					PrometheusURL:  pulumi.String("http://prometheus:9090"),
+					StatsdReceiver: true,
+					SyslogReceiver: &SyslogReceiverArgs{},
+					Ports:          tt.Ports,
+				}
+				_, err := NewOtelCollector(ctx, "otel", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// The Service, the pods and the receivers agree on the ports
+			svc := m.ByName("kubernetes:core/v1:Service", "otlp-grpc")
+			ports := []string{}
+			for _, p := range svc["spec"].ObjectValue()["ports"].ArrayValue() {
+				port := p.ObjectValue()
+				ports = append(ports, fmt.Sprintf("%s/%s/%d", port["name"].StringValue(), port["protocol"].StringValue(), int(port["port"].NumberValue())))
+			}
+			if !slices.Equal(ports, tt.ExpectedPorts) {
+				t.Errorf("expected the service ports %v, got %v", tt.ExpectedPorts, ports)
+			}
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			ctrPorts := []string{}
+			for _, p := range spec["containers"].ArrayValue()[0].ObjectValue()["ports"].ArrayValue() {
+				port := p.ObjectValue()
+				ctrPorts = append(ctrPorts, fmt.Sprintf("%s/%s/%d", port["name"].StringValue(), port["protocol"].StringValue(), int(port["containerPort"].NumberValue())))
+			}
+			if !slices.Equal(ctrPorts, tt.ExpectedPorts) {
+				t.Errorf("expected the container ports %v, got %v", tt.ExpectedPorts, ctrPorts)
+			}
+			cfg := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+			for _, p := range otelPorts(args) {
+				if !strings.Contains(cfg, fmt.Sprintf(`"0.0.0.0:%d"`, p.Port)) {
+					t.Errorf("expected the %s receiver to listen on %d, got:\n%s", p.Name, p.Port, cfg)
+				}
+			}
+
+			// Only the privileged syslog port requires the sysctl
+			_, sysctl := spec["securityContext"]
+			if sysctl != tt.ExpectSysctl {
+				t.Errorf("expected the unprivileged port sysctl %t, got %t", tt.ExpectSysctl, sysctl)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_TracesFailover(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		ColdExtract:    true,
+		TracesFailover: true,
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-pipelines-failover.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	for _, key := range []string{"connectors", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+
+	exporters := cfg["exporters"].(map[string]any)
+	// Jaeger fails fast for the traces to spill, Prometheus still retries
+	otlp := exporters["otlp"].(map[string]any)
+	for _, key := range []string{"sending_queue", "retry_on_failure"} {
+		if enabled := otlp[key].(map[string]any)["enabled"]; enabled != false {
+			t.Errorf("expected otlp exporter %s to be disabled, got %v", key, otlp[key])
+		}
+	}
+	prw := exporters["prometheusremotewrite"].(map[string]any)
+	if enabled := prw["retry_on_failure"].(map[string]any)["enabled"]; enabled != true {
+		t.Errorf("expected prometheusremotewrite exporter to retry, got %v", prw["retry_on_failure"])
+	}
+	// The traces spill on the PVC, apart from the cold extract ones
+	spill, ok := exporters["file/traces_spill"].(map[string]any)
+	if !ok || spill["path"] != "/data/collector/otel_traces_spill" {
+		t.Errorf("expected the spill file exporter, got %v", exporters["file/traces_spill"])
+	}
+
+	// Requires cold extract
+	otel := &OtelCollector{}
+	if err := otel.check(otel.defaults(&OtelCollectorArgs{
+		JaegerURL:      pulumi.String("http://jaeger:4317"),
+		PrometheusURL:  pulumi.String("http://prometheus:9090"),
+		TracesFailover: true,
+	})); err == nil {
+		t.Error("expected traces failover without cold extract to be refused")
+	}
+}
+
+func Test_U_OtelCollector_HeadSampling(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Percent *int
+		Golden  string
+	}{
+		"unset": {
+			Percent: nil,
+			Golden:  "otel-head-sampling-unset.golden.yaml",
+		},
+		"0": {
+			Percent: new(0),
+			Golden:  "otel-head-sampling-0.golden.yaml",
+		},
+		"50": {
+			Percent: new(50),
+			Golden:  "otel-head-sampling-50.golden.yaml",
+		},
+		"100": {
+			Percent: new(100),
+			Golden:  "otel-head-sampling-100.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				HeadSamplingPercent: tt.Percent,
+			})
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for _, key := range []string{"processors", "service"} {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_HeadSampling_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Percent   *int
+		ExpectErr bool
+	}{
+		"unset": {
+			Percent: nil,
+		},
+		"half": {
+			Percent: new(50),
+		},
+		"all": {
+			Percent: new(100),
+		},
+		"none": {
+			Percent: new(0),
+		},
+		"negative": {
+			Percent:   new(-1),
+			ExpectErr: true,
+		},
+		"over-100": {
+			Percent:   new(101),
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:           pulumi.String("http://jaeger:4317"),
+				PrometheusURL:       pulumi.String("http://prometheus:9090"),
+				HeadSamplingPercent: tt.Percent,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_TenantRouting(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		ColdExtract: true,
+		TenantRouting: &TenantRoutingArgs{
+			Tenants: []string{"ctf-a", "ctf-b"},
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-routing-tenants.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+
+	// Only the file exporters are routed
+	files := map[string]any{}
+	for name, exp := range cfg["exporters"].(map[string]any) {
+		if strings.HasPrefix(name, "file/") {
+			files[name] = exp
+		}
+	}
+	if !reflect.DeepEqual(files, expected["exporters"]) {
+		t.Errorf("expected file exporters %v, got %v", expected["exporters"], files)
+	}
+	for _, key := range []string{"connectors", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+}
+
+func Test_U_OtelCollector_TenantDirectories(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Routing     *TenantRoutingArgs
+		PlatformIDs bool
+		// ExpectedCommand of the init container, none if nil.
+		ExpectedCommand []string
+		ExpectedUser    bool
+	}{
+		"no-routing": {},
+		"tenants": {
+			Routing: &TenantRoutingArgs{Tenants: []string{"ctf-b", "ctf-a"}},
+			ExpectedCommand: []string{
+				"mkdir", "-p",
+				"/data/collector/ctf-a",
+				"/data/collector/ctf-b",
+				"/data/collector/default",
+			},
+			ExpectedUser: true,
+		},
+		"platform-ids": {
+			Routing:     &TenantRoutingArgs{Tenants: []string{"ctf-a"}},
+			PlatformIDs: true,
+			ExpectedCommand: []string{
+				"mkdir", "-p",
+				"/data/collector/ctf-a",
+				"/data/collector/default",
+			},
+			ExpectedUser: false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewOtelCollector(ctx, "collector", &OtelCollectorArgs{
+					Namespace:     pulumi.String("monitoring"),
+					JaegerURL:     pulumi.String("jaeger:4317"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					ColdExtract:   true,
+					TenantRouting: tt.Routing,
+					PlatformIDs:   tt.PlatformIDs,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			spec := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			inits, ok := spec["initContainers"]
+			if tt.ExpectedCommand == nil {
+				if ok && len(inits.ArrayValue()) != 0 {
+					t.Fatalf("expected no init container, got %v", inits)
+				}
+				return
+			}
+			if !ok || len(inits.ArrayValue()) != 1 {
+				t.Fatalf("expected an init container, got %v", inits)
+			}
+			ctr := inits.ArrayValue()[0].ObjectValue()
+			cmd := []string{}
+			for _, arg := range ctr["command"].ArrayValue() {
+				cmd = append(cmd, arg.StringValue())
+			}
+			if !reflect.DeepEqual(cmd, tt.ExpectedCommand) {
+				t.Errorf("expected command %v, got %v", tt.ExpectedCommand, cmd)
+			}
+			mount := ctr["volumeMounts"].ArrayValue()[0].ObjectValue()
+			if mount["name"].StringValue() != "signals" || mount["mountPath"].StringValue() != "/data/collector" {
+				t.Errorf("expected the PVC to be mounted, got %v", mount)
+			}
+			_, user := ctr["securityContext"].ObjectValue()["runAsUser"]
+			if user != tt.ExpectedUser {
+				t.Errorf("expected the user to be pinned: %t, got %t", tt.ExpectedUser, user)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_TenantRouting_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ColdExtract bool
+		Routing     *TenantRoutingArgs
+		ExpectErr   bool
+	}{
+		"valid": {
+			ColdExtract: true,
+			Routing:     &TenantRoutingArgs{Tenants: []string{"ctf-a", "ctf_b.2026"}},
+		},
+		"without-cold-extract": {
+			ColdExtract: false,
+			Routing:     &TenantRoutingArgs{Tenants: []string{"ctf-a"}},
+			ExpectErr:   true,
+		},
+		"no-tenant": {
+			ColdExtract: true,
+			Routing:     &TenantRoutingArgs{},
+			ExpectErr:   true,
+		},
+		"reserved-tenant": {
+			ColdExtract: true,
+			Routing:     &TenantRoutingArgs{Tenants: []string{DefaultTenant}},
+			ExpectErr:   true,
+		},
+		"duplicated-tenant": {
+			ColdExtract: true,
+			Routing:     &TenantRoutingArgs{Tenants: []string{"ctf-a", "ctf-a"}},
+			ExpectErr:   true,
+		},
+		"path-traversal": {
+			ColdExtract: true,
+			Routing:     &TenantRoutingArgs{Tenants: []string{"../etc"}},
+			ExpectErr:   true,
+		},
+		"quoting-attribute": {
+			ColdExtract: true,
+			Routing: &TenantRoutingArgs{
+				Attribute: `stack"] or true or attributes["x`,
+				Tenants:   []string{"ctf-a"},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:     pulumi.String("http://jaeger:4317"),
+				PrometheusURL: pulumi.String("http://prometheus:9090"),
+				ColdExtract:   tt.ColdExtract,
+				TenantRouting: tt.Routing,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_ColdExtractLayout(t *testing.T) {
+	t.Parallel()
+
+	if layout := ColdExtractLayout(nil); !reflect.DeepEqual(layout, map[string]string{
+		DefaultTenant: ".",
+	}) {
+		t.Errorf("unexpected layout without routing: %v", layout)
+	}
+	if layout := ColdExtractLayout(&TenantRoutingArgs{
+		Tenants: []string{"ctf-a", "ctf-b"},
+	}); !reflect.DeepEqual(layout, map[string]string{
+		"ctf-a":       "ctf-a",
+		"ctf-b":       "ctf-b",
+		DefaultTenant: "default",
+	}) {
+		t.Errorf("unexpected layout with routing: %v", layout)
+	}
+}
+
+func Test_U_OtelCollector_PodEndpoints(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Replicas          int
+		ClusterDomain     string
+		ExpectStatefulSet bool
+		Expected          []string
+	}{
+		"single-replica": {
+			Replicas:          1,
+			ExpectStatefulSet: false,
+			Expected:          []string{},
+		},
+		"scaled": {
+			Replicas:          3,
+			ExpectStatefulSet: true,
+			Expected: []string{
+				"otel-0.otlp-grpc.monitoring:4317",
+				"otel-1.otlp-grpc.monitoring:4317",
+				"otel-2.otlp-grpc.monitoring:4317",
+			},
+		},
+		"scaled-fully-qualified": {
+			Replicas:          2,
+			ClusterDomain:     "cluster.local",
+			ExpectStatefulSet: true,
+			Expected: []string{
+				"otel-0.otlp-grpc.monitoring.svc.cluster.local:4317",
+				"otel-1.otlp-grpc.monitoring.svc.cluster.local:4317",
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			var got []string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				otel, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+					Namespace:     pulumi.String("monitoring"),
+					JaegerURL:     pulumi.String("http://jaeger:4317"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					Replicas:      tt.Replicas,
+					ClusterDomain: tt.ClusterDomain,
+				})
+				if err != nil {
+					return err
+				}
+				otel.PodEndpoints.ApplyT(func(edps []string) error {
+					defer wg.Done()
+					got = edps
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			sts := len(m.ByType("kubernetes:apps/v1:StatefulSet")) != 0
+			dep := len(m.ByType("kubernetes:apps/v1:Deployment")) != 0
+			if sts != tt.ExpectStatefulSet || dep == tt.ExpectStatefulSet {
+				t.Errorf("expected statefulset: %t, got statefulset: %t and deployment: %t", tt.ExpectStatefulSet, sts, dep)
+			}
+			if !slices.Equal(got, tt.Expected) {
+				t.Errorf("expected pod endpoints %v, got %v", tt.Expected, got)
+			}
+		})
+	}
+}
+
+// renderOtelConfigT renders the collector configuration and parses it back.
+func renderOtelConfigT(t *testing.T, args *OtelCollectorArgs) map[string]any {
+	t.Helper()
+
+	str, err := renderOtelConfig(args, "http://jaeger:4317", "http://prometheus:9090")
+	if err != nil {
+		t.Fatalf("rendering configuration: %s", err)
+	}
+	cfg := map[string]any{}
+	if err := yaml.Unmarshal([]byte(str), &cfg); err != nil {
+		t.Fatalf("invalid configuration: %s\n%s", err, str)
+	}
+	return cfg
+}
+
+func Test_U_OtelCollector_PrometheusBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		PrometheusBasicAuth: &BasicAuthArgs{
+			Username:           PrometheusBasicAuthUsername,
+			PasswordSecretName: pulumi.String("prometheus-web-config"),
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	prw := cfg["exporters"].(map[string]any)["prometheusremotewrite"].(map[string]any)
+	if auth, _ := prw["auth"].(map[string]any); auth["authenticator"] != "basicauth/prometheus" {
+		t.Errorf("expected the remote write to authenticate, got %v", prw["auth"])
+	}
+	ext, _ := cfg["extensions"].(map[string]any)["basicauth/prometheus"].(map[string]any)
+	client, _ := ext["client_auth"].(map[string]any)
+	if client["username"] != PrometheusBasicAuthUsername || client["password"] != "${env:"+otelPrometheusPasswordEnv+"}" {
+		t.Errorf("unexpected basic auth extension %v", ext)
+	}
+	if exts := cfg["service"].(map[string]any)["extensions"]; !reflect.DeepEqual(exts, []any{"basicauth/prometheus"}) {
+		t.Errorf("expected the basic auth extension to be enabled, got %v", exts)
+	}
+
+	// Without, no extension is configured
+	cfg = renderOtelConfigT(t, (&OtelCollector{}).defaults(&OtelCollectorArgs{}))
+	if _, ok := cfg["extensions"]; ok {
+		t.Errorf("expected no extension, got %v", cfg["extensions"])
+	}
+
+	// The password is required
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+			JaegerURL:     pulumi.String("http://jaeger:4317"),
+			PrometheusURL: pulumi.String("http://prometheus:9090"),
+			PrometheusBasicAuth: &BasicAuthArgs{
+				Username: PrometheusBasicAuthUsername,
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+	if err == nil {
+		t.Error("expected the missing password secret to be refused")
+	}
+}
+
+func Test_U_OtelCollector_PrometheusExporter(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		PrometheusOTLP bool
+		Golden         string
+	}{
+		"remote-write": {
+			Golden: "otel-prometheus-remote-write.golden.yaml",
+		},
+		"otlp": {
+			PrometheusOTLP: true,
+			Golden:         "otel-prometheus-otlp.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			// The credentials and queue size apply whatever the exporter
+			args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+				PrometheusOTLP: tt.PrometheusOTLP,
+				PrometheusBasicAuth: &BasicAuthArgs{
+					Username:           PrometheusBasicAuthUsername,
+					PasswordSecretName: pulumi.String("prometheus-web-config"),
+				},
+				QueueSize: 500,
+			})
+			cfg := renderOtelConfigT(t, args)
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for _, key := range []string{"exporters", "service"} {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_PrometheusScrape_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		PrometheusOTLP      bool
+		PrometheusBasicAuth *BasicAuthArgs
+		ExpectErr           bool
+	}{
+		"scrape": {},
+		"otlp": {
+			PrometheusOTLP: true,
+			ExpectErr:      true,
+		},
+		"basic-auth": {
+			PrometheusBasicAuth: &BasicAuthArgs{
+				Username:           PrometheusBasicAuthUsername,
+				PasswordSecretName: pulumi.String("prometheus-web-config"),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:           pulumi.String("http://jaeger:4317"),
+				PrometheusURL:       pulumi.String("http://prometheus:9090"),
+				PrometheusScrape:    true,
+				PrometheusOTLP:      tt.PrometheusOTLP,
+				PrometheusBasicAuth: tt.PrometheusBasicAuth,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_ValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ValidateConfig bool
+		Replicas       int
+		// Rejected is the error of the validation Job, as awaited by the
+		// provider, if the collector rejects the configuration.
+		Rejected string
+		Workload string
+	}{
+		"disabled": {
+			Workload: "kubernetes:apps/v1:Deployment",
+		},
+		"deployment": {
+			ValidateConfig: true,
+			Workload:       "kubernetes:apps/v1:Deployment",
+		},
+		"statefulset": {
+			ValidateConfig: true,
+			Replicas:       2,
+			Workload:       "kubernetes:apps/v1:StatefulSet",
+		},
+		"rejected": {
+			ValidateConfig: true,
+			Rejected:       "Job failed: 'exporters' unknown type: \"kafka\"",
+			Workload:       "kubernetes:apps/v1:Deployment",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			if tt.Rejected != "" {
+				m.Failing = map[string]string{
+					"otel-config-validation": tt.Rejected,
+				}
+			}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+					Namespace:      pulumi.String("monitoring"),
+					JaegerURL:      pulumi.String("http://jaeger:4317"),
+					PrometheusURL:  pulumi.String("http://prometheus:9090"),
+					ValidateConfig: tt.ValidateConfig,
+					Replicas:       tt.Replicas,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+
+			// The collector error fails the update, before the rollout
+			if tt.Rejected != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.Rejected) {
+					t.Fatalf("expected the update to fail with %q, got %v", tt.Rejected, err)
+				}
+				if m.ByName(tt.Workload, "otel") != nil {
+					t.Error("expected the workload not to be rolled out")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			job := m.ByName("kubernetes:batch/v1:Job", "otel-config-validation")
+			if !tt.ValidateConfig {
+				if job != nil {
+					t.Error("expected no validation job")
+				}
+				return
+			}
+			if job == nil {
+				t.Fatal("expected a validation job")
+			}
+			ctr := job["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			if got := ctr["args"].ArrayValue()[0].StringValue(); got != "validate" {
+				t.Errorf("expected the validate command, got %s", got)
+			}
+
+			dependsOnJob := slices.ContainsFunc(m.Dependencies(tt.Workload, "otel"), func(urn string) bool {
+				return strings.HasSuffix(urn, "$kubernetes:batch/v1:Job::otel-config-validation")
+			})
+			if !dependsOnJob {
+				t.Error("expected the workload to depend on the validation job")
+			}
+		})
+	}
+}
+
+func Test_U_OtelCollector_SecretEnvs(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+			Namespace:      pulumi.String("monitoring"),
+			JaegerURL:      pulumi.String("http://jaeger:4317"),
+			PrometheusURL:  pulumi.String("http://prometheus:9090"),
+			ValidateConfig: true,
+			PrometheusBasicAuth: &BasicAuthArgs{
+				Username:           PrometheusBasicAuthUsername,
+				PasswordSecretName: pulumi.String("prometheus-web-config"),
+			},
+			Kafka: &KafkaExporterArgs{
+				Brokers: []string{"kafka-0.example.com:9093"},
+				Topics: KafkaTopicsArgs{
+					Traces: "ctf-traces",
+				},
+				SASL: &KafkaSASLArgs{
+					Username:           "monitoring",
+					PasswordSecretName: pulumi.String("kafka-sasl"),
+					PasswordSecretKey:  "sasl-password",
+				},
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The ConfigMap only holds the placeholders
+	str := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+	cfg := map[string]any{}
+	if err := yaml.Unmarshal([]byte(str), &cfg); err != nil {
+		t.Fatalf("invalid configuration: %s", err)
+	}
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-secrets.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	auth := cfg["exporters"].(map[string]any)["kafka"].(map[string]any)["auth"]
+	if exp := expected["exporters"].(map[string]any)["kafka"].(map[string]any)["auth"]; !reflect.DeepEqual(auth, exp) {
+		t.Errorf("expected kafka auth %v, got %v", exp, auth)
+	}
+	if !reflect.DeepEqual(cfg["extensions"], expected["extensions"]) {
+		t.Errorf("expected extensions %v, got %v", expected["extensions"], cfg["extensions"])
+	}
+
+	// Each placeholder is sourced from its Secret, in the collector and the
+	// validation Job alike
+	refs := map[string][2]string{
+		otelPrometheusPasswordEnv: {"prometheus-web-config", BasicAuthPasswordKey},
+		otelKafkaPasswordEnv:      {"kafka-sasl", "sasl-password"},
+	}
+	for _, match := range regexp.MustCompile(`\$\{env:(\w+)\}`).FindAllStringSubmatch(str, -1) {
+		if _, ok := refs[match[1]]; !ok {
+			t.Errorf("unexpected placeholder %s", match[0])
+		}
+	}
+	for _, res := range []struct{ Type, Name string }{
+		{"kubernetes:apps/v1:Deployment", "otel"},
+		{"kubernetes:batch/v1:Job", "otel-config-validation"},
+	} {
+		ctr := m.ByName(res.Type, res.Name)["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+		envs := map[string][2]string{}
+		for _, env := range ctr["env"].ArrayValue() {
+			e := env.ObjectValue()
+			if e.HasValue("value") {
+				t.Errorf("expected %s env %s to be sourced from a secret, got a value", res.Name, e["name"].StringValue())
+			}
+			ref := e["valueFrom"].ObjectValue()["secretKeyRef"].ObjectValue()
+			envs[e["name"].StringValue()] = [2]string{ref["name"].StringValue(), ref["key"].StringValue()}
+		}
+		if !reflect.DeepEqual(envs, refs) {
+			t.Errorf("expected %s secret envs %v, got %v", res.Name, refs, envs)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package parts
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// CollectorComponents are the components built in an OpenTelemetry
+	// Collector image, by type (e.g. "otlp", "servicegraph").
+	CollectorComponents struct {
+		Receivers  []string `yaml:"receivers" json:"receivers"`
+		Processors []string `yaml:"processors" json:"processors"`
+		Exporters  []string `yaml:"exporters" json:"exporters"`
+		Connectors []string `yaml:"connectors" json:"connectors"`
+		Extensions []string `yaml:"extensions" json:"extensions"`
+	}
+
+	// otelFeature is a configuration feature, and the components it requires
+	// in the collector image.
+	otelFeature struct {
+		Name     string
+		Enabled  func(args *OtelCollectorArgs) bool
+		Requires CollectorComponents
+	}
+)
+
+const (
+	defaultOtelImage = "otel/opentelemetry-collector-contrib:" + OtelCollectorVersion
+)
+
+//go:embed otel-components.yaml
+var otelComponentsManifest []byte
+
+// knownCollectorComponents are the components of the known collector images,
+// by repository.
+var knownCollectorComponents map[string]CollectorComponents
+
+func init() {
+	if err := yaml.Unmarshal(otelComponentsManifest, &knownCollectorComponents); err != nil {
+		panic(fmt.Errorf("invalid OTEL components manifest: %s", err))
+	}
+}
+
+// otelFeatures are the features of the collector configuration, and the
+// components they require. Keep it in sync with otel-config.yaml.tmpl.
+var otelFeatures = []otelFeature{
+	{
+		Name:    "pipelines",
+		Enabled: func(*OtelCollectorArgs) bool { return true },
+		Requires: CollectorComponents{
+			Receivers:  []string{"otlp"},
+			Exporters:  []string{"debug", "otlp"},
+			Connectors: []string{"spanmetrics"},
+		},
+	}, {
+		Name:    "prometheus remote write",
+		Enabled: func(args *OtelCollectorArgs) bool { return prometheusExporter(args) == prometheusRemoteWriteExporter },
+		Requires: CollectorComponents{
+			Exporters: []string{prometheusRemoteWriteExporter},
+		},
+	}, {
+		Name:    "prometheus scrape",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.PrometheusScrape },
+		Requires: CollectorComponents{
+			Exporters: []string{prometheusScrapeExporter},
+		},
+	}, {
+		Name:    "prometheus otlp",
+		Enabled: func(args *OtelCollectorArgs) bool { return prometheusExporter(args) == prometheusOTLPExporter },
+		Requires: CollectorComponents{
+			Exporters: []string{"otlphttp"},
+		},
+	}, {
+		Name:    "cold extract",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.ColdExtract },
+		Requires: CollectorComponents{
+			Exporters: []string{"file"},
+		},
+	}, {
+		Name:    "tenant routing",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.TenantRouting != nil },
+		Requires: CollectorComponents{
+			Connectors: []string{"routing"},
+		},
+	}, {
+		Name:    "head sampling",
+		Enabled: func(args *OtelCollectorArgs) bool { return headSamplingPercent(args) != nil },
+		Requires: CollectorComponents{
+			Processors: []string{headSamplingProcessor},
+		},
+	}, {
+		Name: "memory limiter",
+		Enabled: func(args *OtelCollectorArgs) bool {
+			rl, _ := runtimeLimits(args)
+			return rl.MemoryLimitMiB != 0
+		},
+		Requires: CollectorComponents{
+			Processors: []string{memoryLimiterProcessor},
+		},
+	}, {
+		Name:    "traces failover",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.TracesFailover },
+		Requires: CollectorComponents{
+			Exporters:  []string{"file"},
+			Connectors: []string{"failover"},
+		},
+	}, {
+		Name:    "prometheus basic auth",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.PrometheusBasicAuth != nil },
+		Requires: CollectorComponents{
+			Extensions: []string{"basicauth"},
+		},
+	}, {
+		Name:    "kafka exporter",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.Kafka != nil },
+		Requires: CollectorComponents{
+			Exporters: []string{"kafka"},
+		},
+	}, {
+		Name:    "statsd receiver",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.StatsdReceiver },
+		Requires: CollectorComponents{
+			Receivers: []string{"statsd"},
+		},
+	}, {
+		Name:    "syslog receiver",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.SyslogReceiver != nil },
+		Requires: CollectorComponents{
+			Receivers: []string{"syslog"},
+		},
+	}, {
+		Name:    "redaction",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.Redaction != nil },
+		Requires: CollectorComponents{
+			Processors: []string{"transform"},
+		},
+	}, {
+		Name:    "metrics conversion",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.MetricsConversion != nil },
+		// The counts are deltas, accumulated whatever the conversions
+		Requires: CollectorComponents{
+			Processors: []string{"deltatocumulative"},
+			Connectors: []string{"count", "forward"},
+		},
+	}, {
+		Name: "exponential histograms conversion",
+		Enabled: func(args *OtelCollectorArgs) bool {
+			return args.MetricsConversion != nil && args.MetricsConversion.ExponentialHistograms
+		},
+		Requires: CollectorComponents{
+			Processors: []string{"transform"},
+		},
+	}, {
+		Name:    "ingestion quotas",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.IngestionQuotas != nil },
+		Requires: CollectorComponents{
+			Processors: []string{memoryLimiterProcessor, "deltatocumulative"},
+			Connectors: []string{"count", "forward", "routing"},
+		},
+	}, {
+		Name:    "logs metrics",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.LogsMetrics != nil },
+		Requires: CollectorComponents{
+			Processors: []string{"deltatocumulative"},
+			Connectors: []string{"count"},
+		},
+	}, {
+		Name:    "logs dedup",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.LogsMetrics != nil && args.LogsMetrics.Dedup },
+		Requires: CollectorComponents{
+			Processors: []string{logDedupProcessor},
+			Connectors: []string{"forward"},
+		},
+	}, {
+		Name:    "dependency graph",
+		Enabled: func(args *OtelCollectorArgs) bool { return args.DependencyGraph },
+		Requires: CollectorComponents{
+			Connectors: []string{"servicegraph"},
+		},
+	},
+}
+
+// checkCollectorComponents validates the collector image contains the
+// components of every enabled feature, such that it is reported at preview
+// time rather than by the collector crash-looping on an unknown type.
+func checkCollectorComponents(args *OtelCollectorArgs) (merr error) {
+	available := args.Components
+	if available == nil {
+		comps, ok := knownCollectorComponents[imageRepository(args.Image)]
+		if !ok {
+			return errors.Errorf("unknown collector image %s, its components must be declared", args.Image)
+		}
+		available = &comps
+	}
+
+	for _, feat := range otelFeatures {
+		if !feat.Enabled(args) {
+			continue
+		}
+		for _, missing := range feat.Requires.missing(available) {
+			merr = multierr.Append(merr, errors.Errorf("%s requires the %s, which is not built in the collector image %s", feat.Name, missing, args.Image))
+		}
+	}
+	return
+}
+
+// missing returns the components not available, e.g. "servicegraph connector".
+func (comps CollectorComponents) missing(available *CollectorComponents) []string {
+	var missing []string
+	for _, kind := range []struct {
+		Name                string
+		Required, Available []string
+	}{
+		{"receiver", comps.Receivers, available.Receivers},
+		{"processor", comps.Processors, available.Processors},
+		{"exporter", comps.Exporters, available.Exporters},
+		{"connector", comps.Connectors, available.Connectors},
+		{"extension", comps.Extensions, available.Extensions},
+	} {
+		for _, comp := range kind.Required {
+			if !slices.Contains(kind.Available, comp) {
+				missing = append(missing, comp+" "+kind.Name)
+			}
+		}
+	}
+	return missing
+}
+
+// imageRepository returns the repository of the image, without its tag nor
+// digest.
+// Example: otel/opentelemetry-collector-contrib:0.143.0 -> otel/opentelemetry-collector-contrib
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
@@ -0,0 +1,221 @@
+package parts
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_OtelCollector_Components(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args          *OtelCollectorArgs
+		ExpectErr     bool
+		ExpectedInErr []string
+	}{
+		"default": {
+			Args: &OtelCollectorArgs{},
+		},
+		"contrib-every-feature": {
+			Args: &OtelCollectorArgs{
+				Image:           "otel/opentelemetry-collector-contrib:0.140.0",
+				ColdExtract:     true,
+				TenantRouting:   &TenantRoutingArgs{Tenants: []string{"ctf-2026"}},
+				DependencyGraph: true,
+			},
+		},
+		"contrib-digest": {
+			Args: &OtelCollectorArgs{
+				Image: "otel/opentelemetry-collector-contrib@sha256:0123456789abcdef",
+			},
+		},
+		"core": {
+			Args: &OtelCollectorArgs{
+				Image: "otel/opentelemetry-collector:" + OtelCollectorVersion,
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"prometheus remote write requires the prometheusremotewrite exporter", "pipelines requires the spanmetrics connector"},
+		},
+		"unknown-image": {
+			Args: &OtelCollectorArgs{
+				Image: "ghcr.io/ctfer-io/otelcol:1.0.0",
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"unknown collector image ghcr.io/ctfer-io/otelcol:1.0.0"},
+		},
+		"custom-image": {
+			Args: &OtelCollectorArgs{
+				Image:      "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components: leanComponents(),
+			},
+		},
+		"custom-image-dependency-graph": {
+			Args: &OtelCollectorArgs{
+				Image:           "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components:      leanComponents(),
+				DependencyGraph: true,
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"dependency graph requires the servicegraph connector, which is not built in the collector image ghcr.io/ctfer-io/otelcol:1.0.0"},
+		},
+		"custom-image-cold-extract-tenants": {
+			Args: &OtelCollectorArgs{
+				Image:         "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components:    leanComponents(),
+				ColdExtract:   true,
+				TenantRouting: &TenantRoutingArgs{Tenants: []string{"ctf-2026"}},
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"cold extract requires the file exporter", "tenant routing requires the routing connector"},
+		},
+		"custom-image-prometheus-otlp": {
+			Args: &OtelCollectorArgs{
+				Image:          "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components:     leanComponents(),
+				PrometheusOTLP: true,
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"prometheus otlp requires the otlphttp exporter"},
+		},
+		"custom-image-metrics-conversion": {
+			Args: &OtelCollectorArgs{
+				Image:      "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components: leanComponents(),
+				MetricsConversion: &MetricsConversionArgs{
+					DeltaToCumulative: true,
+				},
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"metrics conversion requires the deltatocumulative processor", "metrics conversion requires the count connector"},
+		},
+		"custom-image-logs-dedup": {
+			Args: &OtelCollectorArgs{
+				Image:      "ghcr.io/ctfer-io/otelcol:1.0.0",
+				Components: leanComponents(),
+				LogsMetrics: &LogsMetricsArgs{
+					Dedup: true,
+				},
+			},
+			ExpectErr:     true,
+			ExpectedInErr: []string{"logs metrics requires the count connector", "logs dedup requires the logdedup processor"},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := RenderOtelConfig(tt.Args, "http://jaeger:4317", "http://prometheus:9090")
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			for _, exp := range tt.ExpectedInErr {
+				if !strings.Contains(err.Error(), exp) {
+					t.Errorf("expected error to contain %q, got: %s", exp, err)
+				}
+			}
+		})
+	}
+}
+
+// Test_U_OtelCollector_Features checks the features require every component
+// the configuration renders, i.e. they are kept in sync with the template.
+func Test_U_OtelCollector_Features(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		ColdExtract:     true,
+		TenantRouting:   &TenantRoutingArgs{Tenants: []string{"ctf-2026"}},
+		DependencyGraph: true,
+		TracesFailover:  true,
+		StatsdReceiver:  true,
+		SyslogReceiver:  &SyslogReceiverArgs{},
+		MetricsConversion: &MetricsConversionArgs{
+			DeltaToCumulative:     true,
+			ExponentialHistograms: true,
+		},
+		LogsMetrics: &LogsMetricsArgs{
+			Dedup: true,
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	required := CollectorComponents{}
+	for _, feat := range otelFeatures {
+		if feat.Enabled(args) {
+			required.Receivers = append(required.Receivers, feat.Requires.Receivers...)
+			required.Processors = append(required.Processors, feat.Requires.Processors...)
+			required.Exporters = append(required.Exporters, feat.Requires.Exporters...)
+			required.Connectors = append(required.Connectors, feat.Requires.Connectors...)
+			required.Extensions = append(required.Extensions, feat.Requires.Extensions...)
+		}
+	}
+
+	for section, comps := range map[string][]string{
+		"receivers":  required.Receivers,
+		"processors": required.Processors,
+		"exporters":  required.Exporters,
+		"connectors": required.Connectors,
+		"extensions": required.Extensions,
+	} {
+		rendered, _ := cfg[section].(map[string]any)
+		for id := range rendered {
+			// Component IDs are type[/name]
+			typ, _, _ := strings.Cut(id, "/")
+			if !slices.Contains(comps, typ) {
+				t.Errorf("%s %s is rendered but required by no feature", section, typ)
+			}
+		}
+	}
+}
+
+func Test_U_CollectorComponents_Manifest(t *testing.T) {
+	t.Parallel()
+
+	// The default image is known
+	if _, ok := knownCollectorComponents[imageRepository(defaultOtelImage)]; !ok {
+		t.Fatalf("default image %s is not in the manifest", defaultOtelImage)
+	}
+
+	// The manifest declares every kind, such that typos are noticed
+	var raw map[string]map[string]any
+	if err := yaml.Unmarshal(otelComponentsManifest, &raw); err != nil {
+		t.Fatalf("invalid manifest: %s", err)
+	}
+	for image, kinds := range raw {
+		for kind := range kinds {
+			if !slices.Contains([]string{"receivers", "processors", "exporters", "connectors", "extensions"}, kind) {
+				t.Errorf("image %s: unknown component kind %s", image, kind)
+			}
+		}
+	}
+}
+
+func Test_U_ImageRepository(t *testing.T) {
+	t.Parallel()
+
+	for image, expected := range map[string]string{
+		"otel/opentelemetry-collector-contrib":                 "otel/opentelemetry-collector-contrib",
+		"otel/opentelemetry-collector-contrib:0.143.0":         "otel/opentelemetry-collector-contrib",
+		"otel/opentelemetry-collector-contrib@sha256:0123abcd": "otel/opentelemetry-collector-contrib",
+		"registry.local:5000/otelcol":                          "registry.local:5000/otelcol",
+		"registry.local:5000/otelcol:1.0.0":                    "registry.local:5000/otelcol",
+	} {
+		if got := imageRepository(image); got != expected {
+			t.Errorf("image %s: expected repository %s, got %s", image, expected, got)
+		}
+	}
+}
+
+// leanComponents are the components of a custom collector image, built with
+// only what the default pipelines require.
+func leanComponents() *CollectorComponents {
+	return &CollectorComponents{
+		Receivers:  []string{"otlp"},
+		Exporters:  []string{"debug", "otlp", "prometheusremotewrite"},
+		Connectors: []string{"spanmetrics", "forward"},
+	}
+}
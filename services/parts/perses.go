@@ -1,15 +1,21 @@
 package parts
 
 import (
-	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
 	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	helmv4 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v4"
 	v1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	policyv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/policy/v1"
+	"github.com/pulumi/pulumi-random/sdk/v4/go/random"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"go.uber.org/multierr"
 )
@@ -20,8 +26,27 @@ type (
 
 		chart    *helmv4.Chart
 		globalDS *corev1.ConfigMap
+		pdb      *policyv1.PodDisruptionBudget
+		encKey   *random.RandomPassword
+		access   *corev1.ConfigMap
+
+		// Bootstrap Job, if enabled
+		bootstrap *batchv1.Job
 
 		PodLabels pulumi.StringMapOutput
+
+		// BootstrapPodLabels are the labels of the bootstrap Job pod, empty
+		// if not enabled.
+		BootstrapPodLabels pulumi.StringMapOutput
+
+		// ServiceName is the name of the Service exposing the Perses UI.
+		ServiceName pulumi.StringOutput
+
+		// Discovery is the contract for dashboards to be discovered.
+		Discovery DashboardDiscovery
+
+		// Ready resolves to true once the Perses workloads rolled out.
+		Ready pulumi.BoolOutput
 	}
 
 	PersesArgs struct {
@@ -29,6 +54,10 @@ type (
 
 		Namespace pulumi.StringInput
 
+		// MonitoringVersion is labeled on the resources, as the
+		// OtelCollectorArgs one.
+		MonitoringVersion string
+
 		Registry pulumi.StringInput
 		registry pulumi.StringOutput
 
@@ -37,9 +66,137 @@ type (
 		// If no Prometheus URL is defined, there will be no data to display,
 		// hence is required.
 		PrometheusURL pulumi.StringInput
+
+		// PrometheusDependsOn are the resources to wait for before creating
+		// the global datasource, e.g. the Prometheus one for its rollout, such
+		// that Perses does not report the datasource unhealthy meanwhile.
+		PrometheusDependsOn []pulumi.Resource
+
+		// ChartWaitsForPrometheus also waits for PrometheusDependsOn before
+		// deploying the Perses chart, such that the UI is only reachable
+		// once it could display data.
+		ChartWaitsForPrometheus bool
+
+		// DisableDatasource does not provide the global datasource, e.g.
+		// when Prometheus runs as an agent that could not be queried.
+		DisableDatasource bool
+
+		// PrometheusBasicAuth authenticates the global datasource queries,
+		// e.g. when the Prometheus RemoteWriteBasicAuth is required. Perses
+		// then proxies them, with the password mounted from its Secret, as
+		// the browser could not be given it.
+		PrometheusBasicAuth *BasicAuthArgs
+
+		// Sizing attributes
+
+		// Replicas of the Perses pods. Each one provisions the dashboards on
+		// its own, from the discovered ConfigMaps.
+		// Defaults to 1.
+		Replicas int
+
+		// Resources of the Perses container.
+		// Defaults to the chart ones.
+		Resources corev1.ResourceRequirementsInput
+
+		// DisruptionBudget of the Perses pods, during voluntary disruptions
+		// (e.g. node drains). Requires more than one replica.
+		DisruptionBudget *DisruptionBudgetArgs
+
+		// Access-related attributes
+
+		// Access turns on the authentication, and provisions the roles of
+		// the organizers and spectators. Defaults to anonymous access.
+		Access *PersesAccessArgs
+
+		// Bootstrap runs a Job awaiting Perses to serve the global
+		// datasource, failing the update if it does not in time.
+		// With the Access, it only awaits the Perses API, as it could not
+		// authenticate.
+		Bootstrap *PersesBootstrapArgs
+
+		// Mesh annotates the pods for the service mesh of the cluster, the
+		// bootstrap Job being kept out of it.
+		// Defaults to no annotation.
+		Mesh *MeshArgs
+
+		// Escape hatch attributes
+
+		// ExtraValues are deep merged into the Perses chart values, for what
+		// the component does not expose. The overrides of the values it
+		// depends on are warned about, and the ones of the dashboard
+		// discovery label refused unless ForceExtraValues.
+		ExtraValues      map[string]any
+		ForceExtraValues bool
 	}
+
+	// DisruptionBudgetArgs bounds the pods evicted at once. MinAvailable and
+	// MaxUnavailable are mutually exclusive, one is required.
+	DisruptionBudgetArgs struct {
+		MinAvailable   int
+		MaxUnavailable int
+	}
+
+	// DashboardDiscovery is the contract of the Perses sidecar, which
+	// provisions the resources (e.g. dashboards) of the labeled ConfigMaps.
+	DashboardDiscovery struct {
+		// LabelKey and LabelValue of the ConfigMaps to discover.
+		LabelKey   string
+		LabelValue string
+
+		// AllNamespaces is true when the ConfigMaps are discovered in every
+		// namespace, else only in the Perses one.
+		AllNamespaces bool
+	}
+)
+
+const (
+	// PersesChartVersion is the version of the Perses Helm chart.
+	PersesChartVersion = "0.19.2"
+
+	// PersesPort is the port Perses listens on, as set by its chart. It
+	// serves both the UI and its own metrics.
+	PersesPort = 8080
+
+	// persesReleaseName is the name of the Perses Helm release, after which
+	// the chart names its Service.
+	persesReleaseName = "perses"
+
+	// persesGlobalDatasourceName is the name of the global datasource
+	// pointing to Prometheus.
+	persesGlobalDatasourceName = "prometheus-datasource"
+
+	// persesPrometheusSecretName is the name of the global secret holding
+	// the basic auth credentials of the global datasource.
+	persesPrometheusSecretName = "prometheus-basic-auth"
+
+	// persesPrometheusPasswordDir is where the Prometheus basic auth
+	// password is mounted in the Perses pods.
+	persesPrometheusPasswordDir = "/etc/perses/prometheus"
 )
 
+// persesDashboardDiscovery is how the Perses sidecar discovers dashboards.
+var persesDashboardDiscovery = DashboardDiscovery{
+	LabelKey:      "perses.dev/resource",
+	LabelValue:    "true",
+	AllNamespaces: true,
+}
+
+// Labels returns the labels for a ConfigMap to be discovered.
+func (d DashboardDiscovery) Labels() pulumi.StringMap {
+	return pulumi.StringMap{
+		d.LabelKey: pulumi.String(d.LabelValue),
+	}
+}
+
+// ToMap returns the discovery contract as an output value.
+func (d DashboardDiscovery) ToMap() pulumi.Map {
+	return pulumi.Map{
+		"labelKey":      pulumi.String(d.LabelKey),
+		"labelValue":    pulumi.String(d.LabelValue),
+		"allNamespaces": pulumi.Bool(d.AllNamespaces),
+	}
+}
+
 func NewPerses(ctx *pulumi.Context, name string, args *PersesArgs, opts ...pulumi.ResourceOption) (*Perses, error) {
 	prs := &Perses{}
 
@@ -80,6 +237,22 @@ func (*Perses) defaults(args *PersesArgs) *PersesArgs {
 		}).(pulumi.StringOutput)
 	}
 
+	if args.Replicas == 0 {
+		args.Replicas = 1
+	}
+
+	if args.Access != nil && args.Access.PublicProject == "" {
+		args.Access.PublicProject = defaultPersesPublicProject
+	}
+
+	if args.Bootstrap != nil && args.Bootstrap.Timeout == 0 {
+		args.Bootstrap.Timeout = defaultPersesBootstrapTimeout
+	}
+
+	if args.PrometheusBasicAuth != nil && args.PrometheusBasicAuth.PasswordSecretKey == "" {
+		args.PrometheusBasicAuth.PasswordSecretKey = BasicAuthPasswordKey
+	}
+
 	return args
 }
 
@@ -87,6 +260,36 @@ func (prs *Perses) check(args *PersesArgs) error {
 	if args.PrometheusURL == nil {
 		return errors.New("no prometheus URL configured")
 	}
+	if args.Replicas < 0 {
+		return errors.New("replicas could not be negative")
+	}
+	if err := checkDisruptionBudget(args.DisruptionBudget, args.Replicas); err != nil {
+		return errors.Wrap(err, "invalid disruption budget")
+	}
+	if args.Access != nil {
+		if err := checkPersesAccess(args.Access); err != nil {
+			return errors.Wrap(err, "invalid access")
+		}
+	}
+	if args.Bootstrap != nil {
+		if err := checkPersesBootstrap(args.Bootstrap); err != nil {
+			return errors.Wrap(err, "invalid bootstrap")
+		}
+	}
+	if ba := args.PrometheusBasicAuth; ba != nil {
+		if ba.Username == "" {
+			return errors.New("no prometheus basic auth username configured")
+		}
+		if ba.PasswordSecretName == nil {
+			return errors.New("no prometheus basic auth password secret configured")
+		}
+	}
+	if err := checkMesh(args.Mesh); err != nil {
+		return errors.Wrap(err, "invalid mesh")
+	}
+	if err := checkPersesExtraValues(args.ExtraValues, args.ForceExtraValues); err != nil {
+		return err
+	}
 
 	wg := &sync.WaitGroup{}
 	checks := 1 // number of checks to perform
@@ -113,99 +316,379 @@ func (prs *Perses) check(args *PersesArgs) error {
 }
 
 func (prs *Perses) provision(ctx *pulumi.Context, args *PersesArgs, opts ...pulumi.ResourceOption) (err error) {
+	config := pulumi.Map{
+		"provisioning": pulumi.Map{
+			// During bootstrap we intensively deploy things, so we need faster than default 10m
+			"interval": pulumi.String("1m"),
+		},
+	}
+	if args.Access != nil {
+		// The key signs the users sessions, it must be shared by the replicas
+		prs.encKey, err = random.NewRandomPassword(ctx, "perses-encryption-key", &random.RandomPasswordArgs{
+			Length:  pulumi.Int(32),
+			Special: pulumi.Bool(false),
+		}, opts...)
+		if err != nil {
+			return
+		}
+		config["security"] = pulumi.Map{
+			"enable_auth":    pulumi.Bool(true),
+			"encryption_key": prs.encKey.Result,
+			"authentication": pulumi.Map{
+				"providers": pulumi.Map{
+					"enable_native": pulumi.Bool(true),
+				},
+			},
+		}
+	}
+
+	values := pulumi.Map{
+		"image": pulumi.Map{
+			"registry": args.registry,
+		},
+		"replicas":  pulumi.Int(args.Replicas),
+		"resources": persesResources(args.Resources),
+		"sidecar": pulumi.Map{
+			// Watch for ConfigMaps with perses.dev/resource=true in all namespaces,
+			// so other services' dashboard can be automatically discovered.
+			"enabled":       pulumi.Bool(true),
+			"label":         pulumi.String(persesDashboardDiscovery.LabelKey),
+			"labelValue":    pulumi.String(persesDashboardDiscovery.LabelValue),
+			"allNamespaces": pulumi.Bool(persesDashboardDiscovery.AllNamespaces),
+		},
+		"config": config,
+	}
+	if ba := args.PrometheusBasicAuth; ba != nil && !args.DisableDatasource {
+		// The global secret reads the password from the mounted Secret
+		values["volumes"] = pulumi.Array{
+			pulumi.Map{
+				"name": pulumi.String("prometheus-basic-auth"),
+				"secret": pulumi.Map{
+					"secretName": ba.PasswordSecretName,
+					"items": pulumi.Array{
+						pulumi.Map{
+							"key":  pulumi.String(ba.PasswordSecretKey),
+							"path": pulumi.String(BasicAuthPasswordKey),
+						},
+					},
+				},
+			},
+		}
+		values["volumeMounts"] = pulumi.Array{
+			pulumi.Map{
+				"name":      pulumi.String("prometheus-basic-auth"),
+				"mountPath": pulumi.String(persesPrometheusPasswordDir),
+				"readOnly":  pulumi.Bool(true),
+			},
+		}
+	}
+	if annotations := args.Mesh.PodAnnotations(); annotations != nil {
+		values["podAnnotations"] = pulumi.ToStringMap(annotations)
+	}
+	if len(args.ExtraValues) != 0 {
+		for _, o := range persesValueOverrides(args.ExtraValues) {
+			if err = ctx.Log.Warn(o.Warning(), &pulumi.LogArgs{Resource: prs}); err != nil {
+				return
+			}
+		}
+		values = mergePersesValues(values, args.ExtraValues)
+	}
+
+	chartOpts := opts
+	if args.ChartWaitsForPrometheus {
+		chartOpts = append(slices.Clone(opts), pulumi.DependsOn(args.PrometheusDependsOn))
+	}
 	prs.chart, err = helmv4.NewChart(ctx, "perses", &helmv4.ChartArgs{
+		Name:  pulumi.String(persesReleaseName),
 		Chart: pulumi.String("perses"),
 		RepositoryOpts: helmv4.RepositoryOptsArgs{
 			Repo: pulumi.String("https://perses.github.io/helm-charts"),
 		},
-		Version:   pulumi.String("0.19.2"),
+		Version:   pulumi.String(PersesChartVersion),
 		Namespace: args.Namespace,
-		Values: pulumi.Map{
-			"image": pulumi.Map{
-				"registry": args.registry,
+		Values:    values,
+	}, chartOpts...)
+	if err != nil {
+		return
+	}
+
+	labels := pulumi.StringMap{
+		"app.kubernetes.io/name":      pulumi.String("perses"),
+		"app.kubernetes.io/component": pulumi.String("perses"),
+		"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+		"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+		"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+	}
+	maps.Copy(labels, persesDashboardDiscovery.Labels()) // Get discovered by Perses
+	if !args.DisableDatasource {
+		var data pulumi.StringMap
+		data, err = persesGlobalDatasourceData(args)
+		if err != nil {
+			return
+		}
+		prs.globalDS, err = corev1.NewConfigMap(ctx, "global-datasource", &corev1.ConfigMapArgs{
+			Metadata: v1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels:    labels,
 			},
-			"sidecar": pulumi.Map{
-				// Watch for ConfigMaps with perses.dev/resource=true in all namespaces,
-				// so other services' dashboard can be automatically discovered.
-				"enabled":       pulumi.Bool(true),
-				"label":         pulumi.String("perses.dev/resource"),
-				"labelValue":    pulumi.String("true"),
-				"allNamespaces": pulumi.Bool(true),
+			Data: data,
+		}, append(slices.Clone(opts), pulumi.DependsOn(args.PrometheusDependsOn))...)
+		if err != nil {
+			return
+		}
+	}
+
+	// The projects and roles are provisioned as the dashboards are
+	if args.Access != nil {
+		var docs map[string]string
+		docs, err = renderPersesAccess(args.Access)
+		if err != nil {
+			return
+		}
+		prs.access, err = corev1.NewConfigMap(ctx, "access", &corev1.ConfigMapArgs{
+			Metadata: v1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels:    labels,
 			},
-			"config": pulumi.Map{
-				"provisioning": pulumi.Map{
-					// During bootstrap we intensively deploy things, so we need faster than default 10m
-					"interval": pulumi.String("1m"),
+			Data: pulumi.ToStringMap(docs),
+		}, opts...)
+		if err != nil {
+			return
+		}
+	}
+
+	// The chart does not define a PodDisruptionBudget
+	if args.DisruptionBudget != nil {
+		prs.pdb, err = policyv1.NewPodDisruptionBudget(ctx, "perses", &policyv1.PodDisruptionBudgetArgs{
+			Metadata: v1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels: pulumi.StringMap{
+					"app.kubernetes.io/component": pulumi.String("perses"),
+					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+					"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 				},
 			},
-		},
-	}, opts...)
-	if err != nil {
-		return
+			Spec: disruptionBudgetSpec(args.DisruptionBudget, persesPodLabels(prs.chart)),
+		}, opts...)
+		if err != nil {
+			return
+		}
 	}
 
-	prs.globalDS, err = corev1.NewConfigMap(ctx, "global-datasource", &corev1.ConfigMapArgs{
-		Metadata: v1.ObjectMetaArgs{
-			Namespace: args.Namespace,
-			Labels: pulumi.StringMap{
-				"app.kubernetes.io/name":      pulumi.String("perses"),
-				"app.kubernetes.io/component": pulumi.String("perses"),
-				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
-				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
-				"perses.dev/resource":         pulumi.String("true"), // Get discovered by Perses
+	if args.Bootstrap != nil {
+		if err = prs.provisionBootstrap(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// PersesScrapeTarget is the Prometheus scrape target of the Perses own
+// metrics, through the Service of its release.
+func PersesScrapeTarget() ScrapeTarget {
+	return ScrapeTarget{
+		JobName: "perses",
+		Service: persesReleaseName,
+		Port:    PersesPort,
+	}
+}
+
+// persesGlobalDatasourceData returns the documents of the global datasource
+// ConfigMap, with its global secret when Prometheus requires the basic auth.
+func persesGlobalDatasourceData(args *PersesArgs) (pulumi.StringMap, error) {
+	basicAuth := args.PrometheusBasicAuth != nil
+	data := pulumi.StringMap{
+		"global-datasource.json": args.PrometheusURL.ToStringOutput().ApplyT(func(url string) (string, error) {
+			return renderPersesGlobalDatasource(url, basicAuth)
+		}).(pulumi.StringOutput),
+	}
+	if basicAuth {
+		doc, err := renderPersesGlobalSecret(args.PrometheusBasicAuth.Username)
+		if err != nil {
+			return nil, err
+		}
+		data["global-secret.json"] = pulumi.String(doc)
+	}
+	return data, nil
+}
+
+// renderPersesGlobalDatasource renders the manifest of the default global
+// datasource, pointing to a Prometheus-compatible query-able endpoint.
+// With the basic auth, the queries go through the Perses proxy which
+// authenticates them with the global secret, else the browser sends them
+// directly.
+// References:
+// - https://perses.dev/perses/docs/api/datasource/
+// - https://perses.dev/plugins/docs/prometheus/model/#prometheusdatasource
+func renderPersesGlobalDatasource(prometheusURL string, basicAuth bool) (string, error) {
+	spec := map[string]any{
+		"directUrl": prometheusURL,
+	}
+	if basicAuth {
+		spec = map[string]any{
+			"proxy": map[string]any{
+				"kind": "HTTPProxy",
+				"spec": map[string]any{
+					"url":    prometheusURL,
+					"secret": persesPrometheusSecretName,
+				},
 			},
+		}
+	}
+	return marshalDocument(map[string]any{
+		"kind": "GlobalDatasource",
+		"metadata": map[string]any{
+			"name": persesGlobalDatasourceName,
 		},
-		Data: pulumi.StringMap{
-			"global-datasource.json": func() pulumi.StringOutput {
-				// Build the manifest to configure the default global datasource, pointing to
-				// a Prometheus-compatible query-able endpoint.
-				// References:
-				// - https://perses.dev/perses/docs/api/datasource/
-				// - https://perses.dev/plugins/docs/prometheus/model/#prometheusdatasource
-				return pulumi.Map{
-					"kind": pulumi.String("GlobalDatasource"),
-					"metadata": pulumi.Map{
-						"name": pulumi.String("prometheus-datasource"),
-					},
-					"spec": pulumi.Map{
-						"default": pulumi.Bool(true),
-						"plugin": pulumi.Map{
-							"kind": pulumi.String("PrometheusDatasource"),
-							"spec": pulumi.Map{
-								"directUrl": args.PrometheusURL,
-							},
-						},
-					},
-				}.ToMapOutput().ApplyT(func(data any) string {
-					b, err := json.Marshal(data)
-					if err != nil {
-						panic(err) // should not happen, we control all this
-					}
-					return string(b)
-				}).(pulumi.StringOutput)
-			}(),
+		"spec": map[string]any{
+			"default": true,
+			"plugin": map[string]any{
+				"kind": "PrometheusDatasource",
+				"spec": spec,
+			},
 		},
-	}, opts...)
-	if err != nil {
-		return
-	}
+	}, false)
+}
 
-	return
+// renderPersesGlobalSecret renders the manifest of the global secret
+// authenticating the global datasource, reading the password from the file
+// mounted in the Perses pods.
+// Reference: https://perses.dev/perses/docs/api/secret/
+func renderPersesGlobalSecret(username string) (string, error) {
+	return marshalDocument(map[string]any{
+		"kind": "GlobalSecret",
+		"metadata": map[string]any{
+			"name": persesPrometheusSecretName,
+		},
+		"spec": map[string]any{
+			"basicAuth": map[string]any{
+				"username":     username,
+				"passwordFile": path.Join(persesPrometheusPasswordDir, BasicAuthPasswordKey),
+			},
+		},
+	}, false)
 }
 
-func (prs *Perses) outputs(ctx *pulumi.Context) error {
-	prs.PodLabels = prs.chart.Resources.ApplyT(func(res []any) (labels pulumi.StringMapOutput) {
+// persesResources returns the chart values of the Perses container
+// resources, empty ones keeping the chart defaults.
+func persesResources(res corev1.ResourceRequirementsInput) pulumi.Input {
+	if res == nil {
+		return pulumi.Map{}
+	}
+	return res
+}
+
+// persesPodLabels returns the labels of the Perses pods, as defined by the
+// chart StatefulSet.
+func persesPodLabels(chart *helmv4.Chart) pulumi.StringMapOutput {
+	return chart.Resources.ApplyT(func(res []any) (labels pulumi.StringMapOutput) {
 		for _, r := range res {
-			svc, ok := r.(*appsv1.StatefulSet)
+			sts, ok := r.(*appsv1.StatefulSet)
 			if !ok {
 				continue
 			}
-			return svc.Spec.Template().Metadata().Labels()
+			return sts.Spec.Template().Metadata().Labels()
 		}
 		return
 	}).(pulumi.StringMapOutput)
+}
+
+// checkDisruptionBudget validates the disruption budget, if any, could be
+// honored by the given number of replicas.
+func checkDisruptionBudget(pdb *DisruptionBudgetArgs, replicas int) error {
+	if pdb == nil {
+		return nil
+	}
+	switch {
+	case pdb.MinAvailable < 0 || pdb.MaxUnavailable < 0:
+		return errors.New("min available and max unavailable could not be negative")
+	case (pdb.MinAvailable == 0) == (pdb.MaxUnavailable == 0):
+		return errors.New("either min available or max unavailable is required")
+	case replicas < 2:
+		return errors.New("requires more than one replica, else the pod could never be evicted")
+	case pdb.MinAvailable >= replicas:
+		return fmt.Errorf("min available %d requires more than %d replicas, else the pods could never be evicted", pdb.MinAvailable, replicas)
+	}
+	return nil
+}
+
+// disruptionBudgetSpec returns the PodDisruptionBudget spec of the pods
+// matching the labels.
+func disruptionBudgetSpec(pdb *DisruptionBudgetArgs, podLabels pulumi.StringMapInput) policyv1.PodDisruptionBudgetSpecArgs {
+	spec := policyv1.PodDisruptionBudgetSpecArgs{
+		Selector: v1.LabelSelectorArgs{
+			MatchLabels: podLabels,
+		},
+	}
+	if pdb.MinAvailable != 0 {
+		spec.MinAvailable = pulumi.Int(pdb.MinAvailable)
+	} else {
+		spec.MaxUnavailable = pulumi.Int(pdb.MaxUnavailable)
+	}
+	return spec
+}
+
+// persesServiceName returns the name of the Service exposing the Perses UI,
+// as defined by the chart.
+func persesServiceName(chart *helmv4.Chart) pulumi.StringOutput {
+	return chart.Resources.ApplyT(func(res []any) pulumi.StringOutput {
+		for _, r := range res {
+			svc, ok := r.(*corev1.Service)
+			if !ok {
+				continue
+			}
+			return svc.Metadata.Name().Elem()
+		}
+		// Resolve rather than hang whatever depends on it, should not happen
+		return pulumi.String("").ToStringOutput()
+	}).(pulumi.StringOutput)
+}
+
+// chartRolledOut resolves to true once all the Deployments and StatefulSets
+// among the chart resources rolled out.
+func chartRolledOut(res []any) pulumi.BoolOutput {
+	ready := []any{}
+	for _, r := range res {
+		switch w := r.(type) {
+		case *appsv1.Deployment:
+			ready = append(ready, rolledOut(w.Spec.Replicas(), w.Status.ReadyReplicas()))
+		case *appsv1.StatefulSet:
+			ready = append(ready, rolledOut(w.Spec.Replicas(), w.Status.ReadyReplicas()))
+		}
+	}
+	return pulumi.All(ready...).ApplyT(func(all []any) bool {
+		for _, r := range all {
+			if !r.(bool) {
+				return false
+			}
+		}
+		return true
+	}).(pulumi.BoolOutput)
+}
+
+func (prs *Perses) outputs(ctx *pulumi.Context) error {
+	prs.PodLabels = persesPodLabels(prs.chart)
+	prs.ServiceName = persesServiceName(prs.chart)
+	prs.BootstrapPodLabels = pulumi.StringMap{}.ToStringMapOutput()
+
+	prs.Discovery = persesDashboardDiscovery
+
+	// The bootstrap Job is awaited, so it resolves once completed
+	prs.Ready = prs.chart.Resources.ApplyT(chartRolledOut).(pulumi.BoolOutput)
+	if prs.bootstrap != nil {
+		prs.BootstrapPodLabels = persesBootstrapPodLabels(ctx).ToStringMapOutput()
+		prs.Ready = pulumi.All(prs.Ready, prs.bootstrap.Metadata.Name()).ApplyT(func(all []any) bool {
+			return all[0].(bool)
+		}).(pulumi.BoolOutput)
+	}
 
 	return ctx.RegisterResourceOutputs(prs, pulumi.Map{
-		"podLabels": prs.PodLabels,
+		"podLabels":          prs.PodLabels,
+		"bootstrapPodLabels": prs.BootstrapPodLabels,
+		"serviceName":        prs.ServiceName,
+		"discovery":          prs.Discovery.ToMap(),
+		"ready":              prs.Ready,
 	})
 }
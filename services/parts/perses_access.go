@@ -0,0 +1,176 @@
+package parts
+
+import (
+	"regexp"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// PersesAccessArgs turns on the Perses authentication, with native users,
+	// and provisions the projects, roles and role bindings of the event.
+	// The users sign up under the names listed, and get the permissions of
+	// their role.
+	PersesAccessArgs struct {
+		// Organizers edit the dashboards and read everything.
+		Organizers []string
+
+		// Spectators only read the dashboards of the PublicProject.
+		Spectators []string
+
+		// PublicProject is the project the spectators read.
+		// Defaults to "public".
+		PublicProject string
+	}
+)
+
+const (
+	defaultPersesPublicProject = "public"
+
+	// PersesOrganizerRole and PersesSpectatorRole are the names of the roles
+	// provisioned by the PersesAccessArgs.
+	PersesOrganizerRole = "organizer"
+	PersesSpectatorRole = "spectator"
+)
+
+// persesNameRegex is the format of the Perses resources names, users included.
+var persesNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// checkPersesAccess validates the users and project are valid Perses names,
+// and that someone could edit the dashboards.
+func checkPersesAccess(access *PersesAccessArgs) (merr error) {
+	if len(access.Organizers) == 0 {
+		merr = multierr.Append(merr, errors.New("perses access requires at least one organizer"))
+	}
+	if !persesNameRegex.MatchString(access.PublicProject) {
+		merr = multierr.Append(merr, errors.Errorf("invalid perses public project %q", access.PublicProject))
+	}
+	for role, users := range map[string][]string{
+		PersesOrganizerRole: access.Organizers,
+		PersesSpectatorRole: access.Spectators,
+	} {
+		seen := map[string]struct{}{}
+		for _, user := range users {
+			if !persesNameRegex.MatchString(user) {
+				merr = multierr.Append(merr, errors.Errorf("invalid perses %s user %q", role, user))
+			}
+			if _, ok := seen[user]; ok {
+				merr = multierr.Append(merr, errors.Errorf("duplicated perses %s user %q", role, user))
+			}
+			seen[user] = struct{}{}
+		}
+	}
+	return
+}
+
+// renderPersesAccess renders the Perses resources of the access, by file name
+// for the sidecar to provision them. The bindings without users are omitted.
+// References:
+// - https://perses.dev/perses/docs/api/project/
+// - https://perses.dev/perses/docs/api/role/
+// - https://perses.dev/perses/docs/api/rolebinding/
+func renderPersesAccess(access *PersesAccessArgs) (map[string]string, error) {
+	docs := map[string]map[string]any{
+		"project-" + access.PublicProject + ".json": {
+			"kind": "Project",
+			"metadata": map[string]any{
+				"name": access.PublicProject,
+			},
+			"spec": map[string]any{},
+		},
+		// Organizers read everything, and edit the dashboards of any project
+		"globalrole-" + PersesOrganizerRole + ".json": {
+			"kind": "GlobalRole",
+			"metadata": map[string]any{
+				"name": PersesOrganizerRole,
+			},
+			"spec": map[string]any{
+				"permissions": []any{
+					map[string]any{
+						"actions": []string{"read"},
+						"scopes":  []string{"*"},
+					},
+					map[string]any{
+						"actions": []string{"create", "update", "delete"},
+						"scopes":  []string{"Dashboard", "Folder", "Variable"},
+					},
+				},
+			},
+		},
+		// Spectators query through the global datasource...
+		"globalrole-" + PersesSpectatorRole + ".json": {
+			"kind": "GlobalRole",
+			"metadata": map[string]any{
+				"name": PersesSpectatorRole,
+			},
+			"spec": map[string]any{
+				"permissions": []any{
+					map[string]any{
+						"actions": []string{"read"},
+						"scopes":  []string{"GlobalDatasource"},
+					},
+				},
+			},
+		},
+		// ...but only read the public project
+		"role-" + PersesSpectatorRole + ".json": {
+			"kind": "Role",
+			"metadata": map[string]any{
+				"name":    PersesSpectatorRole,
+				"project": access.PublicProject,
+			},
+			"spec": map[string]any{
+				"permissions": []any{
+					map[string]any{
+						"actions": []string{"read"},
+						"scopes":  []string{"Project", "Dashboard", "Datasource", "Variable"},
+					},
+				},
+			},
+		},
+	}
+	if len(access.Organizers) != 0 {
+		docs["globalrolebinding-"+PersesOrganizerRole+".json"] = persesBinding("GlobalRoleBinding", PersesOrganizerRole, "", access.Organizers)
+	}
+	if len(access.Spectators) != 0 {
+		docs["globalrolebinding-"+PersesSpectatorRole+".json"] = persesBinding("GlobalRoleBinding", PersesSpectatorRole, "", access.Spectators)
+		docs["rolebinding-"+PersesSpectatorRole+".json"] = persesBinding("RoleBinding", PersesSpectatorRole, access.PublicProject, access.Spectators)
+	}
+
+	out := make(map[string]string, len(docs))
+	for name, doc := range docs {
+		str, err := marshalDocument(doc, false)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = str
+	}
+	return out, nil
+}
+
+// persesBinding renders a (global) role binding of the users to the role,
+// in the project if any.
+func persesBinding(kind, role, project string, users []string) map[string]any {
+	metadata := map[string]any{
+		"name": role,
+	}
+	if project != "" {
+		metadata["project"] = project
+	}
+	subjects := make([]any, 0, len(users))
+	for _, user := range users {
+		subjects = append(subjects, map[string]any{
+			"kind": "User",
+			"name": user,
+		})
+	}
+	return map[string]any{
+		"kind":     kind,
+		"metadata": metadata,
+		"spec": map[string]any{
+			"role":     role,
+			"subjects": subjects,
+		},
+	}
+}
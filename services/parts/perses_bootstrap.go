@@ -0,0 +1,151 @@
+package parts
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	v1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+type (
+	// PersesBootstrapArgs configures the Job awaiting Perses to serve the
+	// global datasource, rather than leaving the dashboards broken until the
+	// next provisioning interval of the sidecar import.
+	PersesBootstrapArgs struct {
+		// Timeout after which the Job fails, and so does the update.
+		// Defaults to 5m.
+		Timeout time.Duration
+	}
+)
+
+const (
+	defaultPersesBootstrapTimeout = 5 * time.Minute
+
+	// persesBootstrapImage is the image of the bootstrap Job, which only
+	// needs a shell and wget.
+	persesBootstrapImage = "library/busybox:1.37.0"
+
+	// persesBootstrapScript polls the Perses API until it answers, then until
+	// it serves the global datasource pointing to Prometheus. As the API
+	// requires to authenticate with the access, it then only waits for it.
+	persesBootstrapScript = `until wget -q -O /dev/null "$PERSES_URL/api/v1/health"; do sleep 2; done
+echo "Perses API answers"
+[ "$CHECK_DATASOURCE" = "true" ] || exit 0
+until wget -q -O - "$PERSES_URL/api/v1/globaldatasources/$DATASOURCE_NAME" 2>/dev/null | grep -qF "$DATASOURCE_URL"; do sleep 2; done
+echo "Global datasource $DATASOURCE_NAME registered"
+`
+)
+
+func checkPersesBootstrap(bootstrap *PersesBootstrapArgs) error {
+	if bootstrap.Timeout < 0 {
+		return errors.New("timeout could not be negative")
+	}
+	if bootstrap.Timeout%time.Second != 0 {
+		return errors.Errorf("timeout %s must be a whole number of seconds", bootstrap.Timeout)
+	}
+	return nil
+}
+
+// provisionBootstrap runs the Job awaiting the global datasource to be
+// served, once the chart and the datasource are created. The provider awaits
+// it until it completes, failing the update once the timeout elapsed.
+// The Job is replaced when the Prometheus URL changes, for the new datasource
+// to be awaited.
+func (prs *Perses) provisionBootstrap(
+	ctx *pulumi.Context,
+	args *PersesArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	deps := []pulumi.Resource{prs.chart}
+	if prs.globalDS != nil {
+		deps = append(deps, prs.globalDS)
+	}
+	if prs.access != nil {
+		deps = append(deps, prs.access)
+	}
+	// The provider would give up awaiting the Job before it fails
+	timeouts := (args.Bootstrap.Timeout + time.Minute).String()
+
+	prs.bootstrap, err = batchv1.NewJob(ctx, "perses-bootstrap", &batchv1.JobArgs{
+		Metadata: v1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("perses"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		Spec: batchv1.JobSpecArgs{
+			BackoffLimit:          pulumi.Int(0),
+			ActiveDeadlineSeconds: pulumi.Int(int(args.Bootstrap.Timeout / time.Second)),
+			Template: corev1.PodTemplateSpecArgs{
+				Metadata: v1.ObjectMetaArgs{
+					Labels:      persesBootstrapPodLabels(ctx),
+					Annotations: meshAnnotations(nil, args.Mesh.JobAnnotations()),
+				},
+				Spec: corev1.PodSpecArgs{
+					RestartPolicy: pulumi.String("Never"),
+					Containers: corev1.ContainerArray{
+						corev1.ContainerArgs{
+							Name:  pulumi.String("bootstrap"),
+							Image: pulumi.Sprintf("%s/%s", args.registry, persesBootstrapImage),
+							Command: pulumi.ToStringArray([]string{
+								"/bin/sh", "-c", persesBootstrapScript,
+							}),
+							Env: corev1.EnvVarArray{
+								corev1.EnvVarArgs{
+									Name:  pulumi.String("PERSES_URL"),
+									Value: pulumi.Sprintf("http://%s:%d", persesServiceName(prs.chart), PersesPort),
+								},
+								corev1.EnvVarArgs{
+									Name:  pulumi.String("CHECK_DATASOURCE"),
+									Value: pulumi.String(fmt.Sprint(args.Access == nil && !args.DisableDatasource)),
+								},
+								corev1.EnvVarArgs{
+									Name:  pulumi.String("DATASOURCE_NAME"),
+									Value: pulumi.String(persesGlobalDatasourceName),
+								},
+								corev1.EnvVarArgs{
+									Name:  pulumi.String("DATASOURCE_URL"),
+									Value: args.PrometheusURL,
+								},
+							},
+							// The UID is left to the image, or to the SCC on
+							// OpenShift, nothing else is granted
+							SecurityContext: corev1.SecurityContextArgs{
+								AllowPrivilegeEscalation: pulumi.Bool(false),
+								ReadOnlyRootFilesystem:   pulumi.Bool(true),
+								Capabilities: corev1.CapabilitiesArgs{
+									Drop: pulumi.ToStringArray([]string{"ALL"}),
+								},
+								SeccompProfile: corev1.SeccompProfileArgs{
+									Type: pulumi.String("RuntimeDefault"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, append(slices.Clone(opts),
+		pulumi.DependsOn(deps),
+		pulumi.Timeouts(&pulumi.CustomTimeouts{Create: timeouts, Update: timeouts}),
+	)...)
+	return
+}
+
+// persesBootstrapPodLabels returns the labels of the bootstrap Job pod.
+func persesBootstrapPodLabels(ctx *pulumi.Context) pulumi.StringMap {
+	return pulumi.StringMap{
+		"app.kubernetes.io/name":      pulumi.String("perses-bootstrap"),
+		"app.kubernetes.io/component": pulumi.String("perses"),
+		"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+		"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+	}
+}
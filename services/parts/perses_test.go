@@ -0,0 +1,682 @@
+package parts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Perses_DashboardDiscovery(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	var discovery DashboardDiscovery
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		prs, err := NewPerses(ctx, "perses", &PersesArgs{
+			Namespace:     pulumi.String("monitoring"),
+			PrometheusURL: pulumi.String("http://prometheus:9090"),
+		})
+		if err != nil {
+			return err
+		}
+		discovery = prs.Discovery
+
+		_, err = NewDashboard(ctx, "challenges", &DashboardArgs{
+			Namespace: pulumi.String("challenges"),
+			Discovery: &prs.Discovery,
+			Dashboard: pulumi.String(`{"kind":"Dashboard"}`),
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The exported contract is the one the sidecar is configured with
+	chart := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")
+	if chart == nil {
+		t.Fatal("perses chart not found")
+	}
+	sidecar := chart["values"].ObjectValue()["sidecar"].ObjectValue()
+	if got := sidecar["label"].StringValue(); got != discovery.LabelKey {
+		t.Errorf("expected sidecar label %s, got %s", discovery.LabelKey, got)
+	}
+	if got := sidecar["labelValue"].StringValue(); got != discovery.LabelValue {
+		t.Errorf("expected sidecar label value %s, got %s", discovery.LabelValue, got)
+	}
+	if got := sidecar["allNamespaces"].BoolValue(); got != discovery.AllNamespaces {
+		t.Errorf("expected sidecar all namespaces %t, got %t", discovery.AllNamespaces, got)
+	}
+
+	// The global datasource and dashboards are labeled for it
+	for _, name := range []string{"global-datasource", "challenges"} {
+		cm := m.ByName("kubernetes:core/v1:ConfigMap", name)
+		if cm == nil {
+			t.Fatalf("configmap %s not found", name)
+		}
+		labels := cm["metadata"].ObjectValue()["labels"].ObjectValue()
+		if got := labels[resource.PropertyKey(discovery.LabelKey)]; !got.IsString() || got.StringValue() != discovery.LabelValue {
+			t.Errorf("configmap %s: expected label %s=%s, got %v", name, discovery.LabelKey, discovery.LabelValue, labels)
+		}
+	}
+}
+
+func Test_U_NewDashboard_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *DashboardArgs
+		ExpectErr bool
+	}{
+		"default-discovery": {
+			Args: &DashboardArgs{
+				Dashboard: pulumi.String(`{"kind":"Dashboard"}`),
+			},
+		},
+		"no-dashboard": {
+			Args:      &DashboardArgs{},
+			ExpectErr: true,
+		},
+		"no-label-key": {
+			Args: &DashboardArgs{
+				Discovery: &DashboardDiscovery{},
+				Dashboard: pulumi.String(`{"kind":"Dashboard"}`),
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewDashboard(ctx, "dashboard", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Perses_Sizing(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args                   *PersesArgs
+		ExpectedReplicas       float64
+		ExpectedCPU            string
+		ExpectedMinAvailable   float64
+		ExpectedMaxUnavailable float64
+		ExpectErr              bool
+	}{
+		"defaults": {
+			Args:             &PersesArgs{},
+			ExpectedReplicas: 1,
+		},
+		"sized": {
+			Args: &PersesArgs{
+				Replicas: 3,
+				Resources: corev1.ResourceRequirementsArgs{
+					Requests: pulumi.StringMap{
+						"cpu":    pulumi.String("50m"),
+						"memory": pulumi.String("128Mi"),
+					},
+				},
+				DisruptionBudget: &DisruptionBudgetArgs{
+					MaxUnavailable: 1,
+				},
+			},
+			ExpectedReplicas:       3,
+			ExpectedCPU:            "50m",
+			ExpectedMaxUnavailable: 1,
+		},
+		"min-available": {
+			Args: &PersesArgs{
+				Replicas: 2,
+				DisruptionBudget: &DisruptionBudgetArgs{
+					MinAvailable: 1,
+				},
+			},
+			ExpectedReplicas:     2,
+			ExpectedMinAvailable: 1,
+		},
+		"pdb-single-replica": {
+			Args: &PersesArgs{
+				DisruptionBudget: &DisruptionBudgetArgs{
+					MaxUnavailable: 1,
+				},
+			},
+			ExpectErr: true,
+		},
+		"pdb-blocks-evictions": {
+			Args: &PersesArgs{
+				Replicas: 2,
+				DisruptionBudget: &DisruptionBudgetArgs{
+					MinAvailable: 2,
+				},
+			},
+			ExpectErr: true,
+		},
+		"pdb-both": {
+			Args: &PersesArgs{
+				Replicas: 3,
+				DisruptionBudget: &DisruptionBudgetArgs{
+					MinAvailable:   1,
+					MaxUnavailable: 1,
+				},
+			},
+			ExpectErr: true,
+		},
+		"pdb-empty": {
+			Args: &PersesArgs{
+				Replicas:         3,
+				DisruptionBudget: &DisruptionBudgetArgs{},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args := tt.Args
+				args.Namespace = pulumi.String("monitoring")
+				args.PrometheusURL = pulumi.String("http://prometheus:9090")
+				_, err := NewPerses(ctx, "perses", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			values := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()
+			if got := values["replicas"].NumberValue(); got != tt.ExpectedReplicas {
+				t.Errorf("expected %v replicas, got %v", tt.ExpectedReplicas, got)
+			}
+			cpu := ""
+			if requests, ok := values["resources"].ObjectValue()["requests"]; ok {
+				cpu = requests.ObjectValue()["cpu"].StringValue()
+			}
+			if cpu != tt.ExpectedCPU {
+				t.Errorf("expected cpu request %q, got %q", tt.ExpectedCPU, cpu)
+			}
+
+			pdb := m.ByName("kubernetes:policy/v1:PodDisruptionBudget", "perses")
+			if (pdb != nil) != (tt.Args.DisruptionBudget != nil) {
+				t.Fatalf("expected pdb %t, got %v", tt.Args.DisruptionBudget != nil, pdb)
+			}
+			if pdb == nil {
+				return
+			}
+			spec := pdb["spec"].ObjectValue()
+			if v, ok := spec["minAvailable"]; (ok && v.NumberValue() != tt.ExpectedMinAvailable) || (!ok && tt.ExpectedMinAvailable != 0) {
+				t.Errorf("expected min available %v, got %v", tt.ExpectedMinAvailable, v)
+			}
+			if v, ok := spec["maxUnavailable"]; (ok && v.NumberValue() != tt.ExpectedMaxUnavailable) || (!ok && tt.ExpectedMaxUnavailable != 0) {
+				t.Errorf("expected max unavailable %v, got %v", tt.ExpectedMaxUnavailable, v)
+			}
+		})
+	}
+}
+
+func Test_U_Perses_PrometheusBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args               *PersesArgs
+		ExpectedSecretName string
+		ExpectedDocs       []string
+		ExpectErr          bool
+	}{
+		"direct": {
+			Args:         &PersesArgs{},
+			ExpectedDocs: []string{"global-datasource.json"},
+		},
+		"basic-auth": {
+			Args: &PersesArgs{
+				PrometheusBasicAuth: &BasicAuthArgs{
+					Username:           PrometheusBasicAuthUsername,
+					PasswordSecretName: pulumi.String("prometheus-credentials"),
+				},
+			},
+			ExpectedSecretName: "prometheus-credentials",
+			ExpectedDocs:       []string{"global-datasource.json", "global-secret.json"},
+		},
+		"no-datasource": {
+			Args: &PersesArgs{
+				DisableDatasource: true,
+				PrometheusBasicAuth: &BasicAuthArgs{
+					Username:           PrometheusBasicAuthUsername,
+					PasswordSecretName: pulumi.String("prometheus-credentials"),
+				},
+			},
+		},
+		"no-username": {
+			Args: &PersesArgs{
+				PrometheusBasicAuth: &BasicAuthArgs{
+					PasswordSecretName: pulumi.String("prometheus-credentials"),
+				},
+			},
+			ExpectErr: true,
+		},
+		"no-password-secret": {
+			Args: &PersesArgs{
+				PrometheusBasicAuth: &BasicAuthArgs{
+					Username: PrometheusBasicAuthUsername,
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args := tt.Args
+				args.Namespace = pulumi.String("monitoring")
+				args.PrometheusURL = pulumi.String("http://prometheus:9090")
+				_, err := NewPerses(ctx, "perses", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// The password is mounted from its Secret
+			values := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()
+			secretName := ""
+			if volumes, ok := values["volumes"]; ok {
+				secretName = volumes.ArrayValue()[0].ObjectValue()["secret"].ObjectValue()["secretName"].StringValue()
+			}
+			if secretName != tt.ExpectedSecretName {
+				t.Errorf("expected the password secret %q, got %q", tt.ExpectedSecretName, secretName)
+			}
+
+			ds := m.ByName("kubernetes:core/v1:ConfigMap", "global-datasource")
+			if ds == nil {
+				if len(tt.ExpectedDocs) != 0 {
+					t.Fatal("expected the global datasource")
+				}
+				return
+			}
+			docs := []string{}
+			for k := range ds["data"].ObjectValue() {
+				docs = append(docs, string(k))
+			}
+			slices.Sort(docs)
+			if !slices.Equal(docs, tt.ExpectedDocs) {
+				t.Errorf("expected the documents %v, got %v", tt.ExpectedDocs, docs)
+			}
+
+			// Proxied queries only, the browser could not authenticate
+			datasource := ds["data"].ObjectValue()["global-datasource.json"].StringValue()
+			if proxied := strings.Contains(datasource, `"proxy"`); proxied != (tt.ExpectedSecretName != "") {
+				t.Errorf("expected the datasource proxied: %t, got %s", tt.ExpectedSecretName != "", datasource)
+			}
+		})
+	}
+}
+
+func Test_U_Perses_Access(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Access       *PersesAccessArgs
+		ExpectedDocs []string
+		ExpectErr    bool
+	}{
+		"anonymous": {},
+		"organizers": {
+			Access: &PersesAccessArgs{
+				Organizers: []string{"alice"},
+			},
+			ExpectedDocs: []string{
+				"globalrole-organizer.json",
+				"globalrole-spectator.json",
+				"globalrolebinding-organizer.json",
+				"project-public.json",
+				"role-spectator.json",
+			},
+		},
+		"spectators": {
+			Access: &PersesAccessArgs{
+				Organizers:    []string{"alice"},
+				Spectators:    []string{"bob", "carol"},
+				PublicProject: "ctf-2026",
+			},
+			ExpectedDocs: []string{
+				"globalrole-organizer.json",
+				"globalrole-spectator.json",
+				"globalrolebinding-organizer.json",
+				"globalrolebinding-spectator.json",
+				"project-ctf-2026.json",
+				"role-spectator.json",
+				"rolebinding-spectator.json",
+			},
+		},
+		"no-organizer": {
+			Access: &PersesAccessArgs{
+				Spectators: []string{"bob"},
+			},
+			ExpectErr: true,
+		},
+		"invalid-user": {
+			Access: &PersesAccessArgs{
+				Organizers: []string{"alice smith"},
+			},
+			ExpectErr: true,
+		},
+		"duplicated-user": {
+			Access: &PersesAccessArgs{
+				Organizers: []string{"alice"},
+				Spectators: []string{"bob", "bob"},
+			},
+			ExpectErr: true,
+		},
+		"invalid-project": {
+			Access: &PersesAccessArgs{
+				Organizers:    []string{"alice"},
+				PublicProject: "ctf/2026",
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPerses(ctx, "perses", &PersesArgs{
+					Namespace:     pulumi.String("monitoring"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					Access:        tt.Access,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// The authentication is only turned on along the roles
+			config := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()["config"].ObjectValue()
+			security, auth := config["security"]
+			if auth != (tt.Access != nil) {
+				t.Errorf("expected the authentication: %t, got %v", tt.Access != nil, security)
+			}
+			if auth && !security.ObjectValue()["enable_auth"].BoolValue() {
+				t.Errorf("expected the authentication to be enabled, got %v", security)
+			}
+
+			// The documents are discovered as the dashboards are
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "access")
+			if tt.Access == nil {
+				if cm != nil {
+					t.Errorf("expected no access ConfigMap, got %v", cm)
+				}
+				return
+			}
+			labels := cm["metadata"].ObjectValue()["labels"].ObjectValue()
+			if got := labels[resource.PropertyKey(persesDashboardDiscovery.LabelKey)]; !got.IsString() || got.StringValue() != persesDashboardDiscovery.LabelValue {
+				t.Errorf("expected the access ConfigMap to be discovered, got %v", labels)
+			}
+			docs := []string{}
+			for k := range cm["data"].ObjectValue() {
+				docs = append(docs, string(k))
+			}
+			slices.Sort(docs)
+			if !slices.Equal(docs, tt.ExpectedDocs) {
+				t.Errorf("expected documents %v, got %v", tt.ExpectedDocs, docs)
+			}
+		})
+	}
+}
+
+func Test_U_Perses_AccessDocuments(t *testing.T) {
+	t.Parallel()
+
+	docs, err := renderPersesAccess(&PersesAccessArgs{
+		Organizers:    []string{"alice"},
+		Spectators:    []string{"bob", "carol"},
+		PublicProject: "public",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join("testdata", "perses-access.golden.json"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := json.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	got := map[string]any{}
+	for name, doc := range docs {
+		var v any
+		if err := json.Unmarshal([]byte(doc), &v); err != nil {
+			t.Fatalf("invalid document %s: %s", name, err)
+		}
+		got[name] = v
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the golden documents, got %v", got)
+	}
+}
+
+func Test_U_Perses_Bootstrap(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Bootstrap        *PersesBootstrapArgs
+		Access           *PersesAccessArgs
+		Failing          string
+		ExpectErr        bool
+		ExpectedDeadline float64
+		ExpectedCheck    string
+	}{
+		"disabled": {},
+		"default-timeout": {
+			Bootstrap:        &PersesBootstrapArgs{},
+			ExpectedDeadline: 300,
+			ExpectedCheck:    "true",
+		},
+		"custom-timeout": {
+			Bootstrap:        &PersesBootstrapArgs{Timeout: 10 * time.Minute},
+			ExpectedDeadline: 600,
+			ExpectedCheck:    "true",
+		},
+		"access": {
+			Bootstrap: &PersesBootstrapArgs{},
+			Access: &PersesAccessArgs{
+				Organizers: []string{"alice"},
+			},
+			ExpectedDeadline: 300,
+			ExpectedCheck:    "false",
+		},
+		"timed-out": {
+			Bootstrap: &PersesBootstrapArgs{},
+			Failing:   "Job has reached the specified deadline",
+			ExpectErr: true,
+		},
+		"negative-timeout": {
+			Bootstrap: &PersesBootstrapArgs{Timeout: -time.Minute},
+			ExpectErr: true,
+		},
+		"sub-second-timeout": {
+			Bootstrap: &PersesBootstrapArgs{Timeout: 1500 * time.Millisecond},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			if tt.Failing != "" {
+				m.Failing = map[string]string{
+					"perses-bootstrap": tt.Failing,
+				}
+			}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPerses(ctx, "perses", &PersesArgs{
+					Namespace:     pulumi.String("monitoring"),
+					PrometheusURL: pulumi.String("http://prometheus:9090"),
+					Access:        tt.Access,
+					Bootstrap:     tt.Bootstrap,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			// The Job failure fails the update
+			if tt.Failing != "" && !strings.Contains(err.Error(), tt.Failing) {
+				t.Errorf("expected the update to fail with %q, got %v", tt.Failing, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			job := m.ByName("kubernetes:batch/v1:Job", "perses-bootstrap")
+			if tt.Bootstrap == nil {
+				if job != nil {
+					t.Error("expected no bootstrap job")
+				}
+				return
+			}
+			if job == nil {
+				t.Fatal("expected a bootstrap job")
+			}
+			spec := job["spec"].ObjectValue()
+			if got := spec["activeDeadlineSeconds"].NumberValue(); got != tt.ExpectedDeadline {
+				t.Errorf("expected a %vs deadline, got %vs", tt.ExpectedDeadline, got)
+			}
+			if got := spec["backoffLimit"].NumberValue(); got != 0 {
+				t.Errorf("expected no retry of the pod, got %v", got)
+			}
+			ctr := spec["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			env := map[string]resource.PropertyValue{}
+			for _, e := range ctr["env"].ArrayValue() {
+				env[e.ObjectValue()["name"].StringValue()] = e.ObjectValue()["value"]
+			}
+			if got := env["CHECK_DATASOURCE"].StringValue(); got != tt.ExpectedCheck {
+				t.Errorf("expected the datasource check %s, got %s", tt.ExpectedCheck, got)
+			}
+			if got := env["DATASOURCE_URL"].StringValue(); got != "http://prometheus:9090" {
+				t.Errorf("expected the datasource URL to be awaited, got %s", got)
+			}
+			if got := env["DATASOURCE_NAME"].StringValue(); got != persesGlobalDatasourceName {
+				t.Errorf("expected the global datasource to be awaited, got %s", got)
+			}
+
+			// Only once the chart and the datasource are created
+			deps := m.Dependencies("kubernetes:batch/v1:Job", "perses-bootstrap")
+			for _, dep := range []string{
+				"$kubernetes:helm.sh/v4:Chart::perses",
+				"$kubernetes:core/v1:ConfigMap::global-datasource",
+			} {
+				if !slices.ContainsFunc(deps, func(urn string) bool { return strings.HasSuffix(urn, dep) }) {
+					t.Errorf("expected the job to depend on %s, got %v", dep, deps)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_ChartRolledOut(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		NotReady []string
+		Expected bool
+	}{
+		"ready": {
+			Expected: true,
+		},
+		"statefulset-not-ready": {
+			NotReady: []string{"perses"},
+			Expected: false,
+		},
+		"deployment-not-ready": {
+			NotReady: []string{"perses-proxy"},
+			Expected: false,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{
+				NotReady: tt.NotReady,
+			}
+			var got bool
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				// Mimic the workloads the chart renders
+				sts, err := appsv1.NewStatefulSet(ctx, "perses", &appsv1.StatefulSetArgs{
+					Spec: appsv1.StatefulSetSpecArgs{
+						Replicas: pulumi.Int(2),
+					},
+				})
+				if err != nil {
+					return err
+				}
+				dep, err := appsv1.NewDeployment(ctx, "perses-proxy", &appsv1.DeploymentArgs{})
+				if err != nil {
+					return err
+				}
+				chartRolledOut([]any{sts, dep}).ApplyT(func(ready bool) error {
+					defer wg.Done()
+					got = ready
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			if got != tt.Expected {
+				t.Errorf("expected ready: %t, got %t", tt.Expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package parts
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// persesCriticalValue is a Perses chart value the component depends on.
+type persesCriticalValue struct {
+	path []string
+
+	// consequence of overriding it, for the warning to explain
+	consequence string
+
+	// denied overrides are refused unless forced
+	denied bool
+}
+
+// persesCriticalValues are the Perses chart values the component depends on,
+// all set by it.
+var persesCriticalValues = []persesCriticalValue{
+	{
+		path:        []string{"sidecar", "label"},
+		consequence: "the ConfigMaps are discovered under another label than the " + persesDashboardDiscovery.LabelKey + " one, so neither the global datasource nor the dashboards are provisioned, and the perses-dashboard-discovery contract does not follow",
+		denied:      true,
+	},
+	{
+		path:        []string{"sidecar", "labelValue"},
+		consequence: "the ConfigMaps are discovered under another label value than the " + persesDashboardDiscovery.LabelValue + " one, so neither the global datasource nor the dashboards are provisioned, and the perses-dashboard-discovery contract does not follow",
+		denied:      true,
+	},
+	{
+		path:        []string{"sidecar", "enabled"},
+		consequence: "disabling the sidecar stops discovering the ConfigMaps, so neither the global datasource nor the dashboards are provisioned",
+	},
+	{
+		path:        []string{"sidecar", "allNamespaces"},
+		consequence: "restricting the sidecar to the Perses namespace stops discovering the dashboards of the other ones, e.g. the challenges",
+	},
+	{
+		path:        []string{"config", "provisioning"},
+		consequence: "the discovered resources could be provisioned later than every minute, or not at all",
+	},
+}
+
+// persesValueOverride is an extra value overriding a critical one.
+type persesValueOverride struct {
+	// Path of the extra value, dot-separated.
+	Path string
+
+	// Critical is the value it overrides, itself or one it replaces or
+	// is part of.
+	Critical persesCriticalValue
+}
+
+// Warning returns the message warning about the override.
+func (o persesValueOverride) Warning() string {
+	return fmt.Sprintf("Perses extra value %s overrides %s: %s", o.Path, strings.Join(o.Critical.path, "."), o.Critical.consequence)
+}
+
+// persesValueOverrides returns the overrides of the critical values by the
+// extra ones, in the order of the critical values then of the paths.
+func persesValueOverrides(extra map[string]any) []persesValueOverride {
+	leaves := persesValueLeaves(extra, nil)
+	overrides := []persesValueOverride{}
+	for _, critical := range persesCriticalValues {
+		for _, leaf := range leaves {
+			// The leaf replaces the critical value, or one of its parents,
+			// or is part of it.
+			if !isPathPrefix(leaf, critical.path) && !isPathPrefix(critical.path, leaf) {
+				continue
+			}
+			overrides = append(overrides, persesValueOverride{
+				Path:     strings.Join(leaf, "."),
+				Critical: critical,
+			})
+		}
+	}
+	return overrides
+}
+
+// persesValueLeaves returns the paths of the values replacing the chart ones
+// once merged, i.e. the ones that are not maps, sorted.
+func persesValueLeaves(extra map[string]any, parent []string) [][]string {
+	leaves := [][]string{}
+	for key, value := range extra {
+		path := append(slices.Clone(parent), key)
+		if sub, ok := value.(map[string]any); ok {
+			leaves = append(leaves, persesValueLeaves(sub, path)...)
+			continue
+		}
+		leaves = append(leaves, path)
+	}
+	slices.SortFunc(leaves, func(a, b []string) int {
+		return slices.Compare(a, b)
+	})
+	return leaves
+}
+
+// isPathPrefix returns whether the prefix path is, or is a parent of, the
+// path.
+func isPathPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
+
+// checkPersesExtraValues refuses the overrides of the denied critical values,
+// unless forced.
+func checkPersesExtraValues(extra map[string]any, force bool) error {
+	if force {
+		return nil
+	}
+	denied := []string{}
+	for _, o := range persesValueOverrides(extra) {
+		if o.Critical.denied {
+			denied = append(denied, o.Path)
+		}
+	}
+	if len(denied) != 0 {
+		// A replaced parent overrides several critical values
+		slices.Sort(denied)
+		denied = slices.Compact(denied)
+		return fmt.Errorf("extra values %s override the dashboard discovery label, refused unless forced", strings.Join(denied, ", "))
+	}
+	return nil
+}
+
+// mergePersesValues deep merges the extra values into the chart ones: maps
+// are merged, anything else replaces the chart value.
+func mergePersesValues(values pulumi.Map, extra map[string]any) pulumi.Map {
+	merged := pulumi.Map{}
+	for key, value := range values {
+		merged[key] = value
+	}
+	for key, value := range extra {
+		sub, ok := value.(map[string]any)
+		if !ok {
+			merged[key] = pulumi.ToOutput(value)
+			continue
+		}
+		base, ok := merged[key].(pulumi.Map)
+		if !ok {
+			// Not a map of values yet (e.g. the resources input), so
+			// replaced as a whole
+			base = pulumi.Map{}
+		}
+		merged[key] = mergePersesValues(base, sub)
+	}
+	return merged
+}
@@ -0,0 +1,111 @@
+package parts
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Perses_ExtraValues(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ExtraValues      map[string]any
+		ForceExtraValues bool
+		ExpectedWarned   []string
+		ExpectedLabel    string
+		ExpectErr        bool
+	}{
+		"none": {
+			ExpectedLabel: persesDashboardDiscovery.LabelKey,
+		},
+		"benign": {
+			ExtraValues: map[string]any{
+				"service": map[string]any{"type": "NodePort"},
+				"sidecar": map[string]any{"resources": map[string]any{}},
+				"config":  map[string]any{"frontend": map[string]any{"disable": false}},
+			},
+			ExpectedLabel: persesDashboardDiscovery.LabelKey,
+		},
+		"warned": {
+			ExtraValues: map[string]any{
+				"sidecar": map[string]any{"enabled": false},
+				"config":  map[string]any{"provisioning": map[string]any{"interval": "10m"}},
+			},
+			ExpectedWarned: []string{"sidecar.enabled", "config.provisioning.interval"},
+			ExpectedLabel:  persesDashboardDiscovery.LabelKey,
+		},
+		"refused-label": {
+			ExtraValues: map[string]any{
+				"sidecar": map[string]any{"label": "grafana_dashboard"},
+			},
+			ExpectErr: true,
+		},
+		"refused-replaced-parent": {
+			ExtraValues: map[string]any{
+				"sidecar": nil,
+			},
+			ExpectErr: true,
+		},
+		"forced-label": {
+			ExtraValues: map[string]any{
+				"sidecar": map[string]any{"label": "grafana_dashboard"},
+			},
+			ForceExtraValues: true,
+			ExpectedWarned:   []string{"sidecar.label"},
+			ExpectedLabel:    "grafana_dashboard",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			warned := []string{}
+			for _, o := range persesValueOverrides(tt.ExtraValues) {
+				warned = append(warned, o.Path)
+			}
+			if !tt.ExpectErr && !slices.Equal(warned, tt.ExpectedWarned) {
+				t.Errorf("expected warnings on %v, got %v", tt.ExpectedWarned, warned)
+			}
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPerses(ctx, "perses", &PersesArgs{
+					Namespace:        pulumi.String("monitoring"),
+					PrometheusURL:    pulumi.String("http://prometheus:9090"),
+					ExtraValues:      tt.ExtraValues,
+					ForceExtraValues: tt.ForceExtraValues,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// Merged, not replacing the other values
+			values := m.ByName("kubernetes:helm.sh/v4:Chart", "perses")["values"].ObjectValue()
+			sidecar := values["sidecar"].ObjectValue()
+			if label := sidecar["label"].StringValue(); label != tt.ExpectedLabel {
+				t.Errorf("expected sidecar label %s, got %s", tt.ExpectedLabel, label)
+			}
+			if !sidecar["allNamespaces"].BoolValue() {
+				t.Error("expected the sidecar to still watch all namespaces")
+			}
+			if values["replicas"].NumberValue() != 1 {
+				t.Errorf("expected the replicas to be kept, got %v", values["replicas"])
+			}
+			if svc, ok := tt.ExtraValues["service"]; ok {
+				if got := values["service"].ObjectValue()["type"].StringValue(); got != svc.(map[string]any)["type"] {
+					t.Errorf("expected the service type %v, got %s", svc, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package parts
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+type (
+	// PipelineSpec is a pipeline of the OTEL Collector configuration, and
+	// the components it is made of.
+	PipelineSpec struct {
+		Name       string   `json:"name"`
+		Receivers  []string `json:"receivers"`
+		Processors []string `json:"processors,omitempty"`
+		Exporters  []string `json:"exporters"`
+	}
+)
+
+// otelPipelines returns the pipelines of the OTEL Collector configuration,
+// derived from the enabled features. It is what otel-config.yaml.tmpl renders
+// in its service.
+func otelPipelines(args *OtelCollectorArgs) []PipelineSpec {
+	// Where the cold extract signals go, if any
+	coldExtract := func(signal string) []string {
+		if !args.ColdExtract {
+			return nil
+		}
+		if args.TenantRouting != nil {
+			return []string{"routing/" + signal}
+		}
+		return []string{"file/" + signal}
+	}
+
+	traces := PipelineSpec{
+		Name:      "traces",
+		Receivers: []string{"otlp"},
+		Exporters: []string{"debug", "otlp", "spanmetrics"},
+	}
+	if args.TracesFailover {
+		traces.Exporters[1] = "failover/traces"
+	}
+	metrics := PipelineSpec{
+		Name:      "metrics",
+		Receivers: []string{"otlp", "spanmetrics"},
+		Exporters: []string{"debug", prometheusExporter(args)},
+	}
+	if args.DependencyGraph {
+		traces.Exporters = append(traces.Exporters, "servicegraph")
+		metrics.Receivers = append(metrics.Receivers, "servicegraph")
+	}
+	// The metrics are converted apart for Prometheus only, and the
+	// incompatible ones counted as received
+	if args.MetricsConversion != nil {
+		metrics.Exporters = []string{"debug", prometheusForward, conversionCount}
+	}
+	logs := PipelineSpec{
+		Name:      "logs",
+		Receivers: []string{"otlp"},
+		Exporters: []string{"debug"},
+	}
+	if args.StatsdReceiver {
+		metrics.Receivers = append(metrics.Receivers, "statsd")
+	}
+	if args.SyslogReceiver != nil {
+		logs.Receivers = append(logs.Receivers, "syslog/tcp", "syslog/udp")
+	}
+	// The records are counted as received, whatever the logs become
+	if args.LogsMetrics != nil {
+		logs.Exporters = append(logs.Exporters, args.LogsMetrics.exporter())
+	}
+
+	// The cold extract signals share the pipelines, unless redacted apart
+	// from the Jaeger and Prometheus ones: they then have their own, fed by
+	// the same receivers.
+	// The memory is checked first, to refuse the data received beyond it
+	var limiter []string
+	if rl, _ := runtimeLimits(args); rl.MemoryLimitMiB != 0 {
+		limiter = []string{memoryLimiterProcessor}
+	}
+	// With quotas, the signals received are rather checked before being
+	// routed by tenant, then forwarded to the pipelines
+	pipelineLimiter := limiter
+	ingested := map[string][]string{}
+	if iq := args.IngestionQuotas; iq != nil {
+		pipelineLimiter = nil
+		for _, p := range []*PipelineSpec{&traces, &metrics, &logs} {
+			ingested[p.Name] = iq.routed(p)
+		}
+	}
+	var processors []string
+	if redactStorage(args) {
+		processors = []string{redactionProcessor}
+	}
+	split := args.ColdExtract && redactStorage(args) != redactColdExtract(args)
+	pipelines := []PipelineSpec{}
+	for _, p := range []PipelineSpec{traces, metrics, logs} {
+		p.Processors = slices.Concat(pipelineLimiter, processors)
+		// The traces are sampled first, not to process the dropped ones
+		if p.Name == "traces" && headSamplingPercent(args) != nil {
+			p.Processors = slices.Concat(pipelineLimiter, []string{headSamplingProcessor}, processors)
+		}
+		// Kafka gets the signals as Jaeger and Prometheus do
+		if args.Kafka != nil && args.Kafka.topics()[p.Name] != "" {
+			p.Exporters = append(p.Exporters, kafkaExporter)
+		}
+		if !split {
+			p.Exporters = append(p.Exporters, coldExtract(p.Name)...)
+			pipelines = append(pipelines, p)
+			continue
+		}
+		cold := PipelineSpec{
+			Name:      p.Name + "/cold",
+			Receivers: p.Receivers,
+			Exporters: coldExtract(p.Name),
+		}
+		cold.Processors = slices.Clone(pipelineLimiter)
+		if p.Name == "traces" && headSamplingPercent(args) != nil {
+			cold.Processors = append(cold.Processors, headSamplingProcessor)
+		}
+		if redactColdExtract(args) {
+			cold.Processors = append(cold.Processors, redactionProcessor)
+		}
+		pipelines = append(pipelines, p, cold)
+	}
+	if args.TracesFailover {
+		pipelines = append(pipelines,
+			PipelineSpec{
+				Name:      "traces/jaeger",
+				Receivers: []string{"failover/traces"},
+				Exporters: []string{"otlp"},
+			},
+			PipelineSpec{
+				Name:      "traces/spill",
+				Receivers: []string{"failover/traces"},
+				Exporters: []string{"file/traces_spill"},
+			},
+		)
+		// The spill lands on the PVC, the traces must be redacted as the
+		// cold extract ones if they were not already
+		if redactColdExtract(args) && !redactStorage(args) {
+			pipelines[len(pipelines)-1].Processors = []string{redactionProcessor}
+		}
+	}
+	if args.MetricsConversion != nil {
+		pipelines = append(pipelines,
+			PipelineSpec{
+				Name:       "metrics/prometheus",
+				Receivers:  []string{prometheusForward},
+				Processors: args.MetricsConversion.processors(),
+				Exporters:  []string{prometheusExporter(args)},
+			},
+			PipelineSpec{
+				Name:       "metrics/conversion",
+				Receivers:  []string{conversionCount},
+				Processors: []string{deltaToCumulativeProcessor},
+				Exporters:  []string{prometheusExporter(args)},
+			},
+		)
+	}
+	if args.LogsMetrics != nil {
+		pipelines = append(pipelines, args.LogsMetrics.pipelines(prometheusExporter(args))...)
+	}
+	if iq := args.IngestionQuotas; iq != nil {
+		for _, signal := range otelSignals {
+			pipelines = append(pipelines, iq.pipelines(signal, ingested[signal], limiter)...)
+		}
+		// The counts are deltas, which Prometheus could not ingest
+		pipelines = append(pipelines, PipelineSpec{
+			Name:       "metrics/quotas",
+			Receivers:  []string{quotaReceivedCount, quotaAcceptedCount},
+			Processors: []string{deltaToCumulativeProcessor},
+			Exporters:  []string{prometheusExporter(args)},
+		})
+	}
+	if args.ColdExtract && args.TenantRouting != nil {
+		for _, signal := range otelSignals {
+			for _, route := range args.TenantRouting.routes() {
+				pipelines = append(pipelines, PipelineSpec{
+					Name:      signal + "/" + route,
+					Receivers: []string{"routing/" + signal},
+					Exporters: []string{"file/" + signal + "/" + route},
+				})
+			}
+		}
+	}
+	return pipelines
+}
+
+// prometheusExporter returns the exporter of the metrics to Prometheus.
+func prometheusExporter(args *OtelCollectorArgs) string {
+	if args.PrometheusScrape {
+		return prometheusScrapeExporter
+	}
+	if args.PrometheusOTLP {
+		return prometheusOTLPExporter
+	}
+	return prometheusRemoteWriteExporter
+}
+
+// pipelinesSummary returns the pipelines in JSON, for documentation and
+// debugging purposes.
+func pipelinesSummary(pipelines []PipelineSpec) string {
+	b, err := json.Marshal(pipelines)
+	if err != nil {
+		panic(err) // should not happen, we control all this
+	}
+	return string(b)
+}
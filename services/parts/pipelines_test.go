@@ -0,0 +1,123 @@
+package parts
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_U_OtelPipelines_Summary(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args              *OtelCollectorArgs
+		ExpectedPipelines []string
+	}{
+		"default": {
+			Args:              &OtelCollectorArgs{},
+			ExpectedPipelines: []string{"traces", "metrics", "logs"},
+		},
+		"dependency-graph": {
+			Args: &OtelCollectorArgs{
+				DependencyGraph: true,
+			},
+			ExpectedPipelines: []string{"traces", "metrics", "logs"},
+		},
+		"cold-extract-failover": {
+			Args: &OtelCollectorArgs{
+				ColdExtract:    true,
+				TracesFailover: true,
+			},
+			ExpectedPipelines: []string{"traces", "metrics", "logs", "traces/jaeger", "traces/spill"},
+		},
+		"redaction-raw-cold-extract": {
+			Args: &OtelCollectorArgs{
+				ColdExtract: true,
+				Redaction: &RedactionArgs{
+					DeleteKeys:     []string{"enduser.id"},
+					RawColdExtract: true,
+				},
+			},
+			ExpectedPipelines: []string{"traces", "traces/cold", "metrics", "metrics/cold", "logs", "logs/cold"},
+		},
+		"head-sampling-redaction-raw-cold-extract": {
+			Args: &OtelCollectorArgs{
+				ColdExtract:         true,
+				HeadSamplingPercent: new(25),
+				Redaction: &RedactionArgs{
+					DeleteKeys:     []string{"enduser.id"},
+					RawColdExtract: true,
+				},
+			},
+			ExpectedPipelines: []string{"traces", "traces/cold", "metrics", "metrics/cold", "logs", "logs/cold"},
+		},
+		"tenant-routing": {
+			Args: &OtelCollectorArgs{
+				ColdExtract: true,
+				TenantRouting: &TenantRoutingArgs{
+					Tenants: []string{"ctf-a"},
+				},
+			},
+			ExpectedPipelines: []string{
+				"traces", "metrics", "logs",
+				"traces/ctf-a", "traces/default",
+				"metrics/ctf-a", "metrics/default",
+				"logs/ctf-a", "logs/default",
+			},
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := (&OtelCollector{}).defaults(tt.Args)
+
+			var summary []PipelineSpec
+			if err := json.Unmarshal([]byte(pipelinesSummary(otelPipelines(args))), &summary); err != nil {
+				t.Fatalf("invalid summary: %s", err)
+			}
+			names := make([]string, 0, len(summary))
+			for _, p := range summary {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.ExpectedPipelines) {
+				t.Errorf("expected pipelines %v, got %v", tt.ExpectedPipelines, names)
+			}
+
+			// The summary matches the rendered configuration
+			rendered := renderOtelConfigT(t, args)["service"].(map[string]any)["pipelines"].(map[string]any)
+			if len(rendered) != len(summary) {
+				t.Fatalf("expected %d rendered pipelines, got %d", len(summary), len(rendered))
+			}
+			for _, p := range summary {
+				r, ok := rendered[p.Name].(map[string]any)
+				if !ok {
+					t.Errorf("pipeline %s is not rendered", p.Name)
+					continue
+				}
+				for key, expected := range map[string][]string{
+					"receivers":  p.Receivers,
+					"processors": p.Processors,
+					"exporters":  p.Exporters,
+				} {
+					got := []string{}
+					for _, c := range asSlice(r[key]) {
+						got = append(got, c.(string))
+					}
+					if len(expected) == 0 && len(got) == 0 {
+						continue
+					}
+					if !reflect.DeepEqual(got, expected) {
+						t.Errorf("pipeline %s: expected %s %v, got %v", p.Name, key, expected, got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
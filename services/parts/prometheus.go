@@ -1,38 +1,245 @@
 package parts
 
 import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
 	appsv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apps/v1"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi-random/sdk/v4/go/random"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"go.uber.org/multierr"
+	"gopkg.in/yaml.v3"
 )
 
 type (
 	Prometheus struct {
 		pulumi.ResourceState
 
-		cfg *corev1.ConfigMap
-		dep *appsv1.Deployment
-		svc *corev1.Service
+		cfg      *corev1.ConfigMap
+		password *random.RandomPassword
+		webcfg   *corev1.Secret
+		dep      *appsv1.Deployment
+		svc      *corev1.Service
+		rule     *apiextensions.CustomResource
+		sa       *corev1.ServiceAccount
+		roles    []*rbacv1.Role
+		bindings []*rbacv1.RoleBinding
+
+		// Service references the Prometheus Service.
+		Service *ServiceRef
 
 		URL       pulumi.StringOutput
 		PodLabels pulumi.StringMapOutput
+
+		// Port is the port Prometheus serves on, through its Service and pods.
+		Port pulumi.IntOutput
+
+		// BasicAuthSecretName is the Secret containing the password of
+		// PrometheusBasicAuthUsername, at BasicAuthSecretKey, if the basic
+		// auth is required.
+		BasicAuthSecretName pulumi.StringPtrOutput
+		BasicAuthSecretKey  pulumi.StringOutput
+
+		// Ready resolves to true once Prometheus rolled out.
+		Ready pulumi.BoolOutput
 	}
 
 	PrometheusArgs struct {
 		Namespace pulumi.StringInput
 
+		// MonitoringVersion is labeled on the resources, as the
+		// OtelCollectorArgs one.
+		MonitoringVersion string
+
 		Registry pulumi.StringInput
 		registry pulumi.StringOutput
+
+		// PublishNotReadyAddresses makes the headless Service resolve to the
+		// Prometheus pod before its readiness probe passes.
+		// Defaults to false.
+		PublishNotReadyAddresses pulumi.BoolInput
+		publishNotReadyAddresses pulumi.BoolOutput
+
+		// AgentMode runs Prometheus as an agent: metrics are only forwarded
+		// to the RemoteWriteURLs, without local storage nor querying.
+		// Requires at least one remote write URL. Incompatible with the
+		// receivers, the metrics being scraped.
+		AgentMode bool
+
+		// AdminAPI turns on the Prometheus admin API, e.g. for the extractor
+		// to take TSDB snapshots. Incompatible with the agent mode.
+		AdminAPI bool
+
+		// QueryLog logs every PromQL query to a file, to find the ones
+		// overloading Prometheus. The file lands in an emptyDir, see
+		// PrometheusQueryLogFile. Incompatible with the agent mode.
+		QueryLog bool
+
+		// QueryTimeout is the maximum time a query may take before being
+		// aborted. Defaults to 2m, as Prometheus does.
+		QueryTimeout time.Duration
+
+		// QueryMaxConcurrency is the maximum number of queries executed
+		// concurrently. Defaults to 20, as Prometheus does.
+		QueryMaxConcurrency int
+
+		// RemoteWriteReceiver turns on the remote write receiver, for senders
+		// (e.g. the OTEL Collector) to push metrics.
+		RemoteWriteReceiver bool
+
+		// OTLPReceiver turns on the native OTLP receiver on /api/v1/otlp, for
+		// senders to push OTLP metrics without the remote write translation.
+		// The metric names keep their dots, only suffixed with their unit
+		// and type.
+		OTLPReceiver bool
+
+		// RemoteWriteBasicAuth requires the basic auth of every request, as
+		// PrometheusBasicAuthUsername with a generated password. The Prometheus
+		// web configuration could not scope it to the remote write receiver,
+		// so queriers need the credentials too. Requires RemoteWriteReceiver
+		// or OTLPReceiver.
+		RemoteWriteBasicAuth bool
+
+		// RemoteWriteBasicAuthSecret is the Secret of the basic auth, rather
+		// than a generated one. It holds the Prometheus web configuration at
+		// PrometheusWebConfigKey, and the password at its key (defaults to
+		// BasicAuthPasswordKey). Requires RemoteWriteBasicAuth.
+		RemoteWriteBasicAuthSecret *ExistingSecretArgs
+
+		// RemoteWriteURLs are the endpoints to forward the metrics to.
+		RemoteWriteURLs pulumi.StringArrayInput
+		remoteWriteURLs pulumi.StringArrayOutput
+
+		// ScrapeTargets are the metrics endpoints of the other parts, rendered
+		// after the Prometheus self-scraping job. Their job names are
+		// reserved.
+		ScrapeTargets []ScrapeTarget
+
+		// ExtraScrapeConfigs are additional scrape jobs, rendered after the
+		// scrape targets ones.
+		ExtraScrapeConfigs []ScrapeConfig
+
+		// AnnotationScrape discovers the annotated pods of some namespaces
+		// to scrape, rendered after the extra scrape configs. Its job name is
+		// reserved.
+		AnnotationScrape *AnnotationScrapeArgs
+
+		// ClusterDomain renders the URL fully-qualified in this cluster domain
+		// (e.g. cluster.local), for clients not resolving it through the
+		// default DNS search path. Defaults to the short form.
+		ClusterDomain string
+
+		// IPFamily of the Service, for IPv6 single-stack or dual-stack
+		// clusters. The headless scrape targets are resolved in its primary
+		// family. Defaults to the cluster ones.
+		IPFamily *IPFamilyArgs
+
+		// Mesh annotates the pods for the service mesh of the cluster.
+		// Defaults to no annotation.
+		Mesh *MeshArgs
+
+		// Port Prometheus serves on, through its Service and pods.
+		// Defaults to 9090.
+		Port int
+
+		// Resources of the Prometheus container.
+		// Defaults to small requests, even smaller in agent mode.
+		Resources corev1.ResourceRequirementsInput
+
+		// ConfigHashAnnotation annotates the ConfigMap with the hash of the
+		// scrape configuration, e.g. to tell it was hot-patched.
+		ConfigHashAnnotation bool
+
+		// ExemplarStorage stores the exemplars pushed along the samples, e.g.
+		// the trace IDs of the OTEL Collector spanmetrics.
+		ExemplarStorage bool
+
+		// Retention of the TSDB, as a Prometheus duration (e.g. 15d).
+		// Defaults to the Prometheus one, i.e. 15d. Not supported in agent
+		// mode.
+		Retention string
+
+		// RelaxedProbes tolerates a slow readiness endpoint, e.g. on a laptop
+		// lab, rather than flapping the pods readiness.
+		RelaxedProbes bool
+
+		// Replicas of the Prometheus pods.
+		// Defaults to 1.
+		Replicas int
+
+		// SpreadAcrossZones spreads the Prometheus pods across the zones, on
+		// a best-effort basis. Only applies with more than one replica.
+		SpreadAcrossZones bool
+
+		// TopologySpreadConstraints of the Prometheus pods, taking precedence over
+		// SpreadAcrossZones. Only applies with more than one replica.
+		TopologySpreadConstraints corev1.TopologySpreadConstraintArrayInput
+
+		// Rollout stages the rollouts of the Deployment.
+		Rollout *RolloutArgs
+
+		// AlertRules ships the default alerts on the Monitoring health, into
+		// the Prometheus rule files or as a PrometheusRule. The agent mode
+		// evaluates no rule, hence requires the latter.
+		AlertRules *AlertRulesArgs
 	}
 )
 
 const (
-	prometheusVersion = "v3.9.1"
+	// PrometheusVersion is the version of the Prometheus image.
+	PrometheusVersion = "v3.9.1"
+
+	defaultQueryTimeout        = 2 * time.Minute
+	defaultQueryMaxConcurrency = 20
+
+	// DefaultPrometheusPort is the port Prometheus serves on, if not set.
+	DefaultPrometheusPort = 9090
+
+	prometheusQueryLogDir  = "/prometheus/query-log"
+	prometheusWebConfigDir = "/etc/prometheus-web"
+
+	// PrometheusBasicAuthUsername is the user to authenticate as, when the
+	// remote write basic auth is required.
+	PrometheusBasicAuthUsername = "otel-collector"
+
+	// BasicAuthPasswordKey is the key of the password in a basic auth Secret.
+	BasicAuthPasswordKey = "password"
+
+	// PrometheusWebConfigKey is the key of the Prometheus web configuration
+	// in the basic auth Secret.
+	PrometheusWebConfigKey = "web.yaml"
+
+	// PrometheusQueryLogFile is where Prometheus logs the queries in its
+	// container, when the query log is turned on.
+	PrometheusQueryLogFile = prometheusQueryLogDir + "/query.log"
 )
 
+//go:embed prometheus-config.yaml.tmpl
+var prometheusConfig string
+var prometheusTemplate *template.Template
+
+func init() {
+	tmpl, err := template.New("prometheus-config").Parse(prometheusConfig)
+	if err != nil {
+		panic(fmt.Errorf("invalid Prometheus configuration template: %s", err))
+	}
+	prometheusTemplate = tmpl
+}
+
 func NewPrometheus(
 	ctx *pulumi.Context,
 	name string,
@@ -42,6 +249,9 @@ func NewPrometheus(
 	prom := &Prometheus{}
 
 	args = prom.defaults(args)
+	if err := prom.check(args); err != nil {
+		return nil, err
+	}
 	if err := ctx.RegisterComponentResource("ctfer-io:monitoring:prometheus", name, prom, opts...); err != nil {
 		return nil, err
 	}
@@ -49,7 +259,7 @@ func NewPrometheus(
 	if err := prom.provision(ctx, args, opts...); err != nil {
 		return nil, err
 	}
-	if err := prom.outputs(ctx); err != nil {
+	if err := prom.outputs(ctx, args); err != nil {
 		return nil, err
 	}
 
@@ -79,15 +289,172 @@ func (*Prometheus) defaults(args *PrometheusArgs) *PrometheusArgs {
 		}).(pulumi.StringOutput)
 	}
 
+	args.publishNotReadyAddresses = pulumi.Bool(false).ToBoolOutput()
+	if args.PublishNotReadyAddresses != nil {
+		args.publishNotReadyAddresses = args.PublishNotReadyAddresses.ToBoolOutput()
+	}
+
+	args.remoteWriteURLs = pulumi.StringArray{}.ToStringArrayOutput()
+	if args.RemoteWriteURLs != nil {
+		args.remoteWriteURLs = args.RemoteWriteURLs.ToStringArrayOutput()
+	}
+
+	if args.Resources == nil {
+		args.Resources = defaultPrometheusResources(args.AgentMode)
+	}
+
+	if args.Replicas == 0 {
+		args.Replicas = 1
+	}
+
+	if args.QueryTimeout == 0 {
+		args.QueryTimeout = defaultQueryTimeout
+	}
+	if args.QueryMaxConcurrency == 0 {
+		args.QueryMaxConcurrency = defaultQueryMaxConcurrency
+	}
+
+	if args.Port == 0 {
+		args.Port = DefaultPrometheusPort
+	}
+
+	args.AlertRules = alertRulesDefaults(args.AlertRules)
+
 	return args
 }
 
+func (*Prometheus) check(args *PrometheusArgs) error {
+	// First-level checks
+	if args.Replicas < 0 {
+		return errors.New("replicas could not be negative")
+	}
+	if err := checkScrapeTargets(args.ScrapeTargets); err != nil {
+		return err
+	}
+	reserved := make([]string, 0, len(args.ScrapeTargets)+1)
+	for _, st := range args.ScrapeTargets {
+		reserved = append(reserved, st.JobName)
+	}
+	if args.AnnotationScrape != nil {
+		if err := checkAnnotationScrape(args.AnnotationScrape); err != nil {
+			return err
+		}
+		reserved = append(reserved, AnnotationScrapeJobName)
+	}
+	if err := checkScrapeConfigs(args.ExtraScrapeConfigs, reserved...); err != nil {
+		return err
+	}
+	if err := checkClusterDomain(args.ClusterDomain); err != nil {
+		return err
+	}
+	if err := checkIPFamily(args.IPFamily); err != nil {
+		return errors.Wrap(err, "invalid ip family")
+	}
+	if err := checkMesh(args.Mesh); err != nil {
+		return errors.Wrap(err, "invalid mesh")
+	}
+	if args.Port < 1 || args.Port > 65535 {
+		return errors.Errorf("port %d is out of the 1-65535 range", args.Port)
+	}
+	if args.AgentMode && args.AdminAPI {
+		return errors.New("prometheus agent mode has no TSDB to administrate, could not turn on the admin api")
+	}
+	if args.AgentMode && args.QueryLog {
+		return errors.New("prometheus agent mode serves no query, could not turn on the query log")
+	}
+	if args.AgentMode && (args.RemoteWriteReceiver || args.OTLPReceiver) {
+		return errors.New("prometheus agent mode could not receive metrics, they must be scraped")
+	}
+	if args.RemoteWriteBasicAuth && !args.RemoteWriteReceiver && !args.OTLPReceiver {
+		return errors.New("remote write basic auth requires the remote write or OTLP receiver")
+	}
+	if args.RemoteWriteBasicAuthSecret != nil && !args.RemoteWriteBasicAuth {
+		return errors.New("remote write basic auth secret requires the remote write basic auth")
+	}
+	if err := checkExistingSecret(args.RemoteWriteBasicAuthSecret); err != nil {
+		return errors.Wrap(err, "invalid remote write basic auth secret")
+	}
+	if args.QueryTimeout < 0 {
+		return errors.New("query timeout could not be negative")
+	}
+	if args.QueryMaxConcurrency < 0 {
+		return errors.New("query max concurrency could not be negative")
+	}
+	if err := checkRollout(args.Rollout); err != nil {
+		return err
+	}
+	if err := checkAlertRules(args.AlertRules); err != nil {
+		return errors.Wrap(err, "invalid alert rules")
+	}
+	if args.AgentMode && args.AlertRules != nil && !args.AlertRules.PrometheusRule {
+		return errors.New("prometheus agent mode evaluates no rule, the alert rules require the prometheus rule")
+	}
+	if args.Retention != "" {
+		if args.AgentMode {
+			return errors.New("prometheus agent mode has no TSDB to retain, could not set the retention")
+		}
+		if !promDurationRegex.MatchString(args.Retention) {
+			return errors.Errorf("invalid retention %q, expected a Prometheus duration (e.g. 15d)", args.Retention)
+		}
+	}
+
+	wg := &sync.WaitGroup{}
+	checks := 1 // number of checks to perform
+	wg.Add(checks)
+	cerr := make(chan error, checks)
+
+	args.remoteWriteURLs.ApplyT(func(urls []string) error {
+		defer wg.Done()
+
+		var merr error
+		if args.AgentMode && len(urls) == 0 {
+			merr = multierr.Append(merr, errors.New("prometheus agent mode requires at least one remote write url"))
+		}
+		for _, u := range urls {
+			if err := checkValidURL(u); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "invalid remote write url %s", u))
+			}
+		}
+		cerr <- merr
+		return nil
+	})
+
+	wg.Wait()
+	close(cerr)
+
+	var merr error
+	for err := range cerr {
+		merr = multierr.Append(merr, err)
+	}
+	return merr
+}
+
 func (prom *Prometheus) provision(
 	ctx *pulumi.Context,
 	args *PrometheusArgs,
 	opts ...pulumi.ResourceOption,
 ) (err error) {
 	// ConfigMap
+	data := pulumi.StringMap{
+		"config": args.remoteWriteURLs.ApplyT(func(urls []string) (string, error) {
+			return renderPrometheusConfig(args, urls)
+		}).(pulumi.StringOutput),
+	}
+	configItems := corev1.KeyToPathArray{
+		corev1.KeyToPathArgs{
+			Key:  pulumi.String("config"),
+			Path: pulumi.String("config.yaml"),
+		},
+	}
+	if ruleFiles(args) {
+		data["rules"] = args.Namespace.ToStringOutput().ApplyT(func(ns string) (string, error) {
+			return renderAlertRules(args.AlertRules, ns)
+		}).(pulumi.StringOutput)
+		configItems = append(configItems, corev1.KeyToPathArgs{
+			Key:  pulumi.String("rules"),
+			Path: pulumi.String(path.Base(PrometheusRulesFile)),
+		})
+	}
 	prom.cfg, err = corev1.NewConfigMap(ctx, "prometheus-conf", &corev1.ConfigMapArgs{
 		Immutable: pulumi.BoolPtr(true),
 		Metadata: metav1.ObjectMetaArgs{
@@ -95,93 +462,150 @@ func (prom *Prometheus) provision(
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/component": pulumi.String("prometheus"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+			Annotations: configHashAnnotations(args.ConfigHashAnnotation, data.ToStringMapOutput()),
 		},
-		Data: pulumi.StringMap{
-			"config": pulumi.String(`
-scrape_configs:
-  - job_name: 'prometheus'
-`),
-		},
+		Data: data,
 	}, opts...)
 	if err != nil {
 		return
 	}
 
+	volumeMounts := corev1.VolumeMountArray{
+		corev1.VolumeMountArgs{
+			Name:      pulumi.String("config-volume"),
+			MountPath: pulumi.String("/etc/prometheus"),
+			ReadOnly:  pulumi.Bool(true),
+		},
+	}
+	volumes := corev1.VolumeArray{
+		corev1.VolumeArgs{
+			Name: pulumi.String("config-volume"),
+			ConfigMap: corev1.ConfigMapVolumeSourceArgs{
+				Name:        prom.cfg.Metadata.Name(),
+				DefaultMode: pulumi.Int(0644),
+				Items:       configItems,
+			},
+		},
+	}
+	if args.QueryLog {
+		volumeMounts = append(volumeMounts, corev1.VolumeMountArgs{
+			Name:      pulumi.String("query-log"),
+			MountPath: pulumi.String(prometheusQueryLogDir),
+		})
+		volumes = append(volumes, corev1.VolumeArgs{
+			Name:     pulumi.String("query-log"),
+			EmptyDir: corev1.EmptyDirVolumeSourceArgs{},
+		})
+	}
+
+	if args.RemoteWriteBasicAuth {
+		secretName, err := prom.provisionBasicAuth(ctx, args, opts...)
+		if err != nil {
+			return err
+		}
+
+		// Mounted as generated, whatever the keys of an existing Secret
+		volumeMounts = append(volumeMounts, corev1.VolumeMountArgs{
+			Name:      pulumi.String("web-config"),
+			MountPath: pulumi.String(prometheusWebConfigDir),
+			ReadOnly:  pulumi.Bool(true),
+		})
+		volumes = append(volumes, corev1.VolumeArgs{
+			Name: pulumi.String("web-config"),
+			Secret: corev1.SecretVolumeSourceArgs{
+				SecretName:  secretName,
+				DefaultMode: pulumi.Int(0444),
+				Items: corev1.KeyToPathArray{
+					corev1.KeyToPathArgs{
+						Key:  pulumi.String(PrometheusWebConfigKey),
+						Path: pulumi.String(PrometheusWebConfigKey),
+					},
+					corev1.KeyToPathArgs{
+						Key:  pulumi.String(prom.basicAuthSecretKey(args)),
+						Path: pulumi.String(BasicAuthPasswordKey),
+					},
+				},
+			},
+		})
+	}
+
+	// The annotated pods are discovered through the API server
+	var serviceAccountName pulumi.StringPtrInput
+	if args.AnnotationScrape != nil {
+		if serviceAccountName, err = prom.provisionAnnotationScrape(ctx, args, opts...); err != nil {
+			return
+		}
+	}
+
 	// Deployment
 	prom.dep, err = appsv1.NewDeployment(ctx, "prometheus", &appsv1.DeploymentArgs{
 		Metadata: metav1.ObjectMetaArgs{
 			Namespace: args.Namespace,
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/name":      pulumi.String("prometheus"),
-				"app.kubernetes.io/version":   pulumi.String(prometheusVersion),
+				"app.kubernetes.io/version":   pulumi.String(PrometheusVersion),
 				"app.kubernetes.io/component": pulumi.String("prometheus"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
+			Annotations: rolloutAnnotations(args.Rollout),
 		},
 		Spec: appsv1.DeploymentSpecArgs{
 			Selector: metav1.LabelSelectorArgs{
 				MatchLabels: pulumi.StringMap{
 					"app.kubernetes.io/name":      pulumi.String("prometheus"),
-					"app.kubernetes.io/version":   pulumi.String(prometheusVersion),
+					"app.kubernetes.io/version":   pulumi.String(PrometheusVersion),
 					"app.kubernetes.io/component": pulumi.String("prometheus"),
 					"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 					"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 				},
 			},
-			Replicas: pulumi.Int(1),
+			Replicas:        pulumi.Int(args.Replicas),
+			Paused:          rolloutPaused(args.Rollout),
+			MinReadySeconds: rolloutMinReadySeconds(args.Rollout),
 			Template: corev1.PodTemplateSpecArgs{
 				Metadata: metav1.ObjectMetaArgs{
 					Namespace: args.Namespace,
 					Labels: pulumi.StringMap{
 						"app.kubernetes.io/name":      pulumi.String("prometheus"),
-						"app.kubernetes.io/version":   pulumi.String(prometheusVersion),
+						"app.kubernetes.io/version":   pulumi.String(PrometheusVersion),
 						"app.kubernetes.io/component": pulumi.String("prometheus"),
 						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 					},
+					Annotations: meshAnnotations(configChecksumAnnotations(data.ToStringMapOutput()), args.Mesh.PodAnnotations()),
 				},
 				Spec: corev1.PodSpecArgs{
+					ServiceAccountName: serviceAccountName,
+					TopologySpreadConstraints: topologySpreadConstraints(args.Replicas, args.SpreadAcrossZones, args.TopologySpreadConstraints, pulumi.StringMap{
+						"app.kubernetes.io/name":      pulumi.String("prometheus"),
+						"app.kubernetes.io/component": pulumi.String("prometheus"),
+						"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+						"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+					}),
 					Containers: corev1.ContainerArray{
 						corev1.ContainerArgs{
 							Name:  pulumi.String("prometheus"),
-							Image: pulumi.Sprintf("%sprom/prometheus:%s", args.registry, prometheusVersion),
-							Args: pulumi.ToStringArray([]string{
-								"--config.file=/etc/prometheus/config.yaml",
-								"--web.enable-remote-write-receiver", // Turn on remote write for OtelCollector exporter
-							}),
+							Image: pulumi.Sprintf("%sprom/prometheus:%s", args.registry, PrometheusVersion),
+							Args:  pulumi.ToStringArray(prometheusFlags(args)),
 							Ports: corev1.ContainerPortArray{
 								corev1.ContainerPortArgs{
 									Name:          pulumi.String("metrics"),
-									ContainerPort: pulumi.Int(9090),
-								},
-							},
-							VolumeMounts: corev1.VolumeMountArray{
-								corev1.VolumeMountArgs{
-									Name:      pulumi.String("config-volume"),
-									MountPath: pulumi.String("/etc/prometheus"),
-									ReadOnly:  pulumi.Bool(true),
-								},
-							},
-						},
-					},
-					Volumes: corev1.VolumeArray{
-						corev1.VolumeArgs{
-							Name: pulumi.String("config-volume"),
-							ConfigMap: corev1.ConfigMapVolumeSourceArgs{
-								Name:        prom.cfg.Metadata.Name(),
-								DefaultMode: pulumi.Int(0644),
-								Items: corev1.KeyToPathArray{
-									corev1.KeyToPathArgs{
-										Key:  pulumi.String("config"),
-										Path: pulumi.String("config.yaml"),
-									},
+									ContainerPort: pulumi.Int(args.Port),
 								},
 							},
+							// The rollout is awaited until Prometheus serves, for
+							// dependents (e.g. the Perses datasource) to wait for it.
+							ReadinessProbe: prometheusReadinessProbe(args),
+							Resources:      args.Resources.ToResourceRequirementsOutput().ToResourceRequirementsPtrOutput(),
+							VolumeMounts:   volumeMounts,
 						},
 					},
+					Volumes: volumes,
 				},
 			},
 		},
@@ -197,40 +621,352 @@ scrape_configs:
 			Labels: pulumi.StringMap{
 				"app.kubernetes.io/component": pulumi.String("prometheus"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
 		},
 		Spec: corev1.ServiceSpecArgs{
 			Selector: pulumi.StringMap{
 				"app.kubernetes.io/name":      pulumi.String("prometheus"),
-				"app.kubernetes.io/version":   pulumi.String(prometheusVersion),
+				"app.kubernetes.io/version":   pulumi.String(PrometheusVersion),
 				"app.kubernetes.io/component": pulumi.String("prometheus"),
 				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
 				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
 			},
-			ClusterIP: pulumi.String("None"), // Headless, for DNS purposes
+			ClusterIP:                pulumi.String("None"), // Headless, for DNS purposes
+			PublishNotReadyAddresses: args.publishNotReadyAddresses,
+			IpFamilyPolicy:           args.IPFamily.policy(),
+			IpFamilies:               args.IPFamily.families(),
 			Ports: corev1.ServicePortArray{
 				corev1.ServicePortArgs{
 					Name: pulumi.String("metrics"),
-					Port: pulumi.Int(9090),
+					Port: pulumi.Int(args.Port),
 				},
 			},
 		},
 	}, opts...)
+	if err != nil {
+		return
+	}
+
+	// PrometheusRule, for the Prometheus Operator of the cluster to evaluate
+	if args.AlertRules != nil && args.AlertRules.PrometheusRule {
+		labels := pulumi.StringMap{}
+		for k, v := range args.AlertRules.PrometheusRuleLabels {
+			labels[k] = pulumi.String(v)
+		}
+		labels["app.kubernetes.io/component"] = pulumi.String("prometheus")
+		labels["app.kubernetes.io/part-of"] = pulumi.String("monitoring")
+		labels["ctfer.io/monitoring-version"] = pulumi.String(args.MonitoringVersion)
+		labels["ctfer.io/stack-name"] = pulumi.String(ctx.Stack())
+
+		prom.rule, err = apiextensions.NewCustomResource(ctx, "prometheus-alerts", &apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("monitoring.coreos.com/v1"),
+			Kind:       pulumi.String("PrometheusRule"),
+			Metadata: metav1.ObjectMetaArgs{
+				Namespace: args.Namespace,
+				Labels:    labels,
+			},
+			OtherFields: kubernetes.UntypedArgs{
+				"spec": args.Namespace.ToStringOutput().ApplyT(func(ns string) (map[string]any, error) {
+					return prometheusRuleSpec(args.AlertRules, ns)
+				}).(pulumi.MapOutput),
+			},
+		}, opts...)
+	}
 
 	return
 }
 
-func (prom *Prometheus) outputs(ctx *pulumi.Context) error {
-	prom.URL = pulumi.Sprintf(
-		"http://%s:%d",
-		prom.svc.Metadata.Name().Elem(),
-		prom.svc.Spec.Ports().Index(pulumi.Int(0)).Port(),
-	)
+func (prom *Prometheus) outputs(ctx *pulumi.Context, args *PrometheusArgs) error {
+	prom.Service = NewServiceRef(prom.svc)
+	prom.Port = prom.Service.Port
+	prom.URL = prom.Service.URL("http", args.ClusterDomain)
 	prom.PodLabels = prom.dep.Spec.Template().Metadata().Labels()
+	prom.Ready = rolledOut(prom.dep.Spec.Replicas(), prom.dep.Status.ReadyReplicas())
+	if args.RemoteWriteBasicAuth {
+		if args.RemoteWriteBasicAuthSecret != nil {
+			prom.BasicAuthSecretName = args.RemoteWriteBasicAuthSecret.Name.ToStringOutput().ToStringPtrOutput()
+		} else {
+			prom.BasicAuthSecretName = prom.webcfg.Metadata.Name()
+		}
+		prom.BasicAuthSecretKey = pulumi.String(prom.basicAuthSecretKey(args)).ToStringOutput()
+	}
 
 	return ctx.RegisterResourceOutputs(prom, pulumi.Map{
-		"url":       prom.URL,
-		"podLabels": prom.PodLabels,
+		"url":                 prom.URL,
+		"port":                prom.Port,
+		"podLabels":           prom.PodLabels,
+		"ready":               prom.Ready,
+		"basicAuthSecretName": prom.BasicAuthSecretName,
+		"basicAuthSecretKey":  prom.BasicAuthSecretKey,
 	})
 }
+
+// provisionBasicAuth generates the basic auth Secret, unless an existing
+// one is referenced, and returns its name.
+func (prom *Prometheus) provisionBasicAuth(ctx *pulumi.Context, args *PrometheusArgs, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	if args.RemoteWriteBasicAuthSecret != nil {
+		return args.RemoteWriteBasicAuthSecret.Name.ToStringOutput(), nil
+	}
+
+	var err error
+	prom.password, err = random.NewRandomPassword(ctx, "prometheus-basic-auth", &random.RandomPasswordArgs{
+		Length:  pulumi.Int(32),
+		Special: pulumi.Bool(false),
+	}, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+
+	// The web configuration only holds the hash, the password is shared
+	// with the senders through the same Secret
+	prom.webcfg, err = corev1.NewSecret(ctx, "prometheus-web-config", &corev1.SecretArgs{
+		Immutable: pulumi.BoolPtr(true),
+		Metadata: metav1.ObjectMetaArgs{
+			Namespace: args.Namespace,
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/component": pulumi.String("prometheus"),
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.MonitoringVersion),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+		},
+		StringData: pulumi.StringMap{
+			PrometheusWebConfigKey: pulumi.Sprintf("basic_auth_users:\n  %s: %s\n", PrometheusBasicAuthUsername, prom.password.BcryptHash),
+			BasicAuthPasswordKey:   prom.password.Result,
+		},
+	}, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, err
+	}
+	return prom.webcfg.Metadata.Name().Elem(), nil
+}
+
+// basicAuthSecretKey returns the key of the password in the basic auth
+// Secret.
+func (*Prometheus) basicAuthSecretKey(args *PrometheusArgs) string {
+	if args.RemoteWriteBasicAuthSecret != nil {
+		return args.RemoteWriteBasicAuthSecret.key(BasicAuthPasswordKey)
+	}
+	return BasicAuthPasswordKey
+}
+
+// ruleFiles tells whether the alert rules land in the Prometheus rule files.
+func ruleFiles(args *PrometheusArgs) bool {
+	return args.AlertRules != nil && !args.AlertRules.PrometheusRule
+}
+
+// prometheusFlags returns the Prometheus container flags for the given
+// (defaulted) arguments.
+func prometheusFlags(args *PrometheusArgs) []string {
+	flags := []string{
+		"--config.file=/etc/prometheus/config.yaml",
+	}
+	if args.Port != DefaultPrometheusPort {
+		flags = append(flags, "--web.listen-address=:"+strconv.Itoa(args.Port))
+	}
+	if args.RemoteWriteReceiver {
+		flags = append(flags, "--web.enable-remote-write-receiver")
+	}
+	if args.OTLPReceiver {
+		flags = append(flags, "--web.enable-otlp-receiver")
+	}
+	if args.RemoteWriteBasicAuth {
+		flags = append(flags, "--web.config.file="+prometheusWebConfigDir+"/"+PrometheusWebConfigKey)
+	}
+	if args.AgentMode {
+		// Prometheus 2.x used --enable-feature=agent, the one we pin is 3.x
+		flags = append(flags, "--agent")
+	}
+	if args.AdminAPI {
+		flags = append(flags, "--web.enable-admin-api")
+	}
+	if args.ExemplarStorage {
+		flags = append(flags, "--enable-feature=exemplar-storage")
+	}
+	if !args.AgentMode {
+		// The agent mode refuses the query flags
+		flags = append(flags,
+			"--query.timeout="+promDuration(args.QueryTimeout),
+			"--query.max-concurrency="+strconv.Itoa(args.QueryMaxConcurrency),
+		)
+	}
+	if args.Retention != "" {
+		flags = append(flags, "--storage.tsdb.retention.time="+args.Retention)
+	}
+	return flags
+}
+
+// promDurationRegex matches the Prometheus durations, e.g. 15d or 1w2d.
+var promDurationRegex = regexp.MustCompile(`^([0-9]+(y|w|d|h|m|s|ms))+$`)
+
+// promDuration formats the duration as Prometheus parses it, which does not
+// support the fractional seconds of time.Duration.String.
+func promDuration(d time.Duration) string {
+	if d%time.Second != 0 {
+		return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+	}
+	return d.String()
+}
+
+// renderPrometheusConfig renders the Prometheus configuration of the given
+// (defaulted) arguments, with the resolved remote write URLs.
+func renderPrometheusConfig(args *PrometheusArgs, remoteWriteURLs []string) (string, error) {
+	scs := make([]ScrapeConfig, 0, len(args.ScrapeTargets)+len(args.ExtraScrapeConfigs)+1)
+	for _, st := range args.ScrapeTargets {
+		sc := st.ScrapeConfig()
+		for i := range sc.DNSSDConfigs {
+			// The headless Services resolve in their primary family
+			sc.DNSSDConfigs[i].Type = args.IPFamily.DNSSDType()
+		}
+		scs = append(scs, sc)
+	}
+	scs = append(scs, args.ExtraScrapeConfigs...)
+	if args.AnnotationScrape != nil {
+		scs = append(scs, args.AnnotationScrape.ScrapeConfig())
+	}
+
+	extra := ""
+	if len(scs) != 0 {
+		b := &bytes.Buffer{}
+		enc := yaml.NewEncoder(b)
+		enc.SetIndent(2)
+		if err := enc.Encode(scs); err != nil {
+			return "", errors.Wrap(err, "encoding scrape configs")
+		}
+		if err := enc.Close(); err != nil {
+			return "", errors.Wrap(err, "encoding scrape configs")
+		}
+		// Indent under scrape_configs
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = "  " + line
+		}
+		extra = strings.Join(lines, "\n")
+	}
+
+	buf := &bytes.Buffer{}
+	if err := prometheusTemplate.Execute(buf, map[string]any{
+		"RemoteWrite":        remoteWriteURLs,
+		"OTLP":               args.OTLPReceiver,
+		"ExtraScrapeConfigs": extra,
+		"Port":               args.Port,
+		"BasicAuth": func() map[string]string {
+			if !args.RemoteWriteBasicAuth {
+				return nil
+			}
+			return map[string]string{
+				"Username":     PrometheusBasicAuthUsername,
+				"PasswordFile": prometheusWebConfigDir + "/" + BasicAuthPasswordKey,
+			}
+		}(),
+		"QueryLogFile": func() string {
+			if args.QueryLog {
+				return PrometheusQueryLogFile
+			}
+			return ""
+		}(),
+		"RuleFiles": func() []string {
+			if ruleFiles(args) {
+				return []string{PrometheusRulesFile}
+			}
+			return nil
+		}(),
+	}); err != nil {
+		return "", err
+	}
+	return normalizeDocument(buf.String()), nil
+}
+
+// RenderPrometheusConfig renders the Prometheus configuration of the
+// arguments offline, i.e. without the Pulumi engine, hence requires literal
+// RemoteWriteURLs (e.g. pulumi.ToStringArray).
+// The arguments are checked as when deploying, but are not modified.
+func RenderPrometheusConfig(args *PrometheusArgs) (string, error) {
+	prom := &Prometheus{}
+	cpy := PrometheusArgs{}
+	if args != nil {
+		cpy = *args
+	}
+
+	// Resolve the URLs first, as checking unknown outputs would never end
+	remoteWriteURLs, err := literalStrings(cpy.RemoteWriteURLs)
+	if err != nil {
+		return "", errors.Wrap(err, "remote write urls")
+	}
+
+	args = prom.defaults(&cpy)
+	if err := prom.check(args); err != nil {
+		return "", err
+	}
+	return renderPrometheusConfig(args, remoteWriteURLs)
+}
+
+// literalStrings returns the values of the array, if only made of literals.
+func literalStrings(in pulumi.StringArrayInput) ([]string, error) {
+	if in == nil {
+		return nil, nil
+	}
+	arr, ok := in.(pulumi.StringArray)
+	if !ok {
+		return nil, errors.Errorf("%T is not a literal string array", in)
+	}
+	strs := make([]string, 0, len(arr))
+	for _, v := range arr {
+		str, ok := v.(pulumi.String)
+		if !ok {
+			return nil, errors.Errorf("%T is not a literal string", v)
+		}
+		strs = append(strs, string(str))
+	}
+	return strs, nil
+}
+
+// prometheusReadinessProbe checks Prometheus serves, every 5s timing out after
+// 1s, or every 10s timing out after 5s if relaxed.
+// With the basic auth, the kubelet could not authenticate without the
+// password in clear in the pod spec, so the probe runs in the container.
+func prometheusReadinessProbe(args *PrometheusArgs) corev1.ProbeArgs {
+	probe := corev1.ProbeArgs{
+		HttpGet: corev1.HTTPGetActionArgs{
+			Path: pulumi.String("/-/ready"),
+			Port: pulumi.String("metrics"),
+		},
+		PeriodSeconds: pulumi.Int(5),
+	}
+	if args.RemoteWriteBasicAuth {
+		probe.HttpGet = nil
+		probe.Exec = corev1.ExecActionArgs{
+			Command: pulumi.ToStringArray([]string{
+				"/bin/sh", "-c",
+				fmt.Sprintf(`wget -q -O /dev/null "http://%s:$(cat %s/%s)@localhost:%d/-/ready"`,
+					PrometheusBasicAuthUsername, prometheusWebConfigDir, BasicAuthPasswordKey, args.Port),
+			}),
+		}
+	}
+	if args.RelaxedProbes {
+		probe.PeriodSeconds = pulumi.Int(10)
+		probe.TimeoutSeconds = pulumi.Int(5)
+		probe.FailureThreshold = pulumi.Int(6)
+	}
+	return probe
+}
+
+func defaultPrometheusResources(agentMode bool) corev1.ResourceRequirementsArgs {
+	// Without local storage nor querying, the agent is far lighter
+	if agentMode {
+		return corev1.ResourceRequirementsArgs{
+			Requests: pulumi.StringMap{
+				"cpu":    pulumi.String("50m"),
+				"memory": pulumi.String("64Mi"),
+			},
+		}
+	}
+	return corev1.ResourceRequirementsArgs{
+		Requests: pulumi.StringMap{
+			"cpu":    pulumi.String("100m"),
+			"memory": pulumi.String("256Mi"),
+		},
+	}
+}
@@ -0,0 +1,638 @@
+package parts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_Prometheus_PublishNotReadyAddresses(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Input    pulumi.BoolInput
+		Expected bool
+	}{
+		"default": {
+			Input:    nil,
+			Expected: false,
+		},
+		"enabled": {
+			Input:    pulumi.Bool(true),
+			Expected: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+					Namespace:                pulumi.String("monitoring"),
+					PublishNotReadyAddresses: tt.Input,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			svc := m.ByName("kubernetes:core/v1:Service", "prometheus-metrics")
+			if svc == nil {
+				t.Fatal("prometheus service not found")
+			}
+			got := svc["spec"].ObjectValue()["publishNotReadyAddresses"]
+			if !got.IsBool() || got.BoolValue() != tt.Expected {
+				t.Fatalf("expected publishNotReadyAddresses to be %t, got %v", tt.Expected, got)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_AgentMode(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		AgentMode           bool
+		RemoteWriteURLs     []string
+		RemoteWriteReceiver bool
+		ExpectErr           bool
+	}{
+		"server": {
+			AgentMode: false,
+		},
+		"server-with-remote-write": {
+			AgentMode:       false,
+			RemoteWriteURLs: []string{"http://mimir:9009/api/v1/push"},
+		},
+		"agent": {
+			AgentMode:       true,
+			RemoteWriteURLs: []string{"http://mimir:9009/api/v1/push"},
+		},
+		"agent-without-remote-write": {
+			AgentMode: true,
+			ExpectErr: true,
+		},
+		"agent-with-remote-write-receiver": {
+			AgentMode:           true,
+			RemoteWriteURLs:     []string{"http://mimir:9009/api/v1/push"},
+			RemoteWriteReceiver: true,
+			ExpectErr:           true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+					Namespace:           pulumi.String("monitoring"),
+					AgentMode:           tt.AgentMode,
+					RemoteWriteURLs:     pulumi.ToStringArray(tt.RemoteWriteURLs),
+					RemoteWriteReceiver: tt.RemoteWriteReceiver,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// Check the flags
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			flags := []string{}
+			for _, f := range ctr["args"].ArrayValue() {
+				flags = append(flags, f.StringValue())
+			}
+			if slices.Contains(flags, "--agent") != tt.AgentMode {
+				t.Errorf("expected --agent presence to be %t, got flags %v", tt.AgentMode, flags)
+			}
+
+			// Check the remote write targets
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")
+			cfg := struct {
+				RemoteWrite []struct {
+					URL string `yaml:"url"`
+				} `yaml:"remote_write"`
+			}{}
+			if err := yaml.Unmarshal([]byte(cm["data"].ObjectValue()["config"].StringValue()), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			if len(cfg.RemoteWrite) != len(tt.RemoteWriteURLs) {
+				t.Fatalf("expected %d remote write targets, got %d", len(tt.RemoteWriteURLs), len(cfg.RemoteWrite))
+			}
+			for i, rw := range cfg.RemoteWrite {
+				if rw.URL != tt.RemoteWriteURLs[i] {
+					t.Errorf("expected remote write url %s, got %s", tt.RemoteWriteURLs[i], rw.URL)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_AdminAPI(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		AdminAPI  bool
+		AgentMode bool
+		ExpectErr bool
+	}{
+		"disabled": {
+			AdminAPI: false,
+		},
+		"enabled": {
+			AdminAPI: true,
+		},
+		"agent-mode": {
+			AdminAPI:  true,
+			AgentMode: true,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := &PrometheusArgs{
+				AdminAPI:  tt.AdminAPI,
+				AgentMode: tt.AgentMode,
+			}
+			if tt.AgentMode {
+				args.RemoteWriteURLs = pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"})
+			}
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args.Namespace = pulumi.String("monitoring")
+				_, err := NewPrometheus(ctx, "prometheus", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			if flags := prometheusFlags(args); slices.Contains(flags, "--web.enable-admin-api") != tt.AdminAPI {
+				t.Errorf("expected --web.enable-admin-api presence to be %t, got flags %v", tt.AdminAPI, flags)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_Port(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Port         int
+		ExpectedPort int
+		ExpectFlag   bool
+		ExpectErr    bool
+	}{
+		"default": {
+			ExpectedPort: 9090,
+		},
+		"custom": {
+			Port:         19090,
+			ExpectedPort: 19090,
+			ExpectFlag:   true,
+		},
+		"out-of-range": {
+			Port:      65536,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := &PrometheusArgs{
+				Port: tt.Port,
+			}
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args.Namespace = pulumi.String("monitoring")
+				_, err := NewPrometheus(ctx, "prometheus", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			// Prometheus listens, is exposed and scrapes itself on the port
+			flag := fmt.Sprintf("--web.listen-address=:%d", tt.ExpectedPort)
+			if flags := prometheusFlags(args); slices.Contains(flags, flag) != tt.ExpectFlag {
+				t.Errorf("expected %s presence to be %t, got flags %v", flag, tt.ExpectFlag, flags)
+			}
+			svc := m.ByName("kubernetes:core/v1:Service", "prometheus-metrics")
+			if port := int(svc["spec"].ObjectValue()["ports"].ArrayValue()[0].ObjectValue()["port"].NumberValue()); port != tt.ExpectedPort {
+				t.Errorf("expected the service port %d, got %d", tt.ExpectedPort, port)
+			}
+			ctr := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			if port := int(ctr["ports"].ArrayValue()[0].ObjectValue()["containerPort"].NumberValue()); port != tt.ExpectedPort {
+				t.Errorf("expected the container port %d, got %d", tt.ExpectedPort, port)
+			}
+			cfg := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")["data"].ObjectValue()["config"].StringValue()
+			if target := fmt.Sprintf("localhost:%d", tt.ExpectedPort); !strings.Contains(cfg, target) {
+				t.Errorf("expected the self-scrape target %s, got:\n%s", target, cfg)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_QueryLog(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args            *PrometheusArgs
+		ExpectedLogFile string
+		ExpectedFlags   []string
+		UnexpectedFlag  string
+		ExpectedVolumes int
+	}{
+		"defaults": {
+			Args: &PrometheusArgs{},
+			ExpectedFlags: []string{
+				"--query.timeout=2m0s",
+				"--query.max-concurrency=20",
+			},
+			ExpectedVolumes: 1,
+		},
+		"query-log": {
+			Args: &PrometheusArgs{
+				QueryLog:            true,
+				QueryTimeout:        1500 * time.Millisecond,
+				QueryMaxConcurrency: 4,
+			},
+			ExpectedLogFile: "/prometheus/query-log/query.log",
+			ExpectedFlags: []string{
+				"--query.timeout=1500ms",
+				"--query.max-concurrency=4",
+			},
+			ExpectedVolumes: 2,
+		},
+		"agent-mode": {
+			Args: &PrometheusArgs{
+				AgentMode:       true,
+				RemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+			},
+			UnexpectedFlag:  "--query.timeout",
+			ExpectedVolumes: 1,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				tt.Args.Namespace = pulumi.String("monitoring")
+				_, err := NewPrometheus(ctx, "prometheus", tt.Args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			// Check the configuration
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")
+			cfg := struct {
+				Global struct {
+					QueryLogFile string `yaml:"query_log_file"`
+				} `yaml:"global"`
+			}{}
+			if err := yaml.Unmarshal([]byte(cm["data"].ObjectValue()["config"].StringValue()), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			if cfg.Global.QueryLogFile != tt.ExpectedLogFile {
+				t.Errorf("expected query log file %q, got %q", tt.ExpectedLogFile, cfg.Global.QueryLogFile)
+			}
+
+			// Check the flags and volumes
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			spec := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+			ctr := spec["containers"].ArrayValue()[0].ObjectValue()
+			flags := []string{}
+			for _, f := range ctr["args"].ArrayValue() {
+				flags = append(flags, f.StringValue())
+			}
+			for _, f := range tt.ExpectedFlags {
+				if !slices.Contains(flags, f) {
+					t.Errorf("expected flag %s, got flags %v", f, flags)
+				}
+			}
+			if tt.UnexpectedFlag != "" && slices.ContainsFunc(flags, func(f string) bool {
+				return strings.HasPrefix(f, tt.UnexpectedFlag)
+			}) {
+				t.Errorf("unexpected flag %s, got flags %v", tt.UnexpectedFlag, flags)
+			}
+			if vols := spec["volumes"].ArrayValue(); len(vols) != tt.ExpectedVolumes {
+				t.Errorf("expected %d volumes, got %d", tt.ExpectedVolumes, len(vols))
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_QueryLog_AgentMode(t *testing.T) {
+	t.Parallel()
+
+	err := (&Prometheus{}).check((&Prometheus{}).defaults(&PrometheusArgs{
+		AgentMode: true,
+		QueryLog:  true,
+	}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func Test_U_Prometheus_Retention(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args      *PrometheusArgs
+		ExpectErr bool
+	}{
+		"days": {
+			Args: &PrometheusArgs{Retention: "15d"},
+		},
+		"composite": {
+			Args: &PrometheusArgs{Retention: "1w2d"},
+		},
+		"go-duration": {
+			Args:      &PrometheusArgs{Retention: "1.5h"},
+			ExpectErr: true,
+		},
+		"agent-mode": {
+			Args: &PrometheusArgs{
+				AgentMode:       true,
+				RemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+				Retention:       "15d",
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&Prometheus{}).check((&Prometheus{}).defaults(tt.Args))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_Exemplars(t *testing.T) {
+	t.Parallel()
+
+	args := (&Prometheus{}).defaults(&PrometheusArgs{
+		RemoteWriteReceiver: true,
+		ExemplarStorage:     true,
+	})
+	if err := (&Prometheus{}).check(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join("testdata", "prometheus-flags-exemplars.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := []string{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	if flags := prometheusFlags(args); !slices.Equal(flags, expected) {
+		t.Errorf("expected flags %v, got %v", expected, flags)
+	}
+}
+
+func Test_U_Prometheus_SelfScrape(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ScrapeTargets      []ScrapeTarget
+		ExtraScrapeConfigs []ScrapeConfig
+		ExpectedJobs       []string
+		ExpectErr          bool
+	}{
+		"no-target": {
+			ExpectedJobs: []string{"prometheus"},
+		},
+		"jaeger": {
+			ScrapeTargets: []ScrapeTarget{JaegerScrapeTarget()},
+			ExpectedJobs:  []string{"prometheus", "jaeger"},
+		},
+		"perses": {
+			ScrapeTargets: []ScrapeTarget{PersesScrapeTarget()},
+			ExpectedJobs:  []string{"prometheus", "perses"},
+		},
+		"jaeger-perses-extra": {
+			ScrapeTargets: []ScrapeTarget{JaegerScrapeTarget(), PersesScrapeTarget()},
+			ExtraScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: []ScrapeDestination{{Ports: []int{8080}}},
+				},
+			},
+			ExpectedJobs: []string{"prometheus", "jaeger", "perses", "challenges"},
+		},
+		"reserved-job": {
+			ScrapeTargets:      []ScrapeTarget{JaegerScrapeTarget()},
+			ExtraScrapeConfigs: []ScrapeConfig{{JobName: "jaeger"}},
+			ExpectErr:          true,
+		},
+		"duplicated-target": {
+			ScrapeTargets: []ScrapeTarget{PersesScrapeTarget(), PersesScrapeTarget()},
+			ExpectErr:     true,
+		},
+		"invalid-target": {
+			ScrapeTargets: []ScrapeTarget{{JobName: "broken"}},
+			ExpectErr:     true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := RenderPrometheusConfig(&PrometheusArgs{
+				ScrapeTargets:      tt.ScrapeTargets,
+				ExtraScrapeConfigs: tt.ExtraScrapeConfigs,
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			out := struct {
+				ScrapeConfigs []ScrapeConfig `yaml:"scrape_configs"`
+			}{}
+			if err := yaml.Unmarshal([]byte(cfg), &out); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			jobs := []string{}
+			for _, sc := range out.ScrapeConfigs {
+				jobs = append(jobs, sc.JobName)
+
+				switch sc.JobName {
+				case "prometheus":
+					if len(sc.StaticConfigs) != 1 || !slices.Equal(sc.StaticConfigs[0].Targets, []string{"localhost:9090"}) {
+						t.Errorf("expected prometheus to scrape itself, got %+v", sc)
+					}
+				case "jaeger":
+					// Every pod behind the headless Service
+					if len(sc.DNSSDConfigs) != 1 {
+						t.Fatalf("expected jaeger to be discovered through DNS, got %+v", sc)
+					}
+					sd := sc.DNSSDConfigs[0]
+					if !slices.Equal(sd.Names, []string{JaegerAdminServiceName}) || sd.Type != "A" || sd.Port != JaegerAdminPort {
+						t.Errorf("unexpected jaeger discovery %+v", sd)
+					}
+				case "perses":
+					if len(sc.StaticConfigs) != 1 || !slices.Equal(sc.StaticConfigs[0].Targets, []string{"perses:8080"}) {
+						t.Errorf("expected perses to be scraped through its Service, got %+v", sc)
+					}
+				}
+			}
+			if !slices.Equal(jobs, tt.ExpectedJobs) {
+				t.Errorf("expected jobs %v, got %v", tt.ExpectedJobs, jobs)
+			}
+		})
+	}
+}
+
+func Test_U_Prometheus_RemoteWriteReceiver(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Receiver  bool
+		OTLP      bool
+		BasicAuth bool
+		ExpectErr bool
+	}{
+		"disabled": {},
+		"receiver": {
+			Receiver: true,
+		},
+		"basic-auth": {
+			Receiver:  true,
+			BasicAuth: true,
+		},
+		"otlp": {
+			OTLP: true,
+		},
+		"otlp-basic-auth": {
+			OTLP:      true,
+			BasicAuth: true,
+		},
+		"basic-auth-without-receiver": {
+			BasicAuth: true,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			args := &PrometheusArgs{
+				RemoteWriteReceiver:  tt.Receiver,
+				OTLPReceiver:         tt.OTLP,
+				RemoteWriteBasicAuth: tt.BasicAuth,
+			}
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				args.Namespace = pulumi.String("monitoring")
+				_, err := NewPrometheus(ctx, "prometheus", args)
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			flags := prometheusFlags(args)
+			if slices.Contains(flags, "--web.enable-remote-write-receiver") != tt.Receiver {
+				t.Errorf("expected --web.enable-remote-write-receiver presence to be %t, got flags %v", tt.Receiver, flags)
+			}
+			if slices.Contains(flags, "--web.enable-otlp-receiver") != tt.OTLP {
+				t.Errorf("expected --web.enable-otlp-receiver presence to be %t, got flags %v", tt.OTLP, flags)
+			}
+			if slices.Contains(flags, "--web.config.file="+prometheusWebConfigDir+"/web.yaml") != tt.BasicAuth {
+				t.Errorf("expected --web.config.file presence to be %t, got flags %v", tt.BasicAuth, flags)
+			}
+
+			// The credentials are generated, and Prometheus scrapes itself with them
+			secret := m.ByName("kubernetes:core/v1:Secret", "prometheus-web-config")
+			if (secret != nil) != tt.BasicAuth {
+				t.Fatalf("expected the web config secret presence to be %t", tt.BasicAuth)
+			}
+			cm := m.ByName("kubernetes:core/v1:ConfigMap", "prometheus-conf")
+			cfg := cm["data"].ObjectValue()["config"].StringValue()
+			if strings.Contains(cfg, "password_file: "+prometheusWebConfigDir+"/"+BasicAuthPasswordKey) != tt.BasicAuth {
+				t.Errorf("expected the self-scrape basic auth presence to be %t, got:\n%s", tt.BasicAuth, cfg)
+			}
+			// The OTLP metric names are not escaped
+			if strings.Contains(cfg, "translation_strategy: NoUTF8EscapingWithSuffixes") != tt.OTLP {
+				t.Errorf("expected the OTLP translation strategy presence to be %t, got:\n%s", tt.OTLP, cfg)
+			}
+			if !tt.BasicAuth {
+				return
+			}
+			stringData := secret["stringData"]
+			if stringData.IsSecret() {
+				stringData = stringData.SecretValue().Element
+			}
+			data := stringData.ObjectValue()
+			if web := data["web.yaml"].StringValue(); web != "basic_auth_users:\n  "+PrometheusBasicAuthUsername+": $2a$10$hash\n" {
+				t.Errorf("unexpected web config:\n%s", web)
+			}
+			if pwd := data[BasicAuthPasswordKey].StringValue(); pwd != "password" {
+				t.Errorf("expected the generated password to be shared, got %s", pwd)
+			}
+
+			// The kubelet could not authenticate, the probe runs in the container
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			ctr := dep["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			probe := ctr["readinessProbe"].ObjectValue()
+			if _, ok := probe["httpGet"]; ok {
+				t.Error("expected no HTTP readiness probe with the basic auth")
+			}
+			if _, ok := probe["exec"]; !ok {
+				t.Error("expected an exec readiness probe with the basic auth")
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package parts
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// rolledOut resolves to true once all the desired replicas are ready.
+// Deployments and StatefulSets are awaited by the provider, so it mostly
+// guards against a rollout reported done while pods are still starting.
+func rolledOut(replicas, ready pulumi.IntPtrOutput) pulumi.BoolOutput {
+	return pulumi.All(replicas, ready).ApplyT(func(all []any) bool {
+		desired, ready := 1, 0
+		if r, ok := all[0].(*int); ok && r != nil {
+			desired = *r
+		}
+		if r, ok := all[1].(*int); ok && r != nil {
+			ready = *r
+		}
+		return ready >= desired
+	}).(pulumi.BoolOutput)
+}
@@ -0,0 +1,129 @@
+package parts
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// RedactionArgs scrubs the attributes of the signals (resource, span,
+	// span event, data point and log record ones) before they are stored,
+	// e.g. player usernames, IPs or flags.
+	//
+	// Both the Jaeger and Prometheus path, and the cold extract one, are
+	// redacted unless kept raw, e.g. to archive the raw signals if the
+	// policy allows.
+	RedactionArgs struct {
+		// DeleteKeys are the attribute keys deleted.
+		DeleteKeys []string
+
+		// MaskPatterns are regexes of the attribute values parts replaced
+		// by RedactedMask.
+		MaskPatterns []string
+
+		// HashPatterns are regexes of the attribute values parts replaced
+		// by their SHA256 checksum, such that they could still be correlated.
+		// They apply after the MaskPatterns.
+		HashPatterns []string
+
+		// RawStorage keeps the signals sent to Jaeger and Prometheus raw.
+		RawStorage bool
+
+		// RawColdExtract keeps the signals written on the cold extract PVC
+		// raw.
+		RawColdExtract bool
+	}
+
+	// redactionContext are the OTTL contexts holding attributes, under a
+	// transform processor statements key.
+	redactionContext struct {
+		Key      string
+		Contexts []string
+	}
+)
+
+const (
+	// RedactedMask replaces the attribute values parts matching a mask
+	// pattern.
+	RedactedMask = "****"
+
+	redactionProcessor = "transform/redaction"
+)
+
+// redactionContexts are the OTTL contexts redacted, by signal.
+var redactionContexts = []redactionContext{
+	{Key: "trace_statements", Contexts: []string{"resource", "span", "spanevent"}},
+	{Key: "metric_statements", Contexts: []string{"resource", "datapoint"}},
+	{Key: "log_statements", Contexts: []string{"resource", "log"}},
+}
+
+// checkRedaction validates the redaction before it is rendered, such that
+// errors are reported at preview time rather than by the collector.
+func checkRedaction(r *RedactionArgs, coldExtract bool) (merr error) {
+	if len(r.DeleteKeys)+len(r.MaskPatterns)+len(r.HashPatterns) == 0 {
+		merr = multierr.Append(merr, errors.New("redaction requires at least one key or pattern"))
+	}
+	if r.RawStorage && (r.RawColdExtract || !coldExtract) {
+		merr = multierr.Append(merr, errors.New("redaction applies to no path, all are kept raw"))
+	}
+	if r.RawColdExtract && !coldExtract {
+		merr = multierr.Append(merr, errors.New("raw cold extract requires cold extract"))
+	}
+	for _, key := range r.DeleteKeys {
+		if key == "" {
+			merr = multierr.Append(merr, errors.New("empty redaction key"))
+		}
+	}
+	for _, pattern := range slices.Concat(r.MaskPatterns, r.HashPatterns) {
+		if pattern == "" {
+			merr = multierr.Append(merr, errors.New("empty redaction pattern"))
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			merr = multierr.Append(merr, errors.Wrapf(err, "invalid redaction pattern %q", pattern))
+		}
+	}
+	return
+}
+
+// redactStorage returns whether the signals sent to Jaeger and Prometheus
+// are redacted.
+func redactStorage(args *OtelCollectorArgs) bool {
+	return args.Redaction != nil && !args.Redaction.RawStorage
+}
+
+// redactColdExtract returns whether the signals written on the cold extract
+// PVC are redacted.
+func redactColdExtract(args *OtelCollectorArgs) bool {
+	return args.ColdExtract && args.Redaction != nil && !args.Redaction.RawColdExtract
+}
+
+// statements returns the OTTL statements redacting the attributes, quoted
+// as YAML strings. Keys are deleted first, then values masked and hashed.
+func (r *RedactionArgs) statements() []string {
+	stmts := make([]string, 0, len(r.DeleteKeys)+len(r.MaskPatterns)+len(r.HashPatterns))
+	for _, key := range r.DeleteKeys {
+		stmts = append(stmts, "delete_key(attributes, "+ottlString(key)+")")
+	}
+	for _, pattern := range r.MaskPatterns {
+		stmts = append(stmts, "replace_all_patterns(attributes, \"value\", "+ottlString(pattern)+", "+ottlString(RedactedMask)+")")
+	}
+	for _, pattern := range r.HashPatterns {
+		stmts = append(stmts, "replace_all_patterns(attributes, \"value\", "+ottlString(pattern)+", \"$0\", SHA256)")
+	}
+	for i, stmt := range stmts {
+		// The collector expands $ in its configuration, $$ escapes it
+		stmt = strings.ReplaceAll(stmt, "$", "$$")
+		stmts[i] = "'" + strings.ReplaceAll(stmt, "'", "''") + "'"
+	}
+	return stmts
+}
+
+// ottlString quotes s as an OTTL string literal.
+func ottlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,179 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_OtelCollector_Redaction(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args   *OtelCollectorArgs
+		Golden string
+	}{
+		"delete-mask-hash": {
+			Args: &OtelCollectorArgs{
+				Redaction: &RedactionArgs{
+					DeleteKeys:   []string{"enduser.id", "client.address"},
+					MaskPatterns: []string{`flag\{[^}]*\}`},
+					HashPatterns: []string{`^player-[a-z0-9]+$`},
+				},
+			},
+			Golden: "otel-redaction.golden.yaml",
+		},
+		"cold-extract": {
+			Args: &OtelCollectorArgs{
+				ColdExtract: true,
+				Redaction: &RedactionArgs{
+					DeleteKeys: []string{"enduser.id"},
+				},
+			},
+			Golden: "otel-redaction-cold-extract.golden.yaml",
+		},
+		"raw-cold-extract": {
+			Args: &OtelCollectorArgs{
+				ColdExtract: true,
+				Redaction: &RedactionArgs{
+					DeleteKeys:     []string{"enduser.id"},
+					RawColdExtract: true,
+				},
+			},
+			Golden: "otel-redaction-raw-cold-extract.golden.yaml",
+		},
+		"raw-storage-failover": {
+			Args: &OtelCollectorArgs{
+				ColdExtract:    true,
+				TracesFailover: true,
+				Redaction: &RedactionArgs{
+					DeleteKeys: []string{"enduser.id"},
+					RawStorage: true,
+				},
+			},
+			Golden: "otel-redaction-raw-storage.golden.yaml",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := renderOtelConfigT(t, (&OtelCollector{}).defaults(tt.Args))
+
+			b, err := os.ReadFile(filepath.Join("testdata", tt.Golden))
+			if err != nil {
+				t.Fatalf("reading golden file: %s", err)
+			}
+			expected := map[string]any{}
+			if err := yaml.Unmarshal(b, &expected); err != nil {
+				t.Fatalf("invalid golden file: %s", err)
+			}
+			for key := range expected {
+				if !reflect.DeepEqual(cfg[key], expected[key]) {
+					t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Redaction_Statements(t *testing.T) {
+	t.Parallel()
+
+	// Quotes survive both the YAML and OTTL parsing, and $ is escaped from
+	// the collector expansion
+	r := &RedactionArgs{
+		DeleteKeys:   []string{`player"name`},
+		MaskPatterns: []string{`it's \d+$`},
+	}
+	doc := "statements: [" + r.statements()[0] + ", " + r.statements()[1] + "]"
+	parsed := struct {
+		Statements []string `yaml:"statements"`
+	}{}
+	if err := yaml.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("invalid statements: %s\n%s", err, doc)
+	}
+	expected := []string{
+		`delete_key(attributes, "player\"name")`,
+		`replace_all_patterns(attributes, "value", "it's \\d+$$", "****")`,
+	}
+	if !reflect.DeepEqual(parsed.Statements, expected) {
+		t.Errorf("expected statements %v, got %v", expected, parsed.Statements)
+	}
+}
+
+func Test_U_OtelCollector_Redaction_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		ColdExtract bool
+		Redaction   *RedactionArgs
+		ExpectErr   bool
+	}{
+		"valid": {
+			ColdExtract: true,
+			Redaction: &RedactionArgs{
+				DeleteKeys:     []string{"enduser.id"},
+				MaskPatterns:   []string{`flag\{[^}]*\}`},
+				RawColdExtract: true,
+			},
+		},
+		"no-rule": {
+			Redaction: &RedactionArgs{},
+			ExpectErr: true,
+		},
+		"empty-key": {
+			Redaction: &RedactionArgs{DeleteKeys: []string{""}},
+			ExpectErr: true,
+		},
+		"invalid-pattern": {
+			Redaction: &RedactionArgs{HashPatterns: []string{`player-(`}},
+			ExpectErr: true,
+		},
+		"all-raw": {
+			ColdExtract: true,
+			Redaction: &RedactionArgs{
+				DeleteKeys:     []string{"enduser.id"},
+				RawStorage:     true,
+				RawColdExtract: true,
+			},
+			ExpectErr: true,
+		},
+		"raw-storage-without-cold-extract": {
+			Redaction: &RedactionArgs{
+				DeleteKeys: []string{"enduser.id"},
+				RawStorage: true,
+			},
+			ExpectErr: true,
+		},
+		"raw-cold-extract-without-cold-extract": {
+			Redaction: &RedactionArgs{
+				DeleteKeys:     []string{"enduser.id"},
+				RawColdExtract: true,
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:     pulumi.String("http://jaeger:4317"),
+				PrometheusURL: pulumi.String("http://prometheus:9090"),
+				ColdExtract:   tt.ColdExtract,
+				Redaction:     tt.Redaction,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package parts
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type (
+	// RemoteWriteWALArgs buffers the metrics remote written to Prometheus in
+	// a write-ahead log, on an emptyDir volume of the collector pod, rather
+	// than in memory only: the ones not yet written are sent once Prometheus
+	// is back from a restart. Each replica writes its own WAL, which is lost
+	// along its pod when rescheduled.
+	// Does not apply to PrometheusOTLP.
+	RemoteWriteWALArgs struct {
+		// Directory the volume is mounted at, holding the WAL.
+		// Defaults to /data/wal.
+		Directory string
+
+		// BufferSize is the number of objects read from the WAL at once.
+		// Defaults to 300.
+		BufferSize int
+
+		// TruncateFrequency is how often the WAL is truncated from the
+		// metrics written. Defaults to 1m.
+		TruncateFrequency time.Duration
+
+		// SizeLimit of the emptyDir volume, as a Kubernetes quantity: the
+		// pod is evicted past it. Defaults to 256Mi.
+		SizeLimit string
+	}
+)
+
+const (
+	defaultWALDirectory         = "/data/wal"
+	defaultWALBufferSize        = 300
+	defaultWALTruncateFrequency = time.Minute
+	defaultWALSizeLimit         = "256Mi"
+
+	otelWALVolume = "wal"
+)
+
+func (wal *RemoteWriteWALArgs) defaults() {
+	if wal.Directory == "" {
+		wal.Directory = defaultWALDirectory
+	}
+	if wal.BufferSize == 0 {
+		wal.BufferSize = defaultWALBufferSize
+	}
+	if wal.TruncateFrequency == 0 {
+		wal.TruncateFrequency = defaultWALTruncateFrequency
+	}
+	if wal.SizeLimit == "" {
+		wal.SizeLimit = defaultWALSizeLimit
+	}
+}
+
+// checkRemoteWriteWAL validates the WAL before it is rendered and mounted.
+func checkRemoteWriteWAL(wal *RemoteWriteWALArgs, exporter string) (merr error) {
+	if exporter != prometheusRemoteWriteExporter {
+		merr = multierr.Append(merr, errors.Errorf("remote write WAL requires the prometheusremotewrite exporter, not the %s one", exporter))
+	}
+	dir := path.Clean(wal.Directory)
+	switch {
+	case !path.IsAbs(wal.Directory):
+		merr = multierr.Append(merr, errors.Errorf("remote write WAL directory %s is not absolute", wal.Directory))
+	case dir == "/":
+		merr = multierr.Append(merr, errors.New("remote write WAL directory could not be the root"))
+	default:
+		// The volumes could not be mounted within each other
+		for _, mounted := range []string{"/etc/otel-collector", "/data/collector"} {
+			if dir == mounted || strings.HasPrefix(dir, mounted+"/") || strings.HasPrefix(mounted, dir+"/") {
+				merr = multierr.Append(merr, errors.Errorf("remote write WAL directory %s overlaps the %s mount", wal.Directory, mounted))
+			}
+		}
+	}
+	if wal.BufferSize < 0 {
+		merr = multierr.Append(merr, errors.New("remote write WAL buffer size could not be negative"))
+	}
+	if wal.TruncateFrequency < 0 {
+		merr = multierr.Append(merr, errors.New("remote write WAL truncate frequency could not be negative"))
+	}
+	if q, err := resource.ParseQuantity(wal.SizeLimit); err != nil {
+		merr = multierr.Append(merr, errors.Wrapf(err, "invalid remote write WAL size limit %s", wal.SizeLimit))
+	} else if q.Sign() <= 0 {
+		merr = multierr.Append(merr, errors.Errorf("remote write WAL size limit %s is not positive", wal.SizeLimit))
+	}
+	return
+}
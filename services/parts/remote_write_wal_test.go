@@ -0,0 +1,162 @@
+package parts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_OtelCollector_RemoteWriteWAL(t *testing.T) {
+	t.Parallel()
+
+	args := (&OtelCollector{}).defaults(&OtelCollectorArgs{
+		QueueSize: 500,
+		RemoteWriteWAL: &RemoteWriteWALArgs{
+			TruncateFrequency: 30 * time.Second,
+		},
+	})
+	cfg := renderOtelConfigT(t, args)
+
+	b, err := os.ReadFile(filepath.Join("testdata", "otel-remote-write-wal.golden.yaml"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	expected := map[string]any{}
+	if err := yaml.Unmarshal(b, &expected); err != nil {
+		t.Fatalf("invalid golden file: %s", err)
+	}
+	for _, key := range []string{"exporters", "service"} {
+		if !reflect.DeepEqual(cfg[key], expected[key]) {
+			t.Errorf("expected %s %v, got %v", key, expected[key], cfg[key])
+		}
+	}
+}
+
+func Test_U_OtelCollector_RemoteWriteWAL_Volume(t *testing.T) {
+	t.Parallel()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+			Namespace:     pulumi.String("monitoring"),
+			JaegerURL:     pulumi.String("http://jaeger:4317"),
+			PrometheusURL: pulumi.String("http://prometheus:9090"),
+			RemoteWriteWAL: &RemoteWriteWALArgs{
+				Directory: "/var/lib/otel-collector/wal",
+				SizeLimit: "64Mi",
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The WAL directory is the mount of its own emptyDir
+	spec := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()
+	mounted := false
+	for _, vm := range spec["containers"].ArrayValue()[0].ObjectValue()["volumeMounts"].ArrayValue() {
+		vm := vm.ObjectValue()
+		if vm["name"].StringValue() == otelWALVolume {
+			mounted = vm["mountPath"].StringValue() == "/var/lib/otel-collector/wal"
+		}
+	}
+	if !mounted {
+		t.Error("expected the WAL volume to be mounted at its directory")
+	}
+	sized := false
+	for _, v := range spec["volumes"].ArrayValue() {
+		v := v.ObjectValue()
+		if v["name"].StringValue() != otelWALVolume {
+			continue
+		}
+		ed, ok := v["emptyDir"]
+		sized = ok && ed.ObjectValue()["sizeLimit"].StringValue() == "64Mi"
+	}
+	if !sized {
+		t.Error("expected the WAL volume to be an emptyDir limited to 64Mi")
+	}
+}
+
+func Test_U_OtelCollector_RemoteWriteWAL_Check(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		WAL            *RemoteWriteWALArgs
+		PrometheusOTLP bool
+		ExpectErr      bool
+	}{
+		"defaults": {
+			WAL: &RemoteWriteWALArgs{},
+		},
+		"custom": {
+			WAL: &RemoteWriteWALArgs{
+				Directory:         "/var/lib/otel-collector/wal",
+				BufferSize:        1000,
+				TruncateFrequency: 2 * time.Minute,
+				SizeLimit:         "1Gi",
+			},
+		},
+		"prometheus-otlp": {
+			WAL:            &RemoteWriteWALArgs{},
+			PrometheusOTLP: true,
+			ExpectErr:      true,
+		},
+		"relative-directory": {
+			WAL:       &RemoteWriteWALArgs{Directory: "data/wal"},
+			ExpectErr: true,
+		},
+		"root-directory": {
+			WAL:       &RemoteWriteWALArgs{Directory: "/"},
+			ExpectErr: true,
+		},
+		"cold-extract-directory": {
+			WAL:       &RemoteWriteWALArgs{Directory: "/data/collector/wal"},
+			ExpectErr: true,
+		},
+		"config-parent-directory": {
+			WAL:       &RemoteWriteWALArgs{Directory: "/etc"},
+			ExpectErr: true,
+		},
+		"negative-buffer-size": {
+			WAL:       &RemoteWriteWALArgs{BufferSize: -1},
+			ExpectErr: true,
+		},
+		"negative-truncate-frequency": {
+			WAL:       &RemoteWriteWALArgs{TruncateFrequency: -time.Second},
+			ExpectErr: true,
+		},
+		"invalid-size-limit": {
+			WAL:       &RemoteWriteWALArgs{SizeLimit: "a lot"},
+			ExpectErr: true,
+		},
+		"zero-size-limit": {
+			WAL:       &RemoteWriteWALArgs{SizeLimit: "0"},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			otel := &OtelCollector{}
+			err := otel.check(otel.defaults(&OtelCollectorArgs{
+				JaegerURL:      pulumi.String("http://jaeger:4317"),
+				PrometheusURL:  pulumi.String("http://prometheus:9090"),
+				PrometheusOTLP: tt.PrometheusOTLP,
+				RemoteWriteWAL: tt.WAL,
+			}))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
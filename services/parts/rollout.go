@@ -0,0 +1,59 @@
+package parts
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// RolloutArgs stage the rollouts of the Deployments, e.g. for the changes
+// applied during a live event to only roll out in a maintenance window.
+type RolloutArgs struct {
+	// Paused records the changes of the pod template, e.g. a new
+	// configuration, without rolling them out until resumed (see the
+	// rollout package). The provider does not await the paused Deployments.
+	Paused bool
+
+	// MinReadySeconds a new pod must be ready for before the rollout goes
+	// on, for a crash-looping one to stop it early.
+	// Defaults to 0, as Kubernetes.
+	MinReadySeconds int
+}
+
+// skipAwaitAnnotation makes the provider not await the rollout of the
+// resource, which never completes while paused.
+const skipAwaitAnnotation = "pulumi.com/skipAwait"
+
+func checkRollout(rollout *RolloutArgs) error {
+	if rollout != nil && rollout.MinReadySeconds < 0 {
+		return errors.New("min ready seconds could not be negative")
+	}
+	return nil
+}
+
+// rolloutPaused returns the paused field of a Deployment spec.
+func rolloutPaused(rollout *RolloutArgs) pulumi.BoolPtrInput {
+	if rollout == nil || !rollout.Paused {
+		return nil
+	}
+	return pulumi.BoolPtr(true)
+}
+
+// rolloutMinReadySeconds returns the minReadySeconds field of a Deployment
+// or StatefulSet spec.
+func rolloutMinReadySeconds(rollout *RolloutArgs) pulumi.IntPtrInput {
+	if rollout == nil || rollout.MinReadySeconds == 0 {
+		return nil
+	}
+	return pulumi.IntPtr(rollout.MinReadySeconds)
+}
+
+// rolloutAnnotations returns the annotations of a Deployment metadata, for
+// the provider not to await it while paused. Returns nil otherwise.
+func rolloutAnnotations(rollout *RolloutArgs) pulumi.StringMapInput {
+	if rollout == nil || !rollout.Paused {
+		return nil
+	}
+	return pulumi.StringMap{
+		skipAwaitAnnotation: pulumi.String("true"),
+	}
+}
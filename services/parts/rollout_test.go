@@ -0,0 +1,175 @@
+package parts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+// provisionRollout provisions the part owning the Deployment, with the
+// rollout and a configuration changed by variant, and returns the
+// Deployment inputs.
+func provisionRollout(t *testing.T, dep string, rollout *RolloutArgs, variant int) resource.PropertyMap {
+	t.Helper()
+
+	m := &mocks.Mocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) (err error) {
+		switch dep {
+		case "otel":
+			_, err = NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+				Namespace:     pulumi.String("monitoring"),
+				JaegerURL:     pulumi.String("http://jaeger:4317"),
+				PrometheusURL: pulumi.String("http://prometheus:9090"),
+				QueueSize:     1000 + variant,
+				Rollout:       rollout,
+			})
+		case "jaeger":
+			_, err = NewJaeger(ctx, "jaeger", &JaegerArgs{
+				Namespace:       pulumi.String("monitoring"),
+				DisableSPM:      true,
+				MemoryMaxTraces: 1000 + variant,
+				Rollout:         rollout,
+			})
+		case "prometheus":
+			_, err = NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+				Namespace:       pulumi.String("monitoring"),
+				RemoteWriteURLs: pulumi.ToStringArray([]string{fmt.Sprintf("http://remote-%d:9090/api/v1/write", variant)}),
+				Rollout:         rollout,
+			})
+		}
+		return
+	}, pulumi.WithMocks("monitoring", "test", m))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	inputs := m.ByName("kubernetes:apps/v1:Deployment", dep)
+	if inputs == nil {
+		t.Fatalf("expected a %s deployment", dep)
+	}
+	return inputs
+}
+
+func Test_U_Rollout(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Rollout             *RolloutArgs
+		ExpectPaused        bool
+		ExpectMinReadySecs  float64
+		ExpectMinReadyUnset bool
+		ExpectSkipAwait     bool
+	}{
+		"default": {
+			ExpectMinReadyUnset: true,
+		},
+		"paused": {
+			Rollout: &RolloutArgs{
+				Paused:          true,
+				MinReadySeconds: 30,
+			},
+			ExpectPaused:       true,
+			ExpectMinReadySecs: 30,
+			ExpectSkipAwait:    true,
+		},
+		"min-ready-seconds": {
+			Rollout: &RolloutArgs{
+				MinReadySeconds: 10,
+			},
+			ExpectMinReadySecs: 10,
+		},
+	}
+
+	for testname, tt := range tests {
+		for _, dep := range []string{"otel", "jaeger", "prometheus"} {
+			t.Run(testname+"/"+dep, func(t *testing.T) {
+				t.Parallel()
+
+				inputs := provisionRollout(t, dep, tt.Rollout, 0)
+				spec := inputs["spec"].ObjectValue()
+
+				paused := spec["paused"]
+				if (paused.IsBool() && paused.BoolValue()) != tt.ExpectPaused {
+					t.Errorf("expected paused: %t, got %v", tt.ExpectPaused, paused)
+				}
+				mrs, ok := spec["minReadySeconds"]
+				if tt.ExpectMinReadyUnset {
+					if ok && !mrs.IsNull() {
+						t.Errorf("expected no min ready seconds, got %v", mrs)
+					}
+				} else if !mrs.IsNumber() || mrs.NumberValue() != tt.ExpectMinReadySecs {
+					t.Errorf("expected min ready seconds %v, got %v", tt.ExpectMinReadySecs, mrs)
+				}
+
+				skipAwait := false
+				if ann, ok := inputs["metadata"].ObjectValue()["annotations"]; ok && ann.IsObject() {
+					v, ok := ann.ObjectValue()[skipAwaitAnnotation]
+					skipAwait = ok && v.StringValue() == "true"
+				}
+				if skipAwait != tt.ExpectSkipAwait {
+					t.Errorf("expected skip await: %t, got %t", tt.ExpectSkipAwait, skipAwait)
+				}
+			})
+		}
+	}
+}
+
+func Test_U_Rollout_ConfigChecksum(t *testing.T) {
+	t.Parallel()
+
+	checksum := func(inputs resource.PropertyMap) string {
+		md := inputs["spec"].ObjectValue()["template"].ObjectValue()["metadata"].ObjectValue()
+		ann, ok := md["annotations"]
+		if !ok || !ann.IsObject() {
+			return ""
+		}
+		v, ok := ann.ObjectValue()[ConfigChecksumAnnotation]
+		if !ok {
+			return ""
+		}
+		return v.StringValue()
+	}
+
+	// While paused, a change of the configuration is still recorded in the
+	// pod template, for the rollout to pick it up once resumed
+	paused := &RolloutArgs{Paused: true}
+	for _, dep := range []string{"otel", "jaeger", "prometheus"} {
+		t.Run(dep, func(t *testing.T) {
+			t.Parallel()
+
+			before := checksum(provisionRollout(t, dep, paused, 0))
+			same := checksum(provisionRollout(t, dep, paused, 0))
+			after := checksum(provisionRollout(t, dep, paused, 1))
+			if before == "" {
+				t.Fatal("expected a config checksum annotation")
+			}
+			if before != same {
+				t.Errorf("expected the checksum to be stable, got %s then %s", before, same)
+			}
+			if before == after {
+				t.Errorf("expected the checksum to change along the configuration, got %s", after)
+			}
+		})
+	}
+}
+
+func Test_U_Rollout_Check(t *testing.T) {
+	t.Parallel()
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+			Namespace: pulumi.String("monitoring"),
+			Rollout: &RolloutArgs{
+				MinReadySeconds: -1,
+			},
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+	if err == nil {
+		t.Fatal("expected negative min ready seconds to be rejected")
+	}
+}
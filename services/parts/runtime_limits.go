@@ -0,0 +1,107 @@
+package parts
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type (
+	// otelRuntimeLimits are the bounds of the OTEL Collector derived from
+	// its container limits, for the Go runtime and the memory_limiter
+	// processor to agree with them rather than being OOM-killed.
+	otelRuntimeLimits struct {
+		// MemoryLimitMiB is both the GOMEMLIMIT and the memory_limiter hard
+		// limit, 0 without memory limit.
+		MemoryLimitMiB int64
+
+		// SpikeLimitMiB is the memory_limiter spike limit, the soft limit
+		// being the hard one minus it.
+		SpikeLimitMiB int64
+
+		// MaxProcs is the GOMAXPROCS, 0 without CPU limit.
+		MaxProcs int64
+	}
+)
+
+const (
+	// DefaultMemoryLimitPercent is the percentage of the memory limit the Go
+	// runtime and the memory_limiter are bound to, if not set. The rest is
+	// left to the non-heap memory.
+	DefaultMemoryLimitPercent = 80
+
+	// spikeLimitPercent is the share of the memory_limiter limit its spike
+	// one is, as the processor defaults to.
+	spikeLimitPercent = 20
+
+	memoryLimiterProcessor = "memory_limiter"
+)
+
+// runtimeLimits derives the runtime limits from the literal limits of the
+// resources. Resources given as outputs are not resolved, so are not
+// derived from.
+func runtimeLimits(args *OtelCollectorArgs) (otelRuntimeLimits, error) {
+	rl := otelRuntimeLimits{}
+	limits := literalLimits(args.Resources)
+
+	if mem, ok := limits["memory"]; ok {
+		q, err := resource.ParseQuantity(mem)
+		if err != nil {
+			return rl, errors.Wrap(err, "invalid memory limit")
+		}
+		rl.MemoryLimitMiB = q.Value() * int64(args.MemoryLimitPercent) / 100 / (1 << 20)
+		if rl.MemoryLimitMiB == 0 {
+			return rl, errors.Errorf("memory limit %s is too low to bound the runtime to %d%% of it", mem, args.MemoryLimitPercent)
+		}
+		rl.SpikeLimitMiB = rl.MemoryLimitMiB * spikeLimitPercent / 100
+	}
+	if cpu, ok := limits["cpu"]; ok {
+		q, err := resource.ParseQuantity(cpu)
+		if err != nil {
+			return rl, errors.Wrap(err, "invalid cpu limit")
+		}
+		// Rounded up, a fraction of a CPU still runs a thread
+		rl.MaxProcs = max(1, (q.MilliValue()+999)/1000)
+	}
+	return rl, nil
+}
+
+// literalLimits returns the limits of the resources, if literal.
+func literalLimits(res corev1.ResourceRequirementsInput) map[string]string {
+	args, ok := res.(corev1.ResourceRequirementsArgs)
+	if !ok {
+		return nil
+	}
+	sm, ok := args.Limits.(pulumi.StringMap)
+	if !ok {
+		return nil
+	}
+	limits := map[string]string{}
+	for k, v := range sm {
+		if str, ok := v.(pulumi.String); ok {
+			limits[k] = string(str)
+		}
+	}
+	return limits
+}
+
+// env returns the environment variables bounding the Go runtime.
+func (rl otelRuntimeLimits) env() corev1.EnvVarArray {
+	env := corev1.EnvVarArray{}
+	if rl.MemoryLimitMiB != 0 {
+		env = append(env, corev1.EnvVarArgs{
+			Name:  pulumi.String("GOMEMLIMIT"),
+			Value: pulumi.String(strconv.FormatInt(rl.MemoryLimitMiB, 10) + "MiB"),
+		})
+	}
+	if rl.MaxProcs != 0 {
+		env = append(env, corev1.EnvVarArgs{
+			Name:  pulumi.String("GOMAXPROCS"),
+			Value: pulumi.String(strconv.FormatInt(rl.MaxProcs, 10)),
+		})
+	}
+	return env
+}
@@ -0,0 +1,175 @@
+package parts
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"gopkg.in/yaml.v3"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_OtelCollector_RuntimeLimits(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Resources corev1.ResourceRequirementsInput
+		Percent   int
+		// ExpectedEnv are the Go runtime environment variables
+		ExpectedEnv map[string]string
+		// ExpectedLimiter are the memory_limiter limit_mib and
+		// spike_limit_mib, if any
+		ExpectedLimiter []int
+		ExpectErr       bool
+	}{
+		"no-resources": {
+			Resources:   nil,
+			ExpectedEnv: map[string]string{},
+		},
+		"requests-only": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Requests: pulumi.StringMap{
+					"cpu":    pulumi.String("250m"),
+					"memory": pulumi.String("512Mi"),
+				},
+			},
+			ExpectedEnv: map[string]string{},
+		},
+		"memory-limit": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("1Gi"),
+				},
+			},
+			ExpectedEnv: map[string]string{
+				"GOMEMLIMIT": "819MiB",
+			},
+			ExpectedLimiter: []int{819, 163},
+		},
+		"memory-cpu-limits-percent": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"cpu":    pulumi.String("1500m"),
+					"memory": pulumi.String("2G"),
+				},
+			},
+			Percent: 90,
+			ExpectedEnv: map[string]string{
+				"GOMEMLIMIT": "1716MiB",
+				"GOMAXPROCS": "2",
+			},
+			ExpectedLimiter: []int{1716, 343},
+		},
+		"small-cpu-limit": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"cpu": pulumi.String("100m"),
+				},
+			},
+			ExpectedEnv: map[string]string{
+				"GOMAXPROCS": "1",
+			},
+		},
+		"output-limits": {
+			// Not literal, hence not derived from
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.ToStringMap(map[string]string{"memory": "1Gi"}).ToStringMapOutput(),
+			},
+			ExpectedEnv: map[string]string{},
+		},
+		"invalid-memory-limit": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("lots"),
+				},
+			},
+			ExpectErr: true,
+		},
+		"memory-limit-too-low": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("1Mi"),
+				},
+			},
+			ExpectErr: true,
+		},
+		"percent-out-of-range": {
+			Resources: corev1.ResourceRequirementsArgs{
+				Limits: pulumi.StringMap{
+					"memory": pulumi.String("1Gi"),
+				},
+			},
+			Percent:   101,
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewOtelCollector(ctx, "otel", &OtelCollectorArgs{
+					Namespace:          pulumi.String("monitoring"),
+					JaegerURL:          pulumi.String("http://jaeger:4317"),
+					PrometheusURL:      pulumi.String("http://prometheus:9090"),
+					Resources:          tt.Resources,
+					MemoryLimitPercent: tt.Percent,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			ctr := m.ByName("kubernetes:apps/v1:Deployment", "otel")["spec"].ObjectValue()["template"].ObjectValue()["spec"].ObjectValue()["containers"].ArrayValue()[0].ObjectValue()
+			env := map[string]string{}
+			if ctr["env"].IsArray() {
+				for _, e := range ctr["env"].ArrayValue() {
+					env[e.ObjectValue()["name"].StringValue()] = e.ObjectValue()["value"].StringValue()
+				}
+			}
+			if !maps.Equal(env, tt.ExpectedEnv) {
+				t.Errorf("expected env %v, got %v", tt.ExpectedEnv, env)
+			}
+
+			// The memory_limiter agrees with GOMEMLIMIT, first of every
+			// pipeline receiving data
+			str := m.ByName("kubernetes:core/v1:ConfigMap", "otel-config")["data"].ObjectValue()["config"].StringValue()
+			cfg := struct {
+				Processors map[string]struct {
+					LimitMiB      int `yaml:"limit_mib"`
+					SpikeLimitMiB int `yaml:"spike_limit_mib"`
+				} `yaml:"processors"`
+				Service struct {
+					Pipelines map[string]struct {
+						Processors []string `yaml:"processors"`
+					} `yaml:"pipelines"`
+				} `yaml:"service"`
+			}{}
+			if err := yaml.Unmarshal([]byte(str), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s", err)
+			}
+			limiter, ok := cfg.Processors[memoryLimiterProcessor]
+			if ok != (tt.ExpectedLimiter != nil) {
+				t.Fatalf("expected memory_limiter: %t, got:\n%s", tt.ExpectedLimiter != nil, str)
+			}
+			if ok && !slices.Equal([]int{limiter.LimitMiB, limiter.SpikeLimitMiB}, tt.ExpectedLimiter) {
+				t.Errorf("expected memory_limiter limits %v, got %v", tt.ExpectedLimiter, []int{limiter.LimitMiB, limiter.SpikeLimitMiB})
+			}
+			for _, name := range otelSignals {
+				processors := cfg.Service.Pipelines[name].Processors
+				if first := len(processors) != 0 && processors[0] == memoryLimiterProcessor; first != ok {
+					t.Errorf("expected the memory_limiter first of the %s pipeline: %t, got %v", name, ok, processors)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package parts
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// ScrapeTarget is the metrics endpoint a part contributes to the
+	// Prometheus scrape configuration, reached through its Service in the
+	// Prometheus namespace.
+	ScrapeTarget struct {
+		// JobName of the scrape job, reserved for the part.
+		JobName string
+
+		// Service exposing the metrics, by its short name.
+		Service string
+
+		// Port the metrics are served on.
+		Port int
+
+		// MetricsPath defaults to the Prometheus one, i.e. /metrics.
+		MetricsPath string
+
+		// Headless Services resolve to every pod, each one scraped on its
+		// own. Others are scraped through their virtual IP.
+		Headless bool
+	}
+
+	// ScrapeConfig is an additional Prometheus scrape job.
+	// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#scrape_config
+	ScrapeConfig struct {
+		JobName        string `yaml:"job_name"`
+		ScrapeInterval string `yaml:"scrape_interval,omitempty"`
+		ScrapeTimeout  string `yaml:"scrape_timeout,omitempty"`
+		MetricsPath    string `yaml:"metrics_path,omitempty"`
+		Scheme         string `yaml:"scheme,omitempty"`
+
+		StaticConfigs []StaticConfig `yaml:"static_configs,omitempty"`
+		DNSSDConfigs  []DNSSDConfig  `yaml:"dns_sd_configs,omitempty"`
+
+		// KubernetesSDConfigs discover the targets through the Kubernetes
+		// API, which Prometheus is granted to only by the AnnotationScrape.
+		KubernetesSDConfigs []KubernetesSDConfig `yaml:"kubernetes_sd_configs,omitempty"`
+
+		// RelabelConfigs rewrite the targets labels before scraping.
+		RelabelConfigs []RelabelConfig `yaml:"relabel_configs,omitempty"`
+
+		// MetricRelabelConfigs rewrite the scraped samples before ingestion,
+		// e.g. to drop high-cardinality labels.
+		MetricRelabelConfigs []RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+
+		// Destinations are where the targets are reached, for the Prometheus
+		// egress NetworkPolicy to let the scraping through. At least one is
+		// required. Not rendered.
+		Destinations []ScrapeDestination `yaml:"-"`
+	}
+
+	// ScrapeDestination is where the targets of a scrape job are, either
+	// selected by labels or an IP block.
+	ScrapeDestination struct {
+		// NamespaceLabels selects the namespaces of the targets. If not set,
+		// only the Prometheus namespace is considered.
+		NamespaceLabels map[string]string
+
+		// PodLabels selects the target pods, every one of the namespaces if
+		// not set.
+		PodLabels map[string]string
+
+		// CIDR is the IP block of the targets, e.g. off-cluster ones.
+		CIDR string
+
+		// Ports the metrics are served on, every one if not set.
+		Ports []int
+	}
+
+	// StaticConfig is a static list of targets to scrape.
+	StaticConfig struct {
+		Targets []string          `yaml:"targets"`
+		Labels  map[string]string `yaml:"labels,omitempty"`
+	}
+
+	// DNSSDConfig discovers the targets to scrape through DNS queries, e.g.
+	// every pod behind a headless Service.
+	// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#dns_sd_config
+	DNSSDConfig struct {
+		Names []string `yaml:"names"`
+		Type  string   `yaml:"type,omitempty"`
+		Port  int      `yaml:"port,omitempty"`
+	}
+
+	// KubernetesSDConfig discovers the targets to scrape through the
+	// Kubernetes API, e.g. the pods of some namespaces.
+	// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kubernetes_sd_config
+	KubernetesSDConfig struct {
+		Role       string                  `yaml:"role"`
+		Namespaces *KubernetesSDNamespaces `yaml:"namespaces,omitempty"`
+	}
+
+	// KubernetesSDNamespaces restricts the discovery to the namespaces, all
+	// of them otherwise.
+	KubernetesSDNamespaces struct {
+		Names []string `yaml:"names"`
+	}
+
+	// RelabelConfig is a Prometheus relabeling step.
+	// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#relabel_config
+	RelabelConfig struct {
+		SourceLabels []string `yaml:"source_labels,omitempty,flow"`
+		Separator    string   `yaml:"separator,omitempty"`
+		Regex        string   `yaml:"regex,omitempty"`
+		Modulus      uint64   `yaml:"modulus,omitempty"`
+		TargetLabel  string   `yaml:"target_label,omitempty"`
+		// Replacement defaults to $1, set it to an empty string to blank the
+		// target label.
+		Replacement *string `yaml:"replacement,omitempty"`
+		Action      string  `yaml:"action,omitempty"`
+	}
+)
+
+var relabelActions = []string{
+	"replace", "keep", "drop", "keepequal", "dropequal", "hashmod",
+	"labelmap", "labeldrop", "labelkeep", "lowercase", "uppercase",
+}
+
+// ScrapeConfig returns the scrape job of the target.
+func (st ScrapeTarget) ScrapeConfig() ScrapeConfig {
+	sc := ScrapeConfig{
+		JobName:     st.JobName,
+		MetricsPath: st.MetricsPath,
+		// The Service is in the Prometheus namespace
+		Destinations: []ScrapeDestination{
+			{
+				Ports: []int{st.Port},
+			},
+		},
+	}
+	if st.Headless {
+		sc.DNSSDConfigs = []DNSSDConfig{
+			{
+				Names: []string{st.Service},
+				Type:  "A",
+				Port:  st.Port,
+			},
+		}
+	} else {
+		sc.StaticConfigs = []StaticConfig{
+			{
+				Targets: []string{hostPort(st.Service, st.Port)},
+			},
+		}
+	}
+	return sc
+}
+
+// checkScrapeTargets validates the scrape targets of the parts, each one
+// having a job of its own.
+func checkScrapeTargets(sts []ScrapeTarget) (merr error) {
+	jobs := map[string]struct{}{
+		"prometheus": {}, // reserved for self-scraping
+	}
+	for i, st := range sts {
+		if st.JobName == "" {
+			merr = multierr.Append(merr, fmt.Errorf("scrape target %d: job name is not provided", i))
+		} else if _, ok := jobs[st.JobName]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("scrape target %d: job name %s is already used", i, st.JobName))
+		}
+		jobs[st.JobName] = struct{}{}
+
+		if st.Service == "" {
+			merr = multierr.Append(merr, fmt.Errorf("scrape target %s: service is not provided", st.JobName))
+		}
+		if st.Port < 1 || st.Port > 65535 {
+			merr = multierr.Append(merr, fmt.Errorf("scrape target %s: port %d is out of the 1-65535 range", st.JobName, st.Port))
+		}
+	}
+	return
+}
+
+// checkScrapeConfigs validates the scrape configs before they are rendered,
+// such that errors are reported at preview time rather than by Prometheus.
+// The reserved job names are the scrape targets ones.
+func checkScrapeConfigs(scs []ScrapeConfig, reserved ...string) (merr error) {
+	jobs := map[string]struct{}{
+		"prometheus": {}, // reserved for self-scraping
+	}
+	for _, job := range reserved {
+		jobs[job] = struct{}{}
+	}
+	for i, sc := range scs {
+		if sc.JobName == "" {
+			merr = multierr.Append(merr, fmt.Errorf("scrape config %d: job name is not provided", i))
+		} else if _, ok := jobs[sc.JobName]; ok {
+			merr = multierr.Append(merr, fmt.Errorf("scrape config %d: job name %s is already used", i, sc.JobName))
+		}
+		jobs[sc.JobName] = struct{}{}
+
+		if len(sc.Destinations) == 0 {
+			merr = multierr.Append(merr, fmt.Errorf("scrape config %s: no destination is declared, Prometheus could not reach the targets", sc.JobName))
+		}
+		for j, sd := range sc.Destinations {
+			if err := sd.check(); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "scrape config %s: destination %d", sc.JobName, j))
+			}
+		}
+		for j, rc := range sc.RelabelConfigs {
+			if err := rc.check(); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "scrape config %s: relabel config %d", sc.JobName, j))
+			}
+		}
+		for j, rc := range sc.MetricRelabelConfigs {
+			if err := rc.check(); err != nil {
+				merr = multierr.Append(merr, errors.Wrapf(err, "scrape config %s: metric relabel config %d", sc.JobName, j))
+			}
+		}
+	}
+	return
+}
+
+func (sd ScrapeDestination) check() (merr error) {
+	hasLabels := len(sd.NamespaceLabels) != 0 || len(sd.PodLabels) != 0
+	switch {
+	case sd.CIDR != "" && hasLabels:
+		merr = multierr.Append(merr, errors.New("cidr and labels are mutually exclusive"))
+	case sd.CIDR != "":
+		if err := CheckCIDR(sd.CIDR); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	case !hasLabels && len(sd.Ports) == 0:
+		merr = multierr.Append(merr, errors.New("neither cidr, labels nor ports are set"))
+	}
+	for _, port := range sd.Ports {
+		if port < 1 || port > 65535 {
+			merr = multierr.Append(merr, fmt.Errorf("port %d is out of the 1-65535 range", port))
+		}
+	}
+	return
+}
+
+func (rc RelabelConfig) check() error {
+	action := rc.Action
+	if action == "" {
+		action = "replace"
+	}
+	if !slices.Contains(relabelActions, action) {
+		return fmt.Errorf("unknown action %s", rc.Action)
+	}
+	// Prometheus fully anchors the regular expressions (RE2 syntax)
+	if rc.Regex != "" {
+		if _, err := regexp.Compile("^(?s:" + rc.Regex + ")$"); err != nil {
+			return errors.Wrap(err, "invalid regex")
+		}
+	}
+	switch action {
+	case "replace", "hashmod", "lowercase", "uppercase", "keepequal", "dropequal":
+		if rc.TargetLabel == "" {
+			return fmt.Errorf("action %s requires a target label", action)
+		}
+	}
+	if action == "hashmod" && rc.Modulus == 0 {
+		return errors.New("action hashmod requires a modulus")
+	}
+	if (action == "labeldrop" || action == "labelkeep") && rc.Regex == "" {
+		return fmt.Errorf("action %s requires a regex", action)
+	}
+	return nil
+}
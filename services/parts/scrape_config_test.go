@@ -0,0 +1,285 @@
+package parts
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_U_ScrapeConfigs_Relabel(t *testing.T) {
+	t.Parallel()
+
+	empty, first := "", "$1"
+	dests := []ScrapeDestination{
+		{PodLabels: map[string]string{"app": "challenge"}, Ports: []int{8080}},
+	}
+
+	var tests = map[string]struct {
+		ScrapeConfigs []ScrapeConfig
+		ExpectErr     bool
+		Expected      map[string]any
+	}{
+		"labeldrop-client-ip": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: dests,
+					StaticConfigs: []StaticConfig{
+						{Targets: []string{"challenge:8080"}},
+					},
+					MetricRelabelConfigs: []RelabelConfig{
+						{Action: "labeldrop", Regex: "client_ip|peer_.*"},
+					},
+				},
+			},
+			Expected: map[string]any{
+				"job_name": "challenges",
+				"static_configs": []any{
+					map[string]any{"targets": []any{"challenge:8080"}},
+				},
+				"metric_relabel_configs": []any{
+					map[string]any{"action": "labeldrop", "regex": "client_ip|peer_.*"},
+				},
+			},
+		},
+		"replace-target-label": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: dests,
+					RelabelConfigs: []RelabelConfig{
+						{
+							SourceLabels: []string{"__address__"},
+							Regex:        "([^:]+):\\d+",
+							TargetLabel:  "instance",
+							Replacement:  &first,
+						},
+					},
+				},
+			},
+			Expected: map[string]any{
+				"job_name": "challenges",
+				"relabel_configs": []any{
+					map[string]any{
+						"source_labels": []any{"__address__"},
+						"regex":         "([^:]+):\\d+",
+						"target_label":  "instance",
+						"replacement":   "$1",
+					},
+				},
+			},
+		},
+		"blank-replacement": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: dests,
+					MetricRelabelConfigs: []RelabelConfig{
+						{TargetLabel: "client_ip", Replacement: &empty},
+					},
+				},
+			},
+			Expected: map[string]any{
+				"job_name": "challenges",
+				"metric_relabel_configs": []any{
+					map[string]any{"target_label": "client_ip", "replacement": ""},
+				},
+			},
+		},
+		"drop-series": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: dests,
+					MetricRelabelConfigs: []RelabelConfig{
+						{SourceLabels: []string{"__name__"}, Regex: "go_gc_.*", Action: "drop"},
+					},
+				},
+			},
+			Expected: map[string]any{
+				"job_name": "challenges",
+				"metric_relabel_configs": []any{
+					map[string]any{"source_labels": []any{"__name__"}, "regex": "go_gc_.*", "action": "drop"},
+				},
+			},
+		},
+		"hashmod": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: dests,
+					RelabelConfigs: []RelabelConfig{
+						{SourceLabels: []string{"__address__"}, Modulus: 4, TargetLabel: "__tmp_hash", Action: "hashmod"},
+					},
+				},
+			},
+			Expected: map[string]any{
+				"job_name": "challenges",
+				"relabel_configs": []any{
+					map[string]any{"source_labels": []any{"__address__"}, "modulus": 4, "target_label": "__tmp_hash", "action": "hashmod"},
+				},
+			},
+		},
+		"invalid-regex": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName: "challenges",
+					MetricRelabelConfigs: []RelabelConfig{
+						{Action: "labeldrop", Regex: "client_ip("},
+					},
+				},
+			},
+			ExpectErr: true,
+		},
+		"unknown-action": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName: "challenges",
+					RelabelConfigs: []RelabelConfig{
+						{Action: "delete", Regex: "client_ip"},
+					},
+				},
+			},
+			ExpectErr: true,
+		},
+		"replace-without-target": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName: "challenges",
+					RelabelConfigs: []RelabelConfig{
+						{SourceLabels: []string{"__address__"}},
+					},
+				},
+			},
+			ExpectErr: true,
+		},
+		"hashmod-without-modulus": {
+			ScrapeConfigs: []ScrapeConfig{
+				{
+					JobName: "challenges",
+					RelabelConfigs: []RelabelConfig{
+						{SourceLabels: []string{"__address__"}, TargetLabel: "__tmp_hash", Action: "hashmod"},
+					},
+				},
+			},
+			ExpectErr: true,
+		},
+		"reserved-job-name": {
+			ScrapeConfigs: []ScrapeConfig{
+				{JobName: "prometheus"},
+			},
+			ExpectErr: true,
+		},
+		"duplicated-job-name": {
+			ScrapeConfigs: []ScrapeConfig{
+				{JobName: "challenges"},
+				{JobName: "challenges"},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkScrapeConfigs(tt.ScrapeConfigs)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if tt.ExpectErr {
+				return
+			}
+
+			str, err := renderPrometheusConfig(&PrometheusArgs{
+				ExtraScrapeConfigs: tt.ScrapeConfigs,
+			}, nil)
+			if err != nil {
+				t.Fatalf("rendering configuration: %s", err)
+			}
+			cfg := map[string]any{}
+			if err := yaml.Unmarshal([]byte(str), &cfg); err != nil {
+				t.Fatalf("invalid configuration: %s\n%s", err, str)
+			}
+
+			scs := cfg["scrape_configs"].([]any)
+			if len(scs) != 2 {
+				t.Fatalf("expected the prometheus and extra scrape configs, got %v", scs)
+			}
+			if !reflect.DeepEqual(scs[1], tt.Expected) {
+				t.Fatalf("expected scrape config %v, got %v", tt.Expected, scs[1])
+			}
+		})
+	}
+}
+
+func Test_U_ScrapeConfigs_Destinations(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Destinations []ScrapeDestination
+		ExpectErr    bool
+	}{
+		"prometheus-namespace": {
+			Destinations: []ScrapeDestination{
+				{Ports: []int{8080}},
+			},
+		},
+		"labels": {
+			Destinations: []ScrapeDestination{
+				{
+					NamespaceLabels: map[string]string{"ctfer.io/kind": "challenge"},
+					PodLabels:       map[string]string{"app": "scoreboard"},
+					Ports:           []int{8080, 9100},
+				},
+			},
+		},
+		"cidr": {
+			Destinations: []ScrapeDestination{
+				{CIDR: "10.0.12.0/24"},
+			},
+		},
+		"no-destination": {
+			ExpectErr: true,
+		},
+		"empty-destination": {
+			Destinations: []ScrapeDestination{{}},
+			ExpectErr:    true,
+		},
+		"cidr-and-labels": {
+			Destinations: []ScrapeDestination{
+				{CIDR: "10.0.12.0/24", PodLabels: map[string]string{"app": "scoreboard"}},
+			},
+			ExpectErr: true,
+		},
+		"invalid-cidr": {
+			Destinations: []ScrapeDestination{
+				{CIDR: "10.0.12.0"},
+			},
+			ExpectErr: true,
+		},
+		"port-out-of-range": {
+			Destinations: []ScrapeDestination{
+				{Ports: []int{0}},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkScrapeConfigs([]ScrapeConfig{
+				{
+					JobName:      "challenges",
+					Destinations: tt.Destinations,
+				},
+			})
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package parts
+
+import (
+	"github.com/pkg/errors"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ExistingSecretArgs references a pre-existing Secret in the namespace of
+// the component, e.g. synced by an ExternalSecret, rather than the component
+// generating the credentials or a Secret from inline values.
+type ExistingSecretArgs struct {
+	Name pulumi.StringInput
+
+	// Key of the credential in the Secret.
+	// Defaults to the one the component would generate it at.
+	Key string
+}
+
+// key returns the key of the credential, or the default one.
+func (s *ExistingSecretArgs) key(def string) string {
+	if s.Key == "" {
+		return def
+	}
+	return s.Key
+}
+
+// checkExistingSecret validates the reference to an existing Secret, if any.
+func checkExistingSecret(s *ExistingSecretArgs) error {
+	if s != nil && s.Name == nil {
+		return errors.New("existing secret name is not provided")
+	}
+	return nil
+}
+
+// secretEnv is an environment variable of a container sourced from the key
+// of a Secret.
+type secretEnv struct {
+	Name   string
+	Secret pulumi.StringInput
+	Key    string
+}
+
+// secretEnvs are the secret-backed environment variables of a container, for
+// its configuration to reference the secret values by placeholder rather
+// than inlining them in a ConfigMap.
+type secretEnvs []secretEnv
+
+// add declares the environment variable from the key of the Secret, and
+// returns the placeholder to embed in the configuration.
+func (envs *secretEnvs) add(name string, secret pulumi.StringInput, key string) string {
+	*envs = append(*envs, secretEnv{
+		Name:   name,
+		Secret: secret,
+		Key:    key,
+	})
+	return envPlaceholder(name)
+}
+
+// envVars returns the environment variables referencing their Secret.
+func (envs secretEnvs) envVars() corev1.EnvVarArray {
+	vars := make(corev1.EnvVarArray, 0, len(envs))
+	for _, env := range envs {
+		vars = append(vars, corev1.EnvVarArgs{
+			Name: pulumi.String(env.Name),
+			ValueFrom: corev1.EnvVarSourceArgs{
+				SecretKeyRef: corev1.SecretKeySelectorArgs{
+					Name: env.Secret,
+					Key:  pulumi.String(env.Key),
+				},
+			},
+		})
+	}
+	return vars
+}
+
+// envPlaceholder is the substitution of the environment variable in an
+// OpenTelemetry Collector configuration, expanded when it starts.
+func envPlaceholder(name string) string {
+	return "${env:" + name + "}"
+}
@@ -0,0 +1,71 @@
+package parts
+
+import (
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// ServiceRef references a Service by its name, namespace and port, such
+// that the parts exchange it rather than the Service resource. It is either
+// managed by a part, or external (e.g. a pre-existing backend).
+type ServiceRef struct {
+	Name      pulumi.StringOutput
+	Namespace pulumi.StringOutput
+	Port      pulumi.IntOutput
+
+	// local is set for the managed Services, whose clients share the
+	// namespace.
+	local bool
+}
+
+// NewServiceRef references the managed Service, on its first port.
+func NewServiceRef(svc *corev1.Service) *ServiceRef {
+	return &ServiceRef{
+		Name:      svc.Metadata.Name().Elem(),
+		Namespace: svc.Metadata.Namespace().Elem(),
+		Port:      svc.Spec.Ports().Index(pulumi.Int(0)).Port(),
+		local:     true,
+	}
+}
+
+// NewExternalServiceRef references a Service not managed by the parts.
+func NewExternalServiceRef(name, namespace string, port int) *ServiceRef {
+	return &ServiceRef{
+		Name:      pulumi.String(name).ToStringOutput(),
+		Namespace: pulumi.String(namespace).ToStringOutput(),
+		Port:      pulumi.Int(port).ToIntOutput(),
+	}
+}
+
+// Host returns the host of the Service, qualified by its namespace, or
+// fully-qualified in the cluster domain if any, which resolves whatever the
+// DNS search path of the client (e.g. dnsPolicy=None or ndots tweaks).
+// Example: otlp-grpc.monitoring, otlp-grpc.monitoring.svc.cluster.local
+func (ref *ServiceRef) Host(clusterDomain string) pulumi.StringOutput {
+	if clusterDomain != "" {
+		return pulumi.Sprintf("%s.%s.svc.%s", ref.Name, ref.Namespace, clusterDomain)
+	}
+	return pulumi.Sprintf("%s.%s", ref.Name, ref.Namespace)
+}
+
+// Endpoint returns the host and port of the Service.
+// Example: otlp-grpc.monitoring:4317
+func (ref *ServiceRef) Endpoint(clusterDomain string) pulumi.StringOutput {
+	return pulumi.All(ref.Host(clusterDomain), ref.Port).ApplyT(func(all []any) string {
+		return hostPort(all[0].(string), all[1].(int))
+	}).(pulumi.StringOutput)
+}
+
+// URL returns the URL of the Service for the clients of the monitoring
+// namespace, with the given scheme. The host of a managed Service is its
+// name, unless a cluster domain is set.
+// Example: http://prometheus:9090, http://prometheus.backends:9090
+func (ref *ServiceRef) URL(scheme, clusterDomain string) pulumi.StringOutput {
+	host := ref.Host(clusterDomain)
+	if ref.local && clusterDomain == "" {
+		host = ref.Name
+	}
+	return pulumi.All(host, ref.Port).ApplyT(func(all []any) string {
+		return scheme + "://" + hostPort(all[0].(string), all[1].(int))
+	}).(pulumi.StringOutput)
+}
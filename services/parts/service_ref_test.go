@@ -0,0 +1,99 @@
+package parts
+
+import (
+	"sync"
+	"testing"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_ServiceRef(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		External         bool
+		ClusterDomain    string
+		ExpectedEndpoint string
+		ExpectedURL      string
+	}{
+		"managed": {
+			ExpectedEndpoint: "prometheus.monitoring:9090",
+			ExpectedURL:      "http://prometheus:9090",
+		},
+		"managed-fully-qualified": {
+			ClusterDomain:    "cluster.local",
+			ExpectedEndpoint: "prometheus.monitoring.svc.cluster.local:9090",
+			ExpectedURL:      "http://prometheus.monitoring.svc.cluster.local:9090",
+		},
+		"external": {
+			External:         true,
+			ExpectedEndpoint: "prometheus.monitoring:9090",
+			ExpectedURL:      "http://prometheus.monitoring:9090",
+		},
+		"external-fully-qualified": {
+			External:         true,
+			ClusterDomain:    "cluster.local",
+			ExpectedEndpoint: "prometheus.monitoring.svc.cluster.local:9090",
+			ExpectedURL:      "http://prometheus.monitoring.svc.cluster.local:9090",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			var endpoint, url string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				ref := NewExternalServiceRef("prometheus", "monitoring", 9090)
+				if !tt.External {
+					svc, err := corev1.NewService(ctx, "prometheus", &corev1.ServiceArgs{
+						Metadata: metav1.ObjectMetaArgs{
+							Name:      pulumi.String("prometheus"),
+							Namespace: pulumi.String("monitoring"),
+						},
+						Spec: corev1.ServiceSpecArgs{
+							Ports: corev1.ServicePortArray{
+								corev1.ServicePortArgs{
+									Name: pulumi.String("http"),
+									Port: pulumi.Int(9090),
+								},
+								corev1.ServicePortArgs{
+									Name: pulumi.String("grpc"),
+									Port: pulumi.Int(9091),
+								},
+							},
+						},
+					})
+					if err != nil {
+						wg.Done()
+						return err
+					}
+					ref = NewServiceRef(svc)
+				}
+				pulumi.All(ref.Endpoint(tt.ClusterDomain), ref.URL("http", tt.ClusterDomain)).ApplyT(func(all []any) error {
+					defer wg.Done()
+					endpoint, url = all[0].(string), all[1].(string)
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+			wg.Wait()
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if endpoint != tt.ExpectedEndpoint {
+				t.Errorf("expected endpoint %s, got %s", tt.ExpectedEndpoint, endpoint)
+			}
+			if url != tt.ExpectedURL {
+				t.Errorf("expected url %s, got %s", tt.ExpectedURL, url)
+			}
+		})
+	}
+}
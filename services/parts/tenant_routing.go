@@ -0,0 +1,88 @@
+package parts
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+)
+
+type (
+	// TenantRoutingArgs routes the cold extract signals into a directory
+	// per tenant, identified by a resource attribute. Signals of other
+	// tenants, or without the attribute, land in the default directory.
+	TenantRoutingArgs struct {
+		// Attribute is the resource attribute identifying the tenant.
+		// Defaults to ctfer.io/stack-name.
+		Attribute string
+
+		// Tenants are the attribute values routed into their own directory.
+		Tenants []string
+	}
+)
+
+const (
+	defaultTenantAttribute = "ctfer.io/stack-name"
+
+	// DefaultTenant is the route of the signals matching no tenant.
+	DefaultTenant = "default"
+
+	// coldExtractMountPath is where the collector mounts the PVC, the
+	// signals being written relatively to its root.
+	coldExtractMountPath = "/data/collector"
+)
+
+var (
+	// tenantRegex restricts the tenants to values safe both as directory
+	// names and collector component IDs.
+	tenantRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+	// attributeRegex restricts the attribute to values safe to quote in
+	// an OTTL condition.
+	attributeRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+)
+
+// checkTenantRouting validates the tenant routing before it is rendered,
+// such that errors are reported at preview time rather than by the collector.
+func checkTenantRouting(tr *TenantRoutingArgs) (merr error) {
+	if !attributeRegex.MatchString(tr.Attribute) {
+		merr = multierr.Append(merr, errors.Errorf("invalid tenant attribute %q", tr.Attribute))
+	}
+	if len(tr.Tenants) == 0 {
+		merr = multierr.Append(merr, errors.New("tenant routing requires at least one tenant"))
+	}
+	for i, tenant := range tr.Tenants {
+		if !tenantRegex.MatchString(tenant) {
+			merr = multierr.Append(merr, errors.Errorf("invalid tenant %q", tenant))
+		}
+		if tenant == DefaultTenant {
+			merr = multierr.Append(merr, errors.Errorf("tenant %s is reserved to the signals matching no tenant", DefaultTenant))
+		}
+		if slices.Contains(tr.Tenants[:i], tenant) {
+			merr = multierr.Append(merr, errors.Errorf("duplicated tenant %s", tenant))
+		}
+	}
+	return
+}
+
+// routes returns the tenants routes, the default one being last.
+func (tr *TenantRoutingArgs) routes() []string {
+	return append(slices.Clone(tr.Tenants), DefaultTenant)
+}
+
+// ColdExtractLayout describes where the cold extract signals land on the
+// PVC, relatively to its root, indexed by tenant. Without tenant routing,
+// all signals land in the same directory, indexed by the DefaultTenant.
+func ColdExtractLayout(tr *TenantRoutingArgs) map[string]string {
+	if tr == nil {
+		return map[string]string{
+			DefaultTenant: ".",
+		}
+	}
+	layout := map[string]string{}
+	for _, route := range tr.routes() {
+		layout[route] = route
+	}
+	return layout
+}
@@ -0,0 +1,42 @@
+package parts
+
+import (
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	zoneTopologyKey = "topology.kubernetes.io/zone"
+)
+
+// topologySpreadConstraints returns the constraints to set on the pod template
+// of a component. With a single replica there is nothing to spread so none are
+// returned. Explicit constraints take precedence over spreadAcrossZones, which
+// renders a best-effort constraint on the zones.
+func topologySpreadConstraints(
+	replicas int,
+	spreadAcrossZones bool,
+	constraints corev1.TopologySpreadConstraintArrayInput,
+	podLabels pulumi.StringMap,
+) corev1.TopologySpreadConstraintArrayInput {
+	if replicas <= 1 {
+		return nil
+	}
+	if constraints != nil {
+		return constraints
+	}
+	if !spreadAcrossZones {
+		return nil
+	}
+	return corev1.TopologySpreadConstraintArray{
+		corev1.TopologySpreadConstraintArgs{
+			MaxSkew:           pulumi.Int(1),
+			TopologyKey:       pulumi.String(zoneTopologyKey),
+			WhenUnsatisfiable: pulumi.String("ScheduleAnyway"),
+			LabelSelector: metav1.LabelSelectorArgs{
+				MatchLabels: podLabels,
+			},
+		},
+	}
+}
@@ -0,0 +1,124 @@
+package parts
+
+import (
+	"testing"
+
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+)
+
+func Test_U_TopologySpreadConstraints(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Replicas            int
+		SpreadAcrossZones   bool
+		Constraints         corev1.TopologySpreadConstraintArrayInput
+		ExpectedTopologyKey string
+	}{
+		"single-replica": {
+			Replicas:          1,
+			SpreadAcrossZones: true,
+		},
+		"single-replica-with-constraints": {
+			Replicas: 1,
+			Constraints: corev1.TopologySpreadConstraintArray{
+				corev1.TopologySpreadConstraintArgs{
+					MaxSkew:           pulumi.Int(1),
+					TopologyKey:       pulumi.String("kubernetes.io/hostname"),
+					WhenUnsatisfiable: pulumi.String("DoNotSchedule"),
+				},
+			},
+		},
+		"replicas-without-spread": {
+			Replicas: 3,
+		},
+		"spread-across-zones": {
+			Replicas:            3,
+			SpreadAcrossZones:   true,
+			ExpectedTopologyKey: "topology.kubernetes.io/zone",
+		},
+		"constraints-precedence": {
+			Replicas:          3,
+			SpreadAcrossZones: true,
+			Constraints: corev1.TopologySpreadConstraintArray{
+				corev1.TopologySpreadConstraintArgs{
+					MaxSkew:           pulumi.Int(1),
+					TopologyKey:       pulumi.String("kubernetes.io/hostname"),
+					WhenUnsatisfiable: pulumi.String("DoNotSchedule"),
+				},
+			},
+			ExpectedTopologyKey: "kubernetes.io/hostname",
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			m := &mocks.Mocks{}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				_, err := NewPrometheus(ctx, "prometheus", &PrometheusArgs{
+					Namespace:                 pulumi.String("monitoring"),
+					Replicas:                  tt.Replicas,
+					SpreadAcrossZones:         tt.SpreadAcrossZones,
+					TopologySpreadConstraints: tt.Constraints,
+				})
+				return err
+			}, pulumi.WithMocks("monitoring", "test", m))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			dep := m.ByName("kubernetes:apps/v1:Deployment", "prometheus")
+			if dep == nil {
+				t.Fatal("prometheus deployment not found")
+			}
+			spec := dep["spec"].ObjectValue()
+			if replicas := int(spec["replicas"].NumberValue()); replicas != tt.Replicas {
+				t.Errorf("expected %d replicas, got %d", tt.Replicas, replicas)
+			}
+
+			podSpec := spec["template"].ObjectValue()["spec"].ObjectValue()
+			tscs, ok := podSpec["topologySpreadConstraints"]
+			if tt.ExpectedTopologyKey == "" {
+				if ok && !tscs.IsNull() {
+					t.Fatalf("expected no topology spread constraints, got %v", tscs)
+				}
+				return
+			}
+			if !ok || !tscs.IsArray() || len(tscs.ArrayValue()) != 1 {
+				t.Fatalf("expected one topology spread constraint, got %v", tscs)
+			}
+			tsc := tscs.ArrayValue()[0].ObjectValue()
+			if key := tsc["topologyKey"].StringValue(); key != tt.ExpectedTopologyKey {
+				t.Errorf("expected topology key %s, got %s", tt.ExpectedTopologyKey, key)
+			}
+			if tt.Constraints == nil {
+				labels := tsc["labelSelector"].ObjectValue()["matchLabels"].ObjectValue()
+				if got := labels[resource.PropertyKey("app.kubernetes.io/component")]; !got.IsString() || got.StringValue() != "prometheus" {
+					t.Errorf("expected label selector on the prometheus pods, got %v", labels)
+				}
+			}
+		})
+	}
+}
+
+func Test_U_Replicas_Negative(t *testing.T) {
+	t.Parallel()
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := NewJaeger(ctx, "jaeger", &JaegerArgs{
+			Namespace:  pulumi.String("monitoring"),
+			DisableSPM: true,
+			Replicas:   -1,
+		})
+		return err
+	}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
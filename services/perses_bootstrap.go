@@ -0,0 +1,64 @@
+package services
+
+import (
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	netwv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// provisionPersesBootstrap allows the Perses bootstrap Job to poll the
+// Perses API, the namespace denying it else. The Job keeps polling until
+// the policies are created.
+func (mon *Monitoring) provisionPersesBootstrap(
+	ctx *pulumi.Context,
+	args *MonitoringArgs,
+	opts ...pulumi.ResourceOption,
+) (err error) {
+	mon.prsbootntp, err = netwv1.NewNetworkPolicy(ctx, "perses-bootstrap-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Namespace: mon.ns.Name,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Egress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: mon.perses.BootstrapPodLabels,
+			},
+			// Perses bootstrap -> Perses
+			Egress: parts.EgressRules(mon.flows, "perses-bootstrap-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	if err != nil {
+		return
+	}
+
+	mon.inprsbootntp, err = netwv1.NewNetworkPolicy(ctx, "in-perses-bootstrap-ntp", &netwv1.NetworkPolicyArgs{
+		Metadata: metav1.ObjectMetaArgs{
+			Labels: pulumi.StringMap{
+				"app.kubernetes.io/part-of":   pulumi.String("monitoring"),
+				"ctfer.io/monitoring-version": pulumi.String(args.BuildInfo.Version),
+				"ctfer.io/stack-name":         pulumi.String(ctx.Stack()),
+			},
+			Namespace: mon.ns.Name,
+		},
+		Spec: netwv1.NetworkPolicySpecArgs{
+			PolicyTypes: pulumi.ToStringArray([]string{
+				"Ingress",
+			}),
+			PodSelector: metav1.LabelSelectorArgs{
+				MatchLabels: mon.perses.PodLabels,
+			},
+			// Perses bootstrap -> Perses
+			Ingress: parts.IngressRules(mon.flows, "in-perses-bootstrap-ntp", mon.partSelectors()),
+		},
+	}, opts...)
+	return
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Presets size the Monitoring coherently for the expected load of an event.
+const (
+	PresetSmall  = "small"
+	PresetMedium = "medium"
+	PresetLarge  = "large"
+
+	// PresetCustom sets nothing, leaving every part to its own defaults.
+	PresetCustom = "custom"
+)
+
+// preset are the values a Preset sets, unless explicitly set in the
+// MonitoringArgs.
+type preset struct {
+	OTELResources corev1.ResourceRequirementsArgs
+	OTELQueueSize int
+	StorageSize   string
+
+	PrometheusResources corev1.ResourceRequirementsArgs
+	PrometheusRetention string
+
+	JaegerResources       corev1.ResourceRequirementsArgs
+	JaegerMemoryMaxTraces int
+}
+
+var presets = map[string]preset{
+	// small fits a few hundred players on a single node.
+	PresetSmall: {
+		OTELResources: resources("100m", "256Mi", "512Mi"),
+		OTELQueueSize: 1000,
+		StorageSize:   "5Gi",
+
+		PrometheusResources: resources("100m", "512Mi", "1Gi"),
+		PrometheusRetention: "7d",
+
+		JaegerResources:       resources("100m", "256Mi", "512Mi"),
+		JaegerMemoryMaxTraces: 50000,
+	},
+	// medium fits a thousand players.
+	PresetMedium: {
+		OTELResources: resources("250m", "512Mi", "1Gi"),
+		OTELQueueSize: 5000,
+		StorageSize:   "20Gi",
+
+		PrometheusResources: resources("250m", "1Gi", "2Gi"),
+		PrometheusRetention: "15d",
+
+		JaegerResources:       resources("250m", "1Gi", "2Gi"),
+		JaegerMemoryMaxTraces: 200000,
+	},
+	// large fits several thousand players, with per-instance challenges.
+	PresetLarge: {
+		OTELResources: resources("1", "1Gi", "2Gi"),
+		OTELQueueSize: 20000,
+		StorageSize:   "100Gi",
+
+		PrometheusResources: resources("1", "4Gi", "8Gi"),
+		PrometheusRetention: "30d",
+
+		JaegerResources:       resources("500m", "4Gi", "8Gi"),
+		JaegerMemoryMaxTraces: 500000,
+	},
+}
+
+// resources requests the cpu and memory, with a memory limit for the
+// container to be killed rather than the node starved.
+func resources(cpu, memory, memoryLimit string) corev1.ResourceRequirementsArgs {
+	return corev1.ResourceRequirementsArgs{
+		Requests: pulumi.StringMap{
+			"cpu":    pulumi.String(cpu),
+			"memory": pulumi.String(memory),
+		},
+		Limits: pulumi.StringMap{
+			"memory": pulumi.String(memoryLimit),
+		},
+	}
+}
+
+// presetDefaults sets the values of the preset, leaving the values set
+// untouched. Unknown presets are left for the check to reject.
+func presetDefaults(args *MonitoringArgs) {
+	p, ok := presets[args.Preset]
+	if !ok {
+		return
+	}
+
+	if args.OTELResources == nil {
+		args.OTELResources = p.OTELResources
+	}
+	if args.OTELQueueSize == 0 {
+		args.OTELQueueSize = p.OTELQueueSize
+	}
+	if args.StorageSize == nil {
+		args.StorageSize = pulumi.String(p.StorageSize)
+	} else {
+		args.StorageSize = args.StorageSize.ToStringOutput().ApplyT(func(size string) string {
+			if size == "" {
+				return p.StorageSize
+			}
+			return size
+		}).(pulumi.StringOutput)
+	}
+
+	if args.PrometheusResources == nil {
+		args.PrometheusResources = p.PrometheusResources
+	}
+	// The agent mode has no TSDB to retain
+	if args.PrometheusRetention == "" && !args.PrometheusAgentMode {
+		args.PrometheusRetention = p.PrometheusRetention
+	}
+
+	if args.JaegerResources == nil {
+		args.JaegerResources = p.JaegerResources
+	}
+	if args.JaegerMemoryMaxTraces == 0 {
+		args.JaegerMemoryMaxTraces = p.JaegerMemoryMaxTraces
+	}
+}
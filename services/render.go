@@ -0,0 +1,55 @@
+package services
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+type (
+	// Configs are the configurations the Monitoring parts are deployed with.
+	Configs struct {
+		OTELCollector string
+		Prometheus    string
+	}
+)
+
+const (
+	// RenderJaegerURL and RenderPrometheusURL stand for the URLs only known
+	// once the Monitoring is deployed, in the rendered configurations.
+	RenderJaegerURL     = "http://jaeger-collector:4317"
+	RenderPrometheusURL = "http://prometheus:9090"
+)
+
+// RenderConfigs renders the OTEL Collector and Prometheus configurations
+// the Monitoring would be deployed with, offline i.e. without the Pulumi
+// engine. It is meant to preview configuration changes, so requires literal
+// inputs where they reach the configurations (e.g. PrometheusRemoteWriteURLs).
+// The arguments are checked as when deploying, but are not modified.
+func RenderConfigs(args *MonitoringArgs) (*Configs, error) {
+	mon := &Monitoring{}
+	cpy := MonitoringArgs{}
+	if args != nil {
+		cpy = *args
+	}
+	cpy.BuildInfo = nil // only stamped on resources, don't default the caller's one
+
+	args = mon.defaults(&cpy)
+	if err := mon.check(args); err != nil {
+		return nil, err
+	}
+
+	promConfig, err := parts.RenderPrometheusConfig(prometheusArgs(args))
+	if err != nil {
+		return nil, errors.Wrap(err, "rendering prometheus config")
+	}
+	otelConfig, err := parts.RenderOtelConfig(otelCollectorArgs(args), RenderJaegerURL, RenderPrometheusURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "rendering otel collector config")
+	}
+
+	return &Configs{
+		OTELCollector: otelConfig,
+		Prometheus:    promConfig,
+	}, nil
+}
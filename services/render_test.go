@@ -0,0 +1,119 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+func Test_U_RenderConfigs(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args         *MonitoringArgs
+		ExpectErr    bool
+		ExpectedOtel []string
+		ExpectedProm []string
+	}{
+		"nil": {
+			Args:         nil,
+			ExpectedOtel: []string{RenderJaegerURL, RenderPrometheusURL},
+			ExpectedProm: []string{"scrape_configs:"},
+		},
+		"full": {
+			Args: &MonitoringArgs{
+				ColdExtract: true,
+				ColdExtractTenantRouting: &parts.TenantRoutingArgs{
+					Tenants: []string{"ctf-2026"},
+				},
+				DependencyGraph: true,
+				ExporterRetry: &parts.ExporterRetryArgs{
+					MaxElapsedTime: 10 * time.Minute,
+				},
+				PrometheusRemoteWriteURLs: pulumi.ToStringArray([]string{"http://mimir:9009/api/v1/push"}),
+				PrometheusQueryLog:        true,
+			},
+			ExpectedOtel: []string{"servicegraph", "routing/traces", "file/traces/ctf-2026", "max_elapsed_time: 10m0s"},
+			ExpectedProm: []string{"http://mimir:9009/api/v1/push", parts.PrometheusQueryLogFile},
+		},
+		"output-remote-write-urls": {
+			Args: &MonitoringArgs{
+				PrometheusRemoteWriteURLs: pulumi.StringArray{
+					pulumi.String("http://mimir:9009/api/v1/push").ToStringOutput(),
+				},
+			},
+			ExpectErr: true,
+		},
+		"invalid-args": {
+			Args: &MonitoringArgs{
+				ColdExtractTenantRouting: &parts.TenantRoutingArgs{
+					Tenants: []string{"ctf-2026"},
+				},
+			},
+			ExpectErr: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			cfgs, err := RenderConfigs(tt.Args)
+			if (err != nil) != tt.ExpectErr {
+				t.Fatalf("expected error: %t, got: %v", tt.ExpectErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			for _, exp := range tt.ExpectedOtel {
+				if !strings.Contains(cfgs.OTELCollector, exp) {
+					t.Errorf("expected otel collector config to contain %q, got:\n%s", exp, cfgs.OTELCollector)
+				}
+			}
+			for _, exp := range tt.ExpectedProm {
+				if !strings.Contains(cfgs.Prometheus, exp) {
+					t.Errorf("expected prometheus config to contain %q, got:\n%s", exp, cfgs.Prometheus)
+				}
+			}
+
+			// Rendering is deterministic, and does not modify the arguments
+			again, err := RenderConfigs(tt.Args)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if *again != *cfgs {
+				t.Error("expected rendering to be deterministic")
+			}
+		})
+	}
+}
+
+func Test_U_RenderConfigs_Unmodified(t *testing.T) {
+	t.Parallel()
+
+	args := &MonitoringArgs{
+		ColdExtract: true,
+		ColdExtractTenantRouting: &parts.TenantRoutingArgs{
+			Tenants: []string{"ctf-2026"},
+		},
+		ExporterRetry: &parts.ExporterRetryArgs{},
+	}
+	if _, err := RenderConfigs(args); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if args.BuildInfo != nil {
+		t.Error("expected build info not to be defaulted")
+	}
+	if *args.ExporterRetry != (parts.ExporterRetryArgs{}) {
+		t.Errorf("expected exporter retry not to be defaulted, got %+v", *args.ExporterRetry)
+	}
+	if args.ColdExtractTenantRouting.Attribute != "" {
+		t.Errorf("expected tenant attribute not to be defaulted, got %s", args.ColdExtractTenantRouting.Attribute)
+	}
+}
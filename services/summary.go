@@ -0,0 +1,223 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// SummarySchemaVersion is the version of the Summary document. It is bumped
+// on breaking changes only, i.e. fields removed or changing meaning.
+const SummarySchemaVersion = 1
+
+type (
+	// Summary is a machine-readable description of a Monitoring, for
+	// downstream automation to know which parts and features are active,
+	// and how to reach them.
+	Summary struct {
+		SchemaVersion int           `json:"schemaVersion"`
+		Version       string        `json:"version"`
+		Namespace     string        `json:"namespace"`
+		Preset        string        `json:"preset"`
+		Parts         []PartSummary `json:"parts"`
+
+		// Protected are the resources protected from deletion, as
+		// <part>/<resource>.
+		Protected []string `json:"protected"`
+	}
+
+	// PartSummary describes a part of the Monitoring.
+	PartSummary struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+
+		// Version of the part image, or of its chart for Perses.
+		Version string `json:"version"`
+
+		// Endpoint of the part, within the cluster. Empty if it does not
+		// serve anything (e.g. the log shipper) or is disabled.
+		Endpoint string `json:"endpoint,omitempty"`
+
+		// URL the part is exposed at outside the cluster, if any.
+		URL string `json:"url,omitempty"`
+
+		// Features of the part, either enabled or not.
+		Features map[string]bool `json:"features,omitempty"`
+	}
+
+	// summaryEndpoints are the endpoints and URLs of the parts, only known
+	// once deployed.
+	summaryEndpoints struct {
+		Namespace    string
+		OTEL         string
+		Jaeger       string
+		JaegerUI     string
+		Prometheus   string
+		PrometheusUI string
+		Perses       string
+		PersesUI     string
+	}
+)
+
+// summary assembles the Summary of the Monitoring, in JSON.
+func (mon *Monitoring) summary(args *MonitoringArgs) pulumi.StringOutput {
+	jaegerUI := pulumi.String("").ToStringOutput()
+	persesUI := pulumi.String("").ToStringOutput()
+	promUI := pulumi.String("").ToStringOutput()
+	if mon.jgrRoute != nil {
+		jaegerUI = routeURL(mon.jgrRoute)
+	}
+	if mon.prsRoute != nil {
+		persesUI = routeURL(mon.prsRoute)
+	}
+	if mon.promRoute != nil {
+		promUI = routeURL(mon.promRoute)
+	}
+
+	return pulumi.All(
+		mon.ns.Name,
+		mon.otel.Endpoint,
+		mon.jaeger.URL,
+		jaegerUI,
+		mon.prom.URL,
+		mon.perses.ServiceName,
+		persesUI,
+		promUI,
+	).ApplyT(func(all []any) (string, error) {
+		namespace := all[0].(string)
+		sum := newSummary(args, summaryEndpoints{
+			Namespace:    namespace,
+			OTEL:         all[1].(string),
+			Jaeger:       all[2].(string),
+			JaegerUI:     all[3].(string),
+			Prometheus:   all[4].(string),
+			Perses:       persesEndpoint(all[5].(string), namespace, args.ClusterDomain),
+			PersesUI:     all[6].(string),
+			PrometheusUI: all[7].(string),
+		})
+		b, err := json.Marshal(sum)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}).(pulumi.StringOutput)
+}
+
+// newSummary describes the parts of the (defaulted) arguments, in a stable
+// order.
+func newSummary(args *MonitoringArgs, edps summaryEndpoints) Summary {
+	return Summary{
+		SchemaVersion: SummarySchemaVersion,
+		Version:       args.BuildInfo.Version,
+		Namespace:     edps.Namespace,
+		Preset:        args.Preset,
+		Protected:     protectedResources(args),
+		Parts: []PartSummary{
+			{
+				Name:     "otel-collector",
+				Enabled:  true,
+				Version:  parts.OtelCollectorVersion,
+				Endpoint: edps.OTEL,
+				Features: map[string]bool{
+					"cold-extract":     args.ColdExtract,
+					"tenant-routing":   args.ColdExtract && args.ColdExtractTenantRouting != nil,
+					"dependency-graph": args.DependencyGraph,
+					"exemplars":        args.Exemplars,
+					"traces-failover":  args.TracesFailover,
+					"receiver-tls":     args.OTELReceiverTLS != nil,
+					"receiver-mtls":    args.OTELReceiverTLS != nil && args.OTELReceiverTLS.RequireClientCertificate,
+					"statsd-receiver":  args.OTELStatsdReceiver,
+					"syslog-receiver":  args.OTELSyslogReceiver != nil,
+					"redaction":        args.OTELRedaction != nil,
+					"kafka":            args.OTELKafka != nil,
+					"head-sampling":    args.OTELHeadSamplingPercent != nil && *args.OTELHeadSamplingPercent < 100,
+					"ingestion-quotas": args.OTELIngestionQuotas != nil,
+					"remote-write-wal": args.OTELRemoteWriteWAL != nil,
+					"logs-metrics":     args.OTELLogsMetrics != nil,
+				},
+			},
+			{
+				Name:     "jaeger",
+				Enabled:  true,
+				Version:  parts.JaegerVersion,
+				Endpoint: edps.Jaeger,
+				URL:      edps.JaegerUI,
+				Features: map[string]bool{
+					"spm":     !args.DisableJaegerSPM,
+					"archive": args.JaegerArchive != nil,
+				},
+			},
+			{
+				Name:     "prometheus",
+				Enabled:  true,
+				Version:  parts.PrometheusVersion,
+				Endpoint: edps.Prometheus,
+				URL:      edps.PrometheusUI,
+				Features: map[string]bool{
+					"agent-mode":              args.PrometheusAgentMode,
+					"admin-api":               args.PrometheusAdminAPI,
+					"query-log":               args.PrometheusQueryLog,
+					"exemplar-storage":        args.Exemplars,
+					"remote-write-basic-auth": args.PrometheusRemoteWriteBasicAuth,
+					"alert-rules":             args.PrometheusAlertRules != nil,
+					"annotation-scrape":       args.PrometheusAnnotationScrape != nil,
+				},
+			},
+			{
+				Name:     "perses",
+				Enabled:  true,
+				Version:  parts.PersesChartVersion,
+				Endpoint: edps.Perses,
+				URL:      edps.PersesUI,
+				Features: map[string]bool{
+					"access":    args.PersesAccess != nil,
+					"bootstrap": args.PersesBootstrap != nil,
+				},
+			},
+			{
+				Name:    "log-shipper",
+				Enabled: args.LogShipper != nil,
+				Version: parts.OtelCollectorVersion,
+			},
+		},
+	}
+}
+
+// persesEndpoint returns the endpoint of the Perses Service, fully-qualified
+// if a cluster domain is set.
+func persesEndpoint(svc, namespace, clusterDomain string) string {
+	return serviceURL(svc, namespace, clusterDomain, parts.PersesPort)
+}
+
+// serviceURL returns the HTTP URL of the Service on the port, fully-qualified
+// if a cluster domain is set.
+func serviceURL(svc, namespace, clusterDomain string, port int) string {
+	if svc == "" {
+		return ""
+	}
+	host := svc + "." + namespace
+	if clusterDomain != "" {
+		host += ".svc." + clusterDomain
+	}
+	return fmt.Sprintf("http://%s:%d", host, port)
+}
+
+// protectedResources lists the resources protected from deletion, in a
+// stable order.
+func protectedResources(args *MonitoringArgs) []string {
+	protected := []string{}
+	if !args.Protect {
+		return protected
+	}
+	protected = append(protected, "namespace/monitoring")
+	if args.ColdExtract {
+		protected = append(protected, "otel-collector/signals")
+	}
+	if args.JaegerArchive != nil && args.JaegerArchive.Badger != nil {
+		protected = append(protected, "jaeger/jaeger-archive")
+	}
+	return protected
+}
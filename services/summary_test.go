@@ -0,0 +1,129 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/ctfer-io/monitoring/internal/mocks"
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+func Test_U_Summary_Stable(t *testing.T) {
+	t.Parallel()
+
+	// The document is a contract with the downstream automation: any change
+	// of this golden file must come along a SummarySchemaVersion bump, unless
+	// it only adds fields.
+	args := (&Monitoring{}).defaults(&MonitoringArgs{
+		ColdExtract:     true,
+		OTELReceiverTLS: &parts.ReceiverTLSArgs{},
+		LogShipper:      &parts.LogShipperArgs{},
+		Protect:         true,
+		BuildInfo: &BuildInfo{
+			Version: "v1.2.3",
+		},
+	})
+	sum := newSummary(args, summaryEndpoints{
+		Namespace:  "monitoring-abcdefgh",
+		OTEL:       "otlp-grpc.monitoring-abcdefgh:4317",
+		Jaeger:     "http://jaeger-grpc:4317",
+		JaegerUI:   "https://jaeger-ui.apps.example.com",
+		Prometheus: "http://prometheus:9090",
+		Perses:     persesEndpoint("perses", "monitoring-abcdefgh", ""),
+	})
+	b, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join("testdata", "summary.golden.json"))
+	if err != nil {
+		t.Fatalf("reading golden file: %s", err)
+	}
+	if string(b)+"\n" != string(expected) {
+		t.Errorf("expected summary:\n%s\ngot:\n%s", expected, b)
+	}
+}
+
+func Test_U_Monitoring_Summary(t *testing.T) {
+	t.Parallel()
+
+	var tests = map[string]struct {
+		Args          *MonitoringArgs
+		ExpectShipper bool
+		ExpectColdExt bool
+	}{
+		"defaults": {
+			Args: &MonitoringArgs{},
+		},
+		"features": {
+			Args: &MonitoringArgs{
+				ColdExtract: true,
+				LogShipper:  &parts.LogShipperArgs{},
+			},
+			ExpectShipper: true,
+			ExpectColdExt: true,
+		},
+	}
+
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				mon, err := NewMonitoring(ctx, "monitoring", tt.Args)
+				if err != nil {
+					return err
+				}
+				mon.Summary.ApplyT(func(sum string) error {
+					defer wg.Done()
+					got = sum
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("monitoring", "test", &mocks.Mocks{}))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wg.Wait()
+
+			sum := Summary{}
+			if err := json.Unmarshal([]byte(got), &sum); err != nil {
+				t.Fatalf("invalid summary %s: %s", got, err)
+			}
+			if sum.SchemaVersion != SummarySchemaVersion {
+				t.Errorf("expected schema version %d, got %d", SummarySchemaVersion, sum.SchemaVersion)
+			}
+			if sum.Namespace != "monitoring-abcdefgh" {
+				t.Errorf("expected namespace monitoring-abcdefgh, got %s", sum.Namespace)
+			}
+
+			byName := map[string]PartSummary{}
+			for _, p := range sum.Parts {
+				byName[p.Name] = p
+			}
+			for _, name := range []string{"otel-collector", "jaeger", "prometheus", "perses"} {
+				if !byName[name].Enabled {
+					t.Errorf("expected %s to be enabled", name)
+				}
+			}
+			if otel := byName["otel-collector"]; otel.Endpoint == "" {
+				t.Error("expected the OTEL Collector endpoint")
+			}
+			if shipper := byName["log-shipper"].Enabled; shipper != tt.ExpectShipper {
+				t.Errorf("expected log shipper enabled: %t, got %t", tt.ExpectShipper, shipper)
+			}
+			if coldExt := byName["otel-collector"].Features["cold-extract"]; coldExt != tt.ExpectColdExt {
+				t.Errorf("expected cold extract: %t, got %t", tt.ExpectColdExt, coldExt)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// annotatedMetric is the metric the annotated pod serves, as scraped by
+// Prometheus under the annotation scrape job.
+const annotatedMetric = `annotation_scrape_smoke{job="` + parts.AnnotationScrapeJobName + `",namespace="default"}`
+
+func Test_S_AnnotationScrape(t *testing.T) {
+	// This test checks a pod annotated with prometheus.io/scrape is
+	// discovered and scraped by Prometheus, without any scrape config.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		OrderedConfig: []integration.ConfigValue{
+			{Key: "prometheus-annotation-scrape-namespaces[0]", Value: "default", Path: true},
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+
+			clientset := newClientset(t)
+			serveAnnotatedMetrics(t, clientset)
+
+			prom := prometheusClient(t, restConfig(t), clientset, namespace)
+			if err := waitForSeries(prom, annotatedMetric, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
+
+// serveAnnotatedMetrics runs a pod in the default namespace serving a
+// static metric on a non-default port and path, both annotated, until the
+// test ends.
+func serveAnnotatedMetrics(t *testing.T, clientset *kubernetes.Clientset) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "annotation-scrape-smoke-",
+			Annotations: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/port":   "8080",
+				"prometheus.io/path":   "/smoke/metrics",
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "metrics",
+					Image:   "busybox:1.37",
+					Command: []string{"sh", "-c"},
+					Args: []string{
+						"mkdir -p /www/smoke && echo 'annotation_scrape_smoke 1' > /www/smoke/metrics && httpd -f -p 8080 -h /www",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the annotated pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
@@ -0,0 +1,66 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_S_DualStack(t *testing.T) {
+	// This test checks the Monitoring component could be deployed on a
+	// dual-stack cluster with IPv6 as the primary family, its Services
+	// being dual-stack and the headless Jaeger one scraped through its
+	// AAAA records.
+	// It requires a dual-stack cluster, as the dual-stack workflow job
+	// creates, hence only runs with DUAL_STACK set.
+
+	if os.Getenv("DUAL_STACK") == "" {
+		t.Skip("not a dual-stack cluster, DUAL_STACK is not set")
+	}
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"ip-family-policy": "RequireDualStack",
+		},
+		OrderedConfig: []integration.ConfigValue{
+			{Key: "ip-families[0]", Value: "IPv6", Path: true},
+			{Key: "ip-families[1]", Value: "IPv4", Path: true},
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+
+			clientset := newClientset(t)
+			svcs, err := clientset.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{
+				LabelSelector: "app.kubernetes.io/part-of=monitoring",
+			})
+			if err != nil {
+				t.Fatalf("listing the Services: %s", err)
+			}
+			for _, svc := range svcs.Items {
+				if !slices.Equal(svc.Spec.IPFamilies, []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol}) {
+					t.Errorf("expected Service %s to be dual-stack, IPv6 first, got %v", svc.Name, svc.Spec.IPFamilies)
+				}
+			}
+
+			prom := prometheusClient(t, restConfig(t), clientset, namespace)
+			if err := waitForSeries(prom, `up{job="jaeger"} == 1`, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
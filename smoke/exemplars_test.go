@@ -0,0 +1,185 @@
+package smoke
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// exemplarsHistogram is the spanmetrics latency histogram, as remote written
+// into Prometheus.
+const exemplarsHistogram = "traces_span_metrics_duration_milliseconds_bucket"
+
+func Test_S_Exemplars(t *testing.T) {
+	// This test checks the traces sent to the OTEL Collector end up as
+	// exemplars of the spanmetrics histogram in Prometheus.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"exemplars": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			config := restConfig(t)
+			clientset := newClientset(t)
+			emitTraces(t, clientset, endpoint)
+
+			if err := waitForExemplar(config, clientset, namespace, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
+
+// emitTraces runs a pod in the default namespace sending traces to the OTEL
+// Collector, until the test ends.
+func emitTraces(t *testing.T, clientset *kubernetes.Clientset, endpoint string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "exemplars-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"traces",
+						"--otlp-endpoint=" + endpoint,
+						"--otlp-insecure",
+						"--rate=10",
+						"--duration=10m",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the traces emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
+
+// waitForExemplar polls the Prometheus exemplars API, from within its pod as
+// the NetworkPolicies only let the monitoring parts reach it, until the
+// spanmetrics histogram has an exemplar or the timeout expires.
+func waitForExemplar(config *rest.Config, clientset *kubernetes.Clientset, namespace string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=prometheus",
+		})
+		if err == nil {
+			for _, pod := range pods.Items {
+				ok, err := hasExemplar(ctx, config, clientset, namespace, pod.Name)
+				if err == nil && ok {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no exemplar of %s reached Prometheus within %s", exemplarsHistogram, timeout)
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+// hasExemplar queries the exemplars of the last hour, and reports whether one
+// carries a trace ID.
+func hasExemplar(ctx context.Context, config *rest.Config, clientset *kubernetes.Clientset, namespace, pod string) (bool, error) {
+	now := time.Now()
+	query := url.Values{
+		"query": {exemplarsHistogram},
+		"start": {strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)},
+		"end":   {strconv.FormatInt(now.Unix(), 10)},
+	}
+	stdout, err := queryPrometheus(ctx, config, clientset, namespace, pod, "/api/v1/query_exemplars?"+query.Encode())
+	if err != nil {
+		return false, err
+	}
+
+	res := struct {
+		Data []struct {
+			Exemplars []struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"exemplars"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(stdout, &res); err != nil {
+		return false, err
+	}
+	for _, series := range res.Data {
+		for _, ex := range series.Exemplars {
+			if ex.Labels["trace_id"] != "" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// queryPrometheus gets the API path from within the Prometheus pod, as the
+// NetworkPolicies only let the monitoring parts reach it.
+func queryPrometheus(ctx context.Context, config *rest.Config, clientset *kubernetes.Clientset, namespace, pod, path string) ([]byte, error) {
+	command := []string{"wget", "-qO-", "http://localhost:9090" + path}
+
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pod).
+		SubResource("exec").
+		Param("container", "prometheus").
+		Param("stdout", "true").
+		Param("stderr", "true")
+	for _, c := range command {
+		req = req.Param("command", c)
+	}
+	exec, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return nil, err
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdout: stdout,
+		Stderr: stderr,
+	}); err != nil {
+		return nil, fmt.Errorf("querying %s: %w (%s)", path, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,138 @@
+package smoke
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+// defaultAgeImage is the image the workflow builds with the age release
+// binary, and loads into the kind cluster.
+const defaultAgeImage = "localhost/age:smoke"
+
+func Test_S_ExtractEncryption(t *testing.T) {
+	// This test checks the signals extracted with the age encryption are
+	// only ciphertext out of the Pod, which decrypts into their archive,
+	// and that an image without the age binary is refused.
+
+	ageImage := os.Getenv("AGE_IMAGE")
+	if ageImage == "" {
+		ageImage = defaultAgeImage
+	}
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"cold-extract": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			pvcName, ok := stack.Outputs["otel-cold-extract-pvc-name"].(string)
+			if !ok || pvcName == "" {
+				t.Fatalf("expected the cold extract PVC name to be exported, got %v", stack.Outputs["otel-cold-extract-pvc-name"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			// Let the collector write some signals on the PVC
+			emitTraces(t, newClientset(t), endpoint)
+			time.Sleep(30 * time.Second)
+
+			identity, err := age.GenerateX25519Identity()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+
+			// An image without the binary is refused before anything is copied
+			_, err = extract.DumpOTelCollector(ctx, namespace, pvcName, t.TempDir(),
+				extract.WithAgeEncryption(extract.AgeEncryption{
+					Recipient: identity.Recipient().String(),
+					Image:     "busybox:1.37",
+				}),
+			)
+			if !errors.Is(err, extract.ErrAgeMissing) {
+				t.Fatalf("expected the image without age to be refused, got %v", err)
+			}
+
+			dir := t.TempDir()
+			res, err := extract.DumpOTelCollector(ctx, namespace, pvcName, dir,
+				extract.WithAgeEncryption(extract.AgeEncryption{
+					Recipient: identity.Recipient().String(),
+					Image:     ageImage,
+				}),
+			)
+			if err != nil {
+				t.Fatalf("extracting encrypted: %s", err)
+			}
+			if res.Encrypted != extract.EncryptedArchiveFile {
+				t.Errorf("expected the encrypted archive in the report, got %q", res.Encrypted)
+			}
+
+			// Only the ciphertext and the report landed
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if e.Name() != extract.EncryptedArchiveFile && e.Name() != extract.ReportFile {
+					t.Errorf("expected only the encrypted archive and the report, got %s", e.Name())
+				}
+			}
+
+			if files := decryptArchive(t, filepath.Join(dir, extract.EncryptedArchiveFile), identity); files == 0 {
+				t.Error("expected the signals files in the decrypted archive")
+			}
+		},
+	})
+}
+
+// decryptArchive decrypts the age-encrypted archive with the identity, and
+// returns the number of files it holds.
+func decryptArchive(t *testing.T, file string, identity age.Identity) int {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	r, err := age.Decrypt(f, identity)
+	if err != nil {
+		t.Fatalf("decrypting the archive: %s", err)
+	}
+	tr := tar.NewReader(r)
+	files := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("reading the decrypted archive: %s", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			files++
+		}
+	}
+}
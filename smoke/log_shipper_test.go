@@ -0,0 +1,127 @@
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func Test_S_LogShipper(t *testing.T) {
+	// This test checks a log line written by a pod is shipped into the
+	// OTEL Collector logs pipeline, where the debug exporter prints it.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"log-shipper": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			if ns, ok := stack.Outputs["log-shipper-namespace"].(string); !ok || ns == "" {
+				t.Errorf("expected the log shipper namespace to be exported, got %v", stack.Outputs["log-shipper-namespace"])
+			}
+			if labels, ok := stack.Outputs["log-shipper-pod-labels"].(map[string]any); !ok || len(labels) == 0 {
+				t.Errorf("expected the log shipper pod labels to be exported, got %v", stack.Outputs["log-shipper-pod-labels"])
+			}
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+
+			clientset := newClientset(t)
+			marker := fmt.Sprintf("log-shipper-smoke-%d", time.Now().UnixNano())
+			emitLogs(t, clientset, marker)
+
+			if err := waitForCollectorLog(clientset, namespace, marker, 3*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
+
+func newClientset(t *testing.T) *kubernetes.Clientset {
+	clientset, err := kubernetes.NewForConfig(restConfig(t))
+	if err != nil {
+		t.Fatalf("creating clientset: %s", err)
+	}
+	return clientset
+}
+
+func restConfig(t *testing.T) *rest.Config {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		t.Fatalf("loading kubeconfig: %s", err)
+	}
+	return config
+}
+
+// emitLogs runs a pod in the default namespace repeatedly writing the marker
+// on its stdout, until the test ends.
+func emitLogs(t *testing.T, clientset *kubernetes.Clientset, marker string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "log-shipper-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "emitter",
+					Image:   "busybox:1.37",
+					Command: []string{"sh", "-c", "while true; do echo " + marker + "; sleep 5; done"},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
+
+// waitForCollectorLog polls the OTEL Collector pods logs until one contains
+// the marker, or the timeout expires.
+func waitForCollectorLog(clientset *kubernetes.Clientset, namespace, marker string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "app.kubernetes.io/name=otel-collector,app.kubernetes.io/component=otel-collector",
+		})
+		if err == nil {
+			for _, pod := range pods.Items {
+				logs, err := clientset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+				if err == nil && strings.Contains(string(logs), marker) {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("log line %s did not reach the OTEL Collector within %s", marker, timeout)
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// logsMetricsService is the service.name of the error logs emitted, the
+// records being counted by it.
+const logsMetricsService = "logs-metrics-smoke"
+
+func Test_S_LogsMetrics(t *testing.T) {
+	// This test checks the error log records sent to the OTEL Collector are
+	// counted into Prometheus by service, without any log backend.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"otel-logs-metrics":       "true",
+			"otel-logs-metrics-dedup": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			clientset := newClientset(t)
+			emitErrorLogs(t, clientset, endpoint)
+
+			prom := prometheusClient(t, restConfig(t), clientset, namespace)
+			query := parts.LogRecordsMetric + `{service_name="` + logsMetricsService + `"} > 0`
+			if err := waitForSeries(prom, query, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
+
+// emitErrorLogs runs a pod in the default namespace sending error log
+// records to the OTEL Collector, until the test ends.
+func emitErrorLogs(t *testing.T, clientset *kubernetes.Clientset, endpoint string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "logs-metrics-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"logs",
+						"--otlp-endpoint=" + endpoint,
+						"--otlp-insecure",
+						"--service=" + logsMetricsService,
+						"--severity-text=Error",
+						"--severity-number=17",
+						"--rate=10",
+						"--duration=10m",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the logs emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
@@ -0,0 +1,118 @@
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/internal/promclient"
+	"github.com/ctfer-io/monitoring/services/parts"
+)
+
+// deltaSum is the sum sent with the delta temporality by telemetrygen, as
+// remote written into Prometheus once accumulated.
+const deltaSum = `{__name__=~"gen(_total)?"}`
+
+func Test_S_MetricsConversion(t *testing.T) {
+	// This test checks the delta temporality metrics sent to the OTEL
+	// Collector are accumulated into Prometheus rather than dropped, and
+	// counted as converted.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"otel-delta-to-cumulative": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			if url, ok := stack.Outputs["prometheus-url"].(string); !ok || url == "" {
+				t.Errorf("expected the Prometheus URL to be exported, got %v", stack.Outputs["prometheus-url"])
+			}
+
+			clientset := newClientset(t)
+			emitDeltaMetrics(t, clientset, endpoint)
+
+			prom := prometheusClient(t, restConfig(t), clientset, namespace)
+			for _, query := range []string{deltaSum, parts.ConvertedDeltaMetric} {
+				if err := waitForSeries(prom, query, 5*time.Minute); err != nil {
+					t.Fatal(err)
+				}
+			}
+		},
+	})
+}
+
+// emitDeltaMetrics runs a pod in the default namespace sending a delta
+// temporality sum to the OTEL Collector, until the test ends.
+func emitDeltaMetrics(t *testing.T, clientset *kubernetes.Clientset, endpoint string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "metrics-conversion-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"metrics",
+						"--otlp-endpoint=" + endpoint,
+						"--otlp-insecure",
+						"--metric-type=Sum",
+						"--aggregation-temporality=delta",
+						"--rate=10",
+						"--duration=10m",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the metrics emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
+
+// waitForSeries polls the Prometheus query API until the query returns a
+// series or the timeout expires.
+func waitForSeries(client *promclient.Client, query string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		res, err := client.InstantQuery(ctx, query, time.Time{})
+		if err == nil && len(res.Vector) != 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no series of %s reached Prometheus within %s", query, timeout)
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
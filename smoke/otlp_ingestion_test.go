@@ -0,0 +1,100 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// otlpMetric is the dotted name of the sum sent by telemetrygen.
+	otlpMetric = "smoke.otlp.gen"
+
+	// otlpSeries is the sum as ingested over OTLP, its name suffixed but
+	// not escaped, and mangledSeries as remote write would have escaped it.
+	otlpSeries    = `{"smoke.otlp.gen_total"}`
+	mangledSeries = `{__name__="smoke_otlp_gen_total"}`
+)
+
+func Test_S_OTLPIngestion(t *testing.T) {
+	// This test checks the metrics pushed by the OTEL Collector to the
+	// Prometheus OTLP receiver keep their dotted names.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"prometheus-otlp-ingestion": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			clientset := newClientset(t)
+			emitOTLPMetrics(t, clientset, endpoint)
+
+			prom := prometheusClient(t, restConfig(t), clientset, namespace)
+			if err := waitForSeries(prom, otlpSeries, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			res, err := prom.InstantQuery(context.Background(), mangledSeries, time.Time{})
+			if err != nil {
+				t.Fatalf("querying %s: %s", mangledSeries, err)
+			}
+			if len(res.Vector) != 0 {
+				t.Errorf("expected the metric names not to be escaped, got %v", res.Vector)
+			}
+		},
+	})
+}
+
+// emitOTLPMetrics runs a pod in the default namespace sending a dotted sum
+// to the OTEL Collector, until the test ends.
+func emitOTLPMetrics(t *testing.T, clientset *kubernetes.Clientset, endpoint string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "otlp-ingestion-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"metrics",
+						"--otlp-endpoint=" + endpoint,
+						"--otlp-insecure",
+						"--otlp-metric-name=" + otlpMetric,
+						"--metric-type=Sum",
+						"--rate=10",
+						"--duration=10m",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the metrics emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
@@ -0,0 +1,78 @@
+package smoke
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+
+	"github.com/ctfer-io/monitoring/internal/promclient"
+)
+
+// prometheusClient returns the client of the Prometheus API, port-forwarded
+// from its pod as the NetworkPolicies only let the monitoring parts reach
+// its exported URL, until the test ends.
+func prometheusClient(t *testing.T, config *rest.Config, clientset *kubernetes.Clientset, namespace string) *promclient.Client {
+	ctx := context.Background()
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/name=prometheus",
+	})
+	if err != nil {
+		t.Fatalf("listing the Prometheus pods: %s", err)
+	}
+	if len(pods.Items) == 0 {
+		t.Fatalf("no Prometheus pod in namespace %s", namespace)
+	}
+
+	rt, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pods.Items[0].Name).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: rt}, http.MethodPost, req.URL())
+
+	stopCh, readyCh := make(chan struct{}), make(chan struct{})
+	fw, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{"0:9090"}, stopCh, readyCh, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fw.ForwardPorts()
+	}()
+	select {
+	case <-readyCh:
+	case err := <-errc:
+		t.Fatalf("port-forwarding Prometheus: %s", err)
+	}
+	t.Cleanup(func() {
+		close(stopCh)
+	})
+	ports, err := fw.GetPorts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Prometheus could still be replaying its WAL once rolled out
+	client, err := promclient.New(fmt.Sprintf("http://127.0.0.1:%d", ports[0].Local),
+		promclient.WithTimeout(10*time.Second),
+		promclient.WithRetry(6, 10*time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
@@ -0,0 +1,125 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func Test_S_ReceiverTLS(t *testing.T) {
+	// This test checks a sender trusting the OTEL Collector CA completes the
+	// TLS handshake with the receiver: telemetrygen verifies the server
+	// certificate against the CA, then its log body reaches the collector.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"otel-receiver-tls": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+			caSecret, ok := stack.Outputs["otel-ca-secret-name"].(string)
+			if !ok || caSecret == "" {
+				t.Fatalf("expected the OTEL Collector CA secret name to be exported, got %v", stack.Outputs["otel-ca-secret-name"])
+			}
+
+			clientset := newClientset(t)
+			marker := "receiver-tls-smoke-" + time.Now().Format("150405")
+			emitTLSLogs(t, clientset, namespace, caSecret, endpoint, marker)
+			if err := waitForCollectorLog(clientset, namespace, marker, 5*time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		},
+	})
+}
+
+// emitTLSLogs copies the OTEL Collector CA certificate in the default
+// namespace, then runs a pod there sending logs of the marker body over TLS,
+// verifying the receiver certificate against it.
+func emitTLSLogs(t *testing.T, clientset *kubernetes.Clientset, namespace, caSecret, endpoint, marker string) {
+	ctx := context.Background()
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, caSecret, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("getting the CA secret: %s", err)
+	}
+	ca, err := clientset.CoreV1().ConfigMaps("default").Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "receiver-tls-smoke-",
+		},
+		Data: map[string]string{
+			"ca.crt": string(secret.Data["ca.crt"]),
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the CA configmap: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().ConfigMaps("default").Delete(context.Background(), ca.Name, metav1.DeleteOptions{})
+	})
+
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "receiver-tls-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"logs",
+						"--otlp-endpoint=" + endpoint,
+						"--ca-cert=/etc/otel-ca/ca.crt",
+						"--body=" + marker,
+						"--rate=1",
+						"--duration=5m",
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "otel-ca",
+							MountPath: "/etc/otel-ca",
+							ReadOnly:  true,
+						},
+					},
+				},
+			},
+			Volumes: []corev1.Volume{
+				{
+					Name: "otel-ca",
+					VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{
+								Name: ca.Name,
+							},
+						},
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the TLS emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
@@ -1,12 +1,20 @@
 package smoke
 
 import (
+	"context"
+	"fmt"
+	"net"
 	"os"
+	"os/exec"
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 func Test_S_Smoke(t *testing.T) {
@@ -25,6 +33,190 @@ func Test_S_Smoke(t *testing.T) {
 	})
 }
 
+func Test_S_IngressPeers(t *testing.T) {
+	// This test checks the OTEL Collector ingress is restricted to some
+	// namespaces: a pod of the allowed one connects to the receiver, one of
+	// another namespace does not. The cluster CNI must enforce the
+	// NetworkPolicies.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		OrderedConfig: []integration.ConfigValue{
+			{Key: "otel-ingress-namespaces[0]", Value: "default", Path: true},
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+
+			clientset := newClientset(t)
+			denied := createNamespace(t, clientset, "ingress-peers-smoke-")
+			for namespace, allowed := range map[string]bool{
+				"default": true,
+				denied:    false,
+			} {
+				connected, err := probeConnection(t, clientset, namespace, endpoint, 3*time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if connected != allowed {
+					t.Errorf("expected a pod of namespace %s to connect to %s: %t, got %t", namespace, endpoint, allowed, connected)
+				}
+			}
+		},
+	})
+}
+
+func Test_S_ReceiverMTLS(t *testing.T) {
+	// This test checks the Monitoring component could be deployed with the
+	// OTEL Collector receiver requiring client certificates, issued by the
+	// cert-manager installed in the cluster.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"otel-receiver-mtls": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			if name, ok := stack.Outputs["otel-ca-secret-name"].(string); !ok || name == "" {
+				t.Errorf("expected the OTEL Collector CA secret name to be exported, got %v", stack.Outputs["otel-ca-secret-name"])
+			}
+		},
+	})
+}
+
+func Test_S_DevMode(t *testing.T) {
+	// This test checks the Monitoring component could be deployed on a
+	// single-node lab cluster (e.g. k3d, kind), with its defaults.
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"dev-mode":     "true",
+			"cold-extract": "true", // the PVC fits the lab too
+		},
+	})
+}
+
+func Test_S_Protect(t *testing.T) {
+	// This test checks the stateful resources are protected from deletion,
+	// then unprotects them explicitly for the teardown to succeed.
+
+	pwd, _ := os.Getwd()
+	dir := path.Join(pwd, "..")
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         dir,
+		StackName:   stackName(t.Name()),
+		Config: map[string]string{
+			"protect":      "true",
+			"cold-extract": "true",
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			protected := map[string]bool{}
+			for _, res := range stack.Deployment.Resources {
+				if res.Protect {
+					protected[string(res.Type)] = true
+				}
+			}
+			for _, typ := range []string{"kubernetes:core/v1:Namespace", "kubernetes:core/v1:PersistentVolumeClaim"} {
+				if !protected[typ] {
+					t.Errorf("expected a protected %s", typ)
+				}
+			}
+
+			cmd := exec.Command("pulumi", "state", "unprotect", "--all", "--yes", "--stack", string(stack.StackName))
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("unprotecting the stack: %s\n%s", err, out)
+			}
+		},
+	})
+}
+
+// createNamespace creates a namespace of the prefix, deleted once the test
+// ends, and returns its name.
+func createNamespace(t *testing.T, clientset *kubernetes.Clientset, prefix string) string {
+	ns, err := clientset.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: prefix,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the namespace: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Namespaces().Delete(context.Background(), ns.Name, metav1.DeleteOptions{})
+	})
+	return ns.Name
+}
+
+// probeConnection runs a pod in the namespace opening a TCP connection to
+// the endpoint, and returns whether it succeeded.
+func probeConnection(t *testing.T, clientset *kubernetes.Clientset, namespace, endpoint string, timeout time.Duration) (bool, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("invalid endpoint %s: %w", endpoint, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pod, err := clientset.CoreV1().Pods(namespace).Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "ingress-peers-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    "probe",
+					Image:   "busybox:1.37",
+					Command: []string{"nc", "-z", "-w", "10", host, port},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, fmt.Errorf("creating the probe pod: %w", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods(namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+
+	for {
+		got, err := clientset.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err == nil {
+			switch got.Status.Phase {
+			case corev1.PodSucceeded:
+				return true, nil
+			case corev1.PodFailed:
+				return false, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, fmt.Errorf("probe pod %s/%s did not complete within %s", namespace, pod.Name, timeout)
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
 func stackName(tname string) (out string) {
 	out = tname
 	out = strings.TrimPrefix(out, "Test_S_")
@@ -0,0 +1,119 @@
+package smoke
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/ctfer-io/monitoring/pkg/extract"
+)
+
+func Test_S_TenantRouting(t *testing.T) {
+	// This test checks the signals of two tenants land in their own
+	// directory of the PVC, as the cold extract layout describes it.
+
+	tenants := []string{"ctf-a", "ctf-b"}
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, ".."),
+		StackName:   stackName(t.Name()),
+		OrderedConfig: []integration.ConfigValue{
+			{Key: "cold-extract", Value: "true"},
+			{Key: "cold-extract-tenants[0]", Value: tenants[0], Path: true},
+			{Key: "cold-extract-tenants[1]", Value: tenants[1], Path: true},
+		},
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			namespace, ok := stack.Outputs["namespace"].(string)
+			if !ok || namespace == "" {
+				t.Fatalf("expected the namespace to be exported, got %v", stack.Outputs["namespace"])
+			}
+			pvcName, ok := stack.Outputs["otel-cold-extract-pvc-name"].(string)
+			if !ok || pvcName == "" {
+				t.Fatalf("expected the cold extract PVC name to be exported, got %v", stack.Outputs["otel-cold-extract-pvc-name"])
+			}
+			endpoint, ok := stack.Outputs["otel-endpoint"].(string)
+			if !ok || endpoint == "" {
+				t.Fatalf("expected the OTEL Collector endpoint to be exported, got %v", stack.Outputs["otel-endpoint"])
+			}
+			layout, ok := stack.Outputs["otel-cold-extract-layout"].(map[string]any)
+			if !ok {
+				t.Fatalf("expected the cold extract layout to be exported, got %v", stack.Outputs["otel-cold-extract-layout"])
+			}
+
+			// Let the collector write the signals of each tenant on the PVC
+			clientset := newClientset(t)
+			for _, tenant := range tenants {
+				emitTenantTraces(t, clientset, endpoint, tenant)
+			}
+			time.Sleep(30 * time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			dir := t.TempDir()
+			if _, err := extract.DumpOTelCollector(ctx, namespace, pvcName, dir); err != nil {
+				t.Fatalf("extracting: %s", err)
+			}
+
+			for _, tenant := range tenants {
+				sub, ok := layout[tenant].(string)
+				if !ok {
+					t.Errorf("expected tenant %s in the layout, got %v", tenant, layout)
+					continue
+				}
+				stat, err := os.Stat(filepath.Join(dir, sub, "otel_traces"))
+				if err != nil {
+					t.Errorf("expected the traces of tenant %s: %s", tenant, err)
+					continue
+				}
+				if stat.Size() == 0 {
+					t.Errorf("expected the traces of tenant %s to be written", tenant)
+				}
+			}
+		},
+	})
+}
+
+// emitTenantTraces runs a pod in the default namespace sending traces of
+// the tenant, until the test ends.
+func emitTenantTraces(t *testing.T, clientset *kubernetes.Clientset, endpoint, tenant string) {
+	ctx := context.Background()
+	pod, err := clientset.CoreV1().Pods("default").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "tenant-routing-smoke-",
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:  "telemetrygen",
+					Image: "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0",
+					Args: []string{
+						"traces",
+						"--otlp-endpoint=" + endpoint,
+						"--otlp-insecure",
+						`--otlp-attributes=ctfer.io/stack-name="` + tenant + `"`,
+						"--rate=10",
+						"--duration=10m",
+					},
+				},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("creating the traces emitter pod: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = clientset.CoreV1().Pods("default").Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+	})
+}
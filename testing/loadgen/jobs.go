@@ -0,0 +1,147 @@
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+)
+
+// runJobs runs one telemetrygen Job per signal concurrently, waits for them
+// to complete then deletes them.
+func runJobs(ctx context.Context, clientset kubernetes.Interface, cfg Config, signals map[string]int) (err error) {
+	jobs := clientset.BatchV1().Jobs(cfg.JobsNamespace)
+
+	names := make([]string, 0, len(signals))
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		for _, name := range names {
+			if derr := jobs.Delete(context.WithoutCancel(ctx), name, metav1.DeleteOptions{
+				PropagationPolicy: &propagation,
+			}); derr != nil && err == nil {
+				err = errors.Wrapf(derr, "deleting job %s", name)
+			}
+		}
+	}()
+
+	for signal, rate := range signals {
+		job, err := jobs.Create(ctx, telemetrygenJob(cfg, signal, rate), metav1.CreateOptions{})
+		if err != nil {
+			return errors.Wrapf(err, "creating %s job", signal)
+		}
+		names = append(names, job.Name)
+	}
+
+	// Leave time for the image pull and the Jobs scheduling
+	timeout := cfg.Duration + 5*time.Minute
+	for _, name := range names {
+		if err := waitForJob(ctx, clientset, cfg.JobsNamespace, name, timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// telemetrygenJob builds the Job generating the signal at the given rate.
+func telemetrygenJob(cfg Config, signal string, rate int) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "loadgen-" + signal + "-",
+			Namespace:    cfg.JobsNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":      "telemetrygen",
+				"app.kubernetes.io/component": "loadgen",
+				"app.kubernetes.io/part-of":   "monitoring",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr(int32(0)),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app.kubernetes.io/name":      "telemetrygen",
+						"app.kubernetes.io/component": "loadgen",
+						"app.kubernetes.io/part-of":   "monitoring",
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:  "telemetrygen",
+							Image: registryPrefix(cfg.Registry) + telemetrygenImage,
+							Args:  telemetrygenArgs(cfg, signal, rate),
+							SecurityContext: &corev1.SecurityContext{
+								AllowPrivilegeEscalation: ptr(false),
+								RunAsNonRoot:             ptr(true),
+								RunAsUser:                ptr(int64(65534)),
+								Capabilities: &corev1.Capabilities{
+									Drop: []corev1.Capability{"ALL"},
+								},
+								SeccompProfile: &corev1.SeccompProfile{
+									Type: corev1.SeccompProfileTypeRuntimeDefault,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// telemetrygenArgs returns the telemetrygen command line for the signal.
+func telemetrygenArgs(cfg Config, signal string, rate int) []string {
+	return []string{
+		signal,
+		"--otlp-endpoint=" + cfg.Endpoint,
+		"--otlp-insecure",
+		fmt.Sprintf("--rate=%d", rate),
+		fmt.Sprintf("--workers=%d", cfg.Workers),
+		fmt.Sprintf("--duration=%s", cfg.Duration),
+	}
+}
+
+func waitForJob(ctx context.Context, clientset kubernetes.Interface, namespace, name string, timeout time.Duration) error {
+	return wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				return true, nil
+			case batchv1.JobFailed:
+				return false, fmt.Errorf("job %s failed: %s", name, cond.Message)
+			}
+		}
+		return false, nil
+	})
+}
+
+func registryPrefix(registry string) string {
+	if registry != "" && !strings.HasSuffix(registry, "/") {
+		registry += "/"
+	}
+	return registry
+}
+
+func ptr[T any](t T) *T {
+	return &t
+}
@@ -0,0 +1,207 @@
+// Package loadgen runs load tests against the Monitoring OTEL Collector
+// pipeline, to size it from measurements rather than guesses.
+//
+// It generates traces and metrics at configurable rates with telemetrygen,
+// run as in-cluster Jobs, then scrapes the collector self-metrics to compute
+// the data loss (refused by the receiver or failed to export) and the export
+// latency. A run is considered lossless under DefaultMaxLossRatio.
+package loadgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// DefaultMaxLossRatio is the ratio of lost data points (spans or metric
+	// points) under which the pipeline is considered lossless.
+	DefaultMaxLossRatio = 0.001
+
+	// DefaultLatencyHistogram is the collector self-metric used to compute
+	// the export latency. It is only exposed with the detailed telemetry level.
+	DefaultLatencyHistogram = "rpc_client_duration_milliseconds"
+
+	telemetrygenImage = "ghcr.io/open-telemetry/opentelemetry-collector-contrib/telemetrygen:v0.143.0"
+)
+
+type (
+	// Config of a load test run.
+	Config struct {
+		// Namespace of the Monitoring, where the OTEL Collector runs.
+		Namespace string
+
+		// Endpoint of the OTEL Collector, e.g. the otel-endpoint stack output.
+		Endpoint string
+
+		// JobsNamespace is where the telemetrygen Jobs are run.
+		// Defaults to Namespace.
+		JobsNamespace string
+
+		// Registry to pull the telemetrygen image from.
+		Registry string
+
+		// TracesRate and MetricsRate are the number of traces and metrics
+		// generated per second per worker. A zero rate skips the signal.
+		TracesRate  int
+		MetricsRate int
+		Workers     int
+		Duration    time.Duration
+
+		// LatencyHistogram is the collector self-metric to compute the
+		// export latency from. Defaults to DefaultLatencyHistogram.
+		LatencyHistogram string
+	}
+
+	// Results of a load test run, written as a JSON artifact to track the
+	// collector sizing across releases.
+	Results struct {
+		StartedAt       time.Time                 `json:"started_at"`
+		DurationSeconds float64                   `json:"duration_seconds"`
+		Workers         int                       `json:"workers"`
+		Signals         map[string]*SignalResults `json:"signals"`
+
+		// ExportLatencyP99 is the 99th percentile of the export latency, in
+		// milliseconds, if the latency histogram is exposed.
+		ExportLatencyP99 *float64 `json:"export_latency_p99_ms,omitempty"`
+		Warnings         []string `json:"warnings"`
+	}
+
+	// SignalResults sums up how the collector handled a signal.
+	SignalResults struct {
+		Rate       int     `json:"rate"`
+		Accepted   float64 `json:"accepted"`
+		Refused    float64 `json:"refused"`
+		SendFailed float64 `json:"send_failed"`
+		LossRatio  float64 `json:"loss_ratio"`
+	}
+)
+
+func (cfg *Config) defaults() {
+	if cfg.JobsNamespace == "" {
+		cfg.JobsNamespace = cfg.Namespace
+	}
+	if cfg.Workers == 0 {
+		cfg.Workers = 1
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = time.Minute
+	}
+	if cfg.LatencyHistogram == "" {
+		cfg.LatencyHistogram = DefaultLatencyHistogram
+	}
+}
+
+func (cfg *Config) check() error {
+	if cfg.Namespace == "" {
+		return errors.New("namespace is not provided")
+	}
+	if cfg.Endpoint == "" {
+		return errors.New("endpoint is not provided")
+	}
+	if cfg.TracesRate < 0 || cfg.MetricsRate < 0 {
+		return errors.New("rates could not be negative")
+	}
+	if cfg.TracesRate == 0 && cfg.MetricsRate == 0 {
+		return errors.New("no signal to generate")
+	}
+	return nil
+}
+
+// Run generates the load and measures how the collector handled it.
+func Run(ctx context.Context, cfg Config) (*Results, error) {
+	cfg.defaults()
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+
+	clientset, config, err := getClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "creating kubernetes client")
+	}
+
+	res := &Results{
+		StartedAt: time.Now(),
+		Workers:   cfg.Workers,
+		Signals:   map[string]*SignalResults{},
+		Warnings:  []string{},
+	}
+
+	// Counters are cumulative over the collector lifetime, so only the
+	// difference is attributed to this run.
+	before, err := scrapeSelfMetrics(ctx, config, clientset, cfg.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "scraping collector self-metrics before load")
+	}
+
+	signals := map[string]int{}
+	if cfg.TracesRate != 0 {
+		signals[signalTraces] = cfg.TracesRate
+	}
+	if cfg.MetricsRate != 0 {
+		signals[signalMetrics] = cfg.MetricsRate
+	}
+	if err := runJobs(ctx, clientset, cfg, signals); err != nil {
+		return nil, err
+	}
+
+	// Let the exporters flush their queues before measuring
+	after, err := waitForFlush(ctx, config, clientset, cfg.Namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "scraping collector self-metrics after load")
+	}
+	res.DurationSeconds = time.Since(res.StartedAt).Seconds()
+
+	for signal, rate := range signals {
+		res.Signals[signal] = signalResults(signal, rate, before, after)
+	}
+
+	if p99, ok := after.histogramQuantile(0.99, cfg.LatencyHistogram); ok {
+		res.ExportLatencyP99 = &p99
+	} else {
+		res.Warnings = append(res.Warnings, fmt.Sprintf("latency histogram %s not exposed, the collector telemetry level may not be detailed", cfg.LatencyHistogram))
+	}
+
+	return res, nil
+}
+
+// Lossless tells whether every signal lost less than maxLossRatio.
+func (res *Results) Lossless(maxLossRatio float64) bool {
+	for _, sr := range res.Signals {
+		if sr.LossRatio > maxLossRatio {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteJSON writes the results as an indented JSON file.
+func (res *Results) WriteJSON(path string) error {
+	b, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+func getClient() (*kubernetes.Clientset, *rest.Config, error) {
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{},
+	).ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, config, nil
+}
@@ -0,0 +1,219 @@
+package loadgen
+
+import (
+	"context"
+	"math"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/pkg/v3/testing/integration"
+)
+
+const exposition = `# HELP otelcol_receiver_accepted_spans_total Number of spans successfully pushed into the pipeline.
+# TYPE otelcol_receiver_accepted_spans_total counter
+otelcol_receiver_accepted_spans_total{receiver="otlp",service_name="otelcol-contrib",transport="grpc"} 1000
+otelcol_receiver_refused_spans_total{receiver="otlp",service_name="otelcol-contrib",transport="grpc"} 2
+otelcol_exporter_send_failed_spans_total{exporter="otlp",service_name="otelcol-contrib"} 3
+otelcol_exporter_send_failed_spans_total{exporter="debug",service_name="otelcol-contrib"} 100
+rpc_client_duration_milliseconds_bucket{rpc_method="Export",le="5"} 50
+rpc_client_duration_milliseconds_bucket{rpc_method="Export",le="10"} 90
+rpc_client_duration_milliseconds_bucket{rpc_method="Export",le="25"} 100
+rpc_client_duration_milliseconds_bucket{rpc_method="Export",le="+Inf"} 100
+weird_label{path="a \"quoted\", value"} 1 1700000000000
+`
+
+func Test_U_ParseSelfMetrics(t *testing.T) {
+	t.Parallel()
+
+	sm, err := parseSelfMetrics(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sm) != 9 {
+		t.Fatalf("expected 9 samples, got %d", len(sm))
+	}
+
+	if v := sm.sum("otelcol_exporter_send_failed_spans_total", nil); v != 103 {
+		t.Errorf("expected 103 send failed spans, got %f", v)
+	}
+	if v := sm.sum("otelcol_exporter_send_failed_spans_total", map[string]string{"exporter": "otlp"}); v != 3 {
+		t.Errorf("expected 3 send failed spans for otlp, got %f", v)
+	}
+	if v := sm.sum("weird_label", map[string]string{"path": `a "quoted", value`}); v != 1 {
+		t.Errorf("expected escaped label to match, got %f", v)
+	}
+
+	if _, err := parseSelfMetrics(strings.NewReader(`broken{label="x" 1`)); err == nil {
+		t.Error("expected an error on unterminated labels")
+	}
+}
+
+func Test_U_HistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	sm, err := parseSelfMetrics(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var tests = map[string]struct {
+		Q        float64
+		Expected float64
+	}{
+		"p50": {Q: 0.5, Expected: 5},
+		"p90": {Q: 0.9, Expected: 10},
+		"p99": {Q: 0.99, Expected: 23.5},
+	}
+	for testname, tt := range tests {
+		t.Run(testname, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := sm.histogramQuantile(tt.Q, DefaultLatencyHistogram)
+			if !ok {
+				t.Fatal("expected the histogram to be found")
+			}
+			if math.Abs(got-tt.Expected) > 1e-9 {
+				t.Fatalf("expected %f, got %f", tt.Expected, got)
+			}
+		})
+	}
+
+	if _, ok := sm.histogramQuantile(0.99, "unknown"); ok {
+		t.Error("expected an unknown histogram not to be found")
+	}
+}
+
+func Test_U_SignalResults(t *testing.T) {
+	t.Parallel()
+
+	before := selfMetrics{}
+	after, err := parseSelfMetrics(strings.NewReader(exposition))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sr := signalResults(signalTraces, 10, before, after)
+	if sr.Accepted != 1000 || sr.Refused != 2 || sr.SendFailed != 3 {
+		t.Fatalf("unexpected results %+v", sr)
+	}
+	if expected := 5.0 / 1002.0; math.Abs(sr.LossRatio-expected) > 1e-9 {
+		t.Errorf("expected loss ratio %f, got %f", expected, sr.LossRatio)
+	}
+
+	res := &Results{Signals: map[string]*SignalResults{signalTraces: sr}}
+	if res.Lossless(DefaultMaxLossRatio) {
+		t.Error("expected the run not to be lossless")
+	}
+}
+
+func Test_U_TelemetrygenJob(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Namespace: "monitoring",
+		Endpoint:  "otlp-grpc.monitoring:4317",
+		Registry:  "registry.example.com",
+		Workers:   2,
+		Duration:  30 * time.Second,
+	}
+	cfg.defaults()
+
+	job := telemetrygenJob(cfg, signalMetrics, 100)
+	if job.Namespace != "monitoring" {
+		t.Errorf("expected the job to run in the monitoring namespace, got %s", job.Namespace)
+	}
+	ctr := job.Spec.Template.Spec.Containers[0]
+	if !strings.HasPrefix(ctr.Image, "registry.example.com/") {
+		t.Errorf("expected the image to be pulled from the registry, got %s", ctr.Image)
+	}
+	expected := []string{
+		"metrics",
+		"--otlp-endpoint=otlp-grpc.monitoring:4317",
+		"--otlp-insecure",
+		"--rate=100",
+		"--workers=2",
+		"--duration=30s",
+	}
+	if !slices.Equal(ctr.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, ctr.Args)
+	}
+}
+
+func Test_I_Loadgen(t *testing.T) {
+	// This test deploys the Monitoring, generates load on the OTEL Collector
+	// and asserts no data is lost. It is gated as it takes a while and
+	// requires a cluster sized as the production one to be meaningful.
+	if os.Getenv("LOADGEN") == "" {
+		t.Skip("set LOADGEN=true to run the load test")
+	}
+
+	cfg := Config{
+		TracesRate:  envInt(t, "LOADGEN_TRACES_RATE", 100),
+		MetricsRate: envInt(t, "LOADGEN_METRICS_RATE", 100),
+		Workers:     envInt(t, "LOADGEN_WORKERS", 1),
+		Duration:    time.Minute,
+	}
+	if d := os.Getenv("LOADGEN_DURATION"); d != "" {
+		dur, err := time.ParseDuration(d)
+		if err != nil {
+			t.Fatalf("invalid LOADGEN_DURATION: %s", err)
+		}
+		cfg.Duration = dur
+	}
+	output := os.Getenv("LOADGEN_RESULTS")
+	if output == "" {
+		output = "loadgen-results.json"
+	}
+
+	pwd, _ := os.Getwd()
+	integration.ProgramTest(t, &integration.ProgramTestOptions{
+		Quick:       true,
+		SkipRefresh: true,
+		Dir:         path.Join(pwd, "..", ".."),
+		StackName:   "loadgen",
+		ExtraRuntimeValidation: func(t *testing.T, stack integration.RuntimeValidationStackInfo) {
+			cfg.Namespace = stack.Outputs["namespace"].(string)
+			cfg.Endpoint = stack.Outputs["otel-endpoint"].(string)
+
+			res, err := Run(context.Background(), cfg)
+			if err != nil {
+				t.Fatalf("running load test: %s", err)
+			}
+			if err := res.WriteJSON(output); err != nil {
+				t.Fatalf("writing results: %s", err)
+			}
+
+			for signal, sr := range res.Signals {
+				t.Logf("%s: accepted=%.0f refused=%.0f send_failed=%.0f loss_ratio=%f", signal, sr.Accepted, sr.Refused, sr.SendFailed, sr.LossRatio)
+			}
+			if res.ExportLatencyP99 != nil {
+				t.Logf("export latency p99: %.2fms", *res.ExportLatencyP99)
+			}
+			for _, w := range res.Warnings {
+				t.Log(w)
+			}
+			if !res.Lossless(DefaultMaxLossRatio) {
+				t.Fatalf("data loss above %f, see %s", DefaultMaxLossRatio, output)
+			}
+		},
+	})
+}
+
+func envInt(t *testing.T, key string, def int) int {
+	t.Helper()
+
+	str := os.Getenv(key)
+	if str == "" {
+		return def
+	}
+	i, err := strconv.Atoi(str)
+	if err != nil {
+		t.Fatalf("invalid %s: %s", key, err)
+	}
+	return i
+}
@@ -0,0 +1,302 @@
+package loadgen
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	// The collector exposes its self-metrics on localhost only, reached
+	// through a port-forward which is not subject to the NetworkPolicies.
+	selfMetricsPort = 8888
+
+	collectorSelector = "app.kubernetes.io/component=otel-collector,app.kubernetes.io/part-of=monitoring"
+)
+
+type (
+	// sample is a line of the Prometheus text exposition format.
+	sample struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}
+
+	// selfMetrics are the samples scraped from the collector.
+	selfMetrics []sample
+)
+
+// scrapeSelfMetrics port-forwards to the collector pod and scrapes its
+// self-metrics.
+func scrapeSelfMetrics(ctx context.Context, config *rest.Config, clientset kubernetes.Interface, namespace string) (selfMetrics, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: collectorSelector,
+		FieldSelector: "status.phase=Running",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) != 1 {
+		return nil, fmt.Errorf("expected a single running collector pod, got %d", len(pods.Items))
+	}
+
+	req := clientset.CoreV1().RESTClient().
+		Post().
+		Namespace(namespace).
+		Resource("pods").
+		Name(pods.Items[0].Name).
+		SubResource("portforward")
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	stopc, readyc := make(chan struct{}), make(chan struct{})
+	fw, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", selfMetricsPort)}, stopc, readyc, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fw.ForwardPorts()
+	}()
+	defer close(stopc)
+
+	select {
+	case <-readyc:
+	case err := <-errc:
+		return nil, errors.Wrap(err, "port-forwarding to the collector")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	ports, err := fw.GetPorts()
+	if err != nil {
+		return nil, err
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://localhost:%d/metrics", ports[0].Local), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(hreq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return parseSelfMetrics(resp.Body)
+}
+
+// waitForFlush scrapes the self-metrics until the exporters counters are
+// stable, i.e. their queues are flushed.
+func waitForFlush(ctx context.Context, config *rest.Config, clientset kubernetes.Interface, namespace string) (sm selfMetrics, err error) {
+	var last float64 = -1
+	err = wait.PollUntilContextTimeout(ctx, 10*time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+		sm, err = scrapeSelfMetrics(ctx, config, clientset, namespace)
+		if err != nil {
+			return false, err
+		}
+		sent := sm.sum("otelcol_exporter_sent_spans_total", nil) + sm.sum("otelcol_exporter_sent_metric_points_total", nil)
+		stable := sent == last
+		last = sent
+		return stable, nil
+	})
+	return
+}
+
+// signalResults computes how the collector handled the signal between the
+// two scrapes.
+func signalResults(signal string, rate int, before, after selfMetrics) *SignalResults {
+	unit, exporter := "spans", "otlp"
+	if signal == signalMetrics {
+		unit, exporter = "metric_points", "prometheusremotewrite"
+	}
+	receiver := map[string]string{"receiver": "otlp"}
+	delta := func(name string, labels map[string]string) float64 {
+		return after.sum(name, labels) - before.sum(name, labels)
+	}
+
+	sr := &SignalResults{
+		Rate:       rate,
+		Accepted:   delta("otelcol_receiver_accepted_"+unit+"_total", receiver),
+		Refused:    delta("otelcol_receiver_refused_"+unit+"_total", receiver),
+		SendFailed: delta("otelcol_exporter_send_failed_"+unit+"_total", map[string]string{"exporter": exporter}),
+	}
+	if total := sr.Accepted + sr.Refused; total != 0 {
+		sr.LossRatio = (sr.Refused + sr.SendFailed) / total
+	}
+	return sr
+}
+
+// parseSelfMetrics parses the Prometheus text exposition format, ignoring
+// comments and timestamps.
+func parseSelfMetrics(r io.Reader) (selfMetrics, error) {
+	sm := selfMetrics{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := parseSample(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing line %q", line)
+		}
+		sm = append(sm, s)
+	}
+	return sm, scanner.Err()
+}
+
+func parseSample(line string) (sample, error) {
+	s := sample{
+		labels: map[string]string{},
+	}
+
+	rest := line
+	if i := strings.IndexByte(line, '{'); i != -1 {
+		j := strings.LastIndexByte(line, '}')
+		if j < i {
+			return s, errors.New("unterminated labels")
+		}
+		s.name = line[:i]
+		if err := parseLabels(line[i+1:j], s.labels); err != nil {
+			return s, err
+		}
+		rest = line[j+1:]
+	} else {
+		var ok bool
+		s.name, rest, ok = strings.Cut(line, " ")
+		if !ok {
+			return s, errors.New("missing value")
+		}
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return s, errors.New("missing value")
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return s, err
+	}
+	s.value = v
+	return s, nil
+}
+
+func parseLabels(str string, labels map[string]string) error {
+	for str != "" {
+		name, rest, ok := strings.Cut(str, "=")
+		if !ok || !strings.HasPrefix(rest, `"`) {
+			return fmt.Errorf("invalid label in %q", str)
+		}
+		// Find the closing quote, skipping the escaped ones
+		end := 1
+		for ; end < len(rest); end++ {
+			if rest[end] == '\\' {
+				end++
+				continue
+			}
+			if rest[end] == '"' {
+				break
+			}
+		}
+		if end >= len(rest) {
+			return fmt.Errorf("unterminated label value in %q", str)
+		}
+		value, err := strconv.Unquote(rest[:end+1])
+		if err != nil {
+			return err
+		}
+		labels[strings.TrimSpace(name)] = value
+		str = strings.TrimLeft(rest[end+1:], ", ")
+	}
+	return nil
+}
+
+// sum adds the values of the samples of the given name matching the labels.
+func (sm selfMetrics) sum(name string, labels map[string]string) (total float64) {
+	for _, s := range sm {
+		if s.name == name && s.matches(labels) {
+			total += s.value
+		}
+	}
+	return
+}
+
+func (s sample) matches(labels map[string]string) bool {
+	for k, v := range labels {
+		if s.labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// histogramQuantile estimates the q-quantile of the histogram, aggregated
+// over all its series, interpolating linearly within buckets as Prometheus
+// histogram_quantile does.
+func (sm selfMetrics) histogramQuantile(q float64, histogram string) (float64, bool) {
+	buckets := map[float64]float64{}
+	for _, s := range sm {
+		if s.name != histogram+"_bucket" {
+			continue
+		}
+		le, err := strconv.ParseFloat(s.labels["le"], 64)
+		if err != nil {
+			continue
+		}
+		buckets[le] += s.value
+	}
+	if len(buckets) == 0 {
+		return 0, false
+	}
+
+	bounds := make([]float64, 0, len(buckets))
+	for le := range buckets {
+		bounds = append(bounds, le)
+	}
+	sort.Float64s(bounds)
+
+	total := buckets[bounds[len(bounds)-1]]
+	if total == 0 {
+		return 0, false
+	}
+	rank := q * total
+
+	prevBound, prevCount := 0.0, 0.0
+	for _, le := range bounds {
+		count := buckets[le]
+		if count >= rank {
+			// The quantile falls in the +Inf bucket, return the highest finite bound
+			if math.IsInf(le, 1) {
+				return prevBound, true
+			}
+			if count == prevCount {
+				return le, true
+			}
+			return prevBound + (le-prevBound)*(rank-prevCount)/(count-prevCount), true
+		}
+		prevBound, prevCount = le, count
+	}
+	return prevBound, true
+}